@@ -53,6 +53,7 @@ var apiNamespaceHandlers = map[string]APINamespaceHandler{
 	"upload_commits":      apiUploadCommits,
 	"bug_list":            apiBugList,
 	"load_bug":            apiLoadBug,
+	"triage_bug":          apiTriageBug,
 }
 
 type JSONHandler func(c context.Context, r *http.Request) (interface{}, error)
@@ -999,7 +1000,7 @@ func apiManagerStats(c context.Context, ns string, r *http.Request, payload []by
 		stats.TotalCrashes += int64(req.Crashes)
 		stats.SuppressedCrashes += int64(req.SuppressedCrashes)
 		stats.TotalExecs += int64(req.Execs)
-		return nil
+		return updateBuildCoverage(c, mgr, req)
 	})
 	return nil, err
 }
@@ -1249,6 +1250,7 @@ func needReproForBug(c context.Context, bug *Bug) bool {
 		len(bug.Commits) == 0 &&
 		bug.Title != corruptedReportTitle &&
 		bug.Title != suppressedReportTitle &&
+		!strings.HasPrefix(bug.Title, dashapi.VerifierMismatchPrefix) &&
 		config.Namespaces[bug.Namespace].NeedRepro(bug) &&
 		(bug.NumRepro < maxReproPerBug ||
 			bug.ReproLevel == ReproLevelNone &&
@@ -57,9 +57,11 @@ var testConfig = &GlobalConfig{
 			Key:                   "test1keytest1keytest1key",
 			FixBisectionAutoClose: true,
 			Clients: map[string]string{
-				client1: password1,
-				"oauth": auth.OauthMagic + "111111122222222",
+				client1:      password1,
+				"oauth":      auth.OauthMagic + "111111122222222",
+				clientTriage: keyTriage,
 			},
+			TriageClients: []string{clientTriage},
 			Repos: []KernelRepo{
 				{
 					URL:    "git://syzkaller.org",
@@ -257,6 +259,7 @@ var testConfig = &GlobalConfig{
 					Config:     &TestConfig{Index: 2},
 				},
 			},
+			Trackers: []IssueTracker{testTracker},
 		},
 	},
 }
@@ -272,6 +275,8 @@ const (
 	keyUser      = "clientuserkeyclientuserkey"
 	clientPublic = "client-public"
 	keyPublic    = "clientpublickeyclientpublickey"
+	clientTriage = "client-triage"
+	keyTriage    = "clienttriagekeyclienttriagekey"
 
 	restrictedManager     = "restricted-manager"
 	noFixBisectionManager = "no-fix-bisection-manager"
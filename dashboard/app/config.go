@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/mail"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/syzkaller/dashboard/dashapi"
@@ -71,6 +72,11 @@ type Config struct {
 	// Per-namespace clients that act only on a particular namespace.
 	// The keys are client identities (names), the values are their passwords.
 	Clients map[string]string
+	// Names of the namespace clients that are allowed to use the triage API
+	// (add labels and comments, set subsystems, close and invalidate bugs).
+	TriageClients []string
+	// If not nil, restricts who can send bug commands over email.
+	EmailCommands *EmailCommandsConfig
 	// A random string used for hashing, can be anything, but once fixed it can't
 	// be changed as it becomes a part of persistent bug identifiers.
 	Key string
@@ -98,6 +104,9 @@ type Config struct {
 	Repos []KernelRepo
 	// If not nil, bugs in this namespace will be exported to the specified Kcidb.
 	Kcidb *KcidbConfig
+	// Bugs in this namespace will be exported to these external issue trackers,
+	// status and fix commits are kept in sync in both directions.
+	Trackers []IssueTracker
 }
 
 // ObsoletingConfig describes how bugs without reproducer should be obsoleted.
@@ -189,6 +198,24 @@ type CCConfig struct {
 	BuildMaintainers []string
 }
 
+// EmailCommandsConfig describes which senders may issue which email commands.
+type EmailCommandsConfig struct {
+	// Secret used to generate per-bug per-sender command tokens (see email.CommandToken).
+	// Senders whose mail servers don't support DKIM/SPF can authenticate with such token,
+	// the token is shown on the bug page to logged in users.
+	TokenSecret string
+	// Per-command policies, commands that are not present are not restricted.
+	Commands map[email.Command]EmailCommandPolicy
+}
+
+type EmailCommandPolicy struct {
+	// If set, the sender must be authenticated by DKIM/SPF/DMARC or by a command token.
+	RequireAuth bool
+	// If not empty, only these senders can issue the command.
+	// Entries are either emails or whole domains in the form "@domain.com".
+	Senders []string
+}
+
 type KcidbConfig struct {
 	// Origin is how this system identified in Kcidb, e.g. "syzbot_foobar".
 	Origin string
@@ -250,6 +277,7 @@ func installConfig(cfg *GlobalConfig) {
 	initHTTPHandlers()
 	initAPIHandlers()
 	initKcidb()
+	initTrackers()
 }
 
 func checkConfig(cfg *GlobalConfig) {
@@ -314,6 +342,14 @@ func checkNamespace(ns string, cfg *Config, namespaces, clientNames map[string]b
 		cfg.SimilarityDomain = ns
 	}
 	checkClients(clientNames, cfg.Clients)
+	for _, name := range cfg.TriageClients {
+		if cfg.Clients[name] == "" {
+			panic(fmt.Sprintf("triage client %q is not a client of namespace %q", name, ns))
+		}
+	}
+	if cfg.EmailCommands != nil {
+		checkEmailCommands(ns, cfg.EmailCommands)
+	}
 	for name, mgr := range cfg.Managers {
 		checkManager(ns, name, mgr)
 	}
@@ -336,6 +372,7 @@ func checkNamespace(ns string, cfg *Config, namespaces, clientNames map[string]b
 	if cfg.Kcidb != nil {
 		checkKcidb(ns, cfg.Kcidb)
 	}
+	checkTrackers(ns, cfg.Trackers)
 	checkKernelRepos(ns, cfg)
 	checkNamespaceReporting(ns, cfg)
 }
@@ -438,6 +475,22 @@ func checkManager(ns, name string, mgr ConfigManager) {
 	checkCC(&mgr.CC)
 }
 
+func checkEmailCommands(ns string, cfg *EmailCommandsConfig) {
+	for cmd, policy := range cfg.Commands {
+		if cmd == email.CmdNone || cmd == email.CmdUnknown {
+			panic(fmt.Sprintf("%v: bad email command %v", ns, cmd))
+		}
+		for _, sender := range policy.Senders {
+			if strings.HasPrefix(sender, "@") && len(sender) > 1 {
+				continue
+			}
+			if _, err := mail.ParseAddress(sender); err != nil {
+				panic(fmt.Sprintf("%v: bad email command sender %q: %v", ns, sender, err))
+			}
+		}
+	}
+}
+
 func checkKcidb(ns string, kcidb *KcidbConfig) {
 	if !regexp.MustCompile("^[a-z0-9_]+$").MatchString(kcidb.Origin) {
 		panic(fmt.Sprintf("%v: bad Kcidb origin %q", ns, kcidb.Origin))
@@ -0,0 +1,90 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/syzkaller/dashboard/dashapi"
+	"golang.org/x/net/context"
+	db "google.golang.org/appengine/v2/datastore"
+	"google.golang.org/appengine/v2/log"
+)
+
+// This file contains detection of per-subsystem coverage regressions between consecutive kernel builds.
+// Coverage is snapshotted once a manager has fuzzed a build for coverRegressionUpTime,
+// so that builds are compared at the same point of their lifetime.
+
+const (
+	coverRegressionUpTime = 3 * time.Hour
+	// Subsystems with less coverage on the previous build are ignored, they are too noisy.
+	coverRegressionMinPCs = 500
+	// Relative coverage drop that is considered a regression.
+	coverRegressionDrop = 0.3
+)
+
+// updateBuildCoverage is called within the updateManager transaction.
+func updateBuildCoverage(c context.Context, mgr *Manager, req *dashapi.ManagerStatsReq) error {
+	if len(req.SubsystemPCs) == 0 || mgr.CurrentBuild == "" || req.UpTime < coverRegressionUpTime {
+		return nil
+	}
+	mgrKey := mgr.key(c)
+	key := db.NewKey(c, "BuildCoverage", mgr.CurrentBuild, 0, mgrKey)
+	if err := db.Get(c, key, new(BuildCoverage)); err == nil {
+		return nil // already have a snapshot for this build
+	} else if err != db.ErrNoSuchEntity {
+		return fmt.Errorf("failed to get build coverage: %v", err)
+	}
+	var prev []*BuildCoverage
+	if _, err := db.NewQuery("BuildCoverage").
+		Ancestor(mgrKey).
+		Order("-Time").
+		Limit(1).
+		GetAll(c, &prev); err != nil {
+		return fmt.Errorf("failed to query build coverage: %v", err)
+	}
+	cov := &BuildCoverage{
+		BuildID: mgr.CurrentBuild,
+		Time:    timeNow(c),
+	}
+	for name, pcs := range req.SubsystemPCs {
+		cov.Subsystems = append(cov.Subsystems, SubsystemCoverage{name, int64(pcs)})
+	}
+	sort.Slice(cov.Subsystems, func(i, j int) bool {
+		return cov.Subsystems[i].Name < cov.Subsystems[j].Name
+	})
+	mgr.CoverRegression = ""
+	if len(prev) != 0 {
+		if regressions := coverRegressions(prev[0].Subsystems, cov.Subsystems); len(regressions) != 0 {
+			mgr.CoverRegression = strings.Join(regressions, ", ")
+			log.Errorf(c, "coverage regression on %v/%v between builds %v and %v: %v",
+				mgr.Namespace, mgr.Name, prev[0].BuildID, cov.BuildID, mgr.CoverRegression)
+		}
+	}
+	if _, err := db.Put(c, key, cov); err != nil {
+		return fmt.Errorf("failed to put build coverage: %v", err)
+	}
+	return nil
+}
+
+func coverRegressions(prev, cur []SubsystemCoverage) []string {
+	curPCs := make(map[string]int64)
+	for _, subsystem := range cur {
+		curPCs[subsystem.Name] = subsystem.PCs
+	}
+	var res []string
+	for _, subsystem := range prev {
+		if subsystem.PCs < coverRegressionMinPCs {
+			continue
+		}
+		pcs := curPCs[subsystem.Name]
+		if float64(pcs) < float64(subsystem.PCs)*(1-coverRegressionDrop) {
+			res = append(res, fmt.Sprintf("%v: %v -> %v", subsystem.Name, subsystem.PCs, pcs))
+		}
+	}
+	return res
+}
@@ -0,0 +1,33 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCoverRegressions(t *testing.T) {
+	prev := []SubsystemCoverage{
+		{"all", 100000},
+		{"net", 20000},
+		{"sound", 5000},
+		{"tiny", 100},
+		{"usb", 3000},
+	}
+	cur := []SubsystemCoverage{
+		{"all", 90000},
+		{"net", 19000},
+		{"sound", 1000},
+		{"tiny", 0},
+	}
+	want := []string{
+		"sound: 5000 -> 1000",
+		"usb: 3000 -> 0",
+	}
+	if diff := cmp.Diff(want, coverRegressions(prev, cur)); diff != "" {
+		t.Fatal(diff)
+	}
+}
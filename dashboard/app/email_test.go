@@ -771,3 +771,40 @@ func TestEmailManagerCC(t *testing.T) {
 		"default@maintainers.com",
 	})
 }
+
+func TestCheckEmailCommand(t *testing.T) {
+	cfg := &EmailCommandsConfig{
+		TokenSecret: "secret",
+		Commands: map[email.Command]EmailCommandPolicy{
+			email.CmdInvalid: {RequireAuth: true},
+			email.CmdFix:     {Senders: []string{"@kernel.org", "maintainer@foo.com"}},
+		},
+	}
+	const bugHash = "0123456789abcdef"
+	tests := []struct {
+		msg *email.Email
+		ok  bool
+	}{
+		{&email.Email{From: "foo@bar.com", Command: email.CmdNone}, true},
+		{&email.Email{From: "foo@bar.com", Command: email.CmdUpstream}, true},
+		{&email.Email{From: "foo@bar.com", Command: email.CmdInvalid}, false},
+		{&email.Email{From: "foo@bar.com", Command: email.CmdInvalid, Authenticated: true}, true},
+		{&email.Email{From: "foo@bar.com", Command: email.CmdInvalid,
+			Token: email.CommandToken("secret", bugHash, "foo@bar.com")}, true},
+		{&email.Email{From: "foo@bar.com", Command: email.CmdInvalid,
+			Token: email.CommandToken("secret", bugHash, "bar@bar.com")}, false},
+		{&email.Email{From: "Foo <foo@Kernel.org>", Command: email.CmdFix}, true},
+		{&email.Email{From: "maintainer+bot@foo.com", Command: email.CmdFix}, true},
+		{&email.Email{From: "foo@evilkernel.org", Command: email.CmdFix}, false},
+		{&email.Email{From: "foo@foo.com", Command: email.CmdFix}, false},
+	}
+	for i, test := range tests {
+		reason := checkEmailCommand(cfg, test.msg, bugHash)
+		if test.ok != (reason == "") {
+			t.Errorf("#%v: want ok=%v, got reason %q", i, test.ok, reason)
+		}
+	}
+	if reason := checkEmailCommand(nil, tests[2].msg, bugHash); reason != "" {
+		t.Errorf("nil config rejected the command: %q", reason)
+	}
+}
@@ -34,6 +34,21 @@ type Manager struct {
 	FailedSyzBuildBug string
 	LastAlive         time.Time
 	CurrentUpTime     time.Duration
+	CoverRegression   string `datastore:",noindex"` // subsystems that lost coverage on the current build
+}
+
+// BuildCoverage holds per-subsystem coverage reached by a manager on a single build
+// after fuzzing it for coverRegressionUpTime.
+// Has Manager as parent entity. Keyed by build ID.
+type BuildCoverage struct {
+	BuildID    string
+	Time       time.Time
+	Subsystems []SubsystemCoverage `datastore:",noindex"`
+}
+
+type SubsystemCoverage struct {
+	Name string
+	PCs  int64
 }
 
 // ManagerStats holds per-day manager runtime stats.
@@ -103,6 +118,56 @@ type Bug struct {
 	// bit 1 - don't want to publish it (syzkaller build/test errors)
 	KcidbStatus int64
 	DailyStats  []BugDailyStats
+	Labels      []string // set by triage clients
+	Subsystems  []string // set by triage clients
+}
+
+// Issue groups bugs that are likely caused by the same root cause.
+// Issues are either created by the periodic clustering of open bugs,
+// or edited manually by admins (in which case clustering does not touch them anymore).
+type Issue struct {
+	Namespace string
+	Root      string   // key hash of the bug whose reporting thread is shared by the issue
+	Bugs      []string // key hashes of all bugs in the issue (including Root)
+	Manual    bool
+	Updated   time.Time
+}
+
+// IssueFeatures are the features of an open bug used to group bugs into issues (see issues.go).
+// They are computed once for each bug, the key name is the bug key hash.
+type IssueFeatures struct {
+	Namespace    string
+	Frames       []string `datastore:",noindex"`
+	GuiltyFile   string   `datastore:",noindex"`
+	BisectCommit string   `datastore:",noindex"`
+	// Report is the crash report the frames were taken from, 0 if the bug had no report.
+	Report int64 `datastore:",noindex"`
+	// BisectCause is the cause bisection status of the bug the features were computed with.
+	BisectCause BisectStatus `datastore:",noindex"`
+}
+
+// TriageAction is an audit record of a single bug update done via the triage API.
+// Has Bug as parent entity.
+type TriageAction struct {
+	Client  string
+	Time    time.Time
+	Action  string
+	Args    string `datastore:",noindex"`
+	Comment string `datastore:",noindex"`
+	// Error is set if the status change of the action failed after the action was recorded.
+	Error string `datastore:",noindex"`
+}
+
+// TrackedIssue is an issue created for the bug in an external tracker (see Config.Trackers).
+// Has Bug as parent entity, key name is the tracker name.
+type TrackedIssue struct {
+	Namespace  string
+	Tracker    string
+	IssueID    string
+	Created    time.Time
+	Synced     time.Time     // last time status/fix commits changed on either side
+	Status     TrackerStatus // last synchronized status
+	FixCommits []string      // last synchronized fix commits
 }
 
 type BugDailyStats struct {
@@ -626,6 +691,16 @@ func stringInList(list []string, str string) bool {
 	return false
 }
 
+func removeString(list []string, str string) []string {
+	var res []string
+	for _, s := range list {
+		if s != str {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
 func mergeString(list []string, str string) []string {
 	if !stringInList(list, str) {
 		list = append(list, str)
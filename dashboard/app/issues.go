@@ -0,0 +1,678 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/appengine/v2"
+	db "google.golang.org/appengine/v2/datastore"
+	"google.golang.org/appengine/v2/log"
+)
+
+// This file contains grouping of bugs into issues.
+// Open bugs are periodically clustered based on similarity of their crash stacks,
+// the guilty file and the cause bisection result. The features of each bug are computed once
+// and stored in IssueFeatures, the automatic issues are updated in place between the runs. Admins can additionally merge and split
+// issues by hand. New reports for bugs in an issue are sent into the thread of the issue root bug.
+
+const (
+	// Number of top crash frames compared between bugs.
+	issueMaxFrames = 8
+	// Minimal similarity score for two bugs to end up in the same issue.
+	issueThreshold = 0.6
+	// Maximal number of entities in a single datastore batch operation.
+	issueFeaturesBatch = 500
+)
+
+// Frames that are present in most reports and say nothing about the root cause.
+var issueIgnoredFiles = []string{
+	"lib/dump_stack.c",
+	"kernel/panic.c",
+	"kernel/locking/lockdep.c",
+	"mm/kasan/",
+	"mm/kfence/",
+	"lib/ubsan.c",
+	"arch/x86/entry/",
+}
+
+type issueFeatures struct {
+	frames       map[string]bool
+	guiltyFile   string
+	bisectCommit string
+}
+
+func handleIssueCluster(w http.ResponseWriter, r *http.Request) {
+	c := appengine.NewContext(r)
+	for ns := range config.Namespaces {
+		if err := clusterNamespaceIssues(c, ns); err != nil {
+			log.Errorf(c, "failed to cluster issues for ns=%v: %v", ns, err)
+		}
+	}
+}
+
+func clusterNamespaceIssues(c context.Context, ns string) error {
+	bugs, _, err := loadAllBugs(c, func(query *db.Query) *db.Query {
+		return query.Filter("Namespace=", ns).
+			Filter("Status=", BugStatusOpen)
+	})
+	if err != nil {
+		return err
+	}
+	var issues []*Issue
+	issueKeys, err := db.NewQuery("Issue").
+		Filter("Namespace=", ns).
+		GetAll(c, &issues)
+	if err != nil {
+		return fmt.Errorf("failed to query issues: %v", err)
+	}
+	manual := make(map[string]bool)
+	var autoIssues []*Issue
+	var autoKeys []*db.Key
+	for i, issue := range issues {
+		if !issue.Manual {
+			autoIssues = append(autoIssues, issue)
+			autoKeys = append(autoKeys, issueKeys[i])
+			continue
+		}
+		for _, bug := range issue.Bugs {
+			manual[bug] = true
+		}
+	}
+	open := make(map[string]bool)
+	var free []*Bug
+	for _, bug := range bugs {
+		open[bug.keyHash()] = true
+		if !manual[bug.keyHash()] {
+			free = append(free, bug)
+		}
+	}
+	if err := deleteIssueFeatures(c, ns, open); err != nil {
+		return err
+	}
+	free, features, err := loadIssueFeatures(c, free)
+	if err != nil {
+		return err
+	}
+	var clusters [][]*Bug
+	for _, cluster := range clusterIssues(features) {
+		var members []*Bug
+		for _, idx := range cluster {
+			members = append(members, free[idx])
+		}
+		clusters = append(clusters, members)
+	}
+	return updateAutoIssues(c, ns, autoIssues, autoKeys, clusters, open)
+}
+
+// updateAutoIssues updates the automatic issues in place to match the new clusters, so that the keys
+// and the roots (and thus the reporting threads) of the issues stay the same as long as their bugs
+// stay together. Issues that don't match any cluster anymore are deleted.
+func updateAutoIssues(c context.Context, ns string, issues []*Issue, keys []*db.Key,
+	clusters [][]*Bug, open map[string]bool) error {
+	now := timeNow(c)
+	used := make([]bool, len(issues))
+	var putKeys []*db.Key
+	var putIssues []*Issue
+	for i, idx := range matchIssues(issues, clusters) {
+		if idx == -1 {
+			putKeys = append(putKeys, db.NewIncompleteKey(c, "Issue", nil))
+			putIssues = append(putIssues, makeIssue(ns, clusters[i], now))
+			continue
+		}
+		used[idx] = true
+		if updateIssue(issues[idx], clusters[i], open, now) {
+			putKeys = append(putKeys, keys[idx])
+			putIssues = append(putIssues, issues[idx])
+		}
+	}
+	var deleteKeys []*db.Key
+	for i, key := range keys {
+		if !used[i] {
+			deleteKeys = append(deleteKeys, key)
+		}
+	}
+	if len(deleteKeys) != 0 {
+		if err := db.DeleteMulti(c, deleteKeys); err != nil {
+			return fmt.Errorf("failed to delete issues: %v", err)
+		}
+	}
+	if len(putKeys) != 0 {
+		if _, err := db.PutMulti(c, putKeys, putIssues); err != nil {
+			return fmt.Errorf("failed to save issues: %v", err)
+		}
+	}
+	return nil
+}
+
+// matchIssues returns for each cluster the index of the existing issue it continues, or -1.
+// Clusters are matched greedily to the issues they share the most bugs with, the root counts twice.
+func matchIssues(issues []*Issue, clusters [][]*Bug) []int {
+	issueOf := make(map[string]int)
+	for i, issue := range issues {
+		for _, hash := range issue.Bugs {
+			issueOf[hash] = i
+		}
+	}
+	type candidate struct {
+		cluster, issue, score int
+	}
+	var candidates []candidate
+	for i, cluster := range clusters {
+		scores := make(map[int]int)
+		for _, bug := range cluster {
+			idx, ok := issueOf[bug.keyHash()]
+			if !ok {
+				continue
+			}
+			scores[idx]++
+			if issues[idx].Root == bug.keyHash() {
+				scores[idx]++
+			}
+		}
+		for idx, score := range scores {
+			candidates = append(candidates, candidate{i, idx, score})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.cluster != b.cluster {
+			return a.cluster < b.cluster
+		}
+		return a.issue < b.issue
+	})
+	res := make([]int, len(clusters))
+	for i := range res {
+		res[i] = -1
+	}
+	used := make(map[int]bool)
+	for _, cand := range candidates {
+		if res[cand.cluster] != -1 || used[cand.issue] {
+			continue
+		}
+		res[cand.cluster] = cand.issue
+		used[cand.issue] = true
+	}
+	return res
+}
+
+// updateIssue sets the bugs of the issue to the cluster and returns whether the issue changed.
+// The root stays unless it's an open bug that left the issue. A closed root is kept in the issue,
+// so that new reports of the other bugs still go into its thread.
+func updateIssue(issue *Issue, cluster []*Bug, open map[string]bool, now time.Time) bool {
+	sortIssueBugs(cluster)
+	var bugs []string
+	hasRoot := false
+	for _, bug := range cluster {
+		bugs = append(bugs, bug.keyHash())
+		hasRoot = hasRoot || bug.keyHash() == issue.Root
+	}
+	root := issue.Root
+	if !hasRoot {
+		if open[root] {
+			root = bugs[0]
+		} else {
+			bugs = append([]string{root}, bugs...)
+		}
+	}
+	if root == issue.Root && stringSetsEqual(bugs, issue.Bugs) {
+		return false
+	}
+	issue.Root = root
+	issue.Bugs = bugs
+	issue.Updated = now
+	return true
+}
+
+func stringSetsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool)
+	for _, s := range a {
+		set[s] = true
+	}
+	for _, s := range b {
+		if !set[s] {
+			return false
+		}
+	}
+	return true
+}
+
+// makeIssue creates an issue from the bugs, the earliest bug becomes the root.
+func makeIssue(ns string, bugs []*Bug, now time.Time) *Issue {
+	sortIssueBugs(bugs)
+	issue := &Issue{
+		Namespace: ns,
+		Root:      bugs[0].keyHash(),
+		Updated:   now,
+	}
+	for _, bug := range bugs {
+		issue.Bugs = append(issue.Bugs, bug.keyHash())
+	}
+	return issue
+}
+
+func sortIssueBugs(bugs []*Bug) {
+	sort.Slice(bugs, func(i, j int) bool {
+		return bugs[i].FirstTime.Before(bugs[j].FirstTime)
+	})
+}
+
+// loadIssueFeatures returns the stored features of the bugs, the missing and stale features
+// are computed and stored. Bugs whose features can't be computed are skipped, so the returned
+// bugs correspond to the returned features.
+func loadIssueFeatures(c context.Context, bugs []*Bug) ([]*Bug, []*issueFeatures, error) {
+	var resBugs []*Bug
+	var res []*issueFeatures
+	for len(bugs) != 0 {
+		batch := bugs
+		if len(batch) > issueFeaturesBatch {
+			batch = batch[:issueFeaturesBatch]
+		}
+		bugs = bugs[len(batch):]
+		keys := make([]*db.Key, len(batch))
+		stored := make([]*IssueFeatures, len(batch))
+		for i, bug := range batch {
+			keys[i] = db.NewKey(c, "IssueFeatures", bug.keyHash(), 0, nil)
+			stored[i] = new(IssueFeatures)
+		}
+		var missing appengine.MultiError
+		if err := db.GetMulti(c, keys, stored); err != nil {
+			var ok bool
+			if missing, ok = err.(appengine.MultiError); !ok {
+				return nil, nil, fmt.Errorf("failed to get issue features: %v", err)
+			}
+		}
+		var putKeys []*db.Key
+		var putFeatures []*IssueFeatures
+		for i, bug := range batch {
+			feat := stored[i]
+			if missing != nil && missing[i] != nil {
+				if missing[i] != db.ErrNoSuchEntity {
+					return nil, nil, fmt.Errorf("failed to get issue features: %v", missing[i])
+				}
+				feat = nil
+			}
+			if feat == nil || feat.stale(bug) {
+				var err error
+				if feat, err = computeIssueFeatures(c, bug); err != nil {
+					log.Errorf(c, "failed to compute issue features for %q: %v", bug.Title, err)
+					continue
+				}
+				putKeys = append(putKeys, keys[i])
+				putFeatures = append(putFeatures, feat)
+			}
+			resBugs = append(resBugs, bug)
+			res = append(res, feat.features())
+		}
+		if len(putKeys) != 0 {
+			if _, err := db.PutMulti(c, putKeys, putFeatures); err != nil {
+				return nil, nil, fmt.Errorf("failed to save issue features: %v", err)
+			}
+		}
+	}
+	return resBugs, res, nil
+}
+
+// deleteIssueFeatures deletes the stored features of the bugs of the namespace that are not open anymore.
+func deleteIssueFeatures(c context.Context, ns string, open map[string]bool) error {
+	keys, err := db.NewQuery("IssueFeatures").
+		Filter("Namespace=", ns).
+		KeysOnly().
+		GetAll(c, nil)
+	if err != nil {
+		return fmt.Errorf("failed to query issue features: %v", err)
+	}
+	var stale []*db.Key
+	for _, key := range keys {
+		if !open[key.StringID()] {
+			stale = append(stale, key)
+		}
+	}
+	for len(stale) != 0 {
+		batch := stale
+		if len(batch) > issueFeaturesBatch {
+			batch = batch[:issueFeaturesBatch]
+		}
+		stale = stale[len(batch):]
+		if err := db.DeleteMulti(c, batch); err != nil {
+			return fmt.Errorf("failed to delete issue features: %v", err)
+		}
+	}
+	return nil
+}
+
+// stale returns true if the features need to be recomputed: the bug had no report
+// or the cause bisection status changed.
+func (f *IssueFeatures) stale(bug *Bug) bool {
+	return f.Report == 0 || f.BisectCause != bug.BisectCause
+}
+
+func (f *IssueFeatures) features() *issueFeatures {
+	feat := &issueFeatures{
+		frames:       make(map[string]bool),
+		guiltyFile:   f.GuiltyFile,
+		bisectCommit: f.BisectCommit,
+	}
+	for _, frame := range f.Frames {
+		feat.frames[frame] = true
+	}
+	return feat
+}
+
+func computeIssueFeatures(c context.Context, bug *Bug) (*IssueFeatures, error) {
+	res := &IssueFeatures{
+		Namespace:   bug.Namespace,
+		BisectCause: bug.BisectCause,
+	}
+	crashes, _, err := queryCrashesForBug(c, bug.key(c), 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(crashes) != 0 && crashes[0].Report != 0 {
+		report, _, err := getText(c, textCrashReport, crashes[0].Report)
+		if err != nil {
+			return nil, err
+		}
+		feat := &issueFeatures{frames: make(map[string]bool)}
+		stack, _, _ := parseSourceStacks(report)
+		fillIssueFrames(feat, stack.Frames)
+		for frame := range feat.frames {
+			res.Frames = append(res.Frames, frame)
+		}
+		sort.Strings(res.Frames)
+		res.GuiltyFile = feat.guiltyFile
+		res.Report = crashes[0].Report
+	}
+	if bug.BisectCause == BisectYes {
+		job, _, _, _, err := loadBisectJob(c, bug, JobBisectCause)
+		if err != nil {
+			return nil, err
+		}
+		if len(job.Commits) == 1 {
+			res.BisectCommit = job.Commits[0].Hash
+		}
+	}
+	return res, nil
+}
+
+func fillIssueFrames(feat *issueFeatures, frames []*uiSourceFrame) {
+	for _, frame := range frames {
+		if len(feat.frames) == issueMaxFrames {
+			break
+		}
+		ignored := false
+		for _, prefix := range issueIgnoredFiles {
+			if strings.HasPrefix(frame.File, prefix) {
+				ignored = true
+				break
+			}
+		}
+		if ignored {
+			continue
+		}
+		if feat.guiltyFile == "" {
+			feat.guiltyFile = frame.File
+		}
+		feat.frames[frame.Func] = true
+	}
+}
+
+func issueSimilarity(a, b *issueFeatures) float64 {
+	score := 0.0
+	common := 0
+	for frame := range a.frames {
+		if b.frames[frame] {
+			common++
+		}
+	}
+	if total := len(a.frames) + len(b.frames) - common; total != 0 {
+		score += 0.5 * float64(common) / float64(total)
+	}
+	if a.guiltyFile != "" && a.guiltyFile == b.guiltyFile {
+		score += 0.3
+	}
+	if a.bisectCommit != "" && a.bisectCommit == b.bisectCommit {
+		score += 0.5
+	}
+	return score
+}
+
+// clusterIssues returns groups of 2 or more similar bugs (as indices into features).
+// Bugs that don't share a frame, the guilty file or the bisection commit have zero similarity,
+// so only the bugs that share one of them are compared.
+func clusterIssues(features []*issueFeatures) [][]int {
+	parent := make([]int, len(features))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	index := make(map[string][]int)
+	for i, feat := range features {
+		for frame := range feat.frames {
+			index["frame:"+frame] = append(index["frame:"+frame], i)
+		}
+		if feat.guiltyFile != "" {
+			index["file:"+feat.guiltyFile] = append(index["file:"+feat.guiltyFile], i)
+		}
+		if feat.bisectCommit != "" {
+			index["commit:"+feat.bisectCommit] = append(index["commit:"+feat.bisectCommit], i)
+		}
+	}
+	compared := make(map[[2]int]bool)
+	for _, similar := range index {
+		for x, i := range similar {
+			for _, j := range similar[x+1:] {
+				if compared[[2]int{i, j}] {
+					continue
+				}
+				compared[[2]int{i, j}] = true
+				if issueSimilarity(features[i], features[j]) >= issueThreshold {
+					parent[find(j)] = find(i)
+				}
+			}
+		}
+	}
+	groups := make(map[int][]int)
+	for i := range features {
+		root := find(i)
+		groups[root] = append(groups[root], i)
+	}
+	var res [][]int
+	for _, group := range groups {
+		if len(group) > 1 {
+			res = append(res, group)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i][0] < res[j][0]
+	})
+	return res
+}
+
+func loadIssueForBug(c context.Context, bugHash string) (*Issue, *db.Key, error) {
+	var issues []*Issue
+	keys, err := db.NewQuery("Issue").
+		Filter("Bugs=", bugHash).
+		Limit(1).
+		GetAll(c, &issues)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query issues: %v", err)
+	}
+	if len(issues) == 0 {
+		return nil, nil, nil
+	}
+	return issues[0], keys[0], nil
+}
+
+// handleIssue allows admins to manually merge bug issues and split bugs from issues.
+func handleIssue(c context.Context, w http.ResponseWriter, r *http.Request) error {
+	if accessLevel(c, r) != AccessAdmin {
+		return ErrAccess
+	}
+	bug, err := findBugByID(c, r)
+	if err != nil {
+		return ErrDontLog{err}
+	}
+	switch action := r.FormValue("action"); action {
+	case "merge":
+		into := new(Bug)
+		if err := db.Get(c, db.NewKey(c, "Bug", r.FormValue("into"), 0, nil), into); err != nil {
+			return ErrDontLog{fmt.Errorf("failed to load bug to merge into: %v", err)}
+		}
+		if into.Namespace != bug.Namespace {
+			return ErrDontLog{fmt.Errorf("can't merge bugs from different namespaces")}
+		}
+		err = mergeIssues(c, bug, into)
+	case "split":
+		err = splitIssue(c, bug)
+	default:
+		return ErrDontLog{fmt.Errorf("unknown action %q", action)}
+	}
+	if err != nil {
+		return err
+	}
+	http.Redirect(w, r, bugLink(bug.keyHash()), http.StatusFound)
+	return nil
+}
+
+// mergeIssues moves the issue of bug (or the bug alone) into the issue of into.
+func mergeIssues(c context.Context, bug, into *Bug) error {
+	src, srcKey, err := loadIssueForBug(c, bug.keyHash())
+	if err != nil {
+		return err
+	}
+	dst, dstKey, err := loadIssueForBug(c, into.keyHash())
+	if err != nil {
+		return err
+	}
+	if dst == nil {
+		dst = makeIssue(into.Namespace, []*Bug{into}, timeNow(c))
+		dstKey = db.NewIncompleteKey(c, "Issue", nil)
+	}
+	if src == nil {
+		src = &Issue{Bugs: []string{bug.keyHash()}}
+	} else if srcKey.Equal(dstKey) {
+		return nil
+	}
+	dst.Bugs = mergeStringList(dst.Bugs, src.Bugs)
+	dst.Manual = true
+	dst.Updated = timeNow(c)
+	if _, err := db.Put(c, dstKey, dst); err != nil {
+		return fmt.Errorf("failed to save issue: %v", err)
+	}
+	if srcKey != nil {
+		if err := db.Delete(c, srcKey); err != nil {
+			return fmt.Errorf("failed to delete issue: %v", err)
+		}
+	}
+	return nil
+}
+
+// splitIssue removes the bug from its issue.
+func splitIssue(c context.Context, bug *Bug) error {
+	issue, key, err := loadIssueForBug(c, bug.keyHash())
+	if err != nil || issue == nil {
+		return err
+	}
+	var rest []string
+	for _, hash := range issue.Bugs {
+		if hash != bug.keyHash() {
+			rest = append(rest, hash)
+		}
+	}
+	if len(rest) < 2 {
+		if err := db.Delete(c, key); err != nil {
+			return fmt.Errorf("failed to delete issue: %v", err)
+		}
+		return nil
+	}
+	issue.Bugs = rest
+	if issue.Root == bug.keyHash() {
+		issue.Root = rest[0]
+	}
+	issue.Manual = true
+	issue.Updated = timeNow(c)
+	if _, err := db.Put(c, key, issue); err != nil {
+		return fmt.Errorf("failed to save issue: %v", err)
+	}
+	return nil
+}
+
+// issueThreadExtID returns ExtID of the reporting thread that the bug report should go to.
+func issueThreadExtID(c context.Context, bug *Bug, reporting string) (string, error) {
+	issue, _, err := loadIssueForBug(c, bug.keyHash())
+	if err != nil || issue == nil || issue.Root == bug.keyHash() {
+		return "", err
+	}
+	root := new(Bug)
+	if err := db.Get(c, db.NewKey(c, "Bug", issue.Root, 0, nil), root); err != nil {
+		return "", fmt.Errorf("failed to get issue root bug: %v", err)
+	}
+	for _, bugReporting := range root.Reporting {
+		if bugReporting.Name == reporting {
+			return bugReporting.ExtID, nil
+		}
+	}
+	return "", nil
+}
+
+type uiIssue struct {
+	BugID string
+	Bugs  *uiBugGroup
+}
+
+func loadIssueUI(c context.Context, r *http.Request, bug *Bug, state *ReportingState,
+	managers []string) (*uiIssue, error) {
+	res := &uiIssue{BugID: bug.keyHash()}
+	issue, _, err := loadIssueForBug(c, bug.keyHash())
+	if err != nil || issue == nil {
+		return res, err
+	}
+	var keys []*db.Key
+	for _, hash := range issue.Bugs {
+		if hash != bug.keyHash() {
+			keys = append(keys, db.NewKey(c, "Bug", hash, 0, nil))
+		}
+	}
+	bugs := make([]*Bug, len(keys))
+	if err := db.GetMulti(c, keys, bugs); err != nil {
+		return nil, fmt.Errorf("failed to get issue bugs: %v", err)
+	}
+	accessLevel := accessLevel(c, r)
+	var results []*uiBug
+	for _, other := range bugs {
+		if accessLevel < other.sanitizeAccess(accessLevel) {
+			continue
+		}
+		results = append(results, createUIBug(c, other, state, managers))
+	}
+	caption := "bugs in the same issue"
+	if issue.Manual {
+		caption += " (edited manually)"
+	}
+	res.Bugs = &uiBugGroup{
+		Now:        timeNow(c),
+		Caption:    caption,
+		ShowStatus: true,
+		Bugs:       results,
+	}
+	return res, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClusterIssues(t *testing.T) {
+	stack := func(file string, funcs ...string) *issueFeatures {
+		feat := &issueFeatures{frames: make(map[string]bool)}
+		frames := []*uiSourceFrame{{Func: "dump_stack", File: "lib/dump_stack.c"}}
+		for _, fn := range funcs {
+			frames = append(frames, &uiSourceFrame{Func: fn, File: file})
+		}
+		fillIssueFrames(feat, frames)
+		return feat
+	}
+	bisected := func(feat *issueFeatures, commit string) *issueFeatures {
+		feat.bisectCommit = commit
+		return feat
+	}
+	features := []*issueFeatures{
+		0: stack("fs/foo.c", "foo_read", "vfs_read", "ksys_read"),
+		1: stack("net/bar.c", "bar_sendmsg", "sock_sendmsg"),
+		2: stack("fs/foo.c", "foo_read", "vfs_read", "ksys_read", "do_syscall_64"),
+		3: bisected(stack("mm/baz.c", "baz_alloc"), "1234"),
+		4: bisected(stack("net/qux.c", "qux_recv", "sock_sendmsg", "baz_alloc"), "1234"),
+		5: stack("fs/other.c", "foo_read"),
+	}
+	if got := features[0].guiltyFile; got != "fs/foo.c" {
+		t.Fatalf("bad guilty file %q", got)
+	}
+	want := [][]int{{0, 2}, {3, 4}}
+	if diff := cmp.Diff(want, clusterIssues(features)); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestUpdateIssues(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	bugs := make(map[string]*Bug)
+	for i, title := range []string{"a", "b", "c", "d", "e"} {
+		bugs[title] = &Bug{Namespace: "test1", Title: title, FirstTime: now.Add(time.Duration(i) * time.Hour)}
+	}
+	hashes := func(titles ...string) []string {
+		var res []string
+		for _, title := range titles {
+			res = append(res, bugs[title].keyHash())
+		}
+		return res
+	}
+	cluster := func(titles ...string) []*Bug {
+		var res []*Bug
+		for _, title := range titles {
+			res = append(res, bugs[title])
+		}
+		return res
+	}
+	issues := []*Issue{
+		{Root: bugs["b"].keyHash(), Bugs: hashes("b", "c")},
+		{Root: bugs["d"].keyHash(), Bugs: hashes("d", "e")},
+	}
+	// The first cluster continues the second issue (it contains its root), the second one
+	// continues the first issue, the third one is new.
+	clusters := [][]*Bug{cluster("a", "d", "e"), cluster("b", "c"), cluster("c", "e")}
+	if diff := cmp.Diff([]int{1, 0, -1}, matchIssues(issues, clusters)); diff != "" {
+		t.Fatal(diff)
+	}
+
+	open := map[string]bool{}
+	for _, bug := range bugs {
+		open[bug.keyHash()] = true
+	}
+	// The root stays the same even though an earlier bug joined the issue.
+	if !updateIssue(issues[1], cluster("a", "d", "e"), open, now) {
+		t.Fatalf("the changed issue is not updated")
+	}
+	if issues[1].Root != bugs["d"].keyHash() || !stringSetsEqual(issues[1].Bugs, hashes("a", "d", "e")) {
+		t.Fatalf("bad updated issue: %+v", issues[1])
+	}
+	if updateIssue(issues[0], cluster("c", "b"), open, now) {
+		t.Fatalf("the unchanged issue is updated")
+	}
+	// A closed root stays in the issue, an open root that left the issue is replaced by the earliest bug.
+	delete(open, bugs["b"].keyHash())
+	updateIssue(issues[0], cluster("c", "e"), open, now)
+	if issues[0].Root != bugs["b"].keyHash() || !stringSetsEqual(issues[0].Bugs, hashes("b", "c", "e")) {
+		t.Fatalf("bad issue with closed root: %+v", issues[0])
+	}
+	updateIssue(issues[1], cluster("a", "e"), open, now)
+	if issues[1].Root != bugs["a"].keyHash() || !stringSetsEqual(issues[1].Bugs, hashes("a", "e")) {
+		t.Fatalf("bad issue with moved root: %+v", issues[1])
+	}
+}
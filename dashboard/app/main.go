@@ -26,6 +26,7 @@ import (
 	db "google.golang.org/appengine/v2/datastore"
 	"google.golang.org/appengine/v2/log"
 	"google.golang.org/appengine/v2/memcache"
+	"google.golang.org/appengine/v2/user"
 	proto "google.golang.org/genproto/googleapis/appengine/logging/v1"
 	ltype "google.golang.org/genproto/googleapis/logging/type"
 )
@@ -37,6 +38,7 @@ func initHTTPHandlers() {
 	http.Handle("/bug", handlerWrapper(handleBug))
 	http.Handle("/text", handlerWrapper(handleText))
 	http.Handle("/admin", handlerWrapper(handleAdmin))
+	http.Handle("/issue", handlerWrapper(handleIssue))
 	http.Handle("/x/.config", handlerWrapper(handleTextX(textKernelConfig)))
 	http.Handle("/x/log.txt", handlerWrapper(handleTextX(textCrashLog)))
 	http.Handle("/x/report.txt", handlerWrapper(handleTextX(textCrashReport)))
@@ -46,6 +48,7 @@ func initHTTPHandlers() {
 	http.Handle("/x/bisect.txt", handlerWrapper(handleTextX(textLog)))
 	http.Handle("/x/error.txt", handlerWrapper(handleTextX(textError)))
 	http.Handle("/x/minfo.txt", handlerWrapper(handleTextX(textMachineInfo)))
+	http.Handle("/x/source.html", handlerWrapper(handleSource))
 	for ns := range config.Namespaces {
 		http.Handle("/"+ns, handlerWrapper(handleMain))
 		http.Handle("/"+ns+"/fixed", handlerWrapper(handleFixed))
@@ -56,6 +59,7 @@ func initHTTPHandlers() {
 		http.Handle("/"+ns+"/graph/crashes", handlerWrapper(handleGraphCrashes))
 	}
 	http.HandleFunc("/cache_update", cacheUpdate)
+	http.HandleFunc("/issue_cluster", handleIssueCluster)
 }
 
 type uiMainPage struct {
@@ -98,6 +102,7 @@ type uiManager struct {
 	TotalCrashes          int64
 	TotalExecs            int64
 	TotalExecsBad         bool // highlight TotalExecs in red
+	CoverRegression       string
 }
 
 type uiBuild struct {
@@ -131,6 +136,9 @@ type uiBugPage struct {
 	DupOf         *uiBugGroup
 	Dups          *uiBugGroup
 	Similar       *uiBugGroup
+	Issue         *uiIssue
+	Triage        []*TriageAction
+	CommandToken  string
 	SampleReport  []byte
 	Crashes       *uiCrashTable
 	FixBisections *uiCrashTable
@@ -178,6 +186,8 @@ type uiBug struct {
 	MissingOn      []string
 	NumManagers    int
 	LastActivity   time.Time
+	Labels         []string
+	Subsystems     []string
 }
 
 type uiCrash struct {
@@ -187,6 +197,7 @@ type uiCrash struct {
 	Maintainers     string
 	LogLink         string
 	ReportLink      string
+	SourceLink      string
 	ReproSyzLink    string
 	ReproCLink      string
 	MachineInfoLink string
@@ -396,6 +407,22 @@ func handleBug(c context.Context, w http.ResponseWriter, r *http.Request) error
 	if err != nil {
 		return err
 	}
+	issue, err := loadIssueUI(c, r, bug, state, managers)
+	if err != nil {
+		return err
+	}
+	var triage []*TriageAction
+	if accessLevel >= AccessUser {
+		if triage, err = loadTriageActions(c, bug); err != nil {
+			return err
+		}
+	}
+	commandToken := ""
+	if cmdCfg := config.Namespaces[bug.Namespace].EmailCommands; cmdCfg != nil && cmdCfg.TokenSecret != "" {
+		if u := user.Current(c); u != nil {
+			commandToken = email.CommandToken(cmdCfg.TokenSecret, bug.keyHash(), u.Email)
+		}
+	}
 	var bisectCause *uiJob
 	if bug.BisectCause > BisectPending {
 		bisectCause, err = getUIJob(c, bug, JobBisectCause)
@@ -423,6 +450,9 @@ func handleBug(c context.Context, w http.ResponseWriter, r *http.Request) error
 		DupOf:        dupOf,
 		Dups:         dups,
 		Similar:      similar,
+		Issue:        issue,
+		Triage:       triage,
+		CommandToken: commandToken,
 		SampleReport: sampleReport,
 		Crashes:      crashesTable,
 		TestPatchJobs: &uiJobList{
@@ -886,6 +916,8 @@ func createUIBug(c context.Context, bug *Bug, state *ReportingState, managers []
 		CreditEmail:    creditEmail,
 		NumManagers:    len(managers),
 		LastActivity:   bug.LastActivity,
+		Labels:         bug.Labels,
+		Subsystems:     bug.Subsystems,
 	}
 	updateBugBadness(c, uiBug)
 	if len(bug.Commits) != 0 {
@@ -1004,6 +1036,7 @@ func makeUICrash(crash *Crash, build *Build) *uiCrash {
 		Maintainers:     strings.Join(crash.Maintainers, ", "),
 		LogLink:         textLink(textCrashLog, crash.Log),
 		ReportLink:      textLink(textCrashReport, crash.Report),
+		SourceLink:      sourceLink(crash.Report),
 		ReproSyzLink:    textLink(textReproSyz, crash.ReproSyz),
 		ReproCLink:      textLink(textReproC, crash.ReproC),
 		MachineInfoLink: textLink(textMachineInfo, crash.MachineInfo),
@@ -1093,6 +1126,7 @@ func loadManagers(c context.Context, accessLevel AccessLevel, ns, manager string
 			TotalCrashes:          stats.TotalCrashes,
 			TotalExecs:            stats.TotalExecs,
 			TotalExecsBad:         stats.TotalExecs == 0,
+			CoverRegression:       mgr.CoverRegression,
 		}
 		if config.Namespaces[mgr.Namespace].Decommissioned {
 			// Don't show bold red highlight for decommissioned namespaces.
@@ -1102,6 +1136,7 @@ func loadManagers(c context.Context, accessLevel AccessLevel, ns, manager string
 			ui.CurrentUpTime = 0
 			ui.LastActiveBad = false
 			ui.TotalExecsBad = false
+			ui.CoverRegression = ""
 		}
 		results = append(results, ui)
 	}
@@ -89,6 +89,10 @@ func handleReportBug(c context.Context, typ string, state *ReportingState, bug *
 func needReport(c context.Context, typ string, state *ReportingState, bug *Bug) (
 	reporting *Reporting, bugReporting *BugReporting, crash *Crash,
 	crashKey *db.Key, reportingIdx int, status, link string, err error) {
+	if strings.HasPrefix(bug.Title, dashapi.VerifierMismatchPrefix) {
+		status = "verifier mismatches are not reported"
+		return
+	}
 	reporting, bugReporting, reportingIdx, status, err = currentReporting(c, bug)
 	if err != nil || reporting == nil {
 		return
@@ -418,11 +422,18 @@ func createBugReport(c context.Context, bug *Bug, crash *Crash, crashKey *db.Key
 		typ = dashapi.ReportRepro
 	}
 
+	issueExtID := ""
+	if bugReporting.ExtID == "" {
+		if issueExtID, err = issueThreadExtID(c, bug, bugReporting.Name); err != nil {
+			return nil, err
+		}
+	}
 	kernelRepo := kernelRepoInfo(build)
 	rep := &dashapi.BugReport{
 		Type:            typ,
 		Config:          reportingConfig,
 		ExtID:           bugReporting.ExtID,
+		IssueExtID:      issueExtID,
 		First:           bugReporting.Reported.IsZero(),
 		Moderation:      reporting.moderation,
 		Log:             crashLog,
@@ -255,8 +255,13 @@ func emailReport(c context.Context, rep *dashapi.BugReport) error {
 	if err := mailTemplates.ExecuteTemplate(body, templ, rep); err != nil {
 		return fmt.Errorf("failed to execute %v template: %v", templ, err)
 	}
+	replyTo := rep.ExtID
+	if replyTo == "" {
+		// Keep reports of bugs from the same issue in a single thread.
+		replyTo = rep.IssueExtID
+	}
 	log.Infof(c, "sending email %q to %q", rep.Title, to)
-	return sendMailText(c, cfg, rep.Title, from, to, rep.ExtID, body.String())
+	return sendMailText(c, cfg, rep.Title, from, to, replyTo, body.String())
 }
 
 // handleIncomingMail is the entry point for incoming emails.
@@ -297,6 +302,14 @@ func incomingMail(c context.Context, r *http.Request) error {
 	fromMailingList := email.CanonicalEmail(msg.From) == mailingList
 	mailingListInCC := checkMailingListInCC(c, msg, mailingList)
 	log.Infof(c, "from/cc mailing list: %v/%v", fromMailingList, mailingListInCC)
+	if reason := checkEmailCommand(config.Namespaces[bug.Namespace].EmailCommands,
+		msg, bug.keyHash()); reason != "" {
+		log.Warningf(c, "rejected email command %q from %q: %v", msg.CommandStr, msg.From, reason)
+		if fromMailingList {
+			return nil
+		}
+		return replyTo(c, msg, reason)
+	}
 	if msg.Command == email.CmdTest {
 		return handleTestCommand(c, msg)
 	}
@@ -348,6 +361,45 @@ func incomingMail(c context.Context, r *http.Request) error {
 	return nil
 }
 
+// checkEmailCommand checks the command against the namespace policy
+// and returns the reason for rejection, or an empty string if the command is allowed.
+func checkEmailCommand(cfg *EmailCommandsConfig, msg *email.Email, bugHash string) string {
+	if cfg == nil || msg.Command == email.CmdNone {
+		return ""
+	}
+	policy, ok := cfg.Commands[msg.Command]
+	if !ok {
+		return ""
+	}
+	if policy.RequireAuth && !msg.Authenticated && !email.CheckCommandToken(msg, cfg.TokenSecret, bugHash) {
+		reason := fmt.Sprintf("Can't authenticate the sender of #syz %v command.\n"+
+			"Please make sure your mail server signs outgoing emails with DKIM", msg.CommandStr)
+		if cfg.TokenSecret != "" {
+			reason += ", or add the command token shown on the bug page to the email"
+		}
+		return reason + "."
+	}
+	if len(policy.Senders) != 0 && !emailSenderAllowed(policy.Senders, msg.From) {
+		return fmt.Sprintf("%v is not allowed to use #syz %v command.",
+			email.CanonicalEmail(msg.From), msg.CommandStr)
+	}
+	return ""
+}
+
+func emailSenderAllowed(senders []string, from string) bool {
+	from = email.CanonicalEmail(from)
+	for _, sender := range senders {
+		if strings.HasPrefix(sender, "@") {
+			if strings.HasSuffix(from, strings.ToLower(sender)) {
+				return true
+			}
+		} else if email.CanonicalEmail(sender) == from {
+			return true
+		}
+	}
+	return false
+}
+
 var emailCmdToStatus = map[email.Command]dashapi.BugStatus{
 	email.CmdNone:     dashapi.BugStatusUpdate,
 	email.CmdUpstream: dashapi.BugStatusUpstream,
@@ -481,6 +481,27 @@ func TestReportingFilter(t *testing.T) {
 	c.expectEQ(string(rep4.Config), `{"Index":2}`)
 }
 
+// Mismatches uploaded by syz-verifier must not be reported.
+func TestReportingVerifierMismatch(t *testing.T) {
+	c := NewCtx(t)
+	defer c.Close()
+
+	build := testBuild(1)
+	c.client.UploadBuild(build)
+
+	crash := testCrash(build, 1)
+	crash.Title = dashapi.VerifierMismatchPrefix + "test$res0: errno 2 | errno 5"
+	resp, _ := c.client.ReportCrash(crash)
+	c.expectEQ(resp.NeedRepro, false)
+	c.client.pollBugs(0)
+	c.advanceTime(24 * time.Hour)
+	c.client.pollBugs(0)
+
+	c.client.ReportCrash(testCrash(build, 2))
+	rep := c.client.pollBug()
+	c.expectEQ(rep.Title, "title2")
+}
+
 func TestMachineInfo(t *testing.T) {
 	c := NewCtx(t)
 	defer c.Close()
@@ -0,0 +1,267 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/syzkaller/pkg/hash"
+	"github.com/google/syzkaller/pkg/vcs"
+	"golang.org/x/net/context"
+	"google.golang.org/appengine/v2/log"
+	"google.golang.org/appengine/v2/memcache"
+)
+
+// This file contains the source view for crash reports:
+// each symbolized frame of the report is shown together with the surrounding
+// source lines fetched from the exact kernel commit the crash happened on.
+// The files are fetched in parallel and cached in memcache, files at a commit never change.
+
+const (
+	sourceContextLines = 5
+	// Limits number of distinct files fetched per request.
+	sourceMaxFiles = 30
+	// Deadline for fetching all files of a request.
+	sourceFetchTimeout    = 10 * time.Second
+	sourceCacheExpiration = 7 * 24 * time.Hour
+)
+
+type uiSourcePage struct {
+	Header    *uiHeader
+	Title     string
+	BugLink   string
+	Commit    string
+	Link      string
+	Main      *uiSourceStack
+	Allocated *uiSourceStack
+	Freed     *uiSourceStack
+}
+
+type uiSourceStack struct {
+	Caption string
+	Frames  []*uiSourceFrame
+}
+
+type uiSourceFrame struct {
+	Func  string
+	File  string
+	Line  int
+	Link  string
+	Lines []*uiSourceLine
+}
+
+type uiSourceLine struct {
+	Num     int
+	Text    string
+	Current bool
+}
+
+func handleSource(c context.Context, w http.ResponseWriter, r *http.Request) error {
+	xid, err := strconv.ParseUint(r.FormValue("x"), 16, 64)
+	if err != nil || xid == 0 {
+		return ErrDontLog{fmt.Errorf("failed to parse text id: %v", err)}
+	}
+	id := int64(xid)
+	bug, crash, err := checkCrashTextAccess(c, r, "Report", id)
+	if err != nil {
+		return err
+	}
+	report, ns, err := getText(c, textCrashReport, id)
+	if err != nil {
+		return err
+	}
+	if err := checkAccessLevel(c, r, config.Namespaces[ns].AccessLevel); err != nil {
+		return err
+	}
+	build, err := loadBuild(c, ns, crash.BuildID)
+	if err != nil {
+		return err
+	}
+	hdr, err := commonHeader(c, r, w, ns)
+	if err != nil {
+		return err
+	}
+	crashStack, allocated, freed := parseSourceStacks(report)
+	fillSources(c, build.KernelRepo, build.KernelCommit, crashStack, allocated, freed)
+	data := &uiSourcePage{
+		Header:    hdr,
+		Title:     crash.Title,
+		BugLink:   bugLink(bug.keyHash()),
+		Commit:    build.KernelCommit,
+		Link:      vcs.CommitLink(build.KernelRepo, build.KernelCommit),
+		Main:      crashStack,
+		Allocated: allocated,
+		Freed:     freed,
+	}
+	if data.Title == "" {
+		data.Title = bug.Title
+	}
+	return serveTemplate(w, "source.html", data)
+}
+
+func sourceLink(report int64) string {
+	if report == 0 {
+		return ""
+	}
+	return fmt.Sprintf("/x/source.html?x=%v", strconv.FormatUint(uint64(report), 16))
+}
+
+// Matches both inlined and non-inlined symbolized frames, e.g.:
+//
+//	__dump_stack lib/dump_stack.c:77 [inline]
+//	dump_stack+0x1b2/0x281 lib/dump_stack.c:113
+var sourceFrameRe = regexp.MustCompile(
+	`^\s*(?:\[[^\]]*\]\s*)?([a-zA-Z0-9_.$]+)(?:\+0x[0-9a-f]+/0x[0-9a-f]+)?\s+([a-zA-Z0-9_\-./]+\.[chS]):([0-9]+)`)
+
+// parseSourceStacks extracts frames from a crash report.
+// For KASAN use-after-free reports the allocation and free stacks are returned separately,
+// otherwise allocated and freed are nil.
+func parseSourceStacks(report []byte) (crash, allocated, freed *uiSourceStack) {
+	crash = &uiSourceStack{Caption: "Crash"}
+	cur := crash
+	for s := bufio.NewScanner(bytes.NewReader(report)); s.Scan(); {
+		line := s.Text()
+		switch {
+		case strings.HasPrefix(line, "Allocated by task"):
+			allocated = &uiSourceStack{Caption: strings.TrimSuffix(line, ":")}
+			cur = allocated
+			continue
+		case strings.HasPrefix(line, "Freed by task"):
+			freed = &uiSourceStack{Caption: strings.TrimSuffix(line, ":")}
+			cur = freed
+			continue
+		case strings.HasPrefix(line, "The buggy address belongs to"):
+			cur = nil
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		match := sourceFrameRe.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		num, err := strconv.Atoi(match[3])
+		if err != nil {
+			continue
+		}
+		cur.Frames = append(cur.Frames, &uiSourceFrame{
+			Func: match[1],
+			File: match[2],
+			Line: num,
+		})
+	}
+	return
+}
+
+// fillSources fetches the files referenced by the frames of the stacks (nil stacks are skipped)
+// and fills in the source lines of the frames.
+func fillSources(c context.Context, repo, commit string, stacks ...*uiSourceStack) {
+	var files []string
+	lines := make(map[string][]string)
+	for _, stack := range stacks {
+		if stack == nil {
+			continue
+		}
+		for _, frame := range stack.Frames {
+			frame.Link = vcs.RawFileLink(repo, commit, frame.File)
+			if _, ok := lines[frame.File]; ok || frame.Link == "" || len(files) >= sourceMaxFiles {
+				continue
+			}
+			lines[frame.File] = nil
+			files = append(files, frame.File)
+		}
+	}
+	ctx, cancel := context.WithTimeout(c, sourceFetchTimeout)
+	defer cancel()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, file := range files {
+		file := file
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := sourceFile(ctx, vcs.RawFileLink(repo, commit, file))
+			mu.Lock()
+			lines[file] = res
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	for _, stack := range stacks {
+		if stack == nil {
+			continue
+		}
+		for _, frame := range stack.Frames {
+			frame.Lines = sourceContext(lines[frame.File], frame.Line, sourceContextLines)
+		}
+	}
+}
+
+// sourceFile returns the lines of the file at the link (which contains the commit) from memcache
+// or fetches and caches it. Returns nil if the file can't be fetched.
+func sourceFile(c context.Context, link string) []string {
+	key := "source-" + hash.String([]byte(link))
+	if item, err := memcache.Get(c, key); err == nil {
+		return strings.Split(string(item.Value), "\n")
+	} else if err != memcache.ErrCacheMiss {
+		log.Warningf(c, "failed to get %v from memcache: %v", link, err)
+	}
+	data, err := fetchSourceFile(c, link)
+	if err != nil {
+		log.Warningf(c, "failed to fetch %v: %v", link, err)
+		return nil
+	}
+	item := &memcache.Item{
+		Key:        key,
+		Value:      data,
+		Expiration: sourceCacheExpiration,
+	}
+	// Files larger than the memcache item limit are not cached.
+	if err := memcache.Set(c, item); err != nil {
+		log.Warningf(c, "failed to cache %v: %v", link, err)
+	}
+	return strings.Split(string(data), "\n")
+}
+
+func fetchSourceFile(c context.Context, link string) ([]byte, error) {
+	req, err := http.NewRequest("GET", link, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(c))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %v", resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// sourceContext returns lines [line-context, line+context] (1-based) of the file.
+func sourceContext(lines []string, line, context int) []*uiSourceLine {
+	var res []*uiSourceLine
+	for i := line - context; i <= line+context; i++ {
+		if i < 1 || i > len(lines) {
+			continue
+		}
+		res = append(res, &uiSourceLine{
+			Num:     i,
+			Text:    lines[i-1],
+			Current: i == line,
+		})
+	}
+	return res
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseSourceStacks(t *testing.T) {
+	report := []byte(`BUG: KASAN: use-after-free in foo+0x1d/0x30 fs/foo.c:10
+Read of size 8 at addr ffff88801b3c4e20 by task syz-executor/1234
+
+Call Trace:
+ __dump_stack lib/dump_stack.c:77 [inline]
+ dump_stack+0x1b2/0x281 lib/dump_stack.c:113
+ foo+0x1d/0x30 fs/foo.c:10
+
+Allocated by task 1233:
+ kmalloc include/linux/slab.h:552 [inline]
+ bar+0x10/0x20 fs/bar.c:20
+
+Freed by task 1232:
+ kfree+0x10/0x20 mm/slab.c:3756
+ baz fs/baz.c:30 [inline]
+
+The buggy address belongs to the object at ffff88801b3c4e00
+ which belongs to the cache kmalloc-64 of size 64
+`)
+	crash, allocated, freed := parseSourceStacks(report)
+	want := []*uiSourceStack{
+		{
+			Caption: "Crash",
+			Frames: []*uiSourceFrame{
+				{Func: "__dump_stack", File: "lib/dump_stack.c", Line: 77},
+				{Func: "dump_stack", File: "lib/dump_stack.c", Line: 113},
+				{Func: "foo", File: "fs/foo.c", Line: 10},
+			},
+		},
+		{
+			Caption: "Allocated by task 1233",
+			Frames: []*uiSourceFrame{
+				{Func: "kmalloc", File: "include/linux/slab.h", Line: 552},
+				{Func: "bar", File: "fs/bar.c", Line: 20},
+			},
+		},
+		{
+			Caption: "Freed by task 1232",
+			Frames: []*uiSourceFrame{
+				{Func: "kfree", File: "mm/slab.c", Line: 3756},
+				{Func: "baz", File: "fs/baz.c", Line: 30},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, []*uiSourceStack{crash, allocated, freed}); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestSourceContext(t *testing.T) {
+	lines := []string{"a", "b", "c", "d"}
+	want := []*uiSourceLine{
+		{Num: 2, Text: "b"},
+		{Num: 3, Text: "c"},
+		{Num: 4, Text: "d", Current: true},
+	}
+	if diff := cmp.Diff(want, sourceContext(lines, 4, 2)); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestSourceFileCache(t *testing.T) {
+	c := NewCtx(t)
+	defer c.Close()
+
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/missing.c" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "a\nb")
+	}))
+	defer srv.Close()
+
+	for i := 0; i < 2; i++ {
+		if diff := cmp.Diff([]string{"a", "b"}, sourceFile(c.ctx, srv.URL+"/foo.c")); diff != "" {
+			t.Fatal(diff)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("the cached file was fetched %v times", requests)
+	}
+	// Failures are not cached.
+	for i := 0; i < 2; i++ {
+		if lines := sourceFile(c.ctx, srv.URL+"/missing.c"); lines != nil {
+			t.Fatalf("got lines of a missing file: %q", lines)
+		}
+	}
+	if requests != 3 {
+		t.Fatalf("got %v requests, want 3", requests)
+	}
+}
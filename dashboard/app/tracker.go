@@ -0,0 +1,358 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/google/syzkaller/dashboard/dashapi"
+	"golang.org/x/net/context"
+	"google.golang.org/appengine/v2"
+	db "google.golang.org/appengine/v2/datastore"
+	"google.golang.org/appengine/v2/log"
+	"google.golang.org/appengine/v2/urlfetch"
+)
+
+// This file contains export of bugs into external issue trackers (see Config.Trackers).
+// Issues are created for reported open bugs. Afterwards status and fix commits are synchronized
+// in both directions: changes made in the tracker since the last synchronization are applied
+// to the bug first, then the resulting bug state is pushed to the tracker.
+
+// IssueTracker is an external issue tracker (e.g. Bugzilla, Jira, GitHub Issues).
+type IssueTracker interface {
+	// Name returns a unique name of this tracker within the namespace (e.g. "bugzilla").
+	// Exported issues are associated with the name, so it must not change over time.
+	Name() string
+	// Validate validates the current object, this is called only during init.
+	Validate() error
+	// CreateIssue creates a new issue and returns its ID in the tracker.
+	CreateIssue(c context.Context, issue *TrackerIssue) (string, error)
+	// UpdateIssue updates status and fix commits of an existing issue.
+	UpdateIssue(c context.Context, id string, issue *TrackerIssue) error
+	// GetIssue returns the current status and fix commits of an existing issue.
+	GetIssue(c context.Context, id string) (*TrackerIssue, error)
+}
+
+type TrackerStatus int
+
+const (
+	TrackerStatusOpen TrackerStatus = iota
+	TrackerStatusFixed
+	TrackerStatusInvalid
+)
+
+func (status TrackerStatus) String() string {
+	switch status {
+	case TrackerStatusOpen:
+		return "open"
+	case TrackerStatusFixed:
+		return "fixed"
+	case TrackerStatusInvalid:
+		return "invalid"
+	default:
+		return fmt.Sprintf("status(%d)", int(status))
+	}
+}
+
+// TrackerIssue is a tracker-independent representation of an exported bug.
+type TrackerIssue struct {
+	Title      string // set only for CreateIssue
+	Body       string // set only for CreateIssue
+	Link       string // link to the bug on the dashboard, set only for CreateIssue
+	Status     TrackerStatus
+	FixCommits []string // sorted titles of fixing commits
+}
+
+// Max number of issues created in a single tracker during a single poll.
+const trackerMaxNewIssues = 30
+
+func initTrackers() {
+	http.HandleFunc("/tracker_poll", handleTrackerPoll)
+}
+
+// trackerHTTPClient is a var so that tests can intercept tracker requests.
+var trackerHTTPClient = func(c context.Context) *http.Client {
+	return urlfetch.Client(c)
+}
+
+func handleTrackerPoll(w http.ResponseWriter, r *http.Request) {
+	c := appengine.NewContext(r)
+	for ns, cfg := range config.Namespaces {
+		if cfg.Decommissioned {
+			continue
+		}
+		for _, tracker := range cfg.Trackers {
+			if err := syncTrackedIssues(c, ns, tracker); err != nil {
+				log.Errorf(c, "tracker: %v/%v: sync failed: %v", ns, tracker.Name(), err)
+			}
+			if err := exportNewIssues(c, ns, tracker); err != nil {
+				log.Errorf(c, "tracker: %v/%v: export failed: %v", ns, tracker.Name(), err)
+			}
+		}
+	}
+}
+
+func exportNewIssues(c context.Context, ns string, tracker IssueTracker) error {
+	filter := func(query *db.Query) *db.Query {
+		return query.Filter("Namespace=", ns).
+			Filter("Status=", BugStatusOpen)
+	}
+	created := 0
+	return foreachBug(c, filter, func(bug *Bug, bugKey *db.Key) error {
+		if created >= trackerMaxNewIssues || !shouldExportBug(bug) {
+			return nil
+		}
+		ok, err := exportBug(c, tracker, bug, bugKey)
+		if err != nil {
+			return err
+		}
+		if ok {
+			created++
+		}
+		return nil
+	})
+}
+
+func shouldExportBug(bug *Bug) bool {
+	return bug.sanitizeAccess(AccessPublic) == AccessPublic &&
+		lastReportedReporting(bug) != nil
+}
+
+func exportBug(c context.Context, tracker IssueTracker, bug *Bug, bugKey *db.Key) (bool, error) {
+	issueKey := trackedIssueKey(c, tracker, bugKey)
+	if err := db.Get(c, issueKey, new(TrackedIssue)); err == nil {
+		return false, nil
+	} else if err != db.ErrNoSuchEntity {
+		return false, fmt.Errorf("failed to get tracked issue: %v", err)
+	}
+	rep, err := loadBugReport(c, bug)
+	if err != nil {
+		return false, err
+	}
+	issue := bugTrackerIssue(bug)
+	issue.Title = bug.displayTitle()
+	issue.Link = rep.Link
+	issue.Body = fmt.Sprintf("%v\n\nDashboard link: %v\n\n%s", issue.Title, rep.Link, rep.Report)
+	id, err := tracker.CreateIssue(c, issue)
+	if err != nil {
+		return false, fmt.Errorf("failed to create issue for %q: %v", bug.displayTitle(), err)
+	}
+	now := timeNow(c)
+	tracked := &TrackedIssue{
+		Namespace:  bug.Namespace,
+		Tracker:    tracker.Name(),
+		IssueID:    id,
+		Created:    now,
+		Synced:     now,
+		Status:     issue.Status,
+		FixCommits: issue.FixCommits,
+	}
+	if _, err := db.Put(c, issueKey, tracked); err != nil {
+		return false, fmt.Errorf("failed to save tracked issue: %v", err)
+	}
+	log.Infof(c, "tracker: exported %v:%v '%v' as %v/%v",
+		bug.Namespace, bugKey.StringID(), bug.displayTitle(), tracker.Name(), id)
+	return true, nil
+}
+
+func syncTrackedIssues(c context.Context, ns string, tracker IssueTracker) error {
+	var issues []*TrackedIssue
+	keys, err := db.NewQuery("TrackedIssue").
+		Filter("Namespace=", ns).
+		Filter("Tracker=", tracker.Name()).
+		GetAll(c, &issues)
+	if err != nil {
+		return fmt.Errorf("failed to query tracked issues: %v", err)
+	}
+	for i, tracked := range issues {
+		if err := syncTrackedIssue(c, tracker, tracked, keys[i]); err != nil {
+			return fmt.Errorf("issue %v: %v", tracked.IssueID, err)
+		}
+	}
+	return nil
+}
+
+func syncTrackedIssue(c context.Context, tracker IssueTracker, tracked *TrackedIssue, key *db.Key) error {
+	bugKey := key.Parent()
+	bug := new(Bug)
+	if err := db.Get(c, bugKey, bug); err != nil {
+		return fmt.Errorf("failed to get bug: %v", err)
+	}
+	remote, err := tracker.GetIssue(c, tracked.IssueID)
+	if err != nil {
+		return err
+	}
+	sort.Strings(remote.FixCommits)
+	if remote.Status != tracked.Status || !sameCommits(remote.FixCommits, tracked.FixCommits) {
+		if err := applyTrackerIssue(c, bug, remote); err != nil {
+			log.Errorf(c, "tracker: failed to apply %v/%v to '%v': %v",
+				tracker.Name(), tracked.IssueID, bug.displayTitle(), err)
+		}
+		if err := db.Get(c, bugKey, bug); err != nil {
+			return fmt.Errorf("failed to get bug: %v", err)
+		}
+	}
+	local := bugTrackerIssue(bug)
+	if local.Status != remote.Status || !sameCommits(local.FixCommits, remote.FixCommits) {
+		if err := tracker.UpdateIssue(c, tracked.IssueID, local); err != nil {
+			return err
+		}
+	}
+	if local.Status == tracked.Status && sameCommits(local.FixCommits, tracked.FixCommits) {
+		return nil
+	}
+	tracked.Status = local.Status
+	tracked.FixCommits = local.FixCommits
+	tracked.Synced = timeNow(c)
+	if _, err := db.Put(c, key, tracked); err != nil {
+		return fmt.Errorf("failed to save tracked issue: %v", err)
+	}
+	return nil
+}
+
+// applyTrackerIssue applies status and fix commits of the issue to the bug.
+// Only open bugs are updated, closed bugs are reopened in the tracker on the next sync.
+func applyTrackerIssue(c context.Context, bug *Bug, issue *TrackerIssue) error {
+	if bug.Status != BugStatusOpen {
+		return nil
+	}
+	reporting := lastReportedReporting(bug)
+	if reporting == nil {
+		return fmt.Errorf("the bug is not reported")
+	}
+	cmd := &dashapi.BugUpdate{
+		ID:     reporting.ID,
+		Status: dashapi.BugStatusUpdate,
+	}
+	switch issue.Status {
+	case TrackerStatusOpen, TrackerStatusFixed:
+		if sameCommits(issue.FixCommits, bug.Commits) {
+			return nil
+		}
+		cmd.FixCommits = issue.FixCommits
+		cmd.ResetFixCommits = len(issue.FixCommits) == 0
+	case TrackerStatusInvalid:
+		cmd.Status = dashapi.BugStatusInvalid
+	}
+	ok, reason, err := incomingCommand(c, cmd)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%v", reason)
+	}
+	return nil
+}
+
+// bugTrackerIssue returns the tracker state corresponding to the bug.
+func bugTrackerIssue(bug *Bug) *TrackerIssue {
+	issue := &TrackerIssue{
+		FixCommits: append([]string{}, bug.Commits...),
+	}
+	sort.Strings(issue.FixCommits)
+	switch bug.Status {
+	case BugStatusOpen:
+		issue.Status = TrackerStatusOpen
+	case BugStatusFixed:
+		issue.Status = TrackerStatusFixed
+	default:
+		issue.Status = TrackerStatusInvalid
+	}
+	return issue
+}
+
+// parseFixCommits parses fix commits stored in a text field of a tracker, one commit title per line.
+func parseFixCommits(text string) []string {
+	var commits []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			commits = append(commits, line)
+		}
+	}
+	sort.Strings(commits)
+	return commits
+}
+
+func formatFixCommits(commits []string) string {
+	return strings.Join(commits, "\n")
+}
+
+func valueOr(val, def string) string {
+	if val != "" {
+		return val
+	}
+	return def
+}
+
+func sameCommits(a, b []string) bool {
+	return len(a) == 0 && len(b) == 0 || reflect.DeepEqual(a, b)
+}
+
+func trackedIssueKey(c context.Context, tracker IssueTracker, bugKey *db.Key) *db.Key {
+	return db.NewKey(c, "TrackedIssue", tracker.Name(), 0, bugKey)
+}
+
+func checkTrackers(ns string, trackers []IssueTracker) {
+	names := make(map[string]bool)
+	for _, tracker := range trackers {
+		name := tracker.Name()
+		if name == "" || names[name] {
+			panic(fmt.Sprintf("%v: empty or duplicate tracker name %q", ns, name))
+		}
+		names[name] = true
+		if err := tracker.Validate(); err != nil {
+			panic(fmt.Sprintf("%v: tracker %v: %v", ns, name, err))
+		}
+	}
+}
+
+// trackerRequest sends a JSON request to a tracker REST API and decodes the JSON reply into result.
+// body and result can be nil.
+func trackerRequest(c context.Context, method, url string, header map[string]string,
+	body, result interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := trackerHTTPClient(c).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	reply, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%v %v: %v\n%s", method, url, resp.Status, reply)
+	}
+	if result == nil || len(reply) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(reply, result); err != nil {
+		return fmt.Errorf("%v %v: failed to parse reply: %v", method, url, err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// BugzillaTracker exports bugs to Bugzilla using the REST API (Bugzilla 5.0+).
+type BugzillaTracker struct {
+	// URL of the Bugzilla instance, e.g. "https://bugzilla.kernel.org".
+	URL       string
+	APIKey    string
+	Product   string
+	Component string
+	Version   string
+	// FixCommitsField is a free text custom field used to store fix commit titles (one per line),
+	// e.g. "cf_fix_commits".
+	FixCommitsField string
+	// Statuses/resolutions used to update bugs. Default to "CONFIRMED", "RESOLVED",
+	// "FIXED" and "INVALID" respectively.
+	OpenStatus        string
+	ClosedStatus      string
+	FixedResolution   string
+	InvalidResolution string
+}
+
+func (bz *BugzillaTracker) Name() string {
+	return "bugzilla"
+}
+
+func (bz *BugzillaTracker) Validate() error {
+	if _, err := url.ParseRequestURI(bz.URL); err != nil {
+		return fmt.Errorf("bad URL %q: %v", bz.URL, err)
+	}
+	if bz.APIKey == "" || bz.Product == "" || bz.Component == "" {
+		return fmt.Errorf("APIKey, Product and Component must be set")
+	}
+	if !strings.HasPrefix(bz.FixCommitsField, "cf_") {
+		return fmt.Errorf("bad fix commits field %q, must be a custom field", bz.FixCommitsField)
+	}
+	return nil
+}
+
+func (bz *BugzillaTracker) CreateIssue(c context.Context, issue *TrackerIssue) (string, error) {
+	req := map[string]interface{}{
+		"product":          bz.Product,
+		"component":        bz.Component,
+		"version":          valueOr(bz.Version, "unspecified"),
+		"summary":          issue.Title,
+		"description":      issue.Body,
+		"url":              issue.Link,
+		bz.FixCommitsField: formatFixCommits(issue.FixCommits),
+	}
+	reply := new(struct {
+		ID int64 `json:"id"`
+	})
+	if err := bz.request(c, "POST", "/rest/bug", req, reply); err != nil {
+		return "", err
+	}
+	if reply.ID == 0 {
+		return "", fmt.Errorf("no bug id in reply")
+	}
+	return fmt.Sprint(reply.ID), nil
+}
+
+func (bz *BugzillaTracker) UpdateIssue(c context.Context, id string, issue *TrackerIssue) error {
+	req := map[string]interface{}{
+		bz.FixCommitsField: formatFixCommits(issue.FixCommits),
+	}
+	switch issue.Status {
+	case TrackerStatusOpen:
+		req["status"] = valueOr(bz.OpenStatus, "CONFIRMED")
+	case TrackerStatusFixed:
+		req["status"] = valueOr(bz.ClosedStatus, "RESOLVED")
+		req["resolution"] = valueOr(bz.FixedResolution, "FIXED")
+	case TrackerStatusInvalid:
+		req["status"] = valueOr(bz.ClosedStatus, "RESOLVED")
+		req["resolution"] = valueOr(bz.InvalidResolution, "INVALID")
+	}
+	return bz.request(c, "PUT", "/rest/bug/"+url.PathEscape(id), req, nil)
+}
+
+func (bz *BugzillaTracker) GetIssue(c context.Context, id string) (*TrackerIssue, error) {
+	reply := new(struct {
+		Bugs []map[string]interface{} `json:"bugs"`
+	})
+	path := fmt.Sprintf("/rest/bug/%v?include_fields=is_open,resolution,%v",
+		url.PathEscape(id), bz.FixCommitsField)
+	if err := bz.request(c, "GET", path, nil, reply); err != nil {
+		return nil, err
+	}
+	if len(reply.Bugs) != 1 {
+		return nil, fmt.Errorf("bug %v: got %v bugs", id, len(reply.Bugs))
+	}
+	bug := reply.Bugs[0]
+	issue := &TrackerIssue{}
+	fixes, _ := bug[bz.FixCommitsField].(string)
+	issue.FixCommits = parseFixCommits(fixes)
+	isOpen, _ := bug["is_open"].(bool)
+	resolution, _ := bug["resolution"].(string)
+	switch {
+	case isOpen:
+		issue.Status = TrackerStatusOpen
+	case resolution == valueOr(bz.FixedResolution, "FIXED"):
+		issue.Status = TrackerStatusFixed
+	default:
+		issue.Status = TrackerStatusInvalid
+	}
+	return issue, nil
+}
+
+func (bz *BugzillaTracker) request(c context.Context, method, path string, body, result interface{}) error {
+	header := map[string]string{"X-BUGZILLA-API-KEY": bz.APIKey}
+	return trackerRequest(c, method, strings.TrimSuffix(bz.URL, "/")+path, header, body, result)
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// GitHubTracker exports bugs to GitHub Issues.
+// GitHub issues don't have custom fields, so fix commits are stored in the issue body
+// as "#syz fix: commit title" lines (the same syntax as in email commands).
+type GitHubTracker struct {
+	Owner  string
+	Repo   string
+	Token  string   // personal access token with issues read/write permission
+	Labels []string // labels added to created issues
+	// APIURL defaults to "https://api.github.com", can be changed for GitHub Enterprise.
+	APIURL string
+}
+
+func (gh *GitHubTracker) Name() string {
+	return "github"
+}
+
+func (gh *GitHubTracker) Validate() error {
+	if gh.Owner == "" || gh.Repo == "" || gh.Token == "" {
+		return fmt.Errorf("Owner, Repo and Token must be set")
+	}
+	return nil
+}
+
+func (gh *GitHubTracker) CreateIssue(c context.Context, issue *TrackerIssue) (string, error) {
+	req := map[string]interface{}{
+		"title":  issue.Title,
+		"body":   githubSetFixCommits(issue.Body, issue.FixCommits),
+		"labels": append([]string{}, gh.Labels...),
+	}
+	reply := new(struct {
+		Number int `json:"number"`
+	})
+	if err := gh.request(c, "POST", "/issues", req, reply); err != nil {
+		return "", err
+	}
+	if reply.Number == 0 {
+		return "", fmt.Errorf("no issue number in reply")
+	}
+	return fmt.Sprint(reply.Number), nil
+}
+
+func (gh *GitHubTracker) UpdateIssue(c context.Context, id string, issue *TrackerIssue) error {
+	current, err := gh.getIssue(c, id)
+	if err != nil {
+		return err
+	}
+	req := map[string]interface{}{
+		"body": githubSetFixCommits(current.Body, issue.FixCommits),
+	}
+	switch issue.Status {
+	case TrackerStatusOpen:
+		req["state"] = "open"
+	case TrackerStatusFixed:
+		req["state"] = "closed"
+		req["state_reason"] = "completed"
+	case TrackerStatusInvalid:
+		req["state"] = "closed"
+		req["state_reason"] = "not_planned"
+	}
+	return gh.request(c, "PATCH", "/issues/"+id, req, nil)
+}
+
+func (gh *GitHubTracker) GetIssue(c context.Context, id string) (*TrackerIssue, error) {
+	current, err := gh.getIssue(c, id)
+	if err != nil {
+		return nil, err
+	}
+	issue := &TrackerIssue{
+		FixCommits: githubFixCommits(current.Body),
+	}
+	switch {
+	case current.State == "open":
+		issue.Status = TrackerStatusOpen
+	case current.StateReason == "not_planned":
+		issue.Status = TrackerStatusInvalid
+	default:
+		issue.Status = TrackerStatusFixed
+	}
+	return issue, nil
+}
+
+type githubIssue struct {
+	State       string `json:"state"`
+	StateReason string `json:"state_reason"`
+	Body        string `json:"body"`
+}
+
+func (gh *GitHubTracker) getIssue(c context.Context, id string) (*githubIssue, error) {
+	issue := new(githubIssue)
+	if err := gh.request(c, "GET", "/issues/"+id, nil, issue); err != nil {
+		return nil, err
+	}
+	return issue, nil
+}
+
+func (gh *GitHubTracker) request(c context.Context, method, path string, body, result interface{}) error {
+	header := map[string]string{
+		"Accept":        "application/vnd.github+json",
+		"Authorization": "Bearer " + gh.Token,
+	}
+	url := fmt.Sprintf("%v/repos/%v/%v%v", strings.TrimSuffix(valueOr(gh.APIURL, "https://api.github.com"), "/"),
+		gh.Owner, gh.Repo, path)
+	return trackerRequest(c, method, url, header, body, result)
+}
+
+var githubFixRe = regexp.MustCompile(`(?m)^#syz fix:[ \t]*(.*?)[ \t]*\r?$`)
+
+func githubFixCommits(body string) []string {
+	var commits []string
+	for _, match := range githubFixRe.FindAllStringSubmatch(body, -1) {
+		if match[1] != "" {
+			commits = append(commits, match[1])
+		}
+	}
+	return parseFixCommits(strings.Join(commits, "\n"))
+}
+
+// githubSetFixCommits replaces all "#syz fix:" lines in the body with the given commits.
+func githubSetFixCommits(body string, commits []string) string {
+	body = strings.TrimRight(githubFixRe.ReplaceAllString(body, ""), "\n")
+	for _, commit := range commits {
+		body += "\n#syz fix: " + commit
+	}
+	return body
+}
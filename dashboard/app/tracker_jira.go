@@ -0,0 +1,151 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// JiraTracker exports bugs to Jira using the REST API v2.
+type JiraTracker struct {
+	// URL of the Jira instance, e.g. "https://foo.atlassian.net".
+	URL string
+	// User and APIToken are used for basic authentication.
+	User      string
+	APIToken  string
+	Project   string // project key, e.g. "KERN"
+	IssueType string // defaults to "Bug"
+	// FixCommitsField is a text custom field used to store fix commit titles (one per line),
+	// e.g. "customfield_10042".
+	FixCommitsField string
+	// Names of workflow transitions used to update issue status.
+	// Default to "Reopen", "Done" and "Won't Do" respectively.
+	OpenTransition    string
+	FixedTransition   string
+	InvalidTransition string
+	// Resolution that denotes fixed issues, defaults to "Done".
+	// Closed issues with any other resolution are considered invalid.
+	FixedResolution string
+}
+
+func (jira *JiraTracker) Name() string {
+	return "jira"
+}
+
+func (jira *JiraTracker) Validate() error {
+	if _, err := url.ParseRequestURI(jira.URL); err != nil {
+		return fmt.Errorf("bad URL %q: %v", jira.URL, err)
+	}
+	if jira.User == "" || jira.APIToken == "" || jira.Project == "" {
+		return fmt.Errorf("User, APIToken and Project must be set")
+	}
+	if !strings.HasPrefix(jira.FixCommitsField, "customfield_") {
+		return fmt.Errorf("bad fix commits field %q, must be a custom field", jira.FixCommitsField)
+	}
+	return nil
+}
+
+func (jira *JiraTracker) CreateIssue(c context.Context, issue *TrackerIssue) (string, error) {
+	req := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":            map[string]string{"key": jira.Project},
+			"issuetype":          map[string]string{"name": valueOr(jira.IssueType, "Bug")},
+			"summary":            issue.Title,
+			"description":        issue.Body,
+			jira.FixCommitsField: formatFixCommits(issue.FixCommits),
+		},
+	}
+	reply := new(struct {
+		Key string `json:"key"`
+	})
+	if err := jira.request(c, "POST", "/rest/api/2/issue", req, reply); err != nil {
+		return "", err
+	}
+	if reply.Key == "" {
+		return "", fmt.Errorf("no issue key in reply")
+	}
+	return reply.Key, nil
+}
+
+func (jira *JiraTracker) UpdateIssue(c context.Context, id string, issue *TrackerIssue) error {
+	current, err := jira.GetIssue(c, id)
+	if err != nil {
+		return err
+	}
+	path := "/rest/api/2/issue/" + url.PathEscape(id)
+	if !sameCommits(current.FixCommits, issue.FixCommits) {
+		req := map[string]interface{}{
+			"fields": map[string]interface{}{
+				jira.FixCommitsField: formatFixCommits(issue.FixCommits),
+			},
+		}
+		if err := jira.request(c, "PUT", path, req, nil); err != nil {
+			return err
+		}
+	}
+	if current.Status == issue.Status {
+		return nil
+	}
+	var name string
+	switch issue.Status {
+	case TrackerStatusOpen:
+		name = valueOr(jira.OpenTransition, "Reopen")
+	case TrackerStatusFixed:
+		name = valueOr(jira.FixedTransition, "Done")
+	case TrackerStatusInvalid:
+		name = valueOr(jira.InvalidTransition, "Won't Do")
+	}
+	transitions := new(struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"transitions"`
+	})
+	if err := jira.request(c, "GET", path+"/transitions", nil, transitions); err != nil {
+		return err
+	}
+	for _, tr := range transitions.Transitions {
+		if tr.Name != name {
+			continue
+		}
+		req := map[string]interface{}{
+			"transition": map[string]string{"id": tr.ID},
+		}
+		return jira.request(c, "POST", path+"/transitions", req, nil)
+	}
+	return fmt.Errorf("issue %v: no transition %q", id, name)
+}
+
+func (jira *JiraTracker) GetIssue(c context.Context, id string) (*TrackerIssue, error) {
+	reply := new(struct {
+		Fields map[string]interface{} `json:"fields"`
+	})
+	path := fmt.Sprintf("/rest/api/2/issue/%v?fields=resolution,%v", url.PathEscape(id), jira.FixCommitsField)
+	if err := jira.request(c, "GET", path, nil, reply); err != nil {
+		return nil, err
+	}
+	issue := &TrackerIssue{Status: TrackerStatusOpen}
+	fixes, _ := reply.Fields[jira.FixCommitsField].(string)
+	issue.FixCommits = parseFixCommits(fixes)
+	// Resolution is set iff the issue is resolved.
+	if resolution, ok := reply.Fields["resolution"].(map[string]interface{}); ok {
+		if name, _ := resolution["name"].(string); name == valueOr(jira.FixedResolution, "Done") {
+			issue.Status = TrackerStatusFixed
+		} else {
+			issue.Status = TrackerStatusInvalid
+		}
+	}
+	return issue, nil
+}
+
+func (jira *JiraTracker) request(c context.Context, method, path string, body, result interface{}) error {
+	auth := base64.StdEncoding.EncodeToString([]byte(jira.User + ":" + jira.APIToken))
+	header := map[string]string{"Authorization": "Basic " + auth}
+	return trackerRequest(c, method, strings.TrimSuffix(jira.URL, "/")+path, header, body, result)
+}
@@ -0,0 +1,165 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/google/syzkaller/dashboard/dashapi"
+	"golang.org/x/net/context"
+	db "google.golang.org/appengine/v2/datastore"
+)
+
+var testTracker = &fakeTracker{}
+
+// fakeTracker keeps issues in memory.
+type fakeTracker struct {
+	mu     sync.Mutex
+	issues map[string]*TrackerIssue
+}
+
+func (ft *fakeTracker) Name() string {
+	return "fake"
+}
+
+func (ft *fakeTracker) Validate() error {
+	return nil
+}
+
+func (ft *fakeTracker) CreateIssue(c context.Context, issue *TrackerIssue) (string, error) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	if ft.issues == nil {
+		ft.issues = make(map[string]*TrackerIssue)
+	}
+	id := fmt.Sprint(len(ft.issues) + 1)
+	clone := *issue
+	ft.issues[id] = &clone
+	return id, nil
+}
+
+func (ft *fakeTracker) UpdateIssue(c context.Context, id string, issue *TrackerIssue) error {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	cur := ft.issues[id]
+	if cur == nil {
+		return fmt.Errorf("no issue %v", id)
+	}
+	cur.Status = issue.Status
+	cur.FixCommits = issue.FixCommits
+	return nil
+}
+
+func (ft *fakeTracker) GetIssue(c context.Context, id string) (*TrackerIssue, error) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	cur := ft.issues[id]
+	if cur == nil {
+		return nil, fmt.Errorf("no issue %v", id)
+	}
+	clone := *cur
+	return &clone, nil
+}
+
+func (ft *fakeTracker) issue(id string) *TrackerIssue {
+	issue, _ := ft.GetIssue(nil, id)
+	return issue
+}
+
+func (ft *fakeTracker) reset() {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	ft.issues = nil
+}
+
+func TestTrackerSync(t *testing.T) {
+	c := NewCtx(t)
+	defer c.Close()
+	defer testTracker.reset()
+
+	client := c.makeClient(clientPublic, keyPublic, true)
+	build := testBuild(1)
+	client.UploadBuild(build)
+	client.ReportCrash(testCrash(build, 1))
+	client.ReportCrash(testCrash(build, 2))
+
+	// Bugs in the non-public reporting are not exported.
+	reps := client.pollBugs(2)
+	_, err := c.GET("/tracker_poll")
+	c.expectOK(err)
+	c.expectEQ(testTracker.issue("1"), (*TrackerIssue)(nil))
+
+	for _, rep := range reps {
+		client.updateBug(rep.ID, dashapi.BugStatusUpstream, "")
+	}
+	reps = client.pollBugs(2)
+	_, err = c.GET("/tracker_poll")
+	c.expectOK(err)
+	issue := testTracker.issue("1")
+	c.expectTrue(issue != nil)
+	c.expectEQ(issue.Status, TrackerStatusOpen)
+	c.expectTrue(issue.Link != "")
+	c.expectTrue(testTracker.issue("2") != nil)
+
+	// Issues are created only once.
+	_, err = c.GET("/tracker_poll")
+	c.expectOK(err)
+	c.expectEQ(testTracker.issue("3"), (*TrackerIssue)(nil))
+
+	// Fix commits set in the tracker are applied to the bug.
+	bugs := make(map[string]*Bug)
+	for _, rep := range reps {
+		bug, _, _ := c.loadBug(rep.ID)
+		issues := c.trackedIssues(bug)
+		c.expectEQ(len(issues), 1)
+		bugs[issues[0].IssueID] = bug
+	}
+	c.expectTrue(bugs["1"] != nil && bugs["2"] != nil)
+	c.expectOK(testTracker.UpdateIssue(nil, "1", &TrackerIssue{
+		Status:     TrackerStatusFixed,
+		FixCommits: []string{"foo: fix the crash"},
+	}))
+	_, err = c.GET("/tracker_poll")
+	c.expectOK(err)
+	bug, _, _ := c.loadBugByHash(bugs["1"].keyHash())
+	c.expectEQ(bug.Commits, []string{"foo: fix the crash"})
+	// The bug is not fixed yet, so the issue is reopened.
+	c.expectEQ(testTracker.issue("1").Status, TrackerStatusOpen)
+	c.expectEQ(testTracker.issue("1").FixCommits, []string{"foo: fix the crash"})
+
+	// Invalidation in the tracker invalidates the bug.
+	c.expectOK(testTracker.UpdateIssue(nil, "2", &TrackerIssue{Status: TrackerStatusInvalid}))
+	_, err = c.GET("/tracker_poll")
+	c.expectOK(err)
+	bug, _, _ = c.loadBugByHash(bugs["2"].keyHash())
+	c.expectEQ(bug.Status, BugStatusInvalid)
+	c.expectEQ(testTracker.issue("2").Status, TrackerStatusInvalid)
+
+	// Local changes are pushed to the tracker.
+	client.updateBug(bugs["1"].Reporting[1].ID, dashapi.BugStatusInvalid, "")
+	_, err = c.GET("/tracker_poll")
+	c.expectOK(err)
+	c.expectEQ(testTracker.issue("1").Status, TrackerStatusInvalid)
+}
+
+func (c *Ctx) trackedIssues(bug *Bug) []*TrackedIssue {
+	var issues []*TrackedIssue
+	_, err := db.NewQuery("TrackedIssue").Ancestor(bug.key(c.ctx)).GetAll(c.ctx, &issues)
+	c.expectOK(err)
+	return issues
+}
+
+func TestGitHubFixCommits(t *testing.T) {
+	body := "title\n\nsome text\n#syz fix: old commit\nmore text"
+	body = githubSetFixCommits(body, []string{"b: fix", "a: fix"})
+	if want := "title\n\nsome text\n\nmore text\n#syz fix: b: fix\n#syz fix: a: fix"; body != want {
+		t.Fatalf("got body:\n%q\nwant:\n%q", body, want)
+	}
+	got := githubFixCommits(body)
+	if want := []string{"a: fix", "b: fix"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got commits %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/syzkaller/dashboard/dashapi"
+	"golang.org/x/net/context"
+	db "google.golang.org/appengine/v2/datastore"
+	"google.golang.org/appengine/v2/log"
+)
+
+// This file contains the triage API that allows external bots to label, comment, close
+// and invalidate bugs. Every update is recorded as TriageAction entities (the audit trail).
+
+// Actions that change the bug status.
+const (
+	triageInvalidate = "invalidate"
+	triageFix        = "fix"
+)
+
+func apiTriageBug(c context.Context, ns string, r *http.Request, payload []byte) (interface{}, error) {
+	req := new(dashapi.TriageReq)
+	if err := json.Unmarshal(payload, req); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal request: %v", err)
+	}
+	client := r.PostFormValue("client")
+	if !stringInList(config.Namespaces[ns].TriageClients, client) {
+		return nil, fmt.Errorf("client %q is not allowed to triage bugs in %v", client, ns)
+	}
+	bugKey := db.NewKey(c, "Bug", req.BugID, 0, nil)
+	bug := new(Bug)
+	if err := db.Get(c, bugKey, bug); err != nil {
+		return nil, fmt.Errorf("failed to get bug %q: %v", req.BugID, err)
+	}
+	if bug.Namespace != ns {
+		return nil, fmt.Errorf("bug %q does not belong to namespace %v", req.BugID, ns)
+	}
+	actions, err := triageActions(req, client, timeNow(c))
+	if err != nil {
+		return nil, err
+	}
+	if len(actions) == 0 {
+		return nil, nil
+	}
+	var bugReporting *BugReporting
+	if req.Invalidate || len(req.FixCommits) != 0 {
+		if bugReporting = lastReportedReporting(bug); bugReporting == nil {
+			return nil, fmt.Errorf("bug %q is not reported yet", req.BugID)
+		}
+	}
+	// The actions are recorded before the status change (which runs its own transaction),
+	// so that a status change is never missing from the audit trail. If the status change fails,
+	// the failure is recorded in the action.
+	keys := make([]*db.Key, len(actions))
+	tx := func(c context.Context) error {
+		bug := new(Bug)
+		if err := db.Get(c, bugKey, bug); err != nil {
+			return fmt.Errorf("failed to get bug: %v", err)
+		}
+		bug.Labels = mergeStringList(bug.Labels, req.AddLabels)
+		for _, label := range req.RemoveLabels {
+			bug.Labels = removeString(bug.Labels, label)
+		}
+		if len(req.Subsystems) != 0 {
+			bug.Subsystems = req.Subsystems
+		}
+		bug.LastActivity = timeNow(c)
+		if _, err := db.Put(c, bugKey, bug); err != nil {
+			return fmt.Errorf("failed to put bug: %v", err)
+		}
+		for i := range keys {
+			keys[i] = db.NewIncompleteKey(c, "TriageAction", bugKey)
+		}
+		var err error
+		if keys, err = db.PutMulti(c, keys, actions); err != nil {
+			return fmt.Errorf("failed to put triage actions: %v", err)
+		}
+		return nil
+	}
+	if err := db.RunInTransaction(c, tx, nil); err != nil {
+		return nil, err
+	}
+	if bugReporting == nil {
+		return nil, nil
+	}
+	cmd := &dashapi.BugUpdate{
+		ID:         bugReporting.ID,
+		Status:     dashapi.BugStatusOpen,
+		FixCommits: req.FixCommits,
+	}
+	if req.Invalidate {
+		cmd.Status = dashapi.BugStatusInvalid
+	}
+	ok, reason, err := incomingCommand(c, cmd)
+	if err == nil && !ok {
+		err = fmt.Errorf("failed to update bug status: %v", reason)
+	}
+	if err != nil {
+		for i, action := range actions {
+			if action.Action != triageInvalidate && action.Action != triageFix {
+				continue
+			}
+			action.Error = err.Error()
+			if _, err := db.Put(c, keys[i], action); err != nil {
+				log.Errorf(c, "failed to record triage action failure: %v", err)
+			}
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+func triageActions(req *dashapi.TriageReq, client string, now time.Time) ([]*TriageAction, error) {
+	for _, list := range [][]string{req.AddLabels, req.RemoveLabels, req.Subsystems} {
+		for _, str := range list {
+			if str == "" || len(str) > maxTextLen || strings.ContainsAny(str, ",\n") {
+				return nil, fmt.Errorf("bad label/subsystem %q", str)
+			}
+		}
+	}
+	if len(req.Comment) > MaxStringLen {
+		return nil, fmt.Errorf("comment is too long (%v bytes)", len(req.Comment))
+	}
+	if req.Invalidate && len(req.FixCommits) != 0 {
+		return nil, fmt.Errorf("can't both invalidate and fix the bug")
+	}
+	var actions []*TriageAction
+	add := func(action, args, comment string) {
+		actions = append(actions, &TriageAction{
+			Client:  client,
+			Time:    now,
+			Action:  action,
+			Args:    args,
+			Comment: comment,
+		})
+	}
+	if len(req.AddLabels) != 0 {
+		add("add labels", strings.Join(req.AddLabels, ", "), "")
+	}
+	if len(req.RemoveLabels) != 0 {
+		add("remove labels", strings.Join(req.RemoveLabels, ", "), "")
+	}
+	if len(req.Subsystems) != 0 {
+		add("set subsystems", strings.Join(req.Subsystems, ", "), "")
+	}
+	if req.Invalidate {
+		add(triageInvalidate, "", "")
+	}
+	if len(req.FixCommits) != 0 {
+		add(triageFix, strings.Join(req.FixCommits, "\n"), "")
+	}
+	if req.Comment != "" {
+		add("comment", "", req.Comment)
+	}
+	return actions, nil
+}
+
+func loadTriageActions(c context.Context, bug *Bug) ([]*TriageAction, error) {
+	var actions []*TriageAction
+	if _, err := db.NewQuery("TriageAction").
+		Ancestor(bug.key(c)).
+		GetAll(c, &actions); err != nil {
+		return nil, fmt.Errorf("failed to query triage actions: %v", err)
+	}
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actions[i].Time.Before(actions[j].Time)
+	})
+	return actions, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/syzkaller/dashboard/dashapi"
+)
+
+func TestTriageBug(t *testing.T) {
+	c := NewCtx(t)
+	defer c.Close()
+
+	build := testBuild(1)
+	c.client.UploadBuild(build)
+	crash := testCrash(build, 1)
+	c.client.ReportCrash(crash)
+	rep := c.client.pollBug()
+	bug, _, _ := c.loadBug(rep.ID)
+
+	// Regular clients are not allowed to triage.
+	c.expectFail("not allowed to triage", c.makeClient(client1, password1, false).TriageBug(&dashapi.TriageReq{
+		BugID:     bug.keyHash(),
+		AddLabels: []string{"foo"},
+	}))
+
+	triage := c.makeClient(clientTriage, keyTriage, true)
+	c.expectOK(triage.TriageBug(&dashapi.TriageReq{
+		BugID:      bug.keyHash(),
+		AddLabels:  []string{"foo", "bar"},
+		Subsystems: []string{"net"},
+		Comment:    "looks like a networking bug",
+	}))
+	c.expectOK(triage.TriageBug(&dashapi.TriageReq{
+		BugID:        bug.keyHash(),
+		RemoveLabels: []string{"foo"},
+		Invalidate:   true,
+	}))
+	bug, _, _ = c.loadBug(rep.ID)
+	c.expectEQ(bug.Labels, []string{"bar"})
+	c.expectEQ(bug.Subsystems, []string{"net"})
+	c.expectEQ(bug.Status, BugStatusInvalid)
+
+	actions, err := loadTriageActions(c.ctx, bug)
+	c.expectOK(err)
+	var got []string
+	for _, action := range actions {
+		c.expectEQ(action.Client, clientTriage)
+		got = append(got, action.Action)
+	}
+	sort.Strings(got)
+	c.expectEQ(got, []string{"add labels", "comment", "invalidate", "remove labels", "set subsystems"})
+
+	// The failed status change is still recorded.
+	c.expectFail("failed to update bug status", triage.TriageBug(&dashapi.TriageReq{
+		BugID:      bug.keyHash(),
+		FixCommits: []string{"foo: fix the bug"},
+	}))
+	actions, err = loadTriageActions(c.ctx, bug)
+	c.expectOK(err)
+	var fix *TriageAction
+	for _, action := range actions {
+		if action.Action == "fix" {
+			fix = action
+		}
+	}
+	c.expectTrue(fix != nil)
+	c.expectNE(fix.Error, "")
+}
@@ -269,6 +269,10 @@ type Crash struct {
 	ReproC    []byte
 }
 
+// VerifierMismatchPrefix is the title prefix of mismatches uploaded by syz-verifier.
+// They are tracked by the dashboard as crashes, but are never reported.
+const VerifierMismatchPrefix = "verifier mismatch in "
+
 type ReportCrashResp struct {
 	NeedRepro bool
 }
@@ -328,6 +332,7 @@ type BugReport struct {
 	ID                string
 	JobID             string
 	ExtID             string // arbitrary reporting ID forwarded from BugUpdate.ExtID
+	IssueExtID        string // ExtID of the first bug in the same issue, if any
 	First             bool   // Set for first report for this bug (Type == ReportNew).
 	Moderation        bool
 	NoRepro           bool // We don't expect repro (e.g. for build/boot errors).
@@ -495,6 +500,22 @@ func (dash *Dashboard) ReportingUpdate(upd *BugUpdate) (*BugUpdateReply, error)
 	return resp, nil
 }
 
+// TriageReq is sent by external triage bots to update a bug.
+// All set fields are applied and recorded in the bug audit trail.
+type TriageReq struct {
+	BugID        string // bug ID as used in dashboard bug links (/bug?id=)
+	AddLabels    []string
+	RemoveLabels []string
+	Subsystems   []string // replaces the current subsystems, if non-empty
+	Comment      string
+	Invalidate   bool     // mark the bug as invalid
+	FixCommits   []string // close the bug with these fixing commits
+}
+
+func (dash *Dashboard) TriageBug(req *TriageReq) error {
+	return dash.Query("triage_bug", req, nil)
+}
+
 type ManagerStatsReq struct {
 	Name string
 	Addr string
@@ -505,6 +526,8 @@ type ManagerStatsReq struct {
 	PCs        uint64 // coverage
 	Cover      uint64 // what we call feedback signal everywhere else
 	CrashTypes uint64
+	// Covered PCs per kernel subsystem (periodically, nil in most requests).
+	SubsystemPCs map[string]uint64
 
 	// Delta since last sync:
 	FuzzingTime       time.Duration
@@ -39,7 +39,7 @@ typedef signed int ssize_t;
 #include <stdlib.h>
 #include <string.h>
 
-#if SYZ_TRACE
+#if SYZ_TRACE || SYZ_STRACE
 #include <errno.h>
 #endif
 
@@ -619,7 +619,10 @@ static void loop(void)
 		reset_loop();
 #endif
 #if SYZ_EXECUTOR
-		receive_execute();
+		// The main execution of a program is followed by repeat_times perturbed repetitions
+		// in new test processes, these don't receive a new request (see execute_one).
+		if (repeat_iter == 0)
+			receive_execute();
 #endif
 		int pid = fork();
 		if (pid < 0)
@@ -715,7 +718,12 @@ static void loop(void)
 			errno = 0;
 			fail("child failed");
 		}
-		reply_execute(0);
+		if (repeat_iter < repeat_times) {
+			repeat_iter++;
+		} else {
+			repeat_iter = 0;
+			reply_execute(0);
+		}
 #endif
 #if SYZ_EXECUTOR || SYZ_USE_TMP_DIR
 		remove_dir(cwdbuf);
@@ -16,7 +16,103 @@ struct kvm_opt {
 	uint64 val;
 };
 
-// syz_kvm_setup_cpu(fd fd_kvmvm, cpufd fd_kvmcpu, usermem vma[24], text ptr[in, array[kvm_text, 1]], ntext len[text], flags flags[kvm_setup_flags], opts ptr[in, array[kvm_setup_opt, 0:2]], nopt len[opts])
+// Flags
+#define KVM_SETUP_ARM64_MMU (1 << 0) // Enable stage 1 translation
+#define KVM_SETUP_ARM64_EL0 (1 << 1) // Run the text at EL0 (usermode)
+
+// Guest physical memory layout (the guest memory is identity mapped if MMU is enabled).
+#define ARM64_ADDR_VECTORS 0x0 // exception vector table (VBAR_EL1)
+#define ARM64_ADDR_PGD 0x1000 // level 1 translation table
+#define ARM64_ADDR_PMD 0x2000 // level 2 translation table
+#define ARM64_ADDR_PTE 0x3000 // level 3 translation table
+#define ARM64_ADDR_TEXT 0x4000
+#define ARM64_ADDR_EL0_STACK 0x16000
+#define ARM64_ADDR_EL1_STACK 0x18000
+// Not backed by guest memory, accesses cause KVM_EXIT_MMIO.
+#define ARM64_ADDR_EXIT 0x10000000
+
+// Exit sequence appended to the text: stores x0 to ARM64_ADDR_EXIT and repeats the store forever,
+// so that every subsequent KVM_RUN exits with KVM_EXIT_MMIO as well.
+static const uint32 kvm_arm64_text_exit[] = {
+    0xd2a2001d, // movz x29, #0x1000, lsl #16
+    0xf90003a0, // str x0, [x29]
+    0x17ffffff, // b .-4
+};
+
+// The same, but stores ESR_EL1 of the exception. Used for all exception vectors.
+static const uint32 kvm_arm64_vector_exit[] = {
+    0xd538521c, // mrs x28, esr_el1
+    0xd2a2001d, // movz x29, #0x1000, lsl #16
+    0xf90003bc, // str x28, [x29]
+    0x17ffffff, // b .-4
+};
+
+// Page/block descriptor bits.
+#define ARM64_PTE_TABLE 0x3
+#define ARM64_PTE_PAGE 0x3
+#define ARM64_PTE_BLOCK 0x1
+#define ARM64_PTE_ATTR_NORMAL (0 << 2) // MAIR_EL1 attr0
+#define ARM64_PTE_ATTR_DEVICE (1 << 2) // MAIR_EL1 attr1
+#define ARM64_PTE_AP_EL0 (1 << 6) // EL0 RW, EL1 RW (implies PXN)
+#define ARM64_PTE_SH_INNER (3 << 8)
+#define ARM64_PTE_AF (1 << 10)
+
+#define ARM64_MAIR_EL1 0x00ff // attr0 - normal WB, attr1 - device nGnRnE
+// T0SZ=25 (39-bit VA, walk starts at level 1), 4K granule, inner shareable WB WA, TTBR1 walks disabled.
+#define ARM64_TCR_EL1 (25 | (1 << 8) | (1 << 10) | (3 << 12) | (1 << 23))
+#define ARM64_SCTLR_M (1 << 0)
+#define ARM64_SCTLR_C (1 << 2)
+#define ARM64_SCTLR_I (1 << 12)
+#define ARM64_CPACR_FPEN (3 << 20) // don't trap FP/SIMD
+#define ARM64_PSTATE_DAIF 0x3c0
+#define ARM64_PSTATE_EL0t 0x0
+#define ARM64_PSTATE_EL1h 0x5
+
+#define ARM64_CORE_REG(x) (KVM_REG_ARM64 | KVM_REG_SIZE_U64 | KVM_REG_ARM_CORE | KVM_REG_ARM_CORE_REG(x))
+#define ARM64_SYSREG_SCTLR_EL1 ARM64_SYS_REG(3, 0, 1, 0, 0)
+#define ARM64_SYSREG_CPACR_EL1 ARM64_SYS_REG(3, 0, 1, 0, 2)
+#define ARM64_SYSREG_TTBR0_EL1 ARM64_SYS_REG(3, 0, 2, 0, 0)
+#define ARM64_SYSREG_TCR_EL1 ARM64_SYS_REG(3, 0, 2, 0, 2)
+#define ARM64_SYSREG_MAIR_EL1 ARM64_SYS_REG(3, 0, 10, 2, 0)
+#define ARM64_SYSREG_VBAR_EL1 ARM64_SYS_REG(3, 0, 12, 0, 0)
+
+static int kvm_arm64_set_reg(int cpufd, uint64 id, uint64 val)
+{
+	struct kvm_one_reg reg = {.id = id, .addr = (uintptr_t)&val};
+	return ioctl(cpufd, KVM_SET_ONE_REG, &reg);
+}
+
+static int kvm_arm64_get_reg(int cpufd, uint64 id, uint64* val)
+{
+	struct kvm_one_reg reg = {.id = id, .addr = (uintptr_t)val};
+	return ioctl(cpufd, KVM_GET_ONE_REG, &reg);
+}
+
+// Sets up identity mapping of the guest memory and the exit page with 4K pages.
+static void kvm_arm64_setup_page_tables(char* host_mem, uintptr_t guest_mem_size, uintptr_t page_size, bool el0)
+{
+	uint64* pgd = (uint64*)(host_mem + ARM64_ADDR_PGD);
+	uint64* pmd = (uint64*)(host_mem + ARM64_ADDR_PMD);
+	uint64* pte = (uint64*)(host_mem + ARM64_ADDR_PTE);
+	memset(pgd, 0, page_size);
+	memset(pmd, 0, page_size);
+	memset(pte, 0, page_size);
+	pgd[0] = ARM64_ADDR_PMD | ARM64_PTE_TABLE;
+	pmd[0] = ARM64_ADDR_PTE | ARM64_PTE_TABLE;
+	// Each level 2 entry maps 2MB.
+	pmd[ARM64_ADDR_EXIT >> 21] = ARM64_ADDR_EXIT | ARM64_PTE_BLOCK | ARM64_PTE_ATTR_DEVICE | ARM64_PTE_AF;
+	if (el0)
+		pmd[ARM64_ADDR_EXIT >> 21] |= ARM64_PTE_AP_EL0;
+	for (uintptr_t i = 0; i < guest_mem_size / page_size; i++) {
+		uint64 addr = i * page_size;
+		pte[i] = addr | ARM64_PTE_PAGE | ARM64_PTE_ATTR_NORMAL | ARM64_PTE_SH_INNER | ARM64_PTE_AF;
+		// Memory writable at EL0 is never executable at EL1, so vectors and page tables stay EL1-only.
+		if (el0 && addr >= ARM64_ADDR_TEXT)
+			pte[i] |= ARM64_PTE_AP_EL0;
+	}
+}
+
+// syz_kvm_setup_cpu(fd fd_kvmvm, cpufd fd_kvmcpu, usermem vma[24], text ptr[in, array[kvm_text, 1]], ntext len[text], flags flags[kvm_setup_flags_arm64], opts ptr[in, array[kvm_setup_opt, 0:2]], nopt len[opts])
 static volatile long syz_kvm_setup_cpu(volatile long a0, volatile long a1, volatile long a2, volatile long a3, volatile long a4, volatile long a5, volatile long a6, volatile long a7)
 {
 	const int vmfd = a0;
@@ -28,9 +124,6 @@ static volatile long syz_kvm_setup_cpu(volatile long a0, volatile long a1, volat
 	const struct kvm_opt* const opt_array_ptr = (struct kvm_opt*)a6;
 	uintptr_t opt_count = a7;
 
-	(void)flags;
-	(void)opt_count;
-
 	const uintptr_t page_size = 4 << 10;
 	const uintptr_t guest_mem = 0;
 	const uintptr_t guest_mem_size = 24 * page_size;
@@ -38,13 +131,13 @@ static volatile long syz_kvm_setup_cpu(volatile long a0, volatile long a1, volat
 	(void)text_count; // fuzzer can spoof count and we need just 1 text, so ignore text_count
 	int text_type = text_array_ptr[0].typ;
 	const void* text = text_array_ptr[0].text;
-	int text_size = text_array_ptr[0].size;
+	uintptr_t text_size = text_array_ptr[0].size;
 	(void)text_type;
-	(void)opt_array_ptr;
 
 	uint32 features = 0;
-	if (opt_count > 1)
-		opt_count = 1;
+	uint64 sctlr_bits = 0;
+	if (opt_count > 2)
+		opt_count = 2;
 	for (uintptr_t i = 0; i < opt_count; i++) {
 		uint64 typ = opt_array_ptr[i].typ;
 		uint64 val = opt_array_ptr[i].val;
@@ -52,6 +145,9 @@ static volatile long syz_kvm_setup_cpu(volatile long a0, volatile long a1, volat
 		case 1:
 			features = val;
 			break;
+		case 2:
+			sctlr_bits = val;
+			break;
 		}
 	}
 
@@ -66,13 +162,40 @@ static volatile long syz_kvm_setup_cpu(volatile long a0, volatile long a1, volat
 	}
 
 	struct kvm_vcpu_init init;
-	ioctl(cpufd, KVM_ARM_PREFERRED_TARGET, &init);
+	ioctl(vmfd, KVM_ARM_PREFERRED_TARGET, &init);
 	init.features[0] = features;
 	ioctl(cpufd, KVM_ARM_VCPU_INIT, &init);
 
-	if (text_size > 1000)
-		text_size = 1000;
-	memcpy(host_mem, text, text_size);
+	// Every exception vector entry is 0x80 bytes, there are 16 of them.
+	for (uintptr_t i = 0; i < 16; i++)
+		memcpy(host_mem + ARM64_ADDR_VECTORS + i * 0x80, kvm_arm64_vector_exit, sizeof(kvm_arm64_vector_exit));
+
+	const uintptr_t max_text_size = ARM64_ADDR_EL0_STACK - page_size - ARM64_ADDR_TEXT - sizeof(kvm_arm64_text_exit);
+	if (text_size > max_text_size)
+		text_size = max_text_size;
+	text_size &= ~3;
+	memcpy(host_mem + ARM64_ADDR_TEXT, text, text_size);
+	memcpy(host_mem + ARM64_ADDR_TEXT + text_size, kvm_arm64_text_exit, sizeof(kvm_arm64_text_exit));
+
+	const bool el0 = flags & KVM_SETUP_ARM64_EL0;
+	uint64 sctlr = 0;
+	kvm_arm64_get_reg(cpufd, ARM64_SYSREG_SCTLR_EL1, &sctlr);
+	sctlr |= sctlr_bits;
+	if (flags & KVM_SETUP_ARM64_MMU) {
+		kvm_arm64_setup_page_tables(host_mem, guest_mem_size, page_size, el0);
+		kvm_arm64_set_reg(cpufd, ARM64_SYSREG_MAIR_EL1, ARM64_MAIR_EL1);
+		kvm_arm64_set_reg(cpufd, ARM64_SYSREG_TCR_EL1, ARM64_TCR_EL1);
+		kvm_arm64_set_reg(cpufd, ARM64_SYSREG_TTBR0_EL1, ARM64_ADDR_PGD);
+		sctlr |= ARM64_SCTLR_M | ARM64_SCTLR_C | ARM64_SCTLR_I;
+	}
+	kvm_arm64_set_reg(cpufd, ARM64_SYSREG_SCTLR_EL1, sctlr);
+	kvm_arm64_set_reg(cpufd, ARM64_SYSREG_CPACR_EL1, ARM64_CPACR_FPEN);
+	kvm_arm64_set_reg(cpufd, ARM64_SYSREG_VBAR_EL1, ARM64_ADDR_VECTORS);
+	kvm_arm64_set_reg(cpufd, ARM64_CORE_REG(sp_el1), ARM64_ADDR_EL1_STACK);
+	kvm_arm64_set_reg(cpufd, ARM64_CORE_REG(regs.sp), ARM64_ADDR_EL0_STACK);
+	kvm_arm64_set_reg(cpufd, ARM64_CORE_REG(regs.pc), ARM64_ADDR_TEXT);
+	kvm_arm64_set_reg(cpufd, ARM64_CORE_REG(regs.pstate),
+			  ARM64_PSTATE_DAIF | (el0 ? ARM64_PSTATE_EL0t : ARM64_PSTATE_EL1h));
 
 	return 0;
 }
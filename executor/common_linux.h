@@ -1851,6 +1851,136 @@ static long syz_io_uring_submit(volatile long a0, volatile long a1, volatile lon
 
 #endif
 
+#if SYZ_EXECUTOR || __NR_syz_io_uring_register_file || __NR_syz_io_uring_unregister_file || __NR_syz_io_uring_register_buffers || __NR_syz_io_uring_unregister_buffers || __NR_syz_io_uring_register_eventfd || __NR_syz_io_uring_unregister_eventfd
+
+#include <errno.h>
+#include <sys/uio.h>
+
+// From linux/io_uring.h
+#define IORING_REGISTER_BUFFERS 0
+#define IORING_UNREGISTER_BUFFERS 1
+#define IORING_REGISTER_FILES 2
+#define IORING_REGISTER_EVENTFD 4
+#define IORING_UNREGISTER_EVENTFD 5
+#define IORING_REGISTER_FILES_UPDATE 6
+#define IORING_REGISTER_EVENTFD_ASYNC 7
+
+// Size of the registered files table, must be larger than the max index in the descriptions.
+#define IO_URING_REGISTERED_FILES 16
+
+#if SYZ_EXECUTOR || __NR_syz_io_uring_register_file || __NR_syz_io_uring_unregister_file
+
+struct io_uring_files_update {
+	uint32 offset;
+	uint32 resv;
+	uint64 fds;
+};
+
+// Places fd into the slot index of the registered files table of the ring.
+static long io_uring_update_file(int ring_fd, uint32 index, int fd)
+{
+	if (index >= IO_URING_REGISTERED_FILES)
+		return -1;
+	// Register a table with all slots empty on the first use.
+	// If a table is already registered (by the fuzzer or by us), this fails with EBUSY.
+	int files[IO_URING_REGISTERED_FILES];
+	for (int i = 0; i < IO_URING_REGISTERED_FILES; i++)
+		files[i] = -1;
+	if (syscall(__NR_io_uring_register, ring_fd, IORING_REGISTER_FILES, files, IO_URING_REGISTERED_FILES) && errno != EBUSY)
+		return -1;
+	struct io_uring_files_update up = {};
+	up.offset = index;
+	up.fds = (uint64)(long)&fd;
+	if (syscall(__NR_io_uring_register, ring_fd, IORING_REGISTER_FILES_UPDATE, &up, 1) < 0)
+		return -1;
+	return index;
+}
+
+#endif
+
+#if SYZ_EXECUTOR || __NR_syz_io_uring_register_file
+
+static long syz_io_uring_register_file(volatile long a0, volatile long a1, volatile long a2)
+{
+	// syzlang: syz_io_uring_register_file(fd fd_io_uring, file fd, index int32[0:10]) io_uring_file_index
+	// C:       syz_io_uring_register_file(int fd, int file, uint32 index) // returns uint32 index
+	return io_uring_update_file((int)a0, (uint32)a2, (int)a1);
+}
+
+#endif
+
+#if SYZ_EXECUTOR || __NR_syz_io_uring_unregister_file
+
+static long syz_io_uring_unregister_file(volatile long a0, volatile long a1)
+{
+	// syzlang: syz_io_uring_unregister_file(fd fd_io_uring, index io_uring_file_index)
+	// C:       syz_io_uring_unregister_file(int fd, uint32 index)
+	if (io_uring_update_file((int)a0, (uint32)a1, -1) < 0)
+		return -1;
+	return 0;
+}
+
+#endif
+
+#if SYZ_EXECUTOR || __NR_syz_io_uring_register_buffers
+
+static long syz_io_uring_register_buffers(volatile long a0, volatile long a1, volatile long a2, volatile long a3)
+{
+	// syzlang: syz_io_uring_register_buffers(fd fd_io_uring, bufs ptr[in, array[iovec_out, 1:4]], nr len[bufs], index int16[0:3]) io_uring_buf_index
+	// C:       syz_io_uring_register_buffers(int fd, struct iovec* bufs, uint32 nr, uint16 index) // returns uint16 index
+	int ring_fd = (int)a0;
+	struct iovec* bufs = (struct iovec*)a1;
+	uint32 nr = (uint32)a2;
+	uint16 index = (uint16)a3;
+	if (nr == 0)
+		return -1;
+	if (syscall(__NR_io_uring_register, ring_fd, IORING_REGISTER_BUFFERS, bufs, nr) < 0)
+		return -1;
+	// Return an index that is valid for the registered table.
+	return index % nr;
+}
+
+#endif
+
+#if SYZ_EXECUTOR || __NR_syz_io_uring_unregister_buffers
+
+static long syz_io_uring_unregister_buffers(volatile long a0, volatile long a1)
+{
+	// syzlang: syz_io_uring_unregister_buffers(fd fd_io_uring, index io_uring_buf_index)
+	// C:       syz_io_uring_unregister_buffers(int fd, uint16 index)
+	return syscall(__NR_io_uring_register, (int)a0, IORING_UNREGISTER_BUFFERS, 0, 0);
+}
+
+#endif
+
+#if SYZ_EXECUTOR || __NR_syz_io_uring_register_eventfd
+
+static long syz_io_uring_register_eventfd(volatile long a0, volatile long a1, volatile long a2)
+{
+	// syzlang: syz_io_uring_register_eventfd(fd fd_io_uring, efd fd_event, async bool32) fd_io_uring_eventfd
+	// C:       syz_io_uring_register_eventfd(int fd, int efd, uint32 async) // returns int efd
+	int efd = (int)a1;
+	int opcode = a2 ? IORING_REGISTER_EVENTFD_ASYNC : IORING_REGISTER_EVENTFD;
+	if (syscall(__NR_io_uring_register, (int)a0, opcode, &efd, 1) < 0)
+		return -1;
+	return efd;
+}
+
+#endif
+
+#if SYZ_EXECUTOR || __NR_syz_io_uring_unregister_eventfd
+
+static long syz_io_uring_unregister_eventfd(volatile long a0, volatile long a1)
+{
+	// syzlang: syz_io_uring_unregister_eventfd(fd fd_io_uring, efd fd_io_uring_eventfd)
+	// C:       syz_io_uring_unregister_eventfd(int fd, int efd)
+	return syscall(__NR_io_uring_register, (int)a0, IORING_UNREGISTER_EVENTFD, 0, 0);
+}
+
+#endif
+
+#endif
+
 #if SYZ_EXECUTOR || __NR_syz_usbip_server_init
 
 #include <errno.h>
@@ -180,6 +180,11 @@ static bool flag_collect_signal;
 static bool flag_dedup_cover;
 static bool flag_threaded;
 static bool flag_coverage_filter;
+static bool flag_perturb_timing;
+static bool flag_perturb_procid;
+static bool flag_perturb_faults;
+static bool flag_collect_outputs;
+static bool flag_collect_state;
 
 // If true, then executor should write the comparisons data to fuzzer.
 static bool flag_comparisons;
@@ -189,11 +194,20 @@ static uint64 syscall_timeout_ms;
 static uint64 program_timeout_ms;
 static uint64 slowdown_scale;
 
+// Number of perturbed repetitions of the program requested with execute_req,
+// and index of the current execution (0 is the main execution, its results are reported).
+static uint64 repeat_times;
+static uint64 repeat_iter;
+static uint64 perturb_state;
+
 #define SYZ_EXECUTOR 1
 #include "common.h"
 
 const int kMaxInput = 4 << 20; // keep in sync with prog.ExecBufferSize
 const int kMaxCommands = 1000; // prog package knows about this constant (prog.execMaxCommands)
+const int kMaxOutputs = 64; // prog package knows about this constant (prog.execMaxOutputs)
+const int kMaxPids = 32; // keep in sync with prog.MaxPids
+const uint64 kMaxRepeat = 16; // keep in sync with ipc.MaxRepeat
 
 const uint64 instr_eof = -1;
 const uint64 instr_copyin = -2;
@@ -275,6 +289,10 @@ struct thread_t {
 	bool fault_injected;
 	cover_t cov;
 	bool soft_fail_state;
+	// Values copied out by output copyouts (ones that are not referenced by later calls),
+	// collected with flag_collect_outputs.
+	uint64 outputs[kMaxOutputs];
+	int num_outputs;
 };
 
 static thread_t threads[kMaxThreads];
@@ -312,6 +330,7 @@ struct execute_req {
 	uint64 syscall_timeout_ms;
 	uint64 program_timeout_ms;
 	uint64 slowdown_scale;
+	uint64 repeat_times;
 	uint64 prog_size;
 };
 
@@ -372,7 +391,12 @@ static thread_t* schedule_call(int call_index, int call_num, uint64 copyout_inde
 static void handle_completion(thread_t* th);
 static void copyout_call_results(thread_t* th);
 static void write_call_output(thread_t* th, bool finished);
+static void setup_perturbation();
+static void perturb_call(call_props_t* call_props);
+static uint32 perturb_rand();
 static void write_extra_output();
+static void collect_state_before();
+static void write_state_output();
 static void execute_call(thread_t* th);
 static void thread_create(thread_t* th, int id, bool need_coverage);
 static void thread_mmap_cover(thread_t* th);
@@ -659,15 +683,25 @@ void receive_execute()
 	flag_comparisons = req.exec_flags & (1 << 3);
 	flag_threaded = req.exec_flags & (1 << 4);
 	flag_coverage_filter = req.exec_flags & (1 << 5);
+	flag_perturb_timing = req.exec_flags & (1 << 6);
+	flag_perturb_procid = req.exec_flags & (1 << 7);
+	flag_perturb_faults = req.exec_flags & (1 << 8);
+	flag_collect_outputs = req.exec_flags & (1 << 9);
+	flag_collect_state = req.exec_flags & (1 << 10);
+	repeat_times = req.repeat_times;
 
 	debug("[%llums] exec opts: procid=%llu threaded=%d cover=%d comps=%d dedup=%d signal=%d"
-	      " timeouts=%llu/%llu/%llu prog=%llu filter=%d\n",
+	      " timeouts=%llu/%llu/%llu prog=%llu filter=%d repeat=%llu perturb=%d/%d/%d outputs=%d state=%d\n",
 	      current_time_ms() - start_time_ms, procid, flag_threaded, flag_collect_cover,
 	      flag_comparisons, flag_dedup_cover, flag_collect_signal, syscall_timeout_ms,
-	      program_timeout_ms, slowdown_scale, req.prog_size, flag_coverage_filter);
+	      program_timeout_ms, slowdown_scale, req.prog_size, flag_coverage_filter, repeat_times,
+	      flag_perturb_timing, flag_perturb_procid, flag_perturb_faults, flag_collect_outputs,
+	      flag_collect_state);
 	if (syscall_timeout_ms == 0 || program_timeout_ms <= syscall_timeout_ms || slowdown_scale == 0)
 		failmsg("bad timeouts", "syscall=%llu, program=%llu, scale=%llu",
 			syscall_timeout_ms, program_timeout_ms, slowdown_scale);
+	if (repeat_times > kMaxRepeat)
+		failmsg("bad repeat times", "repeat=%llu", repeat_times);
 	if (SYZ_EXECUTOR_USES_SHMEM) {
 		if (req.prog_size)
 			fail("need_prog: no program");
@@ -733,10 +767,15 @@ void realloc_output_data()
 // execute_one executes program stored in input_data.
 void execute_one()
 {
+	if (repeat_iter)
+		setup_perturbation();
 #if SYZ_EXECUTOR_USES_SHMEM
-	realloc_output_data();
-	output_pos = output_data;
-	write_output(0); // Number of executed syscalls (updated later).
+	// Output of the main execution must not be overwritten by the repetitions.
+	if (!repeat_iter) {
+		realloc_output_data();
+		output_pos = output_data;
+		write_output(0); // Number of executed syscalls (updated later).
+	}
 #endif
 	uint64 start = current_time_ms();
 	uint64* input_pos = (uint64*)input_data;
@@ -748,6 +787,9 @@ void execute_one()
 			cover_reset(&extra_cov);
 	}
 
+	if (flag_collect_state && !repeat_iter)
+		collect_state_before();
+
 	int call_index = 0;
 	uint64 prog_extra_timeout = 0;
 	uint64 prog_extra_cover_timeout = 0;
@@ -869,6 +911,8 @@ void execute_one()
 			args[i] = read_arg(&input_pos);
 		for (uint64 i = num_args; i < kMaxArgs; i++)
 			args[i] = 0;
+		if (repeat_iter)
+			perturb_call(&call_props);
 		thread_t* th = schedule_call(call_index++, call_num, copyout_index,
 					     num_args, args, input_pos, call_props);
 
@@ -932,6 +976,9 @@ void execute_one()
 		}
 	}
 
+	if (flag_collect_state && !repeat_iter)
+		write_state_output();
+
 #if SYZ_HAVE_CLOSE_FDS
 	close_fds();
 #endif
@@ -947,6 +994,37 @@ void execute_one()
 	}
 }
 
+// setup_perturbation prepares a repetition of the program.
+// Repetitions don't produce any output, so coverage collection is disabled.
+void setup_perturbation()
+{
+	perturb_state = current_time_ms() ^ ((uint64)getpid() << 20) ^ repeat_iter;
+	flag_collect_signal = false;
+	flag_collect_cover = false;
+	flag_comparisons = false;
+	if (flag_perturb_procid)
+		procid = (procid + repeat_iter) % kMaxPids;
+	debug("perturbed repetition %llu/%llu: procid=%llu\n", repeat_iter, repeat_times, procid);
+}
+
+// perturb_call changes timing and fault injection of the next call in a repetition.
+void perturb_call(call_props_t* call_props)
+{
+	if (flag_perturb_timing)
+		sleep_ms(perturb_rand() % 4);
+	if (flag_perturb_faults && call_props->fail_nth == 0 && perturb_rand() % 8 == 0)
+		call_props->fail_nth = 1 + perturb_rand() % 16;
+}
+
+uint32 perturb_rand()
+{
+	// xorshift64
+	perturb_state ^= perturb_state << 13;
+	perturb_state ^= perturb_state >> 7;
+	perturb_state ^= perturb_state << 17;
+	return perturb_state;
+}
+
 thread_t* schedule_call(int call_index, int call_num, uint64 copyout_index, uint64 num_args, uint64* args, uint64* pos, call_props_t call_props)
 {
 	// Find a spare thread to execute the call.
@@ -970,6 +1048,7 @@ thread_t* schedule_call(int call_index, int call_num, uint64 copyout_index, uint
 	last_scheduled = th;
 	th->copyout_pos = pos;
 	th->copyout_index = copyout_index;
+	th->num_outputs = 0;
 	event_reset(&th->done);
 	th->executing = true;
 	th->call_index = call_index;
@@ -1074,12 +1153,18 @@ void copyout_call_results(thread_t* th)
 		switch (instr) {
 		case instr_copyout: {
 			uint64 index = read_input(&th->copyout_pos);
-			if (index >= kMaxCommands)
+			if (index >= kMaxCommands && index != no_copyout)
 				failmsg("result overflows kMaxCommands", "index=%lld", index);
 			char* addr = (char*)read_input(&th->copyout_pos);
 			uint64 size = read_input(&th->copyout_pos);
 			uint64 val = 0;
-			if (copyout(addr, size, &val)) {
+			bool ok = copyout(addr, size, &val);
+			if (index == no_copyout) {
+				// Output copyout, the value is only reported.
+				if (th->num_outputs >= kMaxOutputs)
+					failmsg("too many outputs", "outputs=%d", th->num_outputs);
+				th->outputs[th->num_outputs++] = val;
+			} else if (ok) {
 				results[index].executed = true;
 				results[index].val = val;
 			}
@@ -1095,6 +1180,8 @@ void copyout_call_results(thread_t* th)
 
 void write_call_output(thread_t* th, bool finished)
 {
+	if (repeat_iter)
+		return;
 	uint32 reserrno = 999;
 	const bool blocked = finished && th != last_scheduled;
 	uint32 call_flags = call_flag_executed | (blocked ? call_flag_blocked : 0);
@@ -1138,6 +1225,17 @@ void write_call_output(thread_t* th, bool finished)
 		else
 			write_coverage_signal<uint32>(&th->cov, signal_count_pos, cover_count_pos);
 	}
+	if (flag_collect_outputs) {
+		// The return value followed by the output copyouts, nothing if the call has failed.
+		if (finished && th->res != -1) {
+			write_output(1 + th->num_outputs);
+			write_output_64(th->res);
+			for (int i = 0; i < th->num_outputs; i++)
+				write_output_64(th->outputs[i]);
+		} else {
+			write_output(0);
+		}
+	}
 	debug_verbose("out #%u: index=%u num=%u errno=%d finished=%d blocked=%d sig=%u cover=%u comps=%u\n",
 		      completed, th->call_index, th->call_num, reserrno, finished, blocked,
 		      *signal_count_pos, *cover_count_pos, *comps_count_pos);
@@ -1182,12 +1280,134 @@ void write_extra_output()
 	else
 		write_coverage_signal<uint32>(&extra_cov, signal_count_pos, cover_count_pos);
 	cover_reset(&extra_cov);
+	if (flag_collect_outputs)
+		write_output(0); // outputs count
 	debug_verbose("extra: sig=%u cover=%u\n", *signal_count_pos, *cover_count_pos);
 	completed++;
 	write_completed(completed);
 #endif
 }
 
+#if SYZ_EXECUTOR_USES_SHMEM && GOOS_linux
+#include <dirent.h>
+#include <fcntl.h>
+
+const uint32 kMaxState = 64 << 10;
+const uint32 kMaxStateProbe = 16 << 10;
+const uint32 state_reply_index = -2;
+
+static char state_before[kMaxState];
+static uint32 state_before_size;
+
+static void state_append(char* buf, uint32* pos, uint32 size, const char* data, uint32 len)
+{
+	if (len > size - *pos)
+		len = size - *pos;
+	memcpy(buf + *pos, data, len);
+	*pos += len;
+}
+
+// collect_state writes a snapshot of the state probes to buf and returns its size.
+// The probes are files and directories listed in SYZ_STATE_PROBES separated by colons.
+// For files the contents are written, for directories the entries (with targets of symlinks,
+// e.g. /proc/self/fd shows the fd table).
+static uint32 collect_state(char* buf, uint32 size)
+{
+	const char* probes = getenv("SYZ_STATE_PROBES");
+	uint32 pos = 0;
+	char path[256], entry[512], target[256], line[1024];
+	while (probes && *probes) {
+		const char* end = strchr(probes, ':');
+		uint32 len = end ? end - probes : strlen(probes);
+		const char* probe = probes;
+		probes = end ? end + 1 : 0;
+		if (len == 0 || len >= sizeof(path))
+			continue;
+		memcpy(path, probe, len);
+		path[len] = 0;
+		state_append(buf, &pos, size, line, snprintf(line, sizeof(line), "== %s\n", path));
+		DIR* dir = opendir(path);
+		if (dir) {
+			while (struct dirent* ent = readdir(dir)) {
+				if (strcmp(ent->d_name, ".") == 0 || strcmp(ent->d_name, "..") == 0)
+					continue;
+				snprintf(entry, sizeof(entry), "%s/%s", path, ent->d_name);
+				ssize_t n = readlink(entry, target, sizeof(target) - 1);
+				int written;
+				if (n > 0) {
+					target[n] = 0;
+					written = snprintf(line, sizeof(line), "%s -> %s\n", ent->d_name, target);
+				} else {
+					written = snprintf(line, sizeof(line), "%s\n", ent->d_name);
+				}
+				state_append(buf, &pos, size, line, std::min<int>(written, sizeof(line) - 1));
+			}
+			closedir(dir);
+			continue;
+		}
+		int fd = open(path, O_RDONLY);
+		if (fd == -1)
+			continue;
+		uint32 limit = std::min(size, pos + kMaxStateProbe);
+		while (pos < limit) {
+			ssize_t n = read(fd, buf + pos, limit - pos);
+			if (n <= 0)
+				break;
+			pos += n;
+		}
+		close(fd);
+		if (pos != 0 && buf[pos - 1] != '\n')
+			state_append(buf, &pos, size, "\n", 1);
+	}
+	return pos;
+}
+
+void collect_state_before()
+{
+	state_before_size = collect_state(state_before, sizeof(state_before));
+}
+
+static void write_state_data(const char* data, uint32 size)
+{
+	write_output(size);
+	for (uint32 i = 0; i < size; i += sizeof(uint32)) {
+		uint32 v = 0;
+		memcpy(&v, data + i, std::min<uint32>(sizeof(v), size - i));
+		write_output(v);
+	}
+}
+
+// write_state_output writes the snapshots of the state probes taken before and after the program
+// as a separate reply.
+void write_state_output()
+{
+	static char state_after[kMaxState];
+	uint32 state_after_size = collect_state(state_after, sizeof(state_after));
+	write_output(state_reply_index); // call index
+	write_output(-1); // call num
+	write_output(999); // errno
+	write_output(0); // call flags
+	write_output(0); // signal count
+	write_output(0); // cover count
+	write_output(0); // comps count
+	if (flag_collect_outputs)
+		write_output(0); // outputs count
+	write_state_data(state_before, state_before_size);
+	write_state_data(state_after, state_after_size);
+	debug_verbose("state: before=%u after=%u\n", state_before_size, state_after_size);
+	completed++;
+	write_completed(completed);
+}
+#else
+void collect_state_before()
+{
+}
+
+void write_state_output()
+{
+}
+#endif
+
 void thread_create(thread_t* th, int id, bool need_coverage)
 {
 	th->created = true;
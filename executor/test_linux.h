@@ -64,12 +64,15 @@ static int test_one(int text_type, const char* text, int text_size, int flags, u
 		printf("KVM_RUN returned %d, errno=%d\n", ret, errno);
 		return 1;
 	}
+#if !GOARCH_arm64
+	// KVM_GET_REGS is not supported on arm64, the result is checked in the MMIO exit data instead.
 	struct kvm_regs regs;
 	if (ioctl(cpufd, KVM_GET_REGS, &regs)) {
 		printf("KVM_GET_REGS failed (%d)\n", errno);
 		dump_cpu_state(cpufd, (char*)vm_mem);
 		return 1;
 	}
+#endif
 	if (cpu_mem->exit_reason != reason) {
 		printf("KVM_RUN exit reason %d, expect %d\n", cpu_mem->exit_reason, reason);
 		if (cpu_mem->exit_reason == KVM_EXIT_FAIL_ENTRY)
@@ -90,6 +93,17 @@ static int test_one(int text_type, const char* text, int text_size, int flags, u
 		dump_cpu_state(cpufd, (char*)vm_mem);
 		return 1;
 	}
+#elif GOARCH_arm64
+	if (cpu_mem->mmio.phys_addr != ARM64_ADDR_EXIT) {
+		printf("wrong exit address: 0x%llx\n", (long long)cpu_mem->mmio.phys_addr);
+		dump_cpu_state(cpufd, (char*)vm_mem);
+		return 1;
+	}
+	if (check_rax && *(uint64*)cpu_mem->mmio.data != 0xbadc0de) {
+		printf("wrong result: x0=0x%llx\n", *(long long*)cpu_mem->mmio.data);
+		dump_cpu_state(cpufd, (char*)vm_mem);
+		return 1;
+	}
 #endif
 	munmap(vm_mem, vm_mem_size);
 	munmap(cpu_mem, cpu_mem_size);
@@ -182,6 +196,20 @@ static int test_kvm()
 		if (res)
 			return res;
 	}
+#elif GOARCH_arm64
+	// movz x0, #0xc0de; movk x0, #0xbad, lsl #16
+	const char text_arm64[] = "\xc0\x1b\x98\xd2\xa0\x75\xa1\xf2";
+	// svc #0; must end up in the exception vector that reports ESR_EL1 instead of x0
+	const char text_arm64_svc[] = "\x01\x00\x00\xd4";
+	const int flags_arm64[] = {0, KVM_SETUP_ARM64_MMU, KVM_SETUP_ARM64_EL0, KVM_SETUP_ARM64_MMU | KVM_SETUP_ARM64_EL0};
+	for (unsigned i = 0; i < sizeof(flags_arm64) / sizeof(flags_arm64[0]); i++) {
+		res = test_one(0, text_arm64, sizeof(text_arm64) - 1, flags_arm64[i], KVM_EXIT_MMIO, true);
+		if (res)
+			return res;
+		res = test_one(0, text_arm64_svc, sizeof(text_arm64_svc) - 1, flags_arm64[i], KVM_EXIT_MMIO, false);
+		if (res)
+			return res;
+	}
 #else
 	// Keeping gcc happy
 	const char text8[] = "\x66\xb8\xde\xc0\xad\x0b";
@@ -216,6 +244,13 @@ static void dump_seg(const char* name, struct kvm_segment* seg)
 
 static void dump_cpu_state(int cpufd, char* vm_mem)
 {
+#if GOARCH_arm64
+	uint64 pc = 0, pstate = 0;
+	kvm_arm64_get_reg(cpufd, ARM64_CORE_REG(regs.pc), &pc);
+	kvm_arm64_get_reg(cpufd, ARM64_CORE_REG(regs.pstate), &pstate);
+	printf("PC=0x%llx PSTATE=0x%llx\n", (long long)pc, (long long)pstate);
+	return;
+#endif
 	struct kvm_sregs sregs;
 	if (ioctl(cpufd, KVM_GET_SREGS, &sregs)) {
 		printf("KVM_GET_SREGS failed (%d)\n", errno);
@@ -64,6 +64,24 @@ func (n *Incdir) Info() (Pos, string, string) {
 	return n.Pos, tok2str[tokInclude], ""
 }
 
+type Package struct {
+	Pos  Pos
+	Name *Ident
+}
+
+func (n *Package) Info() (Pos, string, string) {
+	return n.Pos, "package", n.Name.Name
+}
+
+type Import struct {
+	Pos  Pos
+	Name *Ident
+}
+
+func (n *Import) Info() (Pos, string, string) {
+	return n.Pos, "import", n.Name.Name
+}
+
 type Define struct {
 	Pos   Pos
 	Name  *Ident
@@ -150,6 +168,30 @@ func (n *TypeDef) Info() (Pos, string, string) {
 	return n.Pos, "type", n.Name.Name
 }
 
+// CallTemplate describes a family of similar syscalls (e.g. get/set ioctls of a driver)
+// once, it's instantiated for concrete arguments with CallInstance.
+type CallTemplate struct {
+	Pos   Pos
+	Name  *Ident
+	Args  []*Ident
+	Calls []*Call
+}
+
+func (n *CallTemplate) Info() (Pos, string, string) {
+	return n.Pos, "template", n.Name.Name
+}
+
+type CallInstance struct {
+	Pos      Pos
+	Name     *Ident
+	Template *Ident
+	Args     []*Type
+}
+
+func (n *CallInstance) Info() (Pos, string, string) {
+	return n.Pos, "instantiate", n.Name.Name
+}
+
 // Not top-level AST nodes.
 
 type Ident struct {
@@ -38,6 +38,20 @@ func (n *Incdir) Clone() Node {
 	}
 }
 
+func (n *Package) Clone() Node {
+	return &Package{
+		Pos:  n.Pos,
+		Name: n.Name.Clone().(*Ident),
+	}
+}
+
+func (n *Import) Clone() Node {
+	return &Import{
+		Pos:  n.Pos,
+		Name: n.Name.Clone().(*Ident),
+	}
+}
+
 func (n *Define) Clone() Node {
 	return &Define{
 		Pos:   n.Pos,
@@ -77,6 +91,32 @@ func (n *TypeDef) Clone() Node {
 	}
 }
 
+func (n *CallTemplate) Clone() Node {
+	var args []*Ident
+	for _, v := range n.Args {
+		args = append(args, v.Clone().(*Ident))
+	}
+	var calls []*Call
+	for _, c := range n.Calls {
+		calls = append(calls, c.Clone().(*Call))
+	}
+	return &CallTemplate{
+		Pos:   n.Pos,
+		Name:  n.Name.Clone().(*Ident),
+		Args:  args,
+		Calls: calls,
+	}
+}
+
+func (n *CallInstance) Clone() Node {
+	return &CallInstance{
+		Pos:      n.Pos,
+		Name:     n.Name.Clone().(*Ident),
+		Template: n.Template.Clone().(*Ident),
+		Args:     cloneTypes(n.Args),
+	}
+}
+
 func (n *Call) Clone() Node {
 	var ret *Type
 	if n.Ret != nil {
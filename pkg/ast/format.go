@@ -82,6 +82,14 @@ func (n *Incdir) serialize(w io.Writer) {
 	fmt.Fprintf(w, "incdir <%v>\n", n.Dir.Value)
 }
 
+func (n *Package) serialize(w io.Writer) {
+	fmt.Fprintf(w, "package %v\n", n.Name.Name)
+}
+
+func (n *Import) serialize(w io.Writer) {
+	fmt.Fprintf(w, "import %v\n", n.Name.Name)
+}
+
 func (n *Define) serialize(w io.Writer) {
 	fmt.Fprintf(w, "define %v\t%v\n", n.Name.Name, fmtInt(n.Value))
 }
@@ -104,6 +112,19 @@ func (n *TypeDef) serialize(w io.Writer) {
 	}
 }
 
+func (n *CallTemplate) serialize(w io.Writer) {
+	fmt.Fprintf(w, "template %v%v {\n", n.Name.Name, fmtIdentList(n.Args))
+	for _, c := range n.Calls {
+		fmt.Fprintf(w, "\t")
+		c.serialize(w)
+	}
+	fmt.Fprintf(w, "}\n")
+}
+
+func (n *CallInstance) serialize(w io.Writer) {
+	fmt.Fprintf(w, "instantiate %v = %v%v\n", n.Name.Name, n.Template.Name, fmtTypeList(n.Args, "[", "]"))
+}
+
 func (n *Call) serialize(w io.Writer) {
 	fmt.Fprintf(w, "%v(", n.Name.Name)
 	for i, a := range n.Args {
@@ -27,12 +27,15 @@ func Parse(data []byte, filename string, errorHandler ErrorHandler) *Description
 		if _, ok := decl.(*NewLine); ok && prevNewLine {
 			continue
 		}
-		if str, ok := decl.(*Struct); ok && !prevNewLine && !prevComment {
-			top = append(top, &NewLine{Pos: str.Pos})
+		block := isBlock(decl)
+		if block && !prevNewLine && !prevComment {
+			pos, _, _ := decl.Info()
+			top = append(top, &NewLine{Pos: pos})
 		}
 		top = append(top, decl)
-		if str, ok := decl.(*Struct); ok {
-			decl = &NewLine{Pos: str.Pos}
+		if block {
+			pos, _, _ := decl.Info()
+			decl = &NewLine{Pos: pos}
 			top = append(top, decl)
 		}
 		_, prevNewLine = decl.(*NewLine)
@@ -47,6 +50,15 @@ func Parse(data []byte, filename string, errorHandler ErrorHandler) *Description
 	return &Description{top}
 }
 
+// isBlock returns true for multi-line declarations that are separated by new lines.
+func isBlock(decl Node) bool {
+	switch decl.(type) {
+	case *Struct, *CallTemplate:
+		return true
+	}
+	return false
+}
+
 func ParseGlob(glob string, errorHandler ErrorHandler) *Description {
 	if errorHandler == nil {
 		errorHandler = LoggingHandler
@@ -128,8 +140,17 @@ func (p *parser) parseTop() Node {
 		return p.parseResource()
 	case tokIdent:
 		name := p.parseIdent()
-		if name.Name == "type" {
+		switch name.Name {
+		case "type":
 			return p.parseTypeDef()
+		case "package":
+			return p.parsePackage(name.Pos)
+		case "import":
+			return p.parseImport(name.Pos)
+		case "template":
+			return p.parseCallTemplate(name.Pos)
+		case "instantiate":
+			return p.parseCallInstance(name.Pos)
 		}
 		switch p.tok {
 		case tokLParen:
@@ -226,6 +247,20 @@ func (p *parser) parseIncdir() *Incdir {
 	}
 }
 
+func (p *parser) parsePackage(pos0 Pos) *Package {
+	return &Package{
+		Pos:  pos0,
+		Name: p.parseIdent(),
+	}
+}
+
+func (p *parser) parseImport(pos0 Pos) *Import {
+	return &Import{
+		Pos:  pos0,
+		Name: p.parseIdent(),
+	}
+}
+
 func (p *parser) parseResource() *Resource {
 	pos0 := p.pos
 	p.consume(tokResource)
@@ -282,6 +317,45 @@ func (p *parser) parseTypeDef() *TypeDef {
 	}
 }
 
+func (p *parser) parseCallTemplate(pos0 Pos) *CallTemplate {
+	templ := &CallTemplate{
+		Pos:  pos0,
+		Name: p.parseIdent(),
+	}
+	p.consume(tokLBrack)
+	templ.Args = append(templ.Args, p.parseIdent())
+	for p.tryConsume(tokComma) {
+		templ.Args = append(templ.Args, p.parseIdent())
+	}
+	p.consume(tokRBrack)
+	p.consume(tokLBrace)
+	p.consume(tokNewLine)
+	for {
+		for p.tryConsume(tokNewLine) {
+		}
+		if p.tryConsume(tokRBrace) {
+			break
+		}
+		name := p.parseIdent()
+		p.expect(tokLParen)
+		templ.Calls = append(templ.Calls, p.parseCall(name))
+		p.consume(tokNewLine)
+	}
+	return templ
+}
+
+func (p *parser) parseCallInstance(pos0 Pos) *CallInstance {
+	inst := &CallInstance{
+		Pos:  pos0,
+		Name: p.parseIdent(),
+	}
+	p.consume(tokEq)
+	inst.Template = p.parseIdent()
+	p.expect(tokLBrack)
+	inst.Args = p.parseTypeList()
+	return inst
+}
+
 func (p *parser) parseCall(name *Ident) *Call {
 	c := &Call{
 		Pos:      name.Pos,
@@ -264,7 +264,8 @@ func (s *scanner) scanChar(pos Pos) string {
 
 func (s *scanner) scanIdent(pos Pos) (tok token, lit string) {
 	tok = tokIdent
-	for s.ch == '_' || s.ch == '$' ||
+	// Dots are allowed for package-qualified names (pkg.name).
+	for s.ch == '_' || s.ch == '$' || s.ch == '.' ||
 		s.ch >= 'a' && s.ch <= 'z' ||
 		s.ch >= 'A' && s.ch <= 'Z' ||
 		s.ch >= '0' && s.ch <= '9' {
@@ -43,6 +43,14 @@ func (n *Incdir) walk(cb func(Node)) {
 	cb(n.Dir)
 }
 
+func (n *Package) walk(cb func(Node)) {
+	cb(n.Name)
+}
+
+func (n *Import) walk(cb func(Node)) {
+	cb(n.Name)
+}
+
 func (n *Define) walk(cb func(Node)) {
 	cb(n.Name)
 	cb(n.Value)
@@ -69,6 +77,24 @@ func (n *TypeDef) walk(cb func(Node)) {
 	}
 }
 
+func (n *CallTemplate) walk(cb func(Node)) {
+	cb(n.Name)
+	for _, a := range n.Args {
+		cb(a)
+	}
+	for _, c := range n.Calls {
+		cb(c)
+	}
+}
+
+func (n *CallInstance) walk(cb func(Node)) {
+	cb(n.Name)
+	cb(n.Template)
+	for _, a := range n.Args {
+		cb(a)
+	}
+}
+
 func (n *Call) walk(cb func(Node)) {
 	cb(n.Name)
 	for _, f := range n.Args {
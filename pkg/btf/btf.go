@@ -0,0 +1,262 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+// Package btf parses BPF Type Format (BTF) kernel type information
+// as described in Documentation/bpf/btf.rst.
+package btf
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+)
+
+type Kind int
+
+const (
+	KindUnknown Kind = iota
+	KindInt
+	KindPtr
+	KindArray
+	KindStruct
+	KindUnion
+	KindEnum
+	KindFwd
+	KindTypedef
+	KindVolatile
+	KindConst
+	KindRestrict
+	KindFunc
+	KindFuncProto
+	KindVar
+	KindDatasec
+	KindFloat
+	KindDeclTag
+	KindTypeTag
+	KindEnum64
+)
+
+// Type is a single BTF type, Types returned by Parse are indexed by type ID.
+type Type struct {
+	Name string
+	Kind Kind
+	// Size in bytes for int/float/struct/union/enum.
+	Size uint64
+	// Referenced type ID for ptr/typedef/modifiers/var and element type for arrays.
+	Type    uint32
+	Count   uint64 // number of array elements
+	Bits    uint64 // number of value bits for ints
+	Members []Member
+	Enums   []Enum
+
+	kindFlag  bool
+	bitOffset uint64 // for ints
+}
+
+type Member struct {
+	Name      string // empty for anonymous struct/union members
+	Type      uint32
+	BitOffset uint64 // from the beginning of the struct/union
+	BitSize   uint64 // non-zero for bitfields
+}
+
+type Enum struct {
+	Name  string
+	Value uint64
+}
+
+const magic = 0xeb9f
+
+// Load loads BTF from a raw BTF file (e.g. /sys/kernel/btf/vmlinux)
+// or from .BTF section of an ELF file (e.g. vmlinux).
+func Load(file string) ([]*Type, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte(elf.ELFMAG)) {
+		return Parse(data)
+	}
+	ef, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer ef.Close()
+	sec := ef.Section(".BTF")
+	if sec == nil {
+		return nil, fmt.Errorf("%v does not have .BTF section", file)
+	}
+	data, err = sec.Data()
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses raw BTF data. The returned slice is indexed by type ID,
+// the first element is nil since type ID 0 denotes void.
+func Parse(data []byte) ([]*Type, error) {
+	if len(data) < 24 {
+		return nil, fmt.Errorf("BTF data is too short")
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if order.Uint16(data) != magic {
+		order = binary.BigEndian
+		if order.Uint16(data) != magic {
+			return nil, fmt.Errorf("bad BTF magic 0x%x", binary.LittleEndian.Uint16(data))
+		}
+	}
+	hdrLen := uint64(order.Uint32(data[4:]))
+	typeOff, typeLen := uint64(order.Uint32(data[8:])), uint64(order.Uint32(data[12:]))
+	strOff, strLen := uint64(order.Uint32(data[16:])), uint64(order.Uint32(data[20:]))
+	if hdrLen+typeOff+typeLen > uint64(len(data)) || hdrLen+strOff+strLen > uint64(len(data)) {
+		return nil, fmt.Errorf("bad BTF header")
+	}
+	p := &parser{
+		order: order,
+		data:  data[hdrLen+typeOff : hdrLen+typeOff+typeLen],
+		strs:  data[hdrLen+strOff : hdrLen+strOff+strLen],
+	}
+	types := []*Type{nil}
+	for p.pos < len(p.data) {
+		t, err := p.parseType()
+		if err != nil {
+			return nil, fmt.Errorf("type %v at offset %v: %v", len(types), p.pos, err)
+		}
+		types = append(types, t)
+	}
+	for _, t := range types[1:] {
+		if t.Kind != KindStruct && t.Kind != KindUnion || t.kindFlag {
+			continue
+		}
+		// Without kind flag bitfields are described by int types with the bitfield size.
+		for i := range t.Members {
+			m := &t.Members[i]
+			if int(m.Type) >= len(types) {
+				continue
+			}
+			if typ := types[m.Type]; typ != nil && typ.Kind == KindInt && typ.Bits != typ.Size*8 {
+				m.BitSize = typ.Bits
+				m.BitOffset += typ.bitOffset
+			}
+		}
+	}
+	return types, nil
+}
+
+// Resolve skips typedefs and type modifiers and returns the underlying type
+// (nil for void or invalid type IDs).
+func Resolve(types []*Type, id uint32) *Type {
+	for depth := 0; id != 0 && int(id) < len(types) && depth < 100; depth++ {
+		t := types[id]
+		switch t.Kind {
+		case KindTypedef, KindVolatile, KindConst, KindRestrict, KindTypeTag:
+			id = t.Type
+		default:
+			return t
+		}
+	}
+	return nil
+}
+
+type parser struct {
+	order binary.ByteOrder
+	data  []byte
+	strs  []byte
+	pos   int
+}
+
+func (p *parser) parseType() (*Type, error) {
+	if p.pos+12 > len(p.data) {
+		return nil, fmt.Errorf("truncated type")
+	}
+	info := p.order.Uint32(p.data[p.pos+4:])
+	sizeOrType := p.order.Uint32(p.data[p.pos+8:])
+	t := &Type{
+		Name: p.str(p.order.Uint32(p.data[p.pos:])),
+		Kind: Kind(info>>24) & 0x1f,
+	}
+	vlen, kindFlag := int(info&0xffff), info>>31 != 0
+	t.kindFlag = kindFlag
+	p.pos += 12
+	var extra int
+	switch t.Kind {
+	case KindInt, KindVar, KindDeclTag:
+		extra = 4
+	case KindArray:
+		extra = 12
+	case KindStruct, KindUnion, KindDatasec, KindEnum64:
+		extra = 12 * vlen
+	case KindEnum, KindFuncProto:
+		extra = 8 * vlen
+	case KindPtr, KindFwd, KindTypedef, KindVolatile, KindConst, KindRestrict,
+		KindFunc, KindFloat, KindTypeTag:
+	default:
+		return nil, fmt.Errorf("unknown kind %v", t.Kind)
+	}
+	if p.pos+extra > len(p.data) {
+		return nil, fmt.Errorf("truncated type %v", t.Name)
+	}
+	data := p.data[p.pos : p.pos+extra]
+	p.pos += extra
+	switch t.Kind {
+	case KindInt:
+		t.Size = uint64(sizeOrType)
+		t.Bits = uint64(p.order.Uint32(data) & 0xff)
+		t.bitOffset = uint64(p.order.Uint32(data)>>16) & 0xff
+	case KindFloat:
+		t.Size = uint64(sizeOrType)
+	case KindArray:
+		t.Type = p.order.Uint32(data)
+		t.Count = uint64(p.order.Uint32(data[8:]))
+	case KindStruct, KindUnion:
+		t.Size = uint64(sizeOrType)
+		for i := 0; i < vlen; i++ {
+			m := data[i*12:]
+			member := Member{
+				Name:      p.str(p.order.Uint32(m)),
+				Type:      p.order.Uint32(m[4:]),
+				BitOffset: uint64(p.order.Uint32(m[8:])),
+			}
+			if kindFlag {
+				// The upper 8 bits are bitfield size.
+				member.BitSize = member.BitOffset >> 24
+				member.BitOffset &= 0xffffff
+			}
+			t.Members = append(t.Members, member)
+		}
+	case KindEnum:
+		t.Size = uint64(sizeOrType)
+		for i := 0; i < vlen; i++ {
+			m := data[i*8:]
+			val := uint64(p.order.Uint32(m[4:]))
+			if !kindFlag {
+				val = uint64(int64(int32(val)))
+			}
+			t.Enums = append(t.Enums, Enum{p.str(p.order.Uint32(m)), val})
+		}
+	case KindEnum64:
+		t.Size = uint64(sizeOrType)
+		for i := 0; i < vlen; i++ {
+			m := data[i*12:]
+			val := uint64(p.order.Uint32(m[4:])) | uint64(p.order.Uint32(m[8:]))<<32
+			t.Enums = append(t.Enums, Enum{p.str(p.order.Uint32(m)), val})
+		}
+	default:
+		t.Type = sizeOrType
+	}
+	return t, nil
+}
+
+func (p *parser) str(off uint32) string {
+	if int(off) >= len(p.strs) {
+		return ""
+	}
+	s := p.strs[off:]
+	if end := bytes.IndexByte(s, 0); end != -1 {
+		s = s[:end]
+	}
+	return string(s)
+}
@@ -0,0 +1,154 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package btf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/pkg/osutil"
+)
+
+type builder struct {
+	order binary.ByteOrder
+	types bytes.Buffer
+	strs  bytes.Buffer
+	ntype uint32
+}
+
+func newBuilder(order binary.ByteOrder) *builder {
+	b := &builder{order: order}
+	b.strs.WriteByte(0)
+	return b
+}
+
+func (b *builder) str(s string) uint32 {
+	if s == "" {
+		return 0
+	}
+	off := uint32(b.strs.Len())
+	b.strs.WriteString(s)
+	b.strs.WriteByte(0)
+	return off
+}
+
+func (b *builder) put(vals ...uint32) {
+	for _, v := range vals {
+		binary.Write(&b.types, b.order, v)
+	}
+}
+
+func (b *builder) typ(name string, kind Kind, vlen int, kindFlag bool, sizeOrType uint32) uint32 {
+	info := uint32(kind)<<24 | uint32(vlen)
+	if kindFlag {
+		info |= 1 << 31
+	}
+	b.put(b.str(name), info, sizeOrType)
+	b.ntype++
+	return b.ntype
+}
+
+func (b *builder) data() []byte {
+	buf := new(bytes.Buffer)
+	hdr := []interface{}{uint16(magic), uint8(1), uint8(0), uint32(24),
+		uint32(0), uint32(b.types.Len()), uint32(b.types.Len()), uint32(b.strs.Len())}
+	for _, v := range hdr {
+		binary.Write(buf, b.order, v)
+	}
+	buf.Write(b.types.Bytes())
+	buf.Write(b.strs.Bytes())
+	return buf.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		b := newBuilder(order)
+		b.typ("int", KindInt, 0, false, 4)
+		b.put(32)
+		b.typ("", KindInt, 0, false, 4)
+		b.put(2<<16 | 3)
+		b.typ("", KindEnum, 2, false, 4)
+		b.put(b.str("FOO_A"), 1, b.str("FOO_NEG"), 0xffffffff)
+		b.typ("", KindEnum64, 1, true, 8)
+		b.put(b.str("FOO_BIG"), 0x2, 0x1)
+		b.typ("foo", KindStruct, 3, true, 16)
+		b.put(b.str("f1"), 1, 0, 0, 7, 32, b.str("bits"), 1, 3<<24|64)
+		b.typ("", KindPtr, 0, false, 5)
+		b.typ("", KindArray, 0, false, 0)
+		b.put(1, 1, 10)
+		b.typ("foo_t", KindTypedef, 0, false, 5)
+		b.typ("", KindConst, 0, false, 8)
+		b.typ("bar", KindUnion, 2, false, 4)
+		b.put(b.str("u1"), 9, 0, b.str("u2"), 2, 8)
+		b.typ("func", KindFunc, 0, false, 0)
+		data := b.data()
+
+		want := []*Type{
+			nil,
+			{Name: "int", Kind: KindInt, Size: 4, Bits: 32},
+			{Kind: KindInt, Size: 4, Bits: 3, bitOffset: 2},
+			{Kind: KindEnum, Size: 4, Enums: []Enum{{"FOO_A", 1}, {"FOO_NEG", ^uint64(0)}}},
+			{Kind: KindEnum64, Size: 8, Enums: []Enum{{"FOO_BIG", 0x100000002}}, kindFlag: true},
+			{Name: "foo", Kind: KindStruct, Size: 16, Members: []Member{
+				{Name: "f1", Type: 1},
+				{Type: 7, BitOffset: 32},
+				{Name: "bits", Type: 1, BitOffset: 64, BitSize: 3},
+			}, kindFlag: true},
+			{Kind: KindPtr, Type: 5},
+			{Kind: KindArray, Type: 1, Count: 10},
+			{Name: "foo_t", Kind: KindTypedef, Type: 5},
+			{Kind: KindConst, Type: 8},
+			{Name: "bar", Kind: KindUnion, Size: 4, Members: []Member{
+				{Name: "u1", Type: 9},
+				{Name: "u2", Type: 2, BitOffset: 10, BitSize: 3},
+			}},
+			{Name: "func", Kind: KindFunc},
+		}
+		types, err := Parse(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, types, cmp.AllowUnexported(Type{})); diff != "" {
+			t.Fatal(diff)
+		}
+		if got := Resolve(types, 9); got != types[5] {
+			t.Fatalf("resolved to %+v", got)
+		}
+		if got := Resolve(types, 0); got != nil {
+			t.Fatalf("resolved void to %+v", got)
+		}
+
+		file := filepath.Join(t.TempDir(), "btf")
+		if err := osutil.WriteFile(file, data); err != nil {
+			t.Fatal(err)
+		}
+		types, err = Load(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(types) != len(want) {
+			t.Fatalf("loaded %v types, want %v", len(types), len(want))
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	b := newBuilder(binary.LittleEndian)
+	b.typ("foo", KindStruct, 2, false, 16)
+	b.put(b.str("f1"), 1, 0)
+	data := b.data()
+	for _, test := range [][]byte{
+		nil,
+		make([]byte, 30),
+		data[:30],
+		data,
+	} {
+		if _, err := Parse(test); err == nil {
+			t.Errorf("parsing %x did not fail", test)
+		}
+	}
+}
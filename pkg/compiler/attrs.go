@@ -14,7 +14,11 @@ type attrDesc struct {
 	Name string
 	// For now we assume attributes can have only 1 argument and it's an integer,
 	// enough to cover existing cases.
-	HasArg      bool
+	HasArg bool
+	// Arguments are a non-empty list of syscall names (ordering constraints).
+	CallArgs bool
+	// Arguments are a non-empty list of integer constants (expected errnos).
+	ConstArgs   bool
 	CheckConsts func(comp *compiler, parent ast.Node, attr *ast.Type)
 }
 
@@ -27,6 +31,11 @@ var (
 	attrOut        = &attrDesc{Name: "out"}
 	attrInOut      = &attrDesc{Name: "inout"}
 	attrOutOverlay = &attrDesc{Name: "out_overlay"}
+	attrAfter      = &attrDesc{Name: "after", CallArgs: true}
+	attrNotAfter   = &attrDesc{Name: "not_after", CallArgs: true}
+	// Verifier annotations.
+	attrExpectErrno      = &attrDesc{Name: "expect_errno", ConstArgs: true}
+	attrNondeterministic = &attrDesc{Name: "nondeterministic"}
 
 	structAttrs      = makeAttrs(attrPacked, attrSize, attrAlign)
 	unionAttrs       = makeAttrs(attrVarlen, attrSize)
@@ -71,6 +80,12 @@ func initCallAttrs() {
 		}
 		callAttrs[prog.CppName(desc.Name)] = desc
 	}
+	// Ordering constraints are not part of prog.SyscallAttrs since they are not needed by executor.
+	callAttrs[attrAfter.Name] = attrAfter
+	callAttrs[attrNotAfter.Name] = attrNotAfter
+	// Same for verifier annotations.
+	callAttrs[attrExpectErrno.Name] = attrExpectErrno
+	callAttrs[attrNondeterministic.Name] = attrNondeterministic
 }
 
 func structOrUnionAttrs(n *ast.Struct) map[string]*attrDesc {
@@ -17,6 +17,11 @@ import (
 )
 
 func (comp *compiler) typecheck() {
+	comp.expandCallTemplates()
+	comp.resolvePackages()
+	if comp.errors != 0 {
+		return
+	}
 	comp.checkComments()
 	comp.checkDirectives()
 	comp.checkNames()
@@ -34,6 +39,7 @@ func (comp *compiler) check() {
 	comp.checkConstructors()
 	comp.checkVarlens()
 	comp.checkDupConsts()
+	comp.checkCallOrder()
 }
 
 func (comp *compiler) checkComments() {
@@ -615,7 +621,7 @@ func (comp *compiler) collectUsedType(structs, flags, strflags map[string]bool,
 func (comp *compiler) checkUnused() {
 	for _, n := range comp.collectUnused() {
 		pos, typ, name := n.Info()
-		comp.error(pos, fmt.Sprintf("unused %v %v", typ, name))
+		comp.error(pos, "unused %v %v", typ, name)
 	}
 }
 
@@ -842,7 +848,7 @@ func (comp *compiler) checkType(ctx checkCtx, t *ast.Type, flags checkFlags) {
 	}
 	desc := comp.getTypeDesc(t)
 	if desc == nil {
-		comp.error(t.Pos, "unknown type %v", t.Ident)
+		comp.errorSuggest(t.Pos, suggestName(t.Ident, comp.knownTypeNames()), "unknown type %v", t.Ident)
 		return
 	}
 	if desc == typeTypedef {
@@ -1320,3 +1326,66 @@ func (comp *compiler) checkDupConstsCall(n *ast.Call, dups map[string]map[string
 	comp.error(n.Pos, "call %v: duplicate const %v, previously used in call %v at %v",
 		n.Name.Name, constArgID, dup.name, dup.pos)
 }
+
+// checkCallOrder checks call ordering constraints (after/not_after call attributes):
+// they must refer to existing syscalls and "after" constraints must not form cycles,
+// otherwise it would be impossible to generate the calls.
+func (comp *compiler) checkCallOrder() {
+	calls := make(map[string]*ast.Call)
+	for _, decl := range comp.desc.Nodes {
+		if n, ok := decl.(*ast.Call); ok {
+			calls[n.Name.Name] = n
+		}
+	}
+	after := make(map[string][]string)
+	for _, decl := range comp.desc.Nodes {
+		n, ok := decl.(*ast.Call)
+		if !ok {
+			continue
+		}
+		for _, attr := range n.Attrs {
+			if attr.Ident != attrAfter.Name && attr.Ident != attrNotAfter.Name {
+				continue
+			}
+			for _, arg := range attr.Args {
+				if calls[arg.Ident] == nil {
+					comp.error(arg.Pos, "%v attribute refers to unknown syscall %v", attr.Ident, arg.Ident)
+					continue
+				}
+				if attr.Ident == attrAfter.Name {
+					if arg.Ident == n.Name.Name {
+						comp.error(arg.Pos, "syscall %v can't be after itself", n.Name.Name)
+						continue
+					}
+					after[n.Name.Name] = append(after[n.Name.Name], arg.Ident)
+				}
+			}
+		}
+	}
+	checked := make(map[string]bool)
+	var path []string
+	var visit func(name string)
+	visit = func(name string) {
+		for i, prev := range path {
+			if prev == name {
+				comp.error(calls[name].Pos, "call ordering cycle %v",
+					strings.Join(append(path[i:], name), " -> "))
+				return
+			}
+		}
+		if checked[name] {
+			return
+		}
+		checked[name] = true
+		path = append(path, name)
+		for _, next := range after[name] {
+			visit(next)
+		}
+		path = path[:len(path)-1]
+	}
+	for _, decl := range comp.desc.Nodes {
+		if n, ok := decl.(*ast.Call); ok {
+			visit(n.Name.Name)
+		}
+	}
+}
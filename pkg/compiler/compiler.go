@@ -73,7 +73,13 @@ func createCompiler(desc *ast.Description, target *targets.Target, eh ast.ErrorH
 
 // Compile compiles sys description.
 func Compile(desc *ast.Description, consts map[string]uint64, target *targets.Target, eh ast.ErrorHandler) *Prog {
+	return compile(desc, consts, target, eh, nil)
+}
+
+func compile(desc *ast.Description, consts map[string]uint64, target *targets.Target,
+	eh ast.ErrorHandler, dh DiagnosticHandler) *Prog {
 	comp := createCompiler(desc.Clone(), target, eh)
+	comp.dh = dh
 	comp.typecheck()
 	// The subsequent, more complex, checks expect basic validity of the tree,
 	// in particular corrent number of type arguments. If there were errors,
@@ -109,7 +115,7 @@ func Compile(desc *ast.Description, consts map[string]uint64, target *targets.Ta
 		return nil
 	}
 	for _, w := range comp.warnings {
-		eh(w.pos, w.msg)
+		comp.report(w)
 	}
 	return prg
 }
@@ -118,8 +124,9 @@ type compiler struct {
 	desc     *ast.Description
 	target   *targets.Target
 	eh       ast.ErrorHandler
+	dh       DiagnosticHandler // if set, used instead of eh
 	errors   int
-	warnings []warn
+	warnings []*Diagnostic
 	ptrSize  uint64
 
 	unsupported    map[string]bool
@@ -137,18 +144,20 @@ type compiler struct {
 	builtinConsts map[string]uint64
 }
 
-type warn struct {
-	pos ast.Pos
-	msg string
+func (comp *compiler) error(pos ast.Pos, msg string, args ...interface{}) {
+	comp.errorSuggest(pos, "", msg, args...)
 }
 
-func (comp *compiler) error(pos ast.Pos, msg string, args ...interface{}) {
+// errorSuggest is the same as error, but additionally provides a hint on how to fix the problem
+// (only visible in diagnostics).
+func (comp *compiler) errorSuggest(pos ast.Pos, suggestion, msg string, args ...interface{}) {
 	comp.errors++
-	comp.eh(pos, fmt.Sprintf(msg, args...))
+	comp.report(makeDiagnostic(pos, SeverityError, diagCode(msg), fmt.Sprintf(msg, args...), suggestion))
 }
 
 func (comp *compiler) warning(pos ast.Pos, msg string, args ...interface{}) {
-	comp.warnings = append(comp.warnings, warn{pos, fmt.Sprintf(msg, args...)})
+	comp.warnings = append(comp.warnings,
+		makeDiagnostic(pos, SeverityWarning, diagCode(msg), fmt.Sprintf(msg, args...), ""))
 }
 
 func (comp *compiler) structIsVarlen(name string) bool {
@@ -189,7 +198,8 @@ func (comp *compiler) parseAttrs(descs map[string]*attrDesc, parent ast.Node, at
 		}
 		desc := descs[attr.Ident]
 		if desc == nil {
-			comp.error(attr.Pos, "unknown %v %v attribute %v", parentType, parentName, attr.Ident)
+			comp.errorSuggest(attr.Pos, suggestName(attr.Ident, attrNames(descs)),
+				"unknown %v %v attribute %v", parentType, parentName, attr.Ident)
 			return res
 		}
 		if _, ok := res[desc]; ok {
@@ -199,6 +209,10 @@ func (comp *compiler) parseAttrs(descs map[string]*attrDesc, parent ast.Node, at
 		val := uint64(1)
 		if desc.HasArg {
 			val = comp.parseAttrArg(attr)
+		} else if desc.CallArgs {
+			comp.parseAttrCallArgs(attr)
+		} else if desc.ConstArgs {
+			comp.parseAttrConstArgs(attr)
 		} else if len(attr.Args) != 0 {
 			comp.error(attr.Pos, "%v attribute has args", attr.Ident)
 			return res
@@ -208,6 +222,68 @@ func (comp *compiler) parseAttrs(descs map[string]*attrDesc, parent ast.Node, at
 	return res
 }
 
+func (comp *compiler) parseAttrCallArgs(attr *ast.Type) {
+	if len(attr.Args) == 0 {
+		comp.error(attr.Pos, "%v attribute is expected to have syscall name arguments", attr.Ident)
+		return
+	}
+	for _, arg := range attr.Args {
+		if unexpected, _, ok := checkTypeKind(arg, kindIdent); !ok {
+			comp.error(arg.Pos, "unexpected %v, expect syscall name", unexpected)
+			return
+		}
+		if len(arg.Colon) != 0 || len(arg.Args) != 0 {
+			comp.error(arg.Pos, "%v attribute has colon or args", attr.Ident)
+			return
+		}
+	}
+}
+
+func (comp *compiler) parseAttrConstArgs(attr *ast.Type) {
+	if len(attr.Args) == 0 {
+		comp.error(attr.Pos, "%v attribute is expected to have const arguments", attr.Ident)
+		return
+	}
+	for _, arg := range attr.Args {
+		if unexpected, _, ok := checkTypeKind(arg, kindInt); !ok {
+			comp.error(arg.Pos, "unexpected %v, expect int", unexpected)
+			return
+		}
+		if len(arg.Colon) != 0 || len(arg.Args) != 0 {
+			comp.error(arg.Pos, "%v attribute has colon or args", attr.Ident)
+			return
+		}
+	}
+}
+
+// attrConstArgs returns values of consts listed in the call attribute desc.
+func attrConstArgs(n *ast.Call, desc *attrDesc) []uint64 {
+	var vals []uint64
+	for _, attr := range n.Attrs {
+		if attr.Ident != desc.Name {
+			continue
+		}
+		for _, arg := range attr.Args {
+			vals = append(vals, arg.Value)
+		}
+	}
+	return vals
+}
+
+// attrCallArgs returns syscall names listed in the call attribute desc.
+func attrCallArgs(n *ast.Call, desc *attrDesc) []string {
+	var names []string
+	for _, attr := range n.Attrs {
+		if attr.Ident != desc.Name {
+			continue
+		}
+		for _, arg := range attr.Args {
+			names = append(names, arg.Ident)
+		}
+	}
+	return names
+}
+
 func (comp *compiler) parseAttrArg(attr *ast.Type) uint64 {
 	if len(attr.Args) != 1 {
 		comp.error(attr.Pos, "%v attribute is expected to have 1 argument", attr.Ident)
@@ -7,14 +7,22 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	goast "go/ast"
+	"go/parser"
+	"go/token"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/google/syzkaller/pkg/ast"
 	"github.com/google/syzkaller/pkg/serializer"
+	"github.com/google/syzkaller/prog"
 	"github.com/google/syzkaller/sys/targets"
 )
 
@@ -301,3 +309,466 @@ func TestCollectUnused(t *testing.T) {
 		}
 	}
 }
+
+func TestPackages(t *testing.T) {
+	t.Parallel()
+	// The global description and the mydrv package both declare s0, foo$open and foo$ioctl.
+	files := map[string]string{
+		"global.txt": `
+resource fd[int32]
+foo$open() fd
+foo$ioctl(fd fd, arg ptr[in, s0])
+s0 {
+	f0	int8
+}
+`,
+		"mydrv.txt": `
+package mydrv
+resource dev[fd]
+foo$open() dev
+foo$ioctl(fd dev, arg ptr[in, s0])
+foo$fd(fd fd)
+foo$start(fd dev) (after[foo$ioctl])
+s0 {
+	f0	int16
+	f1	len[f2, int32]
+	f2	array[int8]
+}
+`,
+		"user.txt": `
+package user
+import mydrv
+foo$use(fd mydrv.dev, arg ptr[in, mydrv.s0], arg2 ptr[in, s0])
+`,
+	}
+	target := targets.List[targets.TestOS][targets.TestArch64]
+	eh := func(pos ast.Pos, msg string) {
+		t.Errorf("%v: %v", pos, msg)
+	}
+	desc := &ast.Description{}
+	for name, data := range files {
+		desc1 := ast.Parse([]byte(data), name, eh)
+		if desc1 == nil {
+			t.Fatalf("failed to parse %v", name)
+		}
+		desc.Nodes = append(desc.Nodes, desc1.Nodes...)
+	}
+	p := Compile(desc, map[string]uint64{"SYS_foo": 1}, target, eh)
+	if p == nil {
+		t.Fatal("failed to compile")
+	}
+	var calls, resources, structs []string
+	var useArgs []string
+	for _, call := range p.Syscalls {
+		if !strings.HasPrefix(call.Name, "foo$") {
+			continue
+		}
+		calls = append(calls, call.Name)
+		if call.Name == "foo$mydrv.start" {
+			if diff := cmp.Diff([]string{"foo$mydrv.ioctl"}, call.After); diff != "" {
+				t.Errorf("foo$mydrv.start after: %v", diff)
+			}
+		}
+		if call.Name != "foo$user.use" {
+			continue
+		}
+		for _, arg := range call.Args {
+			typ := p.Types[arg.Type.(prog.Ref)]
+			if ptr, ok := typ.(*prog.PtrType); ok {
+				typ = p.Types[ptr.Elem.(prog.Ref)]
+			}
+			useArgs = append(useArgs, typ.Name())
+		}
+	}
+	for _, res := range p.Resources {
+		if !strings.HasPrefix(res.Name, "ANYRES") {
+			resources = append(resources, res.Name)
+		}
+	}
+	for _, typ := range p.Types {
+		if _, ok := typ.(*prog.StructType); ok {
+			structs = append(structs, typ.Name())
+		}
+	}
+	sort.Strings(calls)
+	sort.Strings(resources)
+	sort.Strings(structs)
+	wantCalls := []string{"foo$ioctl", "foo$mydrv.fd", "foo$mydrv.ioctl", "foo$mydrv.open",
+		"foo$mydrv.start", "foo$open", "foo$user.use"}
+	if diff := cmp.Diff(wantCalls, calls); diff != "" {
+		t.Errorf("syscalls: %v", diff)
+	}
+	if diff := cmp.Diff([]string{"mydrv.dev", "mydrv.s0", "s0"}, useArgs); diff != "" {
+		t.Errorf("foo$user.use args: %v", diff)
+	}
+	if diff := cmp.Diff([]string{"fd", "mydrv.dev"}, resources); diff != "" {
+		t.Errorf("resources: %v", diff)
+	}
+	if diff := cmp.Diff([]string{"mydrv.s0", "s0"}, structs); diff != "" {
+		t.Errorf("structs: %v", diff)
+	}
+}
+
+func TestPackagesErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		files map[string]string
+		err   string
+	}{
+		{
+			files: map[string]string{"a.txt": "foo$a(a mydrv.fd)"},
+			err:   "package mydrv is not imported",
+		},
+		{
+			files: map[string]string{"a.txt": "import mydrv"},
+			err:   "unknown package mydrv",
+		},
+		{
+			files: map[string]string{
+				"a.txt": "package mydrv\nresource fd[int32]",
+				"b.txt": "import mydrv\nfoo$a(a mydrv.fd2)",
+			},
+			err: "fd2 is not declared in package mydrv",
+		},
+		{
+			files: map[string]string{"a.txt": "package mydrv\npackage other"},
+			err:   "duplicate package declaration, previously declared as mydrv",
+		},
+		{
+			files: map[string]string{"a.txt": "package mydrv\ntype ptr int8"},
+			err:   "type name ptr conflicts with builtin type",
+		},
+		{
+			files: map[string]string{"a.txt": "s.x {\n\tf int8\n}"},
+			err:   "struct name s.x contains '.'",
+		},
+	}
+	target := targets.List[targets.TestOS][targets.TestArch64]
+	for i, test := range tests {
+		var errors []string
+		eh := func(pos ast.Pos, msg string) {
+			errors = append(errors, msg)
+		}
+		desc := &ast.Description{}
+		for name, data := range test.files {
+			desc1 := ast.Parse([]byte(data), name, eh)
+			if desc1 == nil {
+				t.Fatalf("#%v: failed to parse %v: %v", i, name, errors)
+			}
+			desc.Nodes = append(desc.Nodes, desc1.Nodes...)
+		}
+		Compile(desc, map[string]uint64{"SYS_foo": 1}, target, eh)
+		found := false
+		for _, err := range errors {
+			if err == test.err {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("#%v: want error %q, got %q", i, test.err, errors)
+		}
+	}
+}
+
+func TestCallTemplates(t *testing.T) {
+	t.Parallel()
+	data := `
+resource fd[int32]
+resource fd_dev[fd]
+foo$open() fd_dev
+s0 {
+	f0	int8
+}
+template getset[FD, T, GET, SET] {
+	foo$get(fd FD, cmd const[GET], arg ptr[out, T])
+	foo$set(fd FD, cmd const[SET], arg ptr[in, T])
+	foo(fd FD)
+}
+instantiate dev = getset[fd_dev, s0, 1, 2]
+instantiate dev2 = getset[fd, int32, 3, 4]
+`
+	target := targets.List[targets.TestOS][targets.TestArch64]
+	eh := func(pos ast.Pos, msg string) {
+		t.Errorf("%v: %v", pos, msg)
+	}
+	desc := ast.Parse([]byte(data), "test.txt", eh)
+	if desc == nil {
+		t.Fatal("failed to parse")
+	}
+	p := Compile(desc, map[string]uint64{"SYS_foo": 1}, target, eh)
+	if p == nil {
+		t.Fatal("failed to compile")
+	}
+	calls := make(map[string]*prog.Syscall)
+	for _, call := range p.Syscalls {
+		if strings.HasPrefix(call.Name, "foo") {
+			calls[call.Name] = call
+		}
+	}
+	var names []string
+	for name := range calls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	want := []string{"foo$dev", "foo$dev2", "foo$dev2_get", "foo$dev2_set", "foo$dev_get", "foo$dev_set", "foo$open"}
+	if diff := cmp.Diff(want, names); diff != "" {
+		t.Fatal(diff)
+	}
+	for name, wantRes := range map[string]string{"foo$dev_get": "fd_dev", "foo$dev2_set": "fd"} {
+		typ := p.Types[calls[name].Args[0].Type.(prog.Ref)]
+		if res, ok := typ.(*prog.ResourceType); !ok || res.TypeName != wantRes {
+			t.Errorf("%v: bad first arg %v, want resource %v", name, typ.Name(), wantRes)
+		}
+	}
+	if cmd := p.Types[calls["foo$dev2_get"].Args[1].Type.(prog.Ref)].(*prog.ConstType); cmd.Val != 3 {
+		t.Errorf("foo$dev2_get: cmd %v, want 3", cmd.Val)
+	}
+}
+
+func TestCallTemplatesErrors(t *testing.T) {
+	t.Parallel()
+	const templ = "resource fd[int32]\ntemplate t[FD, T] {\n\tfoo$get(fd FD, arg ptr[out, T])\n}\n"
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{
+			data: templ,
+			err:  "unused template t",
+		},
+		{
+			data: templ + "instantiate a = t[fd]",
+			err:  "template t needs 2 arguments instead of 1",
+		},
+		{
+			data: templ + "instantiate a = t2[fd, int8]",
+			err:  "unknown call template t2",
+		},
+		{
+			data: templ + "instantiate a = t[fd, int8]\ntemplate t[FD] {\n\tfoo$x(fd FD)\n}",
+			err:  "template t redeclared, previously declared at test.txt:2:1",
+		},
+		{
+			data: "template t[FD, fd] {\n\tfoo(fd FD)\n}\ninstantiate a = t[int8, int8]",
+			err:  "template argument fd must be ALL_CAPS",
+		},
+		{
+			data: "template t[FD, FD] {\n\tfoo(fd FD)\n}\ninstantiate a = t[int8, int8]",
+			err:  "duplicate template argument FD",
+		},
+		{
+			data: "template t[FD] {\n\tfoo(fd FD)\n\tfoo(fd FD)\n}\ninstantiate a = t[int8]",
+			err:  "syscall foo redeclared in template t",
+		},
+		{
+			data: "template t[FD, T] {\n\tfoo(fd FD)\n}\ninstantiate a = t[int8, int8]",
+			err:  "template argument T is not used",
+		},
+		{
+			data: templ + "instantiate a = t[fd, int8]\nfoo$a_get(fd fd)",
+			err:  "syscall foo$a_get redeclared, previously declared at test.txt:5:1",
+		},
+	}
+	target := targets.List[targets.TestOS][targets.TestArch64]
+	for i, test := range tests {
+		var errors []string
+		eh := func(pos ast.Pos, msg string) {
+			errors = append(errors, msg)
+		}
+		desc := ast.Parse([]byte(test.data), "test.txt", eh)
+		if desc == nil {
+			t.Fatalf("#%v: failed to parse: %v", i, errors)
+		}
+		Compile(desc, map[string]uint64{"SYS_foo": 1}, target, eh)
+		found := false
+		for _, err := range errors {
+			if err == test.err {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("#%v: want error %q, got %q", i, test.err, errors)
+		}
+	}
+}
+
+func TestVerifierAnnotations(t *testing.T) {
+	t.Parallel()
+	data := `
+foo$0()
+foo$1() (expect_errno[EFOO, 11])
+foo$2() (nondeterministic, expect_errno[EFOO])
+`
+	target := targets.List[targets.TestOS][targets.TestArch64]
+	eh := func(pos ast.Pos, msg string) {
+		t.Errorf("%v: %v", pos, msg)
+	}
+	desc := ast.Parse([]byte(data), "test.txt", eh)
+	if desc == nil {
+		t.Fatal("failed to parse")
+	}
+	p := Compile(desc, map[string]uint64{"SYS_foo": 1, "EFOO": 22}, target, eh)
+	if p == nil {
+		t.Fatal("failed to compile")
+	}
+	type annotations struct {
+		ExpectErrnos     []uint64
+		Nondeterministic bool
+	}
+	want := map[string]annotations{
+		"foo$0": {},
+		"foo$1": {ExpectErrnos: []uint64{22, 11}},
+		"foo$2": {ExpectErrnos: []uint64{22}, Nondeterministic: true},
+	}
+	got := make(map[string]annotations)
+	for _, call := range p.Syscalls {
+		if strings.HasPrefix(call.Name, "foo$") {
+			got[call.Name] = annotations{call.ExpectErrnos, call.Nondeterministic}
+		}
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	t.Parallel()
+	data := `
+foo$0(a int33, b flags[foo_flagz]) (disabledd)
+foo_flags = 1, 2
+`
+	target := targets.List[targets.TestOS][targets.TestArch64]
+	desc := ast.Parse([]byte(data), "test.txt", nil)
+	if desc == nil {
+		t.Fatal("failed to parse")
+	}
+	var diags []*Diagnostic
+	p := CompileDiagnostics(desc, map[string]uint64{"SYS_foo": 1}, target, func(diag *Diagnostic) {
+		diags = append(diags, diag)
+	})
+	if p != nil {
+		t.Fatal("compilation succeed")
+	}
+	want := []*Diagnostic{
+		{
+			File:       "test.txt",
+			Line:       2,
+			Col:        9,
+			Severity:   SeverityError,
+			Code:       "unknown-type",
+			Message:    "unknown type int33",
+			Suggestion: "did you mean int32?",
+		},
+		{
+			File:       "test.txt",
+			Line:       2,
+			Col:        24,
+			Severity:   SeverityError,
+			Code:       "unknown-flags",
+			Message:    "unknown flags foo_flagz",
+			Suggestion: "did you mean foo_flags?",
+		},
+		{
+			File:       "test.txt",
+			Line:       2,
+			Col:        37,
+			Severity:   SeverityError,
+			Code:       "unknown-attribute",
+			Message:    "unknown syscall foo$0 attribute disabledd",
+			Suggestion: "did you mean disabled?",
+		},
+	}
+	if diff := cmp.Diff(want, diags); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+// TestDiagnosticCodes checks that all errors and warnings have explicit codes in diagCodes
+// and that the codes don't change.
+func TestDiagnosticCodes(t *testing.T) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	used := make(map[string]bool)
+	for _, file := range pkgs["compiler"].Files {
+		goast.Inspect(file, func(n goast.Node) bool {
+			if fn, ok := n.(*goast.FuncDecl); ok {
+				// Skip the reporting functions themselves.
+				return fn.Name.Name != "error" && fn.Name.Name != "errorSuggest" && fn.Name.Name != "warning"
+			}
+			call, ok := n.(*goast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*goast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			arg := 0
+			switch sel.Sel.Name {
+			case "error", "warning":
+				arg = 1
+			case "errorSuggest":
+				arg = 2
+			default:
+				return true
+			}
+			format, ok := stringLit(call.Args[arg])
+			if !ok {
+				t.Errorf("%v: message format is not a string literal", fset.Position(call.Pos()))
+				return true
+			}
+			used[format] = true
+			if code := diagCode(format); code == codeOther {
+				t.Errorf("%v: message %q has no code in diagCodes", fset.Position(call.Pos()), format)
+			}
+			return true
+		})
+	}
+	codes := make(map[string]bool)
+	formats := make(map[string]bool)
+	for _, dc := range diagCodes {
+		if formats[dc.format] {
+			t.Errorf("duplicate message %q in diagCodes", dc.format)
+		}
+		formats[dc.format] = true
+		if !used[dc.format] {
+			t.Errorf("message %q in diagCodes is not used, keep its code for the reworded message", dc.format)
+		}
+		codes[dc.code] = true
+	}
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "diagnostic_codes.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if !codes[line] {
+			t.Errorf("code %v is removed from diagCodes", line)
+		}
+		delete(codes, line)
+	}
+	for code := range codes {
+		t.Errorf("new code %v must be added to testdata/diagnostic_codes.txt", code)
+	}
+}
+
+// stringLit returns the value of a string literal or a concatenation of string literals.
+func stringLit(expr goast.Expr) (string, bool) {
+	switch expr := expr.(type) {
+	case *goast.BasicLit:
+		val, err := strconv.Unquote(expr.Value)
+		return val, err == nil
+	case *goast.BinaryExpr:
+		x, ok1 := stringLit(expr.X)
+		y, ok2 := stringLit(expr.Y)
+		return x + y, ok1 && ok2 && expr.Op == token.ADD
+	}
+	return "", false
+}
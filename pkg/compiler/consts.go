@@ -76,6 +76,13 @@ func (comp *compiler) extractConsts() map[string]*ConstInfo {
 				if callAttrs[attr.Ident].HasArg {
 					comp.addConst(infos, attr.Pos, attr.Args[0].Ident)
 				}
+				if callAttrs[attr.Ident].ConstArgs {
+					for _, arg := range attr.Args {
+						if arg.Ident != "" {
+							comp.addConst(infos, arg.Pos, arg.Ident)
+						}
+					}
+				}
 			}
 		case *ast.Struct:
 			for _, attr := range n.Attrs {
@@ -226,6 +233,11 @@ func (comp *compiler) patchConsts(consts0 map[string]uint64) {
 					if callAttrs[attr.Ident].HasArg {
 						comp.patchTypeConst(attr.Args[0], consts, &missing)
 					}
+					if callAttrs[attr.Ident].ConstArgs {
+						for _, arg := range attr.Args {
+							comp.patchTypeConst(arg, consts, &missing)
+						}
+					}
 				}
 			case *ast.Struct:
 				for _, attr := range n.Attrs {
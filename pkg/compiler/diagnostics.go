@@ -0,0 +1,319 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package compiler
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/syzkaller/pkg/ast"
+	"github.com/google/syzkaller/sys/targets"
+)
+
+// Diagnostic is a machine-readable compiler error or warning
+// (e.g. for integration with editors and CI).
+type Diagnostic struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Col      int      `json:"col"`
+	Severity Severity `json:"severity"`
+	// Code is a stable identifier of the kind of the problem (e.g. "unknown-type").
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Suggestion is an optional hint on how to fix the problem.
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// CodeSyntax is the code of parsing errors (see SyntaxDiagnostic).
+const CodeSyntax = "syntax"
+
+type DiagnosticHandler func(diag *Diagnostic)
+
+func (diag *Diagnostic) Pos() ast.Pos {
+	return ast.Pos{File: diag.File, Line: diag.Line, Col: diag.Col}
+}
+
+func (diag *Diagnostic) String() string {
+	res := fmt.Sprintf("%v: %v: %v [%v]", diag.Pos(), diag.Severity, diag.Message, diag.Code)
+	if diag.Suggestion != "" {
+		res += " (" + diag.Suggestion + ")"
+	}
+	return res
+}
+
+// SyntaxDiagnostic converts an error reported by the parser (see ast.ErrorHandler) to a diagnostic.
+func SyntaxDiagnostic(pos ast.Pos, msg string) *Diagnostic {
+	return makeDiagnostic(pos, SeverityError, CodeSyntax, msg, "")
+}
+
+// CompileDiagnostics is the same as Compile, but reports errors and warnings as structured diagnostics.
+func CompileDiagnostics(desc *ast.Description, consts map[string]uint64, target *targets.Target,
+	dh DiagnosticHandler) *Prog {
+	return compile(desc, consts, target, nil, dh)
+}
+
+func makeDiagnostic(pos ast.Pos, severity Severity, code, msg, suggestion string) *Diagnostic {
+	return &Diagnostic{
+		File:       pos.File,
+		Line:       pos.Line,
+		Col:        pos.Col,
+		Severity:   severity,
+		Code:       code,
+		Message:    msg,
+		Suggestion: suggestion,
+	}
+}
+
+func (comp *compiler) report(diag *Diagnostic) {
+	if comp.dh != nil {
+		comp.dh(diag)
+		return
+	}
+	comp.eh(diag.Pos(), diag.Message)
+}
+
+// diagCode returns diagnostic code for the message format string.
+func diagCode(format string) string {
+	for _, dc := range diagCodes {
+		if dc.format == format {
+			return dc.code
+		}
+	}
+	return codeOther
+}
+
+// codeOther is the code of messages missing in diagCodes (TestDiagnosticCodes ensures there are none).
+const codeOther = "other"
+
+// diagCodes lists message format strings of all compiler errors and warnings with their diagnostic codes.
+// The codes are stable: if a message is reworded, the new format must keep the code of the old one
+// (the list of codes is fixed in testdata/diagnostic_codes.txt).
+var diagCodes = []struct {
+	format string
+	code   string
+}{
+	{"%v %v has no fields, need at least 1 field", "has-no-fields-need-at-least-1-field"},
+	{"%v %v=0x%x doesn't fit into %v bits", "0x%x-doesn-t-fit-into-bits"},
+	{"%v arg %v is larger than pointer size", "arg-is-larger-than-pointer-size"},
+	{"%v arg %v is redeclared with size %v, previously declared with size %v at %v",
+		"arg-is-redeclared-with-size-previously-declared-with-size-at"},
+	{"%v argument has subargs", "argument-has-subargs"},
+	{"%v attribute has args", "attribute-has-args"},
+	{"%v attribute has colon or args", "attribute-has-colon-or-args"},
+	{"%v attribute is expected to have 1 argument", "attribute-is-expected-to-have-1-argument"},
+	{"%v attribute is expected to have const arguments", "attribute-is-expected-to-have-const-arguments"},
+	{"%v attribute is expected to have syscall name arguments", "attribute-is-expected-to-have-syscall-name-arguments"},
+	{"%v attribute must not be specified on the first field", "attribute-must-not-be-specified-on-the-first-field"},
+	{"%v attribute refers to unknown syscall %v", "attribute-refers-to-unknown-syscall"},
+	{"%v can't be marked as opt", "can-t-be-marked-as-opt"},
+	{"%v can't be part of path expressions", "can-t-be-part-of-path-expressions"},
+	{"%v can't be resource base (int types can)", "can-t-be-resource-base-int-types-can"},
+	{"%v can't be syscall argument", "can-t-be-syscall-argument"},
+	{"%v can't be syscall return", "can-t-be-syscall-return"},
+	{"%v can't be type alias target", "can-t-be-type-alias-target"},
+	{"%v has multiple direction attributes", "has-multiple-direction-attributes"},
+	{"%v is not declared in package %v", "is-not-declared-in-package"},
+	{"%v must refer to fields", "must-refer-to-fields"},
+	{"%v name %v conflicts with builtin type", "name-conflicts-with-builtin-type"},
+	{"%v name %v contains '.'", "name-contains"},
+	{"%v path %v does not refer to a struct", "path-does-not-refer-to-a-struct"},
+	{"%v target %v does not exist", "target-does-not-exist"},
+	{"%v target %v refers to itself", "target-refers-to-itself"},
+	{"%v type must not be used as output", "type-must-not-be-used-as-output"},
+	{"%v uses reserved name %v", "uses-reserved-name"},
+	{"all %v values are equal %v", "all-values-are-equal"},
+	{"arrays of size 0 are not supported", "arrays-of-size-0-are-not-supported"},
+	{"bad fmt value %v, expect an integer", "bad-fmt-value-expect-an-integer"},
+	{"bad instance name %v", "bad-instance-name"},
+	{"bad int alignment %v", "bad-int-alignment"},
+	{"bad int range [%v:%v]", "bad-int-range"},
+	{"bad package name %v", "bad-package-name"},
+	{"bad size range [%v:%v]", "bad-size-range"},
+	{"bad struct %v alignment %v (must be a sane power of 2)", "bad-struct-alignment-must-be-a-sane-power-of-2"},
+	{"bitfield of size %v is too large for base type of size %v",
+		"bitfield-of-size-is-too-large-for-base-type-of-size"},
+	{"bitfields of size 0 are not supported", "bitfields-of-size-0-are-not-supported"},
+	{"both template parameter %v and its usage have sub-arguments",
+		"both-template-parameter-and-its-usage-have-sub-arguments"},
+	{"call %v: duplicate const %v, previously used in call %v at %v",
+		"call-duplicate-const-previously-used-in-call-at"},
+	{"call ordering cycle %v", "call-ordering-cycle"},
+	{"confusing comment faking a directive (rephrase if it's intentional)",
+		"confusing-comment-faking-a-directive-rephrase-if-it-s-intentional"},
+	{"const val 0x%x does not fit into %v bits", "const-val-0x%x-does-not-fit-into-bits"},
+	{"duplicate %v %v attribute %v", "duplicate-attribute"},
+	{"duplicate %v %v in %v", "duplicate-in"},
+	{"duplicate define %v", "duplicate-define"},
+	{"duplicate import %v", "duplicate-import"},
+	{"duplicate incdir %q", "duplicate-incdir"},
+	{"duplicate include %q", "duplicate-include"},
+	{"duplicate package declaration, previously declared as %v",
+		"duplicate-package-declaration-previously-declared-as"},
+	{"duplicate template argument %v", "duplicate-template-argument"},
+	{"duplicate type argument %v", "duplicate-type-argument"},
+	{"first argument of %v needs to be a range", "first-argument-of-needs-to-be-a-range"},
+	{"fixed-size string can't be non-zero-terminated", "fixed-size-string-can-t-be-non-zero-terminated"},
+	{"flags %v redeclared, previously declared at %v", "flags-redeclared-previously-declared-at"},
+	{"flags uses reserved name %v", "flags-uses-reserved-name"},
+	{"glob only accepts 1 arg, provided %v", "glob-only-accepts-1-arg-provided"},
+	{"int alignment %v is too large for range [%v:%v]", "int-alignment-is-too-large-for-range"},
+	{"int range [%v:%v] is too large for base type of size %v", "int-range-is-too-large-for-base-type-of-size"},
+	{"len target %v refer to an array with variable-size elements (do you mean bytesize?)",
+		"len-target-refer-to-an-array-with-variable-size-elements-do-you-mean-bytesize"},
+	{"literal const bitfield sizes are not supported", "literal-const-bitfield-sizes-are-not-supported"},
+	{"mix of direction and %v attributes is not supported", "mix-of-direction-and-attributes-is-not-supported"},
+	{"multiple %v attributes", "multiple-attributes"},
+	{"no argument name after syscall reference", "no-argument-name-after-syscall-reference"},
+	{"only pseudo csum can have proto", "only-pseudo-csum-can-have-proto"},
+	{"opt can't have arguments", "opt-can-t-have-arguments"},
+	{"package %v imports itself", "package-imports-itself"},
+	{"package %v is not imported", "package-is-not-imported"},
+	{"path expressions are not implemented for csum", "path-expressions-are-not-implemented-for-csum"},
+	{"proc per-process values must not be 0", "proc-per-process-values-must-not-be-0"},
+	{"recursive declaration: %v (mark some pointers as opt)", "recursive-declaration-mark-some-pointers-as-opt"},
+	{"recursive resource %v", "recursive-resource"},
+	{"redefining builtin const %v", "redefining-builtin-const"},
+	{"reserved %v name %v in %v", "reserved-name-in"},
+	{"resource %v can't be created (never mentioned as a syscall return value or output argument/field)",
+		"resource-can-t-be-created-never-mentioned-as-a-syscall-return-value-or-output-argument-field"},
+	{"resource %v is never used as an input (such resources are not useful)",
+		"resource-is-never-used-as-an-input-such-resources-are-not-useful"},
+	{"size attribute has bad value %v, expect [1, 1<<20]", "size-attribute-has-bad-value-expect-1-1-20"},
+	{"string flags %v redeclared, previously declared at %v", "string-flags-redeclared-previously-declared-at"},
+	{"string flags uses reserved name %v", "string-flags-uses-reserved-name"},
+	{"string value %q exceeds buffer length %v", "string-value-exceeds-buffer-length"},
+	{"struct %v has size attribute %v which is less than struct size %v",
+		"struct-has-size-attribute-which-is-less-than-struct-size"},
+	{"syscall %v can't be after itself", "syscall-can-t-be-after-itself"},
+	{"syscall %v has %v arguments, allowed maximum is %v", "syscall-has-arguments-allowed-maximum-is"},
+	{"syscall %v redeclared in template %v", "syscall-redeclared-in-template"},
+	{"syscall %v redeclared, previously declared at %v", "syscall-redeclared-previously-declared-at"},
+	{"syscall can't be in the middle of path expressions", "syscall-can-t-be-in-the-middle-of-path-expressions"},
+	{"template %v does not contain any syscalls", "template-does-not-contain-any-syscalls"},
+	{"template %v needs %v arguments instead of %v", "template-needs-arguments-instead-of"},
+	{"template %v redeclared, previously declared at %v", "template-redeclared-previously-declared-at"},
+	{"template argument %v is not used", "template-argument-is-not-used"},
+	{"template argument %v must be ALL_CAPS", "template-argument-must-be-all_caps"},
+	{"type %v is not a template", "type-is-not-a-template"},
+	{"type %v redeclared, previously declared as %v at %v", "type-redeclared-previously-declared-as-at"},
+	{"type %v redeclared, previously declared as resource at %v", "type-redeclared-previously-declared-as-resource-at"},
+	{"type %v redeclared, previously declared as type alias at %v",
+		"type-redeclared-previously-declared-as-type-alias-at"},
+	{"type alias %v with ':'", "type-alias-with"},
+	{"type argument %v must be ALL_CAPS", "type-argument-must-be-all_caps"},
+	{"type argument BASE must be the last argument", "type-argument-base-must-be-the-last-argument"},
+	{"type instantiation loop: %v", "type-instantiation-loop"},
+	{"type instantiation recursion: %v", "type-instantiation-recursion"},
+	{"unexpected %v after colon, expect %v", "unexpected-after-colon-expect"},
+	{"unexpected %v for %v argument of %v type, expect %+v", "unexpected-for-argument-of-type-expect-v"},
+	{"unexpected %v for %v argument of %v type, expect %v", "unexpected-for-argument-of-type-expect"},
+	{"unexpected %v, expect attribute", "unexpected-expect-attribute"},
+	{"unexpected %v, expect int", "unexpected-expect-int"},
+	{"unexpected %v, expect syscall name", "unexpected-expect-syscall-name"},
+	{"unexpected %v, expect type", "unexpected-expect-type"},
+	{"unexpected ':'", "unexpected"},
+	{"unexpected ':', only struct fields can be bitfields", "unexpected-only-struct-fields-can-be-bitfields"},
+	{"unexpected int %v, string arg must be a string literal or string flags",
+		"unexpected-int-string-arg-must-be-a-string-literal-or-string-flags"},
+	{"unexpected value %v for %v argument of %v type, expect %+v", "unexpected-value-for-argument-of-type-expect-v"},
+	{"union %v has size attribute %v which is less than field %v size %v",
+		"union-has-size-attribute-which-is-less-than-field-size"},
+	{"unknown %v %v attribute %v", "unknown-attribute"},
+	{"unknown call template %v", "unknown-call-template"},
+	{"unknown flags %v", "unknown-flags"},
+	{"unknown package %v", "unknown-package"},
+	{"unknown string flags %v", "unknown-string-flags"},
+	{"unknown type %v", "unknown-type"},
+	{"unsupported %v: %v due to missing const %v", "unsupported-due-to-missing-const"},
+	{"unsupported syscall: %v due to missing const %v", "unsupported-syscall-due-to-missing-const"},
+	{"unused %v %v", "unused"},
+	{"unused template %v", "unused-template"},
+	{"values starting from %v overflow base type", "values-starting-from-overflow-base-type"},
+	{"values starting from %v with step %v overflow base type for %v procs",
+		"values-starting-from-with-step-overflow-base-type-for-procs"},
+	{"variable size field %v in non-varlen union %v", "variable-size-field-in-non-varlen-union"},
+	{"variable size field %v in the middle of non-packed struct %v",
+		"variable-size-field-in-the-middle-of-non-packed-struct"},
+	{"varlen %v %v has size attribute", "varlen-has-size-attribute"},
+	{"wrong number of arguments for type %v, expect %v", "wrong-number-of-arguments-for-type-expect"},
+}
+
+// suggestName returns a "did you mean" hint with the candidate name closest to name,
+// or an empty string if no candidate is close enough.
+func suggestName(name string, candidates []string) string {
+	best, bestDist := "", len(name)/3+2
+	sort.Strings(candidates)
+	for _, cand := range candidates {
+		if dist := editDistance(name, cand); dist != 0 && dist < bestDist {
+			best, bestDist = cand, dist
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf("did you mean %v?", best)
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// knownTypeNames returns names of all types that can be referenced in descriptions.
+func (comp *compiler) knownTypeNames() []string {
+	var names []string
+	for name := range builtinTypes {
+		names = append(names, name)
+	}
+	for name := range comp.resources {
+		names = append(names, name)
+	}
+	for name := range comp.structs {
+		names = append(names, name)
+	}
+	for name := range comp.typedefs {
+		names = append(names, name)
+	}
+	return names
+}
+
+func attrNames(descs map[string]*attrDesc) []string {
+	var names []string
+	for name := range descs {
+		names = append(names, name)
+	}
+	return names
+}
@@ -117,6 +117,9 @@ func (comp *compiler) genSyscall(n *ast.Call, argSizes []uint64) *prog.Syscall {
 	var attrs prog.SyscallAttrs
 	descAttrs := comp.parseAttrs(callAttrs, n, n.Attrs)
 	for desc, val := range descAttrs {
+		if desc.CallArgs || desc.ConstArgs || desc == attrNondeterministic {
+			continue
+		}
 		fld := reflect.ValueOf(&attrs).Elem().FieldByName(desc.Name)
 		if desc.HasArg {
 			fld.SetUint(val)
@@ -126,13 +129,17 @@ func (comp *compiler) genSyscall(n *ast.Call, argSizes []uint64) *prog.Syscall {
 	}
 	fields, _ := comp.genFieldArray(n.Args, argSizes)
 	return &prog.Syscall{
-		Name:        n.Name.Name,
-		CallName:    n.CallName,
-		NR:          n.NR,
-		MissingArgs: len(argSizes) - len(n.Args),
-		Args:        fields,
-		Ret:         ret,
-		Attrs:       attrs,
+		Name:             n.Name.Name,
+		CallName:         n.CallName,
+		NR:               n.NR,
+		MissingArgs:      len(argSizes) - len(n.Args),
+		Args:             fields,
+		Ret:              ret,
+		Attrs:            attrs,
+		After:            attrCallArgs(n, attrAfter),
+		NotAfter:         attrCallArgs(n, attrNotAfter),
+		ExpectErrnos:     attrConstArgs(n, attrExpectErrno),
+		Nondeterministic: descAttrs[attrNondeterministic] != 0,
 	}
 }
 
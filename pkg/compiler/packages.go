@@ -0,0 +1,248 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package compiler
+
+import (
+	"strings"
+
+	"github.com/google/syzkaller/pkg/ast"
+)
+
+// Descriptions can be split into packages to allow independently developed descriptions
+// (e.g. out-of-tree drivers) to coexist with upstream descriptions without name collisions.
+// A file with "package NAME" declaration belongs to package NAME: all resources, types,
+// flags and syscall variants declared in the file get "NAME." prefix (e.g. ioctl$FOO
+// becomes ioctl$NAME.FOO). Unqualified names used in files of the package refer to
+// declarations of the package, or to global (non-package) declarations if the package
+// does not declare such name. Declarations of other packages are referred to as PKG.name
+// and require "import PKG" in the file. Consts are not affected since they come from
+// kernel headers and are global.
+
+type pkgFile struct {
+	pkg     string
+	imports map[string]bool
+}
+
+type pkgResolver struct {
+	comp  *compiler
+	files map[string]*pkgFile
+	decls map[string]map[string]bool // package -> declared names
+	calls map[string]map[string]bool // package -> declared syscalls
+}
+
+func (comp *compiler) resolvePackages() {
+	r := &pkgResolver{
+		comp:  comp,
+		files: make(map[string]*pkgFile),
+		decls: make(map[string]map[string]bool),
+		calls: make(map[string]map[string]bool),
+	}
+	r.collectPackages()
+	r.collectDecls()
+	r.checkImports()
+	if comp.errors != 0 {
+		return
+	}
+	for _, decl := range comp.desc.Nodes {
+		r.resolveDecl(decl)
+	}
+}
+
+func (r *pkgResolver) file(pos ast.Pos) *pkgFile {
+	f := r.files[pos.File]
+	if f == nil {
+		f = &pkgFile{imports: make(map[string]bool)}
+		r.files[pos.File] = f
+	}
+	return f
+}
+
+func (r *pkgResolver) collectPackages() {
+	for _, decl := range r.comp.desc.Nodes {
+		n, ok := decl.(*ast.Package)
+		if !ok {
+			continue
+		}
+		f := r.file(n.Pos)
+		if f.pkg != "" {
+			r.comp.error(n.Pos, "duplicate package declaration, previously declared as %v", f.pkg)
+			continue
+		}
+		if n.Pos.File == ast.BuiltinFile || strings.IndexByte(n.Name.Name, '.') != -1 {
+			r.comp.error(n.Pos, "bad package name %v", n.Name.Name)
+			continue
+		}
+		f.pkg = n.Name.Name
+		if r.decls[f.pkg] == nil {
+			r.decls[f.pkg] = make(map[string]bool)
+			r.calls[f.pkg] = make(map[string]bool)
+		}
+	}
+}
+
+func (r *pkgResolver) collectDecls() {
+	for _, decl := range r.comp.desc.Nodes {
+		pos, typ, name := decl.Info()
+		f := r.file(pos)
+		switch n := decl.(type) {
+		case *ast.Resource, *ast.Struct, *ast.TypeDef:
+			// Otherwise package declarations would shadow builtin types in the package.
+			if f.pkg != "" && (builtinTypes[name] != nil || reservedName[name]) {
+				r.comp.error(pos, "%v name %v conflicts with builtin type", typ, name)
+				continue
+			}
+		case *ast.IntFlags, *ast.StrFlags:
+		case *ast.Call:
+			// Syscall variants are renamed, but they are not types and can't be referenced.
+			if strings.IndexByte(name[len(n.CallName):], '.') != -1 {
+				r.comp.error(pos, "%v name %v contains '.'", typ, name)
+			} else if f.pkg != "" {
+				r.calls[f.pkg][name] = true
+			}
+			continue
+		default:
+			continue
+		}
+		if strings.IndexByte(name, '.') != -1 {
+			r.comp.error(pos, "%v name %v contains '.'", typ, name)
+			continue
+		}
+		if f.pkg != "" {
+			r.decls[f.pkg][name] = true
+		}
+	}
+}
+
+func (r *pkgResolver) checkImports() {
+	for _, decl := range r.comp.desc.Nodes {
+		n, ok := decl.(*ast.Import)
+		if !ok {
+			continue
+		}
+		f := r.file(n.Pos)
+		name := n.Name.Name
+		switch {
+		case r.decls[name] == nil:
+			r.comp.error(n.Pos, "unknown package %v", name)
+		case name == f.pkg:
+			r.comp.error(n.Pos, "package %v imports itself", name)
+		case f.imports[name]:
+			r.comp.error(n.Pos, "duplicate import %v", name)
+		}
+		f.imports[name] = true
+	}
+}
+
+func (r *pkgResolver) resolveDecl(decl ast.Node) {
+	pos, _, _ := decl.Info()
+	f := r.file(pos)
+	prefix := ""
+	if f.pkg != "" {
+		prefix = f.pkg + "."
+	}
+	switch n := decl.(type) {
+	case *ast.Resource:
+		n.Name.Name = prefix + n.Name.Name
+		r.resolveType(f, n.Base, nil)
+	case *ast.Struct:
+		n.Name.Name = prefix + n.Name.Name
+		r.resolveFields(f, n.Fields, nil)
+	case *ast.TypeDef:
+		n.Name.Name = prefix + n.Name.Name
+		params := make(map[string]bool)
+		for _, arg := range n.Args {
+			params[arg.Name] = true
+		}
+		if n.Type != nil {
+			r.resolveType(f, n.Type, params)
+		}
+		if n.Struct != nil {
+			r.resolveFields(f, n.Struct.Fields, params)
+		}
+	case *ast.IntFlags:
+		n.Name.Name = prefix + n.Name.Name
+	case *ast.StrFlags:
+		n.Name.Name = prefix + n.Name.Name
+	case *ast.Call:
+		// Syscalls without variant name refer to the actual kernel syscalls and can't be renamed.
+		if n.Name.Name != n.CallName {
+			n.Name.Name = n.CallName + "$" + prefix + n.Name.Name[len(n.CallName)+1:]
+		}
+		for _, attr := range n.Attrs {
+			if desc := callAttrs[attr.Ident]; desc != nil && desc.CallArgs {
+				for _, arg := range attr.Args {
+					arg.Ident = r.resolveCallName(f, arg.Pos, arg.Ident)
+				}
+			}
+		}
+		r.resolveFields(f, n.Args, nil)
+		if n.Ret != nil {
+			r.resolveType(f, n.Ret, nil)
+		}
+	}
+}
+
+func (r *pkgResolver) resolveFields(f *pkgFile, fields []*ast.Field, params map[string]bool) {
+	for _, fld := range fields {
+		r.resolveType(f, fld.Type, params)
+	}
+}
+
+func (r *pkgResolver) resolveType(f *pkgFile, t *ast.Type, params map[string]bool) {
+	if t.Ident != "" && !params[t.Ident] {
+		t.Ident = r.resolveName(f, t.Pos, t.Ident)
+	}
+	desc := builtinTypes[t.Ident]
+	for i, arg := range t.Args {
+		if desc != nil && i < len(desc.Args) {
+			// Len targets refer to fields rather than types, and int/string args are consts.
+			argType := desc.Args[i].Type
+			if argType == typeArgLenTarget || argType.Kind == kindInt || argType.Kind == kindString {
+				continue
+			}
+		}
+		r.resolveType(f, arg, params)
+	}
+}
+
+func (r *pkgResolver) resolveName(f *pkgFile, pos ast.Pos, name string) string {
+	dot := strings.IndexByte(name, '.')
+	if dot == -1 {
+		if f.pkg != "" && r.decls[f.pkg][name] {
+			return f.pkg + "." + name
+		}
+		return name
+	}
+	pkg, ident := name[:dot], name[dot+1:]
+	if pkg != f.pkg && !f.imports[pkg] {
+		r.comp.error(pos, "package %v is not imported", pkg)
+	} else if !r.decls[pkg][ident] {
+		r.comp.error(pos, "%v is not declared in package %v", ident, pkg)
+	}
+	return name
+}
+
+// resolveCallName resolves syscall names used in call attributes (e.g. after[ioctl$INIT]),
+// qualified names have the package prefix in the variant part (e.g. ioctl$mydrv.INIT).
+func (r *pkgResolver) resolveCallName(f *pkgFile, pos ast.Pos, name string) string {
+	dollar := strings.IndexByte(name, '$')
+	if dollar == -1 {
+		return name
+	}
+	variant := name[dollar+1:]
+	dot := strings.IndexByte(variant, '.')
+	if dot == -1 {
+		if f.pkg != "" && r.calls[f.pkg][name] {
+			return name[:dollar+1] + f.pkg + "." + variant
+		}
+		return name
+	}
+	pkg := variant[:dot]
+	if pkg != f.pkg && !f.imports[pkg] {
+		r.comp.error(pos, "package %v is not imported", pkg)
+	} else if !r.calls[pkg][name[:dollar+1]+variant[dot+1:]] {
+		r.comp.error(pos, "%v is not declared in package %v", name[:dollar+1]+variant[dot+1:], pkg)
+	}
+	return name
+}
@@ -0,0 +1,126 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package compiler
+
+import (
+	"strings"
+
+	"github.com/google/syzkaller/pkg/ast"
+)
+
+// Call templates describe families of nearly identical syscalls once, e.g. get/set ioctl pairs
+// that many drivers have and that differ only in the fd resource, command consts and struct type:
+//
+//	template ioctl_getset[FD, CONFIG, GET, SET] {
+//		ioctl$get(fd FD, cmd const[GET], arg ptr[out, CONFIG])
+//		ioctl$set(fd FD, cmd const[SET], arg ptr[in, CONFIG])
+//	}
+//
+//	instantiate foo = ioctl_getset[fd_foo, foo_config, FOO_GET_CONFIG, FOO_SET_CONFIG]
+//
+// Instantiation substitutes template arguments the same way type templates do and
+// produces ordinary syscalls named after the instance: ioctl$foo_get and ioctl$foo_set
+// (a template call without variant, e.g. ioctl(...), becomes ioctl$foo).
+// Expansion happens before all other checks, so the produced syscalls are
+// indistinguishable from manually written ones. They belong to the file of the instance
+// (for the purposes of packages and const extraction).
+
+func (comp *compiler) expandCallTemplates() {
+	templates := make(map[string]*ast.CallTemplate)
+	broken := make(map[string]bool)
+	for _, decl := range comp.desc.Nodes {
+		n, ok := decl.(*ast.CallTemplate)
+		if !ok {
+			continue
+		}
+		name := n.Name.Name
+		if prev := templates[name]; prev != nil {
+			comp.error(n.Pos, "template %v redeclared, previously declared at %v", name, prev.Pos)
+			continue
+		}
+		templates[name] = n
+		err0 := comp.errors
+		comp.checkCallTemplate(n)
+		if err0 != comp.errors {
+			broken[name] = true
+		}
+	}
+	used := make(map[string]bool)
+	var nodes []ast.Node
+	for _, decl := range comp.desc.Nodes {
+		switch n := decl.(type) {
+		case *ast.CallTemplate:
+		case *ast.CallInstance:
+			templ := templates[n.Template.Name]
+			if templ == nil {
+				comp.error(n.Template.Pos, "unknown call template %v", n.Template.Name)
+				continue
+			}
+			used[templ.Name.Name] = true
+			if broken[templ.Name.Name] {
+				continue
+			}
+			nodes = append(nodes, comp.instantiateCalls(n, templ)...)
+		default:
+			nodes = append(nodes, decl)
+		}
+	}
+	for name, templ := range templates {
+		if !used[name] {
+			comp.error(templ.Pos, "unused template %v", name)
+		}
+	}
+	comp.desc.Nodes = nodes
+}
+
+func (comp *compiler) checkCallTemplate(n *ast.CallTemplate) {
+	names := make(map[string]bool)
+	for _, arg := range n.Args {
+		if names[arg.Name] {
+			comp.error(arg.Pos, "duplicate template argument %v", arg.Name)
+		}
+		names[arg.Name] = true
+		if strings.ToUpper(arg.Name) != arg.Name {
+			comp.error(arg.Pos, "template argument %v must be ALL_CAPS", arg.Name)
+		}
+	}
+	if len(n.Calls) == 0 {
+		comp.error(n.Pos, "template %v does not contain any syscalls", n.Name.Name)
+	}
+	variants := make(map[string]bool)
+	for _, c := range n.Calls {
+		if variants[c.Name.Name] {
+			comp.error(c.Pos, "syscall %v redeclared in template %v", c.Name.Name, n.Name.Name)
+		}
+		variants[c.Name.Name] = true
+	}
+}
+
+func (comp *compiler) instantiateCalls(n *ast.CallInstance, templ *ast.CallTemplate) []ast.Node {
+	if strings.IndexByte(n.Name.Name, '$') != -1 {
+		comp.error(n.Name.Pos, "bad instance name %v", n.Name.Name)
+		return nil
+	}
+	if len(n.Args) != len(templ.Args) {
+		comp.error(n.Pos, "template %v needs %v arguments instead of %v",
+			templ.Name.Name, len(templ.Args), len(n.Args))
+		return nil
+	}
+	inst := templ.Clone().(*ast.CallTemplate)
+	if !comp.instantiate(inst, inst.Args, n.Args) {
+		return nil
+	}
+	var calls []ast.Node
+	for _, c := range inst.Calls {
+		variant := n.Name.Name
+		if pos := strings.IndexByte(c.Name.Name, '$'); pos != -1 {
+			variant += "_" + c.Name.Name[pos+1:]
+		}
+		c.Pos = n.Pos
+		c.Name.Pos = n.Pos
+		c.Name.Name = c.CallName + "$" + variant
+		calls = append(calls, c)
+	}
+	return calls
+}
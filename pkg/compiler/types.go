@@ -438,7 +438,11 @@ var typeArgFlags = &typeArg{
 	Kind: kindIdent,
 	Check: func(comp *compiler, t *ast.Type) {
 		if comp.intFlags[t.Ident] == nil {
-			comp.error(t.Pos, "unknown flags %v", t.Ident)
+			var names []string
+			for name := range comp.intFlags {
+				names = append(names, name)
+			}
+			comp.errorSuggest(t.Pos, suggestName(t.Ident, names), "unknown flags %v", t.Ident)
 			return
 		}
 	},
@@ -752,7 +756,11 @@ var typeArgStringFlags = &typeArg{
 			return
 		}
 		if t.Ident != "" && comp.strFlags[t.Ident] == nil {
-			comp.error(t.Pos, "unknown string flags %v", t.Ident)
+			var names []string
+			for name := range comp.strFlags {
+				names = append(names, name)
+			}
+			comp.errorSuggest(t.Pos, suggestName(t.Ident, names), "unknown string flags %v", t.Ident)
 			return
 		}
 	},
@@ -51,6 +51,8 @@ type Frame struct {
 	PC     uint64
 	Name   string
 	Path   string
+	Func   string // function name from debug info (for inlined frames, the inlined function)
+	Inline bool   // the frame is inlined into the next frame with the same PC
 	Range
 }
 
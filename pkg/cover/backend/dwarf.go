@@ -376,6 +376,8 @@ func symbolizeModule(target *targets.Target, objDir, srcDir, buildDir string,
 				PC:     frame.PC + mod.Addr,
 				Name:   name,
 				Path:   path,
+				Func:   frame.Func,
+				Inline: frame.Inline,
 				Range: Range{
 					StartLine: frame.Line,
 					StartCol:  0,
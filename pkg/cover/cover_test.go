@@ -118,3 +118,54 @@ func TestPerLineCoverage(t *testing.T) {
 		t.Fatal(diff)
 	}
 }
+
+func TestInlineFrames(t *testing.T) {
+	mod := &backend.Module{Name: ""}
+	unit := &backend.CompileUnit{
+		ObjectUnit: backend.ObjectUnit{Name: "a.c", PCs: []uint64{0x10, 0x14}},
+		Path:       "a.c",
+		Module:     mod,
+	}
+	line := func(n int) backend.Range {
+		return backend.Range{StartLine: n, EndLine: n, EndCol: backend.LineEnd}
+	}
+	rg := &ReportGenerator{
+		Impl: &backend.Impl{
+			Units: []*backend.CompileUnit{unit},
+			Symbols: []*backend.Symbol{{
+				ObjectUnit: backend.ObjectUnit{Name: "caller", PCs: unit.PCs},
+				Module:     mod,
+				Unit:       unit,
+				Start:      0x10,
+				End:        0x20,
+			}},
+			Symbolize: func(pcs map[*backend.Module][]uint64) ([]backend.Frame, error) {
+				return []backend.Frame{
+					{Module: mod, PC: 0x10, Name: "a.h", Path: "a.h", Func: "callee", Inline: true, Range: line(5)},
+					{Module: mod, PC: 0x10, Name: "a.c", Path: "a.c", Func: "caller", Range: line(20)},
+					{Module: mod, PC: 0x14, Name: "a.c", Path: "a.c", Func: "caller", Range: line(21)},
+				}, nil
+			},
+		},
+	}
+	files, err := rg.prepareFileMap([]Prog{{PCs: []uint64{0x10}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rg.Frames) != 3 {
+		t.Fatalf("inlined frames are dropped: %+v", rg.Frames)
+	}
+	// Both the inlined code and the call site are covered.
+	if len(files["a.h"].lines[5].progCount) == 0 || len(files["a.c"].lines[20].progCount) == 0 {
+		t.Fatalf("inlined code or call site are not covered")
+	}
+	if len(files["a.c"].lines[21].progCount) != 0 {
+		t.Fatalf("uncovered line is covered")
+	}
+	if into := files["a.h"].lines[5].inlinedInto; len(into) != 1 || !into["caller"] {
+		t.Fatalf("bad inlinedInto: %v", into)
+	}
+	if into := files["a.c"].lines[20].inlinedInto; len(into) != 0 {
+		t.Fatalf("call site is marked as inlined: %v", into)
+	}
+}
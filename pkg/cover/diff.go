@@ -0,0 +1,322 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package cover
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+)
+
+// diffContextLines is the number of unchanged lines shown around changed lines.
+const diffContextLines = 3
+
+type diffFile struct {
+	name     string
+	filename string
+	totalPCs int
+	basePCs  int
+	curPCs   int
+	newPCs   int
+	lostPCs  int
+	lines    map[int]*diffLine
+}
+
+// diffLine holds the number of covered PCs attributed to the line in the base and current coverage.
+type diffLine struct {
+	total int
+	base  int
+	cur   int
+}
+
+func (ln *diffLine) status() string {
+	switch {
+	case ln.cur != 0 && ln.base == 0:
+		return "new"
+	case ln.base != 0 && ln.cur == 0:
+		return "lost"
+	case ln.base != ln.cur:
+		return "changed"
+	case ln.cur != 0:
+		return "covered"
+	default:
+		return "uncovered"
+	}
+}
+
+// DoDiffHTML generates an HTML report that compares two coverage dumps (e.g. before/after
+// a kernel or corpus change): lines covered only in cur are marked as new, lines covered
+// only in base are marked as lost. Every changed line shows the number of covered PCs
+// in both dumps.
+func (rg *ReportGenerator) DoDiffHTML(w io.Writer, base, cur []uint64) error {
+	files, err := rg.prepareDiffFileMap(base, cur)
+	if err != nil {
+		return err
+	}
+	d := new(templateDiffData)
+	for _, f := range files {
+		d.BasePCs += f.basePCs
+		d.CurPCs += f.curPCs
+		d.NewPCs += f.newPCs
+		d.LostPCs += f.lostPCs
+		if f.newPCs == 0 && f.lostPCs == 0 {
+			continue
+		}
+		tf := &templateDiffFile{
+			Name:    f.name,
+			Total:   f.totalPCs,
+			BasePCs: f.basePCs,
+			CurPCs:  f.curPCs,
+			NewPCs:  f.newPCs,
+			LostPCs: f.lostPCs,
+		}
+		lines, err := parseFile(f.filename)
+		if err != nil {
+			tf.Error = err.Error()
+		} else {
+			tf.Lines = diffFileLines(f, lines)
+		}
+		d.Files = append(d.Files, tf)
+	}
+	sort.Slice(d.Files, func(i, j int) bool {
+		fi, fj := d.Files[i], d.Files[j]
+		if di, dj := fi.NewPCs+fi.LostPCs, fj.NewPCs+fj.LostPCs; di != dj {
+			return di > dj
+		}
+		return fi.Name < fj.Name
+	})
+	for i, f := range d.Files {
+		f.Index = i
+	}
+	return coverDiffTemplate.Execute(w, d)
+}
+
+func (rg *ReportGenerator) prepareDiffFileMap(base, cur []uint64) (map[string]*diffFile, error) {
+	progs := fixUpPCs(rg.target.Arch, []Prog{{PCs: base}, {PCs: cur}}, nil)
+	if err := rg.lazySymbolize(progs); err != nil {
+		return nil, err
+	}
+	basePCs, curPCs := make(map[uint64]bool), make(map[uint64]bool)
+	for _, pc := range progs[0].PCs {
+		basePCs[pc] = true
+	}
+	for _, pc := range progs[1].PCs {
+		curPCs[pc] = true
+	}
+	files := make(map[string]*diffFile)
+	getFile := func(name, path string) *diffFile {
+		f := files[name]
+		if f == nil {
+			f = &diffFile{
+				name:     name,
+				filename: path,
+				lines:    make(map[int]*diffLine),
+			}
+			files[name] = f
+		}
+		return f
+	}
+	for _, unit := range rg.Units {
+		f := getFile(unit.Name, unit.Path)
+		f.totalPCs = len(unit.PCs)
+		for _, pc := range unit.PCs {
+			inBase, inCur := basePCs[pc], curPCs[pc]
+			if inBase {
+				f.basePCs++
+			}
+			if inCur {
+				f.curPCs++
+			}
+			if inCur && !inBase {
+				f.newPCs++
+			}
+			if inBase && !inCur {
+				f.lostPCs++
+			}
+		}
+	}
+	matchedPC := false
+	for _, frame := range rg.Frames {
+		f := getFile(frame.Name, frame.Path)
+		ln := f.lines[frame.StartLine]
+		if ln == nil {
+			ln = new(diffLine)
+			f.lines[frame.StartLine] = ln
+		}
+		ln.total++
+		if basePCs[frame.PC] {
+			ln.base++
+			matchedPC = true
+		}
+		if curPCs[frame.PC] {
+			ln.cur++
+			matchedPC = true
+		}
+	}
+	if !matchedPC {
+		return nil, fmt.Errorf("coverage doesn't match any coverage callbacks")
+	}
+	return files, nil
+}
+
+// diffFileLines returns changed lines of the file with some context around them.
+func diffFileLines(f *diffFile, lines [][]byte) []*templateDiffLine {
+	show := make(map[int]bool)
+	for num, ln := range f.lines {
+		if ln.base == ln.cur {
+			continue
+		}
+		for i := num - diffContextLines; i <= num+diffContextLines; i++ {
+			show[i] = true
+		}
+	}
+	var res []*templateDiffLine
+	for i, text := range lines {
+		num := i + 1
+		if !show[num] {
+			continue
+		}
+		if len(res) != 0 && res[len(res)-1].Num != num-1 {
+			res = append(res, &templateDiffLine{Separator: true})
+		}
+		tl := &templateDiffLine{
+			Num:  num,
+			Text: string(text),
+		}
+		if ln := f.lines[num]; ln != nil {
+			tl.Status = ln.status()
+			tl.Base, tl.Cur = ln.base, ln.cur
+		}
+		res = append(res, tl)
+	}
+	return res
+}
+
+type templateDiffData struct {
+	BasePCs int
+	CurPCs  int
+	NewPCs  int
+	LostPCs int
+	Files   []*templateDiffFile
+}
+
+type templateDiffFile struct {
+	Index   int
+	Name    string
+	Total   int
+	BasePCs int
+	CurPCs  int
+	NewPCs  int
+	LostPCs int
+	Error   string
+	Lines   []*templateDiffLine
+}
+
+type templateDiffLine struct {
+	Separator bool
+	Num       int
+	Text      string
+	Status    string
+	Base      int
+	Cur       int
+}
+
+var coverDiffTemplate = template.Must(template.New("").Parse(`
+<!DOCTYPE html>
+<html>
+	<head>
+		<meta http-equiv="Content-Type" content="text/html; charset=utf-8">
+		<style>
+			body {
+				font-family: monospace;
+			}
+			table {
+				border-collapse: collapse;
+			}
+			.summary td, .summary th {
+				border: 1px solid #ddd;
+				padding: 2px 6px;
+			}
+			.source td {
+				padding: 0 6px;
+				white-space: pre;
+				tab-size: 8;
+			}
+			.num, .count {
+				color: #888;
+				text-align: right;
+			}
+			.new {
+				background-color: #c3f0c3;
+			}
+			.lost {
+				background-color: #f5c2c2;
+			}
+			.changed {
+				background-color: #f5ecc2;
+			}
+			.covered {
+				color: #006400;
+			}
+			.uncovered {
+				color: #c80000;
+			}
+			.plus {
+				color: #006400;
+			}
+			.minus {
+				color: #c80000;
+			}
+		</style>
+	</head>
+	<body>
+		<h2>Coverage diff</h2>
+		<p>
+			Base: {{.BasePCs}} PCs, current: {{.CurPCs}} PCs,
+			<span class="plus">+{{.NewPCs}}</span> new, <span class="minus">-{{.LostPCs}}</span> lost.
+		</p>
+		<table class="summary">
+			<tr>
+				<th>File</th>
+				<th>PCs</th>
+				<th>Base</th>
+				<th>Current</th>
+				<th>New</th>
+				<th>Lost</th>
+			</tr>
+			{{range $f := .Files}}
+			<tr>
+				<td><a href="#file{{$f.Index}}">{{$f.Name}}</a></td>
+				<td>{{$f.Total}}</td>
+				<td>{{$f.BasePCs}}</td>
+				<td>{{$f.CurPCs}}</td>
+				<td class="plus">+{{$f.NewPCs}}</td>
+				<td class="minus">-{{$f.LostPCs}}</td>
+			</tr>
+			{{end}}
+		</table>
+		{{range $f := .Files}}
+		<h3 id="file{{$f.Index}}">{{$f.Name}}</h3>
+		{{if $f.Error}}
+			{{$f.Error}}
+		{{else}}
+		<table class="source">
+			{{range $ln := $f.Lines}}
+			{{if $ln.Separator}}
+			<tr><td class="num">...</td><td></td><td></td></tr>
+			{{else}}
+			<tr class="{{$ln.Status}}">
+				<td class="num">{{$ln.Num}}</td>
+				<td class="count">{{if $ln.Status}}{{$ln.Base}} &rarr; {{$ln.Cur}}{{end}}</td>
+				<td>{{$ln.Text}}</td>
+			</tr>
+			{{end}}
+			{{end}}
+		</table>
+		{{end}}
+		{{end}}
+	</body>
+</html>
+`))
@@ -0,0 +1,72 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package cover
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/syzkaller/pkg/cover/backend"
+	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/sys/targets"
+)
+
+func TestDoDiffHTML(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "foo.c")
+	var lines []string
+	for i := 1; i <= 30; i++ {
+		lines = append(lines, "line"+string(rune('a'+i%26)))
+	}
+	if err := osutil.WriteFile(src, []byte(strings.Join(lines, "\n"))); err != nil {
+		t.Fatal(err)
+	}
+	frame := func(pc uint64, line int) backend.Frame {
+		return backend.Frame{
+			PC:    pc,
+			Name:  "foo.c",
+			Path:  src,
+			Range: backend.Range{StartLine: line, EndLine: line, EndCol: backend.LineEnd},
+		}
+	}
+	rg := &ReportGenerator{
+		target: targets.Get(targets.Linux, targets.AMD64),
+		Impl: &backend.Impl{
+			Units: []*backend.CompileUnit{{
+				ObjectUnit: backend.ObjectUnit{Name: "foo.c", PCs: []uint64{1, 2, 3, 4}},
+				Path:       src,
+			}},
+			Frames: []backend.Frame{frame(1, 2), frame(2, 10), frame(3, 20), frame(4, 25)},
+		},
+	}
+	files, err := rg.prepareDiffFileMap([]uint64{1, 2}, []uint64{2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := files["foo.c"]
+	if f.basePCs != 2 || f.curPCs != 2 || f.newPCs != 1 || f.lostPCs != 1 {
+		t.Fatalf("bad file stats: %+v", f)
+	}
+	for line, status := range map[int]string{2: "lost", 10: "covered", 20: "new", 25: "uncovered"} {
+		if got := f.lines[line].status(); got != status {
+			t.Errorf("line %v: got %v, want %v", line, got, status)
+		}
+	}
+	buf := new(bytes.Buffer)
+	if err := rg.DoDiffHTML(buf, []uint64{1, 2}, []uint64{2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{`<tr class="lost">`, `<tr class="new">`, "+1", "-1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report does not contain %q", want)
+		}
+	}
+	// Line 10 is not changed and is too far from changed lines.
+	if strings.Contains(out, `<td class="num">10</td>`) {
+		t.Errorf("report contains unchanged line 10")
+	}
+}
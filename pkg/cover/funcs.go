@@ -0,0 +1,141 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package cover
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/syzkaller/pkg/cover/backend"
+)
+
+// FuncCover aggregates coverage per function. PCs are mapped to functions using symbol
+// information of the report generator (no symbolization is required), so coverage
+// can be added incrementally as it arrives and queried cheaply at any time.
+type FuncCover struct {
+	rg      *ReportGenerator
+	mu      sync.Mutex
+	funcs   map[*backend.Symbol]*funcHits
+	covered map[uint64]bool
+}
+
+type funcHits struct {
+	covered int
+	hits    int
+}
+
+// FuncSummary is coverage summary of a single function.
+type FuncSummary struct {
+	Name    string
+	Module  string
+	File    string // compilation unit name
+	PCs     int    // number of coverage points in the function
+	Covered int    // number of covered coverage points
+	Hits    int    // number of Add calls that covered the function
+}
+
+func (rg *ReportGenerator) NewFuncCover() *FuncCover {
+	return &FuncCover{
+		rg:      rg,
+		funcs:   make(map[*backend.Symbol]*funcHits),
+		covered: make(map[uint64]bool),
+	}
+}
+
+// Add adds coverage of a single program (PCs in the same form as in Prog.PCs).
+func (fc *FuncCover) Add(pcs []uint64) {
+	pcs = fixUpPCs(fc.rg.target.Arch, []Prog{{PCs: pcs}}, nil)[0].PCs
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	hit := make(map[*backend.Symbol]bool)
+	for _, pc := range pcs {
+		sym := fc.rg.findSymbol(pc)
+		if sym == nil || !hasPC(sym.PCs, pc) {
+			continue
+		}
+		fh := fc.funcs[sym]
+		if fh == nil {
+			fh = new(funcHits)
+			fc.funcs[sym] = fh
+		}
+		if !hit[sym] {
+			hit[sym] = true
+			fh.hits++
+		}
+		if !fc.covered[pc] {
+			fc.covered[pc] = true
+			fh.covered++
+		}
+	}
+}
+
+func hasPC(pcs []uint64, pc uint64) bool {
+	idx := sort.Search(len(pcs), func(i int) bool { return pcs[i] >= pc })
+	return idx < len(pcs) && pcs[idx] == pc
+}
+
+// Functions returns summaries of all functions of the subsystem (all functions if subsystem is "all"),
+// sorted by file and function name.
+func (fc *FuncCover) Functions(subsystem string) ([]FuncSummary, error) {
+	return fc.summaries(subsystem, false)
+}
+
+// Uncovered returns summaries of functions of the subsystem that are not covered at all.
+func (fc *FuncCover) Uncovered(subsystem string) ([]FuncSummary, error) {
+	return fc.summaries(subsystem, true)
+}
+
+func (fc *FuncCover) summaries(subsystem string, uncoveredOnly bool) ([]FuncSummary, error) {
+	var paths []string
+	for _, s := range fc.rg.subsystem {
+		if s.Name == subsystem {
+			paths = s.Paths
+			break
+		}
+	}
+	if paths == nil {
+		return nil, fmt.Errorf("unknown subsystem %q", subsystem)
+	}
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	var res []FuncSummary
+	for _, sym := range fc.rg.Symbols {
+		if len(sym.PCs) == 0 || !matchesPaths(sym.Unit.Name, paths) {
+			continue
+		}
+		fh := fc.funcs[sym]
+		if fh == nil {
+			fh = new(funcHits)
+		}
+		if uncoveredOnly && fh.covered != 0 {
+			continue
+		}
+		res = append(res, FuncSummary{
+			Name:    sym.Name,
+			Module:  sym.Module.Name,
+			File:    sym.Unit.Name,
+			PCs:     len(sym.PCs),
+			Covered: fh.covered,
+			Hits:    fh.hits,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].File != res[j].File {
+			return res[i].File < res[j].File
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res, nil
+}
+
+func matchesPaths(name string, paths []string) bool {
+	for _, path := range paths {
+		if strings.HasPrefix(name, path) {
+			return true
+		}
+	}
+	return false
+}
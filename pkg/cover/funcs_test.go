@@ -0,0 +1,68 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package cover
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/pkg/cover/backend"
+	"github.com/google/syzkaller/pkg/mgrconfig"
+	"github.com/google/syzkaller/sys/targets"
+)
+
+func TestFuncCover(t *testing.T) {
+	mod := &backend.Module{Name: ""}
+	netUnit := &backend.CompileUnit{ObjectUnit: backend.ObjectUnit{Name: "net/socket.c"}, Module: mod}
+	fsUnit := &backend.CompileUnit{ObjectUnit: backend.ObjectUnit{Name: "fs/open.c"}, Module: mod}
+	symbol := func(name string, unit *backend.CompileUnit, start, end uint64, pcs ...uint64) *backend.Symbol {
+		return &backend.Symbol{
+			ObjectUnit: backend.ObjectUnit{Name: name, PCs: pcs},
+			Module:     mod,
+			Unit:       unit,
+			Start:      start,
+			End:        end,
+		}
+	}
+	rg := &ReportGenerator{
+		target: targets.Get(targets.TestOS, targets.TestArch64),
+		subsystem: []mgrconfig.Subsystem{
+			{Name: "net", Paths: []string{"net/"}},
+			{Name: "all", Paths: []string{""}},
+		},
+		Impl: &backend.Impl{
+			Symbols: []*backend.Symbol{
+				symbol("sock_create", netUnit, 0x100, 0x200, 0x110, 0x120, 0x130),
+				symbol("sock_close", netUnit, 0x200, 0x300, 0x210),
+				symbol("do_open", fsUnit, 0x300, 0x400, 0x310, 0x320),
+			},
+		},
+	}
+	fc := rg.NewFuncCover()
+	fc.Add([]uint64{0x110, 0x120, 0x115, 0x500})
+	fc.Add([]uint64{0x120, 0x310})
+
+	funcs, err := fc.Functions("all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FuncSummary{
+		{Name: "do_open", File: "fs/open.c", PCs: 2, Covered: 1, Hits: 1},
+		{Name: "sock_close", File: "net/socket.c", PCs: 1},
+		{Name: "sock_create", File: "net/socket.c", PCs: 3, Covered: 2, Hits: 2},
+	}
+	if diff := cmp.Diff(want, funcs); diff != "" {
+		t.Fatal(diff)
+	}
+	uncovered, err := fc.Uncovered("net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]FuncSummary{{Name: "sock_close", File: "net/socket.c", PCs: 1}}, uncovered); diff != "" {
+		t.Fatal(diff)
+	}
+	if _, err := fc.Functions("sound"); err == nil {
+		t.Fatal("no error for unknown subsystem")
+	}
+}
@@ -149,7 +149,8 @@ func (rg *ReportGenerator) DoRawCoverFiles(w http.ResponseWriter, progs []Prog,
 	if err := rg.lazySymbolize(progs); err != nil {
 		return err
 	}
-	sort.Slice(rg.Frames, func(i, j int) bool {
+	// Stable sort to keep inlined frames of a PC before their call sites.
+	sort.SliceStable(rg.Frames, func(i, j int) bool {
 		return rg.Frames[i].PC < rg.Frames[j].PC
 	})
 
@@ -396,6 +397,27 @@ func groupCoverByFilePrefixes(datas []fileStats, subsystems []mgrconfig.Subsyste
 	return d
 }
 
+// SubsystemCover returns number of covered PCs per subsystem.
+func (rg *ReportGenerator) SubsystemCover(progs []Prog, coverFilter map[uint32]uint32) (map[string]int, error) {
+	progs = fixUpPCs(rg.target.Arch, progs, coverFilter)
+	files, err := rg.prepareFileMap(progs)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]int)
+	for _, subsystem := range rg.subsystem {
+		for name, file := range files {
+			for _, path := range subsystem.Paths {
+				if strings.HasPrefix(name, path) {
+					res[subsystem.Name] += file.coveredPCs
+					break
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
 func (rg *ReportGenerator) DoHTMLTable(w io.Writer, progs []Prog, coverFilter map[uint32]uint32) error {
 	progs = fixUpPCs(rg.target.Arch, progs, coverFilter)
 	data, err := rg.convertToStats(progs)
@@ -540,6 +562,16 @@ func fileContents(file *file, lines [][]byte, haveProgs bool) string {
 	}
 	buf.WriteString("</td><td>")
 	for i := range lines {
+		if into := file.lines[i+1].inlinedInto; len(into) != 0 {
+			var funcs []string
+			for fn := range into {
+				funcs = append(funcs, fn)
+			}
+			sort.Strings(funcs)
+			buf.WriteString(fmt.Sprintf("<span class='inline' title='inlined into %v'>%d</span>\n",
+				html.EscapeString(strings.Join(funcs, ", ")), i+1))
+			continue
+		}
 		buf.WriteString(fmt.Sprintf("%d\n", i+1))
 	}
 	buf.WriteString("</td><td>")
@@ -823,6 +855,10 @@ var coverTemplate = template.Must(template.New("").Parse(`
 				color: rgb(200, 100, 0);
 				font-weight: bold;
 			}
+			.inline {
+				text-decoration: underline dotted;
+				cursor: help;
+			}
 			ul, #dir_list {
 				list-style-type: none;
 				padding-left: 16px;
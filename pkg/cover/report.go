@@ -69,8 +69,9 @@ type function struct {
 }
 
 type line struct {
-	progCount map[int]bool // program indices that cover this line
-	progIndex int          // example program index that covers this line
+	progCount   map[int]bool    // program indices that cover this line
+	progIndex   int             // example program index that covers this line
+	inlinedInto map[string]bool // functions the code on this line is inlined into
 }
 
 func (rg *ReportGenerator) prepareFileMap(progs []Prog) (map[string]*file, error) {
@@ -96,9 +97,16 @@ func (rg *ReportGenerator) prepareFileMap(progs []Prog) (map[string]*file, error
 		}
 	}
 	matchedPC := false
-	for _, frame := range rg.Frames {
+	for i, frame := range rg.Frames {
 		f := getFile(files, frame.Name, frame.Path, frame.Module.Name)
 		ln := f.lines[frame.StartLine]
+		if frame.Inline && i+1 < len(rg.Frames) && rg.Frames[i+1].PC == frame.PC {
+			if ln.inlinedInto == nil {
+				ln.inlinedInto = make(map[string]bool)
+			}
+			ln.inlinedInto[rg.Frames[i+1].Func] = true
+			f.lines[frame.StartLine] = ln
+		}
 		coveredBy := progPCs[frame.PC]
 		if len(coveredBy) == 0 {
 			f.uncovered = append(f.uncovered, frame.Range)
@@ -180,11 +188,20 @@ func (rg *ReportGenerator) lazySymbolize(progs []Prog) error {
 		return err
 	}
 	rg.Frames = append(rg.Frames, frames...)
-	uniqueFrames := make(map[uint64]bool)
+	// A PC has several frames if it belongs to inlined code: the inlined function itself
+	// and all call sites up to the real function. Keep all of them, so that both the inlined
+	// code and the call sites are attributed the coverage.
+	type frameKey struct {
+		pc   uint64
+		path string
+		line int
+	}
+	uniqueFrames := make(map[frameKey]bool)
 	var finalFrames []backend.Frame
 	for _, frame := range rg.Frames {
-		if !uniqueFrames[frame.PC] {
-			uniqueFrames[frame.PC] = true
+		key := frameKey{frame.PC, frame.Path, frame.StartLine}
+		if !uniqueFrames[key] {
+			uniqueFrames[key] = true
 			finalFrames = append(finalFrames, frame)
 		}
 	}
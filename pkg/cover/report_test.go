@@ -317,6 +317,13 @@ func generateReport(t *testing.T, target *targets.Target, test Test) ([]byte, []
 		return nil, nil, err
 	}
 	_ = csvFiles
+	subsystemCover, err := rg.SubsystemCover(test.Progs, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if subsystemCover["all"] == 0 {
+		t.Fatalf("no coverage in subsystem \"all\": %v", subsystemCover)
+	}
 
 	return html.Bytes(), csv.Bytes(), nil
 }
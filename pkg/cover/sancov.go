@@ -0,0 +1,75 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package cover
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Sancov is the coverage file format of LLVM SanitizerCoverage (consumed by the sancov tool
+// and other coverage visualizers): an 8-byte magic that denotes PC size followed by
+// an array of PCs. All values are little-endian.
+const (
+	sancovMagic64 = 0xC0BFFFFFFFFFFF64
+	sancovMagic32 = 0xC0BFFFFFFFFFFF32
+)
+
+// WriteSancov writes sorted PCs in the sancov format. ptrSize is the target pointer size (4 or 8).
+func WriteSancov(w io.Writer, pcs []uint64, ptrSize uint64) error {
+	var magic uint64
+	switch ptrSize {
+	case 8:
+		magic = sancovMagic64
+	case 4:
+		magic = sancovMagic32
+	default:
+		return fmt.Errorf("unsupported pointer size %v", ptrSize)
+	}
+	sorted := append([]uint64{}, pcs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	buf := make([]byte, 8+len(sorted)*int(ptrSize))
+	binary.LittleEndian.PutUint64(buf, magic)
+	for i, pc := range sorted {
+		off := 8 + i*int(ptrSize)
+		if ptrSize == 8 {
+			binary.LittleEndian.PutUint64(buf[off:], pc)
+		} else {
+			binary.LittleEndian.PutUint32(buf[off:], uint32(pc))
+		}
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// ParseSancov returns PCs from data in the sancov format.
+func ParseSancov(data []byte) ([]uint64, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("sancov file is too small (%v bytes)", len(data))
+	}
+	var ptrSize int
+	switch magic := binary.LittleEndian.Uint64(data); magic {
+	case sancovMagic64:
+		ptrSize = 8
+	case sancovMagic32:
+		ptrSize = 4
+	default:
+		return nil, fmt.Errorf("bad sancov magic 0x%x", magic)
+	}
+	data = data[8:]
+	if len(data)%ptrSize != 0 {
+		return nil, fmt.Errorf("sancov data size %v is not multiple of %v", len(data), ptrSize)
+	}
+	pcs := make([]uint64, 0, len(data)/ptrSize)
+	for ; len(data) != 0; data = data[ptrSize:] {
+		if ptrSize == 8 {
+			pcs = append(pcs, binary.LittleEndian.Uint64(data))
+		} else {
+			pcs = append(pcs, uint64(binary.LittleEndian.Uint32(data)))
+		}
+	}
+	return pcs, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package cover
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSancov(t *testing.T) {
+	pcs := []uint64{0xffffffff81000010, 0xffffffff81000000, 0xffffffff82345678}
+	buf := new(bytes.Buffer)
+	if err := WriteSancov(buf, pcs, 8); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		0x64, 0xff, 0xff, 0xff, 0xff, 0xff, 0xbf, 0xc0,
+		0x00, 0x00, 0x00, 0x81, 0xff, 0xff, 0xff, 0xff,
+		0x10, 0x00, 0x00, 0x81, 0xff, 0xff, 0xff, 0xff,
+		0x78, 0x56, 0x34, 0x82, 0xff, 0xff, 0xff, 0xff,
+	}
+	if diff := cmp.Diff(want, buf.Bytes()); diff != "" {
+		t.Fatal(diff)
+	}
+	got, err := ParseSancov(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]uint64{0xffffffff81000000, 0xffffffff81000010, 0xffffffff82345678}, got); diff != "" {
+		t.Fatal(diff)
+	}
+
+	buf.Reset()
+	if err := WriteSancov(buf, []uint64{0x8000, 0x1000}, 4); err != nil {
+		t.Fatal(err)
+	}
+	got, err = ParseSancov(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]uint64{0x1000, 0x8000}, got); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if err := WriteSancov(buf, pcs, 2); err == nil {
+		t.Errorf("no error for bad pointer size")
+	}
+	for _, data := range [][]byte{
+		nil,
+		{0x64, 0xff, 0xff, 0xff, 0xff, 0xff, 0xbf},
+		{0x65, 0xff, 0xff, 0xff, 0xff, 0xff, 0xbf, 0xc0},
+		{0x64, 0xff, 0xff, 0xff, 0xff, 0xff, 0xbf, 0xc0, 0x00},
+	} {
+		if _, err := ParseSancov(data); err == nil {
+			t.Errorf("no error for %x", data)
+		}
+	}
+}
@@ -123,6 +123,7 @@ func commonDefines(p *prog.Prog, opts Options) map[string]bool {
 		"SYZ_HANDLE_SEGV":               opts.HandleSegv,
 		"SYZ_REPRO":                     opts.Repro,
 		"SYZ_TRACE":                     opts.Trace,
+		"SYZ_STRACE":                    opts.Strace,
 		"SYZ_WIFI":                      opts.Wifi,
 		"SYZ_802154":                    opts.IEEE802154,
 		"SYZ_SYSCTL":                    opts.Sysctl,
@@ -60,13 +60,13 @@ type context struct {
 
 func (ctx *context) generateSource() ([]byte, error) {
 	ctx.filterCalls()
-	calls, vars, err := ctx.generateProgCalls(ctx.p, ctx.opts.Trace)
+	calls, vars, err := ctx.generateProgCalls(ctx.p, ctx.opts.Trace, ctx.opts.Strace)
 	if err != nil {
 		return nil, err
 	}
 
 	mmapProg := ctx.p.Target.DataMmapProg()
-	mmapCalls, _, err := ctx.generateProgCalls(mmapProg, false)
+	mmapCalls, _, err := ctx.generateProgCalls(mmapProg, false, false)
 	if err != nil {
 		return nil, err
 	}
@@ -174,7 +174,7 @@ func (ctx *context) generateSyscalls(calls []string, hasVars bool) string {
 	opts := ctx.opts
 	buf := new(bytes.Buffer)
 	if !opts.Threaded && !opts.Collide {
-		if len(calls) > 0 && (hasVars || opts.Trace) {
+		if len(calls) > 0 && (hasVars || opts.Trace || opts.Strace) {
 			fmt.Fprintf(buf, "\tintptr_t res = 0;\n")
 		}
 		if opts.Repro {
@@ -187,7 +187,7 @@ func (ctx *context) generateSyscalls(calls []string, hasVars bool) string {
 			fmt.Fprintf(buf, "%s", c)
 		}
 	} else if len(calls) > 0 {
-		if hasVars || opts.Trace {
+		if hasVars || opts.Trace || opts.Strace {
 			fmt.Fprintf(buf, "\tintptr_t res = 0;\n")
 		}
 		fmt.Fprintf(buf, "\tswitch (call) {\n")
@@ -228,7 +228,7 @@ func (ctx *context) generateSyscallDefines() string {
 	return buf.String()
 }
 
-func (ctx *context) generateProgCalls(p *prog.Prog, trace bool) ([]string, []uint64, error) {
+func (ctx *context) generateProgCalls(p *prog.Prog, trace, strace bool) ([]string, []uint64, error) {
 	exec := make([]byte, prog.ExecBufferSize)
 	progSize, err := p.SerializeForExec(exec)
 	if err != nil {
@@ -238,11 +238,11 @@ func (ctx *context) generateProgCalls(p *prog.Prog, trace bool) ([]string, []uin
 	if err != nil {
 		return nil, nil, err
 	}
-	calls, vars := ctx.generateCalls(decoded, trace)
+	calls, vars := ctx.generateCalls(decoded, trace, strace)
 	return calls, vars, nil
 }
 
-func (ctx *context) generateCalls(p prog.ExecProg, trace bool) ([]string, []uint64) {
+func (ctx *context) generateCalls(p prog.ExecProg, trace, strace bool) ([]string, []uint64) {
 	var calls []string
 	csumSeq := 0
 	for ci, call := range p.Calls {
@@ -259,7 +259,13 @@ func (ctx *context) generateCalls(p prog.ExecProg, trace bool) ([]string, []uint
 		resCopyout := call.Index != prog.ExecNoCopyout
 		argCopyout := len(call.Copyout) != 0
 
-		ctx.emitCall(w, call, ci, resCopyout || argCopyout, trace)
+		if strace {
+			ctx.emitStraceEnter(w, call, ci)
+		}
+		ctx.emitCall(w, call, ci, resCopyout || argCopyout || strace, trace)
+		if strace {
+			ctx.emitStraceExit(w, call, ci)
+		}
 
 		if call.Props.Rerun > 0 {
 			// TODO: remove this legacy C89-style definition once we figure out what to do with Akaros.
@@ -319,6 +325,44 @@ func (ctx *context) emitCall(w *bytes.Buffer, call prog.ExecCall, ci int, haveCo
 	}
 }
 
+// emitStraceEnter prints the call with its arguments before the call is executed,
+// so that it's clear what call hangs or crashes the machine.
+func (ctx *context) emitStraceEnter(w *bytes.Buffer, call prog.ExecCall, ci int) {
+	format, args := "", ""
+	for ai, arg := range call.Args {
+		if ai != 0 {
+			format += ", "
+		}
+		format += "0x%llx"
+		switch arg := arg.(type) {
+		case prog.ExecArgConst:
+			args += fmt.Sprintf(", (unsigned long long)%v", ctx.constArgToStr(arg, true, false))
+		case prog.ExecArgResult:
+			args += fmt.Sprintf(", (unsigned long long)%v", ctx.resultArgToStr(arg))
+		default:
+			panic(fmt.Sprintf("unknown arg type: %+v", arg))
+		}
+	}
+	fmt.Fprintf(w, "\tfprintf(stderr, \"#%v %v(%v)\\n\"%v);\n", ci, call.Meta.Name, format, args)
+}
+
+func (ctx *context) emitStraceExit(w *bytes.Buffer, call prog.ExecCall, ci int) {
+	callName := call.Meta.CallName
+	_, trampoline := ctx.sysTarget.SyscallTrampolines[callName]
+	native := ctx.sysTarget.SyscallNumbers && !strings.HasPrefix(callName, "syz_") && !trampoline
+	cast := ""
+	if !native && !strings.HasPrefix(callName, "syz_") {
+		// See the comment in emitCall.
+		cast = "(intptr_t)(int)"
+	}
+	fmt.Fprintf(w, "\tif (%vres == -1)\n", cast)
+	fmt.Fprintf(w, "\t\tfprintf(stderr, \"#%v %v = -1 (errno %%d: %%s)\\n\", errno, strerror(errno));\n",
+		ci, call.Meta.Name)
+	fmt.Fprintf(w, "\telse\n")
+	fmt.Fprintf(w, "\t\tfprintf(stderr, \"#%v %v = 0x%%llx\\n\", (unsigned long long)res);\n",
+		ci, call.Meta.Name)
+}
+
 func (ctx *context) emitCallBody(w *bytes.Buffer, call prog.ExecCall, native bool) {
 	callName, ok := ctx.sysTarget.SyscallTrampolines[call.Meta.CallName]
 	if !ok {
@@ -170,6 +170,7 @@ func TestSource(t *testing.T) {
 	type Test struct {
 		input  string
 		output string
+		strace bool
 	}
 	tests := []Test{
 		{
@@ -205,6 +206,38 @@ NONFAILING(memcpy((void*)0x20000140, "101010101010", 12));
 syscall(SYS_csource6, 0x20000140ul);
 `,
 		},
+		{
+			input: `
+r0 = csource0(0x1)
+csource1(r0) (fail_nth: 1)
+syz_errno(0x5)
+`,
+			output: `
+fprintf(stderr, "#0 csource0(0x%llx)\n", (unsigned long long)1);
+res = syscall(SYS_csource0, 1);
+if (res == -1)
+	fprintf(stderr, "#0 csource0 = -1 (errno %d: %s)\n", errno, strerror(errno));
+else
+	fprintf(stderr, "#0 csource0 = 0x%llx\n", (unsigned long long)res);
+if (res != -1)
+	r[0] = res;
+inject_fault(1);
+fprintf(stderr, "#1 csource1(0x%llx)\n", (unsigned long long)r[0]);
+res = syscall(SYS_csource1, r[0]);
+if (res == -1)
+	fprintf(stderr, "#1 csource1 = -1 (errno %d: %s)\n", errno, strerror(errno));
+else
+	fprintf(stderr, "#1 csource1 = 0x%llx\n", (unsigned long long)res);
+fprintf(stderr, "#2 syz_errno(0x%llx)\n", (unsigned long long)5);
+res = -1;
+NONFAILING(res = syz_errno(5));
+if (res == -1)
+	fprintf(stderr, "#2 syz_errno = -1 (errno %d: %s)\n", errno, strerror(errno));
+else
+	fprintf(stderr, "#2 syz_errno = 0x%llx\n", (unsigned long long)res);
+`,
+			strace: true,
+		},
 	}
 	for i, test := range tests {
 		t.Run(fmt.Sprint(i), func(t *testing.T) {
@@ -216,7 +249,7 @@ syscall(SYS_csource6, 0x20000140ul);
 				target:    target,
 				sysTarget: targets.Get(target.OS, target.Arch),
 			}
-			calls, _, err := ctx.generateProgCalls(p, false)
+			calls, _, err := ctx.generateProgCalls(p, false, test.strace)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -33,7 +33,7 @@ typedef signed int ssize_t;
 #include <stdlib.h>
 #include <string.h>
 
-#if SYZ_TRACE
+#if SYZ_TRACE || SYZ_STRACE
 #include <errno.h>
 #endif
 
@@ -3971,6 +3971,115 @@ static long syz_io_uring_submit(volatile long a0, volatile long a1, volatile lon
 
 #endif
 
+#if SYZ_EXECUTOR || __NR_syz_io_uring_register_file || __NR_syz_io_uring_unregister_file || __NR_syz_io_uring_register_buffers || __NR_syz_io_uring_unregister_buffers || __NR_syz_io_uring_register_eventfd || __NR_syz_io_uring_unregister_eventfd
+
+#include <errno.h>
+#include <sys/uio.h>
+#define IORING_REGISTER_BUFFERS 0
+#define IORING_UNREGISTER_BUFFERS 1
+#define IORING_REGISTER_FILES 2
+#define IORING_REGISTER_EVENTFD 4
+#define IORING_UNREGISTER_EVENTFD 5
+#define IORING_REGISTER_FILES_UPDATE 6
+#define IORING_REGISTER_EVENTFD_ASYNC 7
+#define IO_URING_REGISTERED_FILES 16
+
+#if SYZ_EXECUTOR || __NR_syz_io_uring_register_file || __NR_syz_io_uring_unregister_file
+
+struct io_uring_files_update {
+	uint32 offset;
+	uint32 resv;
+	uint64 fds;
+};
+static long io_uring_update_file(int ring_fd, uint32 index, int fd)
+{
+	if (index >= IO_URING_REGISTERED_FILES)
+		return -1;
+	int files[IO_URING_REGISTERED_FILES];
+	for (int i = 0; i < IO_URING_REGISTERED_FILES; i++)
+		files[i] = -1;
+	if (syscall(__NR_io_uring_register, ring_fd, IORING_REGISTER_FILES, files, IO_URING_REGISTERED_FILES) && errno != EBUSY)
+		return -1;
+	struct io_uring_files_update up = {};
+	up.offset = index;
+	up.fds = (uint64)(long)&fd;
+	if (syscall(__NR_io_uring_register, ring_fd, IORING_REGISTER_FILES_UPDATE, &up, 1) < 0)
+		return -1;
+	return index;
+}
+
+#endif
+
+#if SYZ_EXECUTOR || __NR_syz_io_uring_register_file
+
+static long syz_io_uring_register_file(volatile long a0, volatile long a1, volatile long a2)
+{
+	return io_uring_update_file((int)a0, (uint32)a2, (int)a1);
+}
+
+#endif
+
+#if SYZ_EXECUTOR || __NR_syz_io_uring_unregister_file
+
+static long syz_io_uring_unregister_file(volatile long a0, volatile long a1)
+{
+	if (io_uring_update_file((int)a0, (uint32)a1, -1) < 0)
+		return -1;
+	return 0;
+}
+
+#endif
+
+#if SYZ_EXECUTOR || __NR_syz_io_uring_register_buffers
+
+static long syz_io_uring_register_buffers(volatile long a0, volatile long a1, volatile long a2, volatile long a3)
+{
+	int ring_fd = (int)a0;
+	struct iovec* bufs = (struct iovec*)a1;
+	uint32 nr = (uint32)a2;
+	uint16 index = (uint16)a3;
+	if (nr == 0)
+		return -1;
+	if (syscall(__NR_io_uring_register, ring_fd, IORING_REGISTER_BUFFERS, bufs, nr) < 0)
+		return -1;
+	return index % nr;
+}
+
+#endif
+
+#if SYZ_EXECUTOR || __NR_syz_io_uring_unregister_buffers
+
+static long syz_io_uring_unregister_buffers(volatile long a0, volatile long a1)
+{
+	return syscall(__NR_io_uring_register, (int)a0, IORING_UNREGISTER_BUFFERS, 0, 0);
+}
+
+#endif
+
+#if SYZ_EXECUTOR || __NR_syz_io_uring_register_eventfd
+
+static long syz_io_uring_register_eventfd(volatile long a0, volatile long a1, volatile long a2)
+{
+	int efd = (int)a1;
+	int opcode = a2 ? IORING_REGISTER_EVENTFD_ASYNC : IORING_REGISTER_EVENTFD;
+	if (syscall(__NR_io_uring_register, (int)a0, opcode, &efd, 1) < 0)
+		return -1;
+	return efd;
+}
+
+#endif
+
+#if SYZ_EXECUTOR || __NR_syz_io_uring_unregister_eventfd
+
+static long syz_io_uring_unregister_eventfd(volatile long a0, volatile long a1)
+{
+	return syscall(__NR_io_uring_register, (int)a0, IORING_UNREGISTER_EVENTFD, 0, 0);
+}
+
+#endif
+
+#endif
+
 #if SYZ_EXECUTOR || __NR_syz_usbip_server_init
 
 #include <errno.h>
@@ -7181,6 +7290,85 @@ struct kvm_opt {
 	uint64 typ;
 	uint64 val;
 };
+#define KVM_SETUP_ARM64_MMU (1 << 0)
+#define KVM_SETUP_ARM64_EL0 (1 << 1)
+#define ARM64_ADDR_VECTORS 0x0
+#define ARM64_ADDR_PGD 0x1000
+#define ARM64_ADDR_PMD 0x2000
+#define ARM64_ADDR_PTE 0x3000
+#define ARM64_ADDR_TEXT 0x4000
+#define ARM64_ADDR_EL0_STACK 0x16000
+#define ARM64_ADDR_EL1_STACK 0x18000
+#define ARM64_ADDR_EXIT 0x10000000
+static const uint32 kvm_arm64_text_exit[] = {
+    0xd2a2001d,
+    0xf90003a0,
+    0x17ffffff,
+};
+static const uint32 kvm_arm64_vector_exit[] = {
+    0xd538521c,
+    0xd2a2001d,
+    0xf90003bc,
+    0x17ffffff,
+};
+#define ARM64_PTE_TABLE 0x3
+#define ARM64_PTE_PAGE 0x3
+#define ARM64_PTE_BLOCK 0x1
+#define ARM64_PTE_ATTR_NORMAL (0 << 2)
+#define ARM64_PTE_ATTR_DEVICE (1 << 2)
+#define ARM64_PTE_AP_EL0 (1 << 6)
+#define ARM64_PTE_SH_INNER (3 << 8)
+#define ARM64_PTE_AF (1 << 10)
+
+#define ARM64_MAIR_EL1 0x00ff
+#define ARM64_TCR_EL1 (25 | (1 << 8) | (1 << 10) | (3 << 12) | (1 << 23))
+#define ARM64_SCTLR_M (1 << 0)
+#define ARM64_SCTLR_C (1 << 2)
+#define ARM64_SCTLR_I (1 << 12)
+#define ARM64_CPACR_FPEN (3 << 20)
+#define ARM64_PSTATE_DAIF 0x3c0
+#define ARM64_PSTATE_EL0t 0x0
+#define ARM64_PSTATE_EL1h 0x5
+
+#define ARM64_CORE_REG(x) (KVM_REG_ARM64 | KVM_REG_SIZE_U64 | KVM_REG_ARM_CORE | KVM_REG_ARM_CORE_REG(x))
+#define ARM64_SYSREG_SCTLR_EL1 ARM64_SYS_REG(3, 0, 1, 0, 0)
+#define ARM64_SYSREG_CPACR_EL1 ARM64_SYS_REG(3, 0, 1, 0, 2)
+#define ARM64_SYSREG_TTBR0_EL1 ARM64_SYS_REG(3, 0, 2, 0, 0)
+#define ARM64_SYSREG_TCR_EL1 ARM64_SYS_REG(3, 0, 2, 0, 2)
+#define ARM64_SYSREG_MAIR_EL1 ARM64_SYS_REG(3, 0, 10, 2, 0)
+#define ARM64_SYSREG_VBAR_EL1 ARM64_SYS_REG(3, 0, 12, 0, 0)
+
+static int kvm_arm64_set_reg(int cpufd, uint64 id, uint64 val)
+{
+	struct kvm_one_reg reg = {.id = id, .addr = (uintptr_t)&val};
+	return ioctl(cpufd, KVM_SET_ONE_REG, &reg);
+}
+
+static int kvm_arm64_get_reg(int cpufd, uint64 id, uint64* val)
+{
+	struct kvm_one_reg reg = {.id = id, .addr = (uintptr_t)val};
+	return ioctl(cpufd, KVM_GET_ONE_REG, &reg);
+}
+static void kvm_arm64_setup_page_tables(char* host_mem, uintptr_t guest_mem_size, uintptr_t page_size, bool el0)
+{
+	uint64* pgd = (uint64*)(host_mem + ARM64_ADDR_PGD);
+	uint64* pmd = (uint64*)(host_mem + ARM64_ADDR_PMD);
+	uint64* pte = (uint64*)(host_mem + ARM64_ADDR_PTE);
+	memset(pgd, 0, page_size);
+	memset(pmd, 0, page_size);
+	memset(pte, 0, page_size);
+	pgd[0] = ARM64_ADDR_PMD | ARM64_PTE_TABLE;
+	pmd[0] = ARM64_ADDR_PTE | ARM64_PTE_TABLE;
+	pmd[ARM64_ADDR_EXIT >> 21] = ARM64_ADDR_EXIT | ARM64_PTE_BLOCK | ARM64_PTE_ATTR_DEVICE | ARM64_PTE_AF;
+	if (el0)
+		pmd[ARM64_ADDR_EXIT >> 21] |= ARM64_PTE_AP_EL0;
+	for (uintptr_t i = 0; i < guest_mem_size / page_size; i++) {
+		uint64 addr = i * page_size;
+		pte[i] = addr | ARM64_PTE_PAGE | ARM64_PTE_ATTR_NORMAL | ARM64_PTE_SH_INNER | ARM64_PTE_AF;
+		if (el0 && addr >= ARM64_ADDR_TEXT)
+			pte[i] |= ARM64_PTE_AP_EL0;
+	}
+}
 static volatile long syz_kvm_setup_cpu(volatile long a0, volatile long a1, volatile long a2, volatile long a3, volatile long a4, volatile long a5, volatile long a6, volatile long a7)
 {
 	const int vmfd = a0;
@@ -7192,9 +7380,6 @@ static volatile long syz_kvm_setup_cpu(volatile long a0, volatile long a1, volat
 	const struct kvm_opt* const opt_array_ptr = (struct kvm_opt*)a6;
 	uintptr_t opt_count = a7;
 
-	(void)flags;
-	(void)opt_count;
-
 	const uintptr_t page_size = 4 << 10;
 	const uintptr_t guest_mem = 0;
 	const uintptr_t guest_mem_size = 24 * page_size;
@@ -7202,13 +7387,13 @@ static volatile long syz_kvm_setup_cpu(volatile long a0, volatile long a1, volat
 	(void)text_count;
 	int text_type = text_array_ptr[0].typ;
 	const void* text = text_array_ptr[0].text;
-	int text_size = text_array_ptr[0].size;
+	uintptr_t text_size = text_array_ptr[0].size;
 	(void)text_type;
-	(void)opt_array_ptr;
 
 	uint32 features = 0;
-	if (opt_count > 1)
-		opt_count = 1;
+	uint64 sctlr_bits = 0;
+	if (opt_count > 2)
+		opt_count = 2;
 	for (uintptr_t i = 0; i < opt_count; i++) {
 		uint64 typ = opt_array_ptr[i].typ;
 		uint64 val = opt_array_ptr[i].val;
@@ -7216,6 +7401,9 @@ static volatile long syz_kvm_setup_cpu(volatile long a0, volatile long a1, volat
 		case 1:
 			features = val;
 			break;
+		case 2:
+			sctlr_bits = val;
+			break;
 		}
 	}
 
@@ -7230,13 +7418,38 @@ static volatile long syz_kvm_setup_cpu(volatile long a0, volatile long a1, volat
 	}
 
 	struct kvm_vcpu_init init;
-	ioctl(cpufd, KVM_ARM_PREFERRED_TARGET, &init);
+	ioctl(vmfd, KVM_ARM_PREFERRED_TARGET, &init);
 	init.features[0] = features;
 	ioctl(cpufd, KVM_ARM_VCPU_INIT, &init);
-
-	if (text_size > 1000)
-		text_size = 1000;
-	memcpy(host_mem, text, text_size);
+	for (uintptr_t i = 0; i < 16; i++)
+		memcpy(host_mem + ARM64_ADDR_VECTORS + i * 0x80, kvm_arm64_vector_exit, sizeof(kvm_arm64_vector_exit));
+
+	const uintptr_t max_text_size = ARM64_ADDR_EL0_STACK - page_size - ARM64_ADDR_TEXT - sizeof(kvm_arm64_text_exit);
+	if (text_size > max_text_size)
+		text_size = max_text_size;
+	text_size &= ~3;
+	memcpy(host_mem + ARM64_ADDR_TEXT, text, text_size);
+	memcpy(host_mem + ARM64_ADDR_TEXT + text_size, kvm_arm64_text_exit, sizeof(kvm_arm64_text_exit));
+
+	const bool el0 = flags & KVM_SETUP_ARM64_EL0;
+	uint64 sctlr = 0;
+	kvm_arm64_get_reg(cpufd, ARM64_SYSREG_SCTLR_EL1, &sctlr);
+	sctlr |= sctlr_bits;
+	if (flags & KVM_SETUP_ARM64_MMU) {
+		kvm_arm64_setup_page_tables(host_mem, guest_mem_size, page_size, el0);
+		kvm_arm64_set_reg(cpufd, ARM64_SYSREG_MAIR_EL1, ARM64_MAIR_EL1);
+		kvm_arm64_set_reg(cpufd, ARM64_SYSREG_TCR_EL1, ARM64_TCR_EL1);
+		kvm_arm64_set_reg(cpufd, ARM64_SYSREG_TTBR0_EL1, ARM64_ADDR_PGD);
+		sctlr |= ARM64_SCTLR_M | ARM64_SCTLR_C | ARM64_SCTLR_I;
+	}
+	kvm_arm64_set_reg(cpufd, ARM64_SYSREG_SCTLR_EL1, sctlr);
+	kvm_arm64_set_reg(cpufd, ARM64_SYSREG_CPACR_EL1, ARM64_CPACR_FPEN);
+	kvm_arm64_set_reg(cpufd, ARM64_SYSREG_VBAR_EL1, ARM64_ADDR_VECTORS);
+	kvm_arm64_set_reg(cpufd, ARM64_CORE_REG(sp_el1), ARM64_ADDR_EL1_STACK);
+	kvm_arm64_set_reg(cpufd, ARM64_CORE_REG(regs.sp), ARM64_ADDR_EL0_STACK);
+	kvm_arm64_set_reg(cpufd, ARM64_CORE_REG(regs.pc), ARM64_ADDR_TEXT);
+	kvm_arm64_set_reg(cpufd, ARM64_CORE_REG(regs.pstate),
+			  ARM64_PSTATE_DAIF | (el0 ? ARM64_PSTATE_EL0t : ARM64_PSTATE_EL1h));
 
 	return 0;
 }
@@ -10590,7 +10803,8 @@ static void loop(void)
 		reset_loop();
 #endif
 #if SYZ_EXECUTOR
-		receive_execute();
+		if (repeat_iter == 0)
+			receive_execute();
 #endif
 		int pid = fork();
 		if (pid < 0)
@@ -10670,7 +10884,12 @@ static void loop(void)
 			errno = 0;
 			fail("child failed");
 		}
-		reply_execute(0);
+		if (repeat_iter < repeat_times) {
+			repeat_iter++;
+		} else {
+			repeat_iter = 0;
+			reply_execute(0);
+		}
 #endif
 #if SYZ_EXECUTOR || SYZ_USE_TMP_DIR
 		remove_dir(cwdbuf);
@@ -0,0 +1,241 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package csource
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/syzkaller/prog"
+	"github.com/google/syzkaller/sys/targets"
+)
+
+// KselftestOptions control generation of kselftest tests from reproducers.
+type KselftestOptions struct {
+	// Name of the test (the test binary and the source file name).
+	Name string
+	// Title of the bug the reproducer triggers, used in the test description.
+	Title string
+	// For how long the reproducer is executed.
+	Timeout time.Duration
+	// Kernel configs required by the test in addition to the ones required by Options
+	// (e.g. CONFIG_FOO or CONFIG_FOO=m).
+	Configs []string
+}
+
+// Kselftest is a kselftest-compatible test (files for a tools/testing/selftests subdirectory).
+type Kselftest struct {
+	Source   []byte // NAME.c
+	Makefile []byte // Makefile
+	Config   []byte // config (required kernel configs)
+	Settings []byte // settings (kselftest runner timeout)
+}
+
+// WriteKselftest wraps C reproducer for p into a kselftest test.
+// The test executes the reproducer in a subprocess for opts.Timeout, then kills it
+// and fails if the kernel became tainted during execution (e.g. due to a WARNING or KASAN report).
+// Results are printed in TAP format. Since the kernel can also just crash or hang,
+// the test is intended to be run under a kselftest runner that detects that.
+func WriteKselftest(p *prog.Prog, opts Options, kopts KselftestOptions) (*Kselftest, error) {
+	if p.Target.OS != targets.Linux {
+		return nil, fmt.Errorf("kselftest tests can only be generated for %v, not %v",
+			targets.Linux, p.Target.OS)
+	}
+	if !kselftestNameRe.MatchString(kopts.Name) {
+		return nil, fmt.Errorf("bad kselftest name %q", kopts.Name)
+	}
+	if kopts.Timeout < time.Second {
+		return nil, fmt.Errorf("kselftest timeout %v is too small", kopts.Timeout)
+	}
+	src, err := Write(p, opts)
+	if err != nil {
+		return nil, err
+	}
+	const mainDecl = "\nint main(void)\n"
+	if bytes.Count(src, []byte(mainDecl)) != 1 {
+		return nil, fmt.Errorf("failed to find main in the generated source")
+	}
+	src = bytes.Replace(src, []byte(mainDecl), []byte("\nstatic int syz_repro_main(void)\n"), 1)
+	configs := kselftestConfigs(opts, kopts.Configs)
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "// SPDX-License-Identifier: GPL-2.0\n")
+	if kopts.Title != "" {
+		fmt.Fprintf(buf, "// Regression test for: %v\n", kopts.Title)
+	}
+	if len(configs) != 0 {
+		fmt.Fprintf(buf, "// Required kernel configs: %v\n", strings.Join(configs, " "))
+	}
+	buf.Write(src)
+	fmt.Fprintf(buf, "\n#define KSFT_NAME \"%v\"\n", kopts.Name)
+	fmt.Fprintf(buf, "#define KSFT_REPRO_TIMEOUT_SEC %v\n", int(kopts.Timeout/time.Second))
+	buf.WriteString(kselftestMain)
+
+	makefile := new(bytes.Buffer)
+	fmt.Fprintf(makefile, "# SPDX-License-Identifier: GPL-2.0\n")
+	fmt.Fprintf(makefile, "CFLAGS += -Wall -O2\n")
+	fmt.Fprintf(makefile, "LDLIBS += -lpthread\n\n")
+	fmt.Fprintf(makefile, "TEST_GEN_PROGS := %v\n\n", kopts.Name)
+	fmt.Fprintf(makefile, "include ../lib.mk\n")
+
+	config := new(bytes.Buffer)
+	for _, cfg := range configs {
+		fmt.Fprintf(config, "%v\n", cfg)
+	}
+
+	// Give the test some time to setup and tear down the reproducer.
+	settings := fmt.Sprintf("timeout=%v\n", int(kopts.Timeout/time.Second)+60)
+	return &Kselftest{
+		Source:   buf.Bytes(),
+		Makefile: makefile.Bytes(),
+		Config:   config.Bytes(),
+		Settings: []byte(settings),
+	}, nil
+}
+
+var kselftestNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// kselftestConfigs returns kernel configs required by the C reproducer generated with opts.
+func kselftestConfigs(opts Options, extra []string) []string {
+	required := map[string]bool{}
+	add := func(enabled bool, configs ...string) {
+		if !enabled {
+			return
+		}
+		for _, cfg := range configs {
+			if !strings.Contains(cfg, "=") {
+				cfg += "=y"
+			}
+			required[cfg] = true
+		}
+	}
+	add(opts.Sandbox == sandboxNamespace, "CONFIG_NAMESPACES", "CONFIG_USER_NS", "CONFIG_NET_NS")
+	add(opts.NetInjection, "CONFIG_TUN")
+	add(opts.NetDevices, "CONFIG_VETH", "CONFIG_BRIDGE", "CONFIG_BONDING")
+	add(opts.Cgroups, "CONFIG_CGROUPS")
+	add(opts.BinfmtMisc, "CONFIG_BINFMT_MISC")
+	add(opts.KCSAN, "CONFIG_KCSAN")
+	add(opts.DevlinkPCI, "CONFIG_NETDEVSIM")
+	add(opts.USB, "CONFIG_USB_RAW_GADGET", "CONFIG_USB_DUMMY_HCD")
+	add(opts.VhciInjection, "CONFIG_BT_HCIVHCI")
+	add(opts.Wifi, "CONFIG_MAC80211_HWSIM")
+	add(opts.IEEE802154, "CONFIG_IEEE802154_HWSIM")
+	add(opts.Leak, "CONFIG_DEBUG_KMEMLEAK")
+	add(opts.Fault, "CONFIG_FAULT_INJECTION", "CONFIG_FAILSLAB", "CONFIG_FAULT_INJECTION_DEBUG_FS")
+	add(true, extra...)
+	var configs []string
+	for cfg := range required {
+		configs = append(configs, cfg)
+	}
+	sort.Strings(configs)
+	return configs
+}
+
+const kselftestMain = `
+#include <dirent.h>
+#include <errno.h>
+#include <signal.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/prctl.h>
+#include <sys/wait.h>
+#include <unistd.h>
+
+#define KSFT_PASS 0
+#define KSFT_FAIL 1
+#define KSFT_SKIP 4
+
+static unsigned long ksft_read_taint(void)
+{
+	unsigned long taint = 0;
+	FILE* f = fopen("/proc/sys/kernel/tainted", "r");
+	if (!f)
+		return 0;
+	if (fscanf(f, "%lu", &taint) != 1)
+		taint = 0;
+	fclose(f);
+	return taint;
+}
+
+// Kills all descendants of the test process. The test is a child subreaper,
+// so descendants are reparented to it when their parents die.
+static void ksft_kill_descendants(void)
+{
+	for (int iter = 0; iter < 100; iter++) {
+		DIR* dir = opendir("/proc");
+		if (!dir)
+			break;
+		struct dirent* ent;
+		while ((ent = readdir(dir))) {
+			char path[64], buf[512];
+			int pid = atoi(ent->d_name);
+			if (pid <= 0)
+				continue;
+			snprintf(path, sizeof(path), "/proc/%d/stat", pid);
+			FILE* f = fopen(path, "r");
+			if (!f)
+				continue;
+			size_t n = fread(buf, 1, sizeof(buf) - 1, f);
+			fclose(f);
+			buf[n] = 0;
+			// The format is "pid (comm) state ppid ...", comm can contain spaces and parens.
+			char* end = strrchr(buf, ')');
+			int ppid = 0;
+			if (end && sscanf(end + 1, " %*c %d", &ppid) == 1 && ppid == getpid())
+				kill(pid, SIGKILL);
+		}
+		closedir(dir);
+		int status = 0;
+		while (waitpid(-1, &status, __WALL | WNOHANG) > 0) {
+		}
+		if (waitpid(-1, &status, __WALL | WNOHANG) == -1 && errno == ECHILD)
+			break;
+		usleep(100 * 1000);
+	}
+}
+
+int main(void)
+{
+	printf("TAP version 13\n1..1\n");
+	fflush(stdout);
+	if (geteuid() != 0) {
+		printf("ok 1 %s # SKIP requires root\n", KSFT_NAME);
+		return KSFT_SKIP;
+	}
+	prctl(PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0);
+	unsigned long taint = ksft_read_taint();
+	int pid = fork();
+	if (pid < 0) {
+		printf("not ok 1 %s # fork failed: %d\n", KSFT_NAME, errno);
+		return KSFT_FAIL;
+	}
+	if (pid == 0) {
+		setpgid(0, 0);
+		// Keep stdout for TAP output only.
+		dup2(2, 1);
+		exit(syz_repro_main());
+	}
+	int status = 0;
+	for (int i = 0; i < KSFT_REPRO_TIMEOUT_SEC * 10; i++) {
+		if (waitpid(pid, &status, __WALL | WNOHANG) == pid)
+			break;
+		usleep(100 * 1000);
+	}
+	kill(-pid, SIGKILL);
+	kill(pid, SIGKILL);
+	ksft_kill_descendants();
+	unsigned long new_taint = ksft_read_taint();
+	if (new_taint & ~taint) {
+		printf("not ok 1 %s # kernel tainted: 0x%lx -> 0x%lx\n", KSFT_NAME, taint, new_taint);
+		return KSFT_FAIL;
+	}
+	printf("ok 1 %s\n", KSFT_NAME);
+	return KSFT_PASS;
+}
+`
@@ -0,0 +1,86 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package csource
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/syzkaller/prog"
+	"github.com/google/syzkaller/sys/targets"
+)
+
+func TestKselftest(t *testing.T) {
+	target, err := prog.GetTarget(targets.Linux, targets.AMD64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := target.Deserialize([]byte(`
+r0 = openat(0xffffffffffffff9c, &(0x7f0000000000)='./file0\x00', 0x42, 0x0)
+close(r0)
+`), prog.Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{
+		Threaded:     true,
+		Repeat:       true,
+		Procs:        2,
+		Slowdown:     1,
+		Sandbox:      sandboxNamespace,
+		NetInjection: true,
+		NetDevices:   true,
+		UseTmpDir:    true,
+	}
+	kopts := KselftestOptions{
+		Name:    "repro-foo",
+		Title:   "KASAN: use-after-free Read in foo",
+		Timeout: 10 * time.Second,
+		Configs: []string{"CONFIG_FOO", "CONFIG_BAR=m"},
+	}
+	test, err := WriteKselftest(p, opts, kopts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"// Regression test for: KASAN: use-after-free Read in foo\n",
+		"#define KSFT_NAME \"repro-foo\"\n",
+		"#define KSFT_REPRO_TIMEOUT_SEC 10\n",
+		"static int syz_repro_main(void)\n",
+	} {
+		if !strings.Contains(string(test.Source), want) {
+			t.Errorf("source does not contain %q", want)
+		}
+	}
+	if !strings.Contains(string(test.Makefile), "TEST_GEN_PROGS := repro-foo\n") {
+		t.Errorf("bad Makefile:\n%s", test.Makefile)
+	}
+	wantConfig := "CONFIG_BAR=m\nCONFIG_BONDING=y\nCONFIG_BRIDGE=y\nCONFIG_FOO=y\nCONFIG_NAMESPACES=y\n" +
+		"CONFIG_NET_NS=y\nCONFIG_TUN=y\nCONFIG_USER_NS=y\nCONFIG_VETH=y\n"
+	if string(test.Config) != wantConfig {
+		t.Errorf("bad config:\n%s\nwant:\n%s", test.Config, wantConfig)
+	}
+	if string(test.Settings) != "timeout=70\n" {
+		t.Errorf("bad settings: %s", test.Settings)
+	}
+	if runtime.GOOS == targets.Linux && runtime.GOARCH == targets.AMD64 {
+		bin, err := Build(target, test.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.Remove(bin)
+	}
+
+	for _, kopts := range []KselftestOptions{
+		{Name: "foo bar", Timeout: time.Minute},
+		{Name: "foo", Timeout: time.Millisecond},
+	} {
+		if _, err := WriteKselftest(p, opts, kopts); err == nil {
+			t.Errorf("%+v: did not fail", kopts)
+		}
+	}
+}
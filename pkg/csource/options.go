@@ -49,6 +49,9 @@ type Options struct {
 	// which allows to detect hangs.
 	Repro bool `json:"repro,omitempty"`
 	Trace bool `json:"trace,omitempty"`
+	// Print every call with its arguments and result to stderr (strace-like output),
+	// which allows to compare behavior of the C program with the original execution.
+	Strace bool `json:"strace,omitempty"`
 	LegacyOptions
 }
 
@@ -112,21 +112,14 @@ func (db *DB) BumpVersion(version uint64) error {
 }
 
 func (db *DB) compact() error {
-	buf := new(bytes.Buffer)
-	serializeHeader(buf, db.Version)
-	for key, rec := range db.Records {
-		serializeRecord(buf, key, rec.Val, rec.Seq)
-	}
-	f, err := os.Create(db.filename + ".tmp")
+	wr, err := NewWriter(db.filename, db.Version)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	if _, err := f.Write(buf.Bytes()); err != nil {
-		return err
+	for key, rec := range db.Records {
+		wr.Write(key, rec.Val, rec.Seq)
 	}
-	f.Close()
-	if err := osutil.Rename(f.Name(), db.filename); err != nil {
+	if err := wr.Close(); err != nil {
 		return err
 	}
 	db.uncompacted = len(db.Records)
@@ -192,7 +185,7 @@ func deserializeDB(r *bufio.Reader) (version uint64, records map[string]Record,
 	}
 	version = ver
 	for {
-		key, val, seq, err := deserializeRecord(r)
+		key, val, seq, err := deserializeRecord(r, false)
 		if err == io.EOF {
 			return
 		}
@@ -235,7 +228,8 @@ func deserializeHeader(r *bufio.Reader) (uint64, error) {
 	return userVer, nil
 }
 
-func deserializeRecord(r *bufio.Reader) (key string, val []byte, seq uint64, err error) {
+// deserializeRecord reads the next record, if skipVal is set the value is skipped without decompression.
+func deserializeRecord(r *bufio.Reader, skipVal bool) (key string, val []byte, seq uint64, err error) {
 	var magic uint32
 	if err = binary.Read(r, binary.LittleEndian, &magic); err != nil {
 		return
@@ -263,6 +257,10 @@ func deserializeRecord(r *bufio.Reader) (key string, val []byte, seq uint64, err
 	if err = binary.Read(r, binary.LittleEndian, &valLen); err != nil {
 		return
 	}
+	if skipVal {
+		_, err = r.Discard(int(valLen))
+		return
+	}
 	if valLen != 0 {
 		fr := flate.NewReader(&io.LimitedReader{R: r, N: int64(valLen)})
 		if val, err = ioutil.ReadAll(fr); err != nil {
@@ -275,37 +273,37 @@ func deserializeRecord(r *bufio.Reader) (key string, val []byte, seq uint64, err
 
 // Create creates a new database in the specified file with the specified records.
 func Create(filename string, version uint64, records []Record) error {
-	os.Remove(filename)
-	db, err := Open(filename, true)
+	wr, err := NewWriter(filename, version)
 	if err != nil {
-		return fmt.Errorf("failed to open database file: %v", err)
-	}
-	if err := db.BumpVersion(version); err != nil {
-		return fmt.Errorf("failed to bump database version: %v", err)
+		return fmt.Errorf("failed to create database file: %v", err)
 	}
 	for _, rec := range records {
-		db.Save(hash.String(rec.Val), rec.Val, rec.Seq)
+		wr.Write(hash.String(rec.Val), rec.Val, rec.Seq)
 	}
-	if err := db.Flush(); err != nil {
+	if err := wr.Close(); err != nil {
 		return fmt.Errorf("failed to save database file: %v", err)
 	}
 	return nil
 }
 
 func ReadCorpus(filename string, target *prog.Target) (progs []*prog.Prog, err error) {
-	if filename == "" {
+	if filename == "" || !osutil.IsExist(filename) {
 		return
 	}
-	db, err := Open(filename, false)
+	rd, err := OpenReader(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database file: %v", err)
 	}
-	for _, rec := range db.Records {
+	err = rd.Iterate(func(key string, rec Record) error {
 		p, err := target.Deserialize(rec.Val, prog.NonStrict)
 		if err != nil {
-			return nil, fmt.Errorf("failed to deserialize corpus program: %v", err)
+			return fmt.Errorf("failed to deserialize corpus program: %v", err)
 		}
 		progs = append(progs, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return progs, nil
 }
@@ -198,3 +198,107 @@ func tempFile(t *testing.T) string {
 	}
 	return fn
 }
+
+func TestStream(t *testing.T) {
+	fn := tempFile(t)
+	defer os.Remove(fn)
+	db, err := Open(fn, false)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	db.Save("1", []byte("ab"), 0)
+	db.Save("23", nil, 1)
+	db.Save("456", []byte("abcd"), 1)
+	db.Delete("23")
+	db.Save("456", []byte("ef"), 6)
+	db.Save("7890", []byte("bc"), 0)
+	db.Delete("1")
+	db.Save("1", []byte("x"), 2)
+	if err := db.BumpVersion(42); err != nil {
+		t.Fatal(err)
+	}
+	db.Save("7890", []byte("cd"), 3)
+	if err := db.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	rd, err := OpenReader(fn)
+	if err != nil {
+		t.Fatalf("failed to open reader: %v", err)
+	}
+	if rd.Version != 42 || rd.Len() != len(db.Records) {
+		t.Fatalf("reader has version %v and %v records, want 42 and %v", rd.Version, rd.Len(), len(db.Records))
+	}
+	got := make(map[string]Record)
+	iterate := func(rd *Reader) {
+		err := rd.Iterate(func(key string, rec Record) error {
+			if _, ok := got[key]; ok {
+				t.Fatalf("duplicate key %q", key)
+			}
+			got[key] = rec
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("iteration failed: %v", err)
+		}
+	}
+	iterate(rd)
+	if !reflect.DeepEqual(got, db.Records) {
+		t.Fatalf("bad records: %v, want: %v", got, db.Records)
+	}
+
+	fn2 := tempFile(t)
+	defer os.Remove(fn2)
+	wr, err := NewWriter(fn2, 7)
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	for key, rec := range got {
+		if err := wr.Write(key, rec.Val, rec.Seq); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	if err := wr.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	db2, err := Open(fn2, false)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	if db2.Version != 7 || !reflect.DeepEqual(db2.Records, db.Records) {
+		t.Fatalf("bad written db: version %v, records %v", db2.Version, db2.Records)
+	}
+}
+
+func TestAppender(t *testing.T) {
+	fn := tempFile(t)
+	defer os.Remove(fn)
+	ap, err := OpenAppender(fn, 3)
+	if err != nil {
+		t.Fatalf("failed to open appender: %v", err)
+	}
+	ap.Append("1", []byte("ab"), 0)
+	ap.Append("2", nil, 1)
+	if err := ap.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Reopening must not write the header again.
+	ap, err = OpenAppender(fn, 5)
+	if err != nil {
+		t.Fatalf("failed to open appender: %v", err)
+	}
+	ap.Append("1", []byte("cd"), 2)
+	if err := ap.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db, err := Open(fn, false)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	want := map[string]Record{
+		"1": {[]byte("cd"), 2},
+		"2": {nil, 1},
+	}
+	if db.Version != 3 || !reflect.DeepEqual(db.Records, want) {
+		t.Fatalf("bad appended db: version %v, records %v", db.Version, db.Records)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/syzkaller/pkg/osutil"
+)
+
+// Reader provides streaming access to records of a database file without loading
+// all values in memory (only keys are kept in memory). This allows to process
+// large databases on machines that can't hold the whole database in memory.
+type Reader struct {
+	Version uint64 // user version of the database
+
+	filename string
+	live     map[string]int // key -> index of the record that holds the current value
+}
+
+// OpenReader indexes records of the database file. The file must not be modified
+// while the reader is used.
+func OpenReader(filename string) (*Reader, error) {
+	rd := &Reader{
+		filename: filename,
+		live:     make(map[string]int),
+	}
+	idx := 0
+	version, err := rd.scan(true, func(key string, val []byte, seq uint64) error {
+		if seq == seqDeleted {
+			delete(rd.live, key)
+		} else {
+			rd.live[key] = idx
+		}
+		idx++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	rd.Version = version
+	return rd, nil
+}
+
+// Len returns number of records in the database.
+func (rd *Reader) Len() int {
+	return len(rd.live)
+}
+
+// Iterate calls fn for every record of the database in the order they are stored in the file.
+// Iteration stops on the first error returned by fn.
+func (rd *Reader) Iterate(fn func(key string, rec Record) error) error {
+	idx := 0
+	_, err := rd.scan(false, func(key string, val []byte, seq uint64) error {
+		cur := idx
+		idx++
+		if seq == seqDeleted || rd.live[key] != cur {
+			return nil
+		}
+		return fn(key, Record{val, seq})
+	})
+	return err
+}
+
+func (rd *Reader) scan(skipVal bool, fn func(key string, val []byte, seq uint64) error) (uint64, error) {
+	f, err := os.Open(rd.filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	version, err := deserializeHeader(r)
+	if err != nil {
+		return 0, fmt.Errorf("failed to deserialize database header: %v", err)
+	}
+	for {
+		key, val, seq, err := deserializeRecord(r, skipVal)
+		if err == io.EOF {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to deserialize database record: %v", err)
+		}
+		if err := fn(key, val, seq); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Writer creates a new database file record-by-record.
+// The file is written to a temporary location and replaces filename only on Close.
+type Writer struct {
+	filename string
+	f        *os.File
+	w        *bufio.Writer
+	buf      *bytes.Buffer
+	err      error
+}
+
+func NewWriter(filename string, version uint64) (*Writer, error) {
+	f, err := os.Create(filename + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	wr := &Writer{
+		filename: filename,
+		f:        f,
+		w:        bufio.NewWriter(f),
+		buf:      new(bytes.Buffer),
+	}
+	serializeHeader(wr.buf, version)
+	wr.flushBuf()
+	return wr, nil
+}
+
+func (wr *Writer) Write(key string, val []byte, seq uint64) error {
+	if seq == seqDeleted {
+		panic("reserved seq")
+	}
+	serializeRecord(wr.buf, key, val, seq)
+	wr.flushBuf()
+	return wr.err
+}
+
+func (wr *Writer) flushBuf() {
+	if wr.err == nil {
+		_, wr.err = wr.w.Write(wr.buf.Bytes())
+	}
+	wr.buf.Reset()
+}
+
+// Close finishes writing and atomically replaces the database file.
+// If any of the writes failed, the database file is left intact and the error is returned.
+func (wr *Writer) Close() error {
+	err := wr.err
+	if err == nil {
+		err = wr.w.Flush()
+	}
+	if err1 := wr.f.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = osutil.Rename(wr.f.Name(), wr.filename)
+	}
+	if err != nil {
+		os.Remove(wr.f.Name())
+	}
+	return err
+}
+
+// Appender adds records to the end of a database file without loading it in memory.
+// This is suitable for append-only logs that grow too large to be held by DB.
+type Appender struct {
+	f   *os.File
+	buf *bytes.Buffer
+}
+
+// OpenAppender opens the database file for appending, the file is created if it does not exist.
+func OpenAppender(filename string, version uint64) (*Appender, error) {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_APPEND|os.O_CREATE, osutil.DefaultFilePerm)
+	if err != nil {
+		return nil, err
+	}
+	ap := &Appender{
+		f:   f,
+		buf: new(bytes.Buffer),
+	}
+	if _, err := deserializeHeader(bufio.NewReader(f)); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to deserialize database header: %v", err)
+	}
+	if st, err := f.Stat(); err != nil || st.Size() == 0 {
+		serializeHeader(ap.buf, version)
+		if err := ap.flushBuf(); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return ap, nil
+}
+
+// Append writes the record to the end of the file.
+// Every record is written with a single write call, so a crash leaves at most one truncated record.
+func (ap *Appender) Append(key string, val []byte, seq uint64) error {
+	if seq == seqDeleted {
+		panic("reserved seq")
+	}
+	serializeRecord(ap.buf, key, val, seq)
+	return ap.flushBuf()
+}
+
+func (ap *Appender) flushBuf() error {
+	_, err := ap.f.Write(ap.buf.Bytes())
+	ap.buf.Reset()
+	return err
+}
+
+func (ap *Appender) Close() error {
+	return ap.f.Close()
+}
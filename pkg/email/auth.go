@@ -0,0 +1,89 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package email
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+// senderAuthenticated checks the Authentication-Results header (RFC 8601) added by the receiving
+// mail server and says if the sender in the From header is authenticated by DKIM, SPF or DMARC.
+// Only the topmost header must be passed here: it's the one added by our mail server,
+// the rest can be forged by the sender.
+func senderAuthenticated(authResults, from string) bool {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return false
+	}
+	fromDomain := strings.ToLower(addr.Address[strings.LastIndexByte(addr.Address, '@')+1:])
+	authResults = authCommentRe.ReplaceAllString(authResults, "")
+	// The first element is the authserv-id.
+	for _, res := range strings.Split(authResults, ";")[1:] {
+		fields := strings.Fields(res)
+		if len(fields) == 0 {
+			continue
+		}
+		method := strings.ToLower(fields[0])
+		if method != "dkim=pass" && method != "spf=pass" && method != "dmarc=pass" {
+			continue
+		}
+		for _, prop := range fields[1:] {
+			eq := strings.IndexByte(prop, '=')
+			if eq == -1 {
+				continue
+			}
+			key, val := strings.ToLower(prop[:eq]), strings.ToLower(prop[eq+1:])
+			switch {
+			case method == "dkim=pass" && (key == "header.d" || key == "header.i"),
+				method == "spf=pass" && key == "smtp.mailfrom",
+				method == "dmarc=pass" && key == "header.from":
+				if domainsAligned(fromDomain, val[strings.LastIndexByte(val, '@')+1:]) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+var authCommentRe = regexp.MustCompile(`\([^)]*\)`)
+
+// domainsAligned implements relaxed alignment: one domain must be a subdomain of the other.
+func domainsAligned(from, authenticated string) bool {
+	return authenticated != "" && (from == authenticated ||
+		strings.HasSuffix(from, "."+authenticated) ||
+		strings.HasSuffix(authenticated, "."+from))
+}
+
+const tokenPrefix = "#syz-token:"
+
+// CommandToken returns a token that authenticates commands of the sender for the bug
+// when the sender's mail server does not support DKIM/SPF.
+// The token is included in the email as "#syz-token: TOKEN" line.
+func CommandToken(secret, bugID, sender string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(bugID + "|" + CanonicalEmail(sender)))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// CheckCommandToken checks that the email contains a valid command token for the bug.
+func CheckCommandToken(msg *Email, secret, bugID string) bool {
+	return msg.Token != "" && secret != "" &&
+		hmac.Equal([]byte(msg.Token), []byte(CommandToken(secret, bugID, msg.From)))
+}
+
+func extractToken(body string) string {
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, tokenPrefix) {
+			return strings.TrimSpace(line[len(tokenPrefix):])
+		}
+	}
+	return ""
+}
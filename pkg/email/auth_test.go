@@ -0,0 +1,99 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package email
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSenderAuthenticated(t *testing.T) {
+	tests := []struct {
+		results string
+		from    string
+		auth    bool
+	}{
+		{
+			results: "mx.google.com; dkim=pass header.i=@kernel.org header.s=k20201202 header.b=abc",
+			from:    "Foo Bar <foo@kernel.org>",
+			auth:    true,
+		},
+		{
+			results: "mx.google.com; dkim=pass header.i=@mail.kernel.org",
+			from:    "foo@kernel.org",
+			auth:    true,
+		},
+		{
+			results: "mx.google.com; dkim=fail header.i=@kernel.org; spf=softfail smtp.mailfrom=foo@kernel.org",
+			from:    "foo@kernel.org",
+			auth:    false,
+		},
+		{
+			results: "mx.google.com; dkim=pass header.i=@evil.com; spf=pass smtp.mailfrom=foo@evil.com",
+			from:    "foo@kernel.org",
+			auth:    false,
+		},
+		{
+			results: "mx.google.com; spf=pass (google.com: domain of foo@kernel.org designates 1.2.3.4 " +
+				"as permitted sender) smtp.mailfrom=foo@kernel.org",
+			from: "foo@kernel.org",
+			auth: true,
+		},
+		{
+			results: "mx.google.com; dmarc=pass (p=NONE sp=NONE dis=NONE) header.from=kernel.org",
+			from:    "foo@KERNEL.org",
+			auth:    true,
+		},
+		{
+			results: "mx.google.com; dkim=pass header.i=@notkernel.org",
+			from:    "foo@kernel.org",
+			auth:    false,
+		},
+		{
+			// Authserv-id must not be interpreted as a result.
+			results: "dkim=pass header.i=@kernel.org",
+			from:    "foo@kernel.org",
+			auth:    false,
+		},
+		{
+			results: "mx.google.com; dkim=pass header.i=@kernel.org",
+			from:    "not an email",
+			auth:    false,
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			if got := senderAuthenticated(test.results, test.from); got != test.auth {
+				t.Fatalf("got %v, want %v", got, test.auth)
+			}
+		})
+	}
+}
+
+func TestCommandToken(t *testing.T) {
+	const secret, bugID = "secret", "0123456789abcdef"
+	token := CommandToken(secret, bugID, "Foo Bar <foo+bar@kernel.org>")
+	body := fmt.Sprintf("#syz invalid\n%v %v\n", tokenPrefix, token)
+	msg := &Email{
+		From:  "foo@kernel.org",
+		Token: extractToken(body),
+	}
+	if !CheckCommandToken(msg, secret, bugID) {
+		t.Fatalf("valid token %q is rejected", msg.Token)
+	}
+	if CheckCommandToken(msg, secret, "fedcba9876543210") {
+		t.Fatalf("token is accepted for another bug")
+	}
+	if CheckCommandToken(msg, "another secret", bugID) {
+		t.Fatalf("token is accepted with another secret")
+	}
+	msg.From = "bar@kernel.org"
+	if CheckCommandToken(msg, secret, bugID) {
+		t.Fatalf("token is accepted for another sender")
+	}
+	msg.Token = ""
+	if CheckCommandToken(msg, "", bugID) {
+		t.Fatalf("empty token is accepted")
+	}
+}
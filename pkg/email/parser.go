@@ -29,6 +29,9 @@ type Email struct {
 	Command     Command // command to bot
 	CommandStr  string  // string representation of the command
 	CommandArgs string  // arguments for the command
+	// Set if the receiving mail server authenticated the From address with DKIM/SPF/DMARC.
+	Authenticated bool
+	Token         string // command token (see CommandToken), if any
 }
 
 type Command int
@@ -104,7 +107,7 @@ func Parse(r io.Reader, ownEmails []string) (*Email, error) {
 	bodyStr := string(body)
 	subject := msg.Header.Get("Subject")
 	cmd := CmdNone
-	patch, cmdStr, cmdArgs := "", "", ""
+	patch, cmdStr, cmdArgs, token := "", "", "", ""
 	if !fromMe {
 		for _, a := range attachments {
 			_, patch, _ = ParsePatch(string(a))
@@ -116,23 +119,31 @@ func Parse(r io.Reader, ownEmails []string) (*Email, error) {
 			_, patch, _ = ParsePatch(bodyStr)
 		}
 		cmd, cmdStr, cmdArgs = extractCommand(subject + "\n" + bodyStr)
+		token = extractToken(bodyStr)
+	}
+	authenticated := false
+	// Only the topmost header is added by our mail server, the rest may be forged.
+	if authResults := msg.Header["Authentication-Results"]; len(authResults) != 0 {
+		authenticated = senderAuthenticated(authResults[0], from[0].String())
 	}
 	link := ""
 	if match := groupsLinkRe.FindStringSubmatchIndex(bodyStr); match != nil {
 		link = bodyStr[match[2]:match[3]]
 	}
 	email := &Email{
-		BugID:       bugID,
-		MessageID:   msg.Header.Get("Message-ID"),
-		Link:        link,
-		Subject:     subject,
-		From:        from[0].String(),
-		Cc:          ccList,
-		Body:        bodyStr,
-		Patch:       patch,
-		Command:     cmd,
-		CommandStr:  cmdStr,
-		CommandArgs: cmdArgs,
+		BugID:         bugID,
+		MessageID:     msg.Header.Get("Message-ID"),
+		Link:          link,
+		Subject:       subject,
+		From:          from[0].String(),
+		Cc:            ccList,
+		Body:          bodyStr,
+		Patch:         patch,
+		Command:       cmd,
+		CommandStr:    cmdStr,
+		CommandArgs:   cmdArgs,
+		Authenticated: authenticated,
+		Token:         token,
 	}
 	return email, nil
 }
@@ -0,0 +1,61 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPSender sends plain text emails through an SMTP server.
+type SMTPSender struct {
+	// Server is the host:port of the SMTP server.
+	Server string
+	From   string
+	// User and Password are used for PLAIN authentication if User is not empty.
+	User     string
+	Password string
+}
+
+// Send sends an email with the subject and the body to all recipients.
+func (s *SMTPSender) Send(to []string, subject, body string) error {
+	if len(to) == 0 {
+		return fmt.Errorf("no email recipients")
+	}
+	var auth smtp.Auth
+	if s.User != "" {
+		host, _, err := net.SplitHostPort(s.Server)
+		if err != nil {
+			return fmt.Errorf("bad SMTP server %q: %v", s.Server, err)
+		}
+		auth = smtp.PlainAuth("", s.User, s.Password, host)
+	}
+	msg := FormMessage(s.From, to, subject, body, time.Now())
+	if err := smtp.SendMail(s.Server, auth, s.From, to, msg); err != nil {
+		return fmt.Errorf("failed to send email: %v", err)
+	}
+	return nil
+}
+
+// FormMessage returns a plain text RFC 5322 message.
+func FormMessage(from string, to []string, subject, body string, date time.Time) []byte {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "From: %v\r\n", from)
+	fmt.Fprintf(buf, "To: %v\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(buf, "Subject: %v\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(buf, "Date: %v\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(buf, "Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	fmt.Fprintf(buf, "\r\n")
+	for _, line := range strings.Split(strings.TrimSuffix(body, "\n"), "\n") {
+		buf.WriteString(strings.TrimSuffix(line, "\r"))
+		buf.WriteString("\r\n")
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,36 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package email
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestFormMessage(t *testing.T) {
+	date := time.Date(2021, 8, 20, 10, 30, 0, 0, time.UTC)
+	msg := FormMessage("verifier@example.com", []string{"a@example.com", "b@example.com"},
+		"new mismatch in read", "line1\nline2\n", date)
+	want := "From: verifier@example.com\r\n" +
+		"To: a@example.com, b@example.com\r\n" +
+		"Subject: new mismatch in read\r\n" +
+		"Date: Fri, 20 Aug 2021 10:30:00 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=\"utf-8\"\r\n" +
+		"\r\n" +
+		"line1\r\n" +
+		"line2\r\n"
+	if string(msg) != want {
+		t.Fatalf("bad message:\n%q\nwant:\n%q", msg, want)
+	}
+	// Parse must be able to read the message back.
+	email, err := Parse(bytes.NewReader(msg), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if email.Subject != "new mismatch in read" || email.Body != "line1\r\nline2\r\n" {
+		t.Fatalf("bad parsed message: %q %q", email.Subject, email.Body)
+	}
+}
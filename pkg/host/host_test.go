@@ -5,11 +5,13 @@ package host
 
 import (
 	"fmt"
+	"path/filepath"
 	"runtime"
 	"testing"
 
 	"github.com/google/syzkaller/prog"
 	_ "github.com/google/syzkaller/sys"
+	"github.com/google/syzkaller/sys/targets"
 )
 
 func TestDetectSupportedSyscalls(t *testing.T) {
@@ -50,6 +52,52 @@ func TestDetectSupportedSyscalls(t *testing.T) {
 	}
 }
 
+func TestDetectSupportedSyscallsCached(t *testing.T) {
+	// Note: this test is not parallel because it modifies global kernelBuildID var.
+	oldBuildID := kernelBuildID
+	defer func() { kernelBuildID = oldBuildID }()
+	buildID := "1"
+	kernelBuildID = func() (string, error) { return buildID, nil }
+	target, err := prog.GetTarget(targets.TestOS, targets.TestArch64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enabled := make(map[*prog.Syscall]bool)
+	for _, c := range target.Syscalls[:len(target.Syscalls)/2] {
+		enabled[c] = true
+	}
+	cacheFile := filepath.Join(t.TempDir(), "syscalls")
+	supp0, unsupp0, err := DetectSupportedSyscallsCached(target, "none", enabled, cacheFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Corrupt the cached results to check that they are used.
+	key, err := syscallCacheKey(target, "none", enabled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := saveSyscallCache(cacheFile, key, nil); err != nil {
+		t.Fatal(err)
+	}
+	supp1, unsupp1, err := DetectSupportedSyscallsCached(target, "none", enabled, cacheFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unsupp1) != 0 || len(supp1) != len(target.Syscalls) {
+		t.Fatalf("cached results are not used")
+	}
+	// New kernel must invalidate the cache.
+	buildID = "2"
+	supp2, unsupp2, err := DetectSupportedSyscallsCached(target, "none", enabled, cacheFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(supp2) != len(supp0) || len(unsupp2) != len(unsupp0) {
+		t.Fatalf("stale cache is used: supported %v/%v, unsupported %v/%v",
+			len(supp2), len(supp0), len(unsupp2), len(unsupp0))
+	}
+}
+
 func TestCheck(t *testing.T) {
 	t.Parallel()
 	target, err := prog.GetTarget(runtime.GOOS, runtime.GOARCH)
@@ -4,7 +4,14 @@
 package host
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/google/syzkaller/pkg/hash"
 	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/pkg/osutil"
 	"github.com/google/syzkaller/prog"
 )
 
@@ -68,4 +75,95 @@ func DetectSupportedSyscalls(target *prog.Target, sandbox string, enabled map[*p
 	return supported, unsupported, nil
 }
 
+// DetectSupportedSyscallsCached is the same as DetectSupportedSyscalls,
+// but it saves the results to cacheFile and reuses them on next invocations.
+// The cache is keyed by the kernel build id, descriptions revision, sandbox and the set of enabled syscalls.
+// If the kernel build id can't be determined, the cache is not used.
+func DetectSupportedSyscallsCached(target *prog.Target, sandbox string, enabled map[*prog.Syscall]bool,
+	cacheFile string) (map[*prog.Syscall]bool, map[*prog.Syscall]string, error) {
+	key, err := syscallCacheKey(target, sandbox, enabled)
+	if err != nil {
+		log.Logf(1, "not using syscall cache: %v", err)
+		return DetectSupportedSyscalls(target, sandbox, enabled)
+	}
+	if supported, unsupported, err := loadSyscallCache(target, cacheFile, key); err == nil {
+		log.Logf(1, "loaded supported syscalls from %v", cacheFile)
+		return supported, unsupported, nil
+	}
+	supported, unsupported, err := DetectSupportedSyscalls(target, sandbox, enabled)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := saveSyscallCache(cacheFile, key, unsupported); err != nil {
+		log.Logf(0, "failed to save syscall cache: %v", err)
+	}
+	return supported, unsupported, nil
+}
+
+type syscallCache struct {
+	Key string
+	// Unsupported syscall names with reasons, the rest of syscalls are supported.
+	Unsupported map[string]string
+}
+
+func syscallCacheKey(target *prog.Target, sandbox string, enabled map[*prog.Syscall]bool) (string, error) {
+	if kernelBuildID == nil {
+		return "", fmt.Errorf("kernel build id is not supported on %v", target.OS)
+	}
+	buildID, err := kernelBuildID()
+	if err != nil {
+		return "", err
+	}
+	var ids []int
+	for c := range enabled {
+		ids = append(ids, c.ID)
+	}
+	sort.Ints(ids)
+	return hash.String([]byte(fmt.Sprintf("%v/%v/%v/%v/%v/%v",
+		buildID, target.OS, target.Arch, target.Revision, sandbox, ids))), nil
+}
+
+func loadSyscallCache(target *prog.Target, cacheFile, key string) (
+	map[*prog.Syscall]bool, map[*prog.Syscall]string, error) {
+	data, err := ioutil.ReadFile(cacheFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	cache := new(syscallCache)
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, nil, err
+	}
+	if cache.Key != key {
+		return nil, nil, fmt.Errorf("stale syscall cache")
+	}
+	supported := make(map[*prog.Syscall]bool)
+	unsupported := make(map[*prog.Syscall]string)
+	for _, c := range target.Syscalls {
+		if reason, ok := cache.Unsupported[c.Name]; ok {
+			unsupported[c] = reason
+		} else {
+			supported[c] = true
+		}
+	}
+	return supported, unsupported, nil
+}
+
+func saveSyscallCache(cacheFile, key string, unsupported map[*prog.Syscall]string) error {
+	cache := &syscallCache{
+		Key:         key,
+		Unsupported: make(map[string]string),
+	}
+	for c, reason := range unsupported {
+		cache.Unsupported[c.Name] = reason
+	}
+	data, err := json.MarshalIndent(cache, "", "\t")
+	if err != nil {
+		return err
+	}
+	return osutil.WriteFile(cacheFile, data)
+}
+
+// kernelBuildID returns an identifier of the running kernel, nil if not supported on the OS.
+var kernelBuildID func() (string, error)
+
 var testFallback = false
@@ -5,6 +5,8 @@ package host
 
 import (
 	"bytes"
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -16,6 +18,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/google/syzkaller/pkg/btf"
 	"github.com/google/syzkaller/pkg/osutil"
 	"github.com/google/syzkaller/prog"
 	"github.com/google/syzkaller/sys/targets"
@@ -55,18 +58,29 @@ func isSupportedSyscall(c *prog.Syscall, target *prog.Target) (bool, string) {
 	//    For example, on x86_64 it says that sendfile is not present (only sendfile64).
 	// 3. Check sys_syscallname in /proc/kallsyms.
 	//    Requires CONFIG_KALLSYMS.
-	// Kallsyms seems to be the most reliable and fast. That's what we use first.
-	// If kallsyms is not present, we fallback to execution of syscalls.
-	kallsymsOnce.Do(func() {
-		kallsyms, _ := ioutil.ReadFile("/proc/kallsyms")
-		if len(kallsyms) == 0 {
-			return
+	// 4. Check sys_syscallname function in kernel BTF (/sys/kernel/btf/vmlinux).
+	//    Requires CONFIG_DEBUG_INFO_BTF, but works when kallsyms are hidden.
+	// Kallsyms and BTF seem to be the most reliable and fast. That's what we use first.
+	// Syscalls that are not present in either of them are double-checked by execution,
+	// because names of some syscall functions diverge from the syscall names.
+	// If neither kallsyms nor BTF is present, we fallback to execution of syscalls.
+	symbolsOnce.Do(func() {
+		if kallsyms, _ := ioutil.ReadFile("/proc/kallsyms"); len(kallsyms) != 0 {
+			kallsymsSyscallSet = parseKallsyms(kallsyms, target.Arch)
+		}
+		if types, err := btf.Load("/sys/kernel/btf/vmlinux"); err == nil {
+			btfSyscallSet = parseBTFSyscalls(types, target.Arch)
 		}
-		kallsymsSyscallSet = parseKallsyms(kallsyms, target.Arch)
 	})
-	if !testFallback && len(kallsymsSyscallSet) != 0 {
-		r, v := isSupportedKallsyms(c)
-		return r, v
+	if !testFallback && (len(kallsymsSyscallSet) != 0 || len(btfSyscallSet) != 0) {
+		ok, reason := isSupportedSymbols(c)
+		if ok {
+			return true, ""
+		}
+		if ok, _ := isSupportedTrial(c); ok {
+			return true, ""
+		}
+		return false, reason + " and returns ENOSYS"
 	}
 	return isSupportedTrial(c)
 }
@@ -106,15 +120,65 @@ func parseKallsyms(kallsyms []byte, arch string) map[string]bool {
 	return set
 }
 
-func isSupportedKallsyms(c *prog.Syscall) (bool, string) {
+// parseBTFSyscalls returns names of syscalls that have functions in the kernel BTF.
+func parseBTFSyscalls(types []*btf.Type, arch string) map[string]bool {
+	// Function names follow the same conventions as kallsyms symbols.
+	buf := new(bytes.Buffer)
+	for _, typ := range types {
+		if typ != nil && typ.Kind == btf.KindFunc {
+			fmt.Fprintf(buf, "0 T %v\n", typ.Name)
+		}
+	}
+	return parseKallsyms(buf.Bytes(), arch)
+}
+
+func isSupportedSymbols(c *prog.Syscall) (bool, string) {
 	name := c.CallName
 	if newname := kallsymsRenameMap[name]; newname != "" {
 		name = newname
 	}
-	if !kallsymsSyscallSet[name] {
-		return false, fmt.Sprintf("sys_%v is not enabled in the kernel (not present in /proc/kallsyms)", name)
+	if kallsymsSyscallSet[name] || btfSyscallSet[name] {
+		return true, ""
 	}
-	return true, ""
+	var sources []string
+	if len(kallsymsSyscallSet) != 0 {
+		sources = append(sources, "/proc/kallsyms")
+	}
+	if len(btfSyscallSet) != 0 {
+		sources = append(sources, "BTF")
+	}
+	return false, fmt.Sprintf("sys_%v is not enabled in the kernel (not present in %v)",
+		name, strings.Join(sources, " and "))
+}
+
+// linuxKernelBuildID returns GNU build id of the running kernel from /sys/kernel/notes.
+func linuxKernelBuildID() (string, error) {
+	notes, err := ioutil.ReadFile("/sys/kernel/notes")
+	if err != nil {
+		return "", err
+	}
+	return parseBuildID(notes)
+}
+
+func parseBuildID(notes []byte) (string, error) {
+	const noteGNUBuildID = 3
+	align := func(v uint32) uint32 { return (v + 3) &^ 3 }
+	for len(notes) >= 12 {
+		nameSize := binary.LittleEndian.Uint32(notes[0:])
+		descSize := binary.LittleEndian.Uint32(notes[4:])
+		typ := binary.LittleEndian.Uint32(notes[8:])
+		notes = notes[12:]
+		if uint64(align(nameSize))+uint64(align(descSize)) > uint64(len(notes)) {
+			break
+		}
+		name := notes[:nameSize]
+		desc := notes[align(nameSize) : align(nameSize)+descSize]
+		notes = notes[align(nameSize)+align(descSize):]
+		if typ == noteGNUBuildID && string(name) == "GNU\x00" {
+			return hex.EncodeToString(desc), nil
+		}
+	}
+	return "", fmt.Errorf("no GNU build id note")
 }
 
 func isSupportedTrial(c *prog.Syscall) (bool, string) {
@@ -138,6 +202,7 @@ func isSupportedTrial(c *prog.Syscall) (bool, string) {
 }
 
 func init() {
+	kernelBuildID = linuxKernelBuildID
 	str := os.Getenv("SYZ_TRIAL_TEST")
 	if str == "" {
 		return
@@ -158,8 +223,9 @@ func init() {
 // umount2 is renamed to umount in arch/x86/entry/syscalls/syscall_64.tbl.
 // Where umount is renamed to oldumount is unclear.
 var (
-	kallsymsOnce       sync.Once
+	symbolsOnce        sync.Once
 	kallsymsSyscallSet map[string]bool
+	btfSyscallSet      map[string]bool
 	kallsymsRenameMap  = map[string]string{
 		"umount":  "oldumount",
 		"umount2": "umount",
@@ -289,35 +355,41 @@ func isSyzUsbIPSupported(c *prog.Syscall, target *prog.Target, sandbox string) (
 }
 
 var syzkallSupport = map[string]func(*prog.Syscall, *prog.Target, string) (bool, string){
-	"syz_open_dev":                isSyzOpenDevSupported,
-	"syz_open_procfs":             isSyzOpenProcfsSupported,
-	"syz_open_pts":                alwaysSupported,
-	"syz_execute_func":            alwaysSupported,
-	"syz_emit_ethernet":           isNetInjectionSupported,
-	"syz_extract_tcp_res":         isNetInjectionSupported,
-	"syz_usb_connect":             isSyzUsbSupported,
-	"syz_usb_connect_ath9k":       isSyzUsbSupported,
-	"syz_usb_disconnect":          isSyzUsbSupported,
-	"syz_usb_control_io":          isSyzUsbSupported,
-	"syz_usb_ep_write":            isSyzUsbSupported,
-	"syz_usb_ep_read":             isSyzUsbSupported,
-	"syz_kvm_setup_cpu":           isSyzKvmSetupCPUSupported,
-	"syz_emit_vhci":               isVhciInjectionSupported,
-	"syz_init_net_socket":         isSyzInitNetSocketSupported,
-	"syz_genetlink_get_family_id": isSyzGenetlinkGetFamilyIDSupported,
-	"syz_mount_image":             isSyzMountImageSupported,
-	"syz_read_part_table":         isSyzReadPartTableSupported,
-	"syz_io_uring_submit":         isSyzIoUringSupported,
-	"syz_io_uring_complete":       isSyzIoUringSupported,
-	"syz_io_uring_setup":          isSyzIoUringSupported,
-	"syz_memcpy_off":              isSyzMemcpySupported,
-	"syz_btf_id_by_name":          isBtfVmlinuxSupported,
-	"syz_fuse_handle_req":         isSyzFuseSupported,
-	"syz_80211_inject_frame":      isWifiEmulationSupported,
-	"syz_80211_join_ibss":         isWifiEmulationSupported,
-	"syz_usbip_server_init":       isSyzUsbIPSupported,
-	"syz_clone":                   alwaysSupported,
-	"syz_clone3":                  alwaysSupported,
+	"syz_open_dev":                    isSyzOpenDevSupported,
+	"syz_open_procfs":                 isSyzOpenProcfsSupported,
+	"syz_open_pts":                    alwaysSupported,
+	"syz_execute_func":                alwaysSupported,
+	"syz_emit_ethernet":               isNetInjectionSupported,
+	"syz_extract_tcp_res":             isNetInjectionSupported,
+	"syz_usb_connect":                 isSyzUsbSupported,
+	"syz_usb_connect_ath9k":           isSyzUsbSupported,
+	"syz_usb_disconnect":              isSyzUsbSupported,
+	"syz_usb_control_io":              isSyzUsbSupported,
+	"syz_usb_ep_write":                isSyzUsbSupported,
+	"syz_usb_ep_read":                 isSyzUsbSupported,
+	"syz_kvm_setup_cpu":               isSyzKvmSetupCPUSupported,
+	"syz_emit_vhci":                   isVhciInjectionSupported,
+	"syz_init_net_socket":             isSyzInitNetSocketSupported,
+	"syz_genetlink_get_family_id":     isSyzGenetlinkGetFamilyIDSupported,
+	"syz_mount_image":                 isSyzMountImageSupported,
+	"syz_read_part_table":             isSyzReadPartTableSupported,
+	"syz_io_uring_submit":             isSyzIoUringSupported,
+	"syz_io_uring_complete":           isSyzIoUringSupported,
+	"syz_io_uring_setup":              isSyzIoUringSupported,
+	"syz_io_uring_register_file":      isSyzIoUringSupported,
+	"syz_io_uring_unregister_file":    isSyzIoUringSupported,
+	"syz_io_uring_register_buffers":   isSyzIoUringSupported,
+	"syz_io_uring_unregister_buffers": isSyzIoUringSupported,
+	"syz_io_uring_register_eventfd":   isSyzIoUringSupported,
+	"syz_io_uring_unregister_eventfd": isSyzIoUringSupported,
+	"syz_memcpy_off":                  isSyzMemcpySupported,
+	"syz_btf_id_by_name":              isBtfVmlinuxSupported,
+	"syz_fuse_handle_req":             isSyzFuseSupported,
+	"syz_80211_inject_frame":          isWifiEmulationSupported,
+	"syz_80211_join_ibss":             isWifiEmulationSupported,
+	"syz_usbip_server_init":           isSyzUsbIPSupported,
+	"syz_clone":                       alwaysSupported,
+	"syz_clone3":                      alwaysSupported,
 }
 
 func isSupportedSyzkall(c *prog.Syscall, target *prog.Target, sandbox string) (bool, string) {
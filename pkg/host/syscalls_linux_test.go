@@ -7,11 +7,15 @@
 package host
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"runtime"
 	"syscall"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/pkg/btf"
 	"github.com/google/syzkaller/prog"
 	"github.com/google/syzkaller/sys/targets"
 )
@@ -192,6 +196,48 @@ ffffffe0005c9c76 T __se_sys_listen
 	}
 }
 
+func TestBTFSyscallsParse(t *testing.T) {
+	types := []*btf.Type{
+		nil,
+		{Name: "__x64_sys_bind", Kind: btf.KindFunc},
+		{Name: "__sys_listen", Kind: btf.KindFunc},
+		{Name: "__ia32_sys_accept4", Kind: btf.KindFunc},
+		{Name: "__x64_sys_socket", Kind: btf.KindFuncProto},
+	}
+	got := parseBTFSyscalls(types, targets.AMD64)
+	want := map[string]bool{"bind": true, "accept4": true}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestParseBuildID(t *testing.T) {
+	note := func(name string, typ uint32, desc []byte) []byte {
+		buf := new(bytes.Buffer)
+		binary.Write(buf, binary.LittleEndian, []uint32{uint32(len(name)), uint32(len(desc)), typ})
+		buf.WriteString(name)
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0)
+		}
+		buf.Write(desc)
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0)
+		}
+		return buf.Bytes()
+	}
+	notes := append(note("Xen\x00", 1, []byte{1, 2, 3}), note("GNU\x00", 3, []byte{0xde, 0xad, 0xbe, 0xef, 0x42})...)
+	id, err := parseBuildID(notes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "deadbeef42" {
+		t.Fatalf("got build id %q", id)
+	}
+	if _, err := parseBuildID(note("Xen\x00", 1, []byte{1, 2, 3})); err == nil {
+		t.Fatalf("no error for notes without build id")
+	}
+}
+
 func TestMatchKernelVersion(t *testing.T) {
 	tests := []struct {
 		version string
@@ -21,6 +21,7 @@ import (
 	"github.com/google/syzkaller/pkg/mgrconfig"
 	"github.com/google/syzkaller/pkg/osutil"
 	"github.com/google/syzkaller/pkg/report"
+	"github.com/google/syzkaller/pkg/rpctype"
 	"github.com/google/syzkaller/pkg/tool"
 	"github.com/google/syzkaller/pkg/vcs"
 	"github.com/google/syzkaller/sys/targets"
@@ -453,6 +454,10 @@ type FuzzerCmdArgs struct {
 	Runtest   bool
 	Slowdown  int
 	RawCover  bool
+	// Dir in the VM to cache detected supported syscalls in, caching is disabled if empty.
+	SyscallCache string
+	// TLS files in the VM, if set the fuzzer uses mutual TLS for the manager connection.
+	TLS *rpctype.TLSFiles
 }
 
 func FuzzerCmd(args *FuzzerCmdArgs) string {
@@ -478,6 +483,16 @@ func FuzzerCmd(args *FuzzerCmdArgs) string {
 	if args.RawCover {
 		flags = append(flags, tool.Flag{Name: "raw_cover", Value: "true"})
 	}
+	if args.SyscallCache != "" {
+		flags = append(flags, tool.Flag{Name: "syscall_cache", Value: args.SyscallCache})
+	}
+	if args.TLS != nil {
+		flags = append(flags,
+			tool.Flag{Name: "tls_ca", Value: args.TLS.CA},
+			tool.Flag{Name: "tls_cert", Value: args.TLS.Cert},
+			tool.Flag{Name: "tls_key", Value: args.TLS.Key},
+		)
+	}
 	optionalArg := ""
 	if len(flags) > 0 {
 		optionalArg += " " + tool.OptionalFlags(flags)
@@ -533,7 +548,34 @@ var MakeBin = func() string {
 	return "make"
 }()
 
-func RunnerCmd(prog, fwdAddr, os, arch string, poolIdx, vmIdx int, threaded, newEnv bool) string {
+// CopyTLSFiles copies client TLS files into the VM and returns their paths in the VM.
+// Returns nil if files is nil (TLS is not used).
+func CopyTLSFiles(inst *vm.Instance, files *rpctype.TLSFiles) (*rpctype.TLSFiles, error) {
+	if files == nil {
+		return nil, nil
+	}
+	res := new(rpctype.TLSFiles)
+	var err error
+	if res.CA, err = inst.Copy(files.CA); err != nil {
+		return nil, fmt.Errorf("failed to copy tls files: %v", err)
+	}
+	if res.Cert, err = inst.Copy(files.Cert); err != nil {
+		return nil, fmt.Errorf("failed to copy tls files: %v", err)
+	}
+	if res.Key, err = inst.Copy(files.Key); err != nil {
+		return nil, fmt.Errorf("failed to copy tls files: %v", err)
+	}
+	return res, nil
+}
+
+// RunnerCmd returns command line for syz-runner, tls are TLS files in the VM (optional).
+func RunnerCmd(prog, fwdAddr, os, arch string, poolIdx, vmIdx int, threaded, newEnv, cover bool,
+	tls *rpctype.TLSFiles) string {
+	tlsArg := ""
+	if tls != nil {
+		tlsArg = fmt.Sprintf(" -tls_ca=%v -tls_cert=%v -tls_key=%v", tls.CA, tls.Cert, tls.Key)
+	}
 	return fmt.Sprintf("%s -addr=%s -os=%s -arch=%s -pool=%d -vm=%d "+
-		"-threaded=%t -new-env=%t", prog, fwdAddr, os, arch, poolIdx, vmIdx, threaded, newEnv)
+		"-threaded=%t -new-env=%t -cover=%t%v", prog, fwdAddr, os, arch, poolIdx, vmIdx, threaded, newEnv, cover,
+		tlsArg)
 }
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/google/syzkaller/pkg/rpctype"
 	"github.com/google/syzkaller/pkg/tool"
 	"github.com/google/syzkaller/sys/targets"
 )
@@ -154,8 +155,13 @@ func TestRunnerCmd(t *testing.T) {
 	flagVM := flags.Int("vm", 0, "index of VM that started the Runner")
 	flagThreaded := flags.Bool("threaded", true, "use threaded mode in executor")
 	flagEnv := flags.Bool("new-env", true, "create a new environment for each program")
+	flagCover := flags.Bool("cover", false, "collect coverage")
+	flagTLSCA := flags.String("tls_ca", "", "CA certificate")
+	flagTLSCert := flags.String("tls_cert", "", "client certificate")
+	flagTLSKey := flags.String("tls_key", "", "client key")
 
-	cmdLine := RunnerCmd(os.Args[0], "localhost:1234", targets.Linux, targets.AMD64, 0, 0, false, false)
+	cmdLine := RunnerCmd(os.Args[0], "localhost:1234", targets.Linux, targets.AMD64, 0, 0, false, false, true,
+		&rpctype.TLSFiles{CA: "/ca.crt", Cert: "/client.crt", Key: "/client.key"})
 	args := strings.Split(cmdLine, " ")[1:]
 	if err := flags.Parse(args); err != nil {
 		t.Fatalf("error parsing flags: %v, want: nil", err)
@@ -188,4 +194,12 @@ func TestRunnerCmd(t *testing.T) {
 	if got, want := *flagEnv, false; got != want {
 		t.Errorf("bad new-env: %t, want: %t", got, want)
 	}
+
+	if got, want := *flagCover, true; got != want {
+		t.Errorf("bad cover: %t, want: %t", got, want)
+	}
+
+	if *flagTLSCA != "/ca.crt" || *flagTLSCert != "/client.crt" || *flagTLSKey != "/client.key" {
+		t.Errorf("bad tls files: %q %q %q", *flagTLSCA, *flagTLSCert, *flagTLSKey)
+	}
 }
@@ -55,10 +55,23 @@ const (
 	FlagCollectComps                               // collect KCOV comparisons
 	FlagThreaded                                   // use multiple threads to mitigate blocked syscalls
 	FlagEnableCoverageFilter                       // setup and use bitmap to do coverage filter
+	FlagPerturbTiming                              // add random delays between calls in repetitions
+	FlagPerturbProcID                              // use a different proc id in repetitions
+	FlagPerturbFaults                              // randomly inject faults in repetitions (requires fault injection)
+	FlagCollectOutputs                             // collect return values and outputs of calls (requires shmem)
+	FlagCollectState                               // snapshot Config.StateProbes before and after the program
 )
 
+// MaxRepeat is the maximum value of ExecOpts.Repeat.
+const MaxRepeat = 16
+
 type ExecOpts struct {
 	Flags ExecFlags
+	// Repeat is the number of additional executions of the program that executor does
+	// after the main one without returning to the caller. Results are returned only
+	// for the main execution. The repetitions are perturbed according to FlagPerturb* flags.
+	// Requires fork server.
+	Repeat int
 }
 
 // Config is the configuration for Env.
@@ -73,6 +86,10 @@ type Config struct {
 	Flags EnvFlags
 
 	Timeouts targets.Timeouts
+
+	// StateProbes are files and directories (e.g. /proc/self/mounts or /proc/self/fd) whose contents
+	// are captured with FlagCollectState. Only supported on linux with shmem.
+	StateProbes []string
 }
 
 type CallFlags uint32
@@ -91,11 +108,19 @@ type CallInfo struct {
 	// if dedup == false, then cov effectively contains a trace, otherwise duplicates are removed
 	Comps prog.CompMap // per-call comparison operands
 	Errno int          // call errno (0 if the call was successful)
+	// Outputs contains the return value followed by the values of prog.Call.ExecOutputs,
+	// filled if FlagCollectOutputs is set and the call has succeeded.
+	Outputs []uint64
 }
 
 type ProgInfo struct {
 	Calls []CallInfo
 	Extra CallInfo // stores Signal and Cover collected from background threads
+	// StateBefore and StateAfter are the snapshots of Config.StateProbes taken before and after
+	// the program, filled if FlagCollectState is set. Each probe is written as "== probe" line
+	// followed by the file contents or the directory entries ("name -> target" for symlinks).
+	StateBefore []byte
+	StateAfter  []byte
 }
 
 type Env struct {
@@ -125,6 +150,7 @@ const (
 	compConstMask = 1
 
 	extraReplyIndex = 0xffffffff // uint32(-1)
+	stateReplyIndex = 0xfffffffe // uint32(-2)
 )
 
 func SandboxToFlags(sandbox string) (EnvFlags, error) {
@@ -252,8 +278,20 @@ var rateLimit = time.NewTicker(1 * time.Second)
 // hanged: program hanged and was killed
 // err0: failed to start the process or bug in executor itself.
 func (env *Env) Exec(opts *ExecOpts, p *prog.Prog) (output []byte, info *ProgInfo, hanged bool, err0 error) {
+	if opts.Repeat < 0 || opts.Repeat > MaxRepeat {
+		err0 = fmt.Errorf("bad repeat value %v, must be in [0, %v]", opts.Repeat, MaxRepeat)
+		return
+	}
+	if opts.Repeat != 0 && !env.config.UseForkServer {
+		err0 = fmt.Errorf("repeat requires fork server")
+		return
+	}
 	// Copy-in serialized program.
-	progSize, err := p.SerializeForExec(env.in)
+	serialize := p.SerializeForExec
+	if opts.Flags&FlagCollectOutputs != 0 {
+		serialize = p.SerializeForExecOutputs
+	}
+	progSize, err := serialize(env.in)
 	if err != nil {
 		err0 = err
 		return
@@ -338,7 +376,13 @@ func (env *Env) parseOutput(p *prog.Prog, opts *ExecOpts) (*ProgInfo, error) {
 		reply := *(*callReply)(unsafe.Pointer(&out[0]))
 		out = out[unsafe.Sizeof(callReply{}):]
 		var inf *CallInfo
-		if reply.index != extraReplyIndex {
+		switch reply.index {
+		case extraReplyIndex:
+			extraParts = append(extraParts, CallInfo{})
+			inf = &extraParts[len(extraParts)-1]
+		case stateReplyIndex:
+			inf = &CallInfo{}
+		default:
 			if int(reply.index) >= len(info.Calls) {
 				return nil, fmt.Errorf("bad call %v index %v/%v", i, reply.index, len(info.Calls))
 			}
@@ -351,9 +395,6 @@ func (env *Env) parseOutput(p *prog.Prog, opts *ExecOpts) (*ProgInfo, error) {
 			}
 			inf.Errno = int(reply.errno)
 			inf.Flags = CallFlags(reply.flags)
-		} else {
-			extraParts = append(extraParts, CallInfo{})
-			inf = &extraParts[len(extraParts)-1]
 		}
 		if inf.Signal, ok = readUint32Array(&out, reply.signalSize); !ok {
 			return nil, fmt.Errorf("call %v/%v/%v: signal overflow: %v/%v",
@@ -368,6 +409,19 @@ func (env *Env) parseOutput(p *prog.Prog, opts *ExecOpts) (*ProgInfo, error) {
 			return nil, err
 		}
 		inf.Comps = comps
+		if opts.Flags&FlagCollectOutputs != 0 {
+			if inf.Outputs, err = readOutputs(&out); err != nil {
+				return nil, fmt.Errorf("call %v/%v/%v: %v", i, reply.index, reply.num, err)
+			}
+		}
+		if reply.index == stateReplyIndex {
+			if info.StateBefore, err = readState(&out); err != nil {
+				return nil, err
+			}
+			if info.StateAfter, err = readState(&out); err != nil {
+				return nil, err
+			}
+		}
 	}
 	if len(extraParts) == 0 {
 		return info, nil
@@ -445,6 +499,38 @@ func readComps(outp *[]byte, compsSize uint32) (prog.CompMap, error) {
 	return compMap, nil
 }
 
+func readOutputs(outp *[]byte) ([]uint64, error) {
+	n, ok := readUint32(outp)
+	if !ok {
+		return nil, fmt.Errorf("failed to read number of outputs")
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	if int(n) > len(*outp)/8 {
+		return nil, fmt.Errorf("outputs overflow: %v/%v", n, len(*outp))
+	}
+	res := make([]uint64, n)
+	for i := range res {
+		res[i], _ = readUint64(outp)
+	}
+	return res, nil
+}
+
+func readState(outp *[]byte) ([]byte, error) {
+	size, ok := readUint32(outp)
+	if !ok {
+		return nil, fmt.Errorf("failed to read state size")
+	}
+	padded := (int(size) + 3) &^ 3
+	if padded > len(*outp) {
+		return nil, fmt.Errorf("state overflow: %v/%v", size, len(*outp))
+	}
+	res := append([]byte{}, (*outp)[:size]...)
+	*outp = (*outp)[padded:]
+	return res, nil
+}
+
 func readUint32(outp *[]byte) (uint32, bool) {
 	out := *outp
 	if len(out) < 4 {
@@ -518,6 +604,7 @@ type executeReq struct {
 	syscallTimeoutMS uint64
 	programTimeoutMS uint64
 	slowdownScale    uint64
+	repeatTimes      uint64
 	progSize         uint64
 	// This structure is followed by a serialized test program in encodingexec format.
 	// Both when sent over a pipe or in shared memory.
@@ -608,6 +695,9 @@ func makeCommand(pid int, bin []string, config *Config, inFile, outFile *os.File
 	cmd.Dir = dir
 	// Tell ASAN to not mess with our NONFAILING.
 	cmd.Env = append(append([]string{}, os.Environ()...), "ASAN_OPTIONS=handle_segv=0 allow_user_segv_handler=1")
+	if len(config.StateProbes) != 0 {
+		cmd.Env = append(cmd.Env, "SYZ_STATE_PROBES="+strings.Join(config.StateProbes, ":"))
+	}
 	cmd.Stdin = outrp
 	cmd.Stdout = inwp
 	if config.Flags&FlagDebug != 0 {
@@ -740,6 +830,7 @@ func (c *command) exec(opts *ExecOpts, progData []byte) (output []byte, hanged b
 		syscallTimeoutMS: uint64(c.config.Timeouts.Syscall / time.Millisecond),
 		programTimeoutMS: uint64(c.config.Timeouts.Program / time.Millisecond),
 		slowdownScale:    uint64(c.config.Timeouts.Scale),
+		repeatTimes:      uint64(opts.Repeat),
 		progSize:         uint64(len(progData)),
 	}
 	reqData := (*[unsafe.Sizeof(*req)]byte)(unsafe.Pointer(req))[:]
@@ -760,7 +851,8 @@ func (c *command) exec(opts *ExecOpts, progData []byte) (output []byte, hanged b
 	done := make(chan bool)
 	hang := make(chan bool)
 	go func() {
-		t := time.NewTimer(c.timeout)
+		// Each repetition can take as long as the main execution.
+		t := time.NewTimer(c.timeout * time.Duration(1+opts.Repeat))
 		select {
 		case <-t.C:
 			c.cmd.Process.Kill()
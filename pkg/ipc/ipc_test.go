@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -135,6 +136,168 @@ func TestExecute(t *testing.T) {
 	}
 }
 
+func TestExecutePerturbed(t *testing.T) {
+	target, _, _, useShmem, useForkServer, timeouts := initTest(t)
+	if !useForkServer {
+		t.Skip("repetitions require fork server")
+	}
+	bin := buildExecutor(t, target)
+	defer os.Remove(bin)
+	cfg := &Config{
+		Executor:      bin,
+		UseShmem:      useShmem,
+		UseForkServer: useForkServer,
+		Timeouts:      timeouts,
+	}
+	env, err := MakeEnv(cfg, 0)
+	if err != nil {
+		t.Fatalf("failed to create env: %v", err)
+	}
+	defer env.Close()
+	for _, flag := range []ExecFlags{0, FlagThreaded} {
+		p := prepareTestProgram(target)
+		opts := &ExecOpts{
+			Flags:  flag | FlagPerturbTiming | FlagPerturbProcID,
+			Repeat: 3,
+		}
+		output, info, hanged, err := env.Exec(opts, p)
+		if err != nil {
+			t.Fatalf("failed to run executor: %v", err)
+		}
+		if hanged {
+			t.Fatalf("program hanged:\n%s", output)
+		}
+		// Only the main execution must be reported.
+		if len(info.Calls) != len(p.Calls) {
+			t.Fatalf("got %v calls, want %v:\n%s", len(info.Calls), len(p.Calls), output)
+		}
+		if info.Calls[0].Errno != 0 {
+			t.Fatalf("simple call failed: %v\n%s", info.Calls[0].Errno, output)
+		}
+	}
+	if _, _, _, err := env.Exec(&ExecOpts{Repeat: MaxRepeat + 1}, target.DataMmapProg()); err == nil {
+		t.Fatalf("too large repeat is accepted")
+	}
+}
+
+func TestExecuteOutputs(t *testing.T) {
+	target, _, _, useShmem, useForkServer, timeouts := initTest(t)
+	if target.OS != targets.Linux || !useShmem {
+		t.Skip("the test program is linux-specific and outputs require shmem")
+	}
+	bin := buildExecutor(t, target)
+	defer os.Remove(bin)
+	cfg := &Config{
+		Executor:      bin,
+		UseShmem:      useShmem,
+		UseForkServer: useForkServer,
+		Timeouts:      timeouts,
+	}
+	env, err := MakeEnv(cfg, 0)
+	if err != nil {
+		t.Fatalf("failed to create env: %v", err)
+	}
+	defer env.Close()
+	p := target.DataMmapProg()
+	calls, err := target.Deserialize([]byte(`
+pipe2(&(0x7f0000000000)={<r0=>0xffffffffffffffff, <r1=>0xffffffffffffffff}, 0x0)
+write(r1, &(0x7f0000000040)="0102030405", 0x5)
+read(r0, &(0x7f0000000080)=""/5, 0x5)
+close(0xffffffffffffffff)
+`), prog.Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Calls = append(p.Calls, calls.Calls...)
+	for _, flag := range []ExecFlags{0, FlagThreaded} {
+		output, info, hanged, err := env.Exec(&ExecOpts{Flags: flag | FlagCollectOutputs}, p)
+		if err != nil {
+			t.Fatalf("failed to run executor: %v", err)
+		}
+		if hanged {
+			t.Fatalf("program hanged:\n%s", output)
+		}
+		calls := info.Calls[len(info.Calls)-4:]
+		pipe := calls[0].Outputs
+		if len(pipe) != 3 || pipe[0] != 0 || pipe[1] == pipe[2] || pipe[1] >= 1<<20 || pipe[2] >= 1<<20 {
+			t.Errorf("bad pipe2 outputs: %x", pipe)
+		}
+		if got := fmt.Sprintf("%x", calls[1].Outputs); got != "[5]" {
+			t.Errorf("bad write outputs: %v", got)
+		}
+		if got := fmt.Sprintf("%x", calls[2].Outputs); got != "[5 4030201 5]" {
+			t.Errorf("bad read outputs: %v", got)
+		}
+		if calls[3].Errno == 0 || calls[3].Outputs != nil {
+			t.Errorf("bad outputs of the failed call: %v %x", calls[3].Errno, calls[3].Outputs)
+		}
+	}
+	_, info, _, err := env.Exec(&ExecOpts{}, p)
+	if err != nil {
+		t.Fatalf("failed to run executor: %v", err)
+	}
+	if info.Calls[len(info.Calls)-4].Outputs != nil {
+		t.Errorf("outputs are collected without FlagCollectOutputs")
+	}
+}
+
+func TestExecuteState(t *testing.T) {
+	target, _, _, useShmem, useForkServer, timeouts := initTest(t)
+	if target.OS != targets.Linux || !useShmem {
+		t.Skip("state probes are linux-specific and require shmem")
+	}
+	bin := buildExecutor(t, target)
+	defer os.Remove(bin)
+	cfg := &Config{
+		Executor:      bin,
+		UseShmem:      useShmem,
+		UseForkServer: useForkServer,
+		Timeouts:      timeouts,
+		StateProbes:   []string{"/proc/self/fd", "/non/existent"},
+	}
+	env, err := MakeEnv(cfg, 0)
+	if err != nil {
+		t.Fatalf("failed to create env: %v", err)
+	}
+	defer env.Close()
+	p := target.DataMmapProg()
+	calls, err := target.Deserialize([]byte(`
+pipe2(&(0x7f0000000000)={0xffffffffffffffff, 0xffffffffffffffff}, 0x0)
+`), prog.Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Calls = append(p.Calls, calls.Calls...)
+	for _, flags := range []ExecFlags{FlagCollectState, FlagCollectState | FlagCollectOutputs} {
+		output, info, hanged, err := env.Exec(&ExecOpts{Flags: flags}, p)
+		if err != nil {
+			t.Fatalf("failed to run executor: %v", err)
+		}
+		if hanged {
+			t.Fatalf("program hanged:\n%s", output)
+		}
+		if len(info.Calls) != len(p.Calls) {
+			t.Fatalf("got %v call infos, want %v", len(info.Calls), len(p.Calls))
+		}
+		before, after := string(info.StateBefore), string(info.StateAfter)
+		for _, state := range []string{before, after} {
+			if !strings.HasPrefix(state, "== /proc/self/fd\n") || !strings.Contains(state, "== /non/existent\n") {
+				t.Fatalf("bad state:\n%s", state)
+			}
+		}
+		if strings.Count(after, "pipe:[")-strings.Count(before, "pipe:[") != 2 {
+			t.Errorf("pipe fds are not in the state:\nbefore:\n%s\nafter:\n%s", before, after)
+		}
+	}
+	_, info, _, err := env.Exec(&ExecOpts{}, p)
+	if err != nil {
+		t.Fatalf("failed to run executor: %v", err)
+	}
+	if info.StateBefore != nil || info.StateAfter != nil {
+		t.Errorf("state is collected without FlagCollectState")
+	}
+}
+
 func TestParallel(t *testing.T) {
 	target, _, _, useShmem, useForkServer, timeouts := initTest(t)
 	bin := buildExecutor(t, target)
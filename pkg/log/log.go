@@ -6,20 +6,31 @@
 //  - global verbosity setting that can be used by multiple packages
 //  - ability to disable all output
 //  - ability to cache recent output in memory
+//  - structured loggers with module name and key/value fields (see Logger)
+//  - per-module verbosity that can be adjusted at runtime (see SetLevel, LevelsHandler)
+//  - optional JSON output (-log-json flag)
 package log
 
 import (
 	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	golog "log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
 var (
 	flagV        = flag.Int("vv", 0, "verbosity")
+	flagJSON     = flag.Bool("log-json", false, "write log in JSON format (one object per line)")
 	mu           sync.Mutex
+	moduleLevels = make(map[string]int)
 	cacheMem     int
 	cacheMaxMem  int
 	cachePos     int
@@ -59,9 +70,64 @@ func CachedLogOutput() string {
 	return buf.String()
 }
 
+// Logger is a structured logger. Module is used to adjust verbosity of parts of a program
+// independently (see SetLevel), fields are key/value pairs attached to each message
+// (e.g. VM index or program hash) so that logs of fleets of daemons can be aggregated/filtered.
+type Logger struct {
+	module string
+	fields []field
+}
+
+type field struct {
+	key string
+	val interface{}
+}
+
+// New returns a logger for the module.
+func New(module string) *Logger {
+	return &Logger{module: module}
+}
+
+// With returns a copy of the logger with an additional field.
+func (l *Logger) With(key string, val interface{}) *Logger {
+	fields := make([]field, len(l.fields), len(l.fields)+1)
+	copy(fields, l.fields)
+	return &Logger{
+		module: l.module,
+		fields: append(fields, field{key, val}),
+	}
+}
+
+// V reports whether messages with verbosity v are enabled for the logger.
+func (l *Logger) V(v int) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return v <= level(l.module)
+}
+
+func (l *Logger) Logf(v int, msg string, args ...interface{}) {
+	logf(l, v, msg, args...)
+}
+
 func Logf(v int, msg string, args ...interface{}) {
+	logf(nil, v, msg, args...)
+}
+
+func logf(l *Logger, v int, msg string, args ...interface{}) {
 	mu.Lock()
-	doLog := v <= *flagV
+	module := ""
+	if l != nil {
+		module = l.module
+	}
+	doLog := v <= level(module)
+	if !doLog && (cacheEntries == nil || v > 1) {
+		mu.Unlock()
+		return
+	}
+	text := fmt.Sprintf(msg, args...)
+	if l != nil {
+		text = l.prefix() + text
+	}
 	if cacheEntries != nil && v <= 1 {
 		cacheMem -= len(cacheEntries[cachePos])
 		if cacheMem < 0 {
@@ -71,7 +137,7 @@ func Logf(v int, msg string, args ...interface{}) {
 		if prependTime {
 			timeStr = time.Now().Format("2006/01/02 15:04:05 ")
 		}
-		cacheEntries[cachePos] = fmt.Sprintf(timeStr+msg, args...)
+		cacheEntries[cachePos] = timeStr + text
 		cacheMem += len(cacheEntries[cachePos])
 		cachePos++
 		if cachePos == len(cacheEntries) {
@@ -88,8 +154,121 @@ func Logf(v int, msg string, args ...interface{}) {
 	}
 	mu.Unlock()
 
-	if doLog {
-		golog.Printf(msg, args...)
+	if !doLog {
+		return
+	}
+	if *flagJSON {
+		os.Stderr.Write(formatJSON(l, v, text))
+		return
+	}
+	golog.Print(text)
+}
+
+// prefix returns text representation of module and fields, e.g. "manager vm=1: ".
+func (l *Logger) prefix() string {
+	if l.module == "" && len(l.fields) == 0 {
+		return ""
+	}
+	buf := new(strings.Builder)
+	buf.WriteString(l.module)
+	for _, f := range l.fields {
+		if buf.Len() != 0 {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(buf, "%v=%v", f.key, f.val)
+	}
+	buf.WriteString(": ")
+	return buf.String()
+}
+
+func formatJSON(l *Logger, v int, msg string) []byte {
+	buf := new(bytes.Buffer)
+	add := func(key string, val interface{}) {
+		data, err := json.Marshal(val)
+		if err != nil {
+			data, _ = json.Marshal(fmt.Sprint(val))
+		}
+		if buf.Len() != 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(buf, "%q:%s", key, data)
+	}
+	add("time", time.Now().Format(time.RFC3339Nano))
+	add("level", v)
+	if l != nil {
+		if l.module != "" {
+			add("module", l.module)
+		}
+		for _, f := range l.fields {
+			add(f.key, f.val)
+		}
+	}
+	add("msg", strings.TrimSuffix(msg, "\n"))
+	return []byte("{" + buf.String() + "}\n")
+}
+
+// level returns verbosity for the module, mu must be held.
+func level(module string) int {
+	if v, ok := moduleLevels[module]; ok {
+		return v
+	}
+	return *flagV
+}
+
+// SetLevel sets verbosity for the module, empty module sets the default verbosity
+// used for modules without explicitly set verbosity.
+func SetLevel(module string, v int) {
+	mu.Lock()
+	defer mu.Unlock()
+	if module == "" {
+		*flagV = v
+		return
+	}
+	moduleLevels[module] = v
+}
+
+// ResetLevel makes the module use the default verbosity.
+func ResetLevel(module string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(moduleLevels, module)
+}
+
+// LevelsHandler is an admin http endpoint for runtime adjustment of verbosity:
+//
+//	GET  /path                 - list the default and per-module verbosity
+//	POST /path?module=name&v=2 - set verbosity for the module (default if module is empty)
+//	POST /path?module=name     - reset verbosity of the module to the default
+func LevelsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		module := r.FormValue("module")
+		if vStr := r.FormValue("v"); vStr != "" {
+			v, err := strconv.Atoi(vStr)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("bad verbosity %q", vStr), http.StatusBadRequest)
+				return
+			}
+			SetLevel(module, v)
+			Logf(0, "log verbosity of module %q set to %v", module, v)
+		} else if module != "" {
+			ResetLevel(module)
+			Logf(0, "log verbosity of module %q reset to default", module)
+		}
+	} else if r.Method != http.MethodGet {
+		http.Error(w, "only GET and POST are supported", http.StatusMethodNotAllowed)
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	var modules []string
+	for module := range moduleLevels {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "default: %v\n", *flagV)
+	for _, module := range modules {
+		fmt.Fprintf(w, "%v: %v\n", module, moduleLevels[module])
 	}
 }
 
@@ -4,6 +4,9 @@
 package log
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -33,3 +36,55 @@ func TestCaching(t *testing.T) {
 		}
 	}
 }
+
+func TestLogger(t *testing.T) {
+	l := New("mod").With("vm", 1)
+	l1 := l.With("prog", "abc")
+	if got, want := l1.prefix(), "mod vm=1 prog=abc: "; got != want {
+		t.Fatalf("got prefix %q, want %q", got, want)
+	}
+	if got, want := l.prefix(), "mod vm=1: "; got != want {
+		t.Fatalf("With modified the parent logger: %q", got)
+	}
+	if got, want := New("").With("a", "b").prefix(), "a=b: "; got != want {
+		t.Fatalf("got prefix %q, want %q", got, want)
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(formatJSON(l1, 2, "foo \"bar\"\n"), &obj); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]interface{}{
+		"module": "mod", "vm": 1.0, "prog": "abc", "level": 2.0, "msg": "foo \"bar\"",
+	} {
+		if obj[key] != want {
+			t.Errorf("JSON field %v: got %v, want %v", key, obj[key], want)
+		}
+	}
+}
+
+func TestLevels(t *testing.T) {
+	defer SetLevel("", *flagV)
+	l := New("levels")
+	SetLevel("", 1)
+	if !l.V(1) || l.V(2) {
+		t.Fatalf("default verbosity is not applied")
+	}
+	req := httptest.NewRequest(http.MethodPost, "/log-levels?module=levels&v=3", nil)
+	rec := httptest.NewRecorder()
+	LevelsHandler(rec, req)
+	if !l.V(3) || l.V(4) || New("other").V(2) {
+		t.Fatalf("module verbosity is not applied")
+	}
+	if got, want := rec.Body.String(), "default: 1\nlevels: 3\n"; got != want {
+		t.Fatalf("got levels:\n%v\nwant:\n%v", got, want)
+	}
+	LevelsHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/log-levels?module=levels", nil))
+	if l.V(2) {
+		t.Fatalf("module verbosity is not reset")
+	}
+	rec = httptest.NewRecorder()
+	LevelsHandler(rec, httptest.NewRequest(http.MethodPost, "/log-levels?v=foo", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad verbosity is accepted")
+	}
+}
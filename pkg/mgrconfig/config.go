@@ -5,6 +5,8 @@ package mgrconfig
 
 import (
 	"encoding/json"
+
+	"github.com/google/syzkaller/pkg/osutil"
 )
 
 type Config struct {
@@ -16,6 +18,21 @@ type Config struct {
 	HTTP string `json:"http"`
 	// TCP address to serve RPC for fuzzer processes (optional).
 	RPC string `json:"rpc,omitempty"`
+	// Address of the syz-verifier monitoring http server (e.g. "127.0.0.1:8080", optional).
+	// If set, the manager web UI shows verifier results (mismatches and per-syscall stats) at /verifier.
+	VerifierHTTP string `json:"verifier_http,omitempty"`
+	// Use mutual TLS for the RPC connections from fuzzer processes (optional).
+	// A CA and certificates are generated on each start in <workdir>/tls and client certificates
+	// are copied into VMs, so only processes started by this manager can connect to the RPC port
+	// and the traffic can't be observed/altered on the network between the host and VMs.
+	RPCTLS bool `json:"rpc_tls,omitempty"`
+	// Cgroup v2 directory writable by the manager user (optional, linux only).
+	// If set, commands run by the manager (compilation of C reproducers, symbolization, etc)
+	// are placed into <cgroup>/syz-manager-<name> cgroup with cgroup_limits, e.g.:
+	//	"cgroup": "/sys/fs/cgroup/syzkaller",
+	//	"cgroup_limits": {"memory_mb": 8192, "cpus": 4, "pids": 10000}
+	Cgroup       string              `json:"cgroup,omitempty"`
+	CgroupLimits osutil.CgroupLimits `json:"cgroup_limits,omitempty"`
 	// Location of a working directory for the syz-manager process. Outputs here include:
 	// - <workdir>/crashes/*: crash output files
 	// - <workdir>/corpus.db: corpus with interesting programs
@@ -168,6 +185,19 @@ type Config struct {
 	// Regexps are matched against bug title, guilty file and maintainer emails.
 	Interests []string `json:"interests,omitempty"`
 
+	// Periodic sweeps (optional). During a sweep one VM stops fuzzing and replays the programs
+	// added to the corpus since the previous sweep, then (if kmemleak is set) scans for memory leaks.
+	// This is useful for bugs that are expensive to detect during fuzzing (kmemleak) or that
+	// need many repetitions under a heavy sanitizer (KMSAN). Findings are saved as usual crashes
+	// with the replayed programs attached, e.g.:
+	//	"sweep": {"period": 60, "programs": 1000, "kmemleak": true}
+	Sweep SweepConfig `json:"sweep,omitempty"`
+
+	// Duration of a time-boxed fuzzing session in minutes (optional, e.g. for release validation).
+	// When the session ends, the manager writes a self-contained HTML summary report
+	// to workdir/session-TIMESTAMP.html and shuts down. 0 means fuzz until interrupted.
+	SessionDuration int `json:"session_duration,omitempty"`
+
 	// Type of virtual machine to use, e.g. "qemu", "gce", "android", "isolated", etc.
 	Type string `json:"type"`
 	// VM-type-specific parameters.
@@ -183,6 +213,15 @@ type Subsystem struct {
 	Paths []string `json:"path"`
 }
 
+type SweepConfig struct {
+	// Interval between sweeps in minutes (0 disables sweeps).
+	Period int `json:"period"`
+	// Maximum number of the most recent corpus additions replayed in a sweep (default 1000).
+	Programs int `json:"programs,omitempty"`
+	// Scan for memory leaks after the replay.
+	Kmemleak bool `json:"kmemleak,omitempty"`
+}
+
 type covFilterCfg struct {
 	Files     []string `json:"files,omitempty"`
 	Functions []string `json:"functions,omitempty"`
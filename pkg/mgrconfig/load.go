@@ -171,6 +171,15 @@ func Complete(cfg *Config) error {
 	if cfg.FuzzingVMs < 0 {
 		return fmt.Errorf("fuzzing_vms cannot be less than 0")
 	}
+	if cfg.Sweep.Period < 0 || cfg.Sweep.Programs < 0 {
+		return fmt.Errorf("sweep period and programs cannot be less than 0")
+	}
+	if cfg.Sweep.Programs == 0 {
+		cfg.Sweep.Programs = 1000
+	}
+	if cfg.SessionDuration < 0 {
+		return fmt.Errorf("session_duration cannot be less than 0")
+	}
 
 	var err error
 	cfg.Syscalls, err = ParseEnabledSyscalls(cfg.Target, cfg.EnabledSyscalls, cfg.DisabledSyscalls)
@@ -0,0 +1,44 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package osutil
+
+import (
+	"os/exec"
+)
+
+// CgroupLimits are resource limits for a cgroup, zero values mean no limit.
+type CgroupLimits struct {
+	// Memory limit in megabytes (memory.max), swap is disabled if it's set.
+	MemoryMB int `json:"memory_mb,omitempty"`
+	// Number of CPUs worth of time the processes can use (cpu.max), can be fractional.
+	CPUs float64 `json:"cpus,omitempty"`
+	// Maximum number of processes/threads (pids.max).
+	Pids int `json:"pids,omitempty"`
+}
+
+// Cgroup is a cgroup v2 that processes can be placed into.
+type Cgroup struct {
+	dir string
+}
+
+var commandCgroup *Cgroup
+
+// ConfineCommands makes all subsequent commands started with Run/RunCmd run in cg.
+// These are short-lived commands like kernel builds, image generation, compilers and git,
+// a runaway command is then limited by cg limits instead of taking down the whole host.
+// Long-running processes started with Command/GraciousCommand directly are not affected.
+// Must be called before any commands are started.
+func ConfineCommands(cg *Cgroup) {
+	commandCgroup = cg
+}
+
+// Attach makes cmd join the cgroup before it executes the target binary.
+// Must be called before cmd is started.
+func (cg *Cgroup) Attach(cmd *exec.Cmd) {
+	// Go does not support starting processes in a cgroup directly (before go1.20),
+	// so we start a shell that moves itself into the cgroup and then execs the command.
+	args := []string{"/bin/sh", "-c", `echo 0 >"$0" && exec "$@"`, cg.procsFile(), cmd.Path}
+	cmd.Args = append(args, cmd.Args[1:]...)
+	cmd.Path = args[0]
+}
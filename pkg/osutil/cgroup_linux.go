@@ -0,0 +1,104 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package osutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const cgroupCPUPeriod = 100000
+
+// NewCgroup creates (or reuses) cgroup name under parent and sets limits for it.
+// The parent must be a cgroup v2 directory writable by the current user (e.g. delegated by systemd)
+// with memory/cpu/pids controllers available for the limits that are set.
+// The parent must not contain processes itself, otherwise the controllers can't be enabled for children.
+// Reusing an existing cgroup allows to keep a fixed name across restarts of the process.
+func NewCgroup(parent, name string, limits CgroupLimits) (*Cgroup, error) {
+	data, err := ioutil.ReadFile(filepath.Join(parent, "cgroup.controllers"))
+	if err != nil {
+		return nil, fmt.Errorf("%v is not a cgroup v2 directory: %v", parent, err)
+	}
+	available := make(map[string]bool)
+	for _, c := range strings.Fields(string(data)) {
+		available[c] = true
+	}
+	var controllers []string
+	if limits.MemoryMB != 0 {
+		controllers = append(controllers, "memory")
+	}
+	if limits.CPUs != 0 {
+		controllers = append(controllers, "cpu")
+	}
+	if limits.Pids != 0 {
+		controllers = append(controllers, "pids")
+	}
+	var enable []string
+	for _, c := range controllers {
+		if !available[c] {
+			return nil, fmt.Errorf("cgroup controller %v is not available in %v", c, parent)
+		}
+		enable = append(enable, "+"+c)
+	}
+	if len(enable) != 0 {
+		if err := writeCgroupFile(parent, "cgroup.subtree_control", strings.Join(enable, " ")); err != nil {
+			return nil, err
+		}
+	}
+	cg := &Cgroup{dir: filepath.Join(parent, name)}
+	if err := os.Mkdir(cg.dir, DefaultDirPerm); err != nil && !os.IsExist(err) {
+		return nil, fmt.Errorf("failed to create cgroup: %v", err)
+	}
+	memory, swap, cpu, pids := "max", "max", "max", "max"
+	if limits.MemoryMB != 0 {
+		memory = fmt.Sprint(int64(limits.MemoryMB) << 20)
+		// With swap the processes would just thrash instead of being OOM-killed.
+		swap = "0"
+	}
+	if limits.CPUs != 0 {
+		cpu = fmt.Sprint(int64(limits.CPUs * cgroupCPUPeriod))
+	}
+	if limits.Pids != 0 {
+		pids = fmt.Sprint(limits.Pids)
+	}
+	// Reset the limits that are not set, they may be left from a previous run.
+	files := []struct {
+		controller, file, value string
+	}{
+		{"memory", "memory.max", memory},
+		{"memory", "memory.swap.max", swap},
+		{"cpu", "cpu.max", fmt.Sprintf("%v %v", cpu, cgroupCPUPeriod)},
+		{"pids", "pids.max", pids},
+	}
+	for _, f := range files {
+		if !available[f.controller] {
+			continue
+		}
+		err := writeCgroupFile(cg.dir, f.file, f.value)
+		// memory.swap.max is missing if swap accounting is disabled.
+		if err != nil && !(f.file == "memory.swap.max" && os.IsNotExist(err)) {
+			return nil, err
+		}
+	}
+	return cg, nil
+}
+
+func writeCgroupFile(dir, file, value string) error {
+	f, err := os.OpenFile(filepath.Join(dir, file), os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(value); err != nil {
+		return fmt.Errorf("failed to write %q to %v: %v", value, f.Name(), err)
+	}
+	return nil
+}
+
+func (cg *Cgroup) procsFile() string {
+	return filepath.Join(cg.dir, "cgroup.procs")
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package osutil
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// The test uses a fake cgroup tree made of regular files,
+// so it does not need root nor cgroup v2 mounted on the host.
+func TestCgroup(t *testing.T) {
+	parent := t.TempDir()
+	writeFakeCgroup := func(dir string, files map[string]string) {
+		for name, data := range files {
+			if err := WriteFile(filepath.Join(dir, name), []byte(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	writeFakeCgroup(parent, map[string]string{
+		"cgroup.controllers":     "cpu memory pids",
+		"cgroup.subtree_control": "",
+	})
+	if _, err := NewCgroup(filepath.Join(parent, "missing"), "test", CgroupLimits{}); err == nil {
+		t.Fatalf("no error for a non-cgroup parent")
+	}
+	dir := filepath.Join(parent, "test")
+	if err := MkdirAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	writeFakeCgroup(dir, map[string]string{
+		"cgroup.procs": "",
+		"memory.max":   "",
+		"cpu.max":      "",
+		"pids.max":     "",
+	})
+	cg, err := NewCgroup(parent, "test", CgroupLimits{MemoryMB: 100, CPUs: 1.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string]string{
+		"cgroup.subtree_control": "+memory +cpu",
+		"test/memory.max":        "104857600",
+		"test/cpu.max":           "150000 100000",
+		"test/pids.max":          "max",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(parent, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%v: got %q, want %q", file, data, want)
+		}
+	}
+
+	ConfineCommands(cg)
+	defer ConfineCommands(nil)
+	out, err := RunCmd(time.Minute, "", "echo", "foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "foo bar\n" {
+		t.Fatalf("bad output: %q", out)
+	}
+	procs, err := ioutil.ReadFile(filepath.Join(dir, "cgroup.procs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(procs) != "0\n" {
+		t.Fatalf("command did not join the cgroup: %q", procs)
+	}
+	_, err = RunCmd(time.Minute, "", "false")
+	if verr, ok := err.(*VerboseError); !ok || verr.ExitCode != 1 ||
+		verr.Title != `failed to run ["false"]: exit status 1` {
+		t.Fatalf("bad error: %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package osutil
+
+import (
+	"fmt"
+)
+
+func NewCgroup(parent, name string, limits CgroupLimits) (*Cgroup, error) {
+	return nil, fmt.Errorf("cgroups are not supported on this OS")
+}
+
+func (cg *Cgroup) procsFile() string {
+	return ""
+}
@@ -40,8 +40,13 @@ func Run(timeout time.Duration, cmd *exec.Cmd) ([]byte, error) {
 		cmd.Stderr = output
 	}
 	setPdeathsig(cmd, true)
+	// Keep the original command for error messages, Attach wraps it into a shell.
+	path, args := cmd.Path, cmd.Args
+	if commandCgroup != nil {
+		commandCgroup.Attach(cmd)
+	}
 	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start %v %+v: %v", cmd.Path, cmd.Args, err)
+		return nil, fmt.Errorf("failed to start %v %+v: %v", path, args, err)
 	}
 	done := make(chan bool)
 	timedout := make(chan bool, 1)
@@ -60,9 +65,9 @@ func Run(timeout time.Duration, cmd *exec.Cmd) ([]byte, error) {
 	err := cmd.Wait()
 	close(done)
 	if err != nil {
-		text := fmt.Sprintf("failed to run %q: %v", cmd.Args, err)
+		text := fmt.Sprintf("failed to run %q: %v", args, err)
 		if <-timedout {
-			text = fmt.Sprintf("timedout after %v %q", timeout, cmd.Args)
+			text = fmt.Sprintf("timedout after %v %q", timeout, args)
 		}
 		exitCode := 0
 		if exitErr, ok := err.(*exec.ExitError); ok {
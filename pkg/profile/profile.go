@@ -0,0 +1,202 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+// Package profile captures runtime profiles and execution traces of the current process on demand.
+// It's used to debug performance of long-running daemons (manager, fuzzer, syz-ci, verifier)
+// without restarting them with profiling flags.
+package profile
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"runtime/pprof"
+	"runtime/trace"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	KindCPU   = "cpu"
+	KindTrace = "trace"
+	// KindBundle is a zip archive with all other profiles (see Bundle).
+	KindBundle = "bundle"
+
+	DefaultDuration = 10 * time.Second
+	MaxDuration     = 5 * time.Minute
+)
+
+// Kinds returns all supported profile kinds.
+func Kinds() []string {
+	kinds := []string{KindCPU, KindTrace, KindBundle}
+	for _, p := range pprof.Profiles() {
+		kinds = append(kinds, p.Name())
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Capture captures a profile of the given kind.
+// CPU profile and trace are collected for the duration, other profiles are instant snapshots.
+func Capture(kind string, duration time.Duration) ([]byte, error) {
+	if duration <= 0 || duration > MaxDuration {
+		return nil, fmt.Errorf("bad profile duration %v", duration)
+	}
+	buf := new(bytes.Buffer)
+	switch kind {
+	case KindCPU:
+		if err := pprof.StartCPUProfile(buf); err != nil {
+			return nil, err
+		}
+		time.Sleep(duration)
+		pprof.StopCPUProfile()
+	case KindTrace:
+		if err := trace.Start(buf); err != nil {
+			return nil, err
+		}
+		time.Sleep(duration)
+		trace.Stop()
+	case KindBundle:
+		files, err := Bundle(duration)
+		if err != nil {
+			return nil, err
+		}
+		if err := WriteZip(buf, files); err != nil {
+			return nil, err
+		}
+	default:
+		p := pprof.Lookup(kind)
+		if p == nil {
+			return nil, fmt.Errorf("unknown profile kind %q", kind)
+		}
+		if err := p.WriteTo(buf, 0); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// File is a single file of a profile bundle.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Bundle concurrently captures CPU profile and execution trace for the duration,
+// and then takes snapshots of heap, allocs and goroutine profiles.
+func Bundle(duration time.Duration) ([]File, error) {
+	type result struct {
+		file File
+		err  error
+	}
+	concurrent := []struct{ kind, name string }{
+		{KindCPU, "cpu.pprof"},
+		{KindTrace, "trace.out"},
+	}
+	results := make(chan result, len(concurrent))
+	for _, c := range concurrent {
+		c := c
+		go func() {
+			data, err := Capture(c.kind, duration)
+			results <- result{File{c.name, data}, err}
+		}()
+	}
+	var files []File
+	var errs []error
+	for range concurrent {
+		res := <-results
+		if res.err != nil {
+			errs = append(errs, res.err)
+			continue
+		}
+		files = append(files, res.file)
+	}
+	for _, kind := range []string{"heap", "allocs", "goroutine"} {
+		data, err := Capture(kind, duration)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		files = append(files, File{kind + ".pprof", data})
+	}
+	if len(errs) != 0 {
+		return nil, fmt.Errorf("failed to capture profiles: %v", errs)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+func WriteZip(w io.Writer, files []File) error {
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := zw.Create(f.Name)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(f.Data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func ReadZip(data []byte) ([]File, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	var files []File
+	for _, zf := range zr.File {
+		r, err := zf.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{zf.Name, data})
+	}
+	return files, nil
+}
+
+// ParseDuration parses the "seconds" request parameter.
+func ParseDuration(r *http.Request) (time.Duration, error) {
+	str := r.FormValue("seconds")
+	if str == "" {
+		return DefaultDuration, nil
+	}
+	seconds, err := strconv.Atoi(str)
+	if err != nil || seconds <= 0 || time.Duration(seconds)*time.Second > MaxDuration {
+		return 0, fmt.Errorf("bad seconds value %q", str)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// ServeFile sends the profile as a downloadable file.
+func ServeFile(w http.ResponseWriter, name string, data []byte) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Write(data)
+}
+
+// BundleHandler serves a zip archive with profiles of the current process (see Bundle).
+// Accepts optional "seconds" parameter.
+func BundleHandler(w http.ResponseWriter, r *http.Request) {
+	duration, err := ParseDuration(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := Capture(KindBundle, duration)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ServeFile(w, fmt.Sprintf("profile-%v.zip", time.Now().Format("20060102-150405")), data)
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package profile
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCapture(t *testing.T) {
+	for _, kind := range []string{KindCPU, KindTrace, "heap", "goroutine"} {
+		data, err := Capture(kind, 10*time.Millisecond)
+		if err != nil {
+			t.Fatalf("%v: %v", kind, err)
+		}
+		if len(data) == 0 {
+			t.Fatalf("%v: empty profile", kind)
+		}
+	}
+	if _, err := Capture("foo", time.Second); err == nil {
+		t.Fatalf("unknown profile kind is accepted")
+	}
+	if _, err := Capture("heap", time.Hour); err == nil {
+		t.Fatalf("too long duration is accepted")
+	}
+}
+
+func TestBundle(t *testing.T) {
+	data, err := Capture(KindBundle, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := ReadZip(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range files {
+		if len(f.Data) == 0 {
+			t.Errorf("%v is empty", f.Name)
+		}
+		names = append(names, f.Name)
+	}
+	want := []string{"allocs.pprof", "cpu.pprof", "goroutine.pprof", "heap.pprof", "trace.out"}
+	if len(names) != len(want) {
+		t.Fatalf("got files %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("got files %v, want %v", names, want)
+		}
+	}
+	buf := new(bytes.Buffer)
+	if err := WriteZip(buf, files); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadZip(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBundleHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	BundleHandler(rec, httptest.NewRequest("GET", "/?seconds=1000", nil))
+	if rec.Code != 400 {
+		t.Fatalf("bad duration is accepted: %v", rec.Code)
+	}
+}
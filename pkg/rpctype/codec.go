@@ -0,0 +1,488 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package rpctype
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"net/rpc"
+
+	"github.com/google/syzkaller/pkg/signal"
+)
+
+// The binary codec is used for the high-rate RPC messages (fuzzer<->manager).
+// gob spends most of the time in reflection and allocations for these messages,
+// the binary codec encodes them with hand-written code and decodes byte slices (programs)
+// without copying (they point into the received frame).
+// Messages that don't implement binaryMessage are encoded with gob.
+//
+// Wire format: handshake (see compress.go), then a sequence of frames. Each frame is:
+// uvarint frame size, frame encoding (frameRaw or compressed), payload.
+// Payload is: service method, seq, error, body kind, body.
+// The binary codec works on the raw connection, large frames are compressed
+// with the compression method negotiated in the handshake.
+const codecMagic = "\x00syzrpc2"
+
+const (
+	bodyNone byte = iota
+	bodyGob
+	bodyBinary
+)
+
+const maxFrameSize = 1 << 30
+
+type binaryMessage interface {
+	encode(e *encoder)
+	decode(d *decoder)
+}
+
+type codecConn struct {
+	r     *bufio.Reader
+	w     io.Writer
+	c     io.Closer
+	comp  *compressor
+	enc   encoder
+	frame []byte
+	kind  byte
+	body  []byte
+}
+
+func (cc *codecConn) write(method string, seq uint64, errMsg string, body interface{}) error {
+	e := &cc.enc
+	e.buf = e.buf[:0]
+	e.string(method)
+	e.uint(seq)
+	e.string(errMsg)
+	switch m := body.(type) {
+	case nil:
+		e.buf = append(e.buf, bodyNone)
+	case binaryMessage:
+		e.buf = append(e.buf, bodyBinary)
+		m.encode(e)
+	default:
+		e.buf = append(e.buf, bodyGob)
+		buf := bytes.NewBuffer(e.buf)
+		if err := gob.NewEncoder(buf).Encode(body); err != nil {
+			return err
+		}
+		e.buf = buf.Bytes()
+	}
+	encoding, payload := frameRaw, e.buf
+	if cc.comp != nil && len(payload) >= compressThreshold {
+		compressed, err := cc.comp.compress(payload)
+		if err != nil {
+			return err
+		}
+		if len(compressed) < len(payload) {
+			encoding, payload = cc.comp.id, compressed
+		}
+	}
+	var hdr [binary.MaxVarintLen64]byte
+	frame := append(cc.frame[:0], hdr[:binary.PutUvarint(hdr[:], uint64(len(payload)+1))]...)
+	frame = append(frame, encoding)
+	frame = append(frame, payload...)
+	cc.frame = frame
+	_, err := cc.w.Write(frame)
+	return err
+}
+
+func (cc *codecConn) readHeader() (method string, seq uint64, errMsg string, err error) {
+	size, err := binary.ReadUvarint(cc.r)
+	if err != nil {
+		return "", 0, "", err
+	}
+	if size == 0 || size > maxFrameSize {
+		return "", 0, "", fmt.Errorf("bad rpc frame size: %v", size)
+	}
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(cc.r, frame); err != nil {
+		return "", 0, "", err
+	}
+	payload := frame[1:]
+	if encoding := frame[0]; encoding != frameRaw {
+		if cc.comp == nil || encoding != cc.comp.id {
+			return "", 0, "", fmt.Errorf("unexpected rpc frame encoding %v", encoding)
+		}
+		if payload, err = cc.comp.decompress(payload); err != nil {
+			return "", 0, "", err
+		}
+	}
+	d := &decoder{buf: payload}
+	method = d.string()
+	seq = d.uint()
+	errMsg = d.string()
+	cc.kind = d.byte()
+	if d.err != nil {
+		return "", 0, "", d.err
+	}
+	cc.body = d.buf
+	return method, seq, errMsg, nil
+}
+
+func (cc *codecConn) readBody(body interface{}) error {
+	data := cc.body
+	cc.body = nil
+	if body == nil {
+		return nil
+	}
+	switch cc.kind {
+	case bodyNone:
+		return nil
+	case bodyGob:
+		return gob.NewDecoder(bytes.NewReader(data)).Decode(body)
+	case bodyBinary:
+		m, ok := body.(binaryMessage)
+		if !ok {
+			return fmt.Errorf("can't decode binary rpc message into %T", body)
+		}
+		d := &decoder{buf: data}
+		m.decode(d)
+		if d.err == nil && len(d.buf) != 0 {
+			d.err = fmt.Errorf("%v trailing bytes in rpc message", len(d.buf))
+		}
+		return d.err
+	default:
+		return fmt.Errorf("unknown rpc body kind %v", cc.kind)
+	}
+}
+
+type serverCodec struct {
+	codecConn
+}
+
+// newServerCodec is called after the server has consumed codecMagic from r.
+func newServerCodec(r *bufio.Reader, conn io.ReadWriteCloser) (*serverCodec, error) {
+	comp, err := acceptCompression(r, conn)
+	if err != nil {
+		return nil, err
+	}
+	return &serverCodec{codecConn{r: r, w: conn, c: conn, comp: comp}}, nil
+}
+
+func (sc *serverCodec) ReadRequestHeader(req *rpc.Request) error {
+	var err error
+	req.ServiceMethod, req.Seq, _, err = sc.readHeader()
+	return err
+}
+
+func (sc *serverCodec) ReadRequestBody(body interface{}) error {
+	return sc.readBody(body)
+}
+
+func (sc *serverCodec) WriteResponse(resp *rpc.Response, body interface{}) error {
+	if resp.Error != "" {
+		body = nil
+	}
+	return sc.write(resp.ServiceMethod, resp.Seq, resp.Error, body)
+}
+
+func (sc *serverCodec) Close() error {
+	return sc.c.Close()
+}
+
+type clientCodec struct {
+	codecConn
+}
+
+func newClientCodec(conn io.ReadWriteCloser) (*clientCodec, error) {
+	r := bufio.NewReader(conn)
+	comp, err := offerCompression(r, conn)
+	if err != nil {
+		return nil, err
+	}
+	return &clientCodec{codecConn{r: r, w: conn, c: conn, comp: comp}}, nil
+}
+
+func (cc *clientCodec) WriteRequest(req *rpc.Request, body interface{}) error {
+	return cc.write(req.ServiceMethod, req.Seq, "", body)
+}
+
+func (cc *clientCodec) ReadResponseHeader(resp *rpc.Response) error {
+	var err error
+	resp.ServiceMethod, resp.Seq, resp.Error, err = cc.readHeader()
+	return err
+}
+
+func (cc *clientCodec) ReadResponseBody(body interface{}) error {
+	return cc.readBody(body)
+}
+
+func (cc *clientCodec) Close() error {
+	return cc.c.Close()
+}
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) uint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	e.buf = append(e.buf, tmp[:binary.PutUvarint(tmp[:], v)]...)
+}
+
+func (e *encoder) int(v int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	e.buf = append(e.buf, tmp[:binary.PutVarint(tmp[:], v)]...)
+}
+
+func (e *encoder) bool(v bool) {
+	b := byte(0)
+	if v {
+		b = 1
+	}
+	e.buf = append(e.buf, b)
+}
+
+func (e *encoder) bytes(v []byte) {
+	e.uint(uint64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+func (e *encoder) string(v string) {
+	e.uint(uint64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+func (e *encoder) uint32s(v []uint32) {
+	e.uint(uint64(len(v)))
+	for _, x := range v {
+		e.buf = append(e.buf, byte(x), byte(x>>8), byte(x>>16), byte(x>>24))
+	}
+}
+
+func (e *encoder) signal(v signal.Serial) {
+	e.buf = v.AppendBinary(e.buf)
+}
+
+// decoder has a sticky error: after the first error all methods return zero values.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = fmt.Errorf("corrupted rpc message")
+	}
+	d.buf = nil
+}
+
+func (d *decoder) byte() byte {
+	if len(d.buf) == 0 {
+		d.fail()
+		return 0
+	}
+	v := d.buf[0]
+	d.buf = d.buf[1:]
+	return v
+}
+
+func (d *decoder) uint() uint64 {
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) int() int64 {
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) bool() bool {
+	return d.byte() != 0
+}
+
+// len decodes length of a sequence of elements of elemSize bytes (at least).
+func (d *decoder) len(elemSize int) int {
+	n := d.uint()
+	if n > uint64(len(d.buf)/elemSize) {
+		d.fail()
+		return 0
+	}
+	return int(n)
+}
+
+// bytes returns a slice pointing into the decoded buffer (no copy).
+func (d *decoder) bytes() []byte {
+	n := d.len(1)
+	if n == 0 {
+		return nil
+	}
+	v := d.buf[:n:n]
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) string() string {
+	n := d.len(1)
+	v := string(d.buf[:n])
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) uint32s() []uint32 {
+	n := d.len(4)
+	if n == 0 {
+		return nil
+	}
+	v := make([]uint32, n)
+	for i := range v {
+		v[i] = binary.LittleEndian.Uint32(d.buf[i*4:])
+	}
+	d.buf = d.buf[n*4:]
+	return v
+}
+
+func (d *decoder) signal() signal.Serial {
+	var v signal.Serial
+	if d.err != nil {
+		return v
+	}
+	rest, err := v.DecodeBinary(d.buf)
+	if err != nil {
+		d.fail()
+		return v
+	}
+	d.buf = rest
+	return v
+}
+
+func (inp *Input) encode(e *encoder) {
+	e.string(inp.Call)
+	e.bytes(inp.Prog)
+	e.signal(inp.Signal)
+	e.uint32s(inp.Cover)
+	e.int(int64(inp.CallID))
+	e.uint32s(inp.RawCover)
+}
+
+func (inp *Input) decode(d *decoder) {
+	inp.Call = d.string()
+	inp.Prog = d.bytes()
+	inp.Signal = d.signal()
+	inp.Cover = d.uint32s()
+	inp.CallID = int(d.int())
+	inp.RawCover = d.uint32s()
+}
+
+func (cand *Candidate) encode(e *encoder) {
+	e.bytes(cand.Prog)
+	e.bool(cand.Minimized)
+	e.bool(cand.Smashed)
+}
+
+func (cand *Candidate) decode(d *decoder) {
+	cand.Prog = d.bytes()
+	cand.Minimized = d.bool()
+	cand.Smashed = d.bool()
+}
+
+func (a *NewInputArgs) encode(e *encoder) {
+	e.string(a.Name)
+	a.Input.encode(e)
+}
+
+func (a *NewInputArgs) decode(d *decoder) {
+	a.Name = d.string()
+	a.Input.decode(d)
+}
+
+func (a *PollArgs) encode(e *encoder) {
+	e.string(a.Name)
+	e.bool(a.NeedCandidates)
+	e.signal(a.MaxSignal)
+	e.uint(uint64(len(a.Stats)))
+	for k, v := range a.Stats {
+		e.string(k)
+		e.uint(v)
+	}
+}
+
+func (a *PollArgs) decode(d *decoder) {
+	a.Name = d.string()
+	a.NeedCandidates = d.bool()
+	a.MaxSignal = d.signal()
+	a.Stats = nil
+	if n := d.len(2); n != 0 {
+		a.Stats = make(map[string]uint64, n)
+		for i := 0; i < n; i++ {
+			k := d.string()
+			a.Stats[k] = d.uint()
+		}
+	}
+}
+
+func (r *PollRes) encode(e *encoder) {
+	e.uint(uint64(len(r.Candidates)))
+	for i := range r.Candidates {
+		r.Candidates[i].encode(e)
+	}
+	e.uint(uint64(len(r.NewInputs)))
+	for i := range r.NewInputs {
+		r.NewInputs[i].encode(e)
+	}
+	e.signal(r.MaxSignal)
+	e.bool(r.Profile != nil)
+	if r.Profile != nil {
+		e.int(r.Profile.ID)
+		e.string(r.Profile.Kind)
+		e.int(int64(r.Profile.Seconds))
+	}
+	e.uint(uint64(len(r.RacyPairs)))
+	for _, pair := range r.RacyPairs {
+		e.string(pair.Call0)
+		e.string(pair.Call1)
+		e.int(int64(pair.Reports))
+	}
+}
+
+func (r *PollRes) decode(d *decoder) {
+	r.Candidates = nil
+	if n := d.len(3); n != 0 {
+		r.Candidates = make([]Candidate, n)
+		for i := range r.Candidates {
+			r.Candidates[i].decode(d)
+		}
+	}
+	r.NewInputs = nil
+	if n := d.len(6); n != 0 {
+		r.NewInputs = make([]Input, n)
+		for i := range r.NewInputs {
+			r.NewInputs[i].decode(d)
+		}
+	}
+	r.MaxSignal = d.signal()
+	r.Profile = nil
+	if d.bool() {
+		r.Profile = &ProfileRequest{
+			ID:      d.int(),
+			Kind:    d.string(),
+			Seconds: int(d.int()),
+		}
+	}
+	r.RacyPairs = nil
+	if n := d.len(3); n != 0 {
+		r.RacyPairs = make([]RacyPair, n)
+		for i := range r.RacyPairs {
+			r.RacyPairs[i] = RacyPair{
+				Call0:   d.string(),
+				Call1:   d.string(),
+				Reports: int(d.int()),
+			}
+		}
+	}
+}
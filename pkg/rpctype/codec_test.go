@@ -0,0 +1,492 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package rpctype
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math/rand"
+	"net"
+	"net/rpc"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/syzkaller/pkg/signal"
+)
+
+type testServer struct{}
+
+func (*testServer) Poll(a *PollArgs, r *PollRes) error {
+	r.MaxSignal = a.MaxSignal
+	for i := 0; i < 10; i++ {
+		r.NewInputs = append(r.NewInputs, Input{
+			Call:   a.Name,
+			Prog:   []byte(fmt.Sprintf("prog%v()", i)),
+			Signal: a.MaxSignal,
+			Cover:  []uint32{1, 2, 3},
+			CallID: -1,
+		})
+		r.Candidates = append(r.Candidates, Candidate{Prog: []byte("candidate"), Smashed: i%2 == 0})
+	}
+	return nil
+}
+
+func (*testServer) Check(a *CheckArgs, r *int) error {
+	*r = len(a.EnabledCalls)
+	return nil
+}
+
+func (*testServer) Fail(a *RunnerConnectArgs, r *RunnerConnectRes) error {
+	return fmt.Errorf("pool %v failed", a.Pool)
+}
+
+func startTestServer(t testing.TB) string {
+	s, err := NewRPCServer("127.0.0.1:0", "Test", new(testServer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve()
+	return s.Addr().String()
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	msgs := []binaryMessage{
+		&PollArgs{
+			Name:           "vm-0",
+			NeedCandidates: true,
+			MaxSignal:      testSignal(rnd, 100),
+			Stats:          map[string]uint64{"exec total": 1 << 40, "": 0},
+		},
+		&PollRes{
+			Candidates: []Candidate{{Prog: []byte("a()"), Minimized: true}, {}},
+			NewInputs:  []Input{{Call: "foo", Prog: []byte("foo()"), CallID: -1, RawCover: []uint32{1}}},
+			Profile:    &ProfileRequest{ID: 1, Kind: "heap", Seconds: 10},
+			RacyPairs:  []RacyPair{{Call0: "write", Call1: "read", Reports: 3}, {Call0: "close", Reports: -1}},
+		},
+		&NewInputArgs{
+			Name:  "vm-1",
+			Input: Input{Call: "bar", Prog: []byte("bar()"), Signal: testSignal(rnd, 10), Cover: []uint32{0, 1 << 31}},
+		},
+	}
+	for _, msg := range msgs {
+		e := new(encoder)
+		msg.encode(e)
+		res := reflect.New(reflect.TypeOf(msg).Elem()).Interface().(binaryMessage)
+		d := &decoder{buf: e.buf}
+		res.decode(d)
+		if d.err != nil {
+			t.Fatalf("%T: failed to decode: %v", msg, d.err)
+		}
+		if len(d.buf) != 0 {
+			t.Fatalf("%T: %v trailing bytes", msg, len(d.buf))
+		}
+		if !reflect.DeepEqual(msg, res) {
+			t.Fatalf("%T: decoded message differs:\n%#v\n%#v", msg, msg, res)
+		}
+		// Truncated messages must not crash the decoder.
+		for i := 0; i < len(e.buf); i++ {
+			res.decode(&decoder{buf: e.buf[:i]})
+		}
+	}
+}
+
+// TestCodecAllFields checks that the binary codec round-trips every exported field of the messages,
+// fields added to the message types later are not serialized unless encode/decode are updated.
+func TestCodecAllFields(t *testing.T) {
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for _, msg := range codecMessages {
+		typ := reflect.TypeOf(msg).Elem()
+		orig := reflect.New(typ)
+		fillValue(t, rnd, orig.Elem(), typ.Name())
+		e := new(encoder)
+		orig.Interface().(binaryMessage).encode(e)
+		res := reflect.New(typ)
+		d := &decoder{buf: e.buf}
+		res.Interface().(binaryMessage).decode(d)
+		if d.err != nil {
+			t.Fatalf("%v: failed to decode: %v", typ.Name(), d.err)
+		}
+		if len(d.buf) != 0 {
+			t.Fatalf("%v: %v trailing bytes", typ.Name(), len(d.buf))
+		}
+		checkFields(t, orig.Elem(), res.Elem(), typ.Name())
+	}
+}
+
+// codecMessages lists all binaryMessage types.
+var codecMessages = []binaryMessage{
+	new(Input),
+	new(Candidate),
+	new(NewInputArgs),
+	new(PollArgs),
+	new(PollRes),
+}
+
+// TestCodecMessagesComplete checks that codecMessages lists all types that implement binaryMessage,
+// so that TestCodecAllFields covers new message types.
+func TestCodecMessagesComplete(t *testing.T) {
+	pkgs, err := parser.ParseDir(token.NewFileSet(), ".", func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed := make(map[string]bool)
+	for _, msg := range codecMessages {
+		listed[reflect.TypeOf(msg).Elem().Name()] = true
+	}
+	for _, file := range pkgs["rpctype"].Files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || fn.Name.Name != "encode" {
+				continue
+			}
+			recv := fn.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			name := recv.(*ast.Ident).Name
+			if !listed[name] {
+				t.Errorf("binary message %v is missing in codecMessages", name)
+			}
+		}
+	}
+}
+
+// fillValue sets all fields reachable from v to non-zero values.
+// All slices and maps get 2 elements, so that signal.Serial Elems/Prios have matching lengths.
+func fillValue(t *testing.T, rnd *rand.Rand, v reflect.Value, path string) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.PkgPath != "" {
+				t.Fatalf("%v: unexported field %v can't be checked", path, f.Name)
+			}
+			fillValue(t, rnd, v.Field(i), path+"."+f.Name)
+		}
+	case reflect.Ptr:
+		v.Set(reflect.New(v.Type().Elem()))
+		fillValue(t, rnd, v.Elem(), path)
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 2, 2))
+		for i := 0; i < v.Len(); i++ {
+			fillValue(t, rnd, v.Index(i), fmt.Sprintf("%v[%v]", path, i))
+		}
+	case reflect.Map:
+		v.Set(reflect.MakeMap(v.Type()))
+		for i := 0; i < 2; i++ {
+			key := reflect.New(v.Type().Key()).Elem()
+			fillValue(t, rnd, key, path)
+			val := reflect.New(v.Type().Elem()).Elem()
+			fillValue(t, rnd, val, path)
+			v.SetMapIndex(key, val)
+		}
+	case reflect.String:
+		v.SetString(fmt.Sprintf("%v-%v", path, rnd.Intn(1000)))
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(1 + rnd.Intn(100)))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(uint64(1 + rnd.Intn(100)))
+	default:
+		t.Fatalf("%v: unsupported field kind %v", path, v.Kind())
+	}
+}
+
+// checkFields compares orig and res field-by-field to point to the field that was lost.
+func checkFields(t *testing.T, orig, res reflect.Value, path string) {
+	if orig.Kind() == reflect.Struct {
+		for i := 0; i < orig.NumField(); i++ {
+			checkFields(t, orig.Field(i), res.Field(i), path+"."+orig.Type().Field(i).Name)
+		}
+		return
+	}
+	if !reflect.DeepEqual(orig.Interface(), res.Interface()) {
+		t.Errorf("%v is not round-tripped by the codec:\n%#v\n%#v", path, orig.Interface(), res.Interface())
+	}
+}
+
+func TestCodecRPC(t *testing.T) {
+	addr := startTestServer(t)
+	rnd := rand.New(rand.NewSource(0))
+	newClients := []func(string, time.Duration) (*RPCClient, error){NewRPCClient, NewGobRPCClient, newFlateGobRPCClient}
+	for _, newClient := range newClients {
+		cli, err := newClient(addr, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pollArgs := &PollArgs{Name: "foo", MaxSignal: testSignal(rnd, 10)}
+		pollRes := new(PollRes)
+		if err := cli.Call("Test.Poll", pollArgs, pollRes); err != nil {
+			t.Fatal(err)
+		}
+		if len(pollRes.NewInputs) != 10 || pollRes.NewInputs[9].Call != "foo" ||
+			string(pollRes.NewInputs[9].Prog) != "prog9()" ||
+			!reflect.DeepEqual(pollRes.MaxSignal, pollArgs.MaxSignal) {
+			t.Fatalf("bad poll result: %+v", pollRes)
+		}
+		// Messages without binary encoding are sent with gob.
+		var checkRes int
+		if err := cli.Call("Test.Check", &CheckArgs{EnabledCalls: map[string][]int{"a": nil, "b": {1}}},
+			&checkRes); err != nil {
+			t.Fatal(err)
+		}
+		if checkRes != 2 {
+			t.Fatalf("bad check result: %v", checkRes)
+		}
+		err = cli.Call("Test.Fail", &RunnerConnectArgs{Pool: 3}, new(RunnerConnectRes))
+		if _, ok := err.(rpc.ServerError); !ok || err.Error() != "pool 3 failed" {
+			t.Fatalf("bad error: %v", err)
+		}
+		cli.Close()
+	}
+}
+
+func TestGobClientFallback(t *testing.T) {
+	// Older servers serve only gob encoding over flate.
+	s := rpc.NewServer()
+	if err := s.RegisterName("Test", new(testServer)); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.ServeConn(newFlateConn(conn))
+		}
+	}()
+	cli, err := NewGobRPCClient(ln.Addr().String(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	var checkRes int
+	if err := cli.Call("Test.Check", &CheckArgs{EnabledCalls: map[string][]int{"a": nil}}, &checkRes); err != nil {
+		t.Fatal(err)
+	}
+	if checkRes != 1 {
+		t.Fatalf("bad check result: %v", checkRes)
+	}
+}
+
+func TestCodecGarbage(t *testing.T) {
+	addr := startTestServer(t)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	frame := append([]byte(codecMagic), 1, frameFlate, 0x10, frameFlate, 0xff, 0xff, 0xff)
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+	// The server must close the connection and keep serving other clients.
+	cli, err := NewRPCClient(addr, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	if err := cli.Call("Test.Poll", new(PollArgs), new(PollRes)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCodecCompression(t *testing.T) {
+	offers := [][]byte{nil, {frameFlate}, {42, frameFlate}, {frameZstd, frameFlate}, {frameFlate, frameZstd}}
+	for _, offer := range offers {
+		cli, srv := net.Pipe()
+		errc := make(chan error, 1)
+		var sc *serverCodec
+		go func() {
+			r := bufio.NewReader(srv)
+			if _, err := r.Discard(len(codecMagic)); err != nil {
+				errc <- err
+				return
+			}
+			var err error
+			sc, err = newServerCodec(r, srv)
+			errc <- err
+		}()
+		msg := append([]byte(codecMagic), byte(len(offer)))
+		if _, err := cli.Write(append(msg, offer...)); err != nil {
+			t.Fatal(err)
+		}
+		var reply [1]byte
+		if _, err := cli.Read(reply[:]); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+		want := frameRaw
+		for _, id := range offer {
+			if newCompressor(id) != nil {
+				want = id
+				break
+			}
+		}
+		if reply[0] != want || (sc.comp == nil) != (want == frameRaw) {
+			t.Fatalf("offer %v: chose %v, want %v", offer, reply[0], want)
+		}
+		cli.Close()
+		srv.Close()
+	}
+	// Large messages are compressed, small ones are not.
+	rnd := rand.New(rand.NewSource(0))
+	for _, m := range compressionMethods {
+		testCompression(t, rnd, m.id)
+	}
+}
+
+func testCompression(t *testing.T, rnd *rand.Rand, id byte) {
+	for _, n := range []int{1, 1000} {
+		res := new(PollRes)
+		for i := 0; i < n; i++ {
+			res.Candidates = append(res.Candidates, Candidate{Prog: []byte("mmap(&(0x7f0000000000/0x1000)=nil)")})
+		}
+		res.MaxSignal = testSignal(rnd, n)
+		buf := new(bytes.Buffer)
+		cc := &codecConn{r: bufio.NewReader(buf), w: buf, comp: newCompressor(id)}
+		if err := cc.write("Test.Poll", 1, "", res); err != nil {
+			t.Fatal(err)
+		}
+		raw := new(encoder)
+		res.encode(raw)
+		if compressed := buf.Len() < len(raw.buf); compressed != (n > 1) {
+			t.Fatalf("compression %v, n=%v: frame size %v, message size %v", id, n, buf.Len(), len(raw.buf))
+		}
+		method, seq, _, err := cc.readHeader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		res1 := new(PollRes)
+		if err := cc.readBody(res1); err != nil {
+			t.Fatal(err)
+		}
+		if method != "Test.Poll" || seq != 1 || !reflect.DeepEqual(res, res1) {
+			t.Fatalf("compression %v, n=%v: message differs after round-trip", id, n)
+		}
+	}
+}
+
+// BenchmarkFuzzerManager measures the fuzzer<->manager exchange over a TCP connection:
+// the fuzzer sends a new input and polls the manager, the manager replies with candidates,
+// new inputs of other fuzzers and the max signal delta (amounts typical for a large corpus).
+// The binary codec is compared with gob over flate used before.
+func BenchmarkFuzzerManager(b *testing.B) {
+	clients := []struct {
+		name      string
+		newClient func(string, time.Duration) (*RPCClient, error)
+	}{
+		{"binary", NewRPCClient},
+		{"gob+flate", newFlateGobRPCClient},
+	}
+	for _, client := range clients {
+		client := client
+		b.Run(client.name, func(b *testing.B) {
+			benchmarkFuzzerManager(b, client.newClient)
+		})
+	}
+}
+
+type benchManager struct {
+	res *PollRes
+}
+
+func (mgr *benchManager) NewInput(a *NewInputArgs, r *int) error {
+	return nil
+}
+
+func (mgr *benchManager) Poll(a *PollArgs, r *PollRes) error {
+	*r = *mgr.res
+	return nil
+}
+
+func benchmarkFuzzerManager(b *testing.B, newClient func(string, time.Duration) (*RPCClient, error)) {
+	rnd := rand.New(rand.NewSource(0))
+	mgr := &benchManager{res: &PollRes{MaxSignal: testSignal(rnd, 10000)}}
+	for i := 0; i < 100; i++ {
+		mgr.res.Candidates = append(mgr.res.Candidates, Candidate{Prog: testProg(rnd)})
+	}
+	for i := 0; i < 10; i++ {
+		mgr.res.NewInputs = append(mgr.res.NewInputs, testInput(rnd))
+	}
+	s, err := NewRPCServer("127.0.0.1:0", "Manager", mgr)
+	if err != nil {
+		b.Fatal(err)
+	}
+	go s.Serve()
+	cli, err := newClient(s.Addr().String(), 1)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer cli.Close()
+	inputArgs := &NewInputArgs{Name: "vm-0", Input: testInput(rnd)}
+	pollArgs := &PollArgs{Name: "vm-0", NeedCandidates: true, MaxSignal: testSignal(rnd, 100),
+		Stats: make(map[string]uint64)}
+	for i := 0; i < 30; i++ {
+		pollArgs.Stats[fmt.Sprintf("stat %v", i)] = rnd.Uint64()
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := cli.Call("Manager.NewInput", inputArgs, nil); err != nil {
+			b.Fatal(err)
+		}
+		res := new(PollRes)
+		if err := cli.Call("Manager.Poll", pollArgs, res); err != nil {
+			b.Fatal(err)
+		}
+		if len(res.Candidates) != len(mgr.res.Candidates) {
+			b.Fatalf("got %v candidates, want %v", len(res.Candidates), len(mgr.res.Candidates))
+		}
+	}
+}
+
+func testInput(rnd *rand.Rand) Input {
+	inp := Input{
+		Call:   "mmap",
+		Prog:   testProg(rnd),
+		Signal: testSignal(rnd, 500),
+		CallID: rnd.Intn(5),
+	}
+	for i := 0; i < 2000; i++ {
+		inp.Cover = append(inp.Cover, rnd.Uint32())
+	}
+	return inp
+}
+
+func testProg(rnd *rand.Rand) []byte {
+	buf := new(bytes.Buffer)
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(buf, "r%v = openat$fuse(0x%x, &(0x7f0000%04x)='/dev/fuse\\x00', 0x2, 0x0)\n",
+			i, rnd.Intn(1<<16), rnd.Intn(1<<16))
+	}
+	return buf.Bytes()
+}
+
+func testSignal(rnd *rand.Rand, n int) signal.Serial {
+	raw := make([]uint32, n)
+	for i := range raw {
+		raw[i] = rnd.Uint32()
+	}
+	return signal.FromRaw(raw, uint8(rnd.Intn(3))).Serialize()
+}
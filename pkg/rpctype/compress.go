@@ -0,0 +1,191 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package rpctype
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is negotiated per connection during the handshake:
+// the client sends codecMagic, uvarint number of compression methods it supports
+// and their ids in the order of preference; the server replies with a single byte
+// with the id of the chosen method (frameRaw if there are no common methods).
+// Only frames larger than compressThreshold are compressed, small messages (most of them)
+// are sent as is to not waste CPU time on both sides.
+const (
+	frameRaw byte = iota
+	frameFlate
+	frameZstd
+)
+
+const (
+	compressThreshold     = 4 << 10
+	maxCompressionMethods = 16
+)
+
+type compressor struct {
+	id byte
+	// compress returns a buffer that is valid only until the next call.
+	compress   func(data []byte) ([]byte, error)
+	decompress func(data []byte) ([]byte, error)
+}
+
+// compressionMethods lists supported compression methods in the order of preference.
+var compressionMethods = []struct {
+	id  byte
+	new func() *compressor
+}{
+	{frameZstd, newZstdCompressor},
+	{frameFlate, newFlateCompressor},
+}
+
+func newCompressor(id byte) *compressor {
+	for _, m := range compressionMethods {
+		if m.id == id {
+			return m.new()
+		}
+	}
+	return nil
+}
+
+func offerCompression(r *bufio.Reader, w io.Writer) (*compressor, error) {
+	var hdr [binary.MaxVarintLen64]byte
+	msg := append([]byte(codecMagic), hdr[:binary.PutUvarint(hdr[:], uint64(len(compressionMethods)))]...)
+	for _, m := range compressionMethods {
+		msg = append(msg, m.id)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return nil, err
+	}
+	id, err := r.ReadByte()
+	if err != nil {
+		return nil, &handshakeError{err}
+	}
+	if id == frameRaw {
+		return nil, nil
+	}
+	comp := newCompressor(id)
+	if comp == nil {
+		return nil, &handshakeError{fmt.Errorf("server chose unknown compression %v", id)}
+	}
+	return comp, nil
+}
+
+// handshakeError means that the server did not reply to the handshake properly,
+// e.g. it's an older version that supports only gob encoding.
+type handshakeError struct {
+	err error
+}
+
+func (err *handshakeError) Error() string {
+	return fmt.Sprintf("rpc handshake failed: %v", err.err)
+}
+
+func acceptCompression(r *bufio.Reader, w io.Writer) (*compressor, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxCompressionMethods {
+		return nil, fmt.Errorf("too many compression methods: %v", n)
+	}
+	offered := make([]byte, n)
+	if _, err := io.ReadFull(r, offered); err != nil {
+		return nil, err
+	}
+	var comp *compressor
+	for _, id := range offered {
+		if comp = newCompressor(id); comp != nil {
+			break
+		}
+	}
+	id := frameRaw
+	if comp != nil {
+		id = comp.id
+	}
+	if _, err := w.Write([]byte{id}); err != nil {
+		return nil, err
+	}
+	return comp, nil
+}
+
+func newFlateCompressor() *compressor {
+	wbuf := new(bytes.Buffer)
+	w, err := flate.NewWriter(wbuf, flate.DefaultCompression)
+	if err != nil {
+		panic(err)
+	}
+	r := flate.NewReader(bytes.NewReader(nil))
+	return &compressor{
+		id: frameFlate,
+		compress: func(data []byte) ([]byte, error) {
+			wbuf.Reset()
+			w.Reset(wbuf)
+			if _, err := w.Write(data); err != nil {
+				return nil, err
+			}
+			if err := w.Close(); err != nil {
+				return nil, err
+			}
+			return wbuf.Bytes(), nil
+		},
+		decompress: func(data []byte) ([]byte, error) {
+			if err := r.(flate.Resetter).Reset(bytes.NewReader(data), nil); err != nil {
+				return nil, err
+			}
+			res, err := ioutil.ReadAll(io.LimitReader(r, maxFrameSize+1))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decompress rpc frame: %v", err)
+			}
+			if len(res) > maxFrameSize {
+				return nil, fmt.Errorf("decompressed rpc frame is too large")
+			}
+			return res, nil
+		},
+	}
+}
+
+// zstd encoder and decoder are safe for concurrent use and start background goroutines,
+// so all connections share them.
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+)
+
+func newZstdCompressor() *compressor {
+	zstdOnce.Do(func() {
+		var err error
+		if zstdEncoder, err = zstd.NewWriter(nil); err != nil {
+			panic(err)
+		}
+		if zstdDecoder, err = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxFrameSize)); err != nil {
+			panic(err)
+		}
+	})
+	var buf []byte
+	return &compressor{
+		id: frameZstd,
+		compress: func(data []byte) ([]byte, error) {
+			buf = zstdEncoder.EncodeAll(data, buf[:0])
+			return buf, nil
+		},
+		decompress: func(data []byte) ([]byte, error) {
+			res, err := zstdDecoder.DecodeAll(data, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decompress rpc frame: %v", err)
+			}
+			return res, nil
+		},
+	}
+}
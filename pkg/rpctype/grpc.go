@@ -0,0 +1,153 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package rpctype
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/syzkaller/pkg/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// gRPC is used for the verifier<->runner protocol (see runnerpb), so that runners
+// can be implemented in other languages and middleware can be added with interceptors.
+// Both sides send keepalive pings, so dead connections are detected even if the streams are idle.
+
+// grpcMaxMsgSize is large enough for the execution info with the coverage of all calls.
+const grpcMaxMsgSize = 256 << 20
+
+// NewGRPCServer creates a gRPC server and a listener on addr for it,
+// the server accepts only TLS connections if cfg is not nil.
+func NewGRPCServer(addr string, cfg *tls.Config, opts ...grpc.ServerOption) (*grpc.Server, net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to listen on %v: %v", addr, err)
+	}
+	opts = append([]grpc.ServerOption{
+		grpc.MaxRecvMsgSize(grpcMaxMsgSize),
+		grpc.MaxSendMsgSize(grpcMaxMsgSize),
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    time.Minute,
+			Timeout: time.Minute,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             10 * time.Second,
+			PermitWithoutStream: true,
+		}),
+	}, opts...)
+	if cfg != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg)))
+	}
+	return grpc.NewServer(opts...), ln, nil
+}
+
+// DialGRPC connects to the gRPC server on addr, it uses TLS if cfg is not nil.
+func DialGRPC(addr string, timeScale time.Duration, cfg *tls.Config,
+	opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	if timeScale <= 0 {
+		return nil, fmt.Errorf("bad rpc time scale %v", timeScale)
+	}
+	opts = append([]grpc.DialOption{
+		grpc.WithBlock(),
+		grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(grpcMaxMsgSize),
+			grpc.MaxCallSendMsgSize(grpcMaxMsgSize),
+		),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                time.Minute * timeScale,
+			Timeout:             time.Minute * timeScale,
+			PermitWithoutStream: true,
+		}),
+	}, opts...)
+	if cfg != nil {
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(cfg)))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*timeScale)
+	defer cancel()
+	return grpc.DialContext(ctx, addr, opts...)
+}
+
+// The token authentication is used when runners can't be provisioned with the TLS certificates,
+// e.g. when they run in a remote lab: the clients send the shared secret token
+// in the authorization metadata of each call. Without TLS the token can be sniffed on the network.
+
+const tokenPrefix = "Bearer "
+
+// ReadTokenFile reads the authentication token from the file.
+func ReadTokenFile(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %v", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %v is empty", file)
+	}
+	return token, nil
+}
+
+// TokenServerOptions returns the server options that reject calls without the token.
+func TokenServerOptions(token string) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler) (interface{}, error) {
+			if err := checkToken(ctx, token); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+			handler grpc.StreamHandler) error {
+			if err := checkToken(ss.Context(), token); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
+
+func checkToken(ctx context.Context, token string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, auth := range md.Get("authorization") {
+		if subtle.ConstantTimeCompare([]byte(auth), []byte(tokenPrefix+token)) == 1 {
+			return nil
+		}
+	}
+	addr := "unknown"
+	if p, ok := peer.FromContext(ctx); ok {
+		addr = p.Addr.String()
+	}
+	log.Logf(0, "rejected an unauthenticated rpc from %v", addr)
+	return status.Error(codes.Unauthenticated, "bad or missing authentication token")
+}
+
+// TokenDialOption returns the dial option that sends the token with each call.
+func TokenDialOption(token string) grpc.DialOption {
+	return grpc.WithPerRPCCredentials(tokenCredentials(token))
+}
+
+type tokenCredentials string
+
+func (tc tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": tokenPrefix + string(tc)}, nil
+}
+
+func (tc tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
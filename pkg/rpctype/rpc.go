@@ -4,7 +4,9 @@
 package rpctype
 
 import (
+	"bufio"
 	"compress/flate"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
@@ -16,8 +18,9 @@ import (
 )
 
 type RPCServer struct {
-	ln net.Listener
-	s  *rpc.Server
+	ln  net.Listener
+	s   *rpc.Server
+	tls *tls.Config
 }
 
 func NewRPCServer(addr, name string, receiver interface{}) (*RPCServer, error) {
@@ -36,6 +39,11 @@ func NewRPCServer(addr, name string, receiver interface{}) (*RPCServer, error) {
 	return serv, nil
 }
 
+// SetTLS makes the server accept only TLS connections, must be called before Serve.
+func (serv *RPCServer) SetTLS(cfg *tls.Config) {
+	serv.tls = cfg
+}
+
 func (serv *RPCServer) Serve() {
 	for {
 		conn, err := serv.ln.Accept()
@@ -44,10 +52,46 @@ func (serv *RPCServer) Serve() {
 			continue
 		}
 		setupKeepAlive(conn, time.Minute)
-		go serv.s.ServeConn(newFlateConn(conn))
+		if serv.tls != nil {
+			conn = tls.Server(conn, serv.tls)
+		}
+		go serv.serveConn(conn)
 	}
 }
 
+// serveConn serves clients using the binary codec and clients using plain gob encoding
+// (older versions) on the same port.
+// Gob connections are wrapped in flateConn, binary codec connections negotiate compression.
+// codecMagic can't be a prefix of a valid flate stream (it would be a stored block with mismatching LEN/NLEN),
+// so the two are distinguishable.
+func (serv *RPCServer) serveConn(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	magic, err := r.Peek(len(codecMagic))
+	if err != nil {
+		if serv.tls != nil && err != io.EOF {
+			log.Logf(0, "rpc connection from %v failed: %v", conn.RemoteAddr(), err)
+		}
+		conn.Close()
+		return
+	}
+	if string(magic) == codecMagic {
+		r.Discard(len(codecMagic))
+		codec, err := newServerCodec(r, conn)
+		if err != nil {
+			log.Logf(0, "rpc handshake with %v failed: %v", conn.RemoteAddr(), err)
+			conn.Close()
+			return
+		}
+		serv.s.ServeCodec(codec)
+		return
+	}
+	serv.s.ServeConn(newFlateConn(struct {
+		io.Reader
+		io.Writer
+		io.Closer
+	}{r, conn, conn}))
+}
+
 func (serv *RPCServer) Addr() net.Addr {
 	return serv.ln.Addr()
 }
@@ -75,7 +119,52 @@ func Dial(addr string, timeScale time.Duration) (net.Conn, error) {
 	return conn, nil
 }
 
+// NewRPCClient creates a client that uses the binary codec (see codec.go).
 func NewRPCClient(addr string, timeScale time.Duration) (*RPCClient, error) {
+	return NewRPCClientTLS(addr, timeScale, nil)
+}
+
+// NewRPCClientTLS is the same as NewRPCClient, but uses TLS if cfg is not nil.
+func NewRPCClientTLS(addr string, timeScale time.Duration, cfg *tls.Config) (*RPCClient, error) {
+	conn, err := Dial(addr, timeScale)
+	if err != nil {
+		return nil, err
+	}
+	if cfg != nil {
+		conn = tls.Client(conn, cfg)
+	}
+	// Note: SetDeadline is not implemented on fuchsia, so don't fail on error.
+	conn.SetDeadline(time.Now().Add(time.Minute * timeScale))
+	codec, err := newClientCodec(conn)
+	conn.SetDeadline(time.Time{})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	cli := &RPCClient{
+		conn:      conn,
+		c:         rpc.NewClientWithCodec(codec),
+		timeScale: timeScale,
+	}
+	return cli, nil
+}
+
+// NewGobRPCClient creates a client for hub connections (hub messages are encoded with gob).
+// It negotiates compression the same way NewRPCClient does, but since hub may run an older version
+// that does not support the handshake, it falls back to plain gob encoding over flate in such case.
+func NewGobRPCClient(addr string, timeScale time.Duration) (*RPCClient, error) {
+	cli, err := NewRPCClient(addr, timeScale)
+	if err == nil {
+		return cli, nil
+	}
+	if _, ok := err.(*handshakeError); !ok {
+		return nil, err
+	}
+	log.Logf(0, "rpc handshake with %v failed, falling back to gob: %v", addr, err)
+	return newFlateGobRPCClient(addr, timeScale)
+}
+
+func newFlateGobRPCClient(addr string, timeScale time.Duration) (*RPCClient, error) {
 	conn, err := Dial(addr, timeScale)
 	if err != nil {
 		return nil, err
@@ -99,8 +188,9 @@ func (cli *RPCClient) Close() {
 	cli.c.Close()
 }
 
+// RPCCall does a single call using NewGobRPCClient.
 func RPCCall(addr string, timeScale time.Duration, method string, args, reply interface{}) error {
-	c, err := NewRPCClient(addr, timeScale)
+	c, err := NewGobRPCClient(addr, timeScale)
 	if err != nil {
 		return err
 	}
@@ -7,6 +7,7 @@ package rpctype
 
 import (
 	"math"
+	"time"
 
 	"github.com/google/syzkaller/pkg/host"
 	"github.com/google/syzkaller/pkg/ipc"
@@ -31,6 +32,10 @@ type Candidate struct {
 type ExecTask struct {
 	Prog []byte
 	ID   int64
+	// Trace is set if the program must be executed under strace (used by syz-verifier).
+	Trace bool
+	// Sandbox is the executor sandbox of the program, the default one if empty (used by syz-verifier).
+	Sandbox string
 }
 
 type ConnectArgs struct {
@@ -80,6 +85,33 @@ type PollRes struct {
 	Candidates []Candidate
 	NewInputs  []Input
 	MaxSignal  signal.Serial
+	// Profile is set if the manager wants the fuzzer to capture a runtime profile,
+	// the fuzzer sends it back with Manager.ProfileResult.
+	Profile *ProfileRequest
+	// RacyPairs is set if the set of pairs of calls that preceded KCSAN reports has changed,
+	// it always contains all known pairs.
+	RacyPairs []RacyPair
+}
+
+// RacyPair is a pair of calls that were executed right before a KCSAN data race report.
+type RacyPair struct {
+	Call0 string
+	Call1 string
+	// Reports is the number of KCSAN reports preceded by the pair.
+	Reports int
+}
+
+type ProfileRequest struct {
+	ID      int64
+	Kind    string // see pkg/profile
+	Seconds int
+}
+
+type ProfileResultArgs struct {
+	Name  string
+	ID    int64
+	Data  []byte
+	Error string
 }
 
 type RunnerConnectArgs struct {
@@ -90,6 +122,14 @@ type RunnerConnectRes struct {
 	// CheckUnsupportedCalls is set to true if the Runner needs to query the kernel
 	// for unsupported system calls and report them back to the server.
 	CheckUnsupportedCalls bool
+	// CollectOutputs is set to true if the Runner needs to collect the outputs
+	// of the calls (see ipc.FlagCollectOutputs).
+	CollectOutputs bool
+	// StateProbes are the files and directories the Runner needs to snapshot
+	// before and after each program (see ipc.FlagCollectState).
+	StateProbes []string
+	// HeartbeatPeriod is how often the Runner needs to send heartbeats, 0 if it does not.
+	HeartbeatPeriod time.Duration
 }
 
 // UpdateUnsupportedArgs contains the data passed from client to server in an
@@ -117,12 +157,16 @@ type NextExchangeArgs struct {
 	// Info contains information about the execution of each system call in the
 	// program.
 	Info ipc.ProgInfo
+	// Trace is the strace output of the program if the task asked for it.
+	Trace []byte
 }
 
 // NextExchaneRes contains the data passed from server to client namely
 // programs  to execute on the VM.
 type NextExchangeRes struct {
 	ExecTask
+	// Reboot is set if the client must not execute anything, because the VM is being rebooted.
+	Reboot bool
 }
 
 const (
@@ -159,6 +203,19 @@ type HubSyncArgs struct {
 	Del []string
 	// Repros found since last sync.
 	Repros [][]byte
+	// Outcomes of programs previously received from the hub.
+	Outcomes []HubOutcome
+}
+
+// HubOutcome describes what happened to a program received from the hub.
+// It is used by the hub to maintain per-program reputation.
+type HubOutcome struct {
+	// Sig is the hash of the program.
+	Sig string
+	// Crashed is set if the program caused a crash.
+	Crashed bool
+	// Failed is set if the program was rejected by the manager as malformed.
+	Failed bool
 }
 
 type HubSyncRes struct {
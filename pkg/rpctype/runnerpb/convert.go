@@ -0,0 +1,78 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+// Package runnerpb contains the gRPC protocol between syz-verifier and syz-runner (see runner.proto).
+package runnerpb
+
+import (
+	"github.com/google/syzkaller/pkg/ipc"
+	"github.com/google/syzkaller/prog"
+)
+
+// FromProgInfo converts the execution info to its protobuf message.
+func FromProgInfo(info *ipc.ProgInfo) *ProgInfo {
+	res := &ProgInfo{
+		Extra:       fromCallInfo(&info.Extra),
+		StateBefore: info.StateBefore,
+		StateAfter:  info.StateAfter,
+	}
+	for i := range info.Calls {
+		res.Calls = append(res.Calls, fromCallInfo(&info.Calls[i]))
+	}
+	return res
+}
+
+func fromCallInfo(info *ipc.CallInfo) *CallInfo {
+	res := &CallInfo{
+		Flags:   uint32(info.Flags),
+		Signal:  info.Signal,
+		Cover:   info.Cover,
+		Errno:   int32(info.Errno),
+		Outputs: info.Outputs,
+	}
+	for op1, ops2 := range info.Comps {
+		comp := &Comparison{Op1: op1}
+		for op2 := range ops2 {
+			comp.Op2 = append(comp.Op2, op2)
+		}
+		res.Comps = append(res.Comps, comp)
+	}
+	return res
+}
+
+// ToProgInfo converts the protobuf message back to the execution info.
+func (x *ProgInfo) ToProgInfo() ipc.ProgInfo {
+	res := ipc.ProgInfo{
+		Extra:       x.GetExtra().toCallInfo(),
+		StateBefore: x.GetStateBefore(),
+		StateAfter:  x.GetStateAfter(),
+	}
+	if calls := x.GetCalls(); len(calls) != 0 {
+		res.Calls = make([]ipc.CallInfo, len(calls))
+		for i, call := range calls {
+			res.Calls[i] = call.toCallInfo()
+		}
+	}
+	return res
+}
+
+func (x *CallInfo) toCallInfo() ipc.CallInfo {
+	res := ipc.CallInfo{
+		Flags:   ipc.CallFlags(x.GetFlags()),
+		Signal:  x.GetSignal(),
+		Cover:   x.GetCover(),
+		Errno:   int(x.GetErrno()),
+		Outputs: x.GetOutputs(),
+	}
+	if comps := x.GetComps(); len(comps) != 0 {
+		res.Comps = make(prog.CompMap, len(comps))
+		for _, comp := range comps {
+			ops2 := make(map[uint64]bool, len(comp.Op2))
+			for _, op2 := range comp.Op2 {
+				ops2[op2] = true
+			}
+			res.Comps[comp.Op1] = ops2
+		}
+	}
+	return res
+}
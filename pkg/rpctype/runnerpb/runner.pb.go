@@ -0,0 +1,1752 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        (unknown)
+// source: pkg/rpctype/runnerpb/runner.proto
+
+package runnerpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ConnectRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Pool and VM identify the instance on which the Runner is running.
+	Pool int32 `protobuf:"varint,1,opt,name=pool,proto3" json:"pool,omitempty"`
+	Vm   int32 `protobuf:"varint,2,opt,name=vm,proto3" json:"vm,omitempty"`
+}
+
+func (x *ConnectRequest) Reset() {
+	*x = ConnectRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ConnectRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConnectRequest) ProtoMessage() {}
+
+func (x *ConnectRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConnectRequest.ProtoReflect.Descriptor instead.
+func (*ConnectRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_rpctype_runnerpb_runner_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ConnectRequest) GetPool() int32 {
+	if x != nil {
+		return x.Pool
+	}
+	return 0
+}
+
+func (x *ConnectRequest) GetVm() int32 {
+	if x != nil {
+		return x.Vm
+	}
+	return 0
+}
+
+type ConnectResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// CheckUnsupportedCalls is set if the Runner needs to query the kernel
+	// for unsupported system calls and report them with UpdateUnsupported.
+	CheckUnsupportedCalls bool `protobuf:"varint,1,opt,name=check_unsupported_calls,json=checkUnsupportedCalls,proto3" json:"check_unsupported_calls,omitempty"`
+	// CollectOutputs is set if the Runner needs to collect the outputs of the calls.
+	CollectOutputs bool `protobuf:"varint,2,opt,name=collect_outputs,json=collectOutputs,proto3" json:"collect_outputs,omitempty"`
+	// StateProbes are the files and directories the Runner needs to snapshot
+	// before and after each program.
+	StateProbes []string `protobuf:"bytes,3,rep,name=state_probes,json=stateProbes,proto3" json:"state_probes,omitempty"`
+	// HeartbeatPeriodMs is how often the Runner must call RunnerHeartbeat, 0 if it must not.
+	HeartbeatPeriodMs int64 `protobuf:"varint,4,opt,name=heartbeat_period_ms,json=heartbeatPeriodMs,proto3" json:"heartbeat_period_ms,omitempty"`
+}
+
+func (x *ConnectResponse) Reset() {
+	*x = ConnectResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ConnectResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConnectResponse) ProtoMessage() {}
+
+func (x *ConnectResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConnectResponse.ProtoReflect.Descriptor instead.
+func (*ConnectResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_rpctype_runnerpb_runner_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ConnectResponse) GetCheckUnsupportedCalls() bool {
+	if x != nil {
+		return x.CheckUnsupportedCalls
+	}
+	return false
+}
+
+func (x *ConnectResponse) GetCollectOutputs() bool {
+	if x != nil {
+		return x.CollectOutputs
+	}
+	return false
+}
+
+func (x *ConnectResponse) GetStateProbes() []string {
+	if x != nil {
+		return x.StateProbes
+	}
+	return nil
+}
+
+func (x *ConnectResponse) GetHeartbeatPeriodMs() int64 {
+	if x != nil {
+		return x.HeartbeatPeriodMs
+	}
+	return 0
+}
+
+type SyscallReason struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id     int32  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Reason string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (x *SyscallReason) Reset() {
+	*x = SyscallReason{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SyscallReason) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyscallReason) ProtoMessage() {}
+
+func (x *SyscallReason) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyscallReason.ProtoReflect.Descriptor instead.
+func (*SyscallReason) Descriptor() ([]byte, []int) {
+	return file_pkg_rpctype_runnerpb_runner_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SyscallReason) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *SyscallReason) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type UpdateUnsupportedRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Pool             int32            `protobuf:"varint,1,opt,name=pool,proto3" json:"pool,omitempty"`
+	UnsupportedCalls []*SyscallReason `protobuf:"bytes,2,rep,name=unsupported_calls,json=unsupportedCalls,proto3" json:"unsupported_calls,omitempty"`
+}
+
+func (x *UpdateUnsupportedRequest) Reset() {
+	*x = UpdateUnsupportedRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UpdateUnsupportedRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateUnsupportedRequest) ProtoMessage() {}
+
+func (x *UpdateUnsupportedRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateUnsupportedRequest.ProtoReflect.Descriptor instead.
+func (*UpdateUnsupportedRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_rpctype_runnerpb_runner_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *UpdateUnsupportedRequest) GetPool() int32 {
+	if x != nil {
+		return x.Pool
+	}
+	return 0
+}
+
+func (x *UpdateUnsupportedRequest) GetUnsupportedCalls() []*SyscallReason {
+	if x != nil {
+		return x.UnsupportedCalls
+	}
+	return nil
+}
+
+type UpdateUnsupportedResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *UpdateUnsupportedResponse) Reset() {
+	*x = UpdateUnsupportedResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UpdateUnsupportedResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateUnsupportedResponse) ProtoMessage() {}
+
+func (x *UpdateUnsupportedResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateUnsupportedResponse.ProtoReflect.Descriptor instead.
+func (*UpdateUnsupportedResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_rpctype_runnerpb_runner_proto_rawDescGZIP(), []int{4}
+}
+
+// ExecTask is a program the Runner needs to execute.
+type ExecTask struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Prog []byte `protobuf:"bytes,1,opt,name=prog,proto3" json:"prog,omitempty"`
+	Id   int64  `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	// Reboot is set if the Runner must not execute anything, because the VM is being rebooted.
+	Reboot bool `protobuf:"varint,3,opt,name=reboot,proto3" json:"reboot,omitempty"`
+	// Trace is set if the Runner must execute the program under strace and return the trace.
+	Trace bool `protobuf:"varint,4,opt,name=trace,proto3" json:"trace,omitempty"`
+	// Sandbox is the executor sandbox (none, setuid, namespace, android) the program must be
+	// executed in, the default sandbox of the Runner is used if it is empty.
+	Sandbox string `protobuf:"bytes,5,opt,name=sandbox,proto3" json:"sandbox,omitempty"`
+}
+
+func (x *ExecTask) Reset() {
+	*x = ExecTask{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ExecTask) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecTask) ProtoMessage() {}
+
+func (x *ExecTask) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecTask.ProtoReflect.Descriptor instead.
+func (*ExecTask) Descriptor() ([]byte, []int) {
+	return file_pkg_rpctype_runnerpb_runner_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ExecTask) GetProg() []byte {
+	if x != nil {
+		return x.Prog
+	}
+	return nil
+}
+
+func (x *ExecTask) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *ExecTask) GetReboot() bool {
+	if x != nil {
+		return x.Reboot
+	}
+	return false
+}
+
+func (x *ExecTask) GetTrace() bool {
+	if x != nil {
+		return x.Trace
+	}
+	return false
+}
+
+func (x *ExecTask) GetSandbox() string {
+	if x != nil {
+		return x.Sandbox
+	}
+	return ""
+}
+
+// ExecResult is the result of the execution of the task on the instance.
+type ExecResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Pool   int32 `protobuf:"varint,1,opt,name=pool,proto3" json:"pool,omitempty"`
+	Vm     int32 `protobuf:"varint,2,opt,name=vm,proto3" json:"vm,omitempty"`
+	TaskId int64 `protobuf:"varint,3,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	// Hanged is set if the program was killed due to hanging.
+	Hanged bool      `protobuf:"varint,4,opt,name=hanged,proto3" json:"hanged,omitempty"`
+	Info   *ProgInfo `protobuf:"bytes,5,opt,name=info,proto3" json:"info,omitempty"`
+	// Trace is the strace output of the program if the task asked for it.
+	Trace []byte `protobuf:"bytes,6,opt,name=trace,proto3" json:"trace,omitempty"`
+}
+
+func (x *ExecResult) Reset() {
+	*x = ExecResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ExecResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecResult) ProtoMessage() {}
+
+func (x *ExecResult) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecResult.ProtoReflect.Descriptor instead.
+func (*ExecResult) Descriptor() ([]byte, []int) {
+	return file_pkg_rpctype_runnerpb_runner_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ExecResult) GetPool() int32 {
+	if x != nil {
+		return x.Pool
+	}
+	return 0
+}
+
+func (x *ExecResult) GetVm() int32 {
+	if x != nil {
+		return x.Vm
+	}
+	return 0
+}
+
+func (x *ExecResult) GetTaskId() int64 {
+	if x != nil {
+		return x.TaskId
+	}
+	return 0
+}
+
+func (x *ExecResult) GetHanged() bool {
+	if x != nil {
+		return x.Hanged
+	}
+	return false
+}
+
+func (x *ExecResult) GetInfo() *ProgInfo {
+	if x != nil {
+		return x.Info
+	}
+	return nil
+}
+
+func (x *ExecResult) GetTrace() []byte {
+	if x != nil {
+		return x.Trace
+	}
+	return nil
+}
+
+// ProgInfo mirrors ipc.ProgInfo.
+type ProgInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Calls       []*CallInfo `protobuf:"bytes,1,rep,name=calls,proto3" json:"calls,omitempty"`
+	Extra       *CallInfo   `protobuf:"bytes,2,opt,name=extra,proto3" json:"extra,omitempty"`
+	StateBefore []byte      `protobuf:"bytes,3,opt,name=state_before,json=stateBefore,proto3" json:"state_before,omitempty"`
+	StateAfter  []byte      `protobuf:"bytes,4,opt,name=state_after,json=stateAfter,proto3" json:"state_after,omitempty"`
+}
+
+func (x *ProgInfo) Reset() {
+	*x = ProgInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProgInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProgInfo) ProtoMessage() {}
+
+func (x *ProgInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProgInfo.ProtoReflect.Descriptor instead.
+func (*ProgInfo) Descriptor() ([]byte, []int) {
+	return file_pkg_rpctype_runnerpb_runner_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ProgInfo) GetCalls() []*CallInfo {
+	if x != nil {
+		return x.Calls
+	}
+	return nil
+}
+
+func (x *ProgInfo) GetExtra() *CallInfo {
+	if x != nil {
+		return x.Extra
+	}
+	return nil
+}
+
+func (x *ProgInfo) GetStateBefore() []byte {
+	if x != nil {
+		return x.StateBefore
+	}
+	return nil
+}
+
+func (x *ProgInfo) GetStateAfter() []byte {
+	if x != nil {
+		return x.StateAfter
+	}
+	return nil
+}
+
+// CallInfo mirrors ipc.CallInfo.
+type CallInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Flags   uint32        `protobuf:"varint,1,opt,name=flags,proto3" json:"flags,omitempty"`
+	Signal  []uint32      `protobuf:"varint,2,rep,packed,name=signal,proto3" json:"signal,omitempty"`
+	Cover   []uint32      `protobuf:"varint,3,rep,packed,name=cover,proto3" json:"cover,omitempty"`
+	Comps   []*Comparison `protobuf:"bytes,4,rep,name=comps,proto3" json:"comps,omitempty"`
+	Errno   int32         `protobuf:"varint,5,opt,name=errno,proto3" json:"errno,omitempty"`
+	Outputs []uint64      `protobuf:"varint,6,rep,packed,name=outputs,proto3" json:"outputs,omitempty"`
+}
+
+func (x *CallInfo) Reset() {
+	*x = CallInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CallInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallInfo) ProtoMessage() {}
+
+func (x *CallInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallInfo.ProtoReflect.Descriptor instead.
+func (*CallInfo) Descriptor() ([]byte, []int) {
+	return file_pkg_rpctype_runnerpb_runner_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CallInfo) GetFlags() uint32 {
+	if x != nil {
+		return x.Flags
+	}
+	return 0
+}
+
+func (x *CallInfo) GetSignal() []uint32 {
+	if x != nil {
+		return x.Signal
+	}
+	return nil
+}
+
+func (x *CallInfo) GetCover() []uint32 {
+	if x != nil {
+		return x.Cover
+	}
+	return nil
+}
+
+func (x *CallInfo) GetComps() []*Comparison {
+	if x != nil {
+		return x.Comps
+	}
+	return nil
+}
+
+func (x *CallInfo) GetErrno() int32 {
+	if x != nil {
+		return x.Errno
+	}
+	return 0
+}
+
+func (x *CallInfo) GetOutputs() []uint64 {
+	if x != nil {
+		return x.Outputs
+	}
+	return nil
+}
+
+// Comparison contains the operands compared with the first operand.
+type Comparison struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Op1 uint64   `protobuf:"varint,1,opt,name=op1,proto3" json:"op1,omitempty"`
+	Op2 []uint64 `protobuf:"varint,2,rep,packed,name=op2,proto3" json:"op2,omitempty"`
+}
+
+func (x *Comparison) Reset() {
+	*x = Comparison{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Comparison) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Comparison) ProtoMessage() {}
+
+func (x *Comparison) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Comparison.ProtoReflect.Descriptor instead.
+func (*Comparison) Descriptor() ([]byte, []int) {
+	return file_pkg_rpctype_runnerpb_runner_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Comparison) GetOp1() uint64 {
+	if x != nil {
+		return x.Op1
+	}
+	return 0
+}
+
+func (x *Comparison) GetOp2() []uint64 {
+	if x != nil {
+		return x.Op2
+	}
+	return nil
+}
+
+type RegisterHostRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Name identifies the host, a host that registers again with the same name
+	// (e.g. after a network outage) gets the same VM indices.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Pool int32  `protobuf:"varint,2,opt,name=pool,proto3" json:"pool,omitempty"`
+	// Count is the number of VMs the host runs.
+	Count int32 `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+}
+
+func (x *RegisterHostRequest) Reset() {
+	*x = RegisterHostRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RegisterHostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterHostRequest) ProtoMessage() {}
+
+func (x *RegisterHostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterHostRequest.ProtoReflect.Descriptor instead.
+func (*RegisterHostRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_rpctype_runnerpb_runner_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RegisterHostRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RegisterHostRequest) GetPool() int32 {
+	if x != nil {
+		return x.Pool
+	}
+	return 0
+}
+
+func (x *RegisterHostRequest) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type RegisterHostResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Vms are the indices the Runners in the VMs of the host must use in ConnectRequest and ExecResult.
+	Vms []int32 `protobuf:"varint,1,rep,packed,name=vms,proto3" json:"vms,omitempty"`
+	// HeartbeatPeriodMs is how often the host must call Heartbeat.
+	HeartbeatPeriodMs int64 `protobuf:"varint,2,opt,name=heartbeat_period_ms,json=heartbeatPeriodMs,proto3" json:"heartbeat_period_ms,omitempty"`
+}
+
+func (x *RegisterHostResponse) Reset() {
+	*x = RegisterHostResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RegisterHostResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterHostResponse) ProtoMessage() {}
+
+func (x *RegisterHostResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterHostResponse.ProtoReflect.Descriptor instead.
+func (*RegisterHostResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_rpctype_runnerpb_runner_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *RegisterHostResponse) GetVms() []int32 {
+	if x != nil {
+		return x.Vms
+	}
+	return nil
+}
+
+func (x *RegisterHostResponse) GetHeartbeatPeriodMs() int64 {
+	if x != nil {
+		return x.HeartbeatPeriodMs
+	}
+	return 0
+}
+
+type HeartbeatRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (x *HeartbeatRequest) Reset() {
+	*x = HeartbeatRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HeartbeatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatRequest) ProtoMessage() {}
+
+func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatRequest.ProtoReflect.Descriptor instead.
+func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_rpctype_runnerpb_runner_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *HeartbeatRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type HeartbeatResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Reregister is set if the verifier does not know the host (e.g. the verifier was restarted
+	// or the host missed heartbeats), the host must call RegisterHost again.
+	Reregister bool `protobuf:"varint,1,opt,name=reregister,proto3" json:"reregister,omitempty"`
+	// Reboot are the VMs the host must restart, because clean VMs are needed for some tasks.
+	Reboot []int32 `protobuf:"varint,2,rep,packed,name=reboot,proto3" json:"reboot,omitempty"`
+}
+
+func (x *HeartbeatResponse) Reset() {
+	*x = HeartbeatResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HeartbeatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatResponse) ProtoMessage() {}
+
+func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
+func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_rpctype_runnerpb_runner_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *HeartbeatResponse) GetReregister() bool {
+	if x != nil {
+		return x.Reregister
+	}
+	return false
+}
+
+func (x *HeartbeatResponse) GetReboot() []int32 {
+	if x != nil {
+		return x.Reboot
+	}
+	return nil
+}
+
+type RunnerHeartbeatRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Pool int32 `protobuf:"varint,1,opt,name=pool,proto3" json:"pool,omitempty"`
+	Vm   int32 `protobuf:"varint,2,opt,name=vm,proto3" json:"vm,omitempty"`
+	// BusyMs is for how long the Runner has been executing the current program, 0 if it is
+	// waiting for a task.
+	BusyMs int64 `protobuf:"varint,3,opt,name=busy_ms,json=busyMs,proto3" json:"busy_ms,omitempty"`
+}
+
+func (x *RunnerHeartbeatRequest) Reset() {
+	*x = RunnerHeartbeatRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RunnerHeartbeatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunnerHeartbeatRequest) ProtoMessage() {}
+
+func (x *RunnerHeartbeatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunnerHeartbeatRequest.ProtoReflect.Descriptor instead.
+func (*RunnerHeartbeatRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_rpctype_runnerpb_runner_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *RunnerHeartbeatRequest) GetPool() int32 {
+	if x != nil {
+		return x.Pool
+	}
+	return 0
+}
+
+func (x *RunnerHeartbeatRequest) GetVm() int32 {
+	if x != nil {
+		return x.Vm
+	}
+	return 0
+}
+
+func (x *RunnerHeartbeatRequest) GetBusyMs() int64 {
+	if x != nil {
+		return x.BusyMs
+	}
+	return 0
+}
+
+type RunnerHeartbeatResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *RunnerHeartbeatResponse) Reset() {
+	*x = RunnerHeartbeatResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RunnerHeartbeatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunnerHeartbeatResponse) ProtoMessage() {}
+
+func (x *RunnerHeartbeatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_rpctype_runnerpb_runner_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunnerHeartbeatResponse.ProtoReflect.Descriptor instead.
+func (*RunnerHeartbeatResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_rpctype_runnerpb_runner_proto_rawDescGZIP(), []int{15}
+}
+
+var File_pkg_rpctype_runnerpb_runner_proto protoreflect.FileDescriptor
+
+var file_pkg_rpctype_runnerpb_runner_proto_rawDesc = []byte{
+	0x0a, 0x21, 0x70, 0x6b, 0x67, 0x2f, 0x72, 0x70, 0x63, 0x74, 0x79, 0x70, 0x65, 0x2f, 0x72, 0x75,
+	0x6e, 0x6e, 0x65, 0x72, 0x70, 0x62, 0x2f, 0x72, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x12, 0x06, 0x72, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x22, 0x34, 0x0a, 0x0e, 0x43,
+	0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a,
+	0x04, 0x70, 0x6f, 0x6f, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x6f, 0x6f,
+	0x6c, 0x12, 0x0e, 0x0a, 0x02, 0x76, 0x6d, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x02, 0x76,
+	0x6d, 0x22, 0xc5, 0x01, 0x0a, 0x0f, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x36, 0x0a, 0x17, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x5f, 0x75,
+	0x6e, 0x73, 0x75, 0x70, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x64, 0x5f, 0x63, 0x61, 0x6c, 0x6c, 0x73,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x15, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x55, 0x6e, 0x73,
+	0x75, 0x70, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x64, 0x43, 0x61, 0x6c, 0x6c, 0x73, 0x12, 0x27, 0x0a,
+	0x0f, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x5f, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x73,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0e, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x4f,
+	0x75, 0x74, 0x70, 0x75, 0x74, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x73, 0x74, 0x61, 0x74, 0x65, 0x5f,
+	0x70, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0b, 0x73, 0x74,
+	0x61, 0x74, 0x65, 0x50, 0x72, 0x6f, 0x62, 0x65, 0x73, 0x12, 0x2e, 0x0a, 0x13, 0x68, 0x65, 0x61,
+	0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x5f, 0x70, 0x65, 0x72, 0x69, 0x6f, 0x64, 0x5f, 0x6d, 0x73,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x11, 0x68, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61,
+	0x74, 0x50, 0x65, 0x72, 0x69, 0x6f, 0x64, 0x4d, 0x73, 0x22, 0x37, 0x0a, 0x0d, 0x53, 0x79, 0x73,
+	0x63, 0x61, 0x6c, 0x6c, 0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x02, 0x69, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65,
+	0x61, 0x73, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73,
+	0x6f, 0x6e, 0x22, 0x72, 0x0a, 0x18, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x55, 0x6e, 0x73, 0x75,
+	0x70, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12,
+	0x0a, 0x04, 0x70, 0x6f, 0x6f, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x6f,
+	0x6f, 0x6c, 0x12, 0x42, 0x0a, 0x11, 0x75, 0x6e, 0x73, 0x75, 0x70, 0x70, 0x6f, 0x72, 0x74, 0x65,
+	0x64, 0x5f, 0x63, 0x61, 0x6c, 0x6c, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e,
+	0x72, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x2e, 0x53, 0x79, 0x73, 0x63, 0x61, 0x6c, 0x6c, 0x52, 0x65,
+	0x61, 0x73, 0x6f, 0x6e, 0x52, 0x10, 0x75, 0x6e, 0x73, 0x75, 0x70, 0x70, 0x6f, 0x72, 0x74, 0x65,
+	0x64, 0x43, 0x61, 0x6c, 0x6c, 0x73, 0x22, 0x1b, 0x0a, 0x19, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65,
+	0x55, 0x6e, 0x73, 0x75, 0x70, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x22, 0x76, 0x0a, 0x08, 0x45, 0x78, 0x65, 0x63, 0x54, 0x61, 0x73, 0x6b, 0x12,
+	0x12, 0x0a, 0x04, 0x70, 0x72, 0x6f, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x70,
+	0x72, 0x6f, 0x67, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x02, 0x69, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x62, 0x6f, 0x6f, 0x74, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x06, 0x72, 0x65, 0x62, 0x6f, 0x6f, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x74,
+	0x72, 0x61, 0x63, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x74, 0x72, 0x61, 0x63,
+	0x65, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x61, 0x6e, 0x64, 0x62, 0x6f, 0x78, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x73, 0x61, 0x6e, 0x64, 0x62, 0x6f, 0x78, 0x22, 0x9d, 0x01, 0x0a, 0x0a,
+	0x45, 0x78, 0x65, 0x63, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x6f,
+	0x6f, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x6f, 0x6f, 0x6c, 0x12, 0x0e,
+	0x0a, 0x02, 0x76, 0x6d, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x02, 0x76, 0x6d, 0x12, 0x17,
+	0x0a, 0x07, 0x74, 0x61, 0x73, 0x6b, 0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x06, 0x74, 0x61, 0x73, 0x6b, 0x49, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x61, 0x6e, 0x67, 0x65,
+	0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x64, 0x12,
+	0x24, 0x0a, 0x04, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e,
+	0x72, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x2e, 0x50, 0x72, 0x6f, 0x67, 0x49, 0x6e, 0x66, 0x6f, 0x52,
+	0x04, 0x69, 0x6e, 0x66, 0x6f, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x72, 0x61, 0x63, 0x65, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x74, 0x72, 0x61, 0x63, 0x65, 0x22, 0x9e, 0x01, 0x0a, 0x08,
+	0x50, 0x72, 0x6f, 0x67, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x26, 0x0a, 0x05, 0x63, 0x61, 0x6c, 0x6c,
+	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x72, 0x75, 0x6e, 0x6e, 0x65, 0x72,
+	0x2e, 0x43, 0x61, 0x6c, 0x6c, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x05, 0x63, 0x61, 0x6c, 0x6c, 0x73,
+	0x12, 0x26, 0x0a, 0x05, 0x65, 0x78, 0x74, 0x72, 0x61, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x10, 0x2e, 0x72, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x2e, 0x43, 0x61, 0x6c, 0x6c, 0x49, 0x6e, 0x66,
+	0x6f, 0x52, 0x05, 0x65, 0x78, 0x74, 0x72, 0x61, 0x12, 0x21, 0x0a, 0x0c, 0x73, 0x74, 0x61, 0x74,
+	0x65, 0x5f, 0x62, 0x65, 0x66, 0x6f, 0x72, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b,
+	0x73, 0x74, 0x61, 0x74, 0x65, 0x42, 0x65, 0x66, 0x6f, 0x72, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x73,
+	0x74, 0x61, 0x74, 0x65, 0x5f, 0x61, 0x66, 0x74, 0x65, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x0a, 0x73, 0x74, 0x61, 0x74, 0x65, 0x41, 0x66, 0x74, 0x65, 0x72, 0x22, 0xa8, 0x01, 0x0a,
+	0x08, 0x43, 0x61, 0x6c, 0x6c, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x6c, 0x61,
+	0x67, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x66, 0x6c, 0x61, 0x67, 0x73, 0x12,
+	0x16, 0x0a, 0x06, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x6c, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0d, 0x52,
+	0x06, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x6c, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x76, 0x65, 0x72,
+	0x18, 0x03, 0x20, 0x03, 0x28, 0x0d, 0x52, 0x05, 0x63, 0x6f, 0x76, 0x65, 0x72, 0x12, 0x28, 0x0a,
+	0x05, 0x63, 0x6f, 0x6d, 0x70, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x72,
+	0x75, 0x6e, 0x6e, 0x65, 0x72, 0x2e, 0x43, 0x6f, 0x6d, 0x70, 0x61, 0x72, 0x69, 0x73, 0x6f, 0x6e,
+	0x52, 0x05, 0x63, 0x6f, 0x6d, 0x70, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6e, 0x6f,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6e, 0x6f, 0x12, 0x18, 0x0a,
+	0x07, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x04, 0x52, 0x07,
+	0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x73, 0x22, 0x30, 0x0a, 0x0a, 0x43, 0x6f, 0x6d, 0x70, 0x61,
+	0x72, 0x69, 0x73, 0x6f, 0x6e, 0x12, 0x10, 0x0a, 0x03, 0x6f, 0x70, 0x31, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x03, 0x6f, 0x70, 0x31, 0x12, 0x10, 0x0a, 0x03, 0x6f, 0x70, 0x32, 0x18, 0x02,
+	0x20, 0x03, 0x28, 0x04, 0x52, 0x03, 0x6f, 0x70, 0x32, 0x22, 0x53, 0x0a, 0x13, 0x52, 0x65, 0x67,
+	0x69, 0x73, 0x74, 0x65, 0x72, 0x48, 0x6f, 0x73, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x6f, 0x6f, 0x6c, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x04, 0x70, 0x6f, 0x6f, 0x6c, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e,
+	0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x58,
+	0x0a, 0x14, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x48, 0x6f, 0x73, 0x74, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x76, 0x6d, 0x73, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x05, 0x52, 0x03, 0x76, 0x6d, 0x73, 0x12, 0x2e, 0x0a, 0x13, 0x68, 0x65, 0x61, 0x72,
+	0x74, 0x62, 0x65, 0x61, 0x74, 0x5f, 0x70, 0x65, 0x72, 0x69, 0x6f, 0x64, 0x5f, 0x6d, 0x73, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x11, 0x68, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74,
+	0x50, 0x65, 0x72, 0x69, 0x6f, 0x64, 0x4d, 0x73, 0x22, 0x26, 0x0a, 0x10, 0x48, 0x65, 0x61, 0x72,
+	0x74, 0x62, 0x65, 0x61, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04,
+	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65,
+	0x22, 0x4b, 0x0a, 0x11, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x72, 0x65, 0x72, 0x65, 0x67, 0x69, 0x73,
+	0x74, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x72, 0x65, 0x72, 0x65, 0x67,
+	0x69, 0x73, 0x74, 0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x62, 0x6f, 0x6f, 0x74, 0x18,
+	0x02, 0x20, 0x03, 0x28, 0x05, 0x52, 0x06, 0x72, 0x65, 0x62, 0x6f, 0x6f, 0x74, 0x22, 0x55, 0x0a,
+	0x16, 0x52, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x6f, 0x6f, 0x6c, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x6f, 0x6f, 0x6c, 0x12, 0x0e, 0x0a, 0x02, 0x76,
+	0x6d, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x02, 0x76, 0x6d, 0x12, 0x17, 0x0a, 0x07, 0x62,
+	0x75, 0x73, 0x79, 0x5f, 0x6d, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x62, 0x75,
+	0x73, 0x79, 0x4d, 0x73, 0x22, 0x19, 0x0a, 0x17, 0x52, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x48, 0x65,
+	0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x32,
+	0xb7, 0x03, 0x0a, 0x08, 0x56, 0x65, 0x72, 0x69, 0x66, 0x69, 0x65, 0x72, 0x12, 0x3a, 0x0a, 0x07,
+	0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x12, 0x16, 0x2e, 0x72, 0x75, 0x6e, 0x6e, 0x65, 0x72,
+	0x2e, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x17, 0x2e, 0x72, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x2e, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x58, 0x0a, 0x11, 0x55, 0x70, 0x64, 0x61,
+	0x74, 0x65, 0x55, 0x6e, 0x73, 0x75, 0x70, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x64, 0x12, 0x20, 0x2e,
+	0x72, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x55, 0x6e, 0x73,
+	0x75, 0x70, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x21, 0x2e, 0x72, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x55,
+	0x6e, 0x73, 0x75, 0x70, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x34, 0x0a, 0x08, 0x45, 0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x12, 0x12,
+	0x2e, 0x72, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x2e, 0x45, 0x78, 0x65, 0x63, 0x52, 0x65, 0x73, 0x75,
+	0x6c, 0x74, 0x1a, 0x10, 0x2e, 0x72, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x2e, 0x45, 0x78, 0x65, 0x63,
+	0x54, 0x61, 0x73, 0x6b, 0x28, 0x01, 0x30, 0x01, 0x12, 0x49, 0x0a, 0x0c, 0x52, 0x65, 0x67, 0x69,
+	0x73, 0x74, 0x65, 0x72, 0x48, 0x6f, 0x73, 0x74, 0x12, 0x1b, 0x2e, 0x72, 0x75, 0x6e, 0x6e, 0x65,
+	0x72, 0x2e, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x48, 0x6f, 0x73, 0x74, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x72, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x2e, 0x52,
+	0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x48, 0x6f, 0x73, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x40, 0x0a, 0x09, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74,
+	0x12, 0x18, 0x2e, 0x72, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x2e, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62,
+	0x65, 0x61, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x72, 0x75, 0x6e,
+	0x6e, 0x65, 0x72, 0x2e, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x52, 0x0a, 0x0f, 0x52, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x48,
+	0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x12, 0x1e, 0x2e, 0x72, 0x75, 0x6e, 0x6e, 0x65,
+	0x72, 0x2e, 0x52, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x72, 0x75, 0x6e, 0x6e, 0x65,
+	0x72, 0x2e, 0x52, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61,
+	0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x32, 0x5a, 0x30, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x73,
+	0x79, 0x7a, 0x6b, 0x61, 0x6c, 0x6c, 0x65, 0x72, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x72, 0x70, 0x63,
+	0x74, 0x79, 0x70, 0x65, 0x2f, 0x72, 0x75, 0x6e, 0x6e, 0x65, 0x72, 0x70, 0x62, 0x62, 0x06, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_pkg_rpctype_runnerpb_runner_proto_rawDescOnce sync.Once
+	file_pkg_rpctype_runnerpb_runner_proto_rawDescData = file_pkg_rpctype_runnerpb_runner_proto_rawDesc
+)
+
+func file_pkg_rpctype_runnerpb_runner_proto_rawDescGZIP() []byte {
+	file_pkg_rpctype_runnerpb_runner_proto_rawDescOnce.Do(func() {
+		file_pkg_rpctype_runnerpb_runner_proto_rawDescData = protoimpl.X.CompressGZIP(file_pkg_rpctype_runnerpb_runner_proto_rawDescData)
+	})
+	return file_pkg_rpctype_runnerpb_runner_proto_rawDescData
+}
+
+var file_pkg_rpctype_runnerpb_runner_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_pkg_rpctype_runnerpb_runner_proto_goTypes = []interface{}{
+	(*ConnectRequest)(nil),            // 0: runner.ConnectRequest
+	(*ConnectResponse)(nil),           // 1: runner.ConnectResponse
+	(*SyscallReason)(nil),             // 2: runner.SyscallReason
+	(*UpdateUnsupportedRequest)(nil),  // 3: runner.UpdateUnsupportedRequest
+	(*UpdateUnsupportedResponse)(nil), // 4: runner.UpdateUnsupportedResponse
+	(*ExecTask)(nil),                  // 5: runner.ExecTask
+	(*ExecResult)(nil),                // 6: runner.ExecResult
+	(*ProgInfo)(nil),                  // 7: runner.ProgInfo
+	(*CallInfo)(nil),                  // 8: runner.CallInfo
+	(*Comparison)(nil),                // 9: runner.Comparison
+	(*RegisterHostRequest)(nil),       // 10: runner.RegisterHostRequest
+	(*RegisterHostResponse)(nil),      // 11: runner.RegisterHostResponse
+	(*HeartbeatRequest)(nil),          // 12: runner.HeartbeatRequest
+	(*HeartbeatResponse)(nil),         // 13: runner.HeartbeatResponse
+	(*RunnerHeartbeatRequest)(nil),    // 14: runner.RunnerHeartbeatRequest
+	(*RunnerHeartbeatResponse)(nil),   // 15: runner.RunnerHeartbeatResponse
+}
+var file_pkg_rpctype_runnerpb_runner_proto_depIdxs = []int32{
+	2,  // 0: runner.UpdateUnsupportedRequest.unsupported_calls:type_name -> runner.SyscallReason
+	7,  // 1: runner.ExecResult.info:type_name -> runner.ProgInfo
+	8,  // 2: runner.ProgInfo.calls:type_name -> runner.CallInfo
+	8,  // 3: runner.ProgInfo.extra:type_name -> runner.CallInfo
+	9,  // 4: runner.CallInfo.comps:type_name -> runner.Comparison
+	0,  // 5: runner.Verifier.Connect:input_type -> runner.ConnectRequest
+	3,  // 6: runner.Verifier.UpdateUnsupported:input_type -> runner.UpdateUnsupportedRequest
+	6,  // 7: runner.Verifier.Exchange:input_type -> runner.ExecResult
+	10, // 8: runner.Verifier.RegisterHost:input_type -> runner.RegisterHostRequest
+	12, // 9: runner.Verifier.Heartbeat:input_type -> runner.HeartbeatRequest
+	14, // 10: runner.Verifier.RunnerHeartbeat:input_type -> runner.RunnerHeartbeatRequest
+	1,  // 11: runner.Verifier.Connect:output_type -> runner.ConnectResponse
+	4,  // 12: runner.Verifier.UpdateUnsupported:output_type -> runner.UpdateUnsupportedResponse
+	5,  // 13: runner.Verifier.Exchange:output_type -> runner.ExecTask
+	11, // 14: runner.Verifier.RegisterHost:output_type -> runner.RegisterHostResponse
+	13, // 15: runner.Verifier.Heartbeat:output_type -> runner.HeartbeatResponse
+	15, // 16: runner.Verifier.RunnerHeartbeat:output_type -> runner.RunnerHeartbeatResponse
+	11, // [11:17] is the sub-list for method output_type
+	5,  // [5:11] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_pkg_rpctype_runnerpb_runner_proto_init() }
+func file_pkg_rpctype_runnerpb_runner_proto_init() {
+	if File_pkg_rpctype_runnerpb_runner_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_pkg_rpctype_runnerpb_runner_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ConnectRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_rpctype_runnerpb_runner_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ConnectResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_rpctype_runnerpb_runner_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SyscallReason); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_rpctype_runnerpb_runner_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UpdateUnsupportedRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_rpctype_runnerpb_runner_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UpdateUnsupportedResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_rpctype_runnerpb_runner_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ExecTask); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_rpctype_runnerpb_runner_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ExecResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_rpctype_runnerpb_runner_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProgInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_rpctype_runnerpb_runner_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CallInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_rpctype_runnerpb_runner_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Comparison); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_rpctype_runnerpb_runner_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RegisterHostRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_rpctype_runnerpb_runner_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RegisterHostResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_rpctype_runnerpb_runner_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HeartbeatRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_rpctype_runnerpb_runner_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HeartbeatResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_rpctype_runnerpb_runner_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RunnerHeartbeatRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_rpctype_runnerpb_runner_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RunnerHeartbeatResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_pkg_rpctype_runnerpb_runner_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   16,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_pkg_rpctype_runnerpb_runner_proto_goTypes,
+		DependencyIndexes: file_pkg_rpctype_runnerpb_runner_proto_depIdxs,
+		MessageInfos:      file_pkg_rpctype_runnerpb_runner_proto_msgTypes,
+	}.Build()
+	File_pkg_rpctype_runnerpb_runner_proto = out.File
+	file_pkg_rpctype_runnerpb_runner_proto_rawDesc = nil
+	file_pkg_rpctype_runnerpb_runner_proto_goTypes = nil
+	file_pkg_rpctype_runnerpb_runner_proto_depIdxs = nil
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// VerifierClient is the client API for Verifier service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type VerifierClient interface {
+	// Connect is called when a Runner starts.
+	Connect(ctx context.Context, in *ConnectRequest, opts ...grpc.CallOption) (*ConnectResponse, error)
+	// UpdateUnsupported reports the system calls not supported by the kernel of the pool.
+	UpdateUnsupported(ctx context.Context, in *UpdateUnsupportedRequest, opts ...grpc.CallOption) (*UpdateUnsupportedResponse, error)
+	// Exchange streams the results of the executed programs to the verifier and the next tasks
+	// to the Runner: the Runner sends a result (without info the first time) and receives
+	// the next task in response.
+	Exchange(ctx context.Context, opts ...grpc.CallOption) (Verifier_ExchangeClient, error)
+	// RegisterHost is called by the agents that run the VMs of remote kernels on other machines
+	// (syz-verifier -agent), it assigns the VM indices to the VMs of the host.
+	RegisterHost(ctx context.Context, in *RegisterHostRequest, opts ...grpc.CallOption) (*RegisterHostResponse, error)
+	// Heartbeat is called periodically by the registered hosts. The tasks in progress
+	// on the VMs of a host that misses heartbeats are failed.
+	Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error)
+	// RunnerHeartbeat is called periodically by the Runners if ConnectResponse asks for it.
+	// The VMs of the Runners that miss heartbeats or execute a program for too long are restarted.
+	RunnerHeartbeat(ctx context.Context, in *RunnerHeartbeatRequest, opts ...grpc.CallOption) (*RunnerHeartbeatResponse, error)
+}
+
+type verifierClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewVerifierClient(cc grpc.ClientConnInterface) VerifierClient {
+	return &verifierClient{cc}
+}
+
+func (c *verifierClient) Connect(ctx context.Context, in *ConnectRequest, opts ...grpc.CallOption) (*ConnectResponse, error) {
+	out := new(ConnectResponse)
+	err := c.cc.Invoke(ctx, "/runner.Verifier/Connect", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *verifierClient) UpdateUnsupported(ctx context.Context, in *UpdateUnsupportedRequest, opts ...grpc.CallOption) (*UpdateUnsupportedResponse, error) {
+	out := new(UpdateUnsupportedResponse)
+	err := c.cc.Invoke(ctx, "/runner.Verifier/UpdateUnsupported", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *verifierClient) Exchange(ctx context.Context, opts ...grpc.CallOption) (Verifier_ExchangeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Verifier_serviceDesc.Streams[0], "/runner.Verifier/Exchange", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &verifierExchangeClient{stream}
+	return x, nil
+}
+
+type Verifier_ExchangeClient interface {
+	Send(*ExecResult) error
+	Recv() (*ExecTask, error)
+	grpc.ClientStream
+}
+
+type verifierExchangeClient struct {
+	grpc.ClientStream
+}
+
+func (x *verifierExchangeClient) Send(m *ExecResult) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *verifierExchangeClient) Recv() (*ExecTask, error) {
+	m := new(ExecTask)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *verifierClient) RegisterHost(ctx context.Context, in *RegisterHostRequest, opts ...grpc.CallOption) (*RegisterHostResponse, error) {
+	out := new(RegisterHostResponse)
+	err := c.cc.Invoke(ctx, "/runner.Verifier/RegisterHost", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *verifierClient) Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error) {
+	out := new(HeartbeatResponse)
+	err := c.cc.Invoke(ctx, "/runner.Verifier/Heartbeat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *verifierClient) RunnerHeartbeat(ctx context.Context, in *RunnerHeartbeatRequest, opts ...grpc.CallOption) (*RunnerHeartbeatResponse, error) {
+	out := new(RunnerHeartbeatResponse)
+	err := c.cc.Invoke(ctx, "/runner.Verifier/RunnerHeartbeat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// VerifierServer is the server API for Verifier service.
+type VerifierServer interface {
+	// Connect is called when a Runner starts.
+	Connect(context.Context, *ConnectRequest) (*ConnectResponse, error)
+	// UpdateUnsupported reports the system calls not supported by the kernel of the pool.
+	UpdateUnsupported(context.Context, *UpdateUnsupportedRequest) (*UpdateUnsupportedResponse, error)
+	// Exchange streams the results of the executed programs to the verifier and the next tasks
+	// to the Runner: the Runner sends a result (without info the first time) and receives
+	// the next task in response.
+	Exchange(Verifier_ExchangeServer) error
+	// RegisterHost is called by the agents that run the VMs of remote kernels on other machines
+	// (syz-verifier -agent), it assigns the VM indices to the VMs of the host.
+	RegisterHost(context.Context, *RegisterHostRequest) (*RegisterHostResponse, error)
+	// Heartbeat is called periodically by the registered hosts. The tasks in progress
+	// on the VMs of a host that misses heartbeats are failed.
+	Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error)
+	// RunnerHeartbeat is called periodically by the Runners if ConnectResponse asks for it.
+	// The VMs of the Runners that miss heartbeats or execute a program for too long are restarted.
+	RunnerHeartbeat(context.Context, *RunnerHeartbeatRequest) (*RunnerHeartbeatResponse, error)
+}
+
+// UnimplementedVerifierServer can be embedded to have forward compatible implementations.
+type UnimplementedVerifierServer struct {
+}
+
+func (*UnimplementedVerifierServer) Connect(context.Context, *ConnectRequest) (*ConnectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Connect not implemented")
+}
+func (*UnimplementedVerifierServer) UpdateUnsupported(context.Context, *UpdateUnsupportedRequest) (*UpdateUnsupportedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateUnsupported not implemented")
+}
+func (*UnimplementedVerifierServer) Exchange(Verifier_ExchangeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Exchange not implemented")
+}
+func (*UnimplementedVerifierServer) RegisterHost(context.Context, *RegisterHostRequest) (*RegisterHostResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterHost not implemented")
+}
+func (*UnimplementedVerifierServer) Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
+func (*UnimplementedVerifierServer) RunnerHeartbeat(context.Context, *RunnerHeartbeatRequest) (*RunnerHeartbeatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RunnerHeartbeat not implemented")
+}
+
+func RegisterVerifierServer(s *grpc.Server, srv VerifierServer) {
+	s.RegisterService(&_Verifier_serviceDesc, srv)
+}
+
+func _Verifier_Connect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConnectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VerifierServer).Connect(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/runner.Verifier/Connect",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VerifierServer).Connect(ctx, req.(*ConnectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Verifier_UpdateUnsupported_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateUnsupportedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VerifierServer).UpdateUnsupported(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/runner.Verifier/UpdateUnsupported",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VerifierServer).UpdateUnsupported(ctx, req.(*UpdateUnsupportedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Verifier_Exchange_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(VerifierServer).Exchange(&verifierExchangeServer{stream})
+}
+
+type Verifier_ExchangeServer interface {
+	Send(*ExecTask) error
+	Recv() (*ExecResult, error)
+	grpc.ServerStream
+}
+
+type verifierExchangeServer struct {
+	grpc.ServerStream
+}
+
+func (x *verifierExchangeServer) Send(m *ExecTask) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *verifierExchangeServer) Recv() (*ExecResult, error) {
+	m := new(ExecResult)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Verifier_RegisterHost_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterHostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VerifierServer).RegisterHost(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/runner.Verifier/RegisterHost",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VerifierServer).RegisterHost(ctx, req.(*RegisterHostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Verifier_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VerifierServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/runner.Verifier/Heartbeat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VerifierServer).Heartbeat(ctx, req.(*HeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Verifier_RunnerHeartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunnerHeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VerifierServer).RunnerHeartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/runner.Verifier/RunnerHeartbeat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VerifierServer).RunnerHeartbeat(ctx, req.(*RunnerHeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Verifier_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "runner.Verifier",
+	HandlerType: (*VerifierServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Connect",
+			Handler:    _Verifier_Connect_Handler,
+		},
+		{
+			MethodName: "UpdateUnsupported",
+			Handler:    _Verifier_UpdateUnsupported_Handler,
+		},
+		{
+			MethodName: "RegisterHost",
+			Handler:    _Verifier_RegisterHost_Handler,
+		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _Verifier_Heartbeat_Handler,
+		},
+		{
+			MethodName: "RunnerHeartbeat",
+			Handler:    _Verifier_RunnerHeartbeat_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Exchange",
+			Handler:       _Verifier_Exchange_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "pkg/rpctype/runnerpb/runner.proto",
+}
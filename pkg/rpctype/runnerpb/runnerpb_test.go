@@ -0,0 +1,216 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package runnerpb
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/google/syzkaller/pkg/ipc"
+	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/pkg/rpctype"
+	"github.com/google/syzkaller/prog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func testProgInfo() *ipc.ProgInfo {
+	info := &ipc.ProgInfo{
+		Calls: []ipc.CallInfo{{
+			Flags:   ipc.CallExecuted | ipc.CallFinished,
+			Signal:  []uint32{1, 2, 3},
+			Cover:   []uint32{1 << 31},
+			Comps:   prog.CompMap{1: {2: true, 1 << 63: true}, 3: {4: true}},
+			Outputs: []uint64{0, 42},
+		}, {
+			Flags: ipc.CallExecuted,
+			Errno: 22,
+		}},
+		StateBefore: []byte("== /proc/self/fd\n"),
+		StateAfter:  []byte("== /proc/self/fd\n3 -> pipe:[1]\n"),
+	}
+	info.Extra.Signal = []uint32{5}
+	return info
+}
+
+func TestConvertProgInfo(t *testing.T) {
+	info := testProgInfo()
+	if got := FromProgInfo(info).ToProgInfo(); !reflect.DeepEqual(*info, got) {
+		t.Fatalf("converted info differs:\n%#v\n%#v", *info, got)
+	}
+}
+
+type testServer struct {
+	UnimplementedVerifierServer
+}
+
+func (*testServer) Connect(ctx context.Context, req *ConnectRequest) (*ConnectResponse, error) {
+	if req.Pool < 0 {
+		return nil, fmt.Errorf("bad pool %v", req.Pool)
+	}
+	return &ConnectResponse{CollectOutputs: true, StateProbes: []string{fmt.Sprintf("/pool%v", req.Pool)}}, nil
+}
+
+func (*testServer) Exchange(stream Verifier_ExchangeServer) error {
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// Echo the number of calls and the errno of the last call to check that the info is received.
+		prog := fmt.Sprint(len(res.GetInfo().GetCalls()))
+		if calls := res.GetInfo().ToProgInfo().Calls; len(calls) != 0 {
+			prog += fmt.Sprintf(" %v", calls[len(calls)-1].Errno)
+		}
+		if err := stream.Send(&ExecTask{Prog: []byte(prog), Id: res.TaskId + 1}); err != nil {
+			return err
+		}
+	}
+}
+
+func testService(t *testing.T, useTLS bool, token string) {
+	var serverCfg, clientCfg *tls.Config
+	if useTLS {
+		serverFiles, clientFiles, err := rpctype.GenerateTLS(filepath.Join(t.TempDir(), "tls"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if serverCfg, err = serverFiles.ServerConfig(); err != nil {
+			t.Fatal(err)
+		}
+		if clientCfg, err = clientFiles.ClientConfig(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var serverOpts []grpc.ServerOption
+	if token != "" {
+		serverOpts = rpctype.TokenServerOptions(token)
+	}
+	s, ln, err := rpctype.NewGRPCServer("127.0.0.1:0", serverCfg, serverOpts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterVerifierServer(s, new(testServer))
+	go s.Serve(ln)
+	defer s.Stop()
+
+	ctx := context.Background()
+	if token != "" {
+		// Calls without the token or with a wrong token are rejected.
+		for _, opts := range [][]grpc.DialOption{nil, {rpctype.TokenDialOption(token + "x")}} {
+			conn, err := rpctype.DialGRPC(ln.Addr().String(), 1, clientCfg, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cli := NewVerifierClient(conn)
+			_, err = cli.Connect(ctx, &ConnectRequest{Pool: 2})
+			if status.Code(err) != codes.Unauthenticated {
+				t.Errorf("got error %v, want Unauthenticated", err)
+			}
+			stream, err := cli.Exchange(ctx)
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			if status.Code(err) != codes.Unauthenticated {
+				t.Errorf("got stream error %v, want Unauthenticated", err)
+			}
+			conn.Close()
+		}
+	}
+
+	var dialOpts []grpc.DialOption
+	if token != "" {
+		dialOpts = append(dialOpts, rpctype.TokenDialOption(token))
+	}
+	conn, err := rpctype.DialGRPC(ln.Addr().String(), 1, clientCfg, dialOpts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	cli := NewVerifierClient(conn)
+
+	r, err := cli.Connect(ctx, &ConnectRequest{Pool: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.CollectOutputs || len(r.StateProbes) != 1 || r.StateProbes[0] != "/pool2" {
+		t.Fatalf("bad connect response: %v", r)
+	}
+	if _, err := cli.Connect(ctx, &ConnectRequest{Pool: -1}); err == nil {
+		t.Fatalf("the error is not returned")
+	}
+	if _, err := cli.UpdateUnsupported(ctx, &UpdateUnsupportedRequest{}); err == nil {
+		t.Fatalf("unimplemented method succeeded")
+	}
+
+	stream, err := cli.Exchange(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		res := &ExecResult{TaskId: int64(i)}
+		want := "0"
+		if i != 0 {
+			res.Info = FromProgInfo(testProgInfo())
+			want = "2 22"
+		}
+		if err := stream.Send(res); err != nil {
+			t.Fatal(err)
+		}
+		task, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(task.Prog) != want || task.Id != int64(i+1) {
+			t.Fatalf("got task %q/%v, want %q/%v", task.Prog, task.Id, want, i+1)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Fatalf("the stream is not closed: %v", err)
+	}
+}
+
+func TestService(t *testing.T) {
+	testService(t, false, "")
+}
+
+func TestServiceTLS(t *testing.T) {
+	testService(t, true, "")
+}
+
+func TestServiceToken(t *testing.T) {
+	testService(t, false, "secret")
+	testService(t, true, "secret")
+}
+
+func TestReadTokenFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "token")
+	for _, test := range []struct {
+		data  string
+		token string
+	}{
+		{"secret\n", "secret"},
+		{" \n", ""},
+	} {
+		if err := osutil.WriteFile(file, []byte(test.data)); err != nil {
+			t.Fatal(err)
+		}
+		token, err := rpctype.ReadTokenFile(file)
+		if token != test.token || (err == nil) != (test.token != "") {
+			t.Errorf("%q: got token %q, error %v", test.data, token, err)
+		}
+	}
+}
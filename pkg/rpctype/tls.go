@@ -0,0 +1,181 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package rpctype
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"time"
+
+	"github.com/google/syzkaller/pkg/osutil"
+)
+
+// TLSFiles are paths to PEM-encoded files used for mutual TLS authentication of RPC connections:
+// the CA certificate that must have signed the peer certificate, and own certificate and key.
+type TLSFiles struct {
+	CA   string
+	Cert string
+	Key  string
+}
+
+// GenerateTLS generates a new CA and server/client certificates signed by it in dir.
+// The certificates are not bound to any host names/addresses (VMs connect to the manager
+// via various forwarded addresses), peers are authenticated only by the CA signature.
+func GenerateTLS(dir string) (server, client *TLSFiles, err error) {
+	if err := osutil.MkdirAll(dir); err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "syzkaller rpc ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, nil, err
+	}
+	caFile := filepath.Join(dir, "ca.crt")
+	if err := writePEM(caFile, "CERTIFICATE", caDER); err != nil {
+		return nil, nil, err
+	}
+	generate := func(serial int64, name string, usage x509.ExtKeyUsage) (*TLSFiles, error) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "syzkaller rpc " + name},
+			NotBefore:    caTemplate.NotBefore,
+			NotAfter:     caTemplate.NotAfter,
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		if err != nil {
+			return nil, err
+		}
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		files := &TLSFiles{
+			CA:   caFile,
+			Cert: filepath.Join(dir, name+".crt"),
+			Key:  filepath.Join(dir, name+".key"),
+		}
+		if err := writePEM(files.Cert, "CERTIFICATE", der); err != nil {
+			return nil, err
+		}
+		if err := writePEM(files.Key, "EC PRIVATE KEY", keyDER); err != nil {
+			return nil, err
+		}
+		return files, nil
+	}
+	if server, err = generate(2, "server", x509.ExtKeyUsageServerAuth); err != nil {
+		return nil, nil, err
+	}
+	if client, err = generate(3, "client", x509.ExtKeyUsageClientAuth); err != nil {
+		return nil, nil, err
+	}
+	return server, client, nil
+}
+
+func writePEM(file, typ string, data []byte) error {
+	// Keys must not be readable by other users, so use restrictive permissions for all files.
+	return ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: data}), 0600)
+}
+
+func (files *TLSFiles) load() (tls.Certificate, *x509.CertPool, error) {
+	cert, err := tls.LoadX509KeyPair(files.Cert, files.Key)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("failed to load tls certificate: %v", err)
+	}
+	caData, err := ioutil.ReadFile(files.CA)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("failed to read tls ca: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return tls.Certificate{}, nil, fmt.Errorf("no certificates in %v", files.CA)
+	}
+	return cert, pool, nil
+}
+
+// ServerConfig returns TLS config for RPCServer that accepts only clients with certificates signed by the CA.
+func (files *TLSFiles) ServerConfig() (*tls.Config, error) {
+	cert, pool, err := files.load()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS13,
+	}, nil
+}
+
+// ClientConfig returns TLS config for RPCClient that accepts only servers with certificates signed by the CA.
+func (files *TLSFiles) ClientConfig() (*tls.Config, error) {
+	cert, pool, err := files.load()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS13,
+		// The standard verification checks the host name, but the server certificate
+		// is not bound to any address, so we verify only the signature.
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return fmt.Errorf("no server certificate")
+			}
+			leaf, err := x509.ParseCertificate(rawCerts[0])
+			if err != nil {
+				return err
+			}
+			_, err = leaf.Verify(x509.VerifyOptions{
+				Roots:     pool,
+				KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+				// Clock in freshly booted VMs is frequently off, don't check validity period.
+				CurrentTime: leaf.NotBefore.Add(time.Second),
+			})
+			return err
+		},
+	}, nil
+}
+
+// ClientTLSConfig returns client TLS config for the files passed in command line flags
+// of in-VM processes, or nil if TLS is not used.
+func ClientTLSConfig(ca, cert, key string) (*tls.Config, error) {
+	if ca == "" && cert == "" && key == "" {
+		return nil, nil
+	}
+	files := &TLSFiles{CA: ca, Cert: cert, Key: key}
+	return files.ClientConfig()
+}
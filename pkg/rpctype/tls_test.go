@@ -0,0 +1,63 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package rpctype
+
+import (
+	"testing"
+)
+
+func TestTLS(t *testing.T) {
+	server, client, err := GenerateTLS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherClient, err := GenerateTLS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverCfg, err := server.ServerConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewRPCServer("127.0.0.1:0", "Test", new(testServer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetTLS(serverCfg)
+	go s.Serve()
+	addr := s.Addr().String()
+
+	clientCfg, err := client.ClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli, err := NewRPCClientTLS(addr, 1, clientCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.Call("Test.Poll", &PollArgs{Name: "foo"}, new(PollRes)); err != nil {
+		t.Fatal(err)
+	}
+	cli.Close()
+
+	// Clients without TLS or with certificates from another CA must be rejected.
+	otherCfg, err := otherClient.ClientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cli, err := NewRPCClientTLS(addr, 1, otherCfg); err == nil {
+		err = cli.Call("Test.Poll", &PollArgs{Name: "foo"}, new(PollRes))
+		cli.Close()
+		if err == nil {
+			t.Fatalf("client with a certificate from another CA is accepted")
+		}
+	}
+	if cli, err := NewRPCClient(addr, 1); err == nil {
+		err = cli.Call("Test.Poll", &PollArgs{Name: "foo"}, new(PollRes))
+		cli.Close()
+		if err == nil {
+			t.Fatalf("client without TLS is accepted")
+		}
+	}
+}
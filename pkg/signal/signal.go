@@ -4,6 +4,11 @@
 // Package signal provides types for working with feedback signal.
 package signal
 
+import (
+	"encoding/binary"
+	"fmt"
+)
+
 type (
 	elemType uint32
 	prioType int8
@@ -93,6 +98,43 @@ func (ser Serial) Deserialize() Signal {
 	return s
 }
 
+// AppendBinary appends compact binary encoding of ser to buf (used for RPC).
+func (ser Serial) AppendBinary(buf []byte) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	buf = append(buf, tmp[:binary.PutUvarint(tmp[:], uint64(len(ser.Elems)))]...)
+	for _, e := range ser.Elems {
+		buf = append(buf, byte(e), byte(e>>8), byte(e>>16), byte(e>>24))
+	}
+	for _, p := range ser.Prios {
+		buf = append(buf, byte(p))
+	}
+	return buf
+}
+
+// DecodeBinary decodes ser encoded with AppendBinary from the beginning of data
+// and returns the remaining data.
+func (ser *Serial) DecodeBinary(data []byte) ([]byte, error) {
+	n, size := binary.Uvarint(data)
+	if size <= 0 || n > uint64(len(data)-size)/5 {
+		return nil, fmt.Errorf("corrupted Serial")
+	}
+	data = data[size:]
+	*ser = Serial{}
+	if n == 0 {
+		return data, nil
+	}
+	ser.Elems = make([]elemType, n)
+	ser.Prios = make([]prioType, n)
+	for i := range ser.Elems {
+		ser.Elems[i] = elemType(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	data = data[n*4:]
+	for i := range ser.Prios {
+		ser.Prios[i] = prioType(data[i])
+	}
+	return data[n:], nil
+}
+
 func (s Signal) Diff(s1 Signal) Signal {
 	if s1.Empty() {
 		return nil
@@ -318,6 +318,33 @@ func LogLink(url, hash string) string {
 	return link(url, hash, 2)
 }
 
+// RawFileLink returns a link to the raw contents of file at commit hash,
+// or an empty string if the hosting does not serve plain files.
+func RawFileLink(url, hash, file string) string {
+	if url == "" || hash == "" || file == "" {
+		return ""
+	}
+	if strings.HasPrefix(url, "https://github.com/") {
+		url = strings.TrimSuffix(url, ".git")
+		return "https://raw.githubusercontent.com/" + strings.TrimPrefix(url, "https://github.com/") +
+			"/" + hash + "/" + file
+	}
+	if strings.HasPrefix(url, "https://git.kernel.org/pub/scm/") ||
+		strings.HasPrefix(url, "git://git.kernel.org/pub/scm/") {
+		url = "https" + strings.TrimPrefix(strings.TrimPrefix(url, "git"), "https")
+		return url + "/plain/" + file + "?id=" + hash
+	}
+	for _, cgitHost := range []string{"git.kernel.dk", "git.breakpoint.cc"} {
+		if strings.HasPrefix(url, "https://"+cgitHost) ||
+			strings.HasPrefix(url, "git://"+cgitHost) {
+			url = strings.TrimPrefix(strings.TrimPrefix(url, "git://"), "https://")
+			url = strings.TrimPrefix(url, cgitHost)
+			return "https://" + cgitHost + "/cgit" + url + "/plain/" + file + "?id=" + hash
+		}
+	}
+	return ""
+}
+
 func link(url, hash string, typ int) string {
 	if url == "" || hash == "" {
 		return ""
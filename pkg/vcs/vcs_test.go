@@ -192,6 +192,54 @@ func TestCommitLink(t *testing.T) {
 	}
 }
 
+func TestRawFileLink(t *testing.T) {
+	type Test struct {
+		URL  string
+		Hash string
+		File string
+		Link string
+	}
+	tests := []Test{
+		{
+			"https://github.com/google/syzkaller.git",
+			"76dd003f1b",
+			"pkg/vcs/vcs.go",
+			"https://raw.githubusercontent.com/google/syzkaller/76dd003f1b/pkg/vcs/vcs.go",
+		},
+		{
+			"git://git.kernel.org/pub/scm/linux/kernel/git/torvalds/linux.git",
+			"8fe28cb58b",
+			"mm/slab.c",
+			"https://git.kernel.org/pub/scm/linux/kernel/git/torvalds/linux.git/plain/mm/slab.c?id=8fe28cb58b",
+		},
+		{
+			"git://git.kernel.dk/linux-block",
+			"c9387501192c",
+			"block/blk-core.c",
+			"https://git.kernel.dk/cgit/linux-block/plain/block/blk-core.c?id=c9387501192c",
+		},
+		{
+			"https://android.googlesource.com/kernel/common",
+			"d0c3914ffbe4",
+			"mm/slab.c",
+			"",
+		},
+		{
+			"https://github.com/google/syzkaller",
+			"",
+			"pkg/vcs/vcs.go",
+			"",
+		},
+	}
+	for _, test := range tests {
+		link := RawFileLink(test.URL, test.Hash, test.File)
+		if link != test.Link {
+			t.Errorf("URL: %v\nhash: %v\nfile: %v\nwant: %v\ngot:  %v",
+				test.URL, test.Hash, test.File, test.Link, link)
+		}
+	}
+}
+
 func TestParse(t *testing.T) {
 	// nolint: lll
 	test1 := []byte(`Foo (Maintainer) Bar <a@email.com> (maintainer:KERNEL)
@@ -97,3 +97,19 @@ func DoubleExecCollide(origProg *Prog, rand *rand.Rand) (*Prog, error) {
 	prog.Calls = append(prog.Calls, dupCalls...)
 	return prog, nil
 }
+
+// AppendRacingPair appends copies of calls i and j to the end of the program and makes them
+// execute concurrently: the first copy is async and both copies are rerun the given number of times,
+// so that the overlap window of the two calls grows with rerun.
+// The copies use the same resources as the original calls.
+func AppendRacingPair(origProg *Prog, i, j, rerun int) (*Prog, error) {
+	if len(origProg.Calls)+2 > MaxCalls {
+		return nil, fmt.Errorf("the prog is too big for the AppendRacingPair transformation")
+	}
+	prog := origProg.Clone()
+	pair := cloneCalls([]*Call{prog.Calls[i], prog.Calls[j]}, nil)
+	pair[0].Props = CallProps{Async: true, Rerun: rerun}
+	pair[1].Props = CallProps{Rerun: rerun}
+	prog.Calls = append(prog.Calls, pair...)
+	return prog, nil
+}
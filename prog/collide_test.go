@@ -148,3 +148,35 @@ dup(r3)
 		}
 	}
 }
+
+func TestAppendRacingPair(t *testing.T) {
+	target, err := GetTarget("linux", "amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := target.Deserialize([]byte(`r0 = openat(0xffffffffffffff9c, &AUTO='./file1\x00', 0x42, 0x1ff)
+write(r0, &AUTO="01010101", 0x4)
+read(r0, &AUTO=""/4, 0x4)
+close(r0)
+`), Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raced, err := AppendRacingPair(p, 1, 3, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `r0 = openat(0xffffffffffffff9c, &(0x7f0000000040)='./file1\x00', 0x42, 0x1ff)
+write(r0, &(0x7f0000000080)="01010101", 0x4)
+read(r0, &(0x7f00000000c0)=""/4, 0x4)
+close(r0)
+write(r0, &(0x7f0000000080)="01010101", 0x4) (async, rerun: 32)
+close(r0) (rerun: 32)
+`
+	if got := string(raced.Serialize()); got != want {
+		t.Fatalf("expected:\n%s\ngot:\n%s", want, got)
+	}
+	if len(p.Calls) != 4 {
+		t.Fatalf("the original program was modified")
+	}
+}
@@ -256,7 +256,7 @@ func (dec *execDecoder) commitCall() {
 		dec.numVars = dec.call.Index + 1
 	}
 	for _, copyout := range dec.call.Copyout {
-		if dec.numVars < copyout.Index+1 {
+		if copyout.Index != ExecNoCopyout && dec.numVars < copyout.Index+1 {
 			dec.numVars = copyout.Index + 1
 		}
 	}
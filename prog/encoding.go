@@ -1184,7 +1184,7 @@ func (p *parser) Ident() string {
 		(p.s[p.i] >= 'a' && p.s[p.i] <= 'z' ||
 			p.s[p.i] >= 'A' && p.s[p.i] <= 'Z' ||
 			p.s[p.i] >= '0' && p.s[p.i] <= '9' ||
-			p.s[p.i] == '_' || p.s[p.i] == '$') {
+			p.s[p.i] == '_' || p.s[p.i] == '$' || p.s[p.i] == '.') {
 		p.i++
 	}
 	if i == p.i {
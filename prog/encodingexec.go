@@ -16,6 +16,7 @@
 // There are 2 other special calls:
 //  - execInstrCopyin: copies its second argument into address specified by first argument
 //  - execInstrCopyout: reads value at address specified by first argument (result can be referenced by execArgResult)
+//    copyouts with ExecNoCopyout index are output copyouts, their values are only reported to the caller
 
 package prog
 
@@ -56,6 +57,9 @@ const (
 	ExecNoCopyout  = ^uint64(0)
 
 	execMaxCommands = 1000 // executor knows about this constant (kMaxCommands)
+	execMaxOutputs  = 64   // executor knows about this constant (kMaxOutputs)
+	// execMaxOutputData is the maximum number of bytes of an output buffer that are copied out.
+	execMaxOutputData = 64
 )
 
 var ErrExecBufferTooSmall = errors.New("encodingexec: provided buffer is too small")
@@ -64,12 +68,24 @@ var ErrExecBufferTooSmall = errors.New("encodingexec: provided buffer is too sma
 // Returns number of bytes written to the buffer.
 // If the provided buffer is too small for the program an error is returned.
 func (p *Prog) SerializeForExec(buffer []byte) (int, error) {
+	return p.serializeForExec(buffer, false)
+}
+
+// SerializeForExecOutputs is the same as SerializeForExec, but additionally generates output copyouts
+// of the values returned by the calls in memory (see Call.ExecOutputs), so that they can be compared
+// across executions.
+func (p *Prog) SerializeForExecOutputs(buffer []byte) (int, error) {
+	return p.serializeForExec(buffer, true)
+}
+
+func (p *Prog) serializeForExec(buffer []byte, outputs bool) (int, error) {
 	p.debugValidate()
 	w := &execContext{
-		target: p.Target,
-		buf:    buffer,
-		eof:    false,
-		args:   make(map[Arg]argInfo),
+		target:  p.Target,
+		buf:     buffer,
+		eof:     false,
+		args:    make(map[Arg]argInfo),
+		outputs: outputs,
 	}
 	for _, c := range p.Calls {
 		w.csumMap, w.csumUses = calcChecksumsCall(c)
@@ -112,6 +128,9 @@ func (w *execContext) serializeCall(c *Call) {
 
 	// Generate copyout instructions that persist interesting return values.
 	w.writeCopyout(c)
+	if w.outputs {
+		w.writeOutputs(c)
+	}
 }
 
 type execContext struct {
@@ -120,6 +139,7 @@ type execContext struct {
 	eof        bool
 	args       map[Arg]argInfo
 	copyoutSeq uint64
+	outputs    bool
 	// Per-call state cached here to not pass it through all functions.
 	csumMap  map[Arg]CsumInfo
 	csumUses map[Arg]struct{}
@@ -246,6 +266,79 @@ func (w *execContext) writeCopyout(c *Call) {
 	})
 }
 
+func (w *execContext) writeOutputs(c *Call) {
+	foreachExecOutput(w.target, c, func(out ExecOutput, addr uint64) {
+		w.write(execInstrCopyout)
+		w.write(ExecNoCopyout)
+		w.write(addr + out.Offset)
+		w.write(out.Size)
+	})
+}
+
+// ExecOutput is a value returned by a call in memory that is copied out after the call
+// if the program is serialized with SerializeForExecOutputs.
+type ExecOutput struct {
+	Arg    Arg    // output resource, integer or buffer
+	Offset uint64 // offset of the value within the arg (non-zero only for buffers)
+	Size   uint64 // 1, 2, 4 or 8
+}
+
+// ExecOutputs returns the output values of the call in the order they are copied out:
+// resources, integers and the first bytes of buffers with out or inout direction.
+func (c *Call) ExecOutputs() []ExecOutput {
+	var res []ExecOutput
+	foreachExecOutput(nil, c, func(out ExecOutput, _ uint64) {
+		res = append(res, out)
+	})
+	return res
+}
+
+// foreachExecOutput calls f for each output value of the call with the address of the arg,
+// addresses are not calculated if target is nil.
+func foreachExecOutput(target *Target, c *Call, f func(out ExecOutput, addr uint64)) {
+	n := 0
+	ForeachArg(c, func(arg Arg, ctx *ArgCtx) {
+		if ctx.Base == nil || arg.Dir() == DirIn {
+			return
+		}
+		var addr uint64
+		if target != nil {
+			addr = target.PhysicalAddr(ctx.Base) + ctx.Offset
+		}
+		emit := func(offset, size uint64) {
+			if n < execMaxOutputs {
+				f(ExecOutput{Arg: arg, Offset: offset, Size: size}, addr)
+				n++
+			}
+		}
+		switch a := arg.(type) {
+		case *ResultArg:
+			emit(0, a.Size())
+		case *ConstArg:
+			typ := a.Type()
+			if IsPad(typ) || typ.IsBitfield() {
+				return
+			}
+			if size := a.Size(); size == 1 || size == 2 || size == 4 || size == 8 {
+				emit(0, size)
+			}
+		case *DataArg:
+			size := a.Size()
+			if size > execMaxOutputData {
+				size = execMaxOutputData
+			}
+			for offset := uint64(0); offset < size; {
+				chunk := uint64(8)
+				for chunk > size-offset {
+					chunk /= 2
+				}
+				emit(offset, chunk)
+				offset += chunk
+			}
+		}
+	})
+}
+
 func (w *execContext) write(v uint64) {
 	if len(w.buf) < 8 {
 		w.eof = true
@@ -17,14 +17,62 @@ func TestSerializeForExecRandom(t *testing.T) {
 	buf := make([]byte, ExecBufferSize)
 	for i := 0; i < iters; i++ {
 		p := target.Generate(rs, 10, ct)
-		n, err := p.SerializeForExec(buf)
-		if err != nil {
-			t.Fatalf("failed to serialize: %v", err)
+		for _, serialize := range []func([]byte) (int, error){p.SerializeForExec, p.SerializeForExecOutputs} {
+			n, err := serialize(buf)
+			if err != nil {
+				t.Fatalf("failed to serialize: %v", err)
+			}
+			_, err = target.DeserializeExec(buf[:n])
+			if err != nil {
+				t.Fatal(err)
+			}
 		}
-		_, err = target.DeserializeExec(buf[:n])
-		if err != nil {
-			t.Fatal(err)
+	}
+}
+
+func TestSerializeForExecOutputs(t *testing.T) {
+	target := initTargetTest(t, "test", "64")
+	p, err := target.Deserialize([]byte(`
+r0 = test$res0()
+foo$anyres(&(0x7f0000000000), &(0x7f0000000100))
+mutate_buffer(&(0x7f0000000200)=""/11)
+test$res1(r0)
+`), Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]uint64{nil, {4, 8}, {8, 2, 1}, nil}
+	buf := make([]byte, ExecBufferSize)
+	n, err := p.SerializeForExecOutputs(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := target.DeserializeExec(buf[:n])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range p.Calls {
+		var sizes, copyouts []uint64
+		offset := uint64(0)
+		for _, out := range c.ExecOutputs() {
+			sizes = append(sizes, out.Size)
+			if _, ok := out.Arg.(*DataArg); ok && out.Offset != offset {
+				t.Errorf("call %v: output offset %v, want %v", i, out.Offset, offset)
+			}
+			offset += out.Size
 		}
+		for _, copyout := range decoded.Calls[i].Copyout {
+			if copyout.Index == ExecNoCopyout {
+				copyouts = append(copyouts, copyout.Size)
+			}
+		}
+		if !reflect.DeepEqual(sizes, want[i]) || !reflect.DeepEqual(copyouts, want[i]) {
+			t.Errorf("call %v: outputs %v, copyouts %v, want %v", i, sizes, copyouts, want[i])
+		}
+	}
+	// The result of the first call is still copied out with its own index.
+	if decoded.Calls[0].Index == ExecNoCopyout {
+		t.Errorf("referenced result has no copyout index")
 	}
 }
 
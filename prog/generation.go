@@ -16,18 +16,26 @@ func (target *Target) Generate(rs rand.Source, ncalls int, ct *ChoiceTable) *Pro
 	r := newRand(target, rs)
 	s := newState(target, ct, nil)
 	for len(p.Calls) < ncalls {
-		calls := r.generateCall(s, p, len(p.Calls))
-		for _, c := range calls {
-			s.analyze(c)
-			p.Calls = append(p.Calls, c)
+		for len(p.Calls) < ncalls {
+			calls := r.generateCall(s, p, len(p.Calls))
+			for _, c := range calls {
+				s.analyze(c)
+				p.Calls = append(p.Calls, c)
+			}
+		}
+		// For the last generated call we could get additional calls that create
+		// resources and overflow ncalls. Remove some of these calls.
+		// The resources in the last call will be replaced with the default values,
+		// which is exactly what we want.
+		for len(p.Calls) > ncalls {
+			p.RemoveCall(ncalls - 1)
+		}
+		// But this can break call ordering constraints, then we need to generate more calls.
+		n := len(p.Calls)
+		p.enforceCallOrder(ct)
+		if len(p.Calls) != n {
+			s = analyze(ct, nil, p, nil)
 		}
-	}
-	// For the last generated call we could get additional calls that create
-	// resources and overflow ncalls. Remove some of these calls.
-	// The resources in the last call will be replaced with the default values,
-	// which is exactly what we want.
-	for len(p.Calls) > ncalls {
-		p.RemoveCall(ncalls - 1)
 	}
 	p.sanitizeFix()
 	p.debugValidate()
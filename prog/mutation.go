@@ -48,6 +48,9 @@ func (p *Prog) Mutate(rs rand.Source, ncalls int, ct *ChoiceTable, corpus []*Pro
 		default:
 			ok = ctx.removeCall()
 		}
+		// Mutations can break call ordering constraints (e.g. remove the call that must precede
+		// another call), drop the offending calls. If all calls are dropped, we mutate more.
+		p.enforceCallOrder(ct)
 	}
 	p.sanitizeFix()
 	p.debugValidate()
@@ -56,6 +59,53 @@ func (p *Prog) Mutate(rs rand.Source, ncalls int, ct *ChoiceTable, corpus []*Pro
 	}
 }
 
+// Names of the individual mutation operators accepted by MutateOp.
+const (
+	MutationSquash = "squash"
+	MutationSplice = "splice"
+	MutationInsert = "insert"
+	MutationArg    = "arg"
+	MutationRemove = "remove"
+)
+
+var MutationOps = []string{MutationSquash, MutationSplice, MutationInsert, MutationArg, MutationRemove}
+
+// MutateOp applies a single mutation operator op (one of MutationOps) to the program.
+// It's intended for tools that mutate programs step by step, arguments are the same as for Mutate.
+// Returns false if the operator is not applicable to the program.
+func (p *Prog) MutateOp(rs rand.Source, op string, ncalls int, ct *ChoiceTable, corpus []*Prog) (bool, error) {
+	if ncalls < len(p.Calls) {
+		ncalls = len(p.Calls)
+	}
+	ctx := &mutator{
+		p:      p,
+		r:      newRand(p.Target, rs),
+		ncalls: ncalls,
+		ct:     ct,
+		corpus: corpus,
+	}
+	var ok bool
+	switch op {
+	case MutationSquash:
+		ok = ctx.squashAny()
+	case MutationSplice:
+		ok = ctx.splice()
+	case MutationInsert:
+		ok = ctx.insertCall()
+	case MutationArg:
+		ok = ctx.mutateArg()
+	case MutationRemove:
+		// Don't remove the last call, Mutate never leaves the program empty as well.
+		ok = len(p.Calls) > 1 && ctx.removeCall()
+	default:
+		return false, fmt.Errorf("unknown mutation operator %q", op)
+	}
+	p.enforceCallOrder(ct)
+	p.sanitizeFix()
+	p.debugValidate()
+	return ok, nil
+}
+
 // Internal state required for performing mutations -- currently this matches
 // the arguments passed to Mutate().
 type mutator struct {
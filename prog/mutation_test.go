@@ -467,3 +467,39 @@ func BenchmarkStoreLoadInt(b *testing.B) {
 }
 
 var sink interface{}
+
+func TestMutateOp(t *testing.T) {
+	target, rs, iters := initTest(t)
+	ct := target.DefaultChoiceTable()
+	var corpus []*Prog
+	for i := 0; i < 10; i++ {
+		corpus = append(corpus, target.Generate(rs, 10, ct))
+	}
+	for _, op := range MutationOps {
+		applied := false
+		for i := 0; i < iters; i++ {
+			p := target.Generate(rs, 5, ct)
+			data0 := p.Serialize()
+			ok, err := p.MutateOp(rs, op, 10, ct, corpus)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(p.Calls) == 0 || len(p.Calls) > 10 {
+				t.Fatalf("%v: bad number of calls after mutation: %v", op, len(p.Calls))
+			}
+			data := p.Serialize()
+			if _, err := target.Deserialize(data, NonStrict); err != nil {
+				t.Fatalf("%v: Deserialize failed after mutation: %v\n%s", op, err, data)
+			}
+			if ok && !bytes.Equal(data0, data) {
+				applied = true
+			}
+		}
+		if !applied {
+			t.Errorf("%v: operator never changed the program", op)
+		}
+	}
+	if _, err := target.Generate(rs, 5, ct).MutateOp(rs, "foo", 10, ct, nil); err == nil {
+		t.Errorf("unknown operator is accepted")
+	}
+}
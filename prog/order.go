@@ -0,0 +1,187 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package prog
+
+// Call ordering constraints come from after/not_after call attributes in descriptions.
+// E.g. "ioctl$DEV_START(fd fd_dev) (after[ioctl$DEV_INIT])" says that DEV_START must be
+// preceded by DEV_INIT on the same fd. Calls are considered to operate on the same resource
+// if they use the same resource value (or the value produced by the other call).
+// If the constrained call does not use any resources, the constraint applies to all preceding calls.
+// Generation satisfies "after" constraints by generating one of the required calls first,
+// mutations drop calls that violate the constraints.
+
+func (target *Target) initCallOrder() {
+	resolve := func(names []string) []*Syscall {
+		var res []*Syscall
+		for _, name := range names {
+			// The call may be unsupported on the arch.
+			if meta := target.SyscallMap[name]; meta != nil {
+				res = append(res, meta)
+			}
+		}
+		return res
+	}
+	for _, meta := range target.Syscalls {
+		meta.after = resolve(meta.After)
+		meta.notAfter = resolve(meta.NotAfter)
+		if len(meta.after) != 0 || len(meta.notAfter) != 0 {
+			target.hasCallOrder = true
+		}
+	}
+}
+
+// enabledAfter returns the enabled calls that can satisfy "after" constraint of the call.
+// If none of them are enabled, the constraint is not enforced.
+func enabledAfter(meta *Syscall, ct *ChoiceTable) []*Syscall {
+	if ct == nil {
+		return meta.after
+	}
+	var res []*Syscall
+	for _, call := range meta.after {
+		if ct.Enabled(call.ID) {
+			res = append(res, call)
+		}
+	}
+	return res
+}
+
+// orderViolated says if the call idx violates its ordering constraints.
+func (p *Prog) orderViolated(idx int, ct *ChoiceTable) bool {
+	c := p.Calls[idx]
+	after := enabledAfter(c.Meta, ct)
+	if len(after) == 0 && len(c.Meta.notAfter) == 0 {
+		return false
+	}
+	roots := inputResources(c)
+	satisfied := len(after) == 0
+	for _, prev := range p.Calls[:idx] {
+		if len(roots) != 0 && !usesResources(prev, roots) {
+			continue
+		}
+		if containsSyscall(c.Meta.notAfter, prev.Meta) {
+			return true
+		}
+		if containsSyscall(after, prev.Meta) {
+			satisfied = true
+		}
+	}
+	return !satisfied
+}
+
+// enforceCallOrder removes calls that violate ordering constraints.
+func (p *Prog) enforceCallOrder(ct *ChoiceTable) {
+	if !p.Target.hasCallOrder {
+		return
+	}
+	// Removal of a call can only affect the subsequent calls, so one pass is enough.
+	for i := 0; i < len(p.Calls); {
+		if p.orderViolated(i, ct) {
+			p.RemoveCall(i)
+			continue
+		}
+		i++
+	}
+}
+
+// generateCallOrderPrefix generates calls required by "after" constraint of meta.
+func (r *randGen) generateCallOrderPrefix(s *state, meta *Syscall) []*Call {
+	after := enabledAfter(meta, s.ct)
+	if len(after) == 0 {
+		return nil
+	}
+	calls := r.generateParticularCall(s, after[r.Intn(len(after))])
+	// Make the resources available for the constrained call.
+	for _, c := range calls {
+		s.analyze(c)
+	}
+	return calls
+}
+
+// bindCallOrder makes c operate on a resource used by req (if c does not yet),
+// so that req satisfies "after" constraint of c.
+func (target *Target) bindCallOrder(req, c *Call) {
+	reqRoots := make(map[*ResultArg]bool)
+	for _, root := range resourceRoots(req, true) {
+		reqRoots[root] = true
+	}
+	inputs := inputResourceArgs(c)
+	for _, arg := range inputs {
+		if reqRoots[arg.Res] {
+			return
+		}
+	}
+	for _, arg := range inputs {
+		desc := arg.Type().(*ResourceType).Desc
+		for _, root := range resourceRoots(req, true) {
+			if !target.isCompatibleResource(desc.Name, root.Type().(*ResourceType).Desc.Name) {
+				continue
+			}
+			if arg.Res != nil {
+				delete(arg.Res.uses, arg)
+			}
+			arg.Res, arg.Val = root, 0
+			if root.uses == nil {
+				root.uses = make(map[*ResultArg]bool)
+			}
+			root.uses[arg] = true
+			return
+		}
+	}
+}
+
+// inputResourceArgs returns input resource arguments of c that can refer to resources
+// produced by other calls (including the ones that use special values now).
+func inputResourceArgs(c *Call) []*ResultArg {
+	var args []*ResultArg
+	ForeachArg(c, func(arg Arg, _ *ArgCtx) {
+		if a, ok := arg.(*ResultArg); ok && a.Dir() != DirOut && len(a.uses) == 0 {
+			args = append(args, a)
+		}
+	})
+	return args
+}
+
+// resourceRoots returns resources used by c (and produced by c if produced is set).
+// Every resource is represented by the ResultArg that produces it.
+func resourceRoots(c *Call, produced bool) []*ResultArg {
+	var roots []*ResultArg
+	ForeachArg(c, func(arg Arg, _ *ArgCtx) {
+		a, ok := arg.(*ResultArg)
+		if !ok {
+			return
+		}
+		if a.Res != nil {
+			roots = append(roots, a.Res)
+		} else if produced && a.Dir() != DirIn {
+			roots = append(roots, a)
+		}
+	})
+	return roots
+}
+
+func inputResources(c *Call) map[*ResultArg]bool {
+	roots := make(map[*ResultArg]bool)
+	for _, root := range resourceRoots(c, false) {
+		roots[root] = true
+	}
+	return roots
+}
+
+func usesResources(c *Call, roots map[*ResultArg]bool) bool {
+	for _, root := range resourceRoots(c, true) {
+		if roots[root] {
+			return true
+		}
+	}
+	return false
+}
+
+func containsSyscall(calls []*Syscall, meta *Syscall) bool {
+	for _, call := range calls {
+		if call == meta {
+			return true
+		}
+	}
+	return false
+}
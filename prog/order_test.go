@@ -0,0 +1,98 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package prog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCallOrderViolated(t *testing.T) {
+	target := initTargetTest(t, "test", "64")
+	tests := []struct {
+		prog     string
+		violated bool
+	}{
+		{`
+r0 = test$order_open()
+test$order_init(r0)
+test$order_start(r0)
+`, false},
+		{`
+r0 = test$order_open()
+test$order_start(r0)
+`, true},
+		{`
+r0 = test$order_open()
+r1 = test$order_open()
+test$order_init(r0)
+test$order_start(r1)
+`, true},
+		{`
+r0 = test$order_open()
+test$order_init(r0)
+test$order_stop(r0)
+test$order_start(r0)
+`, true},
+		{`
+r0 = test$order_open()
+r1 = test$order_open()
+test$order_init(r0)
+test$order_stop(r1)
+test$order_start(r0)
+`, false},
+		{`
+test$order_init(0xffffffffffffffff)
+test$order_start(0xffffffffffffffff)
+`, false},
+		{`
+test$order_start(0xffffffffffffffff)
+test$order_init(0xffffffffffffffff)
+`, true},
+	}
+	for i, test := range tests {
+		p, err := target.Deserialize([]byte(strings.TrimSpace(test.prog)), Strict)
+		if err != nil {
+			t.Fatalf("#%v: failed to deserialize: %v", i, err)
+		}
+		violated := false
+		for idx := range p.Calls {
+			violated = violated || p.orderViolated(idx, nil)
+		}
+		if violated != test.violated {
+			t.Errorf("#%v: violated=%v, want %v", i, violated, test.violated)
+		}
+	}
+}
+
+func TestCallOrderGenerateMutate(t *testing.T) {
+	target, rs, iters := initRandomTargetTest(t, "test", "64")
+	enabled := make(map[*Syscall]bool)
+	for _, meta := range target.Syscalls {
+		if strings.HasPrefix(meta.Name, "test$order_") {
+			enabled[meta] = true
+		}
+	}
+	ct := target.BuildChoiceTable(nil, enabled)
+	check := func(p *Prog) {
+		for idx := range p.Calls {
+			if p.orderViolated(idx, ct) {
+				t.Fatalf("call %v violates ordering constraints:\n%s", idx, p.Serialize())
+			}
+		}
+	}
+	started := false
+	for i := 0; i < iters; i++ {
+		p := target.Generate(rs, 10, ct)
+		check(p)
+		p.Mutate(rs, 20, ct, nil)
+		check(p)
+		for _, c := range p.Calls {
+			started = started || c.Meta.Name == "test$order_start"
+		}
+	}
+	if !started {
+		t.Fatalf("test$order_start was never generated")
+	}
+}
@@ -549,10 +549,14 @@ func (r *randGen) generateParticularCall(s *state, meta *Syscall) (calls []*Call
 	if meta.Attrs.Disabled {
 		panic(fmt.Sprintf("generating disabled call %v", meta.Name))
 	}
+	pre := r.generateCallOrderPrefix(s, meta)
 	c := MakeCall(meta, nil)
 	c.Args, calls = r.generateArgs(s, meta.Args, DirIn)
+	if len(pre) != 0 {
+		r.target.bindCallOrder(pre[len(pre)-1], c)
+	}
 	r.target.assignSizesCall(c)
-	return append(calls, c)
+	return append(append(pre, calls...), c)
 }
 
 // GenerateAllSyzProg generates a program that contains all pseudo syz_ calls for testing.
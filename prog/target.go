@@ -65,6 +65,8 @@ type Target struct {
 	// Maps resource name to a list of calls that can create the resource.
 	resourceCtors map[string][]*Syscall
 	any           anyTypes
+	// Set if any syscalls have ordering constraints.
+	hasCallOrder bool
 
 	// The default ChoiceTable is used only by tests and utilities, so we initialize it lazily.
 	defaultOnce        sync.Once
@@ -158,6 +160,7 @@ func (target *Target) initTarget() {
 	for _, res := range target.Resources {
 		target.resourceCtors[res.Name] = target.calcResourceCtors(res, false)
 	}
+	target.initCallOrder()
 }
 
 func (target *Target) GetConst(name string) uint64 {
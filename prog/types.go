@@ -18,9 +18,20 @@ type Syscall struct {
 	Args        []Field
 	Ret         Type
 	Attrs       SyscallAttrs
+	// Ordering constraints (names of syscalls in after/not_after attributes):
+	// the call must be preceded by one of After calls and must not be preceded
+	// by any of NotAfter calls that operate on the same resource.
+	After    []string
+	NotAfter []string
+	// Verifier annotations: result differences between kernels are ignored
+	// if one of the results is one of ExpectErrnos, or for any results if Nondeterministic.
+	ExpectErrnos     []uint64
+	Nondeterministic bool
 
 	inputResources  []*ResourceDesc
 	outputResources []*ResourceDesc
+	after           []*Syscall
+	notAfter        []*Syscall
 }
 
 // SyscallAttrs represents call attributes in syzlang.
@@ -28,6 +28,8 @@ var (
 	flagIncludes  = flag.String("includedirs", "", "path to other kernel source include dirs separated by commas")
 	flagBuildDir  = flag.String("builddir", "", "path to kernel build dir")
 	flagArch      = flag.String("arch", "", "comma-separated list of arches to generate (all by default)")
+	flagBTF       = flag.String("btf", "", "path to kernel BTF (raw or vmlinux) to resolve consts missing in headers")
+	flagDWARF     = flag.String("dwarf", "", "path to vmlinux with DWARF to resolve consts missing in headers")
 )
 
 type Arch struct {
@@ -36,6 +38,7 @@ type Arch struct {
 	includeDirs string
 	buildDir    string
 	build       bool
+	types       *kernelTypes
 	files       []*File
 	err         error
 	done        chan bool
@@ -93,6 +96,9 @@ func main() {
 		tool.Fail(fmt.Errorf("provide path to kernel checkout via -sourcedir " +
 			"flag (or make extract SOURCEDIR)"))
 	}
+	if err := loadArchTypes(arches); err != nil {
+		tool.Fail(err)
+	}
 	if err := extractor.prepare(*flagSourceDir, *flagBuild, arches); err != nil {
 		tool.Fail(err)
 	}
@@ -321,5 +327,43 @@ func processFile(extractor Extractor, arch *Arch, file *File) (map[string]uint64
 	if len(file.info.Consts) == 0 {
 		return nil, nil, nil
 	}
-	return extractor.processFile(arch, file.info)
+	consts, undeclared, err := extractor.processFile(arch, file.info)
+	if arch.types == nil {
+		return consts, undeclared, err
+	}
+	if err != nil {
+		// Headers are not compilable for this config, rely on the debug info only.
+		fmt.Printf("%v: failed to compile headers, resolving consts using debug info\n", inname)
+		consts, undeclared = nil, make(map[string]bool)
+		for _, name := range file.info.Consts {
+			undeclared[name] = true
+		}
+	}
+	if consts == nil {
+		consts = make(map[string]uint64)
+	}
+	arch.types.resolve(file.info, consts, undeclared)
+	return consts, undeclared, nil
+}
+
+func loadArchTypes(arches []*Arch) error {
+	if *flagBTF == "" && *flagDWARF == "" {
+		return nil
+	}
+	if *flagBTF != "" && *flagDWARF != "" {
+		return fmt.Errorf("-btf and -dwarf is an invalid combination")
+	}
+	if len(arches) != 1 {
+		return fmt.Errorf("-btf/-dwarf require a single -arch")
+	}
+	file, useDWARF := *flagBTF, false
+	if *flagDWARF != "" {
+		file, useDWARF = *flagDWARF, true
+	}
+	types, err := loadKernelTypes(file, useDWARF)
+	if err != nil {
+		return fmt.Errorf("failed to load kernel types: %v", err)
+	}
+	arches[0].types = types
+	return nil
 }
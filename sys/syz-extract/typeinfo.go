@@ -0,0 +1,304 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/syzkaller/pkg/btf"
+	"github.com/google/syzkaller/pkg/compiler"
+)
+
+// kernelTypes holds enum values and struct/union layouts extracted from kernel debug info
+// (BTF or DWARF). It's used to resolve consts when kernel headers for the config
+// can't be compiled (or don't contain the const in a compilable form).
+// Only enumerators and defines of the form sizeof(struct foo)/offsetof(struct foo, bar)
+// can be resolved this way, macros are not present in the debug info.
+type kernelTypes struct {
+	enums   map[string]uint64
+	structs map[string]*kernelStruct // "struct foo"/"union foo"
+	// Names with conflicting definitions (can't be used).
+	ambiguous map[string]bool
+}
+
+type kernelStruct struct {
+	size   uint64
+	fields []kernelField
+}
+
+type kernelField struct {
+	name   string // empty for anonymous struct/union members
+	offset uint64
+	typ    *kernelStruct // set if the field is a struct/union itself
+}
+
+func newKernelTypes() *kernelTypes {
+	return &kernelTypes{
+		enums:     make(map[string]uint64),
+		structs:   make(map[string]*kernelStruct),
+		ambiguous: make(map[string]bool),
+	}
+}
+
+func (kt *kernelTypes) addEnum(name string, val uint64) {
+	if prev, ok := kt.enums[name]; ok && prev != val {
+		kt.ambiguous[name] = true
+	}
+	kt.enums[name] = val
+}
+
+func (kt *kernelTypes) addStruct(name string, str *kernelStruct) {
+	if prev := kt.structs[name]; prev != nil {
+		if prev.size != str.size {
+			kt.ambiguous[name] = true
+		}
+		return
+	}
+	kt.structs[name] = str
+}
+
+var (
+	sizeofRe   = regexp.MustCompile(`^sizeof\s*\(\s*((?:struct|union)\s+\w+)\s*\)$`)
+	offsetofRe = regexp.MustCompile(`^offsetof\s*\(\s*((?:struct|union)\s+\w+)\s*,\s*([\w.]+)\s*\)$`)
+	identRe    = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+)
+
+// eval evaluates a const expression, only a few simple forms are supported (see kernelTypes).
+func (kt *kernelTypes) eval(expr string) (uint64, bool) {
+	expr = strings.TrimSpace(expr)
+	for len(expr) > 2 && expr[0] == '(' && expr[len(expr)-1] == ')' {
+		expr = strings.TrimSpace(expr[1 : len(expr)-1])
+	}
+	if v, err := strconv.ParseUint(expr, 0, 64); err == nil {
+		return v, true
+	}
+	if identRe.MatchString(expr) {
+		v, ok := kt.enums[expr]
+		return v, ok && !kt.ambiguous[expr]
+	}
+	if match := sizeofRe.FindStringSubmatch(expr); match != nil {
+		str := kt.lookupStruct(match[1])
+		if str == nil {
+			return 0, false
+		}
+		return str.size, true
+	}
+	if match := offsetofRe.FindStringSubmatch(expr); match != nil {
+		str := kt.lookupStruct(match[1])
+		if str == nil {
+			return 0, false
+		}
+		offset := uint64(0)
+		for _, name := range strings.Split(match[2], ".") {
+			if str == nil {
+				return 0, false
+			}
+			fld := str.field(name)
+			if fld == nil {
+				return 0, false
+			}
+			offset += fld.offset
+			str = fld.typ
+		}
+		return offset, true
+	}
+	return 0, false
+}
+
+func (kt *kernelTypes) lookupStruct(name string) *kernelStruct {
+	name = strings.Join(strings.Fields(name), " ")
+	if kt.ambiguous[name] {
+		return nil
+	}
+	return kt.structs[name]
+}
+
+// field returns the named field, fields of anonymous members are looked up as well.
+// The returned field offset is relative to the start of str.
+func (str *kernelStruct) field(name string) *kernelField {
+	for i := range str.fields {
+		fld := &str.fields[i]
+		if fld.name == name {
+			return fld
+		}
+		if fld.name == "" && fld.typ != nil {
+			if inner := fld.typ.field(name); inner != nil {
+				res := *inner
+				res.offset += fld.offset
+				return &res
+			}
+		}
+	}
+	return nil
+}
+
+// resolve resolves undeclared consts of the file using the debug info.
+func (kt *kernelTypes) resolve(info *compiler.ConstInfo, consts map[string]uint64, undeclared map[string]bool) {
+	for _, name := range info.Consts {
+		if !undeclared[name] {
+			continue
+		}
+		expr := name
+		if def, ok := info.Defines[name]; ok {
+			expr = def
+		}
+		if v, ok := kt.eval(expr); ok {
+			consts[name] = v
+			delete(undeclared, name)
+		}
+	}
+}
+
+// loadKernelTypes loads debug info from a raw BTF blob (e.g. /sys/kernel/btf/vmlinux)
+// or from .BTF section or DWARF of an ELF file (e.g. vmlinux).
+func loadKernelTypes(file string, useDWARF bool) (*kernelTypes, error) {
+	if !useDWARF {
+		types, err := btf.Load(file)
+		if err != nil {
+			return nil, err
+		}
+		return convertBTF(types), nil
+	}
+	ef, err := elf.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer ef.Close()
+	dw, err := ef.DWARF()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DWARF from %v: %v", file, err)
+	}
+	return parseDWARF(dw)
+}
+
+func convertBTF(types []*btf.Type) *kernelTypes {
+	kt := newKernelTypes()
+	structs := make(map[*btf.Type]*kernelStruct)
+	var convert func(id uint32) *kernelStruct
+	convert = func(id uint32) *kernelStruct {
+		t := btf.Resolve(types, id)
+		if t == nil || t.Kind != btf.KindStruct && t.Kind != btf.KindUnion {
+			return nil
+		}
+		if str := structs[t]; str != nil {
+			return str
+		}
+		str := &kernelStruct{size: t.Size}
+		structs[t] = str
+		for _, m := range t.Members {
+			str.fields = append(str.fields, kernelField{
+				name:   m.Name,
+				offset: m.BitOffset / 8,
+				typ:    convert(m.Type),
+			})
+		}
+		return str
+	}
+	for id, t := range types {
+		if t == nil {
+			continue
+		}
+		for _, e := range t.Enums {
+			kt.addEnum(e.Name, e.Value)
+		}
+		if t.Name == "" || t.Kind != btf.KindStruct && t.Kind != btf.KindUnion {
+			continue
+		}
+		prefix := "struct "
+		if t.Kind == btf.KindUnion {
+			prefix = "union "
+		}
+		kt.addStruct(prefix+t.Name, convert(uint32(id)))
+	}
+	return kt
+}
+
+func parseDWARF(dw *dwarf.Data) (*kernelTypes, error) {
+	kt := newKernelTypes()
+	structs := make(map[dwarf.Type]*kernelStruct)
+	var convert func(typ dwarf.Type) *kernelStruct
+	convert = func(typ dwarf.Type) *kernelStruct {
+		for {
+			switch t := typ.(type) {
+			case *dwarf.TypedefType:
+				typ = t.Type
+				continue
+			case *dwarf.QualType:
+				typ = t.Type
+				continue
+			case *dwarf.StructType:
+				if t.Incomplete {
+					return nil
+				}
+				if str := structs[t]; str != nil {
+					return str
+				}
+				str := &kernelStruct{size: uint64(t.ByteSize)}
+				structs[t] = str
+				for _, fld := range t.Field {
+					offset := uint64(fld.ByteOffset)
+					if fld.BitSize != 0 && fld.DataBitOffset != 0 {
+						// DWARF 4 bitfields don't have byte offset.
+						offset = uint64(fld.DataBitOffset / 8)
+					}
+					str.fields = append(str.fields, kernelField{
+						name:   fld.Name,
+						offset: offset,
+						typ:    convert(fld.Type),
+					})
+				}
+				return str
+			}
+			return nil
+		}
+	}
+	r := dw.Reader()
+	for {
+		entry, err := r.Next()
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			break
+		}
+		switch entry.Tag {
+		case dwarf.TagEnumerationType:
+			typ, err := dw.Type(entry.Offset)
+			if err != nil {
+				return nil, err
+			}
+			for _, val := range typ.(*dwarf.EnumType).Val {
+				kt.addEnum(val.Name, uint64(val.Val))
+			}
+		case dwarf.TagStructType, dwarf.TagUnionType:
+			name, _ := entry.Val(dwarf.AttrName).(string)
+			if name == "" || entry.Val(dwarf.AttrDeclaration) != nil {
+				continue
+			}
+			prefix := "struct "
+			if entry.Tag == dwarf.TagUnionType {
+				prefix = "union "
+			}
+			// Every compilation unit has own copy of the type, parsing all of them is slow.
+			if prev := kt.structs[prefix+name]; prev != nil {
+				if size, ok := entry.Val(dwarf.AttrByteSize).(int64); ok && uint64(size) == prev.size {
+					continue
+				}
+			}
+			typ, err := dw.Type(entry.Offset)
+			if err != nil {
+				return nil, err
+			}
+			if str := convert(typ); str != nil {
+				kt.addStruct(prefix+name, str)
+			}
+		}
+	}
+	return kt, nil
+}
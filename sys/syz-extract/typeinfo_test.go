@@ -0,0 +1,124 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/syzkaller/pkg/btf"
+	"github.com/google/syzkaller/pkg/compiler"
+	"github.com/google/syzkaller/pkg/osutil"
+)
+
+func TestBTF(t *testing.T) {
+	types := []*btf.Type{
+		nil,
+		{Name: "int", Kind: btf.KindInt, Size: 4, Bits: 32},
+		{Kind: btf.KindEnum, Size: 4, Enums: []btf.Enum{{Name: "FOO_A", Value: 1}, {Name: "FOO_NEG", Value: ^uint64(0)}}},
+		{Kind: btf.KindEnum64, Size: 8, Enums: []btf.Enum{{Name: "FOO_BIG", Value: 0x100000002}}},
+		{Kind: btf.KindUnion, Size: 8, Members: []btf.Member{
+			{Name: "u1", Type: 1},
+			{Name: "u2", Type: 1},
+		}},
+		{Name: "nested", Kind: btf.KindStruct, Size: 8, Members: []btf.Member{
+			{Name: "n1", Type: 1},
+			{Name: "n2", Type: 1, BitOffset: 32},
+		}},
+		{Name: "nested_t", Kind: btf.KindTypedef, Type: 5},
+		{Name: "foo", Kind: btf.KindStruct, Size: 32, Members: []btf.Member{
+			{Name: "f1", Type: 1},
+			{Type: 4, BitOffset: 64},
+			{Name: "f3", Type: 6, BitOffset: 128},
+			{Name: "bits", Type: 1, BitOffset: 192, BitSize: 3},
+		}},
+	}
+	testKernelTypes(t, convertBTF(types))
+}
+
+func TestDWARF(t *testing.T) {
+	cc, err := exec.LookPath("gcc")
+	if err != nil {
+		t.Skip("gcc is not present")
+	}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "types.c")
+	obj := filepath.Join(dir, "types.o")
+	if err := osutil.WriteFile(src, []byte(`
+enum { FOO_A = 1, FOO_NEG = -1 };
+enum { FOO_BIG = 0x100000002ull };
+typedef struct nested { int n1; int n2; } nested_t;
+struct foo {
+	int f1;
+	int pad;
+	union { int u1; int u2; };
+	int pad2;
+	nested_t f3;
+	int bits : 3;
+	int pad3;
+};
+struct foo foo;
+int big = FOO_A + FOO_NEG + FOO_BIG;
+`)); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := osutil.RunCmd(time.Minute, dir, cc, "-g", "-c", "-o", obj, src); err != nil {
+		t.Skipf("failed to compile: %v\n%s", err, out)
+	}
+	kt, err := loadKernelTypes(obj, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testKernelTypes(t, kt)
+}
+
+func testKernelTypes(t *testing.T, kt *kernelTypes) {
+	info := &compiler.ConstInfo{
+		Consts: []string{"FOO_A", "FOO_NEG", "FOO_BIG", "FOO_SIZE", "NESTED_SIZE",
+			"U2_OFF", "N2_OFF", "BITS_OFF", "FOO_MISSING", "NO_FIELD", "EXPR", "FOUND"},
+		Defines: map[string]string{
+			"FOO_SIZE":    "sizeof(struct foo)",
+			"NESTED_SIZE": "(sizeof(struct  nested))",
+			"U2_OFF":      "offsetof(struct foo, u2)",
+			"N2_OFF":      "offsetof(struct foo, f3.n2)",
+			"BITS_OFF":    "offsetof(struct foo, bits)",
+			"NO_FIELD":    "offsetof(struct foo, f3.n3)",
+			"EXPR":        "FOO_A + 1",
+		},
+	}
+	consts := map[string]uint64{"FOUND": 42}
+	undeclared := make(map[string]bool)
+	for _, name := range info.Consts {
+		if consts[name] == 0 {
+			undeclared[name] = true
+		}
+	}
+	kt.resolve(info, consts, undeclared)
+	wantConsts := map[string]uint64{
+		"FOUND":       42,
+		"FOO_A":       1,
+		"FOO_NEG":     ^uint64(0),
+		"FOO_BIG":     0x100000002,
+		"FOO_SIZE":    32,
+		"NESTED_SIZE": 8,
+		"U2_OFF":      8,
+		"N2_OFF":      20,
+		"BITS_OFF":    24,
+	}
+	for name, want := range wantConsts {
+		if got, ok := consts[name]; !ok || got != want {
+			t.Errorf("%v: got %v/%v, want %v", name, got, ok, want)
+		}
+	}
+	for _, name := range []string{"FOO_MISSING", "NO_FIELD", "EXPR"} {
+		if !undeclared[name] {
+			t.Errorf("%v is resolved to %v", name, consts[name])
+		}
+	}
+	if len(consts)+len(undeclared) != len(info.Consts) {
+		t.Errorf("resolved %v, undeclared %v, total %v", len(consts), len(undeclared), len(info.Consts))
+	}
+}
@@ -458,11 +458,17 @@ var oses = map[string]osCommon{
 		ExecutorUsesForkServer: true,
 		KernelObject:           "vmlinux",
 		PseudoSyscallDeps: map[string][]string{
-			"syz_read_part_table": {"memfd_create"},
-			"syz_mount_image":     {"memfd_create"},
-			"syz_io_uring_setup":  {"io_uring_setup"},
-			"syz_clone3":          {"clone3", "exit"},
-			"syz_clone":           {"clone", "exit"},
+			"syz_read_part_table":             {"memfd_create"},
+			"syz_mount_image":                 {"memfd_create"},
+			"syz_io_uring_setup":              {"io_uring_setup"},
+			"syz_io_uring_register_file":      {"io_uring_register$IORING_REGISTER_FILES_UPDATE"},
+			"syz_io_uring_unregister_file":    {"io_uring_register$IORING_REGISTER_FILES_UPDATE"},
+			"syz_io_uring_register_buffers":   {"io_uring_register$IORING_REGISTER_BUFFERS"},
+			"syz_io_uring_unregister_buffers": {"io_uring_register$IORING_UNREGISTER_BUFFERS"},
+			"syz_io_uring_register_eventfd":   {"io_uring_register$IORING_REGISTER_EVENTFD"},
+			"syz_io_uring_unregister_eventfd": {"io_uring_register$IORING_UNREGISTER_EVENTFD"},
+			"syz_clone3":                      {"clone3", "exit"},
+			"syz_clone":                       {"clone", "exit"},
 		},
 		cflags: []string{"-static-pie"},
 	},
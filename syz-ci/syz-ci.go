@@ -67,6 +67,7 @@ import (
 	"github.com/google/syzkaller/pkg/log"
 	"github.com/google/syzkaller/pkg/mgrconfig"
 	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/pkg/profile"
 )
 
 var (
@@ -104,6 +105,14 @@ type Config struct {
 	JobPollPeriod int `json:"job_poll_period"`
 	// Poll period for commits in seconds (optional, defaults to 3600 seconds)
 	CommitPollPeriod int `json:"commit_poll_period"`
+	// Cgroup v2 directory writable by the syz-ci user (optional, linux only).
+	// If set, kernel/syzkaller builds, image generation and other commands run by syz-ci
+	// are placed into <cgroup>/syz-ci cgroup with cgroup_limits, e.g.:
+	//	"cgroup": "/sys/fs/cgroup/syzkaller",
+	//	"cgroup_limits": {"memory_mb": 65536, "cpus": 32}
+	// syz-manager processes are not confined, they can use own "cgroup" config.
+	Cgroup       string              `json:"cgroup"`
+	CgroupLimits osutil.CgroupLimits `json:"cgroup_limits"`
 }
 
 type ManagerConfig struct {
@@ -174,6 +183,14 @@ func main() {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
+	if cfg.Cgroup != "" {
+		cg, err := osutil.NewCgroup(cfg.Cgroup, "syz-ci", cfg.CgroupLimits)
+		if err != nil {
+			log.Fatalf("failed to create cgroup: %v", err)
+		}
+		osutil.ConfineCommands(cg)
+	}
+
 	shutdownPending := make(chan struct{})
 	osutil.HandleInterrupts(shutdownPending)
 
@@ -242,6 +259,8 @@ func main() {
 		jp.loop()
 	}()
 
+	http.HandleFunc("/log-levels", log.LevelsHandler)
+	http.HandleFunc("/debug/profilebundle", profile.BundleHandler)
 	// For testing. Racy. Use with care.
 	http.HandleFunc("/upload_cover", func(w http.ResponseWriter, r *http.Request) {
 		for _, mgr := range managers {
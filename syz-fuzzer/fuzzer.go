@@ -22,6 +22,7 @@ import (
 	"github.com/google/syzkaller/pkg/ipc/ipcconfig"
 	"github.com/google/syzkaller/pkg/log"
 	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/pkg/profile"
 	"github.com/google/syzkaller/pkg/rpctype"
 	"github.com/google/syzkaller/pkg/signal"
 	"github.com/google/syzkaller/pkg/tool"
@@ -61,6 +62,8 @@ type Fuzzer struct {
 	maxSignal    signal.Signal // max signal ever observed including flakes
 	newSignal    signal.Signal // diff of maxSignal since last sync with master
 
+	race *raceScheduler
+
 	checkResult *rpctype.CheckArgs
 	logMu       sync.Mutex
 }
@@ -83,6 +86,7 @@ const (
 	StatHint
 	StatSeed
 	StatCollide
+	StatRace
 	StatCount
 )
 
@@ -96,6 +100,7 @@ var statNames = [StatCount]string{
 	StatHint:      "exec hints",
 	StatSeed:      "exec seeds",
 	StatCollide:   "exec collide",
+	StatRace:      "exec race",
 }
 
 type OutputType int
@@ -148,6 +153,10 @@ func main() {
 		flagTest     = flag.Bool("test", false, "enable image testing mode")      // used by syz-ci
 		flagRunTest  = flag.Bool("runtest", false, "enable program testing mode") // used by pkg/runtest
 		flagRawCover = flag.Bool("raw_cover", false, "fetch raw coverage")
+		flagTLSCA    = flag.String("tls_ca", "", "CA certificate for manager rpc (mutual TLS)")
+		flagTLSCert  = flag.String("tls_cert", "", "client certificate for manager rpc (mutual TLS)")
+		flagTLSKey   = flag.String("tls_key", "", "client key for manager rpc (mutual TLS)")
+		flagSysCache = flag.String("syscall_cache", "", "dir to cache detected supported syscalls in")
 	)
 	defer tool.Init()()
 	outputType := parseOutputType(*flagOutput)
@@ -183,6 +192,7 @@ func main() {
 		ipcExecOpts:    execOpts,
 		gitRevision:    prog.GitRevision,
 		targetRevision: target.Revision,
+		syscallCache:   *flagSysCache,
 	}
 	if *flagTest {
 		testImage(*flagManager, checkArgs)
@@ -191,8 +201,12 @@ func main() {
 
 	machineInfo, modules := collectMachineInfos(target)
 
+	tlsCfg, err := rpctype.ClientTLSConfig(*flagTLSCA, *flagTLSCert, *flagTLSKey)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
 	log.Logf(0, "dialing manager at %v", *flagManager)
-	manager, err := rpctype.NewRPCClient(*flagManager, timeouts.Scale)
+	manager, err := rpctype.NewRPCClientTLS(*flagManager, timeouts.Scale, tlsCfg)
 	if err != nil {
 		log.Fatalf("failed to connect to manager: %v ", err)
 	}
@@ -270,6 +284,7 @@ func main() {
 		corpusHashes:             make(map[hash.Sig]struct{}),
 		checkResult:              r.CheckResult,
 		fetchRawCover:            *flagRawCover,
+		race:                     newRaceScheduler(),
 	}
 	gateCallback := fuzzer.useBugFrames(r, *flagProcs)
 	fuzzer.gate = ipc.NewGate(2**flagProcs, gateCallback)
@@ -419,6 +434,12 @@ func (fuzzer *Fuzzer) poll(needCandidates bool, stats map[string]uint64) bool {
 	log.Logf(1, "poll: candidates=%v inputs=%v signal=%v",
 		len(r.Candidates), len(r.NewInputs), maxSignal.Len())
 	fuzzer.addMaxSignal(maxSignal)
+	if r.Profile != nil {
+		go fuzzer.sendProfile(r.Profile)
+	}
+	if len(r.RacyPairs) != 0 {
+		fuzzer.race.setReports(r.RacyPairs)
+	}
 	for _, inp := range r.NewInputs {
 		fuzzer.addInputFromAnotherFuzzer(inp)
 	}
@@ -431,6 +452,23 @@ func (fuzzer *Fuzzer) poll(needCandidates bool, stats map[string]uint64) bool {
 	return len(r.NewInputs) != 0 || len(r.Candidates) != 0 || maxSignal.Len() != 0
 }
 
+// sendProfile captures a runtime profile requested by the manager (see syz-manager/profile.go).
+func (fuzzer *Fuzzer) sendProfile(req *rpctype.ProfileRequest) {
+	log.Logf(0, "capturing %v profile for %vs", req.Kind, req.Seconds)
+	a := &rpctype.ProfileResultArgs{
+		Name: fuzzer.name,
+		ID:   req.ID,
+	}
+	data, err := profile.Capture(req.Kind, time.Duration(req.Seconds)*time.Second)
+	if err != nil {
+		a.Error = err.Error()
+	}
+	a.Data = data
+	if err := fuzzer.manager.Call("Manager.ProfileResult", a, nil); err != nil {
+		log.Fatalf("Manager.ProfileResult call failed: %v", err)
+	}
+}
+
 func (fuzzer *Fuzzer) sendInputToManager(inp rpctype.Input) {
 	a := &rpctype.NewInputArgs{
 		Name:  fuzzer.name,
@@ -449,6 +487,7 @@ func (fuzzer *Fuzzer) addInputFromAnotherFuzzer(inp rpctype.Input) {
 	sig := hash.Hash(inp.Prog)
 	sign := inp.Signal.Deserialize()
 	fuzzer.addInputToCorpus(p, sign, sig)
+	fuzzer.race.addSignal(inp.Call, sign)
 }
 
 func (fuzzer *Fuzzer) addCandidateInput(candidate rpctype.Candidate) {
@@ -592,6 +631,13 @@ func (fuzzer *Fuzzer) checkNewCallSignal(p *prog.Prog, info *ipc.CallInfo, call
 	return true
 }
 
+// hasNewCallSignal checks if the call produced signal that is not in max signal, but does not update max signal.
+func (fuzzer *Fuzzer) hasNewCallSignal(p *prog.Prog, info *ipc.CallInfo, call int) bool {
+	fuzzer.signalMu.RLock()
+	defer fuzzer.signalMu.RUnlock()
+	return !fuzzer.maxSignal.DiffRaw(info.Signal, signalPrio(p, info, call)).Empty()
+}
+
 func signalPrio(p *prog.Prog, info *ipc.CallInfo, call int) (prio uint8) {
 	if call == -1 {
 		return 0
@@ -30,6 +30,7 @@ type Proc struct {
 	rnd             *rand.Rand
 	execOpts        *ipc.ExecOpts
 	execOptsCollide *ipc.ExecOpts
+	execOptsRace    *ipc.ExecOpts
 	execOptsCover   *ipc.ExecOpts
 	execOptsComps   *ipc.ExecOpts
 }
@@ -42,6 +43,8 @@ func newProc(fuzzer *Fuzzer, pid int) (*Proc, error) {
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(pid)*1e12))
 	execOptsCollide := *fuzzer.execOpts
 	execOptsCollide.Flags &= ^ipc.FlagCollectSignal
+	// Racing executions collect signal to evaluate the overlap windows.
+	execOptsRace := *fuzzer.execOpts
 	execOptsCover := *fuzzer.execOpts
 	execOptsCover.Flags |= ipc.FlagCollectCover
 	execOptsComps := *fuzzer.execOpts
@@ -53,6 +56,7 @@ func newProc(fuzzer *Fuzzer, pid int) (*Proc, error) {
 		rnd:             rnd,
 		execOpts:        fuzzer.execOpts,
 		execOptsCollide: &execOptsCollide,
+		execOptsRace:    &execOptsRace,
 		execOptsCover:   &execOptsCover,
 		execOptsComps:   &execOptsComps,
 	}
@@ -177,6 +181,7 @@ func (proc *Proc) triageInput(item *WorkTriage) {
 	})
 
 	proc.fuzzer.addInputToCorpus(item.p, inputSignal, sig)
+	proc.fuzzer.race.addSignal(callName, inputSignal)
 
 	if item.flags&ProgSmashed == 0 {
 		proc.fuzzer.workQueue.enqueue(&WorkSmash{item.p, item.call})
@@ -289,10 +294,37 @@ func (proc *Proc) executeAndCollide(execOpts *ipc.ExecOpts, p *prog.Prog, flags
 	}
 	const collideIterations = 2
 	for i := 0; i < collideIterations; i++ {
+		// Race a pair of calls that are likely to race in half of the cases.
+		if proc.rnd.Intn(2) == 0 && proc.raceCollide(p) {
+			continue
+		}
 		proc.executeRaw(proc.execOptsCollide, proc.randomCollide(p), StatCollide)
 	}
 }
 
+// raceCollide executes a pair of calls chosen by the race scheduler concurrently.
+// Returns false if there is no pair to race in the program.
+func (proc *Proc) raceCollide(p *prog.Prog) bool {
+	i, j, ok := proc.fuzzer.race.choosePair(p, proc.rnd)
+	if !ok {
+		return false
+	}
+	w := proc.fuzzer.race.chooseWindow(proc.rnd)
+	raced, err := prog.AppendRacingPair(p, i, j, raceWindows[w])
+	if err != nil {
+		return false
+	}
+	info := proc.executeRaw(proc.execOptsRace, raced, StatRace)
+	newSignal := false
+	for call := len(p.Calls); info != nil && call < len(info.Calls); call++ {
+		if proc.fuzzer.hasNewCallSignal(raced, &info.Calls[call], call) {
+			newSignal = true
+		}
+	}
+	proc.fuzzer.race.windowResult(w, newSignal)
+	return true
+}
+
 func (proc *Proc) randomCollide(origP *prog.Prog) *prog.Prog {
 	// Old-styl collide with a 33% probability.
 	if proc.rnd.Intn(3) == 0 {
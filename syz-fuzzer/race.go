@@ -0,0 +1,163 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math/bits"
+	"math/rand"
+	"strings"
+	"sync"
+
+	"github.com/google/syzkaller/pkg/rpctype"
+	"github.com/google/syzkaller/pkg/signal"
+	"github.com/google/syzkaller/prog"
+)
+
+// raceScheduler chooses pairs of calls of a program that are likely to race and the overlap window
+// for their concurrent execution (see prog.AppendRacingPair).
+// Calls are likely to race if they touch the same kernel code, which we approximate by the overlap
+// of their signal. Pairs of calls that preceded KCSAN reports (sent by the manager) get more weight.
+// The window (number of reruns of the pair) is chosen with an epsilon-greedy strategy based on
+// how often executions with the window produce new signal in the racing calls.
+type raceScheduler struct {
+	mu      sync.Mutex
+	signal  map[string]*raceSketch
+	reports map[[2]string]int
+	windows [len(raceWindows)]raceWindowStats
+}
+
+var raceWindows = [...]int{8, 16, 32, 64, 128}
+
+type raceWindowStats struct {
+	tries uint64
+	hits  uint64
+}
+
+// raceSketch is a fixed-size bitmap of signal elements of a call.
+type raceSketch [raceSketchBits / 64]uint64
+
+const raceSketchBits = 1 << 12
+
+func newRaceScheduler() *raceScheduler {
+	return &raceScheduler{
+		signal:  make(map[string]*raceSketch),
+		reports: make(map[[2]string]int),
+	}
+}
+
+func (rs *raceScheduler) addSignal(call string, sign signal.Signal) {
+	if strings.HasPrefix(call, ".") || sign.Empty() {
+		// Extra signal does not belong to any call.
+		return
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	sketch := rs.signal[call]
+	if sketch == nil {
+		sketch = new(raceSketch)
+		rs.signal[call] = sketch
+	}
+	for elem := range sign {
+		bit := uint32(elem) % raceSketchBits
+		sketch[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (rs *raceScheduler) setReports(pairs []rpctype.RacyPair) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.reports = make(map[[2]string]int)
+	for _, pair := range pairs {
+		rs.reports[racePairKey(pair.Call0, pair.Call1)] = pair.Reports
+	}
+}
+
+// choosePair returns indices of two calls (possibly the same call) to race.
+// Calls are chosen randomly with probability proportional to the score of the pair,
+// ok is false if no pair has a positive score.
+func (rs *raceScheduler) choosePair(p *prog.Prog, rnd *rand.Rand) (i, j int, ok bool) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	var scores []float64
+	total := 0.0
+	for i := range p.Calls {
+		for j := i; j < len(p.Calls); j++ {
+			score := rs.score(p.Calls[i].Meta.Name, p.Calls[j].Meta.Name)
+			scores = append(scores, score)
+			total += score
+		}
+	}
+	if total == 0 {
+		return 0, 0, false
+	}
+	val := rnd.Float64() * total
+	idx := 0
+	for i0 := range p.Calls {
+		for j0 := i0; j0 < len(p.Calls); j0++ {
+			if score := scores[idx]; score != 0 {
+				// Floating point errors can leave val positive after the last pair,
+				// in such case we return the last pair with a positive score.
+				i, j = i0, j0
+				if val -= score; val < 0 {
+					return i, j, true
+				}
+			}
+			idx++
+		}
+	}
+	return i, j, true
+}
+
+// score is the fraction of signal of the smaller call shared by the calls
+// plus the number of KCSAN reports preceded by the pair.
+func (rs *raceScheduler) score(call0, call1 string) float64 {
+	score := float64(rs.reports[racePairKey(call0, call1)])
+	s0, s1 := rs.signal[call0], rs.signal[call1]
+	if s0 == nil || s1 == nil {
+		return score
+	}
+	common, n0, n1 := 0, 0, 0
+	for i := range s0 {
+		common += bits.OnesCount64(s0[i] & s1[i])
+		n0 += bits.OnesCount64(s0[i])
+		n1 += bits.OnesCount64(s1[i])
+	}
+	if n1 < n0 {
+		n0 = n1
+	}
+	return score + float64(common)/float64(n0)
+}
+
+// chooseWindow returns index of the window in raceWindows.
+func (rs *raceScheduler) chooseWindow(rnd *rand.Rand) int {
+	if rnd.Intn(4) == 0 {
+		return rnd.Intn(len(raceWindows))
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	best, bestRate := 0, -1.0
+	for w, st := range rs.windows {
+		// Windows without tries get the 1/2 prior.
+		if rate := float64(st.hits+1) / float64(st.tries+2); rate > bestRate {
+			best, bestRate = w, rate
+		}
+	}
+	return best
+}
+
+func (rs *raceScheduler) windowResult(w int, newSignal bool) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.windows[w].tries++
+	if newSignal {
+		rs.windows[w].hits++
+	}
+}
+
+func racePairKey(call0, call1 string) [2]string {
+	if call1 < call0 {
+		call0, call1 = call1, call0
+	}
+	return [2]string{call0, call1}
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/google/syzkaller/pkg/rpctype"
+	"github.com/google/syzkaller/pkg/signal"
+	"github.com/google/syzkaller/prog"
+	"github.com/google/syzkaller/sys/targets"
+)
+
+func TestRaceSchedulerPairs(t *testing.T) {
+	target := getTarget(t, targets.TestOS, targets.TestArch64)
+	p, err := target.Deserialize([]byte("breaks_returns()\nminimize$0(0x1, 0x1)\ntest$res0()\n"), prog.Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rnd := rand.New(rand.NewSource(0))
+	rs := newRaceScheduler()
+	if _, _, ok := rs.choosePair(p, rnd); ok {
+		t.Fatalf("chose a pair without any information")
+	}
+	// Signal of minimize$0 and test$res0 overlaps, breaks_returns has disjoint signal.
+	rs.addSignal("breaks_returns", signal.FromRaw([]uint32{100, 101}, 0))
+	rs.addSignal("minimize$0", signal.FromRaw([]uint32{1, 2, 3, 4}, 0))
+	rs.addSignal("test$res0", signal.FromRaw([]uint32{3, 4, 5, 6}, 0))
+	rs.addSignal(".extra", signal.FromRaw([]uint32{1, 100}, 0))
+	counts := make(map[[2]int]int)
+	for i := 0; i < 1000; i++ {
+		i, j, ok := rs.choosePair(p, rnd)
+		if !ok {
+			t.Fatalf("no pair chosen")
+		}
+		counts[[2]int{i, j}]++
+	}
+	if counts[[2]int{0, 1}] != 0 || counts[[2]int{0, 2}] != 0 {
+		t.Fatalf("chose pairs with disjoint signal: %v", counts)
+	}
+	if counts[[2]int{1, 2}] == 0 || counts[[2]int{1, 1}] <= counts[[2]int{1, 2}] {
+		t.Fatalf("bad pair distribution: %v", counts)
+	}
+	// KCSAN reports outweigh signal overlap.
+	rs.setReports([]rpctype.RacyPair{{Call0: "test$res0", Call1: "breaks_returns", Reports: 10}})
+	counts = make(map[[2]int]int)
+	for i := 0; i < 1000; i++ {
+		i, j, _ := rs.choosePair(p, rnd)
+		counts[[2]int{i, j}]++
+	}
+	if counts[[2]int{0, 2}] < 500 {
+		t.Fatalf("reported pair is not preferred: %v", counts)
+	}
+}
+
+func TestRaceSchedulerWindows(t *testing.T) {
+	rnd := rand.New(rand.NewSource(0))
+	rs := newRaceScheduler()
+	const best = 3
+	for i := 0; i < 1000; i++ {
+		w := rs.chooseWindow(rnd)
+		rs.windowResult(w, w == best || rnd.Intn(10) == 0)
+	}
+	if rs.windows[best].tries < 500 {
+		t.Fatalf("the best window is not preferred: %+v", rs.windows)
+	}
+}
@@ -6,6 +6,7 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -29,6 +30,7 @@ type checkArgs struct {
 	ipcConfig      *ipc.Config
 	ipcExecOpts    *ipc.ExecOpts
 	featureFlags   map[string]csource.Feature
+	syscallCache   string
 }
 
 func testImage(hostAddr string, args *checkArgs) {
@@ -184,7 +186,7 @@ func checkCalls(args *checkArgs, res *rpctype.CheckArgs) error {
 		// TODO: Add "android" sandbox here when needed. Will require fixing runtests.
 	}
 	for _, sandbox := range sandboxes {
-		enabledCalls, disabledCalls, err := buildCallList(args.target, args.enabledCalls, sandbox)
+		enabledCalls, disabledCalls, err := buildCallList(args.target, args.enabledCalls, sandbox, args.syscallCache)
 		res.EnabledCalls[sandbox] = enabledCalls
 		res.DisabledCalls[sandbox] = disabledCalls
 		if err != nil {
@@ -278,7 +280,7 @@ func checkSimpleProgram(args *checkArgs, features *host.Features) error {
 	return nil
 }
 
-func buildCallList(target *prog.Target, enabledCalls []int, sandbox string) (
+func buildCallList(target *prog.Target, enabledCalls []int, sandbox, cacheDir string) (
 	enabled []int, disabled []rpctype.SyscallReason, err error) {
 	log.Logf(0, "building call list...")
 	calls := make(map[*prog.Syscall]bool)
@@ -295,7 +297,13 @@ func buildCallList(target *prog.Target, enabledCalls []int, sandbox string) (
 		}
 	}
 
-	_, unsupported, err := host.DetectSupportedSyscalls(target, sandbox, calls)
+	var unsupported map[*prog.Syscall]string
+	if cacheDir != "" {
+		cacheFile := filepath.Join(cacheDir, "syscalls."+sandbox)
+		_, unsupported, err = host.DetectSupportedSyscallsCached(target, sandbox, calls, cacheFile)
+	} else {
+		_, unsupported, err = host.DetectSupportedSyscalls(target, sandbox, calls)
+	}
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to detect host supported syscalls: %v", err)
 	}
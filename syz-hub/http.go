@@ -16,6 +16,7 @@ import (
 
 func (hub *Hub) initHTTP(addr string) {
 	http.HandleFunc("/", hub.httpSummary)
+	http.HandleFunc("/log-levels", log.LevelsHandler)
 
 	ln, err := net.Listen("tcp4", addr)
 	if err != nil {
@@ -77,10 +77,11 @@ func (hub *Hub) Connect(a *rpctype.HubConnectArgs, r *int) error {
 	hub.mu.Lock()
 	defer hub.mu.Unlock()
 
-	log.Logf(0, "connect from %v: domain=%v fresh=%v calls=%v corpus=%v",
-		name, a.Domain, a.Fresh, len(a.Calls), len(a.Corpus))
+	mgrLog := log.New("hub").With("manager", name)
+	mgrLog.Logf(0, "connect: domain=%v fresh=%v calls=%v corpus=%v",
+		a.Domain, a.Fresh, len(a.Calls), len(a.Corpus))
 	if err := hub.st.Connect(name, a.Domain, a.Fresh, a.Calls, a.Corpus); err != nil {
-		log.Logf(0, "connect error: %v", err)
+		mgrLog.Logf(0, "connect error: %v", err)
 		return err
 	}
 	return nil
@@ -94,9 +95,10 @@ func (hub *Hub) Sync(a *rpctype.HubSyncArgs, r *rpctype.HubSyncRes) error {
 	hub.mu.Lock()
 	defer hub.mu.Unlock()
 
+	mgrLog := log.New("hub").With("manager", name)
 	domain, inputs, more, err := hub.st.Sync(name, a.Add, a.Del)
 	if err != nil {
-		log.Logf(0, "sync error: %v", err)
+		mgrLog.Logf(0, "sync error: %v", err)
 		return err
 	}
 	if domain != "" {
@@ -107,22 +109,25 @@ func (hub *Hub) Sync(a *rpctype.HubSyncArgs, r *rpctype.HubSyncRes) error {
 		}
 	}
 	r.More = more
+	if err := hub.st.AddOutcomes(name, a.Outcomes); err != nil {
+		mgrLog.Logf(0, "add outcomes error: %v", err)
+	}
 	for _, repro := range a.Repros {
 		if err := hub.st.AddRepro(name, repro); err != nil {
-			log.Logf(0, "add repro error: %v", err)
+			mgrLog.Logf(0, "add repro error: %v", err)
 		}
 	}
 	if a.NeedRepros {
 		repro, err := hub.st.PendingRepro(name)
 		if err != nil {
-			log.Logf(0, "sync error: %v", err)
+			mgrLog.Logf(0, "sync error: %v", err)
 		}
 		if repro != nil {
 			r.Repros = [][]byte{repro}
 		}
 	}
-	log.Logf(0, "sync from %v: recv: add=%v del=%v repros=%v; send: progs=%v repros=%v pending=%v",
-		name, len(a.Add), len(a.Del), len(a.Repros), len(inputs), len(r.Repros), more)
+	mgrLog.Logf(0, "sync: recv: add=%v del=%v repros=%v outcomes=%v; send: progs=%v repros=%v pending=%v",
+		len(a.Add), len(a.Del), len(a.Repros), len(a.Outcomes), len(inputs), len(r.Repros), more)
 	return nil
 }
 
@@ -4,6 +4,7 @@
 package state
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -30,6 +31,37 @@ type State struct {
 	Corpus    *db.DB
 	Repros    *db.DB
 	Managers  map[string]*Manager
+	// ReputationDB persists per-program reputation keyed by program hash.
+	ReputationDB *db.DB
+	reputation   map[string]*Reputation
+}
+
+// Reputation accumulates outcomes of a corpus program reported by managers.
+// Programs with higher reputation score are sent first to new managers,
+// programs that keep failing are not redistributed at all.
+type Reputation struct {
+	// Adopted is the number of managers that received the program from the hub
+	// and added it to their corpus (i.e. it gave them new coverage).
+	Adopted int
+	// Crashed is the number of times the program caused a crash on a manager.
+	Crashed int
+	// Failed is the number of times a manager rejected the program as malformed.
+	Failed int
+}
+
+const (
+	reputationCrashWeight = 3
+	reputationFailWeight  = 2
+	// Programs with score below this are not redistributed.
+	reputationMinScore = -3
+)
+
+// Score returns the cumulative reputation score of a program.
+func (rep *Reputation) Score() int {
+	if rep == nil {
+		return 0
+	}
+	return rep.Adopted + reputationCrashWeight*rep.Crashed - reputationFailWeight*rep.Failed
 }
 
 // Manager represents one syz-manager instance.
@@ -43,14 +75,21 @@ type Manager struct {
 	reproSeqFile  string
 	domainFile    string
 	ownRepros     map[string]bool
-	Connected     time.Time
-	Added         int
-	Deleted       int
-	New           int
-	SentRepros    int
-	RecvRepros    int
-	Calls         map[string]struct{}
-	Corpus        *db.DB
+	// sent contains hashes of programs with seq above corpusSeq that don't need
+	// to be sent to the manager anymore: either they were already sent as part of
+	// the best reputation batch, or they were given up due to the corpus cap.
+	// Entries are dropped once corpusSeq reaches their seq. The set is not persisted,
+	// so after a hub restart the manager may receive the best reputation batch again
+	// (minus the programs it has already added to its corpus).
+	sent       map[string]bool
+	Connected  time.Time
+	Added      int
+	Deleted    int
+	New        int
+	SentRepros int
+	RecvRepros int
+	Calls      map[string]struct{}
+	Corpus     *db.DB
 }
 
 // Make creates State and initializes it from dir.
@@ -70,6 +109,20 @@ func Make(dir string) (*State, error) {
 	if err != nil {
 		log.Fatal(err)
 	}
+	st.ReputationDB, _, err = loadDB(filepath.Join(st.dir, "reputation.db"), "reputation", false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	st.reputation = make(map[string]*Reputation)
+	for key, rec := range st.ReputationDB.Records {
+		rep := new(Reputation)
+		if err := json.Unmarshal(rec.Val, rep); err != nil {
+			log.Logf(0, "bad reputation record %v: %v", key, err)
+			st.ReputationDB.Delete(key)
+			continue
+		}
+		st.reputation[key] = rep
+	}
 
 	managersDir := filepath.Join(st.dir, "manager")
 	osutil.MkdirAll(managersDir)
@@ -93,6 +146,9 @@ func (st *State) Flush() {
 	if err := st.Corpus.Flush(); err != nil {
 		log.Logf(0, "failed to flush corpus database: %v", err)
 	}
+	if err := st.ReputationDB.Flush(); err != nil {
+		log.Logf(0, "failed to flush reputation database: %v", err)
+	}
 	for _, mgr := range st.Managers {
 		if err := mgr.Corpus.Flush(); err != nil {
 			log.Logf(0, "failed to flush corpus database: %v", err)
@@ -147,6 +203,7 @@ func (st *State) createManager(name string) (*Manager, error) {
 		reproSeqFile:  filepath.Join(dir, "repro.seq"),
 		domainFile:    filepath.Join(dir, "domain"),
 		ownRepros:     make(map[string]bool),
+		sent:          make(map[string]bool),
 	}
 	mgr.corpusSeq = loadSeqFile(mgr.corpusSeqFile)
 	if st.corpusSeq < mgr.corpusSeq {
@@ -203,7 +260,8 @@ func (st *State) Connect(name, domain string, fresh bool, calls []string, corpus
 		log.Logf(0, "failed to open corpus database: %v", err)
 		return err
 	}
-	st.addInputs(mgr, corpus)
+	mgr.sent = make(map[string]bool)
+	st.addInputs(mgr, corpus, false)
 	st.purgeCorpus()
 	return nil
 }
@@ -222,7 +280,7 @@ func (st *State) Sync(name string, add [][]byte, del []string) (string, []rpctyp
 		}
 		st.purgeCorpus()
 	}
-	st.addInputs(mgr, add)
+	st.addInputs(mgr, add, true)
 	progs, more, err := st.pendingInputs(mgr)
 	mgr.Added += len(add)
 	mgr.Deleted += len(del)
@@ -230,6 +288,48 @@ func (st *State) Sync(name string, add [][]byte, del []string) (string, []rpctyp
 	return mgr.Domain, progs, more, err
 }
 
+// AddOutcomes updates reputation of programs according to the outcomes reported by the manager.
+func (st *State) AddOutcomes(name string, outcomes []rpctype.HubOutcome) error {
+	mgr := st.Managers[name]
+	if mgr == nil || mgr.Connected.IsZero() {
+		return fmt.Errorf("unconnected manager %v", name)
+	}
+	if len(outcomes) == 0 {
+		return nil
+	}
+	for _, outcome := range outcomes {
+		if _, ok := st.Corpus.Records[outcome.Sig]; !ok {
+			continue
+		}
+		st.updateReputation(outcome.Sig, func(rep *Reputation) {
+			if outcome.Crashed {
+				rep.Crashed++
+			}
+			if outcome.Failed {
+				rep.Failed++
+			}
+		})
+	}
+	if err := st.ReputationDB.Flush(); err != nil {
+		log.Logf(0, "failed to flush reputation database: %v", err)
+	}
+	return nil
+}
+
+func (st *State) updateReputation(sig string, update func(rep *Reputation)) {
+	rep := st.reputation[sig]
+	if rep == nil {
+		rep = new(Reputation)
+		st.reputation[sig] = rep
+	}
+	update(rep)
+	data, err := json.Marshal(rep)
+	if err != nil {
+		panic(err)
+	}
+	st.ReputationDB.Save(sig, data, 0)
+}
+
 func (st *State) AddRepro(name string, repro []byte) error {
 	mgr := st.Managers[name]
 	if mgr == nil || mgr.Connected.IsZero() {
@@ -298,16 +398,17 @@ func (st *State) PendingRepro(name string) ([]byte, error) {
 	return repro, nil
 }
 
+type inputRecord struct {
+	Key string
+	Val []byte
+	Seq uint64
+}
+
 func (st *State) pendingInputs(mgr *Manager) ([]rpctype.HubInput, int, error) {
 	if mgr.corpusSeq == st.corpusSeq {
 		return nil, 0, nil
 	}
-	type Record struct {
-		Key string
-		Val []byte
-		Seq uint64
-	}
-	var records []Record
+	var records []inputRecord
 	for key, rec := range st.Corpus.Records {
 		if mgr.corpusSeq >= rec.Seq {
 			continue
@@ -322,10 +423,11 @@ func (st *State) pendingInputs(mgr *Manager) ([]rpctype.HubInput, int, error) {
 		if !managerSupportsAllCalls(mgr.Calls, calls) {
 			continue
 		}
-		records = append(records, Record{key, rec.Val, rec.Seq})
+		if mgr.sent[key] || st.reputation[key].Score() < reputationMinScore {
+			continue
+		}
+		records = append(records, inputRecord{key, rec.Val, rec.Seq})
 	}
-	maxSeq := st.corpusSeq
-	more := 0
 	const (
 		// Send at most that many records (rounded up to next seq number).
 		maxRecords = 100
@@ -334,12 +436,51 @@ func (st *State) pendingInputs(mgr *Manager) ([]rpctype.HubInput, int, error) {
 		// Otherwise new managers will never chew all this on a busy hub.
 		capRecords = 100000
 	)
+	if mgr.corpusSeq == 0 {
+		// New managers first receive programs with the best reputation.
+		// These are sent out of seq order, so they are remembered in mgr.sent
+		// and skipped afterwards.
+		var best []inputRecord
+		for _, rec := range records {
+			if st.reputation[rec.Key].Score() > 0 {
+				best = append(best, rec)
+			}
+		}
+		if len(best) != 0 {
+			sort.SliceStable(best, func(i, j int) bool {
+				return st.reputation[best[i].Key].Score() > st.reputation[best[j].Key].Score()
+			})
+			if len(best) > maxRecords {
+				best = best[:maxRecords]
+			}
+			return st.sendInputs(mgr, best), len(records) - len(best), nil
+		}
+	}
+	maxSeq := st.corpusSeq
+	more := 0
 	if len(records) > maxRecords {
 		sort.Slice(records, func(i, j int) bool {
 			return records[i].Seq < records[j].Seq
 		})
 		if len(records) > capRecords {
-			records = records[len(records)-capRecords:]
+			// Keep programs with the best reputation, and the newest among equal ones.
+			// The rest are given up for good: they are marked as sent, so they are not
+			// reconsidered when the remaining backlog falls below the cap.
+			sort.Slice(records, func(i, j int) bool {
+				score0 := st.reputation[records[i].Key].Score()
+				score1 := st.reputation[records[j].Key].Score()
+				if score0 != score1 {
+					return score0 > score1
+				}
+				return records[i].Seq > records[j].Seq
+			})
+			for _, rec := range records[capRecords:] {
+				mgr.sent[rec.Key] = true
+			}
+			records = records[:capRecords]
+			sort.Slice(records, func(i, j int) bool {
+				return records[i].Seq < records[j].Seq
+			})
 		}
 		pos := maxRecords
 		maxSeq = records[pos].Seq
@@ -350,16 +491,28 @@ func (st *State) pendingInputs(mgr *Manager) ([]rpctype.HubInput, int, error) {
 		more = len(records) - pos
 		records = records[:pos]
 	}
+	progs := st.sendInputs(mgr, records)
+	mgr.corpusSeq = maxSeq
+	saveSeqFile(mgr.corpusSeqFile, mgr.corpusSeq)
+	for key := range mgr.sent {
+		// The programs below corpusSeq are skipped by seq anyway.
+		if rec, ok := st.Corpus.Records[key]; !ok || rec.Seq <= mgr.corpusSeq {
+			delete(mgr.sent, key)
+		}
+	}
+	return progs, more, nil
+}
+
+func (st *State) sendInputs(mgr *Manager, records []inputRecord) []rpctype.HubInput {
 	progs := make([]rpctype.HubInput, 0, len(records))
 	for _, rec := range records {
+		mgr.sent[rec.Key] = true
 		progs = append(progs, rpctype.HubInput{
 			Domain: st.inputDomain(rec.Key, mgr.Domain),
 			Prog:   rec.Val,
 		})
 	}
-	mgr.corpusSeq = maxSeq
-	saveSeqFile(mgr.corpusSeqFile, mgr.corpusSeq)
-	return progs, more, nil
+	return progs
 }
 
 func (st *State) inputDomain(key, self string) string {
@@ -380,13 +533,15 @@ func (st *State) inputDomain(key, self string) string {
 	return domain
 }
 
-func (st *State) addInputs(mgr *Manager, inputs [][]byte) {
+// addInputs adds inputs to the manager corpus. If adopt is set, inputs that
+// the manager might have received from the hub are accounted in their reputation.
+func (st *State) addInputs(mgr *Manager, inputs [][]byte, adopt bool) {
 	if len(inputs) == 0 {
 		return
 	}
 	st.corpusSeq++
 	for _, input := range inputs {
-		st.addInput(mgr, input)
+		st.addInput(mgr, input, adopt)
 	}
 	if err := mgr.Corpus.Flush(); err != nil {
 		log.Logf(0, "failed to flush corpus database: %v", err)
@@ -394,9 +549,12 @@ func (st *State) addInputs(mgr *Manager, inputs [][]byte) {
 	if err := st.Corpus.Flush(); err != nil {
 		log.Logf(0, "failed to flush corpus database: %v", err)
 	}
+	if err := st.ReputationDB.Flush(); err != nil {
+		log.Logf(0, "failed to flush reputation database: %v", err)
+	}
 }
 
-func (st *State) addInput(mgr *Manager, input []byte) {
+func (st *State) addInput(mgr *Manager, input []byte, adopt bool) {
 	_, ncalls, err := prog.CallSet(input)
 	if err != nil {
 		log.Logf(0, "manager %v: failed to extract call set: %v, program:\n%v", mgr.name, err, string(input))
@@ -407,6 +565,14 @@ func (st *State) addInput(mgr *Manager, input []byte) {
 		return
 	}
 	sig := hash.String(input)
+	if rec, ok := st.Corpus.Records[sig]; ok && adopt {
+		// The program was already sent to the manager (by seq, or as part of
+		// the best reputation batch), and now the manager has added it to its corpus.
+		_, had := mgr.Corpus.Records[sig]
+		if !had && (rec.Seq <= mgr.corpusSeq || mgr.sent[sig]) {
+			st.updateReputation(sig, func(rep *Reputation) { rep.Adopted++ })
+		}
+	}
 	mgr.Corpus.Save(sig, nil, 0)
 	if _, ok := st.Corpus.Records[sig]; !ok {
 		st.Corpus.Save(sig, input, st.corpusSeq)
@@ -426,9 +592,19 @@ func (st *State) purgeCorpus() {
 		}
 		st.Corpus.Delete(key)
 	}
+	for key := range st.reputation {
+		if _, ok := st.Corpus.Records[key]; ok {
+			continue
+		}
+		delete(st.reputation, key)
+		st.ReputationDB.Delete(key)
+	}
 	if err := st.Corpus.Flush(); err != nil {
 		log.Logf(0, "failed to flush corpus database: %v", err)
 	}
+	if err := st.ReputationDB.Flush(); err != nil {
+		log.Logf(0, "failed to flush reputation database: %v", err)
+	}
 }
 
 func managerSupportsAllCalls(mgr, prog map[string]struct{}) bool {
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/pkg/hash"
 	"github.com/google/syzkaller/pkg/rpctype"
 )
 
@@ -186,3 +187,89 @@ func TestDomain(t *testing.T) {
 		}
 	}
 }
+
+func TestReputation(t *testing.T) {
+	st := MakeTestState(t)
+
+	progA, progB, progC, progD := []byte("open()"), []byte("read()"), []byte("close()"), []byte("read()\nclose()")
+	all := [][]byte{progA, progB, progC, progD}
+	calls := []string{"open", "read", "close"}
+	st.Connect("foo", "", false, calls, nil)
+	st.Sync("foo", all, nil)
+
+	st.Connect("bar", "", false, calls, nil)
+	if _, inputs, pending := st.Sync("bar", nil, nil); len(inputs) != 4 || pending != 0 {
+		t.Fatalf("bad sync result: %v, %v", inputs, pending)
+	}
+	// bar adopts B and D, D crashes bar and C is malformed for both managers.
+	st.Sync("bar", [][]byte{progB, progD}, nil)
+	if err := st.state.AddOutcomes("bar", []rpctype.HubOutcome{
+		{Sig: hash.String(progD), Crashed: true},
+		{Sig: hash.String(progC), Failed: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.state.AddOutcomes("foo", []rpctype.HubOutcome{
+		{Sig: hash.String(progC), Failed: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	expectReputation := func(data []byte, adopted, crashed, failed int) {
+		t.Helper()
+		want := &Reputation{Adopted: adopted, Crashed: crashed, Failed: failed}
+		got := st.state.reputation[hash.String(data)]
+		if got == nil {
+			got = new(Reputation)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Fatalf("bad reputation of %q: %v", data, diff)
+		}
+	}
+	expectReputation(progA, 0, 0, 0)
+	expectReputation(progB, 1, 0, 0)
+	expectReputation(progC, 0, 0, 2)
+	expectReputation(progD, 1, 1, 0)
+
+	// New managers receive programs with the best reputation first (D, then B),
+	// programs that keep failing (C) are not sent at all.
+	expectSync := func(name string, add [][]byte, want [][]byte, wantPending int) {
+		t.Helper()
+		_, inputs, pending, err := st.state.Sync(name, add, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got [][]byte
+		for _, inp := range inputs {
+			got = append(got, inp.Prog)
+		}
+		if diff := cmp.Diff(want, got); diff != "" || pending != wantPending {
+			t.Fatalf("pending %v, want %v, inputs: %v", pending, wantPending, diff)
+		}
+	}
+	st.Connect("baz", "", false, calls, nil)
+	expectSync("baz", nil, [][]byte{progD, progB}, 1)
+	expectSync("baz", [][]byte{progD}, [][]byte{progA}, 0)
+	expectSync("baz", nil, nil, 0)
+	expectReputation(progD, 2, 1, 0)
+
+	// Check how persistence works. Reconnects do not count as adoption.
+	st.Reload()
+	st.Connect("foo", "", false, calls, all)
+	st.Connect("bar", "", false, calls, [][]byte{progB, progD})
+	st.Connect("baz", "", false, calls, [][]byte{progD})
+	expectReputation(progB, 1, 0, 0)
+	expectReputation(progC, 0, 0, 2)
+	expectReputation(progD, 2, 1, 0)
+
+	// A manager that received the best reputation batch before the hub restart
+	// receives it again, except for the programs it has already added to its corpus.
+	st.Connect("qux", "", false, calls, nil)
+	expectSync("qux", nil, [][]byte{progD, progB}, 1)
+	st.Reload()
+	st.Connect("foo", "", false, calls, all)
+	st.Connect("qux", "", false, calls, [][]byte{progD})
+	expectSync("qux", nil, [][]byte{progB}, 1)
+	expectSync("qux", nil, [][]byte{progA}, 0)
+	expectSync("qux", nil, nil, 0)
+	expectReputation(progD, 2, 1, 0)
+}
@@ -5,6 +5,7 @@ package main
 
 import (
 	"sync"
+	"time"
 
 	"github.com/google/syzkaller/pkg/cover"
 	"github.com/google/syzkaller/pkg/host"
@@ -34,3 +35,77 @@ func coverToPCs(rg *cover.ReportGenerator, cov []uint32) []uint64 {
 	}
 	return pcs
 }
+
+const subsystemCoverPeriod = time.Hour
+
+// subsystemCover returns number of corpus-covered PCs per kernel subsystem,
+// or nil if coverage is not available yet.
+func (mgr *Manager) subsystemCover() map[string]uint64 {
+	if !mgr.cfg.Cover {
+		return nil
+	}
+	mgr.mu.Lock()
+	initialized := mgr.modulesInitialized
+	mgr.mu.Unlock()
+	if !initialized {
+		return nil
+	}
+	rg, err := getReportGenerator(mgr.cfg, mgr.modules)
+	if err != nil {
+		log.Logf(0, "failed to generate coverage profile: %v", err)
+		return nil
+	}
+	// Only the inputs are copied under the lock, the conversion of coverage of the whole corpus
+	// is slow and must not block the RPC handlers. Input coverage is replaced, never modified in place.
+	type input struct {
+		sig   string
+		prog  []byte
+		cover []uint32
+	}
+	mgr.mu.Lock()
+	inputs := make([]input, 0, len(mgr.corpus))
+	for sig, inp := range mgr.corpus {
+		inputs = append(inputs, input{sig, inp.Prog, inp.Cover})
+	}
+	mgr.mu.Unlock()
+	if len(inputs) == 0 {
+		return nil
+	}
+	progs := make([]cover.Prog, 0, len(inputs))
+	for _, inp := range inputs {
+		progs = append(progs, cover.Prog{
+			Sig:  inp.sig,
+			Data: string(inp.prog),
+			PCs:  coverToPCs(rg, inp.cover),
+		})
+	}
+	subsystems, err := rg.SubsystemCover(progs, nil)
+	if err != nil {
+		log.Logf(0, "failed to compute subsystem coverage: %v", err)
+		return nil
+	}
+	res := make(map[string]uint64)
+	for name, pcs := range subsystems {
+		res[name] = uint64(pcs)
+	}
+	return res
+}
+
+// updateFuncCover adds coverage of new corpus inputs to the per-function coverage
+// and returns it. Inputs are added only once, so repeated queries are cheap.
+func (mgr *Manager) updateFuncCover(rg *cover.ReportGenerator) *cover.FuncCover {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	if mgr.funcCover == nil {
+		mgr.funcCover = rg.NewFuncCover()
+		mgr.funcCoverAdded = make(map[string]bool)
+	}
+	for sig, inp := range mgr.corpus {
+		if mgr.funcCoverAdded[sig] {
+			continue
+		}
+		mgr.funcCoverAdded[sig] = true
+		mgr.funcCover.Add(coverToPCs(rg, inp.Cover))
+	}
+	return mgr.funcCover
+}
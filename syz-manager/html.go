@@ -11,7 +11,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
-	_ "net/http/pprof"
+	"net/http/pprof"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -52,9 +52,20 @@ func (mgr *Manager) initHTTP() {
 	mux.HandleFunc("/rawcoverfiles", mgr.httpRawCoverFiles)
 	mux.HandleFunc("/filterpcs", mgr.httpFilterPCs)
 	mux.HandleFunc("/funccover", mgr.httpFuncCover)
+	mux.HandleFunc("/funcsummary", mgr.httpFuncSummary)
 	mux.HandleFunc("/filecover", mgr.httpFileCover)
 	mux.HandleFunc("/input", mgr.httpInput)
 	mux.HandleFunc("/debuginput", mgr.httpDebugInput)
+	mux.HandleFunc("/verifier", mgr.httpVerifier)
+	mux.HandleFunc("/verifier/mismatch", mgr.httpVerifierMismatch)
+	mux.HandleFunc("/log-levels", log.LevelsHandler)
+	mux.HandleFunc("/fuzzerprofile", mgr.httpFuzzerProfile)
+	mux.HandleFunc("/profilebundle", mgr.httpProfileBundle)
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
 	// Browsers like to request this, without special handler this goes to / handler.
 	mux.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {})
 
@@ -69,9 +80,10 @@ func (mgr *Manager) initHTTP() {
 
 func (mgr *Manager) httpSummary(w http.ResponseWriter, r *http.Request) {
 	data := &UISummaryData{
-		Name:  mgr.cfg.Name,
-		Log:   log.CachedLogOutput(),
-		Stats: mgr.collectStats(),
+		Name:    mgr.cfg.Name,
+		Log:     log.CachedLogOutput(),
+		Stats:   mgr.collectStats(),
+		Fuzzers: mgr.serv.fuzzerNames(),
 	}
 
 	var err error
@@ -111,6 +123,17 @@ func (mgr *Manager) httpSyscalls(w http.ResponseWriter, r *http.Request) {
 	sort.Slice(data.Calls, func(i, j int) bool {
 		return data.Calls[i].Name < data.Calls[j].Name
 	})
+	mgr.mu.Lock()
+	for name, reason := range mgr.disabledSyscalls {
+		data.Disabled = append(data.Disabled, UIDisabledCall{
+			Name:   name,
+			Reason: reason,
+		})
+	}
+	mgr.mu.Unlock()
+	sort.Slice(data.Disabled, func(i, j int) bool {
+		return data.Disabled[i].Name < data.Disabled[j].Name
+	})
 	executeTemplate(w, syscallsTemplate, data)
 }
 
@@ -146,6 +169,9 @@ func (mgr *Manager) collectStats() []UIStat {
 	delete(rawStats, "signal")
 	delete(rawStats, "coverage")
 	delete(rawStats, "filtered coverage")
+	if mgr.cfg.VerifierHTTP != "" {
+		stats = append(stats, UIStat{Name: "verifier", Value: mgr.cfg.VerifierHTTP, Link: "/verifier"})
+	}
 	if mgr.checkResult != nil {
 		stats = append(stats, UIStat{
 			Name:  "syscalls",
@@ -365,6 +391,45 @@ func (mgr *Manager) httpCoverCover(w http.ResponseWriter, r *http.Request, funcF
 	runtime.GC()
 }
 
+// httpFuncSummary lists functions of a subsystem (subsystem=NAME, "all" by default)
+// with the number of covered PCs, or only the uncovered functions if uncovered=1.
+func (mgr *Manager) httpFuncSummary(w http.ResponseWriter, r *http.Request) {
+	if !mgr.cfg.Cover {
+		http.Error(w, "coverage is not enabled", http.StatusInternalServerError)
+		return
+	}
+	mgr.mu.Lock()
+	initialized := mgr.modulesInitialized
+	mgr.mu.Unlock()
+	if !initialized {
+		http.Error(w, "coverage is not ready, please try again later after fuzzer started", http.StatusInternalServerError)
+		return
+	}
+	rg, err := getReportGenerator(mgr.cfg, mgr.modules)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to generate coverage profile: %v", err), http.StatusInternalServerError)
+		return
+	}
+	fc := mgr.updateFuncCover(rg)
+	subsystem := r.FormValue("subsystem")
+	if subsystem == "" {
+		subsystem = "all"
+	}
+	get := fc.Functions
+	if r.FormValue("uncovered") != "" {
+		get = fc.Uncovered
+	}
+	funcs, err := get(subsystem)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, f := range funcs {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v/%v\t%v\n", f.File, f.Name, f.Module, f.Covered, f.PCs, f.Hits)
+	}
+}
+
 func (mgr *Manager) httpCoverFallback(w http.ResponseWriter, r *http.Request) {
 	mgr.mu.Lock()
 	defer mgr.mu.Unlock()
@@ -699,11 +764,13 @@ type UISummaryData struct {
 	Stats   []UIStat
 	Crashes []*UICrashType
 	Log     string
+	Fuzzers []string
 }
 
 type UISyscallsData struct {
-	Name  string
-	Calls []UICallType
+	Name     string
+	Calls    []UICallType
+	Disabled []UIDisabledCall
 }
 
 type UICrashType struct {
@@ -738,6 +805,11 @@ type UICallType struct {
 	Cover  int
 }
 
+type UIDisabledCall struct {
+	Name   string
+	Reason string
+}
+
 type UICorpus struct {
 	Call     string
 	RawCover bool
@@ -777,6 +849,19 @@ var summaryTemplate = html.CreatePage(`
 	{{end}}
 </table>
 
+<form action="/profilebundle">
+	Profile for <input type="number" name="seconds" value="10" min="1" max="300"> seconds,
+	fuzzer: <select name="vm">
+		<option value="">none</option>
+		{{range $f := $.Fuzzers}}
+		<option value="{{$f}}">{{$f}}</option>
+		{{end}}
+	</select>
+	<input type="submit" value="Capture and download">
+	(<a href="/debug/pprof/">pprof</a>)
+</form>
+<br>
+
 <table class="list_table">
 	<caption>Crashes:</caption>
 	<tr>
@@ -837,6 +922,22 @@ var syscallsTemplate = html.CreatePage(`
 	</tr>
 	{{end}}
 </table>
+{{if $.Disabled}}
+<br>
+<table class="list_table">
+	<caption>Disabled syscalls:</caption>
+	<tr>
+		<th><a onclick="return sortTable(this, 'Syscall', textSort)" href="#">Syscall</a></th>
+		<th><a onclick="return sortTable(this, 'Reason', textSort)" href="#">Reason</a></th>
+	</tr>
+	{{range $c := $.Disabled}}
+	<tr>
+		<td>{{$c.Name}}</td>
+		<td>{{$c.Reason}}</td>
+	</tr>
+	{{end}}
+</table>
+{{end}}
 </body></html>
 `)
 
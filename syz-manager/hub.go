@@ -4,6 +4,7 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -64,6 +65,7 @@ type HubConnector struct {
 	fresh          bool
 	hubCorpus      map[hash.Sig]bool
 	newRepros      [][]byte
+	outcomes       []rpctype.HubOutcome
 	hubReproQueue  chan *Crash
 	needMoreRepros chan chan bool
 	keyGet         keyGetter
@@ -73,6 +75,8 @@ type HubConnector struct {
 type HubManagerView interface {
 	getMinimizedCorpus() (corpus, repros [][]byte)
 	addNewCandidates(candidates []rpctype.Candidate)
+	addHubProgs(progs map[hash.Sig]string)
+	takeHubOutcomes() []rpctype.HubOutcome
 }
 
 func (hc *HubConnector) loop() {
@@ -83,13 +87,13 @@ func (hc *HubConnector) loop() {
 		if hub == nil {
 			var err error
 			if hub, err = hc.connect(corpus); err != nil {
-				log.Logf(0, "failed to connect to hub at %v: %v", hc.cfg.HubAddr, err)
+				hubLog.Logf(0, "failed to connect to hub at %v: %v", hc.cfg.HubAddr, err)
 				continue
 			}
-			log.Logf(0, "connected to hub at %v, corpus %v", hc.cfg.HubAddr, len(corpus))
+			hubLog.Logf(0, "connected to hub at %v, corpus %v", hc.cfg.HubAddr, len(corpus))
 		}
 		if err := hc.sync(hub, corpus); err != nil {
-			log.Logf(0, "hub sync failed: %v", err)
+			hubLog.Logf(0, "hub sync failed: %v", err)
 			hub.Close()
 			hub = nil
 		}
@@ -128,7 +132,7 @@ func (hc *HubConnector) connect(corpus [][]byte) (*rpctype.RPCClient, error) {
 	if err := rpctype.RPCCall(hc.cfg.HubAddr, 1, "Hub.Connect", a, nil); err != nil {
 		return nil, err
 	}
-	hub, err := rpctype.NewRPCClient(hc.cfg.HubAddr, 1)
+	hub, err := rpctype.NewGobRPCClient(hc.cfg.HubAddr, 1)
 	if err != nil {
 		return nil, err
 	}
@@ -171,8 +175,12 @@ func (hc *HubConnector) sync(hub *rpctype.RPCClient, corpus [][]byte) error {
 	}
 	a.Repros = hc.newRepros
 	for {
+		a.Outcomes = append(hc.outcomes, hc.mgr.takeHubOutcomes()...)
+		hc.outcomes = nil
 		r := new(rpctype.HubSyncRes)
 		if err := hub.Call("Hub.Sync", a, r); err != nil {
+			// Resend outcomes on the next sync.
+			hc.outcomes = a.Outcomes
 			return err
 		}
 		minimized, smashed, progDropped := hc.processProgs(r.Inputs)
@@ -184,7 +192,7 @@ func (hc *HubConnector) sync(hub *rpctype.RPCClient, corpus [][]byte) error {
 		hc.stats.hubRecvProgDrop.add(progDropped)
 		hc.stats.hubRecvRepro.add(len(r.Repros) - reproDropped)
 		hc.stats.hubRecvReproDrop.add(reproDropped)
-		log.Logf(0, "hub sync: send: add %v, del %v, repros %v;"+
+		hubLog.Logf(0, "hub sync: send: add %v, del %v, repros %v;"+
 			" recv: progs %v (min %v, smash %v), repros %v; more %v",
 			len(a.Add), len(a.Del), len(a.Repros),
 			len(r.Inputs)-progDropped, minimized, smashed,
@@ -202,11 +210,19 @@ func (hc *HubConnector) sync(hub *rpctype.RPCClient, corpus [][]byte) error {
 
 func (hc *HubConnector) processProgs(inputs []rpctype.HubInput) (minimized, smashed, dropped int) {
 	candidates := make([]rpctype.Candidate, 0, len(inputs))
+	progs := make(map[hash.Sig]string)
 	for _, inp := range inputs {
 		bad, disabled := checkProgram(hc.target, hc.enabledCalls, inp.Prog)
 		if bad || disabled {
-			log.Logf(0, "rejecting program from hub (bad=%v, disabled=%v):\n%s",
+			hubLog.Logf(0, "rejecting program from hub (bad=%v, disabled=%v):\n%s",
 				bad, disabled, inp)
+			if bad {
+				// Let the hub know, so that it does not spread broken programs further.
+				hc.outcomes = append(hc.outcomes, rpctype.HubOutcome{
+					Sig:    hash.String(inp.Prog),
+					Failed: true,
+				})
+			}
 			dropped++
 			continue
 		}
@@ -222,7 +238,15 @@ func (hc *HubConnector) processProgs(inputs []rpctype.HubInput) (minimized, smas
 			Minimized: min,
 			Smashed:   smash,
 		})
+		// Fuzzers log programs in the canonical form, remember it to be able
+		// to match crash logs against programs received from hub.
+		p, err := hc.target.Deserialize(inp.Prog, prog.NonStrict)
+		if err != nil {
+			panic(fmt.Sprintf("failed to deserialize checked program: %v", err))
+		}
+		progs[hash.Hash(p.Serialize())] = hash.String(inp.Prog)
 	}
+	hc.mgr.addHubProgs(progs)
 	hc.mgr.addNewCandidates(candidates)
 	return
 }
@@ -258,7 +282,7 @@ func (hc *HubConnector) processRepros(repros [][]byte) int {
 	for _, repro := range repros {
 		bad, disabled := checkProgram(hc.target, hc.enabledCalls, repro)
 		if bad || disabled {
-			log.Logf(0, "rejecting repro from hub (bad=%v, disabled=%v):\n%s",
+			hubLog.Logf(0, "rejecting repro from hub (bad=%v, disabled=%v):\n%s",
 				bad, disabled, repro)
 			dropped++
 			continue
@@ -5,7 +5,15 @@ package main
 
 import (
 	"fmt"
+	"sync"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/pkg/hash"
+	"github.com/google/syzkaller/pkg/mgrconfig"
+	"github.com/google/syzkaller/pkg/rpctype"
+	"github.com/google/syzkaller/prog"
+	_ "github.com/google/syzkaller/sys/test/gen"
 )
 
 func TestMatchDomains(t *testing.T) {
@@ -48,3 +56,106 @@ func TestMatchDomains(t *testing.T) {
 		})
 	}
 }
+
+type testHub struct {
+	mu       sync.Mutex
+	inputs   []rpctype.HubInput
+	outcomes []rpctype.HubOutcome
+}
+
+func (hub *testHub) Sync(a *rpctype.HubSyncArgs, r *rpctype.HubSyncRes) error {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	hub.outcomes = append(hub.outcomes, a.Outcomes...)
+	r.Inputs = hub.inputs
+	hub.inputs = nil
+	return nil
+}
+
+func TestHubOutcomes(t *testing.T) {
+	target, err := prog.GetTarget("test", "64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad, good := []byte("foo$bar()"), []byte("test( )")
+	hub := &testHub{
+		inputs: []rpctype.HubInput{{Prog: bad}, {Prog: good}},
+	}
+	serv, err := rpctype.NewRPCServer("localhost:0", "Hub", hub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go serv.Serve()
+	client, err := rpctype.NewRPCClient(serv.Addr().String(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	mgr := &Manager{
+		target:   target,
+		hubProgs: make(map[hash.Sig]string),
+	}
+	hc := &HubConnector{
+		mgr:          mgr,
+		cfg:          &mgrconfig.Config{},
+		target:       target,
+		stats:        new(Stats),
+		enabledCalls: map[*prog.Syscall]bool{target.SyscallMap["test"]: true},
+		hubCorpus:    make(map[hash.Sig]bool),
+		keyGet:       func() (string, error) { return "", nil },
+	}
+	if err := hc.sync(client, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(mgr.candidates) != 1 {
+		t.Fatalf("got %v candidates, want 1", len(mgr.candidates))
+	}
+	// The good program crashes the kernel, note that it's logged in the canonical form.
+	mgr.addHubCrashOutcomes([]byte("executing program 0:\ntest()\n"))
+	if err := hc.sync(client, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []rpctype.HubOutcome{
+		{Sig: hash.String(bad), Failed: true},
+		{Sig: hash.String(good), Crashed: true},
+	}
+	if diff := cmp.Diff(want, hub.outcomes); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestHubProgsLimit(t *testing.T) {
+	mgr := &Manager{
+		hubProgs: make(map[hash.Sig]string),
+	}
+	sig := func(i int) hash.Sig {
+		return hash.Hash([]byte(fmt.Sprint(i)))
+	}
+	// Programs within a batch are added in random order, so evict whole batches.
+	const batch = 1000
+	total := maxHubProgs + 2*batch
+	for i := 0; i < total; i += batch {
+		progs := make(map[hash.Sig]string)
+		for j := i; j < i+batch && j < total; j++ {
+			progs[sig(j)] = fmt.Sprint(j)
+		}
+		// Programs received again must not be counted twice.
+		if i != 0 {
+			progs[sig(i-1)] = fmt.Sprint(i - 1)
+		}
+		mgr.addHubProgs(progs)
+	}
+	if len(mgr.hubProgs) != maxHubProgs || len(mgr.hubProgsQueue) != maxHubProgs {
+		t.Fatalf("got %v/%v hub programs, want %v", len(mgr.hubProgs), len(mgr.hubProgsQueue), maxHubProgs)
+	}
+	for i := 0; i < total; i++ {
+		hubSig, ok := mgr.hubProgs[sig(i)]
+		if want := i >= total-maxHubProgs; ok != want {
+			t.Fatalf("program %v: present=%v, want %v", i, ok, want)
+		}
+		if ok && hubSig != fmt.Sprint(i) {
+			t.Fatalf("program %v: got hub hash %v", i, hubSig)
+		}
+	}
+}
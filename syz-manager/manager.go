@@ -13,6 +13,7 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"sync"
 	"sync/atomic"
@@ -44,6 +45,14 @@ var (
 	flagBench  = flag.String("bench", "", "write execution statistics into this file periodically")
 )
 
+// Loggers of the manager modules, verbosity of each can be adjusted on /log-levels page.
+var (
+	loopLog = log.New("loop")
+	vmLog   = log.New("vm")
+	rpcLog  = log.New("rpc")
+	hubLog  = log.New("hub")
+)
+
 type Manager struct {
 	cfg            *mgrconfig.Config
 	vmPool         *vm.Pool
@@ -69,8 +78,12 @@ type Manager struct {
 	mu                    sync.Mutex
 	phase                 int
 	targetEnabledSyscalls map[*prog.Syscall]bool
+	disabledSyscalls      map[string]string // syscall name -> reason why it's disabled on the target
 
-	candidates       []rpctype.Candidate // untriaged inputs from corpus and hub
+	candidates       []rpctype.Candidate  // untriaged inputs from corpus and hub
+	hubProgs         map[hash.Sig]string  // canonical hash of programs from hub -> hub hash
+	hubProgsQueue    []hash.Sig           // keys of hubProgs in the order they were added
+	hubOutcomes      []rpctype.HubOutcome // pending outcomes of programs from hub
 	disabledHashes   map[string]struct{}
 	corpus           map[string]CorpusItem
 	seeds            [][]byte
@@ -79,6 +92,7 @@ type Manager struct {
 	memoryLeakFrames map[string]bool
 	dataRaceFrames   map[string]bool
 	saturatedCalls   map[string]bool
+	sweepProgs       [][]byte // corpus additions since the last sweep
 
 	needMoreRepros chan chan bool
 	hubReproQueue  chan *Crash
@@ -92,6 +106,9 @@ type Manager struct {
 	coverFilter        map[uint32]uint32
 	coverFilterBitmap  []byte
 	modulesInitialized bool
+
+	funcCover      *cover.FuncCover // lazily created by updateFuncCover
+	funcCoverAdded map[string]bool  // corpus inputs already added to funcCover
 }
 
 type CorpusItemUpdate struct {
@@ -138,6 +155,7 @@ type Crash struct {
 	hub     bool // this crash was created based on a repro from hub
 	*report.Report
 	machineInfo []byte
+	sweepProgs  []byte // programs replayed during the sweep that found the crash
 }
 
 func main() {
@@ -154,6 +172,14 @@ func main() {
 }
 
 func RunManager(cfg *mgrconfig.Config) {
+	if cfg.Cgroup != "" {
+		cg, err := osutil.NewCgroup(cfg.Cgroup, "syz-manager-"+cfg.Name, cfg.CgroupLimits)
+		if err != nil {
+			log.Fatalf("failed to create cgroup: %v", err)
+		}
+		osutil.ConfineCommands(cg)
+	}
+
 	var vmPool *vm.Pool
 	// Type "none" is a special case for debugging/development when manager
 	// does not start any VMs, but instead you start them manually
@@ -186,6 +212,7 @@ func RunManager(cfg *mgrconfig.Config) {
 		crashTypes:       make(map[string]bool),
 		corpus:           make(map[string]CorpusItem),
 		disabledHashes:   make(map[string]struct{}),
+		hubProgs:         make(map[hash.Sig]string),
 		memoryLeakFrames: make(map[string]bool),
 		dataRaceFrames:   make(map[string]bool),
 		fresh:            true,
@@ -199,7 +226,6 @@ func RunManager(cfg *mgrconfig.Config) {
 
 	mgr.preloadCorpus()
 	mgr.initStats() // Initializes prometheus variables.
-	mgr.initHTTP()  // Creates HTTP server.
 	mgr.collectUsedFiles()
 
 	// Create RPC server for fuzzers.
@@ -207,6 +233,7 @@ func RunManager(cfg *mgrconfig.Config) {
 	if err != nil {
 		log.Fatalf("failed to create rpc server: %v", err)
 	}
+	mgr.initHTTP() // Creates HTTP server, uses mgr.serv.
 
 	if cfg.DashboardAddr != "" {
 		mgr.dash, err = dashapi.New(cfg.DashboardClient, cfg.DashboardAddr, cfg.DashboardKey)
@@ -284,6 +311,10 @@ func RunManager(cfg *mgrconfig.Config) {
 		<-vm.Shutdown
 		return
 	}
+	// The session summary needs vmLoop to report reproduction status of crashes.
+	if cfg.SessionDuration != 0 {
+		go mgr.sessionLoop()
+	}
 	mgr.vmLoop()
 }
 
@@ -328,6 +359,12 @@ func (mgr *Manager) vmLoop() {
 	var reproQueue []*Crash
 	reproDone := make(chan *ReproResult, 1)
 	stopPending := false
+	var sweepTicker <-chan time.Time
+	if mgr.cfg.Sweep.Period > 0 {
+		sweepTicker = time.NewTicker(time.Duration(mgr.cfg.Sweep.Period) * time.Minute).C
+	}
+	sweepPending, sweepRunning := false, false
+	sweepDone := make(chan *RunResult, 1)
 	shutdown := vm.Shutdown
 	for shutdown != nil || len(instances) != vmCount {
 		mgr.mu.Lock()
@@ -342,12 +379,12 @@ func (mgr *Manager) vmLoop() {
 			if !mgr.needRepro(crash) {
 				continue
 			}
-			log.Logf(1, "loop: add to repro queue '%v'", crash.Title)
+			loopLog.Logf(1, "add to repro queue '%v'", crash.Title)
 			reproducing[crash.Title] = true
 			reproQueue = append(reproQueue, crash)
 		}
 
-		log.Logf(1, "loop: phase=%v shutdown=%v instances=%v/%v %+v repro: pending=%v reproducing=%v queued=%v",
+		loopLog.Logf(1, "phase=%v shutdown=%v instances=%v/%v %+v repro: pending=%v reproducing=%v queued=%v",
 			phase, shutdown == nil, len(instances), vmCount, instances,
 			len(pendingRepro), len(reproducing), len(reproQueue))
 
@@ -366,7 +403,7 @@ func (mgr *Manager) vmLoop() {
 				instances = instances[:len(instances)-instancesPerRepro]
 				reproInstances += instancesPerRepro
 				atomic.AddUint32(&mgr.numReproducing, 1)
-				log.Logf(1, "loop: starting repro of '%v' on instances %+v", crash.Title, vmIndexes)
+				loopLog.Logf(1, "starting repro of '%v' on instances %+v", crash.Title, vmIndexes)
 				go func() {
 					features := mgr.checkResult.Features
 					res, stats, err := repro.Run(crash.Output, mgr.cfg, features, mgr.reporter, mgr.vmPool, vmIndexes)
@@ -380,11 +417,25 @@ func (mgr *Manager) vmLoop() {
 					}
 				}()
 			}
+			if sweepPending && !sweepRunning && len(instances) != 0 {
+				sweepPending = false
+				if progs := mgr.takeSweepProgs(); len(progs) != 0 {
+					last := len(instances) - 1
+					idx := instances[last]
+					instances = instances[:last]
+					sweepRunning = true
+					loopLog.Logf(1, "starting sweep on instance %v", idx)
+					go func() {
+						crash, err := mgr.runSweep(idx, progs)
+						sweepDone <- &RunResult{idx, crash, err}
+					}()
+				}
+			}
 			for !canRepro() && len(instances) != 0 {
 				last := len(instances) - 1
 				idx := instances[last]
 				instances = instances[:last]
-				log.Logf(1, "loop: starting instance %v", idx)
+				loopLog.Logf(1, "starting instance %v", idx)
 				go func() {
 					crash, err := mgr.runInstance(idx)
 					runDone <- &RunResult{idx, crash, err}
@@ -393,7 +444,7 @@ func (mgr *Manager) vmLoop() {
 		}
 
 		var stopRequest chan bool
-		if !stopPending && canRepro() {
+		if !stopPending && (canRepro() || sweepPending && !sweepRunning) {
 			stopRequest = mgr.vmStop
 		}
 
@@ -402,12 +453,12 @@ func (mgr *Manager) vmLoop() {
 		case idx := <-bootInstance:
 			instances = append(instances, idx)
 		case stopRequest <- true:
-			log.Logf(1, "loop: issued stop request")
+			loopLog.Logf(1, "issued stop request")
 			stopPending = true
 		case res := <-runDone:
-			log.Logf(1, "loop: instance %v finished, crash=%v", res.idx, res.crash != nil)
+			loopLog.Logf(1, "instance %v finished, crash=%v", res.idx, res.crash != nil)
 			if res.err != nil && shutdown != nil {
-				log.Logf(0, "%v", res.err)
+				vmLog.With("vm", res.idx).Logf(0, "%v", res.err)
 			}
 			stopPending = false
 			instances = append(instances, res.idx)
@@ -416,7 +467,7 @@ func (mgr *Manager) vmLoop() {
 			if shutdown != nil && res.crash != nil {
 				needRepro := mgr.saveCrash(res.crash)
 				if needRepro {
-					log.Logf(1, "loop: add pending repro for '%v'", res.crash.Title)
+					loopLog.Logf(1, "add pending repro for '%v'", res.crash.Title)
 					pendingRepro[res.crash] = true
 				}
 			}
@@ -428,7 +479,7 @@ func (mgr *Manager) vmLoop() {
 				crepro = res.res.CRepro
 				title = res.res.Report.Title
 			}
-			log.Logf(1, "loop: repro on %+v finished '%v', repro=%v crepro=%v desc='%v'",
+			loopLog.Logf(1, "repro on %+v finished '%v', repro=%v crepro=%v desc='%v'",
 				res.instances, res.report0.Title, res.res != nil, crepro, title)
 			if res.err != nil {
 				log.Logf(0, "repro failed: %v", res.err)
@@ -443,11 +494,23 @@ func (mgr *Manager) vmLoop() {
 			} else {
 				mgr.saveRepro(res.res, res.stats, res.hub)
 			}
+		case <-sweepTicker:
+			sweepPending = shutdown != nil
+		case res := <-sweepDone:
+			loopLog.Logf(1, "sweep on instance %v finished, crash=%v", res.idx, res.crash != nil)
+			if res.err != nil && shutdown != nil {
+				vmLog.With("vm", res.idx).Logf(0, "sweep: %v", res.err)
+			}
+			sweepRunning = false
+			instances = append(instances, res.idx)
+			if shutdown != nil && res.crash != nil {
+				mgr.saveCrash(res.crash)
+			}
 		case <-shutdown:
-			log.Logf(1, "loop: shutting down...")
+			loopLog.Logf(1, "shutting down...")
 			shutdown = nil
 		case crash := <-mgr.hubReproQueue:
-			log.Logf(1, "loop: get repro from hub")
+			loopLog.Logf(1, "get repro from hub")
 			pendingRepro[crash] = true
 		case reply := <-mgr.needMoreRepros:
 			reply <- phase >= phaseTriagedHub &&
@@ -668,6 +731,11 @@ func (mgr *Manager) runInstanceInner(index int, instanceName string) (*report.Re
 		}
 	}
 
+	tlsFiles, err := instance.CopyTLSFiles(inst, mgr.serv.tlsClient)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	fuzzerV := 0
 	procs := mgr.cfg.Procs
 	if *flagDebug {
@@ -696,6 +764,9 @@ func (mgr *Manager) runInstanceInner(index int, instanceName string) (*report.Re
 		Runtest:   false,
 		Slowdown:  mgr.cfg.Timeouts.Slowdown,
 		RawCover:  mgr.cfg.RawCover,
+		// Keep the cache next to the fuzzer binary, it survives VM restarts if the image is persistent.
+		SyscallCache: path.Dir(fuzzerBin),
+		TLS:          tlsFiles,
 	}
 	cmd := instance.FuzzerCmd(args)
 	outc, errc, err := inst.Run(mgr.cfg.Timeouts.VMRunningTime, mgr.vmStop, cmd)
@@ -707,7 +778,7 @@ func (mgr *Manager) runInstanceInner(index int, instanceName string) (*report.Re
 	rep := inst.MonitorExecution(outc, errc, mgr.reporter, vm.ExitTimeout)
 	if rep == nil {
 		// This is the only "OK" outcome.
-		log.Logf(0, "%s: running for %v, restarting", instanceName, time.Since(start))
+		vmLog.With("vm", index).Logf(0, "running for %v, restarting", time.Since(start))
 	} else {
 		vmInfo, err = inst.Info()
 		if err != nil {
@@ -746,6 +817,7 @@ func (mgr *Manager) saveCrash(crash *Crash) bool {
 		mgr.mu.Lock()
 		mgr.dataRaceFrames[crash.Frame] = true
 		mgr.mu.Unlock()
+		mgr.serv.addRacyPairs(racyCallPairs(mgr.target, crash.Output))
 	}
 	flags := ""
 	if crash.Corrupted {
@@ -754,7 +826,7 @@ func (mgr *Manager) saveCrash(crash *Crash) bool {
 	if crash.Suppressed {
 		flags += " [suppressed]"
 	}
-	log.Logf(0, "vm-%v: crash: %v%v", crash.vmIndex, crash.Title, flags)
+	vmLog.With("vm", crash.vmIndex).Logf(0, "crash: %v%v", crash.Title, flags)
 
 	if crash.Suppressed {
 		// Collect all of them into a single bucket so that it's possible to control and assess them,
@@ -764,6 +836,9 @@ func (mgr *Manager) saveCrash(crash *Crash) bool {
 	}
 
 	mgr.stats.crashes.inc()
+	if !crash.hub {
+		mgr.addHubCrashOutcomes(crash.Output)
+	}
 	mgr.mu.Lock()
 	if !mgr.crashTypes[crash.Title] {
 		mgr.crashTypes[crash.Title] = true
@@ -835,6 +910,12 @@ func (mgr *Manager) saveCrash(crash *Crash) bool {
 	writeOrRemove("tag", []byte(mgr.cfg.Tag))
 	writeOrRemove("report", crash.Report.Report)
 	writeOrRemove("machineInfo", crash.machineInfo)
+	writeOrRemove("progs", crash.sweepProgs)
+	if crash.sweepProgs != nil {
+		// The console log of a sweep does not contain the programs, so it can't be reproduced
+		// the usual way (progsN can be passed to syz-repro).
+		return false
+	}
 	return mgr.needLocalRepro(crash)
 }
 
@@ -1024,6 +1105,61 @@ func (mgr *Manager) addNewCandidates(candidates []rpctype.Candidate) {
 	}
 }
 
+// maxHubProgs limits the number of programs from hub that crashes are attributed to.
+// The manager does not know when a candidate is triaged, but hub sends only a few thousand
+// programs per sync and fuzzers triage them long before that many new programs arrive,
+// so only the most recent programs can be executing at the time of a crash.
+const maxHubProgs = 10000
+
+func (mgr *Manager) addHubProgs(progs map[hash.Sig]string) {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	for sig, hubSig := range progs {
+		if _, ok := mgr.hubProgs[sig]; !ok {
+			mgr.hubProgsQueue = append(mgr.hubProgsQueue, sig)
+		}
+		mgr.hubProgs[sig] = hubSig
+	}
+	for len(mgr.hubProgsQueue) > maxHubProgs {
+		delete(mgr.hubProgs, mgr.hubProgsQueue[0])
+		mgr.hubProgsQueue = mgr.hubProgsQueue[1:]
+	}
+}
+
+func (mgr *Manager) takeHubOutcomes() []rpctype.HubOutcome {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	outcomes := mgr.hubOutcomes
+	mgr.hubOutcomes = nil
+	return outcomes
+}
+
+// addHubCrashOutcomes attributes the crash to programs received from hub,
+// if any of them were executing at the time of the crash.
+func (mgr *Manager) addHubCrashOutcomes(output []byte) {
+	mgr.mu.Lock()
+	empty := len(mgr.hubProgs) == 0
+	mgr.mu.Unlock()
+	if empty {
+		return
+	}
+	// The last program of each proc is the one that was executing when the kernel crashed.
+	last := make(map[int]*prog.LogEntry)
+	for _, ent := range mgr.target.ParseLog(output) {
+		last[ent.Proc] = ent
+	}
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	for _, ent := range last {
+		if hubSig, ok := mgr.hubProgs[hash.Hash(ent.P.Serialize())]; ok {
+			mgr.hubOutcomes = append(mgr.hubOutcomes, rpctype.HubOutcome{
+				Sig:     hubSig,
+				Crashed: true,
+			})
+		}
+	}
+}
+
 func (mgr *Manager) minimizeCorpus() {
 	if mgr.phase < phaseLoadedCorpus || len(mgr.corpus) <= mgr.lastMinCorpus*103/100 {
 		return
@@ -1160,6 +1296,10 @@ func (mgr *Manager) machineChecked(a *rpctype.CheckArgs, enabledSyscalls map[*pr
 	defer mgr.mu.Unlock()
 	mgr.checkResult = a
 	mgr.targetEnabledSyscalls = enabledSyscalls
+	mgr.disabledSyscalls = make(map[string]string)
+	for _, dc := range a.DisabledCalls[mgr.cfg.Sandbox] {
+		mgr.disabledSyscalls[mgr.target.Syscalls[dc.ID].Name] = dc.Reason
+	}
 	mgr.target.UpdateGlobs(a.GlobFiles)
 	mgr.loadCorpus()
 	mgr.firstConnect = time.Now()
@@ -1202,6 +1342,7 @@ func (mgr *Manager) newInput(inp rpctype.Input, sign signal.Signal) bool {
 		if err := mgr.corpusDB.Flush(); err != nil {
 			log.Logf(0, "failed to save corpus database: %v", err)
 		}
+		mgr.addSweepProg(inp.Prog)
 	}
 	return true
 }
@@ -1285,8 +1426,16 @@ func (mgr *Manager) dashboardReporter() {
 	webAddr := publicWebAddr(mgr.cfg.HTTP)
 	var lastFuzzingTime time.Duration
 	var lastCrashes, lastSuppressedCrashes, lastExecs uint64
+	var lastSubsystemCover time.Time
 	for {
 		time.Sleep(time.Minute)
+		// Per-subsystem coverage is expensive to compute, so it's uploaded less frequently.
+		var subsystemPCs map[string]uint64
+		if time.Since(lastSubsystemCover) > subsystemCoverPeriod {
+			if subsystemPCs = mgr.subsystemCover(); subsystemPCs != nil {
+				lastSubsystemCover = time.Now()
+			}
+		}
 		mgr.mu.Lock()
 		if mgr.firstConnect.IsZero() {
 			mgr.mu.Unlock()
@@ -1307,6 +1456,7 @@ func (mgr *Manager) dashboardReporter() {
 			Crashes:           crashes - lastCrashes,
 			SuppressedCrashes: suppressedCrashes - lastSuppressedCrashes,
 			Execs:             execs - lastExecs,
+			SubsystemPCs:      subsystemPCs,
 		}
 		mgr.mu.Unlock()
 
@@ -0,0 +1,158 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/pkg/profile"
+	"github.com/google/syzkaller/pkg/rpctype"
+)
+
+// Fuzzers don't have their own http servers, so profiles are proxied through the manager:
+// the request is sent to the fuzzer in the next Poll response and the fuzzer
+// sends the profile back with ProfileResult.
+
+func (serv *RPCServer) ProfileResult(a *rpctype.ProfileResultArgs, r *int) error {
+	serv.mu.Lock()
+	res := serv.profiles[a.ID]
+	delete(serv.profiles, a.ID)
+	serv.mu.Unlock()
+	if res != nil {
+		res <- a
+	}
+	return nil
+}
+
+func (serv *RPCServer) requestProfile(name, kind string, duration time.Duration) ([]byte, error) {
+	serv.mu.Lock()
+	f := serv.fuzzers[name]
+	if f == nil {
+		serv.mu.Unlock()
+		return nil, fmt.Errorf("fuzzer %v is not connected", name)
+	}
+	if f.profile != nil {
+		serv.mu.Unlock()
+		return nil, fmt.Errorf("fuzzer %v has a pending profile request", name)
+	}
+	serv.lastProfileID++
+	id := serv.lastProfileID
+	f.profile = &rpctype.ProfileRequest{
+		ID:      id,
+		Kind:    kind,
+		Seconds: int(duration / time.Second),
+	}
+	res := make(chan *rpctype.ProfileResultArgs, 1)
+	serv.profiles[id] = res
+	serv.mu.Unlock()
+
+	// The fuzzer polls every 10 seconds, plus it may be slow to send a large profile.
+	timeout := time.NewTimer(duration + time.Minute*serv.cfg.Timeouts.Scale)
+	defer timeout.Stop()
+	select {
+	case a := <-res:
+		if a.Error != "" {
+			return nil, errors.New(a.Error)
+		}
+		return a.Data, nil
+	case <-timeout.C:
+		serv.mu.Lock()
+		delete(serv.profiles, id)
+		if f.profile != nil && f.profile.ID == id {
+			f.profile = nil
+		}
+		serv.mu.Unlock()
+		return nil, fmt.Errorf("fuzzer %v did not send the profile in time", name)
+	}
+}
+
+func (serv *RPCServer) fuzzerNames() []string {
+	serv.mu.Lock()
+	defer serv.mu.Unlock()
+	var names []string
+	for name := range serv.fuzzers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (mgr *Manager) httpFuzzerProfile(w http.ResponseWriter, r *http.Request) {
+	duration, err := profile.ParseDuration(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	vm, kind := r.FormValue("vm"), r.FormValue("kind")
+	data, err := mgr.serv.requestProfile(vm, kind, duration)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	name := fmt.Sprintf("%v-%v.pprof", vm, kind)
+	switch kind {
+	case profile.KindTrace:
+		name = fmt.Sprintf("%v-trace.out", vm)
+	case profile.KindBundle:
+		name = fmt.Sprintf("%v-profile.zip", vm)
+	}
+	profile.ServeFile(w, name, data)
+}
+
+// httpProfileBundle captures profiles of the manager and, optionally, of one of the fuzzers
+// concurrently and serves them as a single zip archive.
+func (mgr *Manager) httpProfileBundle(w http.ResponseWriter, r *http.Request) {
+	duration, err := profile.ParseDuration(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	vm := r.FormValue("vm")
+	type fuzzerResult struct {
+		files []profile.File
+		err   error
+	}
+	fuzzerRes := make(chan fuzzerResult, 1)
+	if vm != "" {
+		go func() {
+			data, err := mgr.serv.requestProfile(vm, profile.KindBundle, duration)
+			if err != nil {
+				fuzzerRes <- fuzzerResult{err: err}
+				return
+			}
+			files, err := profile.ReadZip(data)
+			fuzzerRes <- fuzzerResult{files, err}
+		}()
+	}
+	files, err := profile.Bundle(duration)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for i := range files {
+		files[i].Name = "manager/" + files[i].Name
+	}
+	if vm != "" {
+		res := <-fuzzerRes
+		if res.err != nil {
+			http.Error(w, fmt.Sprintf("failed to get profile from %v: %v", vm, res.err),
+				http.StatusInternalServerError)
+			return
+		}
+		for _, f := range res.files {
+			files = append(files, profile.File{Name: vm + "/" + f.Name, Data: f.Data})
+		}
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q",
+		fmt.Sprintf("%v-profile-%v.zip", mgr.cfg.Name, time.Now().Format("20060102-150405"))))
+	if err := profile.WriteZip(w, files); err != nil {
+		log.Logf(0, "failed to send profile bundle: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sort"
+
+	"github.com/google/syzkaller/pkg/rpctype"
+	"github.com/google/syzkaller/prog"
+)
+
+// Fuzzers pair calls that are likely to race and execute them concurrently (see syz-fuzzer/race.go).
+// When a KCSAN report happens, we take the last program executed by each proc before the report
+// and send all pairs of calls of these programs to fuzzers, so that they race these pairs more often.
+
+// racyCallPairs returns pairs of calls executed right before the report in the console output.
+func racyCallPairs(target *prog.Target, output []byte) [][2]string {
+	last := make(map[int]*prog.Prog)
+	for _, ent := range target.ParseLog(output) {
+		last[ent.Proc] = ent.P
+	}
+	names := make(map[string]bool)
+	for _, p := range last {
+		for _, c := range p.Calls {
+			names[c.Meta.Name] = true
+		}
+	}
+	var calls []string
+	for name := range names {
+		calls = append(calls, name)
+	}
+	sort.Strings(calls)
+	var pairs [][2]string
+	for i, call0 := range calls {
+		// A call can race with another instance of itself.
+		for _, call1 := range calls[i:] {
+			pairs = append(pairs, [2]string{call0, call1})
+		}
+	}
+	return pairs
+}
+
+func (serv *RPCServer) addRacyPairs(pairs [][2]string) {
+	if len(pairs) == 0 {
+		return
+	}
+	serv.mu.Lock()
+	defer serv.mu.Unlock()
+	if serv.racyPairs == nil {
+		serv.racyPairs = make(map[[2]string]int)
+	}
+	for _, pair := range pairs {
+		serv.racyPairs[pair]++
+	}
+	for _, f := range serv.fuzzers {
+		f.newRacyPairs = true
+	}
+}
+
+func (serv *RPCServer) racyPairList() []rpctype.RacyPair {
+	var res []rpctype.RacyPair
+	for pair, reports := range serv.racyPairs {
+		res = append(res, rpctype.RacyPair{Call0: pair[0], Call1: pair[1], Reports: reports})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Call0 != res[j].Call0 {
+			return res[i].Call0 < res[j].Call0
+		}
+		return res[i].Call1 < res[j].Call1
+	})
+	return res
+}
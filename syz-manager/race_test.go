@@ -0,0 +1,53 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/pkg/rpctype"
+	"github.com/google/syzkaller/prog"
+	_ "github.com/google/syzkaller/sys"
+)
+
+func TestRacyCallPairs(t *testing.T) {
+	target, err := prog.GetTarget("test", "64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := []byte(`
+executing program 0:
+test$res0()
+executing program 1:
+minimize$0(0x1, 0x1)
+executing program 0:
+breaks_returns()
+test$res0()
+BUG: KCSAN: data-race in foo / bar
+`)
+	pairs := racyCallPairs(target, output)
+	want := [][2]string{
+		{"breaks_returns", "breaks_returns"},
+		{"breaks_returns", "minimize$0"},
+		{"breaks_returns", "test$res0"},
+		{"minimize$0", "minimize$0"},
+		{"minimize$0", "test$res0"},
+		{"test$res0", "test$res0"},
+	}
+	if diff := cmp.Diff(want, pairs); diff != "" {
+		t.Fatalf("bad pairs (-want +got):\n%s", diff)
+	}
+
+	serv := &RPCServer{fuzzers: map[string]*Fuzzer{"vm-0": {}}}
+	serv.addRacyPairs(pairs)
+	serv.addRacyPairs(pairs[:1])
+	if !serv.fuzzers["vm-0"].newRacyPairs {
+		t.Fatalf("fuzzer is not notified about new pairs")
+	}
+	list := serv.racyPairList()
+	if len(list) != len(want) || list[0] != (rpctype.RacyPair{Call0: "breaks_returns", Call1: "breaks_returns", Reports: 2}) {
+		t.Fatalf("bad pair list: %+v", list)
+	}
+}
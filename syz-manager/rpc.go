@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"math/rand"
 	"net"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/google/syzkaller/pkg/cover"
+	"github.com/google/syzkaller/pkg/hash"
 	"github.com/google/syzkaller/pkg/host"
 	"github.com/google/syzkaller/pkg/log"
 	"github.com/google/syzkaller/pkg/mgrconfig"
@@ -28,6 +30,7 @@ type RPCServer struct {
 	coverFilter           map[uint32]uint32
 	stats                 *Stats
 	batchSize             int
+	tlsClient             *rpctype.TLSFiles
 
 	mu            sync.Mutex
 	fuzzers       map[string]*Fuzzer
@@ -38,6 +41,10 @@ type RPCServer struct {
 	rotator       *prog.Rotator
 	rnd           *rand.Rand
 	checkFailures int
+	lastProfileID int64
+	profiles      map[int64]chan *rpctype.ProfileResultArgs
+	// racyPairs counts KCSAN reports preceded by pairs of calls (see race.go).
+	racyPairs map[[2]string]int
 }
 
 type Fuzzer struct {
@@ -47,6 +54,8 @@ type Fuzzer struct {
 	newMaxSignal  signal.Signal
 	rotatedSignal signal.Signal
 	machineInfo   []byte
+	profile       *rpctype.ProfileRequest
+	newRacyPairs  bool
 }
 
 type BugFrames struct {
@@ -66,11 +75,12 @@ type RPCManagerView interface {
 
 func startRPCServer(mgr *Manager) (*RPCServer, error) {
 	serv := &RPCServer{
-		mgr:     mgr,
-		cfg:     mgr.cfg,
-		stats:   mgr.stats,
-		fuzzers: make(map[string]*Fuzzer),
-		rnd:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		mgr:      mgr,
+		cfg:      mgr.cfg,
+		stats:    mgr.stats,
+		fuzzers:  make(map[string]*Fuzzer),
+		profiles: make(map[int64]chan *rpctype.ProfileResultArgs),
+		rnd:      rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 	serv.batchSize = 5
 	if serv.batchSize < mgr.cfg.Procs {
@@ -80,6 +90,18 @@ func startRPCServer(mgr *Manager) (*RPCServer, error) {
 	if err != nil {
 		return nil, err
 	}
+	if mgr.cfg.RPCTLS {
+		tlsServer, tlsClient, err := rpctype.GenerateTLS(filepath.Join(mgr.cfg.Workdir, "tls"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate tls certificates: %v", err)
+		}
+		tlsCfg, err := tlsServer.ServerConfig()
+		if err != nil {
+			return nil, err
+		}
+		s.SetTLS(tlsCfg)
+		serv.tlsClient = tlsClient
+	}
 	log.Logf(0, "serving rpc on tcp://%v", s.Addr())
 	serv.port = s.Addr().(*net.TCPAddr).Port
 	go s.Serve()
@@ -87,7 +109,7 @@ func startRPCServer(mgr *Manager) (*RPCServer, error) {
 }
 
 func (serv *RPCServer) Connect(a *rpctype.ConnectArgs, r *rpctype.ConnectRes) error {
-	log.Logf(1, "fuzzer %v connected", a.Name)
+	rpcLog.With("fuzzer", a.Name).Logf(1, "connected")
 	serv.stats.vmRestarts.inc()
 
 	corpus, bugFrames, coverFilter, coverBitmap, err := serv.mgr.fuzzerConnect(a.Modules)
@@ -101,8 +123,9 @@ func (serv *RPCServer) Connect(a *rpctype.ConnectArgs, r *rpctype.ConnectRes) er
 	defer serv.mu.Unlock()
 
 	f := &Fuzzer{
-		name:        a.Name,
-		machineInfo: a.MachineInfo,
+		name:         a.Name,
+		machineInfo:  a.MachineInfo,
+		newRacyPairs: len(serv.racyPairs) != 0,
 	}
 	serv.fuzzers[a.Name] = f
 	r.MemoryLeakFrames = bugFrames.memoryLeaks
@@ -248,11 +271,12 @@ func (serv *RPCServer) Check(a *rpctype.CheckArgs, r *int) error {
 
 func (serv *RPCServer) NewInput(a *rpctype.NewInputArgs, r *int) error {
 	inputSignal := a.Signal.Deserialize()
-	log.Logf(4, "new input from %v for syscall %v (signal=%v, cover=%v)",
-		a.Name, a.Call, inputSignal.Len(), len(a.Cover))
+	inputLog := rpcLog.With("fuzzer", a.Name).With("prog", hash.String(a.Input.Prog))
+	inputLog.Logf(4, "new input for syscall %v (signal=%v, cover=%v)",
+		a.Call, inputSignal.Len(), len(a.Cover))
 	bad, disabled := checkProgram(serv.cfg.Target, serv.targetEnabledSyscalls, a.Input.Prog)
 	if bad || disabled {
-		log.Logf(0, "rejecting program from fuzzer (bad=%v, disabled=%v):\n%s", bad, disabled, a.Input.Prog)
+		inputLog.Logf(0, "rejecting program (bad=%v, disabled=%v):\n%s", bad, disabled, a.Input.Prog)
 		return nil
 	}
 	serv.mu.Lock()
@@ -323,7 +347,7 @@ func (serv *RPCServer) Poll(a *rpctype.PollArgs, r *rpctype.PollRes) error {
 	if f == nil {
 		// This is possible if we called shutdownInstance,
 		// but already have a pending request from this instance in-flight.
-		log.Logf(1, "poll: fuzzer %v is not connected", a.Name)
+		rpcLog.With("fuzzer", a.Name).Logf(1, "poll: fuzzer is not connected")
 		return nil
 	}
 	newMaxSignal := serv.maxSignal.Diff(a.MaxSignal.Deserialize())
@@ -337,6 +361,12 @@ func (serv *RPCServer) Poll(a *rpctype.PollArgs, r *rpctype.PollRes) error {
 			f1.newMaxSignal.Merge(newMaxSignal)
 		}
 	}
+	r.Profile = f.profile
+	f.profile = nil
+	if f.newRacyPairs {
+		r.RacyPairs = serv.racyPairList()
+		f.newRacyPairs = false
+	}
 	if f.rotated {
 		// Let rotated VMs run in isolation, don't send them anything.
 		return nil
@@ -365,8 +395,8 @@ func (serv *RPCServer) Poll(a *rpctype.PollArgs, r *rpctype.PollRes) error {
 			f.inputs = nil
 		}
 	}
-	log.Logf(4, "poll from %v: candidates=%v inputs=%v maxsignal=%v",
-		a.Name, len(r.Candidates), len(r.NewInputs), len(r.MaxSignal.Elems))
+	rpcLog.With("fuzzer", a.Name).Logf(4, "poll: candidates=%v inputs=%v maxsignal=%v",
+		len(r.Candidates), len(r.NewInputs), len(r.MaxSignal.Elems))
 	return nil
 }
 
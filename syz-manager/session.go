@@ -0,0 +1,297 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/syzkaller/pkg/html"
+	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/prog"
+	"github.com/google/syzkaller/vm"
+)
+
+// Time-boxed sessions (see SessionDuration in mgrconfig) fuzz for a fixed time and then
+// write a self-contained HTML summary (no external resources, so it can be archived or
+// attached to a release) and shut the manager down.
+
+const sessionSamplePeriod = time.Minute
+
+type sessionSample struct {
+	Time    time.Duration // since the manager start
+	Execs   uint64
+	Corpus  uint64
+	Cover   uint64
+	Crashes uint64
+}
+
+func (mgr *Manager) sessionLoop() {
+	end := mgr.startTime.Add(time.Duration(mgr.cfg.SessionDuration) * time.Minute)
+	log.Logf(0, "fuzzing session ends at %v", end.Format(time.RFC3339))
+	var samples []sessionSample
+	ticker := time.NewTicker(sessionSamplePeriod)
+	defer ticker.Stop()
+	deadline := time.NewTimer(time.Until(end))
+	defer deadline.Stop()
+	for done := false; !done; {
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			done = true
+		case <-vm.Shutdown:
+			return
+		}
+		samples = append(samples, mgr.sessionSample())
+	}
+	file := filepath.Join(mgr.cfg.Workdir, "session-"+mgr.startTime.Format("20060102-150405")+".html")
+	if err := mgr.writeSessionSummary(file, samples); err != nil {
+		log.Logf(0, "failed to write session summary: %v", err)
+	} else {
+		log.Logf(0, "session summary saved to %v", file)
+	}
+	log.Logf(0, "fuzzing session is finished, shutting down...")
+	// Go through the normal interrupt handling to stop all VMs gracefully.
+	proc, err := os.FindProcess(os.Getpid())
+	if err == nil {
+		err = proc.Signal(os.Interrupt)
+	}
+	if err != nil {
+		log.Fatalf("failed to stop the session: %v", err)
+	}
+}
+
+func (mgr *Manager) sessionSample() sessionSample {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	return sessionSample{
+		Time:    time.Since(mgr.startTime),
+		Execs:   mgr.stats.execTotal.get(),
+		Corpus:  uint64(len(mgr.corpus)),
+		Cover:   mgr.stats.corpusCover.get(),
+		Crashes: mgr.stats.crashes.get(),
+	}
+}
+
+func (mgr *Manager) writeSessionSummary(file string, samples []sessionSample) error {
+	data := &UISessionData{
+		Name:     mgr.cfg.Name,
+		Start:    mgr.startTime,
+		Duration: time.Since(mgr.startTime) / time.Second * time.Second,
+		Graphs:   sessionGraphs(samples),
+	}
+	// Links point to the manager web UI that is not available after the session.
+	for _, stat := range mgr.collectStats() {
+		stat.Link = ""
+		data.Stats = append(data.Stats, stat)
+	}
+	crashes, err := mgr.collectCrashes(mgr.cfg.Workdir)
+	if err != nil {
+		return fmt.Errorf("failed to collect crashes: %v", err)
+	}
+	for _, crash := range crashes {
+		if crash.Active {
+			data.Crashes = append(data.Crashes, crash)
+		}
+	}
+	for name, pcs := range mgr.subsystemCover() {
+		data.Subsystems = append(data.Subsystems, UIStat{Name: name, Value: fmt.Sprint(pcs)})
+	}
+	sort.Slice(data.Subsystems, func(i, j int) bool {
+		return data.Subsystems[i].Name < data.Subsystems[j].Name
+	})
+	data.Provenance = []UIStat{
+		{Name: "syzkaller revision", Value: prog.GitRevision},
+		{Name: "target", Value: mgr.cfg.RawTarget},
+		{Name: "tag", Value: mgr.cfg.Tag},
+		{Name: "kernel obj", Value: mgr.cfg.KernelObj},
+		{Name: "image", Value: mgr.cfg.Image},
+	}
+	if vmlinux, err := os.Stat(filepath.Join(mgr.cfg.KernelObj, mgr.sysTarget.KernelObject)); err == nil {
+		data.Provenance = append(data.Provenance, UIStat{
+			Name:  "kernel build time",
+			Value: vmlinux.ModTime().Format(time.RFC3339),
+		})
+	}
+	if data.Config, err = sessionConfig(mgr.cfg); err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	if err := sessionTemplate.Execute(buf, data); err != nil {
+		return err
+	}
+	return osutil.WriteFile(file, buf.Bytes())
+}
+
+// sessionConfig returns the manager config with secrets removed.
+func sessionConfig(cfg interface{}) (string, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", err
+	}
+	for _, key := range []string{"hub_key", "dashboard_key"} {
+		if _, ok := fields[key]; ok {
+			fields[key] = "<redacted>"
+		}
+	}
+	data, err = json.MarshalIndent(fields, "", "\t")
+	return string(data), err
+}
+
+func sessionGraphs(samples []sessionSample) []UISessionGraph {
+	var times, execs, corpus, cover, crashes []float64
+	for i, s := range samples {
+		rate := 0.0
+		if i != 0 {
+			prev := samples[i-1]
+			if secs := (s.Time - prev.Time).Seconds(); secs > 0 {
+				rate = float64(s.Execs-prev.Execs) / secs
+			}
+		}
+		times = append(times, s.Time.Minutes())
+		execs = append(execs, rate)
+		corpus = append(corpus, float64(s.Corpus))
+		cover = append(cover, float64(s.Cover))
+		crashes = append(crashes, float64(s.Crashes))
+	}
+	return []UISessionGraph{
+		{"executions per second", svgGraph(times, execs)},
+		{"corpus", svgGraph(times, corpus)},
+		{"coverage", svgGraph(times, cover)},
+		{"crashes", svgGraph(times, crashes)},
+	}
+}
+
+// svgGraph renders an inline SVG line graph, xs are minutes since the manager start.
+func svgGraph(xs, ys []float64) template.HTML {
+	const width, height, margin = 600, 150, 20
+	if len(xs) < 2 {
+		return "not enough data"
+	}
+	maxX, maxY := xs[len(xs)-1], 1.0
+	for _, y := range ys {
+		if maxY < y {
+			maxY = y
+		}
+	}
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%v" height="%v">`,
+		width+2*margin, height+2*margin)
+	fmt.Fprintf(buf, `<rect x="%v" y="%v" width="%v" height="%v" fill="none" stroke="#ccc"/>`,
+		margin, margin, width, height)
+	fmt.Fprintf(buf, `<polyline fill="none" stroke="#4285f4" stroke-width="2" points="`)
+	for i := range xs {
+		fmt.Fprintf(buf, "%.1f,%.1f ", margin+xs[i]/maxX*width, margin+height-ys[i]/maxY*height)
+	}
+	fmt.Fprintf(buf, `"/>`)
+	fmt.Fprintf(buf, `<text x="%v" y="%v" font-size="11">%.0f</text>`, margin, margin-5, maxY)
+	fmt.Fprintf(buf, `<text x="%v" y="%v" font-size="11" text-anchor="end">%.0f min</text>`,
+		margin+width, 2*margin+height-5, maxX)
+	fmt.Fprintf(buf, `</svg>`)
+	return template.HTML(buf.String())
+}
+
+type UISessionData struct {
+	Name       string
+	Start      time.Time
+	Duration   time.Duration
+	Stats      []UIStat
+	Subsystems []UIStat
+	Crashes    []*UICrashType
+	Graphs     []UISessionGraph
+	Provenance []UIStat
+	Config     string
+}
+
+type UISessionGraph struct {
+	Title string
+	SVG   template.HTML
+}
+
+var sessionTemplate = html.CreatePage(`
+<!doctype html>
+<html>
+<head>
+	<title>{{.Name}} syzkaller session summary</title>
+	{{HEAD}}
+</head>
+<body>
+<b>{{.Name}} syzkaller session summary</b>: {{formatTime .Start}}, {{.Duration}}
+<br>
+
+<table class="list_table">
+	<caption>Stats:</caption>
+	{{range $s := $.Stats}}
+	<tr>
+		<td class="stat_name">{{$s.Name}}</td>
+		<td class="stat_value">{{$s.Value}}</td>
+	</tr>
+	{{end}}
+</table>
+
+{{if .Subsystems}}
+<table class="list_table">
+	<caption>Subsystem coverage (PCs):</caption>
+	{{range $s := $.Subsystems}}
+	<tr>
+		<td class="stat_name">{{$s.Name}}</td>
+		<td class="stat_value">{{$s.Value}}</td>
+	</tr>
+	{{end}}
+</table>
+{{end}}
+
+<table class="list_table">
+	<caption>Crashes:</caption>
+	<tr>
+		<th>Description</th>
+		<th>Count</th>
+		<th>Last Time</th>
+		<th>Repro</th>
+	</tr>
+	{{range $c := $.Crashes}}
+	<tr>
+		<td class="title">{{$c.Description}}</td>
+		<td class="stat">{{$c.Count}}</td>
+		<td class="time">{{formatTime $c.LastTime}}</td>
+		<td>{{$c.Triaged}}</td>
+	</tr>
+	{{end}}
+</table>
+
+{{range $g := $.Graphs}}
+<b>{{$g.Title}}:</b>
+<br>
+{{$g.SVG}}
+<br>
+{{end}}
+
+<table class="list_table">
+	<caption>Provenance:</caption>
+	{{range $s := $.Provenance}}
+	<tr>
+		<td class="stat_name">{{$s.Name}}</td>
+		<td class="stat_value">{{$s.Value}}</td>
+	</tr>
+	{{end}}
+</table>
+
+<b>Config:</b>
+<br>
+<textarea readonly rows="20" cols="100" wrap=off>
+{{.Config}}
+</textarea>
+</body></html>
+`)
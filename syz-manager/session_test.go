@@ -0,0 +1,50 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/syzkaller/pkg/mgrconfig"
+)
+
+func TestSessionConfig(t *testing.T) {
+	cfg := &mgrconfig.Config{
+		Name:         "test-manager",
+		HubKey:       "hub-secret",
+		DashboardKey: "dashboard-secret",
+	}
+	data, err := sessionConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(data, "secret") {
+		t.Fatalf("config contains secrets:\n%v", data)
+	}
+	if !strings.Contains(data, "test-manager") {
+		t.Fatalf("config does not contain manager name:\n%v", data)
+	}
+}
+
+func TestSessionGraphs(t *testing.T) {
+	samples := []sessionSample{
+		{Time: time.Minute, Execs: 600, Corpus: 10},
+		{Time: 2 * time.Minute, Execs: 1800, Corpus: 20},
+		{Time: 3 * time.Minute, Execs: 1800, Corpus: 25},
+	}
+	graphs := sessionGraphs(samples)
+	if len(graphs) == 0 || graphs[0].Title != "executions per second" {
+		t.Fatalf("bad graphs: %+v", graphs)
+	}
+	// The max rate is 20 execs/sec in the second minute.
+	if svg := string(graphs[0].SVG); !strings.Contains(svg, ">20</text>") ||
+		strings.Count(svg, ",") != len(samples) {
+		t.Fatalf("bad execs graph:\n%v", svg)
+	}
+	if graphs := sessionGraphs(samples[:1]); graphs[0].SVG != "not enough data" {
+		t.Fatalf("graph for a single sample: %v", graphs[0].SVG)
+	}
+}
@@ -32,6 +32,7 @@ type Stats struct {
 	corpusCoverFiltered Stat
 	corpusSignal        Stat
 	maxSignal           Stat
+	sweeps              Stat
 
 	mu         sync.Mutex
 	namedStats map[string]uint64
@@ -74,6 +75,9 @@ func (stats *Stats) all() map[string]uint64 {
 		"signal":            stats.corpusSignal.get(),
 		"max signal":        stats.maxSignal.get(),
 	}
+	if sweeps := stats.sweeps.get(); sweeps != 0 {
+		m["sweeps"] = sweeps
+	}
 	if stats.haveHub {
 		m["hub: send prog add"] = stats.hubSendProgAdd.get()
 		m["hub: send prog del"] = stats.hubSendProgDel.get()
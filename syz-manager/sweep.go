@@ -0,0 +1,108 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/syzkaller/pkg/instance"
+	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/vm"
+)
+
+// Sweeps periodically take a VM off fuzzing to replay recent corpus additions without
+// any other load and then optionally scan for memory leaks (see Sweep in mgrconfig).
+// Bugs found during a sweep are saved as crashes with the replayed programs in progsN files,
+// these files are in the console log format and can be passed to syz-repro.
+
+// addSweepProg remembers a new corpus program for the next sweep, mgr.mu must be held.
+func (mgr *Manager) addSweepProg(data []byte) {
+	if mgr.cfg.Sweep.Period == 0 {
+		return
+	}
+	mgr.sweepProgs = append(mgr.sweepProgs, data)
+	if extra := len(mgr.sweepProgs) - mgr.cfg.Sweep.Programs; extra > 0 {
+		mgr.sweepProgs = append([][]byte{}, mgr.sweepProgs[extra:]...)
+	}
+}
+
+// takeSweepProgs returns programs for the next sweep in the console log format,
+// or nil if no programs were added since the previous sweep.
+func (mgr *Manager) takeSweepProgs() []byte {
+	mgr.mu.Lock()
+	progs := mgr.sweepProgs
+	mgr.sweepProgs = nil
+	mgr.mu.Unlock()
+	buf := new(bytes.Buffer)
+	for _, data := range progs {
+		fmt.Fprintf(buf, "executing program 0:\n%s\n", data)
+	}
+	return buf.Bytes()
+}
+
+func (mgr *Manager) runSweep(index int, progs []byte) (*Crash, error) {
+	mgr.checkUsedFiles()
+	inst, err := mgr.vmPool.Create(index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create instance: %v", err)
+	}
+	defer inst.Close()
+
+	execprogBin, err := inst.Copy(mgr.cfg.ExecprogBin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy binary: %v", err)
+	}
+	executorBin := mgr.sysTarget.ExecutorBin
+	if executorBin == "" {
+		executorBin, err = inst.Copy(mgr.cfg.ExecutorBin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy binary: %v", err)
+		}
+	}
+	progFile := filepath.Join(mgr.cfg.Workdir, fmt.Sprintf("sweep-%v", index))
+	if err := osutil.WriteFile(progFile, progs); err != nil {
+		return nil, err
+	}
+	defer os.Remove(progFile)
+	vmProgFile, err := inst.Copy(progFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy programs: %v", err)
+	}
+
+	cmd := instance.ExecprogCmd(execprogBin, executorBin, mgr.cfg.TargetOS, mgr.cfg.TargetArch,
+		mgr.cfg.Sandbox, false, true, false, mgr.cfg.Procs, -1, -1, true, mgr.cfg.Timeouts.Slowdown, vmProgFile)
+	// syz-execprog checks for leaks while executing programs if kmemleak is enabled in the kernel,
+	// the final scan covers the last programs.
+	if mgr.cfg.Sweep.Kmemleak {
+		cmd += " && " + executorBin + " leak"
+	}
+	numProgs := bytes.Count(progs, []byte("executing program"))
+	timeout := time.Duration(numProgs)*mgr.cfg.Timeouts.Program + 10*time.Minute*mgr.cfg.Timeouts.Scale
+	start := time.Now()
+	outc, errc, err := inst.Run(timeout, nil, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run execprog: %v", err)
+	}
+	rep := inst.MonitorExecution(outc, errc, mgr.reporter, vm.ExitNormal)
+	mgr.stats.sweeps.inc()
+	if rep == nil {
+		vmLog.With("vm", index).Logf(0, "sweep of %v programs finished in %v", numProgs, time.Since(start))
+		return nil, nil
+	}
+	vmInfo, err := inst.Info()
+	if err != nil {
+		vmInfo = []byte(fmt.Sprintf("error getting VM info: %v\n", err))
+	}
+	crash := &Crash{
+		vmIndex:     index,
+		Report:      rep,
+		machineInfo: vmInfo,
+		sweepProgs:  progs,
+	}
+	return crash, nil
+}
@@ -0,0 +1,32 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/syzkaller/pkg/mgrconfig"
+	"github.com/google/syzkaller/prog"
+)
+
+func TestSweepProgs(t *testing.T) {
+	target, err := prog.GetTarget("test", "64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &Manager{cfg: &mgrconfig.Config{Sweep: mgrconfig.SweepConfig{Period: 60, Programs: 2}}}
+	mgr.addSweepProg([]byte("test$res0()\n"))
+	mgr.addSweepProg([]byte("breaks_returns()\n"))
+	mgr.addSweepProg([]byte("minimize$0(0x1, 0x1)\n"))
+	entries := target.ParseLog(mgr.takeSweepProgs())
+	if len(entries) != 2 {
+		t.Fatalf("got %v programs, want 2", len(entries))
+	}
+	if name := entries[0].P.Calls[0].Meta.Name; name != "breaks_returns" {
+		t.Fatalf("the oldest program is not dropped, first call: %v", name)
+	}
+	if progs := mgr.takeSweepProgs(); len(progs) != 0 {
+		t.Fatalf("programs are not reset after a sweep:\n%s", progs)
+	}
+}
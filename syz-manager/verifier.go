@@ -0,0 +1,250 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/syzkaller/pkg/html"
+)
+
+// Verifier results are fetched from the syz-verifier monitoring API (see syz-verifier/monitoring_api.go)
+// on each page load, so that the manager web UI can be used as the single place to look at.
+
+type VerifierStats struct {
+	StartTime           time.Time
+	TotalCallMismatches int64
+	TotalProgs          int64
+	ExecErrorProgs      int64
+	FlakyProgs          int64
+	MismatchingProgs    int64
+	AverExecSpeed       int64
+	DispatchedTasks     int64
+	StarvedTasks        int64
+	AverTaskWait        time.Duration
+	MaxTaskWait         time.Duration
+}
+
+type VerifierCall struct {
+	Name        string
+	Mismatches  int64
+	Occurrences int64
+	States      []string
+}
+
+func (call *VerifierCall) Rate() string {
+	return fmt.Sprintf("%.2f%%", float64(call.Mismatches)*100/float64(call.Occurrences))
+}
+
+type VerifierMismatch struct {
+	ID     string
+	Time   time.Time
+	Report string
+	Prog   string
+}
+
+// Calls returns names of the mismatching calls (marked with [!] in the report).
+func (m *VerifierMismatch) Calls() []string {
+	var calls []string
+	for _, line := range strings.Split(m.Report, "\n") {
+		if !strings.HasPrefix(line, "[!] ") {
+			continue
+		}
+		call := strings.TrimPrefix(line, "[!] ")
+		if pos := strings.IndexByte(call, '('); pos != -1 {
+			call = call[:pos]
+		}
+		// Strip the result assignment, e.g. "r0 = ".
+		if pos := strings.LastIndex(call, " = "); pos != -1 {
+			call = call[pos+3:]
+		}
+		calls = append(calls, call)
+	}
+	return calls
+}
+
+var verifierClient = &http.Client{Timeout: 10 * time.Second}
+
+func (mgr *Manager) verifierURL() string {
+	addr := strings.TrimSuffix(mgr.cfg.VerifierHTTP, "/")
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return addr
+}
+
+func (mgr *Manager) verifierRequest(api string, res interface{}) error {
+	resp, err := verifierClient.Get(mgr.verifierURL() + "/api/" + api + ".json")
+	if err != nil {
+		return fmt.Errorf("failed to query verifier: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("verifier replied with %v to %v request", resp.Status, api)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return fmt.Errorf("failed to parse verifier %v reply: %v", api, err)
+	}
+	return nil
+}
+
+func (mgr *Manager) httpVerifier(w http.ResponseWriter, r *http.Request) {
+	if mgr.cfg.VerifierHTTP == "" {
+		http.Error(w, "verifier_http is not configured", http.StatusNotFound)
+		return
+	}
+	data := &UIVerifierData{
+		Name: mgr.cfg.Name,
+		URL:  mgr.verifierURL(),
+	}
+	stats := new(VerifierStats)
+	for api, res := range map[string]interface{}{
+		"stats":      stats,
+		"calls":      &data.Calls,
+		"mismatches": &data.Mismatches,
+	} {
+		if err := mgr.verifierRequest(api, res); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+	data.Stats = []UIStat{
+		{Name: "uptime", Value: fmt.Sprint(time.Since(stats.StartTime) / 1e9 * 1e9)},
+		{Name: "programs", Value: fmt.Sprint(stats.TotalProgs)},
+		{Name: "programs/min", Value: fmt.Sprint(stats.AverExecSpeed)},
+		{Name: "mismatching programs", Value: fmt.Sprint(stats.MismatchingProgs)},
+		{Name: "flaky programs", Value: fmt.Sprint(stats.FlakyProgs)},
+		{Name: "exec error programs", Value: fmt.Sprint(stats.ExecErrorProgs)},
+		{Name: "call mismatches", Value: fmt.Sprint(stats.TotalCallMismatches)},
+		{Name: "task wait (aver/max)", Value: fmt.Sprintf("%v / %v", stats.AverTaskWait, stats.MaxTaskWait)},
+		{Name: "starved tasks", Value: fmt.Sprintf("%v / %v", stats.StarvedTasks, stats.DispatchedTasks)},
+	}
+	if r.FormValue("all") == "" {
+		// By default show only the diverging calls, there are usually lots of calls without mismatches.
+		var calls []*VerifierCall
+		for _, call := range data.Calls {
+			if call.Mismatches != 0 {
+				calls = append(calls, call)
+			}
+		}
+		data.Calls = calls
+	} else {
+		data.AllCalls = true
+	}
+	executeTemplate(w, verifierTemplate, data)
+}
+
+func (mgr *Manager) httpVerifierMismatch(w http.ResponseWriter, r *http.Request) {
+	if mgr.cfg.VerifierHTTP == "" {
+		http.Error(w, "verifier_http is not configured", http.StatusNotFound)
+		return
+	}
+	var mismatches []*VerifierMismatch
+	if err := mgr.verifierRequest("mismatches", &mismatches); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	id := r.FormValue("id")
+	for _, m := range mismatches {
+		if m.ID == id {
+			executeTemplate(w, verifierMismatchTemplate, m)
+			return
+		}
+	}
+	http.Error(w, fmt.Sprintf("no mismatch %q (old reports are overwritten by newer ones)", id),
+		http.StatusNotFound)
+}
+
+type UIVerifierData struct {
+	Name       string
+	URL        string
+	Stats      []UIStat
+	AllCalls   bool
+	Calls      []*VerifierCall
+	Mismatches []*VerifierMismatch
+}
+
+var verifierTemplate = html.CreatePage(`
+<!doctype html>
+<html>
+<head>
+	<title>{{.Name}} verifier</title>
+	{{HEAD}}
+</head>
+<body>
+<b>{{.Name}} verifier</b> (<a href="{{.URL}}/">{{.URL}}</a>)
+<br>
+
+<table class="list_table">
+	<caption>Stats:</caption>
+	{{range $s := $.Stats}}
+	<tr>
+		<td class="stat_name">{{$s.Name}}</td>
+		<td class="stat_value">{{$s.Value}}</td>
+	</tr>
+	{{end}}
+</table>
+
+<table class="list_table">
+	<caption>
+		Per-syscall divergence
+		{{if .AllCalls}}(<a href="/verifier">only mismatching</a>){{else}}(<a href="/verifier?all=1">all calls</a>){{end}}:
+	</caption>
+	<tr>
+		<th><a onclick="return sortTable(this, 'Syscall', textSort)" href="#">Syscall</a></th>
+		<th><a onclick="return sortTable(this, 'Mismatches', numSort)" href="#">Mismatches</a></th>
+		<th><a onclick="return sortTable(this, 'Occurrences', numSort)" href="#">Occurrences</a></th>
+		<th><a onclick="return sortTable(this, 'Rate', floatSort)" href="#">Rate</a></th>
+		<th>Distinct states</th>
+	</tr>
+	{{range $c := $.Calls}}
+	<tr>
+		<td>{{$c.Name}}</td>
+		<td>{{$c.Mismatches}}</td>
+		<td>{{$c.Occurrences}}</td>
+		<td>{{$c.Rate}}</td>
+		<td>{{range $s := $c.States}}{{$s}}<br>{{end}}</td>
+	</tr>
+	{{end}}
+</table>
+
+<table class="list_table">
+	<caption>Confirmed mismatches:</caption>
+	<tr>
+		<th><a onclick="return sortTable(this, 'Report', textSort)" href="#">Report</a></th>
+		<th><a onclick="return sortTable(this, 'Time', textSort, true)" href="#">Time</a></th>
+		<th><a onclick="return sortTable(this, 'Mismatching calls', textSort)" href="#">Mismatching calls</a></th>
+	</tr>
+	{{range $m := $.Mismatches}}
+	<tr>
+		<td><a href="/verifier/mismatch?id={{$m.ID}}">{{$m.ID}}</a></td>
+		<td class="time">{{formatTime $m.Time}}</td>
+		<td>{{range $c := $m.Calls}}{{$c}} {{end}}</td>
+	</tr>
+	{{end}}
+</table>
+</body></html>
+`)
+
+var verifierMismatchTemplate = html.CreatePage(`
+<!doctype html>
+<html>
+<head>
+	<title>{{.ID}} verifier mismatch</title>
+	{{HEAD}}
+</head>
+<body>
+<b>{{.ID}}</b> ({{formatTime .Time}})
+<br>
+<pre>{{.Report}}</pre>
+{{if .Prog}}
+<b>Program:</b>
+<pre>{{.Prog}}</pre>
+{{end}}
+</body></html>
+`)
@@ -0,0 +1,68 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/pkg/mgrconfig"
+)
+
+func TestVerifierPages(t *testing.T) {
+	replies := map[string]string{
+		"/api/stats.json": `{"TotalProgs": 100, "MismatchingProgs": 3}`,
+		"/api/calls.json": `[{"Name": "open", "Mismatches": 1, "Occurrences": 4, "States": ["Crashed"]},
+			{"Name": "close", "Mismatches": 0, "Occurrences": 10}]`,
+		"/api/mismatches.json": `[{"ID": "result-0", "Report": "ERRNO mismatches found for program:\n\n` +
+			`[=] r0 = open(&(0x7f0000000000)='./file0\\x00', 0x0, 0x0)\n\t↳ Pool: 0, Flags: 3, Errno: 0 (success)\n\n` +
+			`[!] close(r0)\n\t↳ Pool: 0, Crashed\n\n", "Prog": "close(0xffffffffffffffff)\n"}]`,
+	}
+	verifier := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reply, ok := replies[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(reply))
+	}))
+	defer verifier.Close()
+	mgr := &Manager{cfg: &mgrconfig.Config{Name: "test", VerifierHTTP: verifier.URL}}
+
+	var mismatches []*VerifierMismatch
+	if err := mgr.verifierRequest("mismatches", &mismatches); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"close"}, mismatches[0].Calls()); diff != "" {
+		t.Errorf("mismatching calls differ (-want +got):\n%s", diff)
+	}
+
+	get := func(handler http.HandlerFunc, url string) string {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest("GET", url, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%v: status %v: %s", url, rec.Code, rec.Body.Bytes())
+		}
+		return rec.Body.String()
+	}
+	page := get(mgr.httpVerifier, "/verifier")
+	for _, want := range []string{"result-0", "open", "25.00%"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("verifier page does not contain %q", want)
+		}
+	}
+	if strings.Contains(page, "<td>close</td>") {
+		t.Errorf("verifier page contains calls without mismatches")
+	}
+	if page := get(mgr.httpVerifier, "/verifier?all=1"); !strings.Contains(page, "<td>close</td>") {
+		t.Errorf("verifier page does not contain all calls")
+	}
+	if page := get(mgr.httpVerifierMismatch, "/verifier/mismatch?id=result-0"); !strings.Contains(page,
+		"close(0xffffffffffffffff)") {
+		t.Errorf("mismatch page does not contain the program")
+	}
+}
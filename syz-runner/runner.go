@@ -3,27 +3,41 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/syzkaller/pkg/host"
 	"github.com/google/syzkaller/pkg/ipc"
 	"github.com/google/syzkaller/pkg/ipc/ipcconfig"
+	"github.com/google/syzkaller/pkg/osutil"
 	"github.com/google/syzkaller/pkg/rpctype"
+	"github.com/google/syzkaller/pkg/rpctype/runnerpb"
 	"github.com/google/syzkaller/prog"
+	"google.golang.org/grpc"
 )
 
-// Runner is responsible of running programs sent by the host via RPC and
+// Runner is responsible of running programs sent by the host via gRPC and
 // reporting the execution results back to the host.
 type Runner struct {
-	vrf      *rpctype.RPCClient
+	vrf      runnerpb.VerifierClient
 	target   *prog.Target
 	opts     *ipc.ExecOpts
 	config   *ipc.Config
 	pool, vm int
 	newEnv   bool
+	// execStart is the time (UnixNano) the current program started executing, 0 if the Runner
+	// is waiting for a task. It is reported in heartbeats and accessed atomically.
+	execStart int64
 }
 
 func main() {
@@ -33,6 +47,10 @@ func main() {
 	flagOS := flag.String("os", runtime.GOOS, "target OS")
 	flagArch := flag.String("arch", runtime.GOARCH, "target arch")
 	flagEnv := flag.Bool("new-env", true, "create a new environment for each program")
+	flagTLSCA := flag.String("tls_ca", "", "CA certificate for verifier rpc (mutual TLS)")
+	flagTLSCert := flag.String("tls_cert", "", "client certificate for verifier rpc (mutual TLS)")
+	flagTLSKey := flag.String("tls_key", "", "client key for verifier rpc (mutual TLS)")
+	flagTokenFile := flag.String("token_file", "", "file with the authentication token for verifier rpc")
 	flag.Parse()
 
 	target, err := prog.GetTarget(*flagOS, *flagArch)
@@ -44,12 +62,29 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to create default ipc config: %v", err)
 	}
+	if config.Flags&ipc.FlagSignal != 0 {
+		// The verifier reports coverage reached on each kernel.
+		opts.Flags |= ipc.FlagCollectCover
+	}
 
+	tlsCfg, err := rpctype.ClientTLSConfig(*flagTLSCA, *flagTLSCert, *flagTLSKey)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
 	timeouts := config.Timeouts
-	vrf, err := rpctype.NewRPCClient(*flagAddr, timeouts.Scale)
+	var dialOpts []grpc.DialOption
+	if *flagTokenFile != "" {
+		token, err := rpctype.ReadTokenFile(*flagTokenFile)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		dialOpts = append(dialOpts, rpctype.TokenDialOption(token))
+	}
+	conn, err := rpctype.DialGRPC(*flagAddr, timeouts.Scale, tlsCfg, dialOpts...)
 	if err != nil {
 		log.Fatalf("failed to connect to verifier : %v", err)
 	}
+	vrf := runnerpb.NewVerifierClient(conn)
 
 	rn := &Runner{
 		vrf:    vrf,
@@ -61,14 +96,21 @@ func main() {
 		newEnv: *flagEnv,
 	}
 
-	a := &rpctype.RunnerConnectArgs{
-		Pool: rn.pool,
-		VM:   rn.vm,
-	}
-	r := &rpctype.RunnerConnectRes{}
-	if err := vrf.Call("Verifier.Connect", a, r); err != nil {
+	ctx := context.Background()
+	r, err := vrf.Connect(ctx, &runnerpb.ConnectRequest{Pool: int32(rn.pool), Vm: int32(rn.vm)})
+	if err != nil {
 		log.Fatalf("failed to connect to verifier: %v", err)
 	}
+	if r.CollectOutputs {
+		rn.opts.Flags |= ipc.FlagCollectOutputs
+	}
+	if len(r.StateProbes) != 0 {
+		rn.config.StateProbes = r.StateProbes
+		rn.opts.Flags |= ipc.FlagCollectState
+	}
+	if r.HeartbeatPeriodMs != 0 {
+		go rn.heartbeatLoop(time.Duration(r.HeartbeatPeriodMs) * time.Millisecond)
+	}
 
 	enabled := make(map[*prog.Syscall]bool)
 	for _, c := range target.Syscalls {
@@ -80,61 +122,76 @@ func main() {
 			log.Fatalf("failed to get unsupported system calls: %v", err)
 		}
 
-		calls := make([]rpctype.SyscallReason, 0)
+		calls := make([]*runnerpb.SyscallReason, 0)
 		for c, reason := range unsupported {
-			calls = append(calls, rpctype.SyscallReason{
-				ID:     c.ID,
+			calls = append(calls, &runnerpb.SyscallReason{
+				Id:     int32(c.ID),
 				Reason: fmt.Sprintf("%s (not supported on kernel %d)", reason, rn.pool)})
 		}
-		a := &rpctype.UpdateUnsupportedArgs{Pool: rn.pool, UnsupportedCalls: calls}
-		if err := vrf.Call("Verifier.UpdateUnsupported", a, nil); err != nil {
+		a := &runnerpb.UpdateUnsupportedRequest{Pool: int32(rn.pool), UnsupportedCalls: calls}
+		if _, err := vrf.UpdateUnsupported(ctx, a); err != nil {
 			log.Fatalf("failed to send unsupported system calls: %v", err)
 		}
 	}
 
-	res := &rpctype.NextExchangeRes{}
-	if err := rn.vrf.Call("Verifier.NextExchange", &rpctype.NextExchangeArgs{Pool: rn.pool, VM: rn.vm}, res); err != nil {
-		log.Fatalf("failed to get initial program: %v", err)
+	stream, err := vrf.Exchange(ctx)
+	if err != nil {
+		log.Fatalf("failed to open exchange stream: %v", err)
 	}
+	task := rn.exchange(stream, &runnerpb.ExecResult{Pool: int32(rn.pool), Vm: int32(rn.vm)})
+	rn.Run(stream, task)
+}
 
-	rn.Run(res.Prog, res.ID)
+// exchange sends the result to the verifier and returns the next task.
+func (rn *Runner) exchange(stream runnerpb.Verifier_ExchangeClient, res *runnerpb.ExecResult) *runnerpb.ExecTask {
+	if err := stream.Send(res); err != nil {
+		log.Fatalf("failed to send the result to verifier: %v", err)
+	}
+	task, err := stream.Recv()
+	if err != nil {
+		log.Fatalf("failed to receive a task from verifier: %v", err)
+	}
+	waitRebootIfRequested(task)
+	return task
 }
 
 // Run is responsible for requesting new programs from the verifier, executing them and then sending back the Result.
 // TODO: Implement functionality to execute several programs at once and send back a slice of results.
-func (rn *Runner) Run(firstProg []byte, taskID int64) {
-	p, id := firstProg, taskID
-
+func (rn *Runner) Run(stream runnerpb.Verifier_ExchangeClient, task *runnerpb.ExecTask) {
 	env, err := ipc.MakeEnv(rn.config, 0)
 	if err != nil {
 		log.Fatalf("failed to create initial execution environment: %v", err)
 	}
 
 	for {
-		prog, err := rn.target.Deserialize(p, prog.NonStrict)
+		prog, err := rn.target.Deserialize(task.Prog, prog.NonStrict)
 		if err != nil {
 			log.Fatalf("failed to deserialise new program: %v", err)
 		}
 
 		log.Printf("executing program") // watchdog for monitor
-		_, info, hanged, err := env.Exec(rn.opts, prog)
+		atomic.StoreInt64(&rn.execStart, time.Now().UnixNano())
+		var info *ipc.ProgInfo
+		var hanged bool
+		var trace []byte
+		if task.Trace || task.Sandbox != "" {
+			info, hanged, trace, err = rn.execInNewEnv(prog, task)
+		} else {
+			_, info, hanged, err = env.Exec(rn.opts, prog)
+		}
 		if err != nil {
 			log.Fatalf("failed to execute the program: %v", err)
 		}
+		atomic.StoreInt64(&rn.execStart, 0)
 
-		a := &rpctype.NextExchangeArgs{
-			Pool:       rn.pool,
-			VM:         rn.vm,
-			Hanged:     hanged,
-			Info:       *info,
-			ExecTaskID: id,
-		}
-
-		r := &rpctype.NextExchangeRes{}
-		if err := rn.vrf.Call("Verifier.NextExchange", a, r); err != nil {
-			log.Fatalf("failed to make exchange with verifier: %v", err)
-		}
-		p, id = r.Prog, r.ID
+		task = rn.exchange(stream, &runnerpb.ExecResult{
+			Pool:   int32(rn.pool),
+			Vm:     int32(rn.vm),
+			TaskId: task.Id,
+			Hanged: hanged,
+			Info:   runnerpb.FromProgInfo(info),
+			Trace:  trace,
+		})
 
 		if !rn.newEnv {
 			continue
@@ -151,3 +208,89 @@ func (rn *Runner) Run(firstProg []byte, taskID int64) {
 		}
 	}
 }
+
+// execInNewEnv executes the program in a new environment with the sandbox of the task.
+// If the task is traced, syz-executor runs under strace and the strace output is returned.
+// If strace is not available, the program is executed without it and the trace contains the reason.
+func (rn *Runner) execInNewEnv(p *prog.Prog, task *runnerpb.ExecTask) (*ipc.ProgInfo, bool, []byte, error) {
+	config := *rn.config
+	if task.Sandbox != "" {
+		sandboxFlags, err := ipc.SandboxToFlags(task.Sandbox)
+		if err != nil {
+			return nil, false, nil, err
+		}
+		config.Flags &^= ipc.FlagSandboxSetuid | ipc.FlagSandboxNamespace | ipc.FlagSandboxAndroid
+		config.Flags |= sandboxFlags
+	}
+	var trace []byte
+	traceFile := ""
+	if task.Trace {
+		dir, err := ioutil.TempDir("", "syz-runner-trace")
+		if err != nil {
+			return nil, false, nil, err
+		}
+		defer os.RemoveAll(dir)
+		traceFile, trace = straceExecutor(&config, dir)
+	}
+	env, err := ipc.MakeEnv(&config, 1)
+	if err != nil {
+		return nil, false, nil, err
+	}
+	_, info, hanged, err := env.Exec(rn.opts, p)
+	// Closing the environment kills strace, the trace is complete after that.
+	env.Close()
+	if err != nil {
+		return nil, false, nil, err
+	}
+	if traceFile != "" {
+		if trace, err = ioutil.ReadFile(traceFile); err != nil {
+			trace = []byte(fmt.Sprintf("failed to read the trace: %v\n", err))
+		}
+	}
+	return info, hanged, trace, nil
+}
+
+// straceExecutor makes the executor of the config run under strace that writes the trace to a file
+// in dir. Returns the trace file, or the reason instead of the trace if strace is not available.
+func straceExecutor(config *ipc.Config, dir string) (string, []byte) {
+	strace, err := exec.LookPath("strace")
+	if err != nil {
+		return "", []byte(fmt.Sprintf("strace is not available: %v\n", err))
+	}
+	traceFile := filepath.Join(dir, "trace")
+	// The executor runs in its own working directory, so its path must be absolute.
+	bin := strings.Split(config.Executor, " ")
+	bin[0] = osutil.Abs(bin[0])
+	config.Executor = fmt.Sprintf("%v -f -qq -o %v %v", strace, traceFile, strings.Join(bin, " "))
+	return traceFile, nil
+}
+
+// heartbeatLoop tells the verifier that the Runner is alive and for how long it has been
+// executing the current program, so that the VM is restarted if the Runner hangs.
+func (rn *Runner) heartbeatLoop(period time.Duration) {
+	for range time.NewTicker(period).C {
+		var busy time.Duration
+		if start := atomic.LoadInt64(&rn.execStart); start != 0 {
+			busy = time.Since(time.Unix(0, start))
+		}
+		req := &runnerpb.RunnerHeartbeatRequest{
+			Pool:   int32(rn.pool),
+			Vm:     int32(rn.vm),
+			BusyMs: busy.Milliseconds(),
+		}
+		if _, err := rn.vrf.RunnerHeartbeat(context.Background(), req); err != nil {
+			log.Printf("failed to send heartbeat: %v", err)
+		}
+	}
+}
+
+// waitRebootIfRequested blocks forever if the verifier reboots the VM.
+func waitRebootIfRequested(r *runnerpb.ExecTask) {
+	if !r.Reboot {
+		return
+	}
+	log.Printf("waiting for the VM reboot")
+	for {
+		time.Sleep(time.Hour)
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/pkg/mgrconfig"
+	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/pkg/report"
+	"github.com/google/syzkaller/pkg/rpctype"
+	"github.com/google/syzkaller/pkg/rpctype/runnerpb"
+	"github.com/google/syzkaller/vm"
+	"google.golang.org/grpc"
+)
+
+// agent runs the VMs of a remote kernel (see remote.go) on this machine.
+// The Runners in the VMs connect to the verifier through a local proxy of the agent,
+// so the VMs need to reach only the agent's machine and the verifier needs to be reachable
+// only from the agents.
+type agent struct {
+	name      string
+	pool      int
+	cfg       *mgrconfig.Config
+	vmPool    *vm.Pool
+	reporter  *report.Reporter
+	runnerBin string
+	newEnv    bool
+	tls       *rpctype.TLSFiles
+	tokenFile string
+	cli       runnerpb.VerifierClient
+	proxyPort int
+
+	mu sync.Mutex
+	// vms are the indices of the VMs assigned by the verifier.
+	vms []int
+	// stops are closed to restart the VMs.
+	stops []chan bool
+}
+
+// runAgent registers this machine as a host of the kernel pool of the verifier at addr and runs its VMs.
+// tlsDir contains the client certificates of the verifier (ca.crt, client.crt and client.key in
+// <workdir>/tls of the verifier), it is empty if the verifier does not use TLS.
+func runAgent(addr, name string, pool int, cfgFile, tlsDir, tokenFile string, newEnv, debug bool) error {
+	cfg, err := mgrconfig.LoadFile(cfgFile)
+	if err != nil {
+		return err
+	}
+	a := &agent{
+		name:      name,
+		pool:      pool,
+		cfg:       cfg,
+		newEnv:    newEnv,
+		tokenFile: tokenFile,
+	}
+	target, exe := cfg.Target, cfg.SysTarget.ExeExtension
+	a.runnerBin = filepath.Join(cfg.Syzkaller, "bin", target.OS+"_"+target.Arch, "syz-runner"+exe)
+	for _, bin := range []string{a.runnerBin, cfg.ExecutorBin} {
+		if !osutil.IsExist(bin) {
+			return fmt.Errorf("bad syzkaller config: can't find %v", bin)
+		}
+	}
+	if a.reporter, err = report.NewReporter(cfg); err != nil {
+		return fmt.Errorf("failed to create reporter: %v", err)
+	}
+	if a.vmPool, err = vm.Create(cfg, debug); err != nil {
+		return err
+	}
+
+	var opts []grpc.DialOption
+	if tokenFile != "" {
+		token, err := rpctype.ReadTokenFile(tokenFile)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, rpctype.TokenDialOption(token))
+	}
+	if tlsDir != "" {
+		a.tls = &rpctype.TLSFiles{
+			CA:   filepath.Join(tlsDir, "ca.crt"),
+			Cert: filepath.Join(tlsDir, "client.crt"),
+			Key:  filepath.Join(tlsDir, "client.key"),
+		}
+	}
+	var tlsCfg *tls.Config
+	if a.tls != nil {
+		if tlsCfg, err = a.tls.ClientConfig(); err != nil {
+			return err
+		}
+	}
+	conn, err := rpctype.DialGRPC(addr, cfg.Timeouts.Scale, tlsCfg, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the verifier: %v", err)
+	}
+	defer conn.Close()
+	a.cli = runnerpb.NewVerifierClient(conn)
+
+	ln, err := net.Listen("tcp", cfg.RPC)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %v: %v", cfg.RPC, err)
+	}
+	a.proxyPort = ln.Addr().(*net.TCPAddr).Port
+	go proxy(ln, addr)
+
+	period, err := a.register()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < a.vmPool.Count(); i++ {
+		go func(i int) {
+			for {
+				a.runInstance(i)
+			}
+		}(i)
+	}
+	ticker := time.NewTicker(period)
+	for {
+		<-ticker.C
+		a.heartbeat()
+	}
+}
+
+// register (re)registers the host and returns the heartbeat period.
+func (a *agent) register() (time.Duration, error) {
+	r, err := a.cli.RegisterHost(context.Background(), &runnerpb.RegisterHostRequest{
+		Name:  a.name,
+		Pool:  int32(a.pool),
+		Count: int32(a.vmPool.Count()),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to register the host: %v", err)
+	}
+	if len(r.Vms) != a.vmPool.Count() {
+		return 0, fmt.Errorf("verifier assigned %v indices to %v VMs", len(r.Vms), a.vmPool.Count())
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	restart := a.vms != nil
+	a.vms = a.vms[:0]
+	for _, id := range r.Vms {
+		a.vms = append(a.vms, int(id))
+	}
+	if a.stops == nil {
+		a.stops = make([]chan bool, len(a.vms))
+	}
+	if restart {
+		// The verifier failed the tasks of the VMs, the Runners must start over.
+		for i := range a.stops {
+			a.stopLocked(i)
+		}
+	}
+	log.Logf(0, "registered host %v for kernel %v, VMs %v", a.name, a.pool, a.vms)
+	return time.Duration(r.HeartbeatPeriodMs) * time.Millisecond, nil
+}
+
+func (a *agent) heartbeat() {
+	r, err := a.cli.Heartbeat(context.Background(), &runnerpb.HeartbeatRequest{Name: a.name})
+	if err != nil {
+		// The connection is re-established by grpc. If the outage is too long, the verifier
+		// fails the tasks of the host and asks it to register again.
+		log.Logf(0, "heartbeat failed: %v", err)
+		return
+	}
+	if r.Reregister {
+		if _, err := a.register(); err != nil {
+			log.Logf(0, "%v", err)
+		}
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, reboot := range r.Reboot {
+		for i, id := range a.vms {
+			if id == int(reboot) {
+				a.stopLocked(i)
+			}
+		}
+	}
+}
+
+func (a *agent) stopLocked(i int) {
+	if a.stops[i] != nil {
+		close(a.stops[i])
+		a.stops[i] = nil
+	}
+}
+
+// vmIndex returns the index of the VM i assigned by the verifier and the channel that is closed to restart it.
+func (a *agent) vmIndex(i int) (int, <-chan bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	stop := make(chan bool)
+	a.stops[i] = stop
+	return a.vms[i], stop
+}
+
+func (a *agent) runInstance(i int) {
+	inst, err := a.vmPool.Create(i)
+	if err != nil {
+		log.Fatalf("failed to create instance: %v", err)
+	}
+	defer inst.Close()
+	vmID, stop := a.vmIndex(i)
+	cmd, err := setupRunner(inst, a.cfg, a.runnerBin, a.proxyPort, a.pool, vmID, a.newEnv, a.tls, a.tokenFile)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	outc, errc, err := inst.Run(a.cfg.Timeouts.VMRunningTime, stop, cmd)
+	if err != nil {
+		log.Fatalf("failed to start runner: %v", err)
+	}
+	inst.MonitorExecution(outc, errc, a.reporter, vm.ExitTimeout)
+	log.New("vm").With("pool", a.pool).Logf(0, "restarting VM %v", vmID)
+}
+
+// proxy forwards the connections accepted on ln to addr.
+func proxy(ln net.Listener, addr string) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			log.Logf(0, "proxy failed: %v", err)
+			return
+		}
+		go func() {
+			defer conn.Close()
+			upstream, err := net.Dial("tcp", addr)
+			if err != nil {
+				log.Logf(0, "failed to connect to the verifier: %v", err)
+				return
+			}
+			defer upstream.Close()
+			done := make(chan bool, 2)
+			go func() {
+				io.Copy(upstream, conn)
+				done <- true
+			}()
+			go func() {
+				io.Copy(conn, upstream)
+				done <- true
+			}()
+			// If either side is closed, the other one is closed too, so that
+			// the verifier notices crashed VMs and the Runners notice the lost verifier.
+			<-done
+		}()
+	}
+}
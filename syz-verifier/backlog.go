@@ -0,0 +1,173 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/syzkaller/pkg/db"
+	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/prog"
+)
+
+// Before new programs are generated, the verifier executes the programs of the backlog:
+// programs of a syz-db corpus (-corpus) and programs of the tasks restored from the checkpoint.
+//
+// The tasks waiting in the queues are saved with the checkpoint, so that the backlog is not lost
+// when the verifier is restarted. Nothing waits for the results of the saved tasks after a restart,
+// so the programs of the restored tasks are verified again from the start before new programs
+// are generated: their tasks and result channels are created as for the generated programs.
+
+// QueuedTask is the persistent form of an ExecTask waiting in a queue.
+type QueuedTask struct {
+	Kernel       int
+	Env          EnvDescr
+	Prog         string
+	Priority     int `json:",omitempty"`
+	Retries      int `json:",omitempty"`
+	CreationTime time.Time
+}
+
+// queueSnapshot returns all tasks waiting in the queues.
+func (vrf *Verifier) queueSnapshot() []*QueuedTask {
+	vrf.tasksMutex.Lock()
+	defer vrf.tasksMutex.Unlock()
+	var res []*QueuedTask
+	for kernel, queues := range vrf.kernelEnvTasks {
+		for env, q := range queues {
+			for _, task := range q.pq {
+				res = append(res, &QueuedTask{
+					Kernel:       kernel,
+					Env:          EnvDescr(env),
+					Prog:         string(task.Program.Serialize()),
+					Priority:     task.priority,
+					Retries:      task.Retries,
+					CreationTime: task.CreationTime,
+				})
+			}
+		}
+	}
+	return res
+}
+
+// restoreBacklog sets the programs of the saved tasks as the backlog, the programs of the tasks
+// with the highest priority and the oldest ones are first. Programs with system calls that are
+// not enabled anymore and programs verified after the checkpoint was saved are dropped.
+func (vrf *Verifier) restoreBacklog(tasks []*QueuedTask) {
+	type backlogProg struct {
+		p        *prog.Prog
+		priority int
+		created  time.Time
+	}
+	progs := make(map[string]*backlogProg)
+	for _, task := range tasks {
+		if bp := progs[task.Prog]; bp != nil {
+			if bp.priority < task.Priority {
+				bp.priority = task.Priority
+			}
+			if bp.created.After(task.CreationTime) {
+				bp.created = task.CreationTime
+			}
+			continue
+		}
+		p, err := vrf.target.Deserialize([]byte(task.Prog), prog.NonStrict)
+		if err != nil {
+			log.Logf(0, "dropped saved task: failed to deserialize program: %v", err)
+			continue
+		}
+		if !vrf.callsEnabled(p) || vrf.progress.done(p) {
+			continue
+		}
+		progs[task.Prog] = &backlogProg{p, task.Priority, task.CreationTime}
+	}
+	var sorted []*backlogProg
+	for _, bp := range progs {
+		sorted = append(sorted, bp)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].priority != sorted[j].priority {
+			return sorted[i].priority > sorted[j].priority
+		}
+		return sorted[i].created.Before(sorted[j].created)
+	})
+	vrf.backlogMu.Lock()
+	defer vrf.backlogMu.Unlock()
+	for _, bp := range sorted {
+		vrf.backlog = append(vrf.backlog, bp.p)
+	}
+	if len(sorted) != 0 {
+		log.Logf(0, "restored %v programs from %v saved tasks", len(sorted), len(tasks))
+	}
+}
+
+func (vrf *Verifier) callsEnabled(p *prog.Prog) bool {
+	vrf.callsMu.Lock()
+	defer vrf.callsMu.Unlock()
+	for _, c := range p.Calls {
+		if !vrf.calls[c.Meta] {
+			return false
+		}
+	}
+	return true
+}
+
+// loadCorpus appends programs of the syz-db corpus to the backlog, so that they are verified before
+// new programs are generated. Programs that were already verified or contain system calls that
+// are not enabled are skipped.
+func (vrf *Verifier) loadCorpus(file string) error {
+	if !osutil.IsExist(file) {
+		return fmt.Errorf("corpus %v does not exist", file)
+	}
+	corpus, err := db.Open(file, false)
+	if err != nil {
+		return fmt.Errorf("failed to open corpus %v: %v", file, err)
+	}
+	keys := make([]string, 0, len(corpus.Records))
+	for key := range corpus.Records {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var progs []*prog.Prog
+	broken, disabled, verified := 0, 0, 0
+	for _, key := range keys {
+		p, err := vrf.target.Deserialize(corpus.Records[key].Val, prog.NonStrict)
+		if err != nil {
+			broken++
+			continue
+		}
+		if !vrf.callsEnabled(p) {
+			disabled++
+			continue
+		}
+		if !vrf.verified.add(p) {
+			verified++
+			continue
+		}
+		progs = append(progs, p)
+	}
+	log.Logf(0, "loaded %v programs from corpus %v (skipped: %v broken, %v with disabled calls, %v verified)",
+		len(progs), file, broken, disabled, verified)
+	vrf.backlogMu.Lock()
+	defer vrf.backlogMu.Unlock()
+	vrf.backlog = append(vrf.backlog, progs...)
+	return nil
+}
+
+// popBacklog returns the next program of the backlog or nil if it is empty.
+// Programs with calls that were disabled at runtime are skipped.
+func (vrf *Verifier) popBacklog() *prog.Prog {
+	vrf.backlogMu.Lock()
+	defer vrf.backlogMu.Unlock()
+	for len(vrf.backlog) != 0 {
+		p := vrf.backlog[0]
+		vrf.backlog = vrf.backlog[1:]
+		if vrf.callsEnabled(p) {
+			return p
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/syzkaller/pkg/db"
+	"github.com/google/syzkaller/prog"
+)
+
+func TestBacklog(t *testing.T) {
+	target := prog.InitTargetTest(t, "test", "64")
+	deserialize := func(p string) *prog.Prog {
+		res, err := target.Deserialize([]byte(p), prog.Strict)
+		if err != nil {
+			t.Fatalf("failed to deserialise test program: %v", err)
+		}
+		return res
+	}
+	p0 := deserialize("breaks_returns()\n")
+	p1 := deserialize("minimize$0(0x1, 0x1)\n")
+	disabled := deserialize("test$res0()\n")
+
+	vrf := &Verifier{
+		target:         target,
+		stats:          MakeStats(),
+		checkpointFile: filepath.Join(t.TempDir(), "checkpoint.json"),
+	}
+	makeTestQueues(vrf, 2)
+	for kernel := 0; kernel < 2; kernel++ {
+		for _, p := range []*prog.Prog{p0, p1, disabled} {
+			task := MakeExecTask(p)
+			defer DeleteExecTask(task)
+			if p == p1 {
+				task.priority = 1
+			}
+			vrf.kernelEnvTasks[kernel][NewEnvironment].PushTask(task)
+		}
+	}
+	if err := vrf.saveCheckpoint(); err != nil {
+		t.Fatal(err)
+	}
+
+	vrf2 := &Verifier{
+		target:         target,
+		stats:          MakeStats(),
+		checkpointFile: vrf.checkpointFile,
+		calls: map[*prog.Syscall]bool{
+			target.SyscallMap["breaks_returns"]: true,
+			target.SyscallMap["minimize$0"]:     true,
+		},
+	}
+	if err := vrf2.restoreCheckpoint(); err != nil {
+		t.Fatal(err)
+	}
+	// The program with the higher priority is first, the program with the disabled call is dropped.
+	for i, want := range []*prog.Prog{p1, p0, nil} {
+		got := vrf2.popBacklog()
+		if want == nil {
+			if got != nil {
+				t.Errorf("#%v: got program %q, want empty backlog", i, got.Serialize())
+			}
+			continue
+		}
+		if got == nil || string(got.Serialize()) != string(want.Serialize()) {
+			t.Errorf("#%v: got program %v, want %q", i, got, want.Serialize())
+		}
+	}
+}
+
+func TestLoadCorpus(t *testing.T) {
+	target := prog.InitTargetTest(t, "test", "64")
+	file := filepath.Join(t.TempDir(), "corpus.db")
+	corpus, err := db.Open(file, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, p := range map[string]string{
+		"0": "breaks_returns()\n",
+		"1": "minimize$0(0x1, 0x1)\n",
+		"2": "test$res0()\n",
+		"3": "no_such_call()\n",
+	} {
+		corpus.Save(key, []byte(p), 0)
+	}
+	if err := corpus.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	verified, err := target.Deserialize([]byte("minimize$0(0x1, 0x1)\n"), prog.Strict)
+	if err != nil {
+		t.Fatalf("failed to deserialise test program: %v", err)
+	}
+	vrf := &Verifier{
+		target:   target,
+		verified: newProgSet(),
+		calls: map[*prog.Syscall]bool{
+			target.SyscallMap["breaks_returns"]: true,
+			target.SyscallMap["minimize$0"]:     true,
+		},
+	}
+	vrf.verified.add(verified)
+	if err := vrf.loadCorpus(file); err != nil {
+		t.Fatal(err)
+	}
+	// Only the program that is not verified yet and has no disabled calls is loaded.
+	if p := vrf.popBacklog(); p == nil || string(p.Serialize()) != "breaks_returns()\n" {
+		t.Errorf("got program %v, want breaks_returns()", p)
+	}
+	if p := vrf.popBacklog(); p != nil {
+		t.Errorf("got program %q, want empty backlog", p.Serialize())
+	}
+	if err := vrf.loadCorpus(filepath.Join(t.TempDir(), "missing.db")); err == nil {
+		t.Errorf("loading missing corpus did not fail")
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/syzkaller/pkg/log"
+)
+
+// Every program is executed on all kernels, so the verification progresses at the speed of
+// the slowest kernel (e.g. a debug build) and the VMs of the other kernels are partially idle.
+// The statistics show the throughput and the utilization of the VMs of each kernel.
+// With -rebalance, the number of VMs of each kernel that execute tasks is periodically adjusted
+// to the throughput required from the kernel: the remaining VMs are parked (booted, but their
+// Runners wait for a permission to request a task), so that the host resources are spent
+// on the VMs of the slower kernels.
+
+const (
+	rebalancePeriod = time.Minute
+	// rebalanceUtilization is the target utilization of the VMs executing tasks.
+	rebalanceUtilization = 0.9
+)
+
+// PoolStatsJSON describes the throughput and the utilization of the VMs of a kernel.
+type PoolStatsJSON struct {
+	Pool           int
+	VMs            int
+	Tasks          int64
+	TasksPerMinute float64
+	// Utilization is the fraction of time the VMs spent executing tasks.
+	Utilization float64
+}
+
+// addPoolTask records a task executed on the kernel.
+func (stats *Stats) addPoolTask(pool int, exec time.Duration) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	if stats.poolTasks == nil {
+		stats.poolTasks = make(map[int]int64)
+		stats.poolBusy = make(map[int]time.Duration)
+	}
+	stats.poolTasks[pool]++
+	stats.poolBusy[pool] += exec
+}
+
+// poolBusySnapshot returns the total execution time of the tasks of each kernel.
+func (stats *Stats) poolBusySnapshot() map[int]time.Duration {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	res := make(map[int]time.Duration)
+	for pool, busy := range stats.poolBusy {
+		res[pool] = busy
+	}
+	return res
+}
+
+// poolStats returns the statistics of all kernels ordered by pool index.
+// Must be called with stats.mu held.
+func (stats *Stats) poolStats(deltaTime float64) []*PoolStatsJSON {
+	var res []*PoolStatsJSON
+	for pool, tasks := range stats.poolTasks {
+		ps := &PoolStatsJSON{Pool: pool, VMs: stats.poolVMs[pool], Tasks: tasks}
+		if deltaTime != 0 {
+			ps.TasksPerMinute = float64(tasks) / deltaTime
+			if ps.VMs != 0 {
+				ps.Utilization = stats.poolBusy[pool].Minutes() / (deltaTime * float64(ps.VMs))
+			}
+		}
+		res = append(res, ps)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Pool < res[j].Pool
+	})
+	return res
+}
+
+func formatPoolStats(pools []*PoolStatsJSON) string {
+	var res []string
+	for _, ps := range pools {
+		res = append(res, fmt.Sprintf("pool %d: %0.2f %% (%0.2f tasks / minute)",
+			ps.Pool, ps.Utilization*100, ps.TasksPerMinute))
+	}
+	return strings.Join(res, ", ")
+}
+
+// poolThrottle limits the number of VMs of a kernel executing tasks.
+// All methods can be called on a nil object.
+type poolThrottle struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	// limit is the maximal number of VMs executing tasks, 0 if not limited.
+	limit int
+	// VMs (vmTasksKey) that are allowed to execute tasks.
+	active map[int]bool
+}
+
+func newPoolThrottle() *poolThrottle {
+	pt := &poolThrottle{active: make(map[int]bool)}
+	pt.cond = sync.NewCond(&pt.mu)
+	return pt
+}
+
+// acquire is called before the VM requests a task, it waits until the VM can execute tasks.
+func (pt *poolThrottle) acquire(vm int) {
+	if pt == nil {
+		return
+	}
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	if pt.active[vm] {
+		if pt.limit == 0 || len(pt.active) <= pt.limit {
+			return
+		}
+		// The limit was decreased, the VM is parked.
+		delete(pt.active, vm)
+	}
+	for pt.limit != 0 && len(pt.active) >= pt.limit {
+		pt.cond.Wait()
+	}
+	pt.active[vm] = true
+}
+
+// release is called when the VM exits.
+func (pt *poolThrottle) release(vm int) {
+	if pt == nil {
+		return
+	}
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	if pt.active[vm] {
+		delete(pt.active, vm)
+		pt.cond.Signal()
+	}
+}
+
+func (pt *poolThrottle) setLimit(limit int) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	pt.limit = limit
+	pt.cond.Broadcast()
+}
+
+func (pt *poolThrottle) getLimit() int {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	return pt.limit
+}
+
+// poolThrottle returns the throttle of the kernel, nil if the kernel is not throttled.
+func (vrf *Verifier) poolThrottle(pool int) *poolThrottle {
+	if pi := vrf.pools[pool]; pi != nil {
+		return pi.throttle
+	}
+	return nil
+}
+
+// rebalanceLimit returns the number of VMs of a kernel with count VMs that can execute tasks
+// if limit VMs (0 if not limited) were utilized by utilization during the last period.
+func rebalanceLimit(count, limit int, utilization float64) int {
+	if limit == 0 {
+		limit = count
+	}
+	want := limit + 1
+	if utilization < rebalanceUtilization {
+		want = int(math.Ceil(float64(limit) * utilization / rebalanceUtilization))
+	}
+	if want < 1 {
+		want = 1
+	}
+	if want >= count {
+		return 0
+	}
+	return want
+}
+
+// rebalanceLoop periodically adjusts the number of VMs of each kernel that can execute tasks.
+func (vrf *Verifier) rebalanceLoop(period time.Duration) {
+	vrf.progGeneratorInit.Wait()
+	prev := vrf.stats.poolBusySnapshot()
+	for range time.NewTicker(period).C {
+		busy := vrf.stats.poolBusySnapshot()
+		for pool, pi := range vrf.pools {
+			if pi.throttle == nil {
+				// The VMs of remote kernels are not rebalanced.
+				continue
+			}
+			count, limit := pi.pool.Count(), pi.throttle.getLimit()
+			vms := limit
+			if vms == 0 {
+				vms = count
+			}
+			utilization := (busy[pool] - prev[pool]).Seconds() / (period.Seconds() * float64(vms))
+			if n := rebalanceLimit(count, limit, utilization); n != limit {
+				pi.throttle.setLimit(n)
+				if n == 0 {
+					n = count
+				}
+				log.Logf(0, "pool %v: %v of %v VMs execute tasks (utilization %0.2f %%)",
+					pool, n, count, utilization*100)
+			}
+		}
+		prev = busy
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRebalanceLimit(t *testing.T) {
+	tests := []struct {
+		count, limit int
+		utilization  float64
+		want         int
+	}{
+		// Fully utilized kernels are not limited.
+		{10, 0, 1, 0},
+		{10, 0, 0.95, 0},
+		// 10 VMs are utilized by 45 %, 5 VMs are enough.
+		{10, 0, 0.45, 5},
+		{10, 5, 0.45, 3},
+		{10, 5, 0.95, 6},
+		{10, 9, 0.95, 0},
+		{10, 1, 0, 1},
+		{1, 0, 0.1, 0},
+	}
+	for i, test := range tests {
+		if got := rebalanceLimit(test.count, test.limit, test.utilization); got != test.want {
+			t.Errorf("#%v: rebalanceLimit(%v, %v, %v) = %v, want %v",
+				i, test.count, test.limit, test.utilization, got, test.want)
+		}
+	}
+}
+
+func TestPoolThrottle(t *testing.T) {
+	pt := newPoolThrottle()
+	pt.acquire(0)
+	pt.acquire(1)
+	pt.setLimit(1)
+	// VM 0 is over the limit and is parked until VM 1 exits.
+	acquired := make(chan bool)
+	go func() {
+		pt.acquire(0)
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatalf("the VM over the limit was not parked")
+	case <-time.After(10 * time.Millisecond):
+	}
+	pt.acquire(1)
+	pt.release(1)
+	<-acquired
+
+	var nilThrottle *poolThrottle
+	nilThrottle.acquire(0)
+	nilThrottle.release(0)
+}
+
+func TestPoolStats(t *testing.T) {
+	stats := MakeStats()
+	stats.poolVMs = map[int]int{0: 2, 1: 2}
+	stats.addPoolTask(0, 30*time.Second)
+	stats.addPoolTask(0, 30*time.Second)
+	stats.addPoolTask(1, 90*time.Second)
+	got := stats.poolStats(1)
+	want := []*PoolStatsJSON{
+		{Pool: 0, VMs: 2, Tasks: 2, TasksPerMinute: 2, Utilization: 0.5},
+		{Pool: 1, VMs: 2, Tasks: 1, TasksPerMinute: 1, Utilization: 0.75},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("pool stats mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := formatPoolStats(got), "pool 0: 50.00 % (2.00 tasks / minute), "+
+		"pool 1: 75.00 % (1.00 tasks / minute)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,328 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/syzkaller/pkg/hash"
+	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/prog"
+)
+
+// The verification state (Stats, hashes of the tested programs and the queued tasks) is periodically saved
+// to <workdir>/checkpoint.json and restored on startup, so that long campaigns survive
+// crashes of the verifier and host reboots.
+
+// Checkpoint is the persistent form of the verification state.
+type Checkpoint struct {
+	Time time.Time
+	// Elapsed is the total duration of the verification excluding the time the verifier was not running.
+	Elapsed                   time.Duration
+	TotalCallMismatches       int64
+	TotalProgs                int64
+	ExecErrorProgs            int64
+	FlakyProgs                int64
+	MismatchingProgs          int64
+	SuppressedProgs           int64
+	CoverDivergentProgs       int64
+	StateDivergentProgs       int64
+	CleanVMReruns             int64
+	CleanVMFlips              int64
+	HubProgs                  int64
+	SnapshotRestores          int64
+	RunnerRestarts            int64
+	RequeuedTasks             int64
+	DispatchedTasks           int64
+	StarvedTasks              int64
+	TotalTaskWait             time.Duration
+	MaxTaskWait               time.Duration
+	TimedOutTasks             int64
+	AbandonedTasks            int64
+	QueueBlockedTasks         int64
+	QueueDroppedTasks         int64
+	MismatchingNondeterminism float64
+	FlakyNondeterminism       float64
+	FlakyCauses               map[string]int64      `json:",omitempty"`
+	CoverDivergences          map[string]int64      `json:",omitempty"`
+	StateDivergences          map[string]int64      `json:",omitempty"`
+	PoolTasks                 map[int]int64         `json:",omitempty"`
+	PoolBusy                  map[int]time.Duration `json:",omitempty"`
+	Calls                     []*CallCheckpoint
+	// Signatures contains the number of mismatching programs with each signature.
+	Signatures map[string]int64 `json:",omitempty"`
+	// Verified contains hashes of all programs tested so far.
+	Verified []string
+	// Queue contains the tasks waiting in the queues (see backlog.go).
+	Queue []*QueuedTask `json:",omitempty"`
+}
+
+// CallCheckpoint is the persistent form of CallStats.
+type CallCheckpoint struct {
+	Name        string
+	Mismatches  int64
+	Occurrences int64
+	States      []ReturnState
+	Outliers    map[int]int64 `json:",omitempty"`
+}
+
+// checkpoint returns the current state of the statistics.
+func (stats *Stats) checkpoint() *Checkpoint {
+	calls := stats.callsSnapshot()
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	cp := &Checkpoint{
+		Time:                      time.Now(),
+		Elapsed:                   time.Since(stats.StartTime),
+		TotalCallMismatches:       atomic.LoadInt64(&stats.TotalCallMismatches),
+		TotalProgs:                atomic.LoadInt64(&stats.TotalProgs),
+		ExecErrorProgs:            atomic.LoadInt64(&stats.ExecErrorProgs),
+		FlakyProgs:                stats.FlakyProgs,
+		MismatchingProgs:          stats.MismatchingProgs,
+		SuppressedProgs:           atomic.LoadInt64(&stats.SuppressedProgs),
+		CoverDivergentProgs:       atomic.LoadInt64(&stats.CoverDivergentProgs),
+		StateDivergentProgs:       atomic.LoadInt64(&stats.StateDivergentProgs),
+		CleanVMReruns:             atomic.LoadInt64(&stats.CleanVMReruns),
+		CleanVMFlips:              atomic.LoadInt64(&stats.CleanVMFlips),
+		HubProgs:                  atomic.LoadInt64(&stats.HubProgs),
+		SnapshotRestores:          atomic.LoadInt64(&stats.SnapshotRestores),
+		RunnerRestarts:            atomic.LoadInt64(&stats.RunnerRestarts),
+		RequeuedTasks:             atomic.LoadInt64(&stats.RequeuedTasks),
+		DispatchedTasks:           stats.DispatchedTasks,
+		StarvedTasks:              stats.StarvedTasks,
+		TotalTaskWait:             stats.TotalTaskWait,
+		MaxTaskWait:               stats.MaxTaskWait,
+		TimedOutTasks:             atomic.LoadInt64(&stats.TimedOutTasks),
+		AbandonedTasks:            atomic.LoadInt64(&stats.AbandonedTasks),
+		QueueBlockedTasks:         atomic.LoadInt64(&stats.QueueBlockedTasks),
+		QueueDroppedTasks:         atomic.LoadInt64(&stats.QueueDroppedTasks),
+		MismatchingNondeterminism: stats.mismatchingNondeterminism,
+		FlakyNondeterminism:       stats.flakyNondeterminism,
+		FlakyCauses:               copyCounts(stats.flakyCauses),
+		CoverDivergences:          copyCounts(stats.coverDivergences),
+		StateDivergences:          copyCounts(stats.stateDivergences),
+		Signatures:                copyCounts(stats.signatures),
+	}
+	if len(stats.poolTasks) != 0 {
+		cp.PoolTasks = make(map[int]int64)
+		cp.PoolBusy = make(map[int]time.Duration)
+		for pool, tasks := range stats.poolTasks {
+			cp.PoolTasks[pool] = tasks
+			cp.PoolBusy[pool] = stats.poolBusy[pool]
+		}
+	}
+	for _, cs := range calls {
+		call := &CallCheckpoint{
+			Name:        cs.Name,
+			Mismatches:  cs.Mismatches,
+			Occurrences: cs.Occurrences,
+			Outliers:    cs.Outliers,
+		}
+		for state := range cs.States {
+			call.States = append(call.States, state)
+		}
+		sort.Slice(call.States, func(i, j int) bool {
+			return call.States[i].String() < call.States[j].String()
+		})
+		cp.Calls = append(cp.Calls, call)
+	}
+	return cp
+}
+
+// restore adds the state saved in the checkpoint to the statistics.
+// Calls that are not enabled anymore are not restored, but still count in the totals.
+func (stats *Stats) restore(cp *Checkpoint) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.StartTime = time.Now().Add(-cp.Elapsed)
+	atomic.AddInt64(&stats.TotalCallMismatches, cp.TotalCallMismatches)
+	atomic.AddInt64(&stats.TotalProgs, cp.TotalProgs)
+	atomic.AddInt64(&stats.ExecErrorProgs, cp.ExecErrorProgs)
+	atomic.AddInt64(&stats.FlakyProgs, cp.FlakyProgs)
+	atomic.AddInt64(&stats.MismatchingProgs, cp.MismatchingProgs)
+	atomic.AddInt64(&stats.SuppressedProgs, cp.SuppressedProgs)
+	atomic.AddInt64(&stats.CoverDivergentProgs, cp.CoverDivergentProgs)
+	atomic.AddInt64(&stats.StateDivergentProgs, cp.StateDivergentProgs)
+	atomic.AddInt64(&stats.CleanVMReruns, cp.CleanVMReruns)
+	atomic.AddInt64(&stats.CleanVMFlips, cp.CleanVMFlips)
+	atomic.AddInt64(&stats.HubProgs, cp.HubProgs)
+	atomic.AddInt64(&stats.SnapshotRestores, cp.SnapshotRestores)
+	atomic.AddInt64(&stats.RunnerRestarts, cp.RunnerRestarts)
+	atomic.AddInt64(&stats.RequeuedTasks, cp.RequeuedTasks)
+	atomic.AddInt64(&stats.TimedOutTasks, cp.TimedOutTasks)
+	atomic.AddInt64(&stats.AbandonedTasks, cp.AbandonedTasks)
+	atomic.AddInt64(&stats.QueueBlockedTasks, cp.QueueBlockedTasks)
+	atomic.AddInt64(&stats.QueueDroppedTasks, cp.QueueDroppedTasks)
+	stats.DispatchedTasks += cp.DispatchedTasks
+	stats.StarvedTasks += cp.StarvedTasks
+	stats.TotalTaskWait += cp.TotalTaskWait
+	if stats.MaxTaskWait < cp.MaxTaskWait {
+		stats.MaxTaskWait = cp.MaxTaskWait
+	}
+	stats.mismatchingNondeterminism += cp.MismatchingNondeterminism
+	stats.flakyNondeterminism += cp.FlakyNondeterminism
+	if len(cp.FlakyCauses) != 0 && stats.flakyCauses == nil {
+		stats.flakyCauses = make(map[string]int64)
+	}
+	for cause, count := range cp.FlakyCauses {
+		stats.flakyCauses[cause] += count
+	}
+	if len(cp.CoverDivergences) != 0 && stats.coverDivergences == nil {
+		stats.coverDivergences = make(map[string]int64)
+	}
+	for call, count := range cp.CoverDivergences {
+		stats.coverDivergences[call] += count
+	}
+	if len(cp.StateDivergences) != 0 && stats.stateDivergences == nil {
+		stats.stateDivergences = make(map[string]int64)
+	}
+	for probe, count := range cp.StateDivergences {
+		stats.stateDivergences[probe] += count
+	}
+	if len(cp.PoolTasks) != 0 && stats.poolTasks == nil {
+		stats.poolTasks = make(map[int]int64)
+		stats.poolBusy = make(map[int]time.Duration)
+	}
+	for pool, tasks := range cp.PoolTasks {
+		stats.poolTasks[pool] += tasks
+		stats.poolBusy[pool] += cp.PoolBusy[pool]
+	}
+	if len(cp.Signatures) != 0 && stats.signatures == nil {
+		stats.signatures = make(map[string]int64)
+	}
+	for sig, count := range cp.Signatures {
+		if stats.signatures[sig] == 0 {
+			stats.UniqueMismatches++
+		}
+		stats.signatures[sig] += count
+	}
+	for _, call := range cp.Calls {
+		cs := stats.Calls[call.Name]
+		if cs == nil {
+			continue
+		}
+		atomic.AddInt64(&cs.Mismatches, call.Mismatches)
+		atomic.AddInt64(&cs.Occurrences, call.Occurrences)
+		for _, state := range call.States {
+			cs.States[state] = true
+		}
+		if len(call.Outliers) != 0 && cs.Outliers == nil {
+			cs.Outliers = make(map[int]int64)
+		}
+		for pool, n := range call.Outliers {
+			cs.Outliers[pool] += n
+		}
+	}
+}
+
+// progSet is the set of hashes of the tested programs. All methods can be called on a nil object.
+type progSet struct {
+	mu     sync.Mutex
+	hashes map[string]bool
+}
+
+func newProgSet() *progSet {
+	return &progSet{hashes: make(map[string]bool)}
+}
+
+// add adds the program to the set and returns false if it is already there.
+func (ps *progSet) add(p *prog.Prog) bool {
+	if ps == nil {
+		return true
+	}
+	h := hash.String(p.Serialize())
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.hashes[h] {
+		return false
+	}
+	ps.hashes[h] = true
+	return true
+}
+
+func (ps *progSet) list() []string {
+	if ps == nil {
+		return nil
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	res := make([]string, 0, len(ps.hashes))
+	for h := range ps.hashes {
+		res = append(res, h)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func (ps *progSet) restore(hashes []string) {
+	if ps == nil {
+		return
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for _, h := range hashes {
+		ps.hashes[h] = true
+	}
+}
+
+// saveCheckpoint atomically replaces the checkpoint file with the current verification state.
+func (vrf *Verifier) saveCheckpoint() error {
+	cp := vrf.stats.checkpoint()
+	cp.Verified = vrf.verified.list()
+	cp.Queue = vrf.queueSnapshot()
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp := vrf.checkpointFile + ".tmp"
+	if err := osutil.WriteFile(tmp, data); err != nil {
+		return err
+	}
+	return os.Rename(tmp, vrf.checkpointFile)
+}
+
+// restoreCheckpoint restores the verification state from the checkpoint file, if it exists.
+// Must be called after the syscall mask of the statistics is set.
+func (vrf *Verifier) restoreCheckpoint() error {
+	data, err := ioutil.ReadFile(vrf.checkpointFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cp := new(Checkpoint)
+	if err := json.Unmarshal(data, cp); err != nil {
+		return fmt.Errorf("failed to parse checkpoint %v: %v", vrf.checkpointFile, err)
+	}
+	vrf.stats.restore(cp)
+	if vrf.progress == nil {
+		// The progress log contains only programs whose verification has completed,
+		// the checkpoint also contains programs that were being verified.
+		vrf.verified.restore(cp.Verified)
+	}
+	if len(cp.Queue) != 0 {
+		vrf.restoreBacklog(cp.Queue)
+	}
+	log.Logf(0, "restored checkpoint from %v: %v programs tested in %v",
+		cp.Time.Format(time.RFC3339), cp.TotalProgs, cp.Elapsed.Round(time.Second))
+	return nil
+}
+
+// checkpointLoop periodically saves the verification state.
+func (vrf *Verifier) checkpointLoop(period time.Duration) {
+	for range time.NewTicker(period).C {
+		if err := vrf.saveCheckpoint(); err != nil {
+			log.Logf(0, "failed to save checkpoint: %v", err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestCheckpoint(t *testing.T) {
+	stats := dummyStats()
+	stats.StartTime = time.Now().Add(-time.Hour)
+	verified := newProgSet()
+	p := getTestProgram(t)
+	if !verified.add(p) {
+		t.Fatalf("new program is reported as verified")
+	}
+	vrf := &Verifier{
+		checkpointFile: filepath.Join(t.TempDir(), "checkpoint.json"),
+		stats:          stats,
+		verified:       verified,
+	}
+	if err := vrf.restoreCheckpoint(); err != nil {
+		t.Fatalf("restoring missing checkpoint failed: %v", err)
+	}
+	if err := vrf.saveCheckpoint(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := MakeStats()
+	for name := range stats.Calls {
+		restored.Calls[name] = &CallStats{Name: name, States: make(map[ReturnState]bool)}
+	}
+	vrf2 := &Verifier{
+		checkpointFile: vrf.checkpointFile,
+		stats:          restored,
+		verified:       newProgSet(),
+	}
+	if err := vrf2.restoreCheckpoint(); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(stats, restored, cmpopts.IgnoreUnexported(Stats{}),
+		cmpopts.EquateApproxTime(time.Second)); diff != "" {
+		t.Errorf("restored stats mismatch (-want +got):\n%s", diff)
+	}
+	if vrf2.verified.add(p) {
+		t.Errorf("restored program is not reported as verified")
+	}
+}
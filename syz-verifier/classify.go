@@ -0,0 +1,216 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// A program that produced different results on the kernels is rerun to tell genuine mismatches
+// from divergence caused by nondeterminism (background activity, external state, etc).
+// The null hypothesis is that the program is flaky and diverges in each run independently
+// with probability flakyRate. After each run we compute the probability to observe
+// at least the same number of divergent runs under the null hypothesis (one-sided binomial test).
+// If the probability falls below mismatchThreshold, the mismatch is confirmed.
+// If it can't fall below the threshold even if all the remaining reruns diverge, the program is flaky.
+//
+// Alternatively, with the agreement rerun policy the mismatch is confirmed if at least
+// the agreement fraction of all 1+reruns runs diverge (1 requires all runs to diverge).
+// The reruns stop as soon as the outcome is known.
+const (
+	defaultFlakyRate         = 0.5
+	defaultMismatchThreshold = 0.01
+	defaultAgreement         = 1.0
+)
+
+// Rerun policies.
+const (
+	rerunPolicyBinomial  = "binomial"
+	rerunPolicyAgreement = "agreement"
+)
+
+func checkRerunPolicy(policy string, reruns int, flakyRate, mismatchThreshold, agreement float64) error {
+	if policy != rerunPolicyBinomial && policy != rerunPolicyAgreement {
+		return fmt.Errorf("unknown rerun policy %q, expected %v or %v",
+			policy, rerunPolicyBinomial, rerunPolicyAgreement)
+	}
+	if agreement <= 0 || agreement > 1 {
+		return fmt.Errorf("agreement must be in (0, 1], got %v", agreement)
+	}
+	if policy != rerunPolicyBinomial {
+		return nil
+	}
+	minRuns := minBinomialRuns(flakyRate, mismatchThreshold)
+	if minRuns == 0 {
+		return fmt.Errorf("mismatches can't be confirmed with flaky rate %v and mismatch threshold %v",
+			flakyRate, mismatchThreshold)
+	}
+	if 1+reruns < minRuns {
+		return fmt.Errorf("mismatches can't be confirmed with %v reruns, flaky rate %v and mismatch threshold %v "+
+			"require at least %v reruns", reruns, flakyRate, mismatchThreshold, minRuns-1)
+	}
+	return nil
+}
+
+// maxBinomialRuns bounds the search in minBinomialRuns.
+const maxBinomialRuns = 1000
+
+// minBinomialRuns returns the minimal number of runs in which a program must diverge
+// for the binomial test to confirm the mismatch, or 0 if it's never confirmed.
+func minBinomialRuns(flakyRate, mismatchThreshold float64) int {
+	for n := 1; n <= maxBinomialRuns; n++ {
+		if binomialTail(n, n, flakyRate) < mismatchThreshold {
+			return n
+		}
+	}
+	return 0
+}
+
+// Causes of the divergence of flaky programs, so that infrastructure noise
+// can be told from nondeterministic kernel behavior.
+const (
+	// The kernels returned different states in some runs, but the same states in others.
+	flakyCauseErrno = "nondeterministic errno"
+	// The program hanged on some kernel in every divergent run.
+	flakyCauseTimeout = "timeout"
+	// The program diverged, but a rerun failed because the executor or the VM crashed.
+	flakyCauseCrash = "executor crash"
+	// The confirmed mismatch was not reproduced in clean VMs (see cleanvm.go).
+	flakyCauseVMState = "VM state"
+)
+
+// Verdict is the result of testing a program on all kernels.
+type Verdict struct {
+	// Results of the last divergent run, nil if the program never diverged.
+	Results []*ExecResult `json:"-"`
+	// Runs is the number of runs, Divergent is the number of runs with different results on the kernels.
+	Runs      int
+	Divergent int
+	// Nondeterminism is the probability that the observed divergence is due to nondeterminism
+	// (the p-value of the binomial test).
+	Nondeterminism float64
+	// Mismatch is set if the divergence is confirmed, otherwise a divergent program is flaky.
+	Mismatch bool
+	// Timeouts is the number of divergent runs in which the program hanged on some kernel.
+	Timeouts int `json:",omitempty"`
+	// Cause is the cause of the divergence of a flaky program.
+	Cause string `json:",omitempty"`
+	// Signature identifies the confirmed mismatch (see signature.go).
+	Signature string `json:",omitempty"`
+	// NewSignature is set if this is the first mismatching program with the signature.
+	NewSignature bool `json:"-"`
+	// Suppressed describes the suppression matching the mismatch, the mismatch is not reported then
+	// (see suppress.go).
+	Suppressed string `json:",omitempty"`
+	// Triage is the divergence analysis of a program with a new signature (see triage.go).
+	Triage *Triage `json:",omitempty"`
+	// Sandboxes are the results of the reruns of a program with a new signature
+	// in other executor sandboxes (see sandbox.go).
+	Sandboxes []*SandboxResult `json:",omitempty"`
+	// Traces are the strace outputs of a program with a new signature on each kernel (see trace.go).
+	Traces [][]byte `json:"-"`
+	// CoverDivergence contains the calls whose coverage differs drastically between the kernels
+	// in a program that did not diverge (see coverdiv.go).
+	CoverDivergence []*CoverDivergence `json:",omitempty"`
+	// NewCoverDivergence is set if some of the calls diverged for the first time.
+	NewCoverDivergence bool `json:"-"`
+	// StateDivergence contains the probes whose change differs between the kernels
+	// in a program that did not diverge (see statediv.go).
+	StateDivergence []*StateDivergence `json:",omitempty"`
+	// NewStateDivergence is set if some of the probes diverged for the first time.
+	NewStateDivergence bool `json:"-"`
+}
+
+// diverges returns true if results of the kernels are not the same.
+func diverges(results []*ExecResult) bool {
+	for _, res := range results[1:] {
+		if !results[0].IsEqual(res) {
+			return true
+		}
+	}
+	return false
+}
+
+// hanged returns true if the program hanged on some kernel.
+func hanged(results []*ExecResult) bool {
+	for _, res := range results {
+		if res.Hanged {
+			return true
+		}
+	}
+	return false
+}
+
+// flakyCause returns the cause of the divergence of a flaky program
+// that was executed without errors.
+func flakyCause(v *Verdict) string {
+	if v.Timeouts == v.Divergent {
+		return flakyCauseTimeout
+	}
+	return flakyCauseErrno
+}
+
+// binomialTail returns the probability of at least k successes in n trials with success probability p.
+func binomialTail(n, k int, p float64) float64 {
+	if k <= 0 {
+		return 1
+	}
+	if p <= 0 {
+		return 0
+	}
+	if p >= 1 {
+		return 1
+	}
+	lgn, _ := math.Lgamma(float64(n + 1))
+	res := 0.0
+	for i := k; i <= n; i++ {
+		lgi, _ := math.Lgamma(float64(i + 1))
+		lgni, _ := math.Lgamma(float64(n - i + 1))
+		res += math.Exp(lgn - lgi - lgni + float64(i)*math.Log(p) + float64(n-i)*math.Log1p(-p))
+	}
+	return math.Min(res, 1)
+}
+
+// classify updates the verdict after a run and returns true if no more runs are needed.
+func (vrf *Verifier) classify(v *Verdict, results []*ExecResult) bool {
+	v.Runs++
+	if diverges(results) {
+		v.Divergent++
+		v.Results = results
+		if hanged(results) {
+			v.Timeouts++
+		}
+	}
+	if v.Divergent == 0 {
+		return true
+	}
+	v.Nondeterminism = binomialTail(v.Runs, v.Divergent, vrf.flakyRate)
+	if vrf.rerunPolicy == rerunPolicyAgreement {
+		return vrf.classifyAgreement(v)
+	}
+	if v.Nondeterminism < vrf.mismatchThreshold {
+		v.Mismatch = true
+		return true
+	}
+	// Check if the mismatch can still be confirmed if all the remaining reruns diverge.
+	maxRuns := 1 + vrf.reruns
+	remaining := maxRuns - v.Runs
+	return remaining <= 0 || binomialTail(maxRuns, v.Divergent+remaining, vrf.flakyRate) >= vrf.mismatchThreshold
+}
+
+// classifyAgreement classifies the verdict with the agreement rerun policy.
+func (vrf *Verifier) classifyAgreement(v *Verdict) bool {
+	maxRuns := 1 + vrf.reruns
+	// Subtract a small value, so that e.g. 0.7*10 is not rounded up to 8.
+	needed := int(math.Ceil(vrf.agreement*float64(maxRuns) - 1e-9))
+	if needed < 1 {
+		needed = 1
+	}
+	if v.Divergent >= needed {
+		v.Mismatch = true
+		return true
+	}
+	return v.Divergent+maxRuns-v.Runs < needed
+}
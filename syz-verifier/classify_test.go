@@ -0,0 +1,236 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/prog"
+)
+
+func TestBinomialTail(t *testing.T) {
+	tests := []struct {
+		n, k int
+		p    float64
+		want float64
+	}{
+		{1, 0, 0.5, 1},
+		{1, 1, 0.5, 0.5},
+		{7, 7, 0.5, 1.0 / 128},
+		{2, 1, 0.5, 0.75},
+		{11, 10, 0.5, 12.0 / 2048},
+		{10, 3, 0.1, 0.07019083},
+		{5, 5, 0, 0},
+	}
+	for _, test := range tests {
+		if got := binomialTail(test.n, test.k, test.p); math.Abs(got-test.want) > 1e-8 {
+			t.Errorf("binomialTail(%v, %v, %v) = %v, want %v", test.n, test.k, test.p, got, test.want)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	same := []*ExecResult{makeExecResult(0, []int{1, 2}), makeExecResult(1, []int{1, 2})}
+	diff := []*ExecResult{makeExecResult(0, []int{1, 2}), makeExecResult(1, []int{1, 3})}
+	tests := []struct {
+		name      string
+		runs      [][]*ExecResult
+		mismatch  bool
+		divergent int
+	}{
+		{"no divergence", [][]*ExecResult{same}, false, 0},
+		{"deterministic mismatch", [][]*ExecResult{diff, diff, diff, diff, diff, diff, diff}, true, 7},
+		{"mismatch after a match", [][]*ExecResult{diff, same, diff, diff, diff, diff, diff, diff, diff, diff, diff},
+			true, 10},
+		{"flaky", [][]*ExecResult{diff, same, same}, false, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vrf := &Verifier{
+				reruns:            10,
+				flakyRate:         defaultFlakyRate,
+				mismatchThreshold: defaultMismatchThreshold,
+			}
+			v := new(Verdict)
+			for i, res := range test.runs {
+				done := vrf.classify(v, res)
+				if done != (i == len(test.runs)-1) {
+					t.Fatalf("run %v: done=%v", i, done)
+				}
+			}
+			if v.Mismatch != test.mismatch || v.Divergent != test.divergent || v.Runs != len(test.runs) {
+				t.Fatalf("bad verdict: %+v", v)
+			}
+			if v.Mismatch && v.Nondeterminism >= defaultMismatchThreshold ||
+				!v.Mismatch && v.Divergent != 0 && v.Nondeterminism < defaultMismatchThreshold {
+				t.Fatalf("bad nondeterminism probability: %+v", v)
+			}
+		})
+	}
+}
+
+func TestClassifyAgreement(t *testing.T) {
+	same := []*ExecResult{makeExecResult(0, []int{1, 2}), makeExecResult(1, []int{1, 2})}
+	diff := []*ExecResult{makeExecResult(0, []int{1, 2}), makeExecResult(1, []int{1, 3})}
+	tests := []struct {
+		name      string
+		agreement float64
+		runs      [][]*ExecResult
+		mismatch  bool
+	}{
+		{"all runs diverge", 1, [][]*ExecResult{diff, diff, diff, diff}, true},
+		{"one run matches", 1, [][]*ExecResult{diff, diff, same}, false},
+		{"majority diverges", 0.5, [][]*ExecResult{diff, same, diff}, true},
+		{"majority matches", 0.7, [][]*ExecResult{diff, same, same}, false},
+		{"first run diverges", 0.1, [][]*ExecResult{diff}, true},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			vrf := &Verifier{
+				reruns:      3,
+				flakyRate:   defaultFlakyRate,
+				rerunPolicy: rerunPolicyAgreement,
+				agreement:   test.agreement,
+			}
+			v := new(Verdict)
+			for i, res := range test.runs {
+				done := vrf.classify(v, res)
+				if done != (i == len(test.runs)-1) {
+					t.Fatalf("run %v: done=%v", i, done)
+				}
+			}
+			if v.Mismatch != test.mismatch {
+				t.Fatalf("bad verdict: %+v", v)
+			}
+		})
+	}
+	if err := checkRerunPolicy(rerunPolicyAgreement, 3, defaultFlakyRate, defaultMismatchThreshold, 0); err == nil {
+		t.Errorf("zero agreement is accepted")
+	}
+	if err := checkRerunPolicy("foo", 10, defaultFlakyRate, defaultMismatchThreshold, defaultAgreement); err == nil {
+		t.Errorf("unknown rerun policy is accepted")
+	}
+}
+
+// classifyDivergent classifies a program that diverges in all runs.
+func classifyDivergent(vrf *Verifier) *Verdict {
+	v := new(Verdict)
+	for done := false; !done; {
+		done = vrf.classify(v, []*ExecResult{makeExecResult(0, []int{1}), makeExecResult(1, []int{2})})
+	}
+	return v
+}
+
+func TestCheckBinomialReruns(t *testing.T) {
+	if minRuns := minBinomialRuns(defaultFlakyRate, defaultMismatchThreshold); minRuns != 7 {
+		t.Fatalf("minBinomialRuns returned %v, want 7", minRuns)
+	}
+	tests := []struct {
+		reruns            int
+		flakyRate         float64
+		mismatchThreshold float64
+		ok                bool
+	}{
+		{10, defaultFlakyRate, defaultMismatchThreshold, true},
+		{6, defaultFlakyRate, defaultMismatchThreshold, true},
+		{5, defaultFlakyRate, defaultMismatchThreshold, false},
+		{0, defaultFlakyRate, defaultMismatchThreshold, false},
+		{5, defaultFlakyRate, 0.05, true},
+		{3, 0.1, defaultMismatchThreshold, true},
+		{10, 1, defaultMismatchThreshold, false},
+		{10, defaultFlakyRate, 0, false},
+	}
+	for _, test := range tests {
+		err := checkRerunPolicy(rerunPolicyBinomial, test.reruns, test.flakyRate, test.mismatchThreshold,
+			defaultAgreement)
+		if (err == nil) != test.ok {
+			t.Errorf("reruns=%v flaky-rate=%v mismatch-threshold=%v: got error %v",
+				test.reruns, test.flakyRate, test.mismatchThreshold, err)
+		}
+		if err != nil || test.flakyRate >= 1 {
+			continue
+		}
+		// The check must agree with classify: a program that diverges in all runs is confirmed.
+		vrf := &Verifier{
+			reruns:            test.reruns,
+			flakyRate:         test.flakyRate,
+			mismatchThreshold: test.mismatchThreshold,
+			rerunPolicy:       rerunPolicyBinomial,
+		}
+		if v := classifyDivergent(vrf); !v.Mismatch {
+			t.Errorf("reruns=%v flaky-rate=%v mismatch-threshold=%v: mismatch is not confirmed: %+v",
+				test.reruns, test.flakyRate, test.mismatchThreshold, v)
+		}
+	}
+	// With fewer reruns than accepted classify never confirms the mismatch.
+	vrf := &Verifier{
+		reruns:            5,
+		flakyRate:         defaultFlakyRate,
+		mismatchThreshold: defaultMismatchThreshold,
+		rerunPolicy:       rerunPolicyBinomial,
+	}
+	if v := classifyDivergent(vrf); v.Mismatch {
+		t.Errorf("mismatch is confirmed with 5 reruns: %+v", v)
+	}
+}
+
+func TestFlakyCause(t *testing.T) {
+	same := func() []*ExecResult {
+		return []*ExecResult{makeExecResult(0, []int{1, 3, 2}), makeExecResult(1, []int{1, 3, 2})}
+	}
+	diff := func() []*ExecResult {
+		return []*ExecResult{makeExecResult(0, []int{1, 3, 2}), makeExecResult(1, []int{1, 3, 5})}
+	}
+	hang := func() []*ExecResult {
+		res := diff()
+		res[1].Hanged = true
+		return res
+	}
+	tests := []struct {
+		name  string
+		runs  [][]*ExecResult
+		cause string
+	}{
+		{"errno", [][]*ExecResult{diff(), same(), same()}, flakyCauseErrno},
+		{"timeout", [][]*ExecResult{hang(), same(), same()}, flakyCauseTimeout},
+		{"timeout and errno", [][]*ExecResult{hang(), diff(), same(), same(), same()}, flakyCauseErrno},
+		{"crash", [][]*ExecResult{diff(), nil}, flakyCauseCrash},
+		{"mismatch", [][]*ExecResult{diff(), diff(), diff()}, ""},
+	}
+	p := getTestProgram(t)
+	stats := emptyTestStats()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vrf := &Verifier{
+				stats:             stats,
+				reruns:            2,
+				flakyRate:         defaultFlakyRate,
+				mismatchThreshold: 0.2,
+			}
+			run := 0
+			v := vrf.testProgram(p, func(p *prog.Prog, env EnvDescr) ([]*ExecResult, error) {
+				res := test.runs[run]
+				run++
+				if res == nil {
+					return nil, errors.New("VM crashed during the task execution")
+				}
+				return res, nil
+			})
+			if v == nil || v.Cause != test.cause {
+				t.Fatalf("bad verdict: %+v, want cause %q", v, test.cause)
+			}
+		})
+	}
+	want := map[string]int64{flakyCauseErrno: 2, flakyCauseTimeout: 1, flakyCauseCrash: 1}
+	if diff := cmp.Diff(want, stats.GetJSON(1).FlakyCauses); diff != "" {
+		t.Errorf("flaky causes (-want +got):\n%s", diff)
+	}
+	if stats.ExecErrorProgs != 0 {
+		t.Errorf("crash during reruns is counted as an execution error")
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sync/atomic"
+)
+
+// Tasks in CleanVMEnvironment are executed only by Runners in VMs that did not execute any other
+// task since they booted, so the results are not polluted by side effects of prior programs.
+// If CleanVMEnvironment tasks of a kernel are waiting and not enough VMs of the kernel are being
+// rebooted for them, a VM that already executed tasks is rebooted instead of receiving a new task.
+// With -clean-vm-rerun, confirmed mismatches are rerun once in clean VMs; if the rerun
+// does not diverge, the mismatch is attributed to the state of the VMs.
+
+// vmEnvState tracks whether a VM is clean. It is protected by RPCServer.mu.
+type vmEnvState struct {
+	// dirty is set once a task was dispatched to the VM.
+	dirty bool
+	// rebooting is set when the VM is rebooted to execute CleanVMEnvironment tasks.
+	rebooting bool
+	// stop is closed to reboot the VM, stopped is set once it is closed.
+	stop    chan bool
+	stopped bool
+}
+
+// vmBooted is called when the VM is (re)created and returns the channel that is closed to reboot the VM.
+func (srv *RPCServer) vmBooted(poolID, vmID int) <-chan bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.vmEnvs == nil {
+		srv.vmEnvs = make(map[int]*vmEnvState)
+	}
+	key := vmTasksKey(poolID, vmID)
+	st := &vmEnvState{stop: make(chan bool)}
+	// The reboot is accounted until the VM asks for its first task.
+	if prev := srv.vmEnvs[key]; prev != nil && prev.rebooting {
+		st.rebooting = true
+	}
+	srv.vmEnvs[key] = st
+	return st.stop
+}
+
+// vmEnv returns the environment of the tasks the VM can execute and marks the VM dirty.
+// The VM is expected to receive a task right after the call.
+func (srv *RPCServer) vmEnv(poolID, vmID int) EnvDescr {
+	srv.mu.Lock()
+	st := srv.vmEnvs[vmTasksKey(poolID, vmID)]
+	if st == nil || st.dirty {
+		srv.mu.Unlock()
+		return NewEnvironment
+	}
+	st.dirty = true
+	rebooted := st.rebooting
+	st.rebooting = false
+	srv.mu.Unlock()
+	if rebooted {
+		srv.vrf.cleanVMRebootDone(poolID)
+	}
+	if srv.vrf.snapshots && !srv.remote.isRemote(poolID) {
+		return SnapshotEnvironment
+	}
+	return CleanVMEnvironment
+}
+
+// rebootVM stops the VM, so that it is recreated.
+func (srv *RPCServer) rebootVM(poolID, vmID int) {
+	srv.stopVM(poolID, vmID, true)
+}
+
+// restartVM stops the VM, so that it is recreated, without accounting it as a reboot
+// for CleanVMEnvironment tasks (see runnerhealth.go).
+func (srv *RPCServer) restartVM(poolID, vmID int) {
+	srv.stopVM(poolID, vmID, false)
+}
+
+func (srv *RPCServer) stopVM(poolID, vmID int, rebooting bool) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	st := srv.vmEnvs[vmTasksKey(poolID, vmID)]
+	if st == nil || st.stopped {
+		return
+	}
+	if rebooting {
+		st.rebooting = true
+	}
+	st.stopped = true
+	close(st.stop)
+	srv.remote.requestReboot(poolID, vmID)
+}
+
+// needCleanVMReboot returns true if a VM of the kernel must be rebooted (or restored from
+// the snapshot) to execute the waiting CleanVMEnvironment and SnapshotEnvironment tasks
+// and accounts the reboot. Must be called with vrf.tasksMutex held.
+func (vrf *Verifier) needCleanVMReboot(kernel int) bool {
+	waiting := vrf.kernelEnvTasks[kernel][CleanVMEnvironment].Len() +
+		vrf.kernelEnvTasks[kernel][SnapshotEnvironment].Len()
+	if waiting <= vrf.cleanVMReboots[kernel] {
+		return false
+	}
+	if vrf.cleanVMReboots == nil {
+		vrf.cleanVMReboots = make(map[int]int)
+	}
+	vrf.cleanVMReboots[kernel]++
+	return true
+}
+
+// cleanVMRebootDone is called when a VM rebooted to execute CleanVMEnvironment tasks is ready.
+func (vrf *Verifier) cleanVMRebootDone(kernel int) {
+	vrf.tasksMutex.Lock()
+	defer vrf.tasksMutex.Unlock()
+	if vrf.cleanVMReboots[kernel] > 0 {
+		vrf.cleanVMReboots[kernel]--
+	}
+}
+
+// rerunInCleanVM reruns the mismatching program in clean VMs and returns true
+// if the results do not diverge anymore.
+func (vrf *Verifier) rerunInCleanVM(v *Verdict, run func(env EnvDescr) ([]*ExecResult, error)) bool {
+	env := CleanVMEnvironment
+	if vrf.snapshots {
+		env = SnapshotEnvironment
+	}
+	res, err := run(env)
+	if err != nil {
+		return false
+	}
+	atomic.AddInt64(&vrf.stats.CleanVMReruns, 1)
+	if diverges(res) {
+		return false
+	}
+	atomic.AddInt64(&vrf.stats.CleanVMFlips, 1)
+	v.Mismatch = false
+	v.Cause = flakyCauseVMState
+	return true
+}
@@ -0,0 +1,113 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/syzkaller/prog"
+)
+
+func makeTestQueues(vrf *Verifier, kernels int) {
+	vrf.initQueues(kernels)
+}
+
+func TestCleanVMEnvironment(t *testing.T) {
+	p := getTestProgram(t)
+	vrf := &Verifier{stats: MakeStats()}
+	makeTestQueues(vrf, 1)
+	srv := &RPCServer{vrf: vrf}
+	stop0 := srv.vmBooted(0, 0)
+	srv.vmBooted(0, 1)
+
+	clean := MakeExecTask(p)
+	defer DeleteExecTask(clean)
+	other := MakeExecTask(p)
+	defer DeleteExecTask(other)
+	vrf.kernelEnvTasks[0][CleanVMEnvironment].PushTask(clean)
+	vrf.kernelEnvTasks[0][NewEnvironment].PushTask(other)
+
+	// VM 0 is clean, so it gets the clean VM task first and becomes dirty.
+	if env := srv.vmEnv(0, 0); env != CleanVMEnvironment {
+		t.Fatalf("booted VM env: got %v, want %v", env, CleanVMEnvironment)
+	}
+	if task := vrf.GetRunnerTask(0, CleanVMEnvironment); task == nil || task.ID != clean.ID {
+		t.Fatalf("clean VM got task %+v, want %v", task, clean.ID)
+	}
+	if env := srv.vmEnv(0, 0); env != NewEnvironment {
+		t.Fatalf("used VM env: got %v, want %v", env, NewEnvironment)
+	}
+
+	// A new clean VM task makes the dirty VM 0 reboot.
+	clean2 := MakeExecTask(p)
+	defer DeleteExecTask(clean2)
+	vrf.kernelEnvTasks[0][CleanVMEnvironment].PushTask(clean2)
+	if task := vrf.GetRunnerTask(0, NewEnvironment); task != nil {
+		t.Fatalf("dirty VM got task %+v instead of the reboot", task)
+	}
+	srv.rebootVM(0, 0)
+	select {
+	case <-stop0:
+	default:
+		t.Fatalf("the VM is not stopped")
+	}
+	// Only one VM is rebooted for one task, the other dirty VMs get the remaining tasks.
+	if task := vrf.GetRunnerTask(0, NewEnvironment); task == nil || task.ID != other.ID {
+		t.Fatalf("dirty VM got task %+v, want %v", task, other.ID)
+	}
+	// VM 1 is still clean and gets the task before the rebooted VM.
+	if env := srv.vmEnv(0, 1); env != CleanVMEnvironment {
+		t.Fatalf("booted VM env: got %v, want %v", env, CleanVMEnvironment)
+	}
+	if task := vrf.GetRunnerTask(0, CleanVMEnvironment); task == nil || task.ID != clean2.ID {
+		t.Fatalf("clean VM got task %+v, want %v", task, clean2.ID)
+	}
+	srv.vmBooted(0, 0)
+	if env := srv.vmEnv(0, 0); env != CleanVMEnvironment {
+		t.Fatalf("rebooted VM env: got %v, want %v", env, CleanVMEnvironment)
+	}
+	if vrf.cleanVMReboots[0] != 0 {
+		t.Fatalf("reboot is still accounted: %v", vrf.cleanVMReboots)
+	}
+}
+
+func TestCleanVMRerun(t *testing.T) {
+	p := getTestProgram(t)
+	diff := []*ExecResult{makeExecResult(0, []int{1, 3, 2}), makeExecResult(1, []int{1, 3, 5})}
+	same := []*ExecResult{makeExecResult(0, []int{1, 3, 2}), makeExecResult(1, []int{1, 3, 2})}
+	vrf := &Verifier{
+		stats:             emptyTestStats(),
+		reruns:            2,
+		flakyRate:         defaultFlakyRate,
+		mismatchThreshold: 0.2,
+		cleanVMRerun:      true,
+	}
+	for _, test := range []struct {
+		clean    []*ExecResult
+		mismatch bool
+	}{
+		{diff, true},
+		{same, false},
+	} {
+		var envs []EnvDescr
+		v := vrf.testProgram(p, func(p *prog.Prog, env EnvDescr) ([]*ExecResult, error) {
+			envs = append(envs, env)
+			if env == CleanVMEnvironment {
+				return test.clean, nil
+			}
+			return diff, nil
+		})
+		if len(envs) != 4 || envs[3] != CleanVMEnvironment {
+			t.Fatalf("bad execution environments: %v", envs)
+		}
+		if v.Mismatch != test.mismatch || !test.mismatch && v.Cause != flakyCauseVMState {
+			t.Fatalf("bad verdict: %+v", v)
+		}
+	}
+	if vrf.stats.CleanVMReruns != 2 || vrf.stats.CleanVMFlips != 1 || vrf.stats.MismatchingProgs != 1 ||
+		vrf.stats.FlakyProgs != 1 {
+		t.Errorf("bad stats: reruns %v, flips %v, mismatching %v, flaky %v", vrf.stats.CleanVMReruns,
+			vrf.stats.CleanVMFlips, vrf.stats.MismatchingProgs, vrf.stats.FlakyProgs)
+	}
+}
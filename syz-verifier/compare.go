@@ -0,0 +1,128 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+)
+
+// Two statistics files saved with -stats-json can be compared with -compare to find syscalls
+// whose mismatch rate changed between verification campaigns (e.g. of different kernel versions).
+
+// compareZScore is the minimal absolute value of the z-score of the two-proportion z-test
+// for a change of the mismatch rate to be reported (corresponds to p < 0.01).
+const compareZScore = 2.576
+
+// CallDiff describes the change of the mismatch rate of a syscall between two campaigns.
+type CallDiff struct {
+	Name           string
+	OldMismatches  int64
+	OldOccurrences int64
+	NewMismatches  int64
+	NewOccurrences int64
+	// Z is the z-score of the change, positive if the mismatch rate increased.
+	Z float64
+}
+
+func (d *CallDiff) oldRate() float64 {
+	return getPercentage(d.OldMismatches, d.OldOccurrences)
+}
+
+func (d *CallDiff) newRate() float64 {
+	return getPercentage(d.NewMismatches, d.NewOccurrences)
+}
+
+// diffStats returns syscalls executed in both campaigns whose mismatch rate changed significantly,
+// in decreasing order of the significance of the change.
+func diffStats(old, cur *StatsJSON) []*CallDiff {
+	oldCalls := make(map[string]*CallStatsJSON)
+	for _, call := range old.Calls {
+		oldCalls[call.Name] = call
+	}
+	var res []*CallDiff
+	for _, call := range cur.Calls {
+		prev := oldCalls[call.Name]
+		if prev == nil || prev.Occurrences == 0 || call.Occurrences == 0 {
+			continue
+		}
+		d := &CallDiff{
+			Name:           call.Name,
+			OldMismatches:  prev.Mismatches,
+			OldOccurrences: prev.Occurrences,
+			NewMismatches:  call.Mismatches,
+			NewOccurrences: call.Occurrences,
+		}
+		d.Z = zScore(d.OldMismatches, d.OldOccurrences, d.NewMismatches, d.NewOccurrences)
+		if math.Abs(d.Z) >= compareZScore {
+			res = append(res, d)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		zi, zj := math.Abs(res[i].Z), math.Abs(res[j].Z)
+		if zi != zj {
+			return zi > zj
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// zScore returns the z-score of the two-proportion z-test for x1/n1 and x2/n2.
+func zScore(x1, n1, x2, n2 int64) float64 {
+	p1, p2 := float64(x1)/float64(n1), float64(x2)/float64(n2)
+	p := float64(x1+x2) / float64(n1+n2)
+	se := math.Sqrt(p * (1 - p) * (1/float64(n1) + 1/float64(n2)))
+	if se == 0 {
+		return 0
+	}
+	return (p2 - p1) / se
+}
+
+func loadStatsJSON(file string) (*StatsJSON, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	stats := new(StatsJSON)
+	if err := json.Unmarshal(data, stats); err != nil {
+		return nil, fmt.Errorf("failed to parse %v: %v", file, err)
+	}
+	return stats, nil
+}
+
+// runCompare prints syscalls whose mismatch rate changed significantly between the two stats files.
+func runCompare(w io.Writer, oldFile, newFile string) error {
+	old, err := loadStatsJSON(oldFile)
+	if err != nil {
+		return err
+	}
+	cur, err := loadStatsJSON(newFile)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "programs: %d -> %d, mismatching programs: %d (%0.2f %%) -> %d (%0.2f %%)\n\n",
+		old.TotalProgs, cur.TotalProgs,
+		old.MismatchingProgs, getPercentage(old.MismatchingProgs, old.TotalProgs),
+		cur.MismatchingProgs, getPercentage(cur.MismatchingProgs, cur.TotalProgs))
+	diffs := diffStats(old, cur)
+	if len(diffs) == 0 {
+		fmt.Fprintf(w, "no significant changes of syscall mismatch rates\n")
+		return nil
+	}
+	for _, d := range diffs {
+		change := "increased"
+		if d.Z < 0 {
+			change = "decreased"
+		}
+		fmt.Fprintf(w, "%s: mismatch rate %s: %d / %d (%0.2f %%) -> %d / %d (%0.2f %%), z = %0.2f\n",
+			d.Name, change, d.OldMismatches, d.OldOccurrences, d.oldRate(),
+			d.NewMismatches, d.NewOccurrences, d.newRate(), d.Z)
+	}
+	return nil
+}
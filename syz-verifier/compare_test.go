@@ -0,0 +1,37 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffStats(t *testing.T) {
+	old := &StatsJSON{Calls: []*CallStatsJSON{
+		{Name: "same", Mismatches: 10, Occurrences: 1000},
+		{Name: "more", Mismatches: 1, Occurrences: 1000},
+		{Name: "less", Mismatches: 100, Occurrences: 1000},
+		{Name: "rare", Mismatches: 0, Occurrences: 3},
+		{Name: "gone", Mismatches: 100, Occurrences: 1000},
+	}}
+	cur := &StatsJSON{Calls: []*CallStatsJSON{
+		{Name: "same", Mismatches: 12, Occurrences: 1000},
+		{Name: "more", Mismatches: 50, Occurrences: 1000},
+		{Name: "less", Mismatches: 10, Occurrences: 500},
+		{Name: "rare", Mismatches: 2, Occurrences: 3},
+		{Name: "added", Mismatches: 100, Occurrences: 1000},
+	}}
+	var got []string
+	for _, d := range diffStats(old, cur) {
+		got = append(got, d.Name)
+		if (d.Name == "more") != (d.Z > 0) {
+			t.Errorf("%v: bad sign of z-score: %v", d.Name, d.Z)
+		}
+	}
+	if diff := cmp.Diff([]string{"more", "less"}, got); diff != "" {
+		t.Errorf("diff mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/syzkaller/pkg/ipc"
+)
+
+// Coverage is collected by the Runners if "cover" is enabled in the kernel config.
+// The number of distinct PCs reached on each kernel shows whether the kernels
+// are exercised comparably.
+
+// KernelCoverageJSON describes the coverage reached on a kernel.
+type KernelCoverageJSON struct {
+	Pool int
+	// Cover is the number of distinct PCs.
+	Cover int
+	// CoverPerMinute is the average growth rate of the coverage.
+	CoverPerMinute float64
+}
+
+// addCoverage records the coverage of the program executed on the kernel.
+func (stats *Stats) addCoverage(pool int, info *ipc.ProgInfo) {
+	n := len(info.Extra.Cover)
+	for _, call := range info.Calls {
+		n += len(call.Cover)
+	}
+	if n == 0 {
+		return
+	}
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	if stats.cover == nil {
+		stats.cover = make(map[int]map[uint32]bool)
+	}
+	pcs := stats.cover[pool]
+	if pcs == nil {
+		pcs = make(map[uint32]bool)
+		stats.cover[pool] = pcs
+	}
+	for _, call := range info.Calls {
+		for _, pc := range call.Cover {
+			pcs[pc] = true
+		}
+	}
+	for _, pc := range info.Extra.Cover {
+		pcs[pc] = true
+	}
+}
+
+// coverage returns the coverage of all kernels ordered by pool index.
+// Must be called with stats.mu held.
+func (stats *Stats) coverage(deltaTime float64) []*KernelCoverageJSON {
+	var res []*KernelCoverageJSON
+	for pool, pcs := range stats.cover {
+		kc := &KernelCoverageJSON{Pool: pool, Cover: len(pcs)}
+		if deltaTime != 0 {
+			kc.CoverPerMinute = float64(len(pcs)) / deltaTime
+		}
+		res = append(res, kc)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Pool < res[j].Pool
+	})
+	return res
+}
+
+func formatCoverage(cover []*KernelCoverageJSON) string {
+	var res []string
+	for _, kc := range cover {
+		res = append(res, fmt.Sprintf("pool %d: %d (%0.2f / minute)", kc.Pool, kc.Cover, kc.CoverPerMinute))
+	}
+	return strings.Join(res, ", ")
+}
@@ -0,0 +1,40 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/pkg/ipc"
+)
+
+func TestCoverage(t *testing.T) {
+	stats := dummyStats()
+	stats.addCoverage(0, &ipc.ProgInfo{})
+	if cover := stats.GetJSON(10).Coverage; cover != nil {
+		t.Fatalf("empty coverage is reported: %+v", cover)
+	}
+	stats.addCoverage(1, &ipc.ProgInfo{Calls: []ipc.CallInfo{{Cover: []uint32{1, 2}}, {Cover: []uint32{2, 3}}}})
+	stats.addCoverage(0, &ipc.ProgInfo{
+		Calls: []ipc.CallInfo{{Cover: []uint32{1, 2, 3, 4}}},
+		Extra: ipc.CallInfo{Cover: []uint32{5}},
+	})
+	stats.addCoverage(1, &ipc.ProgInfo{Calls: []ipc.CallInfo{{Cover: []uint32{3, 4}}}})
+	want := []*KernelCoverageJSON{
+		{Pool: 0, Cover: 5, CoverPerMinute: 0.5},
+		{Pool: 1, Cover: 4, CoverPerMinute: 0.4},
+	}
+	if diff := cmp.Diff(want, stats.GetJSON(10).Coverage); diff != "" {
+		t.Errorf("coverage mismatch (-want +got):\n%s", diff)
+	}
+	if text, want := stats.GetTextDescription(10),
+		"coverage (PCs): pool 0: 5 (0.50 / minute), pool 1: 4 (0.40 / minute)\n"; !strings.Contains(text, want) {
+		t.Errorf("stats don't contain %q:\n%s", want, text)
+	}
+	if cover := stats.sample(nil).Cover; cover[0] != 5 || cover[1] != 4 {
+		t.Errorf("bad coverage in the stats sample: %v", cover)
+	}
+}
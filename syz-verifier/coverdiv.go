@@ -0,0 +1,122 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/google/syzkaller/pkg/ipc"
+	"github.com/google/syzkaller/prog"
+)
+
+// With -cover-divergence=N, programs whose calls return the same states on all kernels, but reach
+// drastically different amounts of kernel code are flagged as well: a call diverges if the number
+// of distinct PCs it reached on some kernel is at least N times larger than on another kernel.
+// The PCs themselves can't be compared, since the kernels are different builds. This is a weaker
+// divergence indicator than the return states (e.g. a refactoring of the kernel changes its coverage
+// too), but it catches calls that silently take a different path. Requires "cover" in the configs.
+
+// coverDivergenceMinPCs is the minimum difference of the number of PCs for a call to diverge,
+// smaller differences of calls with a small coverage are noise.
+const coverDivergenceMinPCs = 50
+
+// CoverDivergence describes a call whose coverage differs drastically between the kernels.
+type CoverDivergence struct {
+	// Call is the name of the call and Index is its index in the program.
+	Call  string
+	Index int
+	// Cover is the number of distinct PCs reached by the call on each kernel (pool index).
+	Cover map[int]int
+}
+
+// coverDivergence returns the calls whose coverage on the kernels differs at least ratio times.
+func coverDivergence(p *prog.Prog, results []*ExecResult, ratio float64) []*CoverDivergence {
+	var res []*CoverDivergence
+	for _, r := range results {
+		if r.Crashed || r.Hanged || len(r.Info.Calls) != len(p.Calls) {
+			return nil
+		}
+	}
+	for idx, call := range p.Calls {
+		cover := make(map[int]int)
+		min, max := -1, 0
+		for _, r := range results {
+			ci := &r.Info.Calls[idx]
+			if ci.Flags&ipc.CallFinished == 0 {
+				cover = nil
+				break
+			}
+			n := distinctPCs(ci.Cover)
+			cover[r.Pool] = n
+			if min == -1 || n < min {
+				min = n
+			}
+			if n > max {
+				max = n
+			}
+		}
+		if cover == nil || max-min < coverDivergenceMinPCs || float64(max) < ratio*float64(min) {
+			continue
+		}
+		res = append(res, &CoverDivergence{Call: call.Meta.Name, Index: idx, Cover: cover})
+	}
+	return res
+}
+
+func distinctPCs(cover []uint32) int {
+	pcs := make(map[uint32]bool, len(cover))
+	for _, pc := range cover {
+		pcs[pc] = true
+	}
+	return len(pcs)
+}
+
+// addCoverDivergence records the coverage divergent program and returns true
+// if some of the calls diverged for the first time.
+func (stats *Stats) addCoverDivergence(divs []*CoverDivergence) bool {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	atomic.AddInt64(&stats.CoverDivergentProgs, 1)
+	if stats.coverDivergences == nil {
+		stats.coverDivergences = make(map[string]int64)
+	}
+	added := false
+	for _, div := range divs {
+		if stats.coverDivergences[div.Call] == 0 {
+			added = true
+		}
+		stats.coverDivergences[div.Call]++
+	}
+	return added
+}
+
+// createCoverReport creates the report of the coverage divergent program.
+func createCoverReport(p *prog.Prog, divs []*CoverDivergence, ratio float64) []byte {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "COVERAGE DIVERGENCE: the calls return the same states, "+
+		"but their coverage differs at least %.2f times\n\n", ratio)
+	buf.Write(p.Serialize())
+	fmt.Fprintf(buf, "\n")
+	for _, div := range divs {
+		fmt.Fprintf(buf, "call #%d %s: %s\n", div.Index, div.Call, formatCoverDivergence(div.Cover))
+	}
+	return buf.Bytes()
+}
+
+func formatCoverDivergence(cover map[int]int) string {
+	pools := make([]int, 0, len(cover))
+	for pool := range cover {
+		pools = append(pools, pool)
+	}
+	sort.Ints(pools)
+	var res []string
+	for _, pool := range pools {
+		res = append(res, fmt.Sprintf("pool %d: %d PCs", pool, cover[pool]))
+	}
+	return strings.Join(res, ", ")
+}
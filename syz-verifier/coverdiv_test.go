@@ -0,0 +1,92 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/syzkaller/pkg/ipc"
+	"github.com/google/syzkaller/prog"
+)
+
+func makeCoverResult(pool int, covers ...int) *ExecResult {
+	r := &ExecResult{Pool: pool}
+	for _, n := range covers {
+		ci := ipc.CallInfo{Flags: ipc.CallExecuted | ipc.CallFinished}
+		for pc := 0; pc < n; pc++ {
+			// Duplicate PCs must be counted once.
+			ci.Cover = append(ci.Cover, uint32(pc), uint32(pc))
+		}
+		r.Info.Calls = append(r.Info.Calls, ci)
+	}
+	return r
+}
+
+func TestCoverDivergence(t *testing.T) {
+	p := getTestProgram(t)
+	tests := []struct {
+		name    string
+		res     []*ExecResult
+		diverge []int
+	}{
+		{"same", []*ExecResult{makeCoverResult(0, 100, 10, 0), makeCoverResult(1, 120, 10, 0)}, nil},
+		{"ratio", []*ExecResult{makeCoverResult(0, 100, 10, 0), makeCoverResult(1, 400, 10, 0)}, []int{0}},
+		{"below ratio", []*ExecResult{makeCoverResult(0, 100, 10, 0), makeCoverResult(1, 399, 10, 0)}, nil},
+		{"small", []*ExecResult{makeCoverResult(0, 100, 1, 0), makeCoverResult(1, 100, 40, 0)}, nil},
+		{"three kernels", []*ExecResult{makeCoverResult(0, 100, 10, 0), makeCoverResult(1, 100, 10, 60),
+			makeCoverResult(2, 100, 10, 0)}, []int{2}},
+		{"crashed", []*ExecResult{makeCoverResult(0, 100, 10, 0), makeExecResultCrashed(1)}, nil},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			var got []int
+			for _, div := range coverDivergence(p, test.res, 4) {
+				got = append(got, div.Index)
+				if div.Call != p.Calls[div.Index].Meta.Name || len(div.Cover) != len(test.res) {
+					t.Errorf("bad divergence: %+v", div)
+				}
+			}
+			if len(got) != len(test.diverge) || len(got) != 0 && got[0] != test.diverge[0] {
+				t.Errorf("got diverging calls %v, want %v", got, test.diverge)
+			}
+		})
+	}
+}
+
+func TestTestProgramCoverDivergence(t *testing.T) {
+	p := getTestProgram(t)
+	vrf := &Verifier{
+		stats:             emptyTestStats(),
+		reruns:            2,
+		flakyRate:         defaultFlakyRate,
+		mismatchThreshold: 0.2,
+		coverDivergence:   4,
+	}
+	run := func(p *prog.Prog, env EnvDescr) ([]*ExecResult, error) {
+		return []*ExecResult{makeCoverResult(0, 100, 10, 0), makeCoverResult(1, 400, 10, 0)}, nil
+	}
+	v := vrf.testProgram(p, run)
+	if v.Mismatch || len(v.CoverDivergence) != 1 || !v.NewCoverDivergence {
+		t.Fatalf("bad verdict: %+v", v)
+	}
+	if v = vrf.testProgram(p, run); v.NewCoverDivergence {
+		t.Errorf("the same call diverged for the first time twice")
+	}
+	if vrf.stats.CoverDivergentProgs != 2 || vrf.stats.coverDivergences["breaks_returns"] != 2 {
+		t.Errorf("got %v coverage divergent programs, %v", vrf.stats.CoverDivergentProgs,
+			vrf.stats.coverDivergences)
+	}
+	report := string(createCoverReport(p, v.CoverDivergence, vrf.coverDivergence))
+	if !strings.Contains(report, "call #0 breaks_returns: pool 0: 100 PCs, pool 1: 400 PCs") {
+		t.Errorf("bad report:\n%s", report)
+	}
+
+	// The coverage is not compared by default.
+	vrf.coverDivergence = 0
+	if v = vrf.testProgram(p, run); v.CoverDivergence != nil {
+		t.Errorf("coverage is compared with -cover-divergence=0")
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/google/syzkaller/pkg/csource"
+	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/pkg/mgrconfig"
+	"github.com/google/syzkaller/prog"
+)
+
+// Each mismatch report result-N is accompanied by result-N.c, a standalone C reproducer
+// of the program, so that the difference can be reproduced without syzkaller tooling.
+// The program is executed once in a single thread and prints the result of each call
+// to stderr as "#N call = res" or "#N call = -1 (errno E: description)". The header of
+// the reproducer lists the return states of the calls on all kernels, the calls that
+// differ are marked as mismatches.
+
+// cReproOpts returns the options for the C reproducers of the programs executed on the kernel.
+func cReproOpts(cfg *mgrconfig.Config) csource.Options {
+	opts := csource.DefaultOpts(cfg)
+	opts.Threaded = false
+	opts.Repeat = false
+	opts.Procs = 1
+	opts.NetReset = false
+	opts.Repro = false
+	opts.Strace = true
+	return opts
+}
+
+// createCRepro returns the C reproducer of the mismatch described by the result report.
+func createCRepro(rr *ResultReport, p *prog.Prog, opts csource.Options) ([]byte, error) {
+	src, err := csource.Write(p, opts)
+	if err != nil {
+		return nil, err
+	}
+	if formatted, err := csource.Format(src); err != nil {
+		log.Logf(1, "%v", err)
+	} else {
+		src = formatted
+	}
+
+	header := new(bytes.Buffer)
+	if rr.Verdict != nil && rr.Verdict.Signature != "" {
+		fmt.Fprintf(header, "// syz-verifier mismatch: %v\n//\n", rr.Verdict.Signature)
+	}
+	fmt.Fprintf(header, "// Expected results of the calls:\n")
+	for idx, cr := range rr.Reports {
+		mismatch := ""
+		if cr.Mismatch {
+			mismatch = " (mismatch)"
+		}
+		fmt.Fprintf(header, "// #%v %v%v\n", idx, cr.Call, mismatch)
+		for _, pool := range sortedPools(cr.States) {
+			fmt.Fprintf(header, "//   kernel %v: %v\n", pool, cr.States[pool])
+		}
+	}
+
+	// Keep the "autogenerated" line first, the header follows it.
+	pos := bytes.IndexByte(src, '\n') + 1
+	res := append([]byte{}, src[:pos]...)
+	res = append(res, header.Bytes()...)
+	return append(res, src[pos:]...), nil
+}
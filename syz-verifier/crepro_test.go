@@ -0,0 +1,64 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/syzkaller/pkg/mgrconfig"
+	"github.com/google/syzkaller/sys/targets"
+)
+
+func TestCRepro(t *testing.T) {
+	setErrnoTarget(targets.Linux, targets.AMD64)
+	p := getTestProgram(t)
+	cfg := &mgrconfig.Config{Sandbox: "none"}
+	cfg.TargetOS, cfg.Timeouts.Slowdown = "test", 1
+	opts := cReproOpts(cfg)
+	if err := opts.Check(cfg.TargetOS); err != nil {
+		t.Fatalf("bad options: %v", err)
+	}
+	vrf := Verifier{
+		resultsdir: makeTestResultDirectory(t),
+		stats:      emptyTestStats(),
+		cRepro:     &opts,
+	}
+	v := &Verdict{
+		Results: []*ExecResult{
+			makeExecResult(0, []int{1, 3, 2}),
+			makeExecResult(1, []int{1, 3, 5}),
+		},
+		Signature: "test$res0: errno 2 | errno 5",
+	}
+	vrf.SaveDiffResults(v, p)
+
+	data, err := ioutil.ReadFile(filepath.Join(vrf.resultsdir, "result-0.c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := string(data)
+	header := "// autogenerated by syzkaller (https://github.com/google/syzkaller)\n" +
+		"// syz-verifier mismatch: test$res0: errno 2 | errno 5\n" +
+		"//\n" +
+		"// Expected results of the calls:\n" +
+		"// #0 breaks_returns\n" +
+		"//   kernel 0: Flags: 0 (not executed), Errno: 1 EPERM (operation not permitted)\n" +
+		"//   kernel 1: Flags: 0 (not executed), Errno: 1 EPERM (operation not permitted)\n" +
+		"// #1 minimize$0\n" +
+		"//   kernel 0: Flags: 0 (not executed), Errno: 3 ESRCH (no such process)\n" +
+		"//   kernel 1: Flags: 0 (not executed), Errno: 3 ESRCH (no such process)\n" +
+		"// #2 test$res0 (mismatch)\n" +
+		"//   kernel 0: Flags: 0 (not executed), Errno: 2 ENOENT (no such file or directory)\n" +
+		"//   kernel 1: Flags: 0 (not executed), Errno: 5 EIO (input/output error)\n"
+	if !strings.HasPrefix(src, header) {
+		t.Errorf("bad reproducer header:\n%s", src)
+	}
+	// The results of the calls are printed by the reproducer.
+	if !strings.Contains(src, `"#2 test$res0 = -1 (errno %d: %s)\n"`) {
+		t.Errorf("reproducer does not print the call results:\n%s", src)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/google/syzkaller/pkg/ipc"
+	"github.com/google/syzkaller/prog"
+)
+
+// With -cross-arch, the kernels may be built for different architectures of the same OS
+// (e.g. the same kernel version for amd64 and arm64). Programs are generated for the target
+// of the first kernel and deserialized by the Runners for their own targets, so only
+// the system calls available on all architectures are enabled. Errnos that have different
+// numbers on some architectures (e.g. mips) are translated to the errnos of the first kernel,
+// so that only architecture-dependent behavior is reported as mismatches.
+
+// poolTarget returns the target of the kernel.
+func (vrf *Verifier) poolTarget(pool int) *prog.Target {
+	if pi := vrf.pools[pool]; pi != nil && pi.cfg != nil && pi.cfg.Target != nil {
+		return pi.cfg.Target
+	}
+	return vrf.target
+}
+
+// poolSyscall returns the system call of the verified target that corresponds to the system
+// call with the id on the kernel or nil if the verified target doesn't have it.
+func (vrf *Verifier) poolSyscall(pool, id int) *prog.Syscall {
+	target := vrf.poolTarget(pool)
+	if target == vrf.target {
+		return vrf.target.Syscalls[id]
+	}
+	return vrf.target.SyscallMap[target.Syscalls[id].Name]
+}
+
+// initCrossArch disables the system calls not available on some of the architectures
+// and prepares translation of errnos returned by the kernels.
+func (vrf *Verifier) initCrossArch() {
+	for pool := range vrf.pools {
+		target := vrf.poolTarget(pool)
+		if target == vrf.target {
+			continue
+		}
+		for c := range vrf.calls {
+			if target.SyscallMap[c.Name] == nil {
+				vrf.reasons[c] = fmt.Sprintf("not available on %v/%v (kernel %d)", target.OS, target.Arch, pool)
+				vrf.reportReasons = true
+			}
+		}
+		mapping := errnoMapping(errnoTableOf(target.OS, target.Arch), errnoTableOf(vrf.target.OS, vrf.target.Arch))
+		if mapping == nil {
+			continue
+		}
+		if vrf.errnoMaps == nil {
+			vrf.errnoMaps = make(map[int]map[int]int)
+		}
+		vrf.errnoMaps[pool] = mapping
+	}
+}
+
+// translateErrnos translates errnos returned by the kernel to the errnos of the verified target.
+func (vrf *Verifier) translateErrnos(pool int, info *ipc.ProgInfo) {
+	mapping := vrf.errnoMaps[pool]
+	if mapping == nil {
+		return
+	}
+	for i := range info.Calls {
+		if errno, ok := mapping[info.Calls[i].Errno]; ok {
+			info.Calls[i].Errno = errno
+		}
+	}
+}
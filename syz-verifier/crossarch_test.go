@@ -0,0 +1,80 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/pkg/ipc"
+	"github.com/google/syzkaller/pkg/mgrconfig"
+	"github.com/google/syzkaller/prog"
+	"github.com/google/syzkaller/sys/targets"
+)
+
+func TestErrnoMapping(t *testing.T) {
+	if m := errnoMapping(linuxErrnos, linuxErrnos); m != nil {
+		t.Errorf("same errno tables: got mapping %v", m)
+	}
+	if m := errnoMapping(nil, linuxErrnos); m != nil {
+		t.Errorf("no errno table: got mapping %v", m)
+	}
+	m := errnoMapping(mipsErrnos, linuxErrnos)
+	for mips, want := range map[int]int{45: 35, 89: 38, 1133: 122} {
+		if got := m[mips]; got != want {
+			t.Errorf("mips errno %v (%v): got %v, want %v", mips, mipsErrnos[mips].name, got, want)
+		}
+	}
+	if _, ok := m[22]; ok {
+		t.Errorf("EINVAL is the same on mips, got mapping %v", m[22])
+	}
+}
+
+func TestInitCrossArch(t *testing.T) {
+	amd64, err := prog.GetTarget(targets.Linux, targets.AMD64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arm64, err := prog.GetTarget(targets.Linux, targets.ARM64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mips64le, err := prog.GetTarget(targets.Linux, targets.MIPS64LE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vrf := &Verifier{
+		target: amd64,
+		pools: map[int]*poolInfo{
+			0: {cfg: &mgrconfig.Config{Derived: mgrconfig.Derived{Target: amd64}}},
+			1: {cfg: &mgrconfig.Config{Derived: mgrconfig.Derived{Target: arm64}}},
+			2: {cfg: &mgrconfig.Config{Derived: mgrconfig.Derived{Target: mips64le}}},
+		},
+		calls: map[*prog.Syscall]bool{
+			amd64.SyscallMap["open"]:   true,
+			amd64.SyscallMap["openat"]: true,
+		},
+		reasons: make(map[*prog.Syscall]string),
+	}
+	vrf.initCrossArch()
+	if reason := vrf.reasons[amd64.SyscallMap["open"]]; reason != "not available on linux/arm64 (kernel 1)" {
+		t.Errorf("open: got reason %q", reason)
+	}
+	if reason, ok := vrf.reasons[amd64.SyscallMap["openat"]]; ok {
+		t.Errorf("openat: got reason %q", reason)
+	}
+	if c := vrf.poolSyscall(1, arm64.SyscallMap["openat"].ID); c != amd64.SyscallMap["openat"] {
+		t.Errorf("poolSyscall: got %v, want openat", c)
+	}
+
+	info := &ipc.ProgInfo{Calls: []ipc.CallInfo{{Errno: 45}, {Errno: 22}}}
+	vrf.translateErrnos(1, info)
+	if got := []int{info.Calls[0].Errno, info.Calls[1].Errno}; !cmp.Equal(got, []int{45, 22}) {
+		t.Errorf("arm64 errnos: got %v, want unchanged", got)
+	}
+	vrf.translateErrnos(2, info)
+	if got := []int{info.Calls[0].Errno, info.Calls[1].Errno}; !cmp.Equal(got, []int{35, 22}) {
+		t.Errorf("mips64le errnos: got %v, want [35 22]", got)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/syzkaller/dashboard/dashapi"
+	"github.com/google/syzkaller/pkg/hash"
+	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/pkg/mgrconfig"
+	"github.com/google/syzkaller/prog"
+)
+
+// With -dashboard, mismatches with new signatures (see signature.go) are uploaded
+// to the dashboard configured in the first kernel config (dashboard_client, dashboard_addr,
+// dashboard_key) as crashes of the manager <name>-verifier, so that they are deduplicated
+// and tracked like crashes in the namespace of the dashboard client. The titles start with
+// dashapi.VerifierMismatchPrefix, the dashboard never reports such bugs (mismatches are not kernel bugs
+// that could be sent to the kernel mailing lists).
+// The title of a mismatch is its signature, the report lists the kernel commits (the tags
+// of the kernel configs) and the return states of all calls on all kernels, the program
+// is uploaded as the syz reproducer. The build uploaded for the verifier describes
+// the first kernel, its ID is derived from the tags of all kernels.
+
+// dashClient is implemented by dashapi.Dashboard.
+type dashClient interface {
+	UploadBuild(build *dashapi.Build) error
+	ReportCrash(crash *dashapi.Crash) (*dashapi.ReportCrashResp, error)
+}
+
+type dashReporter struct {
+	dash    dashClient
+	build   *dashapi.Build
+	kernels string
+
+	mu sync.Mutex
+	// uploaded is set once the build is uploaded.
+	uploaded bool
+}
+
+func newDashReporter(dash dashClient, cfgs []*mgrconfig.Config) *dashReporter {
+	var kernels, tags []string
+	for idx, cfg := range cfgs {
+		tag := cfg.Tag
+		if tag == "" {
+			tag = "unknown"
+		}
+		tags = append(tags, tag)
+		kernels = append(kernels, fmt.Sprintf("kernel %v: %v/%v, %v", idx, cfg.TargetOS, cfg.TargetArch, tag))
+	}
+	cfg := cfgs[0]
+	return &dashReporter{
+		dash: dash,
+		build: &dashapi.Build{
+			Manager:             cfg.Name + "-verifier",
+			ID:                  hash.String([]byte(strings.Join(tags, " "))),
+			OS:                  cfg.TargetOS,
+			Arch:                cfg.TargetArch,
+			VMArch:              cfg.TargetVMArch,
+			SyzkallerCommit:     prog.GitRevisionBase,
+			SyzkallerCommitDate: prog.GitRevisionDate,
+			KernelCommit:        cfg.Tag,
+		},
+		kernels: strings.Join(kernels, "\n") + "\n",
+	}
+}
+
+// checkDashboardConfig checks that the config has the settings needed for -dashboard.
+func checkDashboardConfig(cfg *mgrconfig.Config) error {
+	if cfg.DashboardClient == "" || cfg.DashboardAddr == "" {
+		return fmt.Errorf("-dashboard requires dashboard_client and dashboard_addr in the first kernel config")
+	}
+	return nil
+}
+
+// report uploads the mismatch found in the program. Can be called on a nil object.
+func (dr *dashReporter) report(v *Verdict, p *prog.Prog, pools int) {
+	if dr == nil {
+		return
+	}
+	if err := dr.uploadBuild(); err != nil {
+		log.Logf(0, "failed to upload build to dashboard: %v", err)
+		return
+	}
+	rr := CompareResults(v.Results, p)
+	rr.Verdict = v
+	crash := &dashapi.Crash{
+		BuildID:  dr.build.ID,
+		Title:    dashapi.VerifierMismatchPrefix + v.Signature,
+		Log:      []byte(rr.Prog),
+		Report:   append([]byte(dr.kernels+"\n"), createReport(rr, pools)...),
+		ReproSyz: []byte(rr.Prog),
+	}
+	if _, err := dr.dash.ReportCrash(crash); err != nil {
+		log.Logf(0, "failed to report mismatch to dashboard: %v", err)
+	}
+}
+
+func (dr *dashReporter) uploadBuild() error {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	if dr.uploaded {
+		return nil
+	}
+	if err := dr.dash.UploadBuild(dr.build); err != nil {
+		return err
+	}
+	dr.uploaded = true
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/syzkaller/dashboard/dashapi"
+	"github.com/google/syzkaller/pkg/mgrconfig"
+)
+
+type testDashboard struct {
+	builds    []*dashapi.Build
+	crashes   []*dashapi.Crash
+	failBuild bool
+}
+
+func (dash *testDashboard) UploadBuild(build *dashapi.Build) error {
+	if dash.failBuild {
+		return errors.New("upload failed")
+	}
+	dash.builds = append(dash.builds, build)
+	return nil
+}
+
+func (dash *testDashboard) ReportCrash(crash *dashapi.Crash) (*dashapi.ReportCrashResp, error) {
+	dash.crashes = append(dash.crashes, crash)
+	return new(dashapi.ReportCrashResp), nil
+}
+
+func TestDashReporter(t *testing.T) {
+	p := getTestProgram(t)
+	v := &Verdict{
+		Results: []*ExecResult{
+			makeExecResult(0, []int{1, 3, 2}),
+			makeExecResult(1, []int{1, 3, 5}),
+		},
+		Runs:      3,
+		Divergent: 3,
+		Mismatch:  true,
+		Signature: "test$res0: errno 2 | errno 5",
+	}
+	var cfgs []*mgrconfig.Config
+	for i, tag := range []string{"1111", "2222"} {
+		cfg := &mgrconfig.Config{Name: fmt.Sprintf("ci-%v", i), Tag: tag}
+		cfg.TargetOS, cfg.TargetArch, cfg.TargetVMArch = "test", "64", "64"
+		cfgs = append(cfgs, cfg)
+	}
+	dash := &testDashboard{failBuild: true}
+	dr := newDashReporter(dash, cfgs)
+	// Nothing is reported until the build is uploaded.
+	dr.report(v, p, 2)
+	if len(dash.crashes) != 0 {
+		t.Fatalf("mismatch reported without a build")
+	}
+	dash.failBuild = false
+	dr.report(v, p, 2)
+	dr.report(v, p, 2)
+	(*dashReporter)(nil).report(v, p, 2)
+
+	if len(dash.builds) != 1 {
+		t.Fatalf("got %v uploaded builds, want 1", len(dash.builds))
+	}
+	build := dash.builds[0]
+	if build.Manager != "ci-0-verifier" || build.KernelCommit != "1111" || build.OS != "test" {
+		t.Errorf("bad build: %+v", build)
+	}
+	// The build ID depends on the commits of all kernels.
+	cfgs[1].Tag = "3333"
+	if newDashReporter(dash, cfgs).build.ID == build.ID {
+		t.Errorf("build ID does not depend on the second kernel")
+	}
+	if len(dash.crashes) != 2 {
+		t.Fatalf("got %v reported mismatches, want 2", len(dash.crashes))
+	}
+	crash := dash.crashes[0]
+	if crash.BuildID != build.ID || crash.Title != "verifier mismatch in test$res0: errno 2 | errno 5" ||
+		string(crash.ReproSyz) != string(p.Serialize()) {
+		t.Errorf("bad crash: %+v", crash)
+	}
+	for _, want := range []string{"kernel 0: test/64, 1111\nkernel 1: test/64, 2222\n", "Pool: 1"} {
+		if !strings.Contains(string(crash.Report), want) {
+			t.Errorf("report does not contain %q:\n%s", want, crash.Report)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// A Runner may never return the result of a task (e.g. if the executor hangs without crashing the VM).
+// With -task-timeout, a task that did not return a result within the timeout since it was dispatched
+// is put back to the queue after a backoff that doubles with every retry. After -task-retries retries
+// the task is abandoned, i.e. it fails with an error as if the VM crashed.
+
+// defaultTaskBackoff is the delay before the first retry of a timed out task.
+const defaultTaskBackoff = 5 * time.Second
+
+// taskBackoff returns the delay before the retry of the task that timed out retries times.
+func (vrf *Verifier) taskBackoff(retries int) time.Duration {
+	return vrf.taskRetryBackoff << (retries - 1)
+}
+
+// waitTaskResult waits for the result of the task pushed to the queue q of the kernel.
+// The tasks of stalled Runners are put back to the queue (see runnerhealth.go).
+func (vrf *Verifier) waitTaskResult(task *ExecTask, q *ExecTaskQueue, kernel int) *ExecResult {
+	for {
+		res := vrf.waitTaskDeadline(task, q, kernel)
+		if !res.requeued {
+			return res
+		}
+		atomic.AddInt64(&vrf.stats.RequeuedTasks, 1)
+		vrf.tasksMutex.Lock()
+		task.Deadline = time.Time{}
+		vrf.pushTask(kernel, q, task)
+		vrf.tasksMutex.Unlock()
+	}
+}
+
+// waitTaskDeadline waits for the result of the task and retries it if it times out.
+func (vrf *Verifier) waitTaskDeadline(task *ExecTask, q *ExecTaskQueue, kernel int) *ExecResult {
+	if vrf.taskTimeout == 0 {
+		return <-task.ExecResultChan
+	}
+	ticker := time.NewTicker(vrf.taskTimeout / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case res := <-task.ExecResultChan:
+			return res
+		case <-ticker.C:
+		}
+		vrf.tasksMutex.Lock()
+		expired := !task.Deadline.IsZero() && time.Now().After(task.Deadline)
+		if expired {
+			task.Deadline = time.Time{}
+			task.Retries++
+		}
+		retries := task.Retries
+		vrf.tasksMutex.Unlock()
+		if !expired {
+			continue
+		}
+		atomic.AddInt64(&vrf.stats.TimedOutTasks, 1)
+		if retries > vrf.taskRetries {
+			atomic.AddInt64(&vrf.stats.AbandonedTasks, 1)
+			return &ExecResult{
+				Pool:       kernel,
+				ExecTaskID: task.ID,
+				Error:      fmt.Errorf("task abandoned after %d retries", vrf.taskRetries),
+			}
+		}
+		// The result may still arrive from the Runner that timed out.
+		select {
+		case res := <-task.ExecResultChan:
+			return res
+		case <-time.After(vrf.taskBackoff(retries)):
+		}
+		vrf.tasksMutex.Lock()
+		vrf.pushTask(kernel, q, task)
+		vrf.tasksMutex.Unlock()
+	}
+}
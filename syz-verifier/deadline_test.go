@@ -0,0 +1,70 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/syzkaller/pkg/ipc"
+)
+
+func TestTaskDeadline(t *testing.T) {
+	p := getTestProgram(t)
+	vrf := &Verifier{
+		stats:            MakeStats(),
+		taskTimeout:      40 * time.Millisecond,
+		taskRetries:      1,
+		taskRetryBackoff: 10 * time.Millisecond,
+	}
+	makeTestQueues(vrf, 1)
+
+	type runResult struct {
+		res []*ExecResult
+		err error
+	}
+	run := func() chan runResult {
+		done := make(chan runResult, 1)
+		go func() {
+			res, err := vrf.Run(p, NewEnvironment)
+			done <- runResult{res, err}
+		}()
+		return done
+	}
+
+	// The result is returned after the first retry.
+	done := run()
+	task := vrf.GetRunnerTask(0, NewEnvironment)
+	if retried := vrf.GetRunnerTask(0, NewEnvironment); retried.ID != task.ID {
+		t.Fatalf("got task %v, want retried task %v", retried.ID, task.ID)
+	}
+	result := &ExecResult{Pool: 0, ExecTaskID: task.ID, Info: ipc.ProgInfo{Calls: []ipc.CallInfo{{}}}}
+	PutExecResult(result)
+	// The late result of the first attempt is dropped.
+	PutExecResult(result)
+	if r := <-done; r.err != nil || len(r.res) != 1 || r.res[0] != result {
+		t.Fatalf("got results %v, error %v, want the result of the retried task", r.res, r.err)
+	}
+	if vrf.stats.TimedOutTasks != 1 || vrf.stats.AbandonedTasks != 0 {
+		t.Errorf("got %v timed out and %v abandoned tasks, want 1 and 0",
+			vrf.stats.TimedOutTasks, vrf.stats.AbandonedTasks)
+	}
+
+	// The task is abandoned after all retries.
+	done = run()
+	task = vrf.GetRunnerTask(0, NewEnvironment)
+	if retried := vrf.GetRunnerTask(0, NewEnvironment); retried.ID != task.ID {
+		t.Fatalf("got task %v, want retried task %v", retried.ID, task.ID)
+	}
+	if r := <-done; r.err == nil {
+		t.Fatalf("got results %v, want an error", r.res)
+	}
+	if vrf.stats.TimedOutTasks != 3 || vrf.stats.AbandonedTasks != 1 {
+		t.Errorf("got %v timed out and %v abandoned tasks, want 3 and 1",
+			vrf.stats.TimedOutTasks, vrf.stats.AbandonedTasks)
+	}
+	if c := GetExecResultChan(task.ID); c != nil {
+		t.Errorf("the result channel of the abandoned task was not deleted")
+	}
+}
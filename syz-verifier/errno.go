@@ -0,0 +1,365 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/google/syzkaller/pkg/ipc"
+	"github.com/google/syzkaller/sys/targets"
+)
+
+// Return states are printed with symbolic errno names and descriptions of the target OS
+// (e.g. "Errno: 22 EINVAL (invalid argument)") and decoded call flags. Errno numbers differ
+// between OSes (and some architectures), so the table is selected with setErrnoTarget.
+// For targets without a table only the errno description of the host OS is printed.
+
+type errnoInfo struct {
+	name string
+	desc string
+}
+
+// errnoTable is the errno table of the verified target.
+var errnoTable = linuxErrnos
+
+// setErrnoTarget selects the errno table for the target.
+func setErrnoTarget(os, arch string) {
+	errnoTable = errnoTableOf(os, arch)
+}
+
+// errnoTableOf returns the errno table of the target or nil if there is none.
+func errnoTableOf(os, arch string) map[int]errnoInfo {
+	if os != targets.Linux {
+		return nil
+	}
+	if arch == targets.MIPS64LE {
+		return mipsErrnos
+	}
+	return linuxErrnos
+}
+
+// errnoMapping returns the mapping of errnos of one errno table to the errnos with the same
+// names in another one. It returns nil if the errnos do not need to be translated.
+func errnoMapping(from, to map[int]errnoInfo) map[int]int {
+	if from == nil || to == nil {
+		return nil
+	}
+	byName := make(map[string]int)
+	for errno, info := range to {
+		byName[info.name] = errno
+	}
+	res := make(map[int]int)
+	for errno, info := range from {
+		if mapped, ok := byName[info.name]; ok && mapped != errno {
+			res[errno] = mapped
+		}
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
+
+func formatErrno(errno int) string {
+	if errno == 0 {
+		return "0 (success)"
+	}
+	if info, ok := errnoTable[errno]; ok {
+		return fmt.Sprintf("%d %s (%s)", errno, info.name, info.desc)
+	}
+	return fmt.Sprintf("%d (%s)", errno, syscall.Errno(errno).Error())
+}
+
+func formatCallFlags(flags ipc.CallFlags) string {
+	if flags == 0 {
+		return "0 (not executed)"
+	}
+	var names []string
+	for _, f := range []struct {
+		flag ipc.CallFlags
+		name string
+	}{
+		{ipc.CallExecuted, "executed"},
+		{ipc.CallFinished, "finished"},
+		{ipc.CallBlocked, "blocked"},
+		{ipc.CallFaultInjected, "fault injected"},
+	} {
+		if flags&f.flag != 0 {
+			names = append(names, f.name)
+		}
+	}
+	return fmt.Sprintf("%d (%s)", flags, strings.Join(names, "|"))
+}
+
+// linuxErrnos contains errnos of asm-generic/errno.h used by all Linux architectures except mips.
+var linuxErrnos = map[int]errnoInfo{
+	1:   {"EPERM", "operation not permitted"},
+	2:   {"ENOENT", "no such file or directory"},
+	3:   {"ESRCH", "no such process"},
+	4:   {"EINTR", "interrupted system call"},
+	5:   {"EIO", "input/output error"},
+	6:   {"ENXIO", "no such device or address"},
+	7:   {"E2BIG", "argument list too long"},
+	8:   {"ENOEXEC", "exec format error"},
+	9:   {"EBADF", "bad file descriptor"},
+	10:  {"ECHILD", "no child processes"},
+	11:  {"EAGAIN", "resource temporarily unavailable"},
+	12:  {"ENOMEM", "cannot allocate memory"},
+	13:  {"EACCES", "permission denied"},
+	14:  {"EFAULT", "bad address"},
+	15:  {"ENOTBLK", "block device required"},
+	16:  {"EBUSY", "device or resource busy"},
+	17:  {"EEXIST", "file exists"},
+	18:  {"EXDEV", "invalid cross-device link"},
+	19:  {"ENODEV", "no such device"},
+	20:  {"ENOTDIR", "not a directory"},
+	21:  {"EISDIR", "is a directory"},
+	22:  {"EINVAL", "invalid argument"},
+	23:  {"ENFILE", "too many open files in system"},
+	24:  {"EMFILE", "too many open files"},
+	25:  {"ENOTTY", "inappropriate ioctl for device"},
+	26:  {"ETXTBSY", "text file busy"},
+	27:  {"EFBIG", "file too large"},
+	28:  {"ENOSPC", "no space left on device"},
+	29:  {"ESPIPE", "illegal seek"},
+	30:  {"EROFS", "read-only file system"},
+	31:  {"EMLINK", "too many links"},
+	32:  {"EPIPE", "broken pipe"},
+	33:  {"EDOM", "numerical argument out of domain"},
+	34:  {"ERANGE", "numerical result out of range"},
+	35:  {"EDEADLK", "resource deadlock avoided"},
+	36:  {"ENAMETOOLONG", "file name too long"},
+	37:  {"ENOLCK", "no locks available"},
+	38:  {"ENOSYS", "function not implemented"},
+	39:  {"ENOTEMPTY", "directory not empty"},
+	40:  {"ELOOP", "too many levels of symbolic links"},
+	42:  {"ENOMSG", "no message of desired type"},
+	43:  {"EIDRM", "identifier removed"},
+	44:  {"ECHRNG", "channel number out of range"},
+	45:  {"EL2NSYNC", "level 2 not synchronized"},
+	46:  {"EL3HLT", "level 3 halted"},
+	47:  {"EL3RST", "level 3 reset"},
+	48:  {"ELNRNG", "link number out of range"},
+	49:  {"EUNATCH", "protocol driver not attached"},
+	50:  {"ENOCSI", "no CSI structure available"},
+	51:  {"EL2HLT", "level 2 halted"},
+	52:  {"EBADE", "invalid exchange"},
+	53:  {"EBADR", "invalid request descriptor"},
+	54:  {"EXFULL", "exchange full"},
+	55:  {"ENOANO", "no anode"},
+	56:  {"EBADRQC", "invalid request code"},
+	57:  {"EBADSLT", "invalid slot"},
+	59:  {"EBFONT", "bad font file format"},
+	60:  {"ENOSTR", "device not a stream"},
+	61:  {"ENODATA", "no data available"},
+	62:  {"ETIME", "timer expired"},
+	63:  {"ENOSR", "out of streams resources"},
+	64:  {"ENONET", "machine is not on the network"},
+	65:  {"ENOPKG", "package not installed"},
+	66:  {"EREMOTE", "object is remote"},
+	67:  {"ENOLINK", "link has been severed"},
+	68:  {"EADV", "advertise error"},
+	69:  {"ESRMNT", "srmount error"},
+	70:  {"ECOMM", "communication error on send"},
+	71:  {"EPROTO", "protocol error"},
+	72:  {"EMULTIHOP", "multihop attempted"},
+	73:  {"EDOTDOT", "RFS specific error"},
+	74:  {"EBADMSG", "bad message"},
+	75:  {"EOVERFLOW", "value too large for defined data type"},
+	76:  {"ENOTUNIQ", "name not unique on network"},
+	77:  {"EBADFD", "file descriptor in bad state"},
+	78:  {"EREMCHG", "remote address changed"},
+	79:  {"ELIBACC", "can not access a needed shared library"},
+	80:  {"ELIBBAD", "accessing a corrupted shared library"},
+	81:  {"ELIBSCN", ".lib section in a.out corrupted"},
+	82:  {"ELIBMAX", "attempting to link in too many shared libraries"},
+	83:  {"ELIBEXEC", "cannot exec a shared library directly"},
+	84:  {"EILSEQ", "invalid or incomplete multibyte or wide character"},
+	85:  {"ERESTART", "interrupted system call should be restarted"},
+	86:  {"ESTRPIPE", "streams pipe error"},
+	87:  {"EUSERS", "too many users"},
+	88:  {"ENOTSOCK", "socket operation on non-socket"},
+	89:  {"EDESTADDRREQ", "destination address required"},
+	90:  {"EMSGSIZE", "message too long"},
+	91:  {"EPROTOTYPE", "protocol wrong type for socket"},
+	92:  {"ENOPROTOOPT", "protocol not available"},
+	93:  {"EPROTONOSUPPORT", "protocol not supported"},
+	94:  {"ESOCKTNOSUPPORT", "socket type not supported"},
+	95:  {"ENOTSUP", "operation not supported"},
+	96:  {"EPFNOSUPPORT", "protocol family not supported"},
+	97:  {"EAFNOSUPPORT", "address family not supported by protocol"},
+	98:  {"EADDRINUSE", "address already in use"},
+	99:  {"EADDRNOTAVAIL", "cannot assign requested address"},
+	100: {"ENETDOWN", "network is down"},
+	101: {"ENETUNREACH", "network is unreachable"},
+	102: {"ENETRESET", "network dropped connection on reset"},
+	103: {"ECONNABORTED", "software caused connection abort"},
+	104: {"ECONNRESET", "connection reset by peer"},
+	105: {"ENOBUFS", "no buffer space available"},
+	106: {"EISCONN", "transport endpoint is already connected"},
+	107: {"ENOTCONN", "transport endpoint is not connected"},
+	108: {"ESHUTDOWN", "cannot send after transport endpoint shutdown"},
+	109: {"ETOOMANYREFS", "too many references: cannot splice"},
+	110: {"ETIMEDOUT", "connection timed out"},
+	111: {"ECONNREFUSED", "connection refused"},
+	112: {"EHOSTDOWN", "host is down"},
+	113: {"EHOSTUNREACH", "no route to host"},
+	114: {"EALREADY", "operation already in progress"},
+	115: {"EINPROGRESS", "operation now in progress"},
+	116: {"ESTALE", "stale file handle"},
+	117: {"EUCLEAN", "structure needs cleaning"},
+	118: {"ENOTNAM", "not a XENIX named type file"},
+	119: {"ENAVAIL", "no XENIX semaphores available"},
+	120: {"EISNAM", "is a named type file"},
+	121: {"EREMOTEIO", "remote I/O error"},
+	122: {"EDQUOT", "disk quota exceeded"},
+	123: {"ENOMEDIUM", "no medium found"},
+	124: {"EMEDIUMTYPE", "wrong medium type"},
+	125: {"ECANCELED", "operation canceled"},
+	126: {"ENOKEY", "required key not available"},
+	127: {"EKEYEXPIRED", "key has expired"},
+	128: {"EKEYREVOKED", "key has been revoked"},
+	129: {"EKEYREJECTED", "key was rejected by service"},
+	130: {"EOWNERDEAD", "owner died"},
+	131: {"ENOTRECOVERABLE", "state not recoverable"},
+	132: {"ERFKILL", "operation not possible due to RF-kill"},
+	133: {"EHWPOISON", "errno 133"},
+}
+
+// mipsErrnos contains errnos of arch/mips/include/uapi/asm/errno.h.
+var mipsErrnos = map[int]errnoInfo{
+	1:    {"EPERM", "operation not permitted"},
+	2:    {"ENOENT", "no such file or directory"},
+	3:    {"ESRCH", "no such process"},
+	4:    {"EINTR", "interrupted system call"},
+	5:    {"EIO", "input/output error"},
+	6:    {"ENXIO", "no such device or address"},
+	7:    {"E2BIG", "argument list too long"},
+	8:    {"ENOEXEC", "exec format error"},
+	9:    {"EBADF", "bad file descriptor"},
+	10:   {"ECHILD", "no child processes"},
+	11:   {"EAGAIN", "resource temporarily unavailable"},
+	12:   {"ENOMEM", "cannot allocate memory"},
+	13:   {"EACCES", "permission denied"},
+	14:   {"EFAULT", "bad address"},
+	15:   {"ENOTBLK", "block device required"},
+	16:   {"EBUSY", "device or resource busy"},
+	17:   {"EEXIST", "file exists"},
+	18:   {"EXDEV", "invalid cross-device link"},
+	19:   {"ENODEV", "no such device"},
+	20:   {"ENOTDIR", "not a directory"},
+	21:   {"EISDIR", "is a directory"},
+	22:   {"EINVAL", "invalid argument"},
+	23:   {"ENFILE", "too many open files in system"},
+	24:   {"EMFILE", "too many open files"},
+	25:   {"ENOTTY", "inappropriate ioctl for device"},
+	26:   {"ETXTBSY", "text file busy"},
+	27:   {"EFBIG", "file too large"},
+	28:   {"ENOSPC", "no space left on device"},
+	29:   {"ESPIPE", "illegal seek"},
+	30:   {"EROFS", "read-only file system"},
+	31:   {"EMLINK", "too many links"},
+	32:   {"EPIPE", "broken pipe"},
+	33:   {"EDOM", "numerical argument out of domain"},
+	34:   {"ERANGE", "numerical result out of range"},
+	35:   {"ENOMSG", "no message of desired type"},
+	36:   {"EIDRM", "identifier removed"},
+	37:   {"ECHRNG", "channel number out of range"},
+	38:   {"EL2NSYNC", "level 2 not synchronized"},
+	39:   {"EL3HLT", "level 3 halted"},
+	40:   {"EL3RST", "level 3 reset"},
+	41:   {"ELNRNG", "link number out of range"},
+	42:   {"EUNATCH", "protocol driver not attached"},
+	43:   {"ENOCSI", "no CSI structure available"},
+	44:   {"EL2HLT", "level 2 halted"},
+	45:   {"EDEADLK", "resource deadlock avoided"},
+	46:   {"ENOLCK", "no locks available"},
+	50:   {"EBADE", "invalid exchange"},
+	51:   {"EBADR", "invalid request descriptor"},
+	52:   {"EXFULL", "exchange full"},
+	53:   {"ENOANO", "no anode"},
+	54:   {"EBADRQC", "invalid request code"},
+	55:   {"EBADSLT", "invalid slot"},
+	59:   {"EBFONT", "bad font file format"},
+	60:   {"ENOSTR", "device not a stream"},
+	61:   {"ENODATA", "no data available"},
+	62:   {"ETIME", "timer expired"},
+	63:   {"ENOSR", "out of streams resources"},
+	64:   {"ENONET", "machine is not on the network"},
+	65:   {"ENOPKG", "package not installed"},
+	66:   {"EREMOTE", "object is remote"},
+	67:   {"ENOLINK", "link has been severed"},
+	68:   {"EADV", "advertise error"},
+	69:   {"ESRMNT", "srmount error"},
+	70:   {"ECOMM", "communication error on send"},
+	71:   {"EPROTO", "protocol error"},
+	73:   {"EDOTDOT", "RFS specific error"},
+	74:   {"EMULTIHOP", "multihop attempted"},
+	77:   {"EBADMSG", "bad message"},
+	78:   {"ENAMETOOLONG", "file name too long"},
+	79:   {"EOVERFLOW", "value too large for defined data type"},
+	80:   {"ENOTUNIQ", "name not unique on network"},
+	81:   {"EBADFD", "file descriptor in bad state"},
+	82:   {"EREMCHG", "remote address changed"},
+	83:   {"ELIBACC", "can not access a needed shared library"},
+	84:   {"ELIBBAD", "accessing a corrupted shared library"},
+	85:   {"ELIBSCN", ".lib section in a.out corrupted"},
+	86:   {"ELIBMAX", "attempting to link in too many shared libraries"},
+	87:   {"ELIBEXEC", "cannot exec a shared library directly"},
+	88:   {"EILSEQ", "invalid or incomplete multibyte or wide character"},
+	89:   {"ENOSYS", "function not implemented"},
+	90:   {"ELOOP", "too many levels of symbolic links"},
+	91:   {"ERESTART", "interrupted system call should be restarted"},
+	92:   {"ESTRPIPE", "streams pipe error"},
+	93:   {"ENOTEMPTY", "directory not empty"},
+	94:   {"EUSERS", "too many users"},
+	95:   {"ENOTSOCK", "socket operation on non-socket"},
+	96:   {"EDESTADDRREQ", "destination address required"},
+	97:   {"EMSGSIZE", "message too long"},
+	98:   {"EPROTOTYPE", "protocol wrong type for socket"},
+	99:   {"ENOPROTOOPT", "protocol not available"},
+	120:  {"EPROTONOSUPPORT", "protocol not supported"},
+	121:  {"ESOCKTNOSUPPORT", "socket type not supported"},
+	122:  {"ENOTSUP", "operation not supported"},
+	123:  {"EPFNOSUPPORT", "protocol family not supported"},
+	124:  {"EAFNOSUPPORT", "address family not supported by protocol"},
+	125:  {"EADDRINUSE", "address already in use"},
+	126:  {"EADDRNOTAVAIL", "cannot assign requested address"},
+	127:  {"ENETDOWN", "network is down"},
+	128:  {"ENETUNREACH", "network is unreachable"},
+	129:  {"ENETRESET", "network dropped connection on reset"},
+	130:  {"ECONNABORTED", "software caused connection abort"},
+	131:  {"ECONNRESET", "connection reset by peer"},
+	132:  {"ENOBUFS", "no buffer space available"},
+	133:  {"EISCONN", "transport endpoint is already connected"},
+	134:  {"ENOTCONN", "transport endpoint is not connected"},
+	135:  {"EUCLEAN", "structure needs cleaning"},
+	137:  {"ENOTNAM", "not a XENIX named type file"},
+	138:  {"ENAVAIL", "no XENIX semaphores available"},
+	139:  {"EISNAM", "is a named type file"},
+	140:  {"EREMOTEIO", "remote I/O error"},
+	143:  {"ESHUTDOWN", "cannot send after transport endpoint shutdown"},
+	144:  {"ETOOMANYREFS", "too many references: cannot splice"},
+	145:  {"ETIMEDOUT", "connection timed out"},
+	146:  {"ECONNREFUSED", "connection refused"},
+	147:  {"EHOSTDOWN", "host is down"},
+	148:  {"EHOSTUNREACH", "no route to host"},
+	149:  {"EALREADY", "operation already in progress"},
+	150:  {"EINPROGRESS", "operation now in progress"},
+	151:  {"ESTALE", "stale file handle"},
+	158:  {"ECANCELED", "operation canceled"},
+	159:  {"ENOMEDIUM", "no medium found"},
+	160:  {"EMEDIUMTYPE", "wrong medium type"},
+	161:  {"ENOKEY", "required key not available"},
+	162:  {"EKEYEXPIRED", "key has expired"},
+	163:  {"EKEYREVOKED", "key has been revoked"},
+	164:  {"EKEYREJECTED", "key was rejected by service"},
+	165:  {"EOWNERDEAD", "owner died"},
+	166:  {"ENOTRECOVERABLE", "state not recoverable"},
+	167:  {"ERFKILL", "operation not possible due to RF-kill"},
+	168:  {"EHWPOISON", "errno 133"},
+	1133: {"EDQUOT", "disk quota exceeded"},
+}
@@ -0,0 +1,33 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/syzkaller/sys/targets"
+)
+
+func TestReturnStateString(t *testing.T) {
+	defer setErrnoTarget(targets.Linux, targets.AMD64)
+	tests := []struct {
+		os    string
+		state ReturnState
+		want  string
+	}{
+		{targets.Linux, ReturnState{Errno: 0, Flags: 3}, "Flags: 3 (executed|finished), Errno: 0 (success)"},
+		{targets.Linux, ReturnState{Errno: 11, Flags: 15},
+			"Flags: 15 (executed|finished|blocked|fault injected), Errno: 11 EAGAIN (resource temporarily unavailable)"},
+		{targets.Linux, ReturnState{Errno: 1000}, "Flags: 0 (not executed), Errno: 1000 (errno 1000)"},
+		{targets.Linux, ReturnState{Crashed: true}, "Crashed"},
+		// No errno names for other OSes.
+		{targets.FreeBSD, ReturnState{Errno: 1, Flags: 1}, "Flags: 1 (executed), Errno: 1 (operation not permitted)"},
+	}
+	for i, test := range tests {
+		setErrnoTarget(test.os, targets.AMD64)
+		if got := test.state.String(); got != test.want {
+			t.Errorf("#%v: got %q, want %q", i, got, test.want)
+		}
+	}
+}
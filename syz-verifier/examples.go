@@ -0,0 +1,126 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/syzkaller/prog"
+)
+
+// For every syscall with confirmed mismatches the shortest program that reproduces the mismatch
+// is kept, so that the report can show the top mismatching syscalls with examples to start triage from.
+
+// reportTopMismatches is the number of syscalls in the top mismatches section of the report.
+const reportTopMismatches = 10
+
+// mismatchExample is a representative program for the mismatches of a syscall.
+type mismatchExample struct {
+	prog   string
+	calls  int
+	states map[int]ReturnState
+}
+
+// MismatchExampleJSON describes a top mismatching syscall and its representative program.
+type MismatchExampleJSON struct {
+	Call        string
+	Mismatches  int64
+	Occurrences int64
+	Prog        string
+	// States are return states of the call in the program on each kernel ordered by pool index.
+	States []*ReturnStateJSON
+}
+
+// addMismatchExamples records p as the representative program for the mismatching calls
+// if it is shorter than the current one.
+func (stats *Stats) addMismatchExamples(p *prog.Prog, results []*ExecResult) {
+	rr := CompareResults(results, p)
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	for idx, cr := range rr.Reports {
+		if !cr.Mismatch {
+			continue
+		}
+		if ex := stats.examples[cr.Call]; ex != nil && ex.calls <= idx+1 {
+			continue
+		}
+		// Calls are executed sequentially, so the calls after the mismatching one can't affect it.
+		short := p.Clone()
+		for len(short.Calls) > idx+1 {
+			short.RemoveCall(len(short.Calls) - 1)
+		}
+		if stats.examples == nil {
+			stats.examples = make(map[string]*mismatchExample)
+		}
+		stats.examples[cr.Call] = &mismatchExample{
+			prog:   string(short.Serialize()),
+			calls:  idx + 1,
+			states: cr.States,
+		}
+	}
+}
+
+// topMismatches returns up to reportTopMismatches syscalls with the most mismatches
+// that have a representative program. Must be called with stats.mu held.
+func (stats *Stats) topMismatches() []*MismatchExampleJSON {
+	var res []*MismatchExampleJSON
+	for name, ex := range stats.examples {
+		cs := stats.Calls[name]
+		if cs == nil {
+			continue
+		}
+		top := &MismatchExampleJSON{
+			Call:        name,
+			Mismatches:  cs.Mismatches,
+			Occurrences: cs.Occurrences,
+			Prog:        ex.prog,
+		}
+		pools := make([]int, 0, len(ex.states))
+		for pool := range ex.states {
+			pools = append(pools, pool)
+		}
+		sort.Ints(pools)
+		for _, pool := range pools {
+			state := ex.states[pool]
+			top.States = append(top.States, &ReturnStateJSON{
+				Errno:       state.Errno,
+				Flags:       state.Flags,
+				Crashed:     state.Crashed,
+				Outputs:     state.Outputs,
+				Description: state.String(),
+			})
+		}
+		res = append(res, top)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Mismatches != res[j].Mismatches {
+			return res[i].Mismatches > res[j].Mismatches
+		}
+		return res[i].Call < res[j].Call
+	})
+	if len(res) > reportTopMismatches {
+		res = res[:reportTopMismatches]
+	}
+	return res
+}
+
+func formatTopMismatches(top []*MismatchExampleJSON) string {
+	var result strings.Builder
+	fmt.Fprintf(&result, "top mismatching syscalls:\n\n")
+	for i, t := range top {
+		fmt.Fprintf(&result, "%d. %s: %d mismatches / %d occurrences\n", i+1, t.Call, t.Mismatches, t.Occurrences)
+		fmt.Fprintf(&result, "representative program:\n")
+		for _, line := range strings.Split(strings.TrimSpace(t.Prog), "\n") {
+			fmt.Fprintf(&result, "\t%s\n", line)
+		}
+		fmt.Fprintf(&result, "return states of %s:\n", t.Call)
+		for pool, state := range t.States {
+			fmt.Fprintf(&result, "\t↳ Pool: %d, %s\n", pool, state.Description)
+		}
+		fmt.Fprintf(&result, "\n")
+	}
+	return result.String()
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/prog"
+)
+
+func TestTopMismatches(t *testing.T) {
+	stats := emptyTestStats()
+	stats.Calls["breaks_returns"].Mismatches = 1
+	stats.Calls["test$res0"].Mismatches = 2
+	p := getTestProgram(t)
+	stats.addMismatchExamples(p, []*ExecResult{
+		makeExecResult(0, []int{1, 3, 2}),
+		makeExecResult(1, []int{4, 3, 5}),
+	})
+	short, err := p.Target.Deserialize([]byte("test$res0()\n"), prog.Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats.addMismatchExamples(short, []*ExecResult{
+		makeExecResult(0, []int{2}),
+		makeExecResult(1, []int{22}),
+	})
+	// A longer program does not replace the representative one.
+	stats.addMismatchExamples(p, []*ExecResult{
+		makeExecResult(0, []int{1, 3, 2}),
+		makeExecResult(1, []int{1, 3, 7}),
+	})
+	got := stats.GetJSON(1).TopMismatches
+	want := []*MismatchExampleJSON{
+		{
+			Call:       "test$res0",
+			Mismatches: 2,
+			Prog:       "test$res0()\n",
+			States: []*ReturnStateJSON{
+				{Errno: 2, Description: "Flags: 0 (not executed), Errno: 2 ENOENT (no such file or directory)"},
+				{Errno: 22, Description: "Flags: 0 (not executed), Errno: 22 EINVAL (invalid argument)"},
+			},
+		},
+		{
+			Call:       "breaks_returns",
+			Mismatches: 1,
+			Prog:       "breaks_returns()\n",
+			States: []*ReturnStateJSON{
+				{Errno: 1, Description: "Flags: 0 (not executed), Errno: 1 EPERM (operation not permitted)"},
+				{Errno: 4, Description: "Flags: 0 (not executed), Errno: 4 EINTR (interrupted system call)"},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("top mismatches (-want +got):\n%s", diff)
+	}
+	wantText := "top mismatching syscalls:\n\n" +
+		"1. test$res0: 2 mismatches / 0 occurrences\n" +
+		"representative program:\n" +
+		"\ttest$res0()\n" +
+		"return states of test$res0:\n" +
+		"\t↳ Pool: 0, Flags: 0 (not executed), Errno: 2 ENOENT (no such file or directory)\n" +
+		"\t↳ Pool: 1, Flags: 0 (not executed), Errno: 22 EINVAL (invalid argument)\n\n"
+	if text := stats.GetTextDescription(1); !strings.Contains(text, wantText) {
+		t.Errorf("stats don't contain top mismatches:\n%s", text)
+	}
+}
@@ -5,7 +5,7 @@ package main
 
 import (
 	"fmt"
-	"syscall"
+	"sort"
 
 	"github.com/google/syzkaller/pkg/ipc"
 	"github.com/google/syzkaller/prog"
@@ -26,7 +26,12 @@ type ExecResult struct {
 	// Source task ID is used to route result back to the caller.
 	ExecTaskID int64
 	// To signal the processing errors.
-	Error error
+	Error error `json:"-"`
+	// Trace is the strace output of the program if it was executed under strace (see trace.go).
+	Trace []byte `json:"-"`
+	// requeued is set if the task needs to be put back to the queue, because the Runner
+	// executing it stalled (see runnerhealth.go).
+	requeued bool
 }
 
 func (l *ExecResult) IsEqual(r *ExecResult) bool {
@@ -43,7 +48,8 @@ func (l *ExecResult) IsEqual(r *ExecResult) bool {
 
 	for i := 0; i < len(lCalls); i++ {
 		if lCalls[i].Errno != rCalls[i].Errno ||
-			lCalls[i].Flags != rCalls[i].Flags {
+			lCalls[i].Flags != rCalls[i].Flags ||
+			!outputsEqual(lCalls[i].Outputs, rCalls[i].Outputs) {
 			return false
 		}
 	}
@@ -58,6 +64,8 @@ type ResultReport struct {
 	Reports []*CallReport
 	// Mismatch says whether the Reports differ.
 	Mismatch bool
+	// Verdict is the classification of the mismatch (optional).
+	Verdict *Verdict
 }
 
 type CallReport struct {
@@ -67,6 +75,9 @@ type CallReport struct {
 	States map[int]ReturnState
 	// Mismatch is set to true if the returned error codes were not the same.
 	Mismatch bool
+	// Outliers are the pools whose return state differs from the state
+	// returned by the majority of the kernels (see outlierPools).
+	Outliers []int `json:",omitempty"`
 }
 
 // ReturnState stores the results of executing a system call.
@@ -78,22 +89,41 @@ type ReturnState struct {
 	// Crashed is set to true if the kernel crashed while executing the program
 	// that contains the system call.
 	Crashed bool
+	// Outputs are the normalized return value and outputs of the call (see outputs.go),
+	// empty if the outputs are not compared or the call failed.
+	Outputs string `json:",omitempty"`
 }
 
 func (s ReturnState) String() string {
-	state := ""
-
 	if s.Crashed {
 		return "Crashed"
 	}
+	res := fmt.Sprintf("Flags: %s, Errno: %s", formatCallFlags(s.Flags), formatErrno(s.Errno))
+	if s.Outputs != "" {
+		res += ", Outputs: " + s.Outputs
+	}
+	return res
+}
 
-	state += fmt.Sprintf("Flags: %d, ", s.Flags)
-	errDesc := "success"
-	if s.Errno != 0 {
-		errDesc = syscall.Errno(s.Errno).Error()
+// statesMatch says whether the return states of the call on different kernels
+// are the same, taking into account verifier annotations in the descriptions
+// (see expect_errno and nondeterministic call attributes).
+func statesMatch(meta *prog.Syscall, s0, s1 ReturnState) bool {
+	if s0 == s1 {
+		return true
+	}
+	if s0.Crashed || s1.Crashed {
+		return false
+	}
+	if meta.Nondeterministic {
+		return true
 	}
-	state += fmt.Sprintf("Errno: %d (%s)", s.Errno, errDesc)
-	return state
+	for _, errno := range meta.ExpectErrnos {
+		if uint64(s0.Errno) == errno || uint64(s1.Errno) == errno {
+			return true
+		}
+	}
+	return false
 }
 
 // CompareResults checks whether the ExecResult of the same program,
@@ -120,22 +150,77 @@ func CompareResults(res []*ExecResult, prog *prog.Prog) *ResultReport {
 			}
 
 			ci := r.Info.Calls[idx]
-			cr.States[r.Pool] = ReturnState{Errno: ci.Errno, Flags: ci.Flags}
+			cr.States[r.Pool] = ReturnState{
+				Errno:   ci.Errno,
+				Flags:   ci.Flags,
+				Outputs: formatOutputs(call, ci.Outputs),
+			}
 		}
 		rr.Reports = append(rr.Reports, cr)
 	}
 
-	pool0 := res[0].Pool
-	for _, cr := range rr.Reports {
-		for _, state := range cr.States {
-			// For each CallReport, verify whether the ReturnStates from all
-			// the pools that executed the program are the same
-			if state0 := cr.States[pool0]; state0 != state {
-				cr.Mismatch = true
-				rr.Mismatch = true
+	for idx, cr := range rr.Reports {
+		meta := prog.Calls[idx].Meta
+		// For each CallReport, verify whether the ReturnStates from all
+		// the pools that executed the program are the same. All pairs are
+		// compared, because states matching due to the call annotations
+		// (e.g. an expected errno) are not transitive.
+		for i, r0 := range res {
+			for _, r1 := range res[i+1:] {
+				if !statesMatch(meta, cr.States[r0.Pool], cr.States[r1.Pool]) {
+					cr.Mismatch = true
+					rr.Mismatch = true
+				}
 			}
 		}
+		if cr.Mismatch {
+			cr.Outliers = outlierPools(meta, cr.States)
+		}
 	}
 
 	return rr
 }
+
+// outlierPools returns the pools whose return state of the mismatching call differs from
+// the state returned by the majority of the kernels. If there is no majority (e.g. only
+// two kernels are compared), the state of the kernel with the lowest pool index is
+// considered the reference one. The majority is computed over identical states,
+// because states matching due to the call annotations (e.g. an expected errno) would
+// make any kernel returning such state the reference one.
+func outlierPools(meta *prog.Syscall, states map[int]ReturnState) []int {
+	ref := majorityState(states)
+	var res []int
+	for _, pool := range sortedPools(states) {
+		if !statesMatch(meta, ref, states[pool]) {
+			res = append(res, pool)
+		}
+	}
+	return res
+}
+
+// majorityState returns the reference state for outlierPools.
+func majorityState(states map[int]ReturnState) ReturnState {
+	pools := sortedPools(states)
+	ref, refVotes := 0, -1
+	for _, pool := range pools {
+		votes := 0
+		for _, other := range pools {
+			if states[pool] == states[other] {
+				votes++
+			}
+		}
+		if votes > refVotes {
+			ref, refVotes = pool, votes
+		}
+	}
+	return states[ref]
+}
+
+func sortedPools(states map[int]ReturnState) []int {
+	pools := make([]int, 0, len(states))
+	for pool := range states {
+		pools = append(pools, pool)
+	}
+	sort.Ints(pools)
+	return pools
+}
@@ -81,7 +81,20 @@ func TestCompareResults(t *testing.T) {
 				makeExecResultCrashed(1),
 				makeExecResultCrashed(4),
 			},
-			wantReport: nil,
+			wantReport: &ResultReport{
+				Prog: p,
+				Reports: []*CallReport{
+					{Call: "breaks_returns", States: map[int]ReturnState{
+						1: crashedReturnState(),
+						4: crashedReturnState()}},
+					{Call: "minimize$0", States: map[int]ReturnState{
+						1: crashedReturnState(),
+						4: crashedReturnState()}},
+					{Call: "test$res0", States: map[int]ReturnState{
+						1: crashedReturnState(),
+						4: crashedReturnState()}},
+				},
+			},
 		},
 		{
 			name: "mismatches because results and crashes",
@@ -97,17 +110,17 @@ func TestCompareResults(t *testing.T) {
 						1: crashedReturnState(),
 						2: returnState(11, 1),
 						4: returnState(11, 1)},
-						Mismatch: true},
+						Mismatch: true, Outliers: []int{1}},
 					{Call: "minimize$0", States: map[int]ReturnState{
 						1: crashedReturnState(),
 						2: returnState(33, 3),
 						4: returnState(33, 3)},
-						Mismatch: true},
+						Mismatch: true, Outliers: []int{1}},
 					{Call: "test$res0", States: map[int]ReturnState{
 						1: crashedReturnState(),
 						2: returnState(22, 3),
 						4: returnState(22, 3)},
-						Mismatch: true},
+						Mismatch: true, Outliers: []int{1}},
 				},
 				Mismatch: true,
 			},
@@ -117,7 +130,20 @@ func TestCompareResults(t *testing.T) {
 			res: []*ExecResult{
 				makeExecResult(2, []int{11, 33, 22}, []int{1, 3, 3}...),
 				makeExecResult(4, []int{11, 33, 22}, []int{1, 3, 3}...)},
-			wantReport: nil,
+			wantReport: &ResultReport{
+				Prog: p,
+				Reports: []*CallReport{
+					{Call: "breaks_returns", States: map[int]ReturnState{
+						2: returnState(11, 1),
+						4: returnState(11, 1)}},
+					{Call: "minimize$0", States: map[int]ReturnState{
+						2: returnState(33, 3),
+						4: returnState(33, 3)}},
+					{Call: "test$res0", States: map[int]ReturnState{
+						2: returnState(22, 3),
+						4: returnState(22, 3)}},
+				},
+			},
 		},
 		{
 			name: "mismatches found in results",
@@ -130,13 +156,43 @@ func TestCompareResults(t *testing.T) {
 				Reports: []*CallReport{
 					{Call: "breaks_returns", States: map[int]ReturnState{1: {Errno: 1, Flags: 4}, 4: {Errno: 1, Flags: 4}}},
 					{Call: "minimize$0", States: map[int]ReturnState{1: {Errno: 3, Flags: 7}, 4: {Errno: 3, Flags: 7}}},
-					{Call: "test$res0", States: map[int]ReturnState{1: {Errno: 2, Flags: 7}, 4: {Errno: 5, Flags: 3}}, Mismatch: true},
+					{Call: "test$res0", States: map[int]ReturnState{1: {Errno: 2, Flags: 7}, 4: {Errno: 5, Flags: 3}},
+						Mismatch: true, Outliers: []int{4}},
+				},
+				Mismatch: true,
+			},
+		},
+		{
+			name: "outliers found by majority vote",
+			res: []*ExecResult{
+				makeExecResult(0, []int{1, 3, 2}, []int{4, 7, 7}...),
+				makeExecResult(1, []int{1, 4, 2}, []int{4, 7, 7}...),
+				makeExecResult(2, []int{1, 3, 5}, []int{4, 7, 3}...),
+			},
+			wantReport: &ResultReport{
+				Prog: p,
+				Reports: []*CallReport{
+					{Call: "breaks_returns", States: map[int]ReturnState{
+						0: {Errno: 1, Flags: 4},
+						1: {Errno: 1, Flags: 4},
+						2: {Errno: 1, Flags: 4}}},
+					{Call: "minimize$0", States: map[int]ReturnState{
+						0: {Errno: 3, Flags: 7},
+						1: {Errno: 4, Flags: 7},
+						2: {Errno: 3, Flags: 7}},
+						Mismatch: true, Outliers: []int{1}},
+					{Call: "test$res0", States: map[int]ReturnState{
+						0: {Errno: 2, Flags: 7},
+						1: {Errno: 2, Flags: 7},
+						2: {Errno: 5, Flags: 3}},
+						Mismatch: true, Outliers: []int{2}},
 				},
 				Mismatch: true,
 			},
 		}}
 
 	for _, test := range tests {
+		test := test
 		t.Run(test.name, func(t *testing.T) {
 			target := prog.InitTargetTest(t, "test", "64")
 			prog, err := target.Deserialize([]byte(p), prog.Strict)
@@ -150,3 +206,52 @@ func TestCompareResults(t *testing.T) {
 		})
 	}
 }
+
+func TestStatesMatch(t *testing.T) {
+	plain := &prog.Syscall{Name: "plain"}
+	expect := &prog.Syscall{Name: "expect", ExpectErrnos: []uint64{11}}
+	nondet := &prog.Syscall{Name: "nondet", Nondeterministic: true}
+	tests := []struct {
+		meta   *prog.Syscall
+		s0, s1 ReturnState
+		want   bool
+	}{
+		{plain, ReturnState{Errno: 1}, ReturnState{Errno: 1}, true},
+		{plain, ReturnState{Errno: 1}, ReturnState{Errno: 2}, false},
+		{expect, ReturnState{Errno: 0}, ReturnState{Errno: 11}, true},
+		{expect, ReturnState{Errno: 11, Flags: 1}, ReturnState{Errno: 2, Flags: 3}, true},
+		{expect, ReturnState{Errno: 1}, ReturnState{Errno: 2}, false},
+		{expect, ReturnState{Errno: 11}, ReturnState{Crashed: true}, false},
+		{nondet, ReturnState{Errno: 1}, ReturnState{Errno: 2}, true},
+		{nondet, ReturnState{Crashed: true}, ReturnState{Errno: 2}, false},
+	}
+	for i, test := range tests {
+		if got := statesMatch(test.meta, test.s0, test.s1); got != test.want {
+			t.Errorf("#%v: %v: %v vs %v: got %v, want %v", i, test.meta.Name, test.s0, test.s1, got, test.want)
+		}
+	}
+}
+
+func TestOutlierPools(t *testing.T) {
+	plain := &prog.Syscall{Name: "plain"}
+	expect := &prog.Syscall{Name: "expect", ExpectErrnos: []uint64{11}}
+	tests := []struct {
+		meta   *prog.Syscall
+		states map[int]ReturnState
+		want   []int
+	}{
+		{plain, map[int]ReturnState{0: {Errno: 1}, 1: {Errno: 1}}, nil},
+		// No majority, the first pool is the reference.
+		{plain, map[int]ReturnState{0: {Errno: 1}, 1: {Errno: 2}}, []int{1}},
+		{plain, map[int]ReturnState{0: {Errno: 1}, 1: {Errno: 2}, 2: {Errno: 2}}, []int{0}},
+		{plain, map[int]ReturnState{0: {Errno: 1}, 1: {Errno: 2}, 2: {Errno: 3}}, []int{1, 2}},
+		{plain, map[int]ReturnState{0: {Errno: 1}, 1: {Crashed: true}, 2: {Errno: 1}}, []int{1}},
+		{expect, map[int]ReturnState{0: {Errno: 1}, 1: {Errno: 11}, 2: {Errno: 2}, 3: {Errno: 2}}, []int{0}},
+	}
+	for i, test := range tests {
+		got := outlierPools(test.meta, test.states)
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("#%v: outliers mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+}
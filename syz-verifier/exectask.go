@@ -5,6 +5,7 @@ package main
 
 import (
 	"container/heap"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -15,31 +16,79 @@ import (
 
 type EnvDescr int64
 
+const (
+	// The effective priority of a task grows by one for every taskPriorityAging it waits in the queue,
+	// so a task with priority p is not delayed by tasks with priority p+n that arrived more than
+	// n*taskPriorityAging after it and low-priority tasks can't starve under high-priority load.
+	taskPriorityAging = 10 * time.Second
+	// Tasks that waited in the queue for longer than this are counted as starved in Stats.
+	taskStarvationTime = time.Minute
+)
+
 const (
 	AnyEnvironment EnvDescr = iota
 	NewEnvironment
-	// TODO: add CleanVMEnvironment support.
+	// CleanVMEnvironment tasks are executed in VMs that did not execute other tasks (see cleanvm.go).
+	CleanVMEnvironment
+	// SnapshotEnvironment tasks are executed in VMs that did not execute other tasks since they were
+	// restored from the snapshot taken after boot (see snapshot.go).
+	SnapshotEnvironment
 
 	EnvironmentsCount
 )
 
+func (env EnvDescr) String() string {
+	switch env {
+	case AnyEnvironment:
+		return "any"
+	case NewEnvironment:
+		return "new"
+	case CleanVMEnvironment:
+		return "clean-vm"
+	case SnapshotEnvironment:
+		return "snapshot"
+	default:
+		return fmt.Sprintf("env-%d", int64(env))
+	}
+}
+
 // ExecTask is the atomic analysis entity. Once executed, it could trigger the
 // pipeline propagation fof the program.
 type ExecTask struct {
-	CreationTime   time.Time
+	CreationTime time.Time
+	// DispatchTime is the time when the task was sent to a Runner, protected by Verifier.tasksMutex.
+	DispatchTime time.Time
+	// Deadline is the time by which the Runner must return the result (see deadline.go)
+	// and Retries is the number of times the task timed out, protected by Verifier.tasksMutex.
+	Deadline       time.Time
+	Retries        int
 	Program        *prog.Prog
 	ID             int64
 	ExecResultChan ExecResultChan
+	// Trace is set if the program is executed under strace (see trace.go).
+	Trace bool
+	// Sandbox is the executor sandbox of the program, the default sandbox of the Runner
+	// if empty (see sandbox.go).
+	Sandbox string
 
 	priority int // The priority of the item in the queue.
 	// The index is needed by update and is maintained by the heap.Interface methods.
 	index int // The index of the item in the heap.
 }
 
+// agedPriority returns the key that orders tasks by their effective priority.
+// The effective priority of all waiting tasks grows with the same rate,
+// so their order does not change over time and the key does not depend on the current time.
+func (t *ExecTask) agedPriority() int64 {
+	return int64(t.priority)*int64(taskPriorityAging) - t.CreationTime.UnixNano()
+}
+
 func (t *ExecTask) ToRPC() *rpctype.ExecTask {
 	return &rpctype.ExecTask{
-		Prog: t.Program.Serialize(),
-		ID:   t.ID,
+		Prog:    t.Program.Serialize(),
+		ID:      t.ID,
+		Trace:   t.Trace,
+		Sandbox: t.Sandbox,
 	}
 }
 
@@ -55,7 +104,7 @@ func MakeExecTask(prog *prog.Prog) *ExecTask {
 	task := &ExecTask{
 		CreationTime:   time.Now(),
 		Program:        prog,
-		ExecResultChan: make(ExecResultChan),
+		ExecResultChan: make(ExecResultChan, 1),
 		ID:             atomic.AddInt64(&TaskCounter, 1),
 	}
 
@@ -85,7 +134,7 @@ func MakeExecTaskQueue() *ExecTaskQueue {
 	}
 }
 
-// ExecTaskQueue respects the pq.priority. Internally it is a thread-safe PQ.
+// ExecTaskQueue respects the pq.priority with aging (see taskPriorityAging). Internally it is a thread-safe PQ.
 type ExecTaskQueue struct {
 	pq ExecTaskPriorityQueue
 }
@@ -107,6 +156,21 @@ func (q *ExecTaskQueue) Len() int {
 	return q.pq.Len()
 }
 
+// lowest returns the task with the lowest priority, the queue must not be empty.
+func (q *ExecTaskQueue) lowest() *ExecTask {
+	res := q.pq[0]
+	for _, task := range q.pq[1:] {
+		if task.agedPriority() < res.agedPriority() {
+			res = task
+		}
+	}
+	return res
+}
+
+func (q *ExecTaskQueue) remove(task *ExecTask) {
+	heap.Remove(&q.pq, task.index)
+}
+
 // ExecTaskPriorityQueue reused example from https://pkg.go.dev/container/heap
 type ExecTaskPriorityQueue []*ExecTask
 
@@ -114,7 +178,7 @@ func (pq ExecTaskPriorityQueue) Len() int { return len(pq) }
 
 func (pq ExecTaskPriorityQueue) Less(i, j int) bool {
 	// We want Pop to give us the highest, not lowest, priority so we use greater than here.
-	return pq[i].priority > pq[j].priority
+	return pq[i].agedPriority() > pq[j].agedPriority()
 }
 
 func (pq ExecTaskPriorityQueue) Swap(i, j int) {
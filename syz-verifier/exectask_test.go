@@ -0,0 +1,72 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExecTaskQueueAging(t *testing.T) {
+	start := time.Now()
+	makeTask := func(id int64, priority int, age time.Duration) *ExecTask {
+		return &ExecTask{ID: id, priority: priority, CreationTime: start.Add(-age)}
+	}
+	q := MakeExecTaskQueue()
+	// A low-priority task that waits for a long time must be dispatched before
+	// high-priority tasks that arrived much later, but not before recent ones.
+	q.PushTask(makeTask(0, 0, 10*taskPriorityAging))
+	q.PushTask(makeTask(1, 5, 0))
+	q.PushTask(makeTask(2, 20, 0))
+	q.PushTask(makeTask(3, 5, 6*taskPriorityAging))
+	// Tasks with the same priority are dispatched in the arrival order.
+	q.PushTask(makeTask(4, 0, 0))
+	q.PushTask(makeTask(5, 0, time.Second))
+	var order []int64
+	for q.Len() != 0 {
+		task, _ := q.PopTask()
+		order = append(order, task.ID)
+	}
+	want := []int64{2, 3, 0, 1, 5, 4}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("got order %v, want %v", order, want)
+		}
+	}
+}
+
+func TestTaskWaitStats(t *testing.T) {
+	stats := MakeStats()
+	stats.addTaskWait(time.Second)
+	stats.addTaskWait(2 * taskStarvationTime)
+	if stats.DispatchedTasks != 2 || stats.StarvedTasks != 1 || stats.MaxTaskWait != 2*taskStarvationTime ||
+		stats.TotalTaskWait != time.Second+2*taskStarvationTime {
+		t.Fatalf("bad task wait stats: %+v", stats)
+	}
+}
+
+func TestTaskLatencyStats(t *testing.T) {
+	stats := MakeStats()
+	for i := 1; i <= 100; i++ {
+		stats.addTaskLatency(NewEnvironment, time.Duration(i)*time.Millisecond, time.Duration(i)*time.Second)
+	}
+	got := stats.GetJSON(1).Latency
+	if len(got) != 1 || got[0].Env != "new" {
+		t.Fatalf("bad latency stats: %+v", got)
+	}
+	if lat := got[0].Wait; lat.Count != 100 || lat.P50 != 50*time.Millisecond ||
+		lat.P90 != 90*time.Millisecond || lat.P99 != 99*time.Millisecond {
+		t.Errorf("bad wait percentiles: %+v", lat)
+	}
+	if lat := got[0].Exec; lat.P50 != 50*time.Second || lat.P99 != 99*time.Second {
+		t.Errorf("bad exec percentiles: %+v", lat)
+	}
+	// Only the most recent latencyWindow tasks are taken into account.
+	for i := 0; i < latencyWindow; i++ {
+		stats.addTaskLatency(NewEnvironment, time.Hour, time.Hour)
+	}
+	if lat := stats.GetJSON(1).Latency[0].Wait; lat.Count != 100+latencyWindow || lat.P50 != time.Hour {
+		t.Errorf("bad wait percentiles after the window is full: %+v", lat)
+	}
+}
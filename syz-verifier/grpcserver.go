@@ -0,0 +1,125 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/pkg/rpctype"
+	"github.com/google/syzkaller/pkg/rpctype/runnerpb"
+)
+
+// grpcServer implements the gRPC Verifier service (see runner.proto) on top of RPCServer.
+// Each Runner keeps a single Exchange stream open: it sends the result of the previous task
+// and receives the next task in response.
+type grpcServer struct {
+	runnerpb.UnimplementedVerifierServer
+	srv *RPCServer
+}
+
+func (gs *grpcServer) Connect(ctx context.Context, req *runnerpb.ConnectRequest) (
+	*runnerpb.ConnectResponse, error) {
+	r := new(rpctype.RunnerConnectRes)
+	if err := gs.srv.Connect(&rpctype.RunnerConnectArgs{Pool: int(req.Pool), VM: int(req.Vm)}, r); err != nil {
+		return nil, err
+	}
+	return &runnerpb.ConnectResponse{
+		CheckUnsupportedCalls: r.CheckUnsupportedCalls,
+		CollectOutputs:        r.CollectOutputs,
+		StateProbes:           r.StateProbes,
+		HeartbeatPeriodMs:     r.HeartbeatPeriod.Milliseconds(),
+	}, nil
+}
+
+func (gs *grpcServer) UpdateUnsupported(ctx context.Context, req *runnerpb.UpdateUnsupportedRequest) (
+	*runnerpb.UpdateUnsupportedResponse, error) {
+	a := &rpctype.UpdateUnsupportedArgs{Pool: int(req.Pool)}
+	for _, c := range req.UnsupportedCalls {
+		a.UnsupportedCalls = append(a.UnsupportedCalls, rpctype.SyscallReason{ID: int(c.Id), Reason: c.Reason})
+	}
+	if err := gs.srv.UpdateUnsupported(a, nil); err != nil {
+		return nil, err
+	}
+	return new(runnerpb.UpdateUnsupportedResponse), nil
+}
+
+func (gs *grpcServer) Exchange(stream runnerpb.Verifier_ExchangeServer) error {
+	pool, vm := -1, -1
+	defer func() {
+		// The crashes of local VMs are handled by the VM loop, the tasks in progress are failed
+		// in cleanup. There is no VM loop for remote VMs, so they are cleaned up when the stream ends.
+		if gs.srv.remote.isRemote(pool) {
+			gs.srv.cleanup(pool, vm)
+			gs.srv.vrf.health.booting(pool, vm)
+		}
+	}()
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			log.Logf(1, "runner exchange stream failed: %v", err)
+			return err
+		}
+		pool, vm = int(res.Pool), int(res.Vm)
+		a := &rpctype.NextExchangeArgs{
+			Pool:       int(res.Pool),
+			VM:         int(res.Vm),
+			ExecTaskID: res.TaskId,
+			Hanged:     res.Hanged,
+			Trace:      res.Trace,
+		}
+		if res.Info != nil {
+			a.Info = res.Info.ToProgInfo()
+		}
+		r := new(rpctype.NextExchangeRes)
+		if err := gs.srv.NextExchange(a, r); err != nil {
+			return err
+		}
+		task := &runnerpb.ExecTask{
+			Prog:    r.Prog,
+			Id:      r.ID,
+			Reboot:  r.Reboot,
+			Trace:   r.Trace,
+			Sandbox: r.Sandbox,
+		}
+		if err := stream.Send(task); err != nil {
+			return err
+		}
+	}
+}
+
+func (gs *grpcServer) RegisterHost(ctx context.Context, req *runnerpb.RegisterHostRequest) (
+	*runnerpb.RegisterHostResponse, error) {
+	vms, err := gs.srv.RegisterHost(req.Name, int(req.Pool), int(req.Count))
+	if err != nil {
+		return nil, err
+	}
+	r := &runnerpb.RegisterHostResponse{HeartbeatPeriodMs: remoteHeartbeatPeriod.Milliseconds()}
+	for _, vm := range vms {
+		r.Vms = append(r.Vms, int32(vm))
+	}
+	return r, nil
+}
+
+func (gs *grpcServer) Heartbeat(ctx context.Context, req *runnerpb.HeartbeatRequest) (
+	*runnerpb.HeartbeatResponse, error) {
+	reboot, ok := gs.srv.Heartbeat(req.Name)
+	r := &runnerpb.HeartbeatResponse{Reregister: !ok}
+	for _, vm := range reboot {
+		r.Reboot = append(r.Reboot, int32(vm))
+	}
+	return r, nil
+}
+
+func (gs *grpcServer) RunnerHeartbeat(ctx context.Context, req *runnerpb.RunnerHeartbeatRequest) (
+	*runnerpb.RunnerHeartbeatResponse, error) {
+	busy := time.Duration(req.BusyMs) * time.Millisecond
+	gs.srv.RunnerHeartbeat(int(req.Pool), int(req.Vm), busy)
+	return new(runnerpb.RunnerHeartbeatResponse), nil
+}
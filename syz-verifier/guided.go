@@ -0,0 +1,135 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/prog"
+)
+
+// With -guided, confirmed mismatching programs are fed back into the program generation,
+// similar to coverage-guided fuzzing with the divergence instead of the coverage as the signal.
+// The choice table is periodically rebuilt with the mismatching programs as the corpus, so that
+// the syscalls that mismatched and the syscalls used together with them are chosen more often,
+// and a part of the programs is generated by mutating the mismatching programs, so that
+// similar argument patterns are explored.
+
+const (
+	// guidedCorpusSize is the maximum number of mismatching programs kept, the oldest are dropped.
+	guidedCorpusSize = 1000
+	// guidedMutateRate is the fraction of programs generated by mutating mismatching programs.
+	guidedMutateRate = 0.3
+	// guidedRebuildPeriod is the period of rebuilding the choice table if new programs mismatched.
+	guidedRebuildPeriod = time.Minute
+)
+
+// guidedCorpus contains the confirmed mismatching programs. All methods can be called on a nil object.
+type guidedCorpus struct {
+	mu      sync.Mutex
+	progs   []*prog.Prog
+	changed bool
+}
+
+func newGuidedCorpus() *guidedCorpus {
+	return &guidedCorpus{}
+}
+
+// add adds the mismatching program to the corpus.
+func (gc *guidedCorpus) add(p *prog.Prog) {
+	if gc == nil {
+		return
+	}
+	p = p.Clone()
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	if len(gc.progs) >= guidedCorpusSize {
+		gc.progs = gc.progs[1:]
+	}
+	gc.progs = append(gc.progs, p)
+	gc.changed = true
+}
+
+// list returns the programs of the corpus and resets the changed flag.
+func (gc *guidedCorpus) list() []*prog.Prog {
+	if gc == nil {
+		return nil
+	}
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.changed = false
+	return append([]*prog.Prog{}, gc.progs...)
+}
+
+// isChanged returns true if programs were added since the last list call.
+func (gc *guidedCorpus) isChanged() bool {
+	if gc == nil {
+		return false
+	}
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.changed
+}
+
+// sample returns a copy of a random program of the corpus or nil if the corpus is empty.
+func (gc *guidedCorpus) sample(rnd *rand.Rand) *prog.Prog {
+	if gc == nil {
+		return nil
+	}
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	if len(gc.progs) == 0 {
+		return nil
+	}
+	return gc.progs[rnd.Intn(len(gc.progs))].Clone()
+}
+
+// buildChoiceTable builds the choice table for the enabled calls with the mismatching programs
+// as the corpus. Must be called with vrf.callsMu held.
+func (vrf *Verifier) buildChoiceTable() {
+	var corpus []*prog.Prog
+next:
+	for _, p := range vrf.guided.list() {
+		// Programs with calls disabled at runtime can't be in the corpus of the choice table.
+		for _, c := range p.Calls {
+			if !vrf.calls[c.Meta] {
+				continue next
+			}
+		}
+		corpus = append(corpus, p)
+	}
+	vrf.choiceTable = vrf.target.BuildChoiceTable(corpus, vrf.calls)
+	if len(corpus) != 0 {
+		log.Logf(1, "rebuilt choice table with %v mismatching programs", len(corpus))
+	}
+}
+
+// guidedLoop periodically rebuilds the choice table if new programs mismatched.
+func (vrf *Verifier) guidedLoop(period time.Duration) {
+	vrf.progGeneratorInit.Wait()
+	for range time.NewTicker(period).C {
+		if !vrf.guided.isChanged() {
+			continue
+		}
+		vrf.callsMu.Lock()
+		vrf.buildChoiceTable()
+		vrf.callsMu.Unlock()
+	}
+}
+
+// mutateMismatching returns a mutated mismatching program or nil if no program should be mutated.
+func (vrf *Verifier) mutateMismatching(rnd *rand.Rand, ct *prog.ChoiceTable) *prog.Prog {
+	if vrf.guided == nil || rnd.Float64() >= guidedMutateRate {
+		return nil
+	}
+	p := vrf.guided.sample(rnd)
+	if p == nil || !vrf.callsEnabled(p) {
+		return nil
+	}
+	p.Mutate(rnd, prog.RecommendedCalls, ct, nil)
+	return p
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/google/syzkaller/prog"
+)
+
+func TestGuidedCorpus(t *testing.T) {
+	target := prog.InitTargetTest(t, "test", "64")
+	deserialize := func(p string) *prog.Prog {
+		res, err := target.Deserialize([]byte(p), prog.Strict)
+		if err != nil {
+			t.Fatalf("failed to deserialise test program: %v", err)
+		}
+		return res
+	}
+	calls := make(map[*prog.Syscall]bool)
+	for _, name := range []string{"breaks_returns", "minimize$0", "test$res0", "test$res1"} {
+		calls[target.SyscallMap[name]] = true
+	}
+	vrf := &Verifier{
+		target: target,
+		calls:  calls,
+		guided: newGuidedCorpus(),
+	}
+	vrf.guided.add(deserialize("test$res0()\nminimize$0(0x1, 0x1)\n"))
+	if !vrf.guided.isChanged() {
+		t.Fatalf("corpus is not changed after add")
+	}
+	// The program with the disabled call must not get into the choice table.
+	vrf.guided.add(deserialize("test$res2()\n"))
+	vrf.buildChoiceTable()
+	if vrf.guided.isChanged() {
+		t.Fatalf("corpus is changed after the choice table is rebuilt")
+	}
+
+	rnd := rand.New(rand.NewSource(0))
+	mutated := 0
+	for i := 0; i < 100; i++ {
+		p := vrf.mutateMismatching(rnd, vrf.choiceTable)
+		if p == nil {
+			continue
+		}
+		mutated++
+		if !vrf.callsEnabled(p) {
+			t.Fatalf("mutated program contains disabled calls:\n%s", p.Serialize())
+		}
+	}
+	if mutated == 0 || mutated > 50 {
+		t.Errorf("mutated %v of 100 programs", mutated)
+	}
+
+	for i := 0; i < guidedCorpusSize; i++ {
+		vrf.guided.add(deserialize("breaks_returns()\n"))
+	}
+	if got := len(vrf.guided.list()); got != guidedCorpusSize {
+		t.Errorf("corpus contains %v programs, want %v", got, guidedCorpusSize)
+	}
+
+	var nilCorpus *guidedCorpus
+	nilCorpus.add(deserialize("breaks_returns()\n"))
+	if nilCorpus.list() != nil || nilCorpus.sample(rnd) != nil {
+		t.Errorf("nil corpus is not empty")
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// vmHealth tracks the state of all VMs and their Runners for the web UI and the detection
+// of stalled Runners (see runnerhealth.go).
+// All methods can be called on a nil object.
+type vmHealth struct {
+	mu  sync.Mutex
+	vms map[int]*VMStatus
+}
+
+// VMStatus describes the state of a single VM.
+type VMStatus struct {
+	Pool int
+	VM   int
+	// Host is the name of the remote host that runs the VM (see remote.go), empty for local VMs.
+	Host     string
+	Running  bool // the Runner is started, otherwise the VM is booting
+	Boots    int
+	LastBoot time.Time
+	BootTime time.Duration // duration of the last boot
+	// Started is the time the Runner was started.
+	Started time.Time
+	// LastResult is the time of the last program result received from the Runner.
+	LastResult time.Time
+	Results    int64
+	// LastHeartbeat is the time of the last heartbeat of the Runner and Busy is for how long
+	// the Runner had been executing the current program at that time.
+	LastHeartbeat time.Time
+	Busy          time.Duration
+	// Stalled is set if the Runner stopped responding and the VM is being restarted.
+	Stalled  bool
+	Restarts int
+}
+
+// State returns the state of the VM: booting, running or stalled.
+func (st VMStatus) State() string {
+	switch {
+	case st.Stalled:
+		return "stalled"
+	case st.Running:
+		return "running"
+	default:
+		return "booting"
+	}
+}
+
+func newVMHealth() *vmHealth {
+	return &vmHealth{vms: make(map[int]*VMStatus)}
+}
+
+func (vh *vmHealth) get(pool, vm int) *VMStatus {
+	st := vh.vms[vmTasksKey(pool, vm)]
+	if st == nil {
+		st = &VMStatus{Pool: pool, VM: vm}
+		vh.vms[vmTasksKey(pool, vm)] = st
+	}
+	return st
+}
+
+func (vh *vmHealth) booting(pool, vm int) {
+	if vh == nil {
+		return
+	}
+	vh.mu.Lock()
+	defer vh.mu.Unlock()
+	st := vh.get(pool, vm)
+	st.Running = false
+	st.Stalled = false
+	st.Busy = 0
+	st.Boots++
+	st.LastBoot = time.Now()
+}
+
+func (vh *vmHealth) running(pool, vm int) {
+	if vh == nil {
+		return
+	}
+	vh.mu.Lock()
+	defer vh.mu.Unlock()
+	st := vh.get(pool, vm)
+	st.Running = true
+	st.Started = time.Now()
+	st.BootTime = st.Started.Sub(st.LastBoot)
+}
+
+func (vh *vmHealth) setHost(pool, vm int, host string) {
+	if vh == nil {
+		return
+	}
+	vh.mu.Lock()
+	defer vh.mu.Unlock()
+	vh.get(pool, vm).Host = host
+}
+
+func (vh *vmHealth) resultReceived(pool, vm int) {
+	if vh == nil {
+		return
+	}
+	vh.mu.Lock()
+	defer vh.mu.Unlock()
+	st := vh.get(pool, vm)
+	st.LastResult = time.Now()
+	st.Results++
+	st.Busy = 0
+}
+
+func (vh *vmHealth) heartbeat(pool, vm int, busy time.Duration) {
+	if vh == nil {
+		return
+	}
+	vh.mu.Lock()
+	defer vh.mu.Unlock()
+	st := vh.get(pool, vm)
+	st.LastHeartbeat = time.Now()
+	st.Busy = busy
+}
+
+// snapshot returns states of all VMs ordered by pool and VM index.
+func (vh *vmHealth) snapshot() []VMStatus {
+	if vh == nil {
+		return nil
+	}
+	vh.mu.Lock()
+	defer vh.mu.Unlock()
+	var res []VMStatus
+	for _, st := range vh.vms {
+		res = append(res, *st)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Pool != res[j].Pool {
+			return res[i].Pool < res[j].Pool
+		}
+		return res[i].VM < res[j].VM
+	})
+	return res
+}
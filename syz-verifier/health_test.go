@@ -0,0 +1,35 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestVMHealth(t *testing.T) {
+	var nilHealth *vmHealth
+	nilHealth.booting(0, 0)
+	if vms := nilHealth.snapshot(); vms != nil {
+		t.Fatalf("nil health returned VMs: %+v", vms)
+	}
+
+	vh := newVMHealth()
+	vh.booting(1, 0)
+	vh.booting(0, 1)
+	vh.running(0, 1)
+	vh.resultReceived(0, 1)
+	vh.resultReceived(0, 1)
+	vh.booting(0, 1)
+	vms := vh.snapshot()
+	if len(vms) != 2 {
+		t.Fatalf("got %v VMs, want 2", len(vms))
+	}
+	if vm := vms[0]; vm.Pool != 0 || vm.VM != 1 || vm.Running || vm.Boots != 2 ||
+		vm.Results != 2 || vm.LastResult.IsZero() {
+		t.Errorf("bad VM 0/1 state: %+v", vm)
+	}
+	if vm := vms[1]; vm.Pool != 1 || vm.VM != 0 || vm.Running || vm.Boots != 1 || vm.Results != 0 {
+		t.Errorf("bad VM 1/0 state: %+v", vm)
+	}
+}
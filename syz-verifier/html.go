@@ -0,0 +1,187 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/google/syzkaller/pkg/html"
+)
+
+const (
+	// Number of the most recent mismatching programs shown in the web UI.
+	uiMaxMismatches = 20
+	// The web UI page is reloaded with this period (in seconds).
+	uiRefreshPeriod = 10
+)
+
+type uiSummaryData struct {
+	Now        time.Time
+	Refresh    int
+	Stats      *StatsJSON
+	Calls      []*CallStatsJSON // only calls with mismatches
+	Mismatches []*mismatchJSON
+	VMs        []VMStatus
+}
+
+// httpSummary renders the main page of the web UI.
+func (monitor *Monitor) httpSummary(w http.ResponseWriter, r *http.Request) {
+	data := &uiSummaryData{
+		Now:     time.Now(),
+		Refresh: uiRefreshPeriod,
+		VMs:     monitor.health.snapshot(),
+	}
+	if stats := monitor.externalStats; stats != nil {
+		data.Stats = stats.GetJSON(time.Since(stats.StartTime).Minutes())
+		for _, call := range data.Stats.Calls {
+			if call.Mismatches != 0 {
+				data.Calls = append(data.Calls, call)
+			}
+		}
+	}
+	data.Mismatches = monitor.renderMismatches().([]*mismatchJSON)
+	if len(data.Mismatches) > uiMaxMismatches {
+		data.Mismatches = data.Mismatches[:uiMaxMismatches]
+	}
+	buf := new(bytes.Buffer)
+	if err := summaryTemplate.Execute(buf, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(buf.Bytes())
+}
+
+var summaryTemplate = html.CreatePage(`
+<!doctype html>
+<html>
+<head>
+	<title>syz-verifier</title>
+	<meta http-equiv="refresh" content="{{.Refresh}}">
+	{{HEAD}}
+</head>
+<body>
+<b>syz-verifier</b> ({{formatTime .Now}}, refreshed every {{.Refresh}} seconds)
+<br>
+
+{{with $s := .Stats}}
+<table class="list_table">
+	<caption>Stats:</caption>
+	<tr><td class="stat_name">start time</td><td class="stat_value">{{formatTime $s.StartTime}}</td></tr>
+	<tr><td class="stat_name">programs</td><td class="stat_value">{{$s.TotalProgs}}</td></tr>
+	<tr><td class="stat_name">programs / minute</td><td class="stat_value">{{printf "%.2f" $s.ProgsPerMinute}}</td></tr>
+	<tr><td class="stat_name">mismatching programs</td><td class="stat_value">{{$s.MismatchingProgs}}</td></tr>
+	<tr><td class="stat_name">flaky programs</td><td class="stat_value">{{$s.FlakyProgs}}</td></tr>
+	{{range $cause, $count := $s.FlakyCauses}}
+	<tr><td class="stat_name">flaky programs: {{$cause}}</td><td class="stat_value">{{$count}}</td></tr>
+	{{end}}
+	<tr><td class="stat_name">exec error programs</td><td class="stat_value">{{$s.ExecErrorProgs}}</td></tr>
+	<tr><td class="stat_name">calls</td><td class="stat_value">{{$s.TotalCalls}}</td></tr>
+	<tr><td class="stat_name">call mismatches</td><td class="stat_value">{{$s.TotalCallMismatches}}</td></tr>
+	{{range $kc := $s.Coverage}}
+	<tr><td class="stat_name">coverage: pool {{$kc.Pool}}</td>
+		<td class="stat_value">{{$kc.Cover}} ({{printf "%.2f" $kc.CoverPerMinute}} / minute)</td></tr>
+	{{end}}
+	{{range $pool, $n := $s.Outliers}}
+	<tr><td class="stat_name">outlier: pool {{$pool}}</td><td class="stat_value">{{$n}}</td></tr>
+	{{end}}
+	<tr><td class="stat_name">task wait (average / max)</td>
+		<td class="stat_value">{{$s.AverTaskWait}} / {{$s.MaxTaskWait}}</td></tr>
+	<tr><td class="stat_name">starved tasks</td><td class="stat_value">{{$s.StarvedTasks}} / {{$s.DispatchedTasks}}</td></tr>
+	{{range $lat := $s.Latency}}
+	<tr><td class="stat_name">task wait ({{$lat.Env}} env)</td><td class="stat_value">{{$lat.Wait}}</td></tr>
+	<tr><td class="stat_name">task execution ({{$lat.Env}} env)</td><td class="stat_value">{{$lat.Exec}}</td></tr>
+	{{end}}
+</table>
+{{if $s.Subsystems}}
+<table class="list_table">
+	<caption>Subsystems:</caption>
+	<tr>
+		<th>Subsystem</th>
+		<th>Mismatches</th>
+		<th>Occurrences</th>
+		<th>Mismatching calls</th>
+	</tr>
+	{{range $ss := $s.Subsystems}}
+	<tr>
+		<td>{{$ss.Name}}</td>
+		<td class="stat">{{$ss.Mismatches}}</td>
+		<td class="stat">{{$ss.Occurrences}}</td>
+		<td class="stat">{{$ss.MismatchingCalls}} / {{$ss.Calls}}</td>
+	</tr>
+	{{end}}
+</table>
+{{end}}
+{{end}}
+
+<table class="list_table">
+	<caption>Mismatching calls:</caption>
+	<tr>
+		<th>Call</th>
+		<th>Mismatches</th>
+		<th>Occurrences</th>
+		<th>States</th>
+		<th>Outliers</th>
+	</tr>
+	{{range $c := .Calls}}
+	<tr>
+		<td>{{$c.Name}}</td>
+		<td class="stat">{{$c.Mismatches}}</td>
+		<td class="stat">{{$c.Occurrences}}</td>
+		<td>{{range $st := $c.States}}{{$st.Description}}<br>{{end}}</td>
+		<td>{{range $pool, $n := $c.Outliers}}pool {{$pool}}: {{$n}}<br>{{end}}</td>
+	</tr>
+	{{end}}
+</table>
+
+<table class="list_table">
+	<caption>VMs:</caption>
+	<tr>
+		<th>Pool</th>
+		<th>VM</th>
+		<th>Host</th>
+		<th>State</th>
+		<th>Boots</th>
+		<th>Last boot</th>
+		<th>Boot time</th>
+		<th>Restarts</th>
+		<th>Results</th>
+		<th>Last result</th>
+	</tr>
+	{{range $vm := .VMs}}
+	<tr>
+		<td>{{$vm.Pool}}</td>
+		<td>{{$vm.VM}}</td>
+		<td>{{$vm.Host}}</td>
+		<td>{{$vm.State}}</td>
+		<td class="stat">{{$vm.Boots}}</td>
+		<td class="time">{{formatTime $vm.LastBoot}}</td>
+		<td class="stat">{{formatDuration $vm.BootTime}}</td>
+		<td class="stat">{{$vm.Restarts}}</td>
+		<td class="stat">{{$vm.Results}}</td>
+		<td class="time">{{formatLateness $.Now $vm.LastResult}}</td>
+	</tr>
+	{{end}}
+</table>
+
+<b>Recent mismatches:</b>
+<br>
+{{range $m := .Mismatches}}
+<b>{{$m.ID}}</b> ({{formatTime $m.Time}})
+<pre>{{$m.Prog}}</pre>
+<pre>{{$m.Report}}</pre>
+{{end}}
+
+<a href="/api/stats.json">stats.json</a>
+<a href="/api/calls.json">calls.json</a>
+<a href="/api/calls.csv">calls.csv</a>
+<a href="/api/mismatches.json">mismatches.json</a>
+<a href="/api/export.json">export.json</a>
+<a href="/api/vms.json">vms.json</a>
+<a href="/metrics">metrics</a>
+<a href="/debug/pprof/">pprof</a>
+<a href="/debug/profilebundle">profile bundle</a>
+</body></html>
+`)
@@ -0,0 +1,171 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/syzkaller/pkg/hash"
+	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/pkg/mgrconfig"
+	"github.com/google/syzkaller/pkg/rpctype"
+	"github.com/google/syzkaller/prog"
+)
+
+// With -hub, the verifier connects to the syz-hub configured in the first kernel config
+// (hub_client, hub_addr, hub_key, hub_domain) as manager <name>-verifier and appends the corpus
+// programs contributed by the managers to the backlog (see backlog.go), so that programs found
+// by fuzzing are verified in addition to the generated ones. The verifier does not contribute
+// programs, it only reports the programs it could not parse, so that the hub lowers their reputation.
+// Programs are pulled only when the backlog is short, so they are not piling up in memory
+// if the hub has a large corpus.
+
+const (
+	hubSyncPeriod = time.Minute
+	// hubMaxBacklog is the length of the backlog above which no new programs are pulled.
+	hubMaxBacklog = 1000
+)
+
+// hubClient is implemented by rpctype.RPCClient.
+type hubClient interface {
+	Call(method string, args, reply interface{}) error
+	Close()
+}
+
+type hubConnector struct {
+	vrf    *Verifier
+	addr   string
+	client string
+	key    string
+	name   string
+	domain string
+	// fresh is set until the first successful connect, then the hub sends all of its corpus.
+	fresh    bool
+	outcomes []rpctype.HubOutcome
+	dial     func(addr string) (hubClient, error)
+}
+
+func newHubConnector(vrf *Verifier, cfg *mgrconfig.Config) *hubConnector {
+	return &hubConnector{
+		vrf:    vrf,
+		addr:   cfg.HubAddr,
+		client: cfg.HubClient,
+		key:    cfg.HubKey,
+		name:   cfg.Name + "-verifier",
+		domain: cfg.TargetOS + "/" + cfg.HubDomain,
+		fresh:  true,
+		dial: func(addr string) (hubClient, error) {
+			return rpctype.NewGobRPCClient(addr, 1)
+		},
+	}
+}
+
+func (hc *hubConnector) loop() {
+	hc.vrf.progGeneratorInit.Wait()
+	var hub hubClient
+	for ; ; time.Sleep(hubSyncPeriod) {
+		if hub == nil {
+			var err error
+			if hub, err = hc.connect(); err != nil {
+				log.Logf(0, "failed to connect to hub at %v: %v", hc.addr, err)
+				continue
+			}
+			log.Logf(0, "connected to hub at %v", hc.addr)
+		}
+		if err := hc.sync(hub); err != nil {
+			log.Logf(0, "hub sync failed: %v", err)
+			hub.Close()
+			hub = nil
+		}
+	}
+}
+
+func (hc *hubConnector) connect() (hubClient, error) {
+	a := &rpctype.HubConnectArgs{
+		Client:  hc.client,
+		Key:     hc.key,
+		Manager: hc.name,
+		Domain:  hc.domain,
+		Fresh:   hc.fresh,
+	}
+	// The hub sends only programs with calls enabled on all kernels.
+	a.Calls = hc.vrf.enabledCalls()
+	hub, err := hc.dial(hc.addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := hub.Call("Hub.Connect", a, nil); err != nil {
+		hub.Close()
+		return nil, err
+	}
+	hc.fresh = false
+	return hub, nil
+}
+
+// sync pulls programs from the hub until it has no more programs or the backlog is long enough.
+func (hc *hubConnector) sync(hub hubClient) error {
+	for hc.vrf.backlogLen() < hubMaxBacklog {
+		a := &rpctype.HubSyncArgs{
+			Client:   hc.client,
+			Key:      hc.key,
+			Manager:  hc.name,
+			Outcomes: hc.outcomes,
+		}
+		r := new(rpctype.HubSyncRes)
+		if err := hub.Call("Hub.Sync", a, r); err != nil {
+			return err
+		}
+		// If the call fails, the outcomes are sent again with the next sync.
+		hc.outcomes = nil
+		progs := r.Progs
+		for _, inp := range r.Inputs {
+			progs = append(progs, inp.Prog)
+		}
+		added, dropped := hc.addProgs(progs)
+		log.Logf(0, "hub sync: recv: progs %v (dropped %v); more %v", added, dropped, r.More)
+		if r.More == 0 {
+			break
+		}
+	}
+	return nil
+}
+
+// addProgs appends the programs to the backlog and returns the number of the added and the dropped ones.
+func (hc *hubConnector) addProgs(data [][]byte) (int, int) {
+	var progs []*prog.Prog
+	for _, d := range data {
+		p, err := hc.vrf.target.Deserialize(d, prog.NonStrict)
+		if err != nil {
+			log.Logf(1, "rejecting program from hub: %v\n%s", err, d)
+			// Let the hub know, so that it does not spread broken programs further.
+			hc.outcomes = append(hc.outcomes, rpctype.HubOutcome{Sig: hash.String(d), Failed: true})
+			continue
+		}
+		if !hc.vrf.callsEnabled(p) || !hc.vrf.verified.add(p) {
+			continue
+		}
+		progs = append(progs, p)
+	}
+	atomic.AddInt64(&hc.vrf.stats.HubProgs, int64(len(progs)))
+	hc.vrf.backlogMu.Lock()
+	defer hc.vrf.backlogMu.Unlock()
+	hc.vrf.backlog = append(hc.vrf.backlog, progs...)
+	return len(progs), len(data) - len(progs)
+}
+
+func (vrf *Verifier) backlogLen() int {
+	vrf.backlogMu.Lock()
+	defer vrf.backlogMu.Unlock()
+	return len(vrf.backlog)
+}
+
+// checkHubConfig checks that the config has the settings needed for -hub.
+func checkHubConfig(cfg *mgrconfig.Config) error {
+	if cfg.HubClient == "" || cfg.HubAddr == "" {
+		return fmt.Errorf("-hub requires hub_client and hub_addr in the first kernel config")
+	}
+	return nil
+}
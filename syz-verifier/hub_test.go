@@ -0,0 +1,118 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/pkg/hash"
+	"github.com/google/syzkaller/pkg/rpctype"
+	"github.com/google/syzkaller/prog"
+)
+
+// testHub replies to each sync with the next batch of programs.
+type testHub struct {
+	connects []rpctype.HubConnectArgs
+	syncs    []rpctype.HubSyncArgs
+	batches  [][]string
+}
+
+func (hub *testHub) Call(method string, args, reply interface{}) error {
+	switch method {
+	case "Hub.Connect":
+		hub.connects = append(hub.connects, *args.(*rpctype.HubConnectArgs))
+	case "Hub.Sync":
+		hub.syncs = append(hub.syncs, *args.(*rpctype.HubSyncArgs))
+		if len(hub.batches) == 0 {
+			return nil
+		}
+		r := reply.(*rpctype.HubSyncRes)
+		for _, p := range hub.batches[0] {
+			r.Inputs = append(r.Inputs, rpctype.HubInput{Domain: "test/", Prog: []byte(p)})
+		}
+		hub.batches = hub.batches[1:]
+		r.More = len(hub.batches)
+	default:
+		return fmt.Errorf("unknown method %v", method)
+	}
+	return nil
+}
+
+func (hub *testHub) Close() {}
+
+func TestHubConnector(t *testing.T) {
+	target := prog.InitTargetTest(t, "test", "64")
+	vrf := &Verifier{
+		target:   target,
+		stats:    MakeStats(),
+		verified: newProgSet(),
+		calls: map[*prog.Syscall]bool{
+			target.SyscallMap["breaks_returns"]: true,
+			target.SyscallMap["minimize$0"]:     true,
+		},
+	}
+	hub := &testHub{batches: [][]string{
+		{"breaks_returns()\n", "test$res0()\n", "broken(\n"},
+		{"minimize$0(0x1, 0x1)\n", "breaks_returns()\n"},
+	}}
+	hc := &hubConnector{
+		vrf:    vrf,
+		client: "client",
+		name:   "client-mgr-verifier",
+		domain: "test/",
+		fresh:  true,
+		dial: func(addr string) (hubClient, error) {
+			return hub, nil
+		},
+	}
+	conn, err := hc.connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hc.sync(conn); err != nil {
+		t.Fatal(err)
+	}
+	if len(hub.connects) != 1 || !hub.connects[0].Fresh || hub.connects[0].Manager != "client-mgr-verifier" ||
+		!cmp.Equal(hub.connects[0].Calls, []string{"breaks_returns", "minimize$0"}) {
+		t.Errorf("bad connect: %+v", hub.connects)
+	}
+	// The programs are pulled until the hub has no more programs, the broken program
+	// is reported to the hub in the next sync.
+	if len(hub.syncs) != 2 || len(hub.syncs[0].Outcomes) != 0 ||
+		!cmp.Equal(hub.syncs[1].Outcomes, []rpctype.HubOutcome{{Sig: hash.String([]byte("broken(\n")), Failed: true}}) {
+		t.Errorf("bad syncs: %+v", hub.syncs)
+	}
+	// The programs with disabled calls and duplicates are skipped.
+	for _, want := range []string{"breaks_returns()\n", "minimize$0(0x1, 0x1)\n", ""} {
+		got := ""
+		if p := vrf.popBacklog(); p != nil {
+			got = string(p.Serialize())
+		}
+		if got != want {
+			t.Errorf("got backlog program %q, want %q", got, want)
+		}
+	}
+	if vrf.stats.HubProgs != 2 {
+		t.Errorf("got %v hub programs, want 2", vrf.stats.HubProgs)
+	}
+
+	// Nothing is pulled if the backlog is long enough.
+	if _, err := hc.connect(); err != nil {
+		t.Fatal(err)
+	}
+	if hub.connects[1].Fresh {
+		t.Errorf("the second connect is fresh")
+	}
+	for i := 0; i < hubMaxBacklog; i++ {
+		vrf.backlog = append(vrf.backlog, nil)
+	}
+	if err := hc.sync(conn); err != nil {
+		t.Fatal(err)
+	}
+	if len(hub.syncs) != 2 {
+		t.Errorf("synced with a full backlog")
+	}
+}
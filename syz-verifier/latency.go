@@ -0,0 +1,111 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// latencyWindow is the number of the most recent tasks used to compute latency percentiles.
+const latencyWindow = 10000
+
+// taskLatency keeps queue wait and execution times of the recent tasks of one environment type.
+type taskLatency struct {
+	wait latencySamples
+	exec latencySamples
+}
+
+// latencySamples is a ring buffer with the latencyWindow most recent samples.
+type latencySamples struct {
+	samples []time.Duration
+	next    int
+	count   int64
+}
+
+func (ls *latencySamples) add(d time.Duration) {
+	ls.count++
+	if len(ls.samples) < latencyWindow {
+		ls.samples = append(ls.samples, d)
+		return
+	}
+	ls.samples[ls.next] = d
+	ls.next = (ls.next + 1) % latencyWindow
+}
+
+// LatencyPercentiles describes the distribution of the latencies of the recent tasks.
+type LatencyPercentiles struct {
+	// Count is the total number of tasks, percentiles are computed for the latencyWindow most recent ones.
+	Count int64
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+}
+
+func (lp LatencyPercentiles) String() string {
+	return fmt.Sprintf("p50 %v, p90 %v, p99 %v", lp.P50, lp.P90, lp.P99)
+}
+
+func (ls *latencySamples) percentiles() LatencyPercentiles {
+	res := LatencyPercentiles{Count: ls.count}
+	if len(ls.samples) == 0 {
+		return res
+	}
+	sorted := append([]time.Duration{}, ls.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	percentile := func(p float64) time.Duration {
+		idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+		if idx < 0 {
+			idx = 0
+		}
+		return sorted[idx]
+	}
+	res.P50, res.P90, res.P99 = percentile(0.5), percentile(0.9), percentile(0.99)
+	return res
+}
+
+// EnvLatencyJSON contains latency percentiles of the tasks of one environment type.
+type EnvLatencyJSON struct {
+	Env string
+	// Wait is the time between creation of the task and its dispatch to a Runner.
+	Wait LatencyPercentiles
+	// Exec is the time between the dispatch of the task and receiving its result.
+	Exec LatencyPercentiles
+}
+
+// addTaskLatency records the queue wait and execution times of a task executed in env.
+func (stats *Stats) addTaskLatency(env EnvDescr, wait, exec time.Duration) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	if stats.latency == nil {
+		stats.latency = make(map[EnvDescr]*taskLatency)
+	}
+	tl := stats.latency[env]
+	if tl == nil {
+		tl = new(taskLatency)
+		stats.latency[env] = tl
+	}
+	tl.wait.add(wait)
+	tl.exec.add(exec)
+}
+
+// latencies returns latency percentiles for all environment types that executed tasks.
+// Must be called with stats.mu held.
+func (stats *Stats) latencies() []*EnvLatencyJSON {
+	var res []*EnvLatencyJSON
+	for env := AnyEnvironment; env < EnvironmentsCount; env++ {
+		tl := stats.latency[env]
+		if tl == nil {
+			continue
+		}
+		res = append(res, &EnvLatencyJSON{
+			Env:  env.String(),
+			Wait: tl.wait.percentiles(),
+			Exec: tl.exec.percentiles(),
+		})
+	}
+	return res
+}
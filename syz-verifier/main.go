@@ -11,11 +11,16 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/google/syzkaller/dashboard/dashapi"
+	"github.com/google/syzkaller/pkg/email"
 	"github.com/google/syzkaller/pkg/log"
 	"github.com/google/syzkaller/pkg/mgrconfig"
 	"github.com/google/syzkaller/pkg/osutil"
 	"github.com/google/syzkaller/pkg/report"
+	"github.com/google/syzkaller/pkg/rpctype"
 	"github.com/google/syzkaller/pkg/tool"
 	"github.com/google/syzkaller/prog"
 	"github.com/google/syzkaller/vm"
@@ -33,9 +38,15 @@ type poolInfo struct {
 	cfg      *mgrconfig.Config
 	pool     *vm.Pool
 	Reporter *report.Reporter
+	// runnerBin is the syz-runner binary for the target of the kernel.
+	runnerBin string
 	// checked is set to true when the set of system calls not supported on the
 	// kernel is known.
 	checked bool
+	// standby is set if some of the VMs are kept booted but idle (see standbyPool).
+	standby *standbyPool
+	// throttle is set if the number of VMs executing tasks is rebalanced (see balance.go).
+	throttle *poolThrottle
 }
 
 func main() {
@@ -44,10 +55,182 @@ func main() {
 	flagDebug := flag.Bool("debug", false, "dump all VM output to console")
 	flagStats := flag.String("stats", "", "where stats will be written when"+
 		"execution of syz-verifier finishes, defaults to stdout")
+	flagStatsJSON := flag.Bool("stats-json", false, "write stats in JSON instead of text")
+	flagStatsCSV := flag.String("stats-csv", "", "where per-syscall stats will be written in CSV "+
+		"when execution of syz-verifier finishes, relative to the workdir")
+	flagReportSinks := flag.String("report-sinks", "", "comma-separated list of destinations of stats reports "+
+		"written every -report-interval, on SIGUSR1 and at exit: stdout, file:<path> (relative to the workdir, "+
+		"previous reports are rotated), http(s)://<url> (POST request)")
+	flagReportInterval := flag.Duration("report-interval", 0, "period of the -report-sinks reports "+
+		"(0 to write them only on demand and at exit)")
 	flagEnv := flag.Bool("new-env", true, "create a new environment for each program")
-	flagReruns := flag.Int("rerun", 3, "number of time program is rerun when a mismatch is found")
+	flagReruns := flag.Int("rerun", 10, "maximum number of times program is rerun when a mismatch is found")
+	flagFlakyRate := flag.Float64("flaky-rate", defaultFlakyRate,
+		"probability that a flaky program diverges in a run (null hypothesis of the flaky classifier)")
+	flagMismatchThreshold := flag.Float64("mismatch-threshold", defaultMismatchThreshold,
+		"mismatch is confirmed when probability that the divergence is due to nondeterminism is below this value")
+	flagMinimize := flag.Bool("minimize", true, "minimize programs with new mismatches before "+
+		"writing them to the results dir")
+	flagTriage := flag.Bool("triage", true, "find the earlier calls that cause new mismatches "+
+		"and include the analysis in the mismatch reports")
+	flagSnapshots := flag.Bool("snapshots", false, "restore the VMs of local kernels from snapshots taken "+
+		"after boot instead of rebooting them (qemu only)")
+	flagSandboxes := flag.String("sandboxes", "", "comma-separated executor sandboxes (none, setuid, "+
+		"namespace, android) programs with new mismatches are rerun in to tell permission-dependent mismatches")
+	flagTraces := flag.Bool("traces", false, "execute programs with new mismatches under strace "+
+		"on all kernels and write the traces to the results dir (strace must be installed in the VMs)")
+	flagSuppressions := flag.String("suppressions", "", "JSON file with known mismatches that are "+
+		"not counted and reported (see suppress.go)")
+	flagCompareOutputs := flag.Bool("compare-outputs", false, "compare return values and values "+
+		"returned in memory (resources, integers and output buffers) in addition to errno")
+	flagCoverDivergence := flag.Float64("cover-divergence", 0, "flag programs that did not diverge, "+
+		"but whose calls reach at least this many times more PCs on some kernel (0 disables, requires cover)")
+	flagStateProbes := flag.String("state-probes", "", "comma-separated files and directories (e.g. "+
+		"/proc/self/fd,/proc/self/mounts) whose change is compared for programs that did not diverge")
+	flagGuided := flag.Bool("guided", false, "bias the program generation towards syscalls and "+
+		"programs that mismatched")
+	flagRerunPolicy := flag.String("rerun-policy", rerunPolicyBinomial, "how mismatches are confirmed: "+
+		"binomial (see -flaky-rate and -mismatch-threshold) or agreement (see -agreement)")
+	flagAgreement := flag.Float64("agreement", defaultAgreement, "with -rerun-policy=agreement, the mismatch "+
+		"is confirmed if at least this fraction of all 1+rerun runs diverge")
+	flagStandby := flag.Int("standby", 0, "number of booted idle VMs per kernel that replace crashed VMs")
+	flagStandbyMax := flag.Int("standby-max", 0, "if greater than -standby, the number of standby VMs "+
+		"is tuned between -standby and -standby-max based on the VM restart and task arrival rates")
+	flagCleanVMRerun := flag.Bool("clean-vm-rerun", false, "rerun confirmed mismatches once in freshly "+
+		"booted VMs, mismatches that are not reproduced are attributed to the state of the VMs")
+	flagRecord := flag.String("record", "", "append all tested programs and their results to this file")
+	flagReplay := flag.String("replay", "", "re-compute verdicts for programs recorded with -record "+
+		"in this file without starting VMs, results are saved to <workdir>/replay")
+	flagVerdicts := flag.String("verdicts", "", "append verdicts of all tested programs to this file "+
+		"(one JSON object per line, \"-\" for stdout)")
+	flagDB := flag.String("db", "", "results database with outcomes of all tested programs, "+
+		"defaults to <workdir>/results.db")
+	flagQuery := flag.String("query", "", "print outcomes from the -db database that match the query "+
+		"(e.g. \"verdict mismatch and contains call bpf$PROG_LOAD and since monday\", see query.go) and exit")
+	flagCheckpoint := flag.Duration("checkpoint", 10*time.Minute, "period of saving stats and hashes of tested "+
+		"programs to <workdir>/checkpoint.json, they are restored on startup if the file exists (0 to disable)")
+	flagTimeline := flag.String("timeline", "", "append snapshots of the stats to this file "+
+		"(one JSON object per line), relative to the workdir")
+	flagTimelinePeriod := flag.Duration("timeline-period", 10*time.Minute, "period of the -timeline snapshots")
+	flagNotifyWebhook := flag.String("notify-webhook", "", "URL to POST a JSON notification to "+
+		"whenever a mismatch with a new signature is found")
+	flagNotifyEmail := flag.String("notify-email", "", "comma-separated list of emails "+
+		"to send reports of mismatches with new signatures to (requires -smtp and -smtp-from)")
+	flagDashboard := flag.Bool("dashboard", false, "upload mismatches with new signatures to the dashboard "+
+		"configured in the first kernel config (dashboard_client, dashboard_addr, dashboard_key)")
+	flagSMTP := flag.String("smtp", "", "host:port of the SMTP server for -notify-email")
+	flagSMTPFrom := flag.String("smtp-from", "", "sender address of -notify-email emails")
+	flagSMTPUser := flag.String("smtp-user", "", "SMTP user name, the password is taken from "+
+		"the SYZ_VERIFIER_SMTP_PASSWORD environment variable")
+	flagTaskTimeout := flag.Duration("task-timeout", 10*time.Minute, "retry tasks that did not return "+
+		"a result within this time after they were sent to a runner (0 to wait forever)")
+	flagRunnerTimeout := flag.Duration("runner-timeout", 10*time.Minute, "restart the VMs of runners that "+
+		"did not send heartbeats or executed a single program for longer than this time (0 to disable)")
+	flagTaskRetries := flag.Int("task-retries", 3, "maximum number of retries of a timed out task")
+	flagQueueCapacity := flag.Int("queue-capacity", 0, "maximum number of tasks waiting in the queue "+
+		"of each kernel (0 for unlimited)")
+	flagQueuePolicy := flag.String("queue-policy", queuePolicyBlock, "what to do with a new task if the "+
+		"queue is full: block (wait, which slows down the program generation) or "+
+		"drop-lowest (drop the task with the lowest priority)")
+	flagRebalance := flag.Bool("rebalance", false, "adjust the number of VMs of each kernel that execute "+
+		"tasks to the throughput of the slowest kernel")
+	flagResume := flag.Bool("resume", false, "log hashes of verified programs to workdir/verified.log, "+
+		"skip them and do not report their known mismatches again after a restart")
+	flagCorpus := flag.String("corpus", "", "syz-db corpus (e.g. corpus.db of syz-manager) whose programs "+
+		"are verified before random programs are generated")
+	flagHub := flag.Bool("hub", false, "verify corpus programs of syz-hub configured in the first kernel config "+
+		"(hub_client, hub_addr, hub_key), the verifier connects as manager <name>-verifier")
+	flagPrioritize := flag.Bool("prioritize", false, "execute first programs with rarely executed "+
+		"syscalls or syscalls that often mismatched")
+	flagCrossArch := flag.Bool("cross-arch", false, "allow kernels built for different architectures "+
+		"of the same OS, programs are generated for the architecture of the first kernel")
+	flagRPCTokenFile := flag.String("rpc-token-file", "", "file with a secret token that runners must present, "+
+		"allows runners that are not started by the verifier (e.g. in a remote lab) to connect securely")
+	flagRemotePools := flag.String("remote-pools", "", "comma-separated indices of the kernels in -configs "+
+		"whose VMs run on other machines started with -agent instead of locally")
+	flagAgent := flag.String("agent", "", "run the VMs of a remote kernel on this machine for the verifier "+
+		"listening on this address (syz-verifier -agent=host:port -agent-pool=N -configs=kernel.cfg)")
+	flagAgentName := flag.String("agent-name", "", "name that identifies this machine across -agent restarts, "+
+		"defaults to the host name")
+	flagAgentPool := flag.Int("agent-pool", 0, "index of the kernel in -configs of the verifier "+
+		"whose VMs are run by -agent")
+	flagAgentTLS := flag.String("agent-tls", "", "directory with ca.crt, client.crt and client.key "+
+		"copied from <workdir>/tls of the verifier, if it uses rpc_tls")
+	flagCompare := flag.Bool("compare", false, "compare two stats files saved with -stats-json "+
+		"(syz-verifier -compare old.json new.json), print syscalls whose mismatch rate changed significantly and exit")
 	flag.Parse()
 
+	if *flagCompare {
+		if flag.NArg() != 2 {
+			tool.Failf("usage: syz-verifier -compare old_stats.json new_stats.json")
+		}
+		if err := runCompare(os.Stdout, flag.Arg(0), flag.Arg(1)); err != nil {
+			tool.Fail(err)
+		}
+		return
+	}
+
+	if *flagAgent != "" {
+		if len(cfgs) != 1 {
+			tool.Failf("-agent requires a single kernel config")
+		}
+		name := *flagAgentName
+		if name == "" {
+			var err error
+			if name, err = os.Hostname(); err != nil {
+				tool.Fail(err)
+			}
+		}
+		tool.Fail(runAgent(*flagAgent, name, *flagAgentPool, cfgs[0], *flagAgentTLS, *flagRPCTokenFile,
+			*flagEnv, *flagDebug))
+	}
+
+	if err := checkQueuePolicy(*flagQueuePolicy); err != nil {
+		tool.Fail(err)
+	}
+	if err := checkRerunPolicy(*flagRerunPolicy, *flagReruns, *flagFlakyRate, *flagMismatchThreshold,
+		*flagAgreement); err != nil {
+		tool.Fail(err)
+	}
+	if *flagCoverDivergence != 0 && *flagCoverDivergence < 1 {
+		tool.Failf("-cover-divergence must be 0 or at least 1")
+	}
+	stateProbes, err := parseStateProbes(*flagStateProbes)
+	if err != nil {
+		tool.Fail(err)
+	}
+	sandboxes, err := parseSandboxes(*flagSandboxes)
+	if err != nil {
+		tool.Fail(err)
+	}
+	rpcToken := ""
+	if *flagRPCTokenFile != "" {
+		if rpcToken, err = rpctype.ReadTokenFile(*flagRPCTokenFile); err != nil {
+			tool.Fail(err)
+		}
+	}
+	remotePools, err := parseRemotePools(*flagRemotePools, len(cfgs))
+	if err != nil {
+		tool.Fail(err)
+	}
+	remote := make(map[int]bool)
+	for _, pool := range remotePools {
+		remote[pool] = true
+	}
+	if *flagCorpus != "" && !osutil.IsExist(*flagCorpus) {
+		tool.Failf("corpus %v does not exist", *flagCorpus)
+	}
+
+	if *flagQuery != "" {
+		if *flagDB == "" {
+			tool.Failf("-query requires -db")
+		}
+		if err := runQuery(os.Stdout, *flagDB, *flagQuery); err != nil {
+			tool.Fail(err)
+		}
+		return
+	}
+
 	pools := make(map[int]*poolInfo)
 	for idx, cfg := range cfgs {
 		var err error
@@ -56,9 +239,17 @@ func main() {
 		if err != nil {
 			log.Fatalf("%v", err)
 		}
-		pi.pool, err = vm.Create(pi.cfg, *flagDebug)
-		if err != nil {
-			log.Fatalf("%v", err)
+		if *flagReplay == "" && !remote[idx] {
+			pi.pool, err = vm.Create(pi.cfg, *flagDebug)
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			if *flagStandby > 0 || *flagStandbyMax > 0 {
+				pi.standby = newStandbyPool(pi.pool.Count(), *flagStandby, *flagStandbyMax)
+			}
+			if *flagRebalance {
+				pi.throttle = newPoolThrottle()
+			}
 		}
 		pools[idx] = pi
 	}
@@ -69,6 +260,21 @@ func main() {
 	}
 
 	cfg := pools[0].cfg
+	if *flagHub {
+		if err := checkHubConfig(cfg); err != nil {
+			tool.Fail(err)
+		}
+	}
+	if *flagDashboard {
+		if err := checkDashboardConfig(cfg); err != nil {
+			tool.Fail(err)
+		}
+	}
+	if *flagSnapshots {
+		if err := checkSnapshotsConfig(pools, remotePools); err != nil {
+			tool.Fail(err)
+		}
+	}
 	workdir, target, sysTarget, addr := cfg.Workdir, cfg.Target, cfg.SysTarget, cfg.RPC
 	for idx := 1; idx < len(pools); idx++ {
 		cfg := pools[idx].cfg
@@ -80,9 +286,14 @@ func main() {
 			log.Fatalf("working directory mismatch")
 		}
 		if target != cfg.Target {
-			log.Fatalf("target mismatch")
-		}
-		if sysTarget != cfg.SysTarget {
+			if !*flagCrossArch {
+				log.Fatalf("target mismatch (use -cross-arch to verify kernels of different architectures)")
+			}
+			if target.OS != cfg.Target.OS {
+				log.Fatalf("OS mismatch")
+			}
+			log.Logf(0, "kernel %d: %v/%v", idx, cfg.Target.OS, cfg.Target.Arch)
+		} else if sysTarget != cfg.SysTarget {
 			log.Fatalf("system target mismatch")
 		}
 		if addr != pools[idx].cfg.RPC {
@@ -90,20 +301,69 @@ func main() {
 		}
 	}
 
-	exe := sysTarget.ExeExtension
-	runnerBin := filepath.Join(cfg.Syzkaller, "bin", target.OS+"_"+target.Arch, "syz-runner"+exe)
-	if !osutil.IsExist(runnerBin) {
-		log.Fatalf("bad syzkaller config: can't find %v", runnerBin)
+	statsCSV := ""
+	if *flagStatsCSV != "" {
+		statsCSV = filepath.Join(workdir, *flagStatsCSV)
+	}
+
+	setErrnoTarget(target.OS, target.Arch)
+	stats := MakeStats()
+	if subsystems, err := loadSubsystems(filepath.Join(cfg.Syzkaller, "sys", target.OS)); err != nil {
+		log.Logf(0, "subsystem statistics are disabled: %v", err)
+	} else {
+		stats.subsystems = subsystems
 	}
-	execBin := cfg.ExecutorBin
-	if !osutil.IsExist(execBin) {
-		log.Fatalf("bad syzkaller config: can't find %v", execBin)
+	for idx, pi := range pools {
+		if pi.pool == nil {
+			continue
+		}
+		if stats.poolVMs == nil {
+			stats.poolVMs = make(map[int]int)
+		}
+		stats.poolVMs[idx] = pi.pool.Count()
+	}
+
+	if *flagReplay != "" {
+		replay(*flagReplay, &Verifier{
+			workdir:           workdir,
+			resultsdir:        filepath.Join(workdir, "replay"),
+			pools:             pools,
+			target:            target,
+			stats:             stats,
+			statsJSON:         *flagStatsJSON,
+			statsCSV:          statsCSV,
+			reruns:            *flagReruns,
+			flakyRate:         *flagFlakyRate,
+			mismatchThreshold: *flagMismatchThreshold,
+			rerunPolicy:       *flagRerunPolicy,
+			agreement:         *flagAgreement,
+			compareOutputs:    *flagCompareOutputs,
+			coverDivergence:   *flagCoverDivergence,
+			stateProbes:       stateProbes,
+			cleanVMRerun:      *flagCleanVMRerun,
+		}, *flagStats)
+		return
+	}
+
+	for _, pi := range pools {
+		if pi.pool == nil {
+			// The binaries of remote kernels are checked by the agents.
+			continue
+		}
+		target, exe := pi.cfg.Target, pi.cfg.SysTarget.ExeExtension
+		pi.runnerBin = filepath.Join(pi.cfg.Syzkaller, "bin", target.OS+"_"+target.Arch, "syz-runner"+exe)
+		if !osutil.IsExist(pi.runnerBin) {
+			log.Fatalf("bad syzkaller config: can't find %v", pi.runnerBin)
+		}
+		if !osutil.IsExist(pi.cfg.ExecutorBin) {
+			log.Fatalf("bad syzkaller config: can't find %v", pi.cfg.ExecutorBin)
+		}
 	}
 
 	crashdir := filepath.Join(workdir, "crashes")
 	osutil.MkdirAll(crashdir)
-	for idx := range pools {
-		OS, Arch := target.OS, target.Arch
+	for idx, pi := range pools {
+		OS, Arch := pi.cfg.Target.OS, pi.cfg.Target.Arch
 		targetPath := OS + "-" + Arch + "-" + strconv.Itoa(idx)
 		osutil.MkdirAll(filepath.Join(workdir, targetPath))
 		osutil.MkdirAll(filepath.Join(crashdir, targetPath))
@@ -113,7 +373,6 @@ func main() {
 	osutil.MkdirAll(resultsdir)
 
 	var sw io.Writer
-	var err error
 	if *flagStats == "" {
 		sw = os.Stdout
 	} else {
@@ -140,30 +399,178 @@ func main() {
 	}
 
 	vrf := &Verifier{
-		workdir:       workdir,
-		crashdir:      crashdir,
-		resultsdir:    resultsdir,
-		pools:         pools,
-		target:        target,
-		calls:         calls,
-		reasons:       make(map[*prog.Syscall]string),
-		runnerBin:     runnerBin,
-		executorBin:   execBin,
-		addr:          addr,
-		reportReasons: len(cfg.EnabledSyscalls) != 0 || len(cfg.DisabledSyscalls) != 0,
-		stats:         MakeStats(),
-		statsWrite:    sw,
-		newEnv:        *flagEnv,
-		reruns:        *flagReruns,
+		workdir:           workdir,
+		crashdir:          crashdir,
+		resultsdir:        resultsdir,
+		pools:             pools,
+		target:            target,
+		calls:             calls,
+		reasons:           make(map[*prog.Syscall]string),
+		addr:              addr,
+		tls:               cfg.RPCTLS,
+		rpcTokenFile:      *flagRPCTokenFile,
+		rpcToken:          rpcToken,
+		remotePools:       remotePools,
+		reportReasons:     len(cfg.EnabledSyscalls) != 0 || len(cfg.DisabledSyscalls) != 0,
+		stats:             stats,
+		statsWrite:        sw,
+		statsJSON:         *flagStatsJSON,
+		statsCSV:          statsCSV,
+		health:            newVMHealth(),
+		newEnv:            *flagEnv,
+		reruns:            *flagReruns,
+		flakyRate:         *flagFlakyRate,
+		mismatchThreshold: *flagMismatchThreshold,
+		rerunPolicy:       *flagRerunPolicy,
+		agreement:         *flagAgreement,
+		compareOutputs:    *flagCompareOutputs,
+		coverDivergence:   *flagCoverDivergence,
+		stateProbes:       stateProbes,
+		cleanVMRerun:      *flagCleanVMRerun,
+		minimize:          *flagMinimize,
+		triage:            *flagTriage,
+		traces:            *flagTraces,
+		sandboxes:         sandboxes,
+		snapshots:         *flagSnapshots,
+		prioritize:        *flagPrioritize,
+		taskTimeout:       *flagTaskTimeout,
+		taskRetries:       *flagTaskRetries,
+		taskRetryBackoff:  defaultTaskBackoff,
+		runnerTimeout:     *flagRunnerTimeout,
+		queueCapacity:     *flagQueueCapacity,
+		queuePolicy:       *flagQueuePolicy,
+		corpusFile:        *flagCorpus,
+	}
+
+	stats.health = vrf.health
+	vrf.initCrossArch()
+	cRepro := cReproOpts(cfg)
+	vrf.cRepro = &cRepro
+
+	if *flagReportSinks != "" {
+		sinks, err := parseReportSinks(*flagReportSinks, workdir, *flagStatsJSON)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		vrf.reports = &reportSinks{sinks: sinks, gen: vrf.writeStats}
+	}
+
+	if *flagNotifyWebhook != "" || *flagNotifyEmail != "" {
+		vrf.notifier = &notifier{webhook: *flagNotifyWebhook}
+		if *flagNotifyEmail != "" {
+			if *flagSMTP == "" || *flagSMTPFrom == "" {
+				log.Fatalf("-notify-email requires -smtp and -smtp-from")
+			}
+			vrf.notifier.to = strings.Split(*flagNotifyEmail, ",")
+			vrf.notifier.sender = &email.SMTPSender{
+				Server:   *flagSMTP,
+				From:     *flagSMTPFrom,
+				User:     *flagSMTPUser,
+				Password: os.Getenv("SYZ_VERIFIER_SMTP_PASSWORD"),
+			}
+		}
+	}
+
+	if *flagDashboard {
+		dash, err := dashapi.New(cfg.DashboardClient, cfg.DashboardAddr, cfg.DashboardKey)
+		if err != nil {
+			log.Fatalf("failed to create dashapi connection: %v", err)
+		}
+		var kernelCfgs []*mgrconfig.Config
+		for idx := 0; idx < len(pools); idx++ {
+			kernelCfgs = append(kernelCfgs, pools[idx].cfg)
+		}
+		vrf.dash = newDashReporter(dash, kernelCfgs)
+	}
+
+	if *flagRecord != "" {
+		vrf.recorder, err = newRecorder(*flagRecord)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+	if *flagVerdicts != "" {
+		vrf.verdicts, err = openVerdictStream(*flagVerdicts)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+	dbFile := *flagDB
+	if dbFile == "" {
+		dbFile = filepath.Join(workdir, "results.db")
+	}
+	vrf.store, err = openOutcomeStore(dbFile)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if *flagCheckpoint > 0 {
+		vrf.checkpointFile = filepath.Join(workdir, "checkpoint.json")
+		vrf.checkpointPeriod = *flagCheckpoint
+		vrf.verified = newProgSet()
+	}
+
+	if *flagSuppressions != "" {
+		vrf.suppressions, err = loadSuppressions(*flagSuppressions)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+	if *flagGuided {
+		vrf.guided = newGuidedCorpus()
+	}
+	if *flagResume {
+		vrf.progress, err = openProgressLog(filepath.Join(workdir, "verified.log"))
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if vrf.verified == nil {
+			vrf.verified = newProgSet()
+		}
+		vrf.verified.restore(vrf.progress.hashes())
 	}
 
 	vrf.Init()
 
+	if *flagTimeline != "" {
+		tl, err := openTimeline(filepath.Join(workdir, *flagTimeline))
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		go vrf.timelineLoop(tl, *flagTimelinePeriod)
+	}
+
+	if vrf.reports != nil {
+		go vrf.reportLoop(*flagReportInterval)
+	}
 	vrf.StartProgramsAnalysis()
 	vrf.startInstances()
+	if *flagStandbyMax > *flagStandby {
+		go vrf.tuneStandby(time.Minute)
+	}
+	if *flagRebalance {
+		go vrf.rebalanceLoop(rebalancePeriod)
+	}
+	if vrf.guided != nil {
+		go vrf.guidedLoop(guidedRebuildPeriod)
+	}
+	if *flagHub {
+		go newHubConnector(vrf, cfg).loop()
+	}
 
 	monitor := MakeMonitor()
 	monitor.SetStatsTracking(vrf.stats)
+	monitor.SetResultsDir(resultsdir)
+	monitor.SetQueueTracking(vrf.queueLen)
+	if vrf.queueCapacity != 0 {
+		monitor.SetQueueSaturationTracking(vrf.queueSaturation)
+	}
+	monitor.SetHealthTracking(vrf.health)
+	monitor.SetSyscallMaskControl(vrf.enabledCalls, vrf.updateSyscallMask)
+	monitor.SetPauseControl(vrf.isPaused, vrf.setPaused)
+	if vrf.reports != nil {
+		monitor.SetReportTrigger(vrf.reports.report)
+	}
 
 	// TODO: move binding address to configuration
 	log.Logf(0, "run the Monitor at http://127.0.0.1:8080/")
@@ -171,3 +578,35 @@ func main() {
 
 	select {}
 }
+
+func replay(file string, vrf *Verifier, statsFile string) {
+	f, err := os.Open(file)
+	if err != nil {
+		log.Fatalf("failed to open record log: %v", err)
+	}
+	defer f.Close()
+	osutil.MkdirAll(vrf.resultsdir)
+	calls := make(map[*prog.Syscall]bool)
+	for _, c := range vrf.target.Syscalls {
+		calls[c] = true
+	}
+	vrf.stats.SetSyscallMask(calls)
+	if err := vrf.Replay(f); err != nil {
+		log.Fatalf("%v", err)
+	}
+	w := io.Writer(os.Stdout)
+	if statsFile != "" {
+		sf, err := os.Create(filepath.Join(vrf.workdir, statsFile))
+		if err != nil {
+			log.Fatalf("failed to create stats output file: %v", err)
+		}
+		defer sf.Close()
+		w = sf
+	}
+	if err := vrf.writeStats(w); err != nil {
+		log.Fatalf("failed to write stats: %v", err)
+	}
+	if err := vrf.writeStatsCSV(); err != nil {
+		log.Fatalf("failed to write stats: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricsCollector exports the verification statistics in the Prometheus format ("/metrics").
+// Values are read from Stats on every scrape, so nothing needs to be updated during verification.
+type metricsCollector struct {
+	monitor *Monitor
+
+	progs            *prometheus.Desc
+	execErrorProgs   *prometheus.Desc
+	mismatchingProgs *prometheus.Desc
+	flakyProgs       *prometheus.Desc
+	flakyCauses      *prometheus.Desc
+	callMismatches   *prometheus.Desc
+	progsPerMinute   *prometheus.Desc
+	queueLen         *prometheus.Desc
+	queueSaturation  *prometheus.Desc
+	queueBlocked     *prometheus.Desc
+	queueDropped     *prometheus.Desc
+	syscallMismatch  *prometheus.Desc
+	syscallOccur     *prometheus.Desc
+}
+
+func newMetricsCollector(monitor *Monitor) *metricsCollector {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc("syz_verifier_"+name, help, labels, nil)
+	}
+	return &metricsCollector{
+		monitor:          monitor,
+		progs:            desc("progs_total", "Total number of verified programs"),
+		execErrorProgs:   desc("exec_error_progs_total", "Number of programs that failed to execute on some kernel"),
+		mismatchingProgs: desc("mismatching_progs_total", "Number of programs with confirmed mismatches"),
+		flakyProgs:       desc("flaky_progs_total", "Number of programs with flaky mismatches"),
+		flakyCauses:      desc("flaky_progs_by_cause_total", "Number of flaky programs with the cause", "cause"),
+		callMismatches:   desc("call_mismatches_total", "Total number of call mismatches"),
+		progsPerMinute:   desc("progs_per_minute", "Average number of verified programs per minute"),
+		queueLen:         desc("task_queue_len", "Number of tasks waiting for a runner"),
+		queueSaturation:  desc("task_queue_saturation", "Fill ratio of the fullest task queue"),
+		queueBlocked:     desc("task_queue_blocked_total", "Number of tasks that waited for space in a full queue"),
+		queueDropped:     desc("task_queue_dropped_total", "Number of tasks dropped from full queues"),
+		syscallMismatch:  desc("syscall_mismatches_total", "Number of mismatches of the syscall", "syscall"),
+		syscallOccur:     desc("syscall_occurrences_total", "Number of verified executions of the syscall", "syscall"),
+	}
+}
+
+func (mc *metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{mc.progs, mc.execErrorProgs, mc.mismatchingProgs, mc.flakyProgs,
+		mc.flakyCauses, mc.callMismatches, mc.progsPerMinute, mc.queueLen, mc.queueSaturation, mc.queueBlocked,
+		mc.queueDropped, mc.syscallMismatch, mc.syscallOccur} {
+		ch <- desc
+	}
+}
+
+func (mc *metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := mc.monitor.externalStats
+	if stats == nil {
+		return
+	}
+	counter := func(desc *prometheus.Desc, val int64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(val), labels...)
+	}
+	progs := atomic.LoadInt64(&stats.TotalProgs)
+	counter(mc.progs, progs)
+	counter(mc.execErrorProgs, atomic.LoadInt64(&stats.ExecErrorProgs))
+	counter(mc.mismatchingProgs, atomic.LoadInt64(&stats.MismatchingProgs))
+	counter(mc.flakyProgs, atomic.LoadInt64(&stats.FlakyProgs))
+	for cause, count := range stats.flakyCausesSnapshot() {
+		counter(mc.flakyCauses, count, cause)
+	}
+	counter(mc.callMismatches, atomic.LoadInt64(&stats.TotalCallMismatches))
+	minutes := time.Since(stats.StartTime).Minutes()
+	if minutes > 0 {
+		ch <- prometheus.MustNewConstMetric(mc.progsPerMinute, prometheus.GaugeValue, float64(progs)/minutes)
+	}
+	if mc.monitor.queueLen != nil {
+		ch <- prometheus.MustNewConstMetric(mc.queueLen, prometheus.GaugeValue, float64(mc.monitor.queueLen()))
+	}
+	if mc.monitor.queueSaturation != nil {
+		ch <- prometheus.MustNewConstMetric(mc.queueSaturation, prometheus.GaugeValue, mc.monitor.queueSaturation())
+	}
+	counter(mc.queueBlocked, atomic.LoadInt64(&stats.QueueBlockedTasks))
+	counter(mc.queueDropped, atomic.LoadInt64(&stats.QueueDroppedTasks))
+	for _, cs := range stats.callsSnapshot() {
+		counter(mc.syscallMismatch, cs.Mismatches, cs.Name)
+		counter(mc.syscallOccur, cs.Occurrences, cs.Name)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/prog"
+)
+
+// With -minimize, programs with new mismatch signatures are minimized before they are written
+// to the results dir, so that the reported programs are small enough to triage. A candidate
+// program is accepted if a single run on all kernels diverges with the same signature.
+
+// minimizeMaxRuns limits the number of runs spent on the minimization of a program.
+const minimizeMaxRuns = 200
+
+// minimizeMismatch returns the minimized program and its verdict with the results of the last
+// divergent run of the minimized program. Returns the original program and verdict if the
+// program can't be minimized.
+func (vrf *Verifier) minimizeMismatch(p *prog.Prog, v *Verdict, run runFunc) (*prog.Prog, *Verdict) {
+	callIndex := -1
+	for i, cr := range CompareResults(v.Results, p).Reports {
+		if cr.Mismatch {
+			callIndex = i
+			break
+		}
+	}
+	var last []*ExecResult
+	runs := 0
+	pred := func(p1 *prog.Prog, _ int) bool {
+		if runs >= minimizeMaxRuns {
+			return false
+		}
+		runs++
+		res := reproduceMismatch(p1, v.Signature, run)
+		if res == nil {
+			return false
+		}
+		last = res
+		return true
+	}
+	minimized, _ := prog.Minimize(p, callIndex, false, pred)
+	if last == nil {
+		log.Logf(1, "failed to minimize mismatching program in %v runs", runs)
+		return p, v
+	}
+	log.Logf(0, "minimized mismatching program from %v to %v calls in %v runs",
+		len(p.Calls), len(minimized.Calls), runs)
+	res := *v
+	res.Results = last
+	return minimized, &res
+}
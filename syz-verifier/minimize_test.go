@@ -0,0 +1,55 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/syzkaller/prog"
+)
+
+func TestMinimizeMismatch(t *testing.T) {
+	target := prog.InitTargetTest(t, "test", "64")
+	p, err := target.Deserialize([]byte("breaks_returns()\nminimize$0(0x1, 0x1)\nbreaks_returns()\n"), prog.Strict)
+	if err != nil {
+		t.Fatalf("failed to deserialise test program: %v", err)
+	}
+	// minimize$0 fails with EPERM on the second kernel.
+	mismatching := func(p *prog.Prog, env EnvDescr) ([]*ExecResult, error) {
+		var errnos0, errnos1 []int
+		for _, c := range p.Calls {
+			errnos0 = append(errnos0, 0)
+			if c.Meta.Name == "minimize$0" {
+				errnos1 = append(errnos1, 1)
+			} else {
+				errnos1 = append(errnos1, 0)
+			}
+		}
+		return []*ExecResult{makeExecResult(0, errnos0), makeExecResult(1, errnos1)}, nil
+	}
+	matching := func(p *prog.Prog, env EnvDescr) ([]*ExecResult, error) {
+		errnos := make([]int, len(p.Calls))
+		return []*ExecResult{makeExecResult(0, errnos), makeExecResult(1, errnos)}, nil
+	}
+	res, _ := mismatching(p, NewEnvironment)
+	v := &Verdict{Results: res, Mismatch: true, Signature: mismatchSignature(p, res)}
+	vrf := &Verifier{}
+
+	minimized, mv := vrf.minimizeMismatch(p, v, mismatching)
+	if len(minimized.Calls) != 1 || minimized.Calls[0].Meta.Name != "minimize$0" {
+		t.Errorf("bad minimized program:\n%s", minimized.Serialize())
+	}
+	if len(mv.Results) != 2 || len(mv.Results[1].Info.Calls) != 1 || mv.Signature != v.Signature {
+		t.Errorf("bad verdict of the minimized program: %+v", mv)
+	}
+	if len(v.Results[0].Info.Calls) != 3 {
+		t.Errorf("verdict of the original program was changed")
+	}
+
+	// The divergence doesn't reproduce, so the program is not minimized.
+	same, sv := vrf.minimizeMismatch(p, v, matching)
+	if same != p || sv != v {
+		t.Errorf("program was minimized without divergence")
+	}
+}
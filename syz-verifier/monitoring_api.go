@@ -5,14 +5,38 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
+
+	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/pkg/profile"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Monitor provides http based data for the syz-verifier monitoring.
 // TODO: Add tests to monitoring_api.
 type Monitor struct {
 	externalStats *Stats
+	resultsdir    string
+	queueLen      func() int
+	// queueSaturation is set if the capacity of the task queues is limited.
+	queueSaturation func() float64
+	health          *vmHealth
+	report          func() error
+	// enabledCalls and updateSyscallMask are set if the syscall mask can be changed at runtime.
+	enabledCalls      func() []string
+	updateSyscallMask func(req *SyscallMaskJSON) error
+	// paused and setPaused are set if the verifier can be paused.
+	paused    func() bool
+	setPaused func(paused bool)
 }
 
 // MakeMonitor creates the Monitor instance.
@@ -32,13 +56,179 @@ func (monitor *Monitor) SetStatsTracking(s *Stats) {
 	monitor.externalStats = s
 }
 
+// SetResultsDir points Monitor to the directory with mismatch reports.
+func (monitor *Monitor) SetResultsDir(dir string) {
+	monitor.resultsdir = dir
+}
+
+// SetQueueTracking sets the function that returns the number of tasks waiting for runners.
+func (monitor *Monitor) SetQueueTracking(queueLen func() int) {
+	monitor.queueLen = queueLen
+}
+
+// SetQueueSaturationTracking points Monitor to the function returning the saturation of the task queues.
+func (monitor *Monitor) SetQueueSaturationTracking(saturation func() float64) {
+	monitor.queueSaturation = saturation
+}
+
+// SetHealthTracking points Monitor to the VM states shown in the web UI.
+func (monitor *Monitor) SetHealthTracking(health *vmHealth) {
+	monitor.health = health
+}
+
+// SetReportTrigger sets the function that writes a report to the report sinks ("POST /api/report").
+func (monitor *Monitor) SetReportTrigger(report func() error) {
+	monitor.report = report
+}
+
+// SetSyscallMaskControl sets the functions that return and change the enabled syscalls ("/api/syscalls").
+func (monitor *Monitor) SetSyscallMaskControl(enabled func() []string, update func(req *SyscallMaskJSON) error) {
+	monitor.enabledCalls = enabled
+	monitor.updateSyscallMask = update
+}
+
+// SetPauseControl sets the functions that return and change whether the verifier is paused
+// ("POST /api/pause", "POST /api/resume").
+func (monitor *Monitor) SetPauseControl(paused func() bool, setPaused func(paused bool)) {
+	monitor.paused = paused
+	monitor.setPaused = setPaused
+}
+
 // InitHTTPHandlers initializes the API routing.
 func (monitor *Monitor) initHTTPHandlers() {
 	http.Handle("/api/stats.json", jsonResponse(monitor.renderStats))
+	http.Handle("/api/calls.json", jsonResponse(monitor.renderCalls))
+	http.Handle("/api/mismatches.json", jsonResponse(monitor.renderMismatches))
+	http.Handle("/api/export.json", jsonResponse(monitor.renderExport))
+	http.Handle("/api/vms.json", jsonResponse(func() interface{} { return monitor.health.snapshot() }))
+	http.HandleFunc("/api/calls.csv", monitor.httpCallsCSV)
+	http.HandleFunc("/api/report", monitor.httpReport)
+	http.HandleFunc("/api/syscalls", monitor.httpSyscalls)
+	http.HandleFunc("/api/pause", monitor.httpPause(true))
+	http.HandleFunc("/api/resume", monitor.httpPause(false))
+	// API for external orchestration, e.g. to decide when a verification run has converged.
+	http.Handle("/stats", getOnly(jsonResponse(monitor.renderExport)))
+	http.Handle("/calls/", getOnly(http.HandlerFunc(monitor.httpCall)))
+	http.Handle("/queue", getOnly(jsonResponse(monitor.renderQueue)))
+	http.Handle("/metrics", monitor.metricsHandler())
+	http.HandleFunc("/log-levels", log.LevelsHandler)
+	http.HandleFunc("/debug/profilebundle", profile.BundleHandler)
 
-	http.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
-		writer.Write([]byte("<a href='api/stats.json'>stats_json</a>"))
-	})
+	http.HandleFunc("/", monitor.httpSummary)
+}
+
+// httpReport writes a report to the report sinks on demand.
+func (monitor *Monitor) httpReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "use POST to write a report", http.StatusMethodNotAllowed)
+		return
+	}
+	if monitor.report == nil {
+		http.Error(w, "no report sinks", http.StatusServiceUnavailable)
+		return
+	}
+	if err := monitor.report(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
+
+// httpPause returns the handler that pauses or resumes the verifier.
+func (monitor *Monitor) httpPause(paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "use POST", http.StatusMethodNotAllowed)
+			return
+		}
+		if monitor.setPaused == nil {
+			http.Error(w, "verifier can't be paused", http.StatusServiceUnavailable)
+			return
+		}
+		monitor.setPaused(paused)
+		w.Write([]byte("ok\n"))
+	}
+}
+
+// httpSyscalls renders the enabled syscalls, on POST the syscalls of the request (SyscallMaskJSON)
+// are enabled and disabled first.
+func (monitor *Monitor) httpSyscalls(w http.ResponseWriter, r *http.Request) {
+	if monitor.enabledCalls == nil {
+		http.Error(w, "syscall mask can't be changed", http.StatusServiceUnavailable)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPost:
+		req := new(SyscallMaskJSON)
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			http.Error(w, fmt.Sprintf("bad request: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := monitor.updateSyscallMask(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "use GET or POST", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, monitor.enabledCalls())
+}
+
+// httpCall renders statistics of the call "/calls/{name}".
+func (monitor *Monitor) httpCall(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/calls/")
+	if monitor.externalStats == nil {
+		http.Error(w, "no stats", http.StatusServiceUnavailable)
+		return
+	}
+	cs, ok := monitor.externalStats.callSnapshot(name)
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown call %q", name), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, makeCallStatsJSON(&cs))
+}
+
+// queueJSON provides information for the "/queue" render.
+type queueJSON struct {
+	// Len is the number of tasks waiting for runners.
+	Len int
+	// Saturation is the fill ratio of the fullest queue if the queue capacity is limited.
+	Saturation float64 `json:",omitempty"`
+	// Paused is set if the program generation and the dispatch of tasks are paused.
+	Paused bool `json:",omitempty"`
+	// VMs contains states of the VMs running the Runners.
+	VMs []VMStatus
+}
+
+// renderQueue renders the task queue length and the states of the Runners.
+func (monitor *Monitor) renderQueue() interface{} {
+	res := &queueJSON{VMs: monitor.health.snapshot()}
+	if monitor.queueLen != nil {
+		res.Len = monitor.queueLen()
+	}
+	if monitor.queueSaturation != nil {
+		res.Saturation = monitor.queueSaturation()
+	}
+	if monitor.paused != nil {
+		res.Paused = monitor.paused()
+	}
+	return res
+}
+
+// httpCallsCSV renders the per-call statistics in the CSV format.
+func (monitor *Monitor) httpCallsCSV(w http.ResponseWriter, r *http.Request) {
+	if monitor.externalStats == nil {
+		http.Error(w, "no stats", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename=calls.csv")
+	if err := monitor.externalStats.WriteCSV(w); err != nil {
+		log.Logf(0, "failed to write calls.csv: %v", err)
+	}
 }
 
 // statsJSON provides information for the "/api/stats.json" render.
@@ -50,11 +240,23 @@ type statsJSON struct {
 	FlakyProgs          int64
 	MismatchingProgs    int64
 	AverExecSpeed       int64
+	DispatchedTasks     int64
+	StarvedTasks        int64
+	AverTaskWait        time.Duration
+	MaxTaskWait         time.Duration
 }
 
 // handleStats renders the statsJSON object.
 func (monitor *Monitor) renderStats() interface{} {
 	stats := monitor.externalStats
+	stats.mu.Lock()
+	dispatched, starved, totalWait, maxWait := stats.DispatchedTasks, stats.StarvedTasks,
+		stats.TotalTaskWait, stats.MaxTaskWait
+	stats.mu.Unlock()
+	var averWait time.Duration
+	if dispatched != 0 {
+		averWait = totalWait / time.Duration(dispatched)
+	}
 	return &statsJSON{
 		StartTime:           stats.StartTime,
 		TotalCallMismatches: stats.TotalCallMismatches,
@@ -63,25 +265,120 @@ func (monitor *Monitor) renderStats() interface{} {
 		FlakyProgs:          stats.FlakyProgs,
 		MismatchingProgs:    stats.MismatchingProgs,
 		AverExecSpeed:       60 * stats.TotalProgs / int64(1+time.Since(stats.StartTime).Seconds()),
+		DispatchedTasks:     dispatched,
+		StarvedTasks:        starved,
+		AverTaskWait:        averWait,
+		MaxTaskWait:         maxWait,
 	}
 }
 
+// renderExport renders the full structured statistics (the same as written with -stats-json).
+func (monitor *Monitor) renderExport() interface{} {
+	stats := monitor.externalStats
+	return stats.GetJSON(time.Since(stats.StartTime).Minutes())
+}
+
+// callStatsJSON provides per-syscall information for the "/api/calls.json" render.
+type callStatsJSON struct {
+	Name        string
+	Mismatches  int64
+	Occurrences int64
+	States      []string
+}
+
+// renderCalls renders statistics of all executed calls in decreasing order of the mismatch rate.
+func (monitor *Monitor) renderCalls() interface{} {
+	res := []*callStatsJSON{}
+	for _, cs := range monitor.externalStats.callsSnapshot() {
+		call := &callStatsJSON{
+			Name:        cs.Name,
+			Mismatches:  cs.Mismatches,
+			Occurrences: cs.Occurrences,
+		}
+		for state := range cs.States {
+			call.States = append(call.States, state.String())
+		}
+		sort.Strings(call.States)
+		res = append(res, call)
+	}
+	return res
+}
+
+// mismatchJSON provides information for the "/api/mismatches.json" render.
+type mismatchJSON struct {
+	ID     string
+	Time   time.Time
+	Report string
+	Prog   string
+}
+
+// renderMismatches renders the saved mismatch reports, newest first.
+func (monitor *Monitor) renderMismatches() interface{} {
+	res := []*mismatchJSON{}
+	files, _ := ioutil.ReadDir(monitor.resultsdir)
+	for _, f := range files {
+		if !strings.HasPrefix(f.Name(), "result-") || filepath.Ext(f.Name()) != "" {
+			continue
+		}
+		file := filepath.Join(monitor.resultsdir, f.Name())
+		report, err := ioutil.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		// Reports written by older versions don't have the program file.
+		prog, err := ioutil.ReadFile(file + ".prog")
+		if err != nil && !os.IsNotExist(err) {
+			continue
+		}
+		res = append(res, &mismatchJSON{
+			ID:     f.Name(),
+			Time:   f.ModTime(),
+			Report: string(report),
+			Prog:   string(prog),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Time.After(res[j].Time)
+	})
+	return res
+}
+
+// metricsHandler serves the statistics in the Prometheus format.
+func (monitor *Monitor) metricsHandler() http.Handler {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(newMetricsCollector(monitor))
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
+
 // jsonResponse provides general response forming logic.
 func jsonResponse(getData func() interface{}) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-
-		data := getData()
-		json, err := json.MarshalIndent(
-			data,
-			"",
-			"\t",
-		)
-		if err != nil {
-			http.Error(w, err.Error(), 500) // Internal Server Error.
+		writeJSON(w, getData())
+	})
+}
+
+func writeJSON(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json, err := json.MarshalIndent(
+		data,
+		"",
+		"\t",
+	)
+	if err != nil {
+		http.Error(w, err.Error(), 500) // Internal Server Error.
+		return
+	}
+
+	w.Write(json)
+}
+
+// getOnly rejects requests with methods other than GET.
+func getOnly(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "use GET", http.StatusMethodNotAllowed)
 			return
 		}
-
-		w.Write(json)
+		h.ServeHTTP(w, r)
 	})
 }
@@ -0,0 +1,148 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/pkg/osutil"
+)
+
+func TestRenderCalls(t *testing.T) {
+	monitor := &Monitor{externalStats: dummyStats()}
+	got := monitor.renderCalls()
+	want := []*callStatsJSON{
+		{"bar", 5, 6, []string{"Crashed", "Flags: 7 (executed|finished|blocked), Errno: 10 ECHILD (no child processes)",
+			"Flags: 7 (executed|finished|blocked), Errno: 22 EINVAL (invalid argument)"}},
+		{"tar", 3, 4, []string{"Flags: 7 (executed|finished|blocked), Errno: 17 EEXIST (file exists)",
+			"Flags: 7 (executed|finished|blocked), Errno: 31 EMLINK (too many links)",
+			"Flags: 7 (executed|finished|blocked), Errno: 5 EIO (input/output error)"}},
+		{"foo", 2, 8, []string{"Flags: 7 (executed|finished|blocked), Errno: 1 EPERM (operation not permitted)",
+			"Flags: 7 (executed|finished|blocked), Errno: 3 ESRCH (no such process)"}},
+		{"biz", 0, 2, nil},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("calls mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRenderMismatches(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"result-0":      "report 0",
+		"result-0.prog": "prog 0",
+		"result-1":      "report 1",
+		"foo":           "bar",
+	}
+	for name, data := range files {
+		if err := osutil.WriteFile(filepath.Join(dir, name), []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	monitor := &Monitor{resultsdir: dir}
+	got := monitor.renderMismatches().([]*mismatchJSON)
+	if len(got) != 2 {
+		t.Fatalf("got %v mismatches, want 2", len(got))
+	}
+	byID := map[string]*mismatchJSON{got[0].ID: got[0], got[1].ID: got[1]}
+	if m := byID["result-0"]; m == nil || m.Report != "report 0" || m.Prog != "prog 0" {
+		t.Errorf("bad result-0: %+v", m)
+	}
+	if m := byID["result-1"]; m == nil || m.Report != "report 1" || m.Prog != "" {
+		t.Errorf("bad result-1: %+v", m)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	monitor := &Monitor{externalStats: dummyStats()}
+	monitor.SetQueueTracking(func() int { return 3 })
+	rec := httptest.NewRecorder()
+	monitor.metricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"syz_verifier_progs_total 24\n",
+		"syz_verifier_flaky_progs_total 4\n",
+		"syz_verifier_mismatching_progs_total 6\n",
+		"syz_verifier_call_mismatches_total 10\n",
+		"syz_verifier_task_queue_len 3\n",
+		`syz_verifier_syscall_mismatches_total{syscall="bar"} 5` + "\n",
+		`syz_verifier_syscall_occurrences_total{syscall="biz"} 2` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics don't contain %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestHTTPSummary(t *testing.T) {
+	dir := t.TempDir()
+	if err := osutil.WriteFile(filepath.Join(dir, "result-0"), []byte("mismatch report")); err != nil {
+		t.Fatal(err)
+	}
+	if err := osutil.WriteFile(filepath.Join(dir, "result-0.prog"), []byte("mismatching_prog()")); err != nil {
+		t.Fatal(err)
+	}
+	health := newVMHealth()
+	health.booting(1, 2)
+	monitor := &Monitor{externalStats: dummyStats(), resultsdir: dir, health: health}
+	rec := httptest.NewRecorder()
+	monitor.httpSummary(rec, httptest.NewRequest("GET", "/", nil))
+	body := rec.Body.String()
+	for _, want := range []string{"<td>bar</td>", "mismatch report", "mismatching_prog()", "booting"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("summary doesn't contain %q:\n%s", want, body)
+		}
+	}
+	// Calls without mismatches are not shown.
+	if strings.Contains(body, "<td>biz</td>") {
+		t.Errorf("summary contains a call without mismatches")
+	}
+}
+
+func TestQueryAPI(t *testing.T) {
+	health := newVMHealth()
+	health.booting(0, 1)
+	monitor := &Monitor{externalStats: dummyStats(), health: health}
+	monitor.SetQueueTracking(func() int { return 3 })
+	get := func(h http.Handler, method, url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, url, nil))
+		return rec
+	}
+	callHandler := getOnly(http.HandlerFunc(monitor.httpCall))
+
+	rec := get(callHandler, "GET", "/calls/bar")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/calls/bar: %v %v", rec.Code, rec.Body.String())
+	}
+	call := new(CallStatsJSON)
+	if err := json.Unmarshal(rec.Body.Bytes(), call); err != nil {
+		t.Fatal(err)
+	}
+	if call.Name != "bar" || call.Mismatches != 5 || call.Occurrences != 6 || len(call.States) != 3 ||
+		call.Outliers[1] != 4 {
+		t.Errorf("bad call stats: %+v", call)
+	}
+	if rec := get(callHandler, "GET", "/calls/read"); rec.Code != http.StatusNotFound {
+		t.Errorf("/calls/read: got %v, want %v", rec.Code, http.StatusNotFound)
+	}
+	if rec := get(callHandler, "POST", "/calls/bar"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /calls/bar: got %v, want %v", rec.Code, http.StatusMethodNotAllowed)
+	}
+
+	rec = get(getOnly(jsonResponse(monitor.renderQueue)), "GET", "/queue")
+	queue := new(queueJSON)
+	if err := json.Unmarshal(rec.Body.Bytes(), queue); err != nil {
+		t.Fatal(err)
+	}
+	if queue.Len != 3 || len(queue.VMs) != 1 || queue.VMs[0].VM != 1 || queue.VMs[0].Running {
+		t.Errorf("bad queue: %+v", queue)
+	}
+}
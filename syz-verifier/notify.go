@@ -0,0 +1,99 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/syzkaller/pkg/email"
+	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/prog"
+)
+
+// When a mismatch with a previously unseen signature (see signature.go) is found,
+// a JSON notification can be posted to a webhook (-notify-webhook)
+// and the mismatch report can be emailed (-notify-email).
+
+// MismatchNotification is posted to the webhook for every new unique mismatch.
+type MismatchNotification struct {
+	Time           time.Time
+	Signature      string
+	Prog           string
+	Runs           int
+	Divergent      int
+	Nondeterminism float64
+	// Calls contains return states of each call of the program on all kernels.
+	Calls []*VerdictCall
+}
+
+type notifier struct {
+	webhook string
+	// Email notifications are sent if sender is set.
+	sender *email.SMTPSender
+	to     []string
+}
+
+// notify sends notifications about the mismatch found in the program.
+// Can be called on a nil object.
+func (n *notifier) notify(v *Verdict, p *prog.Prog, pools int) {
+	if n == nil {
+		return
+	}
+	rr := CompareResults(v.Results, p)
+	rr.Verdict = v
+	if n.webhook != "" {
+		if err := n.postWebhook(makeMismatchNotification(rr)); err != nil {
+			log.Logf(0, "failed to notify about new mismatch: %v", err)
+		}
+	}
+	if n.sender != nil {
+		subject := fmt.Sprintf("syz-verifier: new mismatch in %v", firstMismatch(rr))
+		if err := n.sender.Send(n.to, subject, string(createReport(rr, pools))); err != nil {
+			log.Logf(0, "failed to notify about new mismatch: %v", err)
+		}
+	}
+}
+
+func makeMismatchNotification(rr *ResultReport) *MismatchNotification {
+	v := rr.Verdict
+	return &MismatchNotification{
+		Time:           time.Now(),
+		Signature:      v.Signature,
+		Prog:           rr.Prog,
+		Runs:           v.Runs,
+		Divergent:      v.Divergent,
+		Nondeterminism: v.Nondeterminism,
+		Calls:          makeVerdictCalls(rr.Reports),
+	}
+}
+
+func (n *notifier) postWebhook(msg *MismatchNotification) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(n.webhook, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%v: %v", n.webhook, resp.Status)
+	}
+	return nil
+}
+
+// firstMismatch returns the name of the first mismatching call of the program.
+func firstMismatch(rr *ResultReport) string {
+	for _, cr := range rr.Reports {
+		if cr.Mismatch {
+			return cr.Call
+		}
+	}
+	return "program"
+}
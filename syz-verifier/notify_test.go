@@ -0,0 +1,86 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNotifyWebhook(t *testing.T) {
+	var got []*MismatchNotification
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msg := new(MismatchNotification)
+		if err := json.NewDecoder(r.Body).Decode(msg); err != nil {
+			t.Errorf("bad notification: %v", err)
+		}
+		got = append(got, msg)
+	}))
+	defer srv.Close()
+
+	p := getTestProgram(t)
+	v := &Verdict{
+		Results: []*ExecResult{
+			makeExecResult(0, []int{1, 3, 2}),
+			makeExecResult(1, []int{1, 3, 5}),
+		},
+		Runs:      3,
+		Divergent: 3,
+		Mismatch:  true,
+		Signature: "test$res0: ...",
+	}
+	n := &notifier{webhook: srv.URL}
+	n.notify(v, p, 2)
+	// Nil notifier must not send anything.
+	(*notifier)(nil).notify(v, p, 2)
+
+	state := func(errno int, descr string) *ReturnStateJSON {
+		return &ReturnStateJSON{Errno: errno, Description: descr}
+	}
+	want := []*MismatchNotification{{
+		Signature: "test$res0: ...",
+		Prog:      string(p.Serialize()),
+		Runs:      3,
+		Divergent: 3,
+		Calls: []*VerdictCall{
+			{
+				Call: "breaks_returns",
+				States: []*ReturnStateJSON{
+					state(1, "Flags: 0 (not executed), Errno: 1 EPERM (operation not permitted)"),
+					state(1, "Flags: 0 (not executed), Errno: 1 EPERM (operation not permitted)"),
+				},
+			},
+			{
+				Call: "minimize$0",
+				States: []*ReturnStateJSON{
+					state(3, "Flags: 0 (not executed), Errno: 3 ESRCH (no such process)"),
+					state(3, "Flags: 0 (not executed), Errno: 3 ESRCH (no such process)"),
+				},
+			},
+			{
+				Call:     "test$res0",
+				Mismatch: true,
+				States: []*ReturnStateJSON{
+					state(2, "Flags: 0 (not executed), Errno: 2 ENOENT (no such file or directory)"),
+					state(5, "Flags: 0 (not executed), Errno: 5 EIO (input/output error)"),
+				},
+				Outliers: []int{1},
+			},
+		},
+	}}
+	for _, msg := range got {
+		msg.Time = time.Time{}
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("notifications (-want +got):\n%s", diff)
+	}
+	if call := firstMismatch(CompareResults(v.Results, p)); call != "test$res0" {
+		t.Errorf("first mismatching call: got %v, want test$res0", call)
+	}
+}
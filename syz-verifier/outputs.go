@@ -0,0 +1,88 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/syzkaller/prog"
+)
+
+// With -compare-outputs, the runners collect the outputs of the successful calls (see
+// ipc.FlagCollectOutputs): the return value followed by the values returned in memory, i.e.
+// resources, integers and the first bytes of buffers with out or inout direction
+// (see prog.Call.ExecOutputs). The outputs are compared in addition to errno, so that
+// calls that succeed on all kernels, but return different data are detected as well.
+// Resource values (e.g. file descriptors or ids) are allocated by each kernel in its own way,
+// so they are replaced with their index in the order of the first appearance in the program
+// and only the relations between the resources are compared.
+
+// normalizeOutputs replaces the resource values in the outputs of the calls with their indices.
+func normalizeOutputs(p *prog.Prog, results []*ExecResult) {
+	for _, res := range results {
+		indices := make(map[uint64]uint64)
+		for idx, call := range p.Calls {
+			if idx >= len(res.Info.Calls) {
+				break
+			}
+			outs := res.Info.Calls[idx].Outputs
+			resources := resourceOutputs(call)
+			if len(outs) != len(resources) {
+				continue
+			}
+			for i, v := range outs {
+				if !resources[i] {
+					continue
+				}
+				index, ok := indices[v]
+				if !ok {
+					index = uint64(len(indices))
+					indices[v] = index
+				}
+				outs[i] = index
+			}
+		}
+	}
+}
+
+// resourceOutputs says for each output of the call whether it is a resource.
+func resourceOutputs(call *prog.Call) []bool {
+	res := []bool{call.Ret != nil}
+	for _, out := range call.ExecOutputs() {
+		_, ok := out.Arg.(*prog.ResultArg)
+		res = append(res, ok)
+	}
+	return res
+}
+
+// formatOutputs returns the human-readable normalized outputs of the call,
+// resources are formatted as rN.
+func formatOutputs(call *prog.Call, outs []uint64) string {
+	if len(outs) == 0 {
+		return ""
+	}
+	resources := resourceOutputs(call)
+	res := make([]string, len(outs))
+	for i, v := range outs {
+		if len(outs) == len(resources) && resources[i] {
+			res[i] = fmt.Sprintf("r%v", v)
+		} else {
+			res[i] = fmt.Sprintf("0x%x", v)
+		}
+	}
+	return strings.Join(res, " ")
+}
+
+func outputsEqual(l, r []uint64) bool {
+	if len(l) != len(r) {
+		return false
+	}
+	for i := range l {
+		if l[i] != r[i] {
+			return false
+		}
+	}
+	return true
+}
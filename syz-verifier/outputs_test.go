@@ -0,0 +1,67 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/syzkaller/pkg/ipc"
+	"github.com/google/syzkaller/prog"
+)
+
+func TestCompareOutputs(t *testing.T) {
+	target := prog.InitTargetTest(t, "test", "64")
+	p, err := target.Deserialize([]byte("r0 = test$res0()\n"+
+		"test$res1(r0)\n"+
+		"foo$anyres(&(0x7f0000000000), &(0x7f0000000100))\n"), prog.Strict)
+	if err != nil {
+		t.Fatalf("failed to deserialise test program: %v", err)
+	}
+	makeResult := func(pool int, outputs ...[]uint64) *ExecResult {
+		r := &ExecResult{Pool: pool}
+		for _, outs := range outputs {
+			r.Info.Calls = append(r.Info.Calls, ipc.CallInfo{
+				Flags:   ipc.CallExecuted | ipc.CallFinished,
+				Outputs: outs,
+			})
+		}
+		return r
+	}
+	results := []*ExecResult{
+		makeResult(0, []uint64{5}, []uint64{0}, []uint64{0, 5, 7}),
+		// The same relations between the resources, but different values.
+		makeResult(1, []uint64{9}, []uint64{0}, []uint64{0, 9, 8}),
+		// Both resources of the last call are the same.
+		makeResult(2, []uint64{5}, []uint64{0}, []uint64{0, 3, 3}),
+	}
+	normalizeOutputs(p, results)
+	if !results[0].IsEqual(results[1]) {
+		t.Errorf("results with the same normalized outputs are not equal")
+	}
+	if results[0].IsEqual(results[2]) {
+		t.Errorf("results with different outputs are equal")
+	}
+
+	rr := CompareResults(results, p)
+	if !rr.Mismatch || rr.Reports[0].Mismatch || rr.Reports[1].Mismatch || !rr.Reports[2].Mismatch {
+		t.Fatalf("only the last call must mismatch")
+	}
+	want := map[int]string{
+		0: "Errno: 0 (success), Outputs: 0x0 r0 r1",
+		1: "Errno: 0 (success), Outputs: 0x0 r0 r1",
+		2: "Errno: 0 (success), Outputs: 0x0 r1 r1",
+	}
+	for pool, state := range rr.Reports[2].States {
+		if got := state.String(); !strings.HasSuffix(got, want[pool]) {
+			t.Errorf("pool %v: got state %q, want %q", pool, got, want[pool])
+		}
+	}
+	if got := rr.Reports[1].States[0].Outputs; got != "0x0" {
+		t.Errorf("got outputs %q of the call without resources, want \"0x0\"", got)
+	}
+	if got := rr.Reports[0].States[0].Outputs; got != "r0" {
+		t.Errorf("got outputs %q of the resource returning call, want \"r0\"", got)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/google/syzkaller/pkg/log"
+)
+
+// The verifier can be paused ("POST /api/pause") and resumed ("POST /api/resume"), e.g. when the
+// host needs resources or to snapshot the VMs. While the verifier is paused no new programs are
+// generated and no tasks are sent to the Runners, but the tasks that are already executed
+// are finished and their results are processed. Tasks created for the reruns of the programs
+// being verified wait in the queues until the verifier is resumed.
+
+// setPaused pauses or resumes the program generation and the dispatch of tasks.
+func (vrf *Verifier) setPaused(paused bool) {
+	vrf.tasksMutex.Lock()
+	defer vrf.tasksMutex.Unlock()
+	if vrf.paused == paused {
+		return
+	}
+	vrf.paused = paused
+	if paused {
+		log.Logf(0, "verifier paused")
+		return
+	}
+	log.Logf(0, "verifier resumed")
+	vrf.onResumed.Broadcast()
+}
+
+// isPaused returns true if the verifier is paused.
+func (vrf *Verifier) isPaused() bool {
+	vrf.tasksMutex.Lock()
+	defer vrf.tasksMutex.Unlock()
+	return vrf.paused
+}
+
+// waitResumed waits until the verifier is resumed if it is paused.
+func (vrf *Verifier) waitResumed() {
+	vrf.tasksMutex.Lock()
+	defer vrf.tasksMutex.Unlock()
+	for vrf.paused {
+		vrf.onResumed.Wait()
+	}
+}
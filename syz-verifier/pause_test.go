@@ -0,0 +1,49 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/syzkaller/pkg/rpctype"
+)
+
+func TestPause(t *testing.T) {
+	p := getTestProgram(t)
+	vrf := &Verifier{stats: MakeStats()}
+	makeTestQueues(vrf, 1)
+	vrf.setPaused(true)
+	if !vrf.isPaused() {
+		t.Fatalf("verifier is not paused")
+	}
+
+	task := MakeExecTask(p)
+	defer DeleteExecTask(task)
+	vrf.tasksMutex.Lock()
+	vrf.pushTask(0, vrf.kernelEnvTasks[0][NewEnvironment], task)
+	vrf.tasksMutex.Unlock()
+	dispatched := make(chan *rpctype.ExecTask)
+	go func() {
+		dispatched <- vrf.GetRunnerTask(0, NewEnvironment)
+	}()
+	resumed := make(chan bool)
+	go func() {
+		vrf.waitResumed()
+		close(resumed)
+	}()
+	select {
+	case <-dispatched:
+		t.Fatalf("task was dispatched while the verifier is paused")
+	case <-resumed:
+		t.Fatalf("program generation was not paused")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	vrf.setPaused(false)
+	if got := <-dispatched; got.ID != task.ID {
+		t.Errorf("got task %v, want %v", got.ID, task.ID)
+	}
+	<-resumed
+}
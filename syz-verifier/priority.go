@@ -0,0 +1,63 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"sync/atomic"
+
+	"github.com/google/syzkaller/prog"
+)
+
+// With -prioritize, tasks of programs that are more likely to diverge are dispatched first:
+// programs containing system calls executed much less often than the average one, and
+// programs containing system calls that mismatched often in the previous programs.
+// A task with priority p overtakes the tasks with lower priority that arrived less than
+// p*taskPriorityAging before it (see ExecTask.agedPriority).
+
+const (
+	// maxRarityPriority is the maximal priority for rarely executed calls. A call
+	// executed 2^n times less often than the average one gets priority n.
+	maxRarityPriority = 3
+	// maxMismatchPriority is the priority for calls that always mismatch.
+	maxMismatchPriority = 3
+	// mismatchRatePrior is the number of occurrences added to the occurrences of a call
+	// when computing its mismatch rate, so that a few mismatches of a new call don't
+	// give it the maximal priority.
+	mismatchRatePrior = 10
+)
+
+// progPriority returns the priority of the tasks executing the program.
+func (vrf *Verifier) progPriority(p *prog.Prog) int {
+	if !vrf.prioritize {
+		return 0
+	}
+	return vrf.stats.progPriority(p)
+}
+
+// progPriority returns the priority of the program based on the statistics of its calls.
+func (stats *Stats) progPriority(p *prog.Prog) int {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	var total int64
+	for _, cs := range stats.Calls {
+		total += atomic.LoadInt64(&cs.Occurrences)
+	}
+	if total == 0 {
+		return 0
+	}
+	mean := float64(total) / float64(len(stats.Calls))
+	rarity, mismatchRate := 0.0, 0.0
+	for _, c := range p.Calls {
+		cs := stats.Calls[c.Meta.Name]
+		if cs == nil {
+			continue
+		}
+		occurrences := atomic.LoadInt64(&cs.Occurrences)
+		mismatches := atomic.LoadInt64(&cs.Mismatches)
+		rarity = math.Max(rarity, math.Log2(mean/float64(occurrences+1)))
+		mismatchRate = math.Max(mismatchRate, float64(mismatches)/float64(occurrences+mismatchRatePrior))
+	}
+	return int(math.Min(rarity, maxRarityPriority)) + int(math.Round(mismatchRate*maxMismatchPriority))
+}
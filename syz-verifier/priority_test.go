@@ -0,0 +1,51 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/syzkaller/prog"
+)
+
+func TestProgPriority(t *testing.T) {
+	target := prog.InitTargetTest(t, "test", "64")
+	vrf := &Verifier{stats: emptyTestStats(), prioritize: true}
+	p, err := target.Deserialize([]byte("test$res0()\n"), prog.Strict)
+	if err != nil {
+		t.Fatalf("failed to deserialise test program: %v", err)
+	}
+	if got := vrf.progPriority(p); got != 0 {
+		t.Errorf("no statistics: got priority %v, want 0", got)
+	}
+
+	vrf.stats.Calls["breaks_returns"] = makeCallStats("breaks_returns", 1000, 0, nil)
+	vrf.stats.Calls["minimize$0"] = makeCallStats("minimize$0", 1000, 505, nil)
+	vrf.stats.Calls["test$res0"] = makeCallStats("test$res0", 10, 0, nil)
+	tests := []struct {
+		prog string
+		want int
+	}{
+		{"breaks_returns()\n", 0},
+		// Mismatch rate 505 / (1000 + mismatchRatePrior) = 0.5.
+		{"minimize$0(0x1, 0x1)\n", 2},
+		// 670 occurrences on average, 2^5 times more than test$res0.
+		{"test$res0()\n", maxRarityPriority},
+		{"breaks_returns()\nminimize$0(0x1, 0x1)\ntest$res0()\n", maxRarityPriority + 2},
+	}
+	for _, test := range tests {
+		p, err := target.Deserialize([]byte(test.prog), prog.Strict)
+		if err != nil {
+			t.Fatalf("failed to deserialise test program: %v", err)
+		}
+		if got := vrf.progPriority(p); got != test.want {
+			t.Errorf("%q: got priority %v, want %v", test.prog, got, test.want)
+		}
+	}
+
+	vrf.prioritize = false
+	if got := vrf.progPriority(p); got != 0 {
+		t.Errorf("prioritization disabled: got priority %v, want 0", got)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/google/syzkaller/pkg/hash"
+	"github.com/google/syzkaller/prog"
+)
+
+// With the -resume flag the hash of every verified program is appended to workdir/verified.log
+// as soon as its verification completes, followed by the mismatch signature for mismatching
+// programs. The checkpoint is saved only periodically and contains programs that were generated
+// but not verified yet, so after a restart the log is used instead to skip programs that were
+// already verified and to not report again mismatches with known signatures.
+
+// progressLog is the log of verified programs. All methods can be called on a nil object.
+type progressLog struct {
+	mu         sync.Mutex
+	file       *os.File
+	verified   map[string]bool
+	signatures map[string]bool
+}
+
+// openProgressLog reads the verified programs from the log file and opens it for appending.
+func openProgressLog(file string) (*progressLog, error) {
+	pl := &progressLog{
+		verified:   make(map[string]bool),
+		signatures: make(map[string]bool),
+	}
+	if err := pl.read(file); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open progress log: %v", err)
+	}
+	pl.file = f
+	return pl, nil
+}
+
+func (pl *progressLog) read(file string) error {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open progress log: %v", err)
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		// The last line may be truncated if the verifier was killed while writing it,
+		// then the program is simply verified again.
+		h, sig, _ := cut(s.Text(), " ")
+		if len(h) != len(hash.String(nil)) {
+			continue
+		}
+		pl.verified[h] = true
+		if sig != "" {
+			pl.signatures[sig] = true
+		}
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("failed to read progress log %v: %v", file, err)
+	}
+	return nil
+}
+
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// hashes returns the hashes of all verified programs.
+func (pl *progressLog) hashes() []string {
+	if pl == nil {
+		return nil
+	}
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	res := make([]string, 0, len(pl.verified))
+	for h := range pl.verified {
+		res = append(res, h)
+	}
+	return res
+}
+
+// done returns true if the program was already verified.
+func (pl *progressLog) done(p *prog.Prog) bool {
+	if pl == nil {
+		return false
+	}
+	h := hash.String(p.Serialize())
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	return pl.verified[h]
+}
+
+// knownSignature returns true if a mismatch with the signature was found before the restart.
+func (pl *progressLog) knownSignature(sig string) bool {
+	if pl == nil {
+		return false
+	}
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	return pl.signatures[sig]
+}
+
+// add appends the verified program and the mismatch signature, if any, to the log.
+func (pl *progressLog) add(p *prog.Prog, v *Verdict) error {
+	if pl == nil {
+		return nil
+	}
+	h := hash.String(p.Serialize())
+	line := h
+	if v.Mismatch && v.Signature != "" {
+		line += " " + strings.ReplaceAll(v.Signature, "\n", " ")
+	}
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.verified[h] = true
+	_, err := pl.file.WriteString(line + "\n")
+	return err
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/syzkaller/prog"
+)
+
+func TestProgressLog(t *testing.T) {
+	target := prog.InitTargetTest(t, "test", "64")
+	deserialize := func(p string) *prog.Prog {
+		res, err := target.Deserialize([]byte(p), prog.Strict)
+		if err != nil {
+			t.Fatalf("failed to deserialise test program: %v", err)
+		}
+		return res
+	}
+	p0 := deserialize("breaks_returns()\n")
+	p1 := deserialize("minimize$0(0x1, 0x1)\n")
+	p2 := deserialize("test$res0()\n")
+	const sig = "minimize$0: Flags: 1, Errno: 0 (success) | Flags: 1, Errno: 1 (operation not permitted)"
+
+	file := filepath.Join(t.TempDir(), "verified.log")
+	pl, err := openProgressLog(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pl.add(p0, &Verdict{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := pl.add(p1, &Verdict{Mismatch: true, Signature: sig}); err != nil {
+		t.Fatal(err)
+	}
+	if !pl.done(p0) || !pl.done(p1) || pl.done(p2) {
+		t.Errorf("wrong verified programs before restart")
+	}
+	// A line truncated by a crash is ignored.
+	if _, err := pl.file.WriteString("0123456789"); err != nil {
+		t.Fatal(err)
+	}
+	pl.file.Close()
+
+	pl, err = openProgressLog(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pl.file.Close()
+	if got := len(pl.hashes()); got != 2 {
+		t.Errorf("restored %v hashes, want 2", got)
+	}
+	if !pl.done(p0) || !pl.done(p1) || pl.done(p2) {
+		t.Errorf("wrong verified programs after restart")
+	}
+	if !pl.knownSignature(sig) || pl.knownSignature("other") {
+		t.Errorf("wrong known signatures after restart")
+	}
+
+	// Programs of the restored tasks that were verified after the checkpoint are dropped.
+	vrf := &Verifier{
+		target:   target,
+		progress: pl,
+		calls: map[*prog.Syscall]bool{
+			target.SyscallMap["breaks_returns"]: true,
+			target.SyscallMap["minimize$0"]:     true,
+			target.SyscallMap["test$res0"]:      true,
+		},
+	}
+	vrf.restoreBacklog([]*QueuedTask{
+		{Prog: string(p0.Serialize())},
+		{Prog: string(p2.Serialize())},
+	})
+	if p := vrf.popBacklog(); p == nil || string(p.Serialize()) != string(p2.Serialize()) {
+		t.Errorf("wrong restored program: %v", p)
+	}
+	if p := vrf.popBacklog(); p != nil {
+		t.Errorf("verified program was restored: %s", p.Serialize())
+	}
+
+	var nilLog *progressLog
+	if nilLog.done(p0) || nilLog.knownSignature(sig) || nilLog.add(p0, &Verdict{}) != nil {
+		t.Errorf("nil progress log is not empty")
+	}
+}
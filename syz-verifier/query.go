@@ -0,0 +1,264 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/syzkaller/pkg/osutil"
+)
+
+// Queries select outcomes from the results database. A query consists of clauses joined
+// with "and"/"or" ("and" binds tighter), every clause can be negated with "not". Supported clauses:
+//
+//	verdict KIND             - the verdict is one of mismatch, flaky, suppressed, match or error
+//	contains call NAME       - the program contains a call matching NAME (a glob, e.g. "bpf$*")
+//	mismatch call NAME       - return states of a call matching NAME differ on the kernels
+//	program HASH             - the hash of the program starts with HASH (all verdicts for a program)
+//	since DATE               - the verdict was computed at or after DATE
+//	before DATE              - the verdict was computed before DATE
+//
+// DATE is either YYYY-MM-DD, YYYY-MM-DDTHH:MM, today, yesterday or a weekday name (e.g. monday)
+// which denotes the start of the last such day in local time.
+// For example: "mismatch call bpf$PROG_LOAD and since monday".
+// Queries are translated to an SQL condition on the outcomes table for the SQLite store,
+// the pkg/db store matches them against every outcome.
+type outcomeQuery [][]*outcomeClause // disjunction of conjunctions
+
+type outcomeClause struct {
+	negate bool
+	cond   string
+	args   []interface{}
+	match  func(o *Outcome) bool
+}
+
+func parseOutcomeQuery(str string, now time.Time) (outcomeQuery, error) {
+	var q outcomeQuery
+	for _, disjunct := range splitQuery(strings.Fields(str), "or") {
+		var conj []*outcomeClause
+		for _, words := range splitQuery(disjunct, "and") {
+			clause, err := parseOutcomeClause(words, now)
+			if err != nil {
+				return nil, err
+			}
+			conj = append(conj, clause)
+		}
+		q = append(q, conj)
+	}
+	return q, nil
+}
+
+func splitQuery(words []string, sep string) [][]string {
+	res := [][]string{nil}
+	for _, word := range words {
+		if word == sep {
+			res = append(res, nil)
+			continue
+		}
+		res[len(res)-1] = append(res[len(res)-1], word)
+	}
+	return res
+}
+
+func parseOutcomeClause(words []string, now time.Time) (*outcomeClause, error) {
+	clause := new(outcomeClause)
+	if len(words) != 0 && words[0] == "not" {
+		clause.negate = true
+		words = words[1:]
+	}
+	if len(words) < 2 {
+		return nil, fmt.Errorf("bad query clause %q", strings.Join(words, " "))
+	}
+	switch what := words[0]; what {
+	case "verdict":
+		if len(words) != 2 {
+			return nil, fmt.Errorf("%v: want one verdict", what)
+		}
+		kind := words[1]
+		switch kind {
+		case "mismatch", "flaky", "suppressed", "match", "error":
+		default:
+			return nil, fmt.Errorf("%v: unknown verdict %q", what, kind)
+		}
+		clause.cond = "o.kind = ?"
+		clause.args = []interface{}{kind}
+		clause.match = func(o *Outcome) bool {
+			return o.Kind() == kind
+		}
+	case "contains", "mismatch":
+		if len(words) != 3 || words[1] != "call" {
+			return nil, fmt.Errorf("%v call: want one call name", what)
+		}
+		pattern := words[2]
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%v call: bad pattern %q: %v", what, pattern, err)
+		}
+		clause.cond = "o.id IN (SELECT outcome FROM calls WHERE call GLOB ?)"
+		if what == "mismatch" {
+			clause.cond = "o.id IN (SELECT outcome FROM calls WHERE call GLOB ? AND mismatch)"
+		}
+		clause.args = []interface{}{pattern}
+		onlyMismatches := what == "mismatch"
+		clause.match = func(o *Outcome) bool {
+			for _, cr := range o.Calls {
+				if onlyMismatches && !cr.Mismatch {
+					continue
+				}
+				if ok, _ := filepath.Match(pattern, cr.Call); ok {
+					return true
+				}
+			}
+			return false
+		}
+	case "program":
+		if len(words) != 2 {
+			return nil, fmt.Errorf("%v: want one hash", what)
+		}
+		// Hashes are lowercase hex, checking it also guarantees that the prefix has no GLOB metacharacters.
+		prefix := strings.ToLower(words[1])
+		if strings.Trim(prefix, "0123456789abcdef") != "" {
+			return nil, fmt.Errorf("%v: bad hash %q", what, words[1])
+		}
+		// GLOB with a constant prefix uses the index on hash.
+		clause.cond = "o.hash GLOB ?"
+		clause.args = []interface{}{prefix + "*"}
+		clause.match = func(o *Outcome) bool {
+			return strings.HasPrefix(o.Hash, prefix)
+		}
+	case "since", "before":
+		if len(words) != 2 {
+			return nil, fmt.Errorf("%v: want one date", what)
+		}
+		date, err := parseQueryDate(words[1], now)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", what, err)
+		}
+		clause.cond = "o.finished >= ?"
+		if what == "before" {
+			clause.cond = "o.finished < ?"
+		}
+		clause.args = []interface{}{date.UnixNano()}
+		before := what == "before"
+		clause.match = func(o *Outcome) bool {
+			return o.Finished.Before(date) == before
+		}
+	default:
+		return nil, fmt.Errorf("unknown query clause %q", strings.Join(words, " "))
+	}
+	return clause, nil
+}
+
+func parseQueryDate(str string, now time.Time) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, str, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch str = strings.ToLower(str); str {
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if str == strings.ToLower(day.String()) {
+			return today.AddDate(0, 0, -((int(today.Weekday()) - int(day) + 7) % 7)), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("bad date %q", str)
+}
+
+func (q outcomeQuery) match(o *Outcome) bool {
+	for _, conj := range q {
+		matched := true
+		for _, clause := range conj {
+			if clause.match(o) == clause.negate {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// where returns the SQL condition and its arguments.
+func (q outcomeQuery) where() (string, []interface{}) {
+	var disj []string
+	var args []interface{}
+	for _, conj := range q {
+		var conds []string
+		for _, clause := range conj {
+			cond := "(" + clause.cond + ")"
+			if clause.negate {
+				cond = "NOT " + cond
+			}
+			conds = append(conds, cond)
+			args = append(args, clause.args...)
+		}
+		disj = append(disj, "("+strings.Join(conds, " AND ")+")")
+	}
+	return strings.Join(disj, " OR "), args
+}
+
+// runQuery prints outcomes from the results database that match the query.
+func runQuery(w io.Writer, file, queryStr string) error {
+	q, err := parseOutcomeQuery(queryStr, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to parse query: %v", err)
+	}
+	if !osutil.IsExist(file) {
+		return fmt.Errorf("results database %v does not exist", file)
+	}
+	st, err := openOutcomeStore(file)
+	if err != nil {
+		return err
+	}
+	defer st.close()
+	total, err := st.count()
+	if err != nil {
+		return err
+	}
+	matched := 0
+	err = st.selectOutcomes(q, func(o *Outcome) error {
+		matched++
+		printOutcome(w, o)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%v/%v outcomes matched the query\n", matched, total)
+	return nil
+}
+
+func printOutcome(w io.Writer, o *Outcome) {
+	fmt.Fprintf(w, "%v %v %v", o.Finished.Format("2006-01-02 15:04:05"), o.Hash, o.Kind())
+	if v := o.Verdict; v != nil && v.Divergent != 0 {
+		fmt.Fprintf(w, ", diverged in %v of %v runs", v.Divergent, v.Runs)
+	}
+	fmt.Fprintf(w, "\n")
+	calls := strings.Split(strings.TrimSuffix(o.Prog, "\n"), "\n")
+	for idx, call := range calls {
+		tick := "[=]"
+		if idx < len(o.Calls) && o.Calls[idx].Mismatch {
+			tick = "[!]"
+		}
+		fmt.Fprintf(w, "\t%v %v\n", tick, call)
+		if idx >= len(o.Calls) || !o.Calls[idx].Mismatch {
+			continue
+		}
+		for pool := 0; pool < len(o.Calls[idx].States); pool++ {
+			fmt.Fprintf(w, "\t\t↳ Pool: %d, %s\n", pool, o.Calls[idx].States[pool])
+		}
+	}
+	fmt.Fprintf(w, "\n")
+}
@@ -0,0 +1,26 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseOutcomeQueryErrors(t *testing.T) {
+	for _, query := range []string{
+		"verdict good",
+		"contains test$res0",
+		"since the beginning",
+		"since funday",
+		"mismatch call [",
+		"program",
+		"program 0123*",
+		"program xyz",
+	} {
+		if _, err := parseOutcomeQuery(query, time.Now()); err == nil {
+			t.Errorf("query %q parsed successfully", query)
+		}
+	}
+}
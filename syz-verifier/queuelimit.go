@@ -0,0 +1,82 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// With -queue-capacity, the number of tasks waiting in each queue (of a kernel and an environment)
+// is limited, so that the queues don't grow without limit if the Runners fall behind the program
+// generation. When a queue is full, depending on -queue-policy, the task either waits until a task
+// is dispatched from the queue (which blocks the generation of new programs), or the task with
+// the lowest priority in the queue (possibly the new one) is dropped and fails with an error.
+
+const (
+	queuePolicyBlock      = "block"
+	queuePolicyDropLowest = "drop-lowest"
+)
+
+var errTaskDropped = errors.New("task dropped from the full queue")
+
+func checkQueuePolicy(policy string) error {
+	if policy != queuePolicyBlock && policy != queuePolicyDropLowest {
+		return fmt.Errorf("unknown queue policy %q, expected %v or %v",
+			policy, queuePolicyBlock, queuePolicyDropLowest)
+	}
+	return nil
+}
+
+// pushTask pushes the task to the queue of the kernel respecting the queue capacity.
+// Must be called with vrf.tasksMutex held.
+func (vrf *Verifier) pushTask(kernel int, q *ExecTaskQueue, task *ExecTask) {
+	if vrf.queueCapacity > 0 && q.Len() >= vrf.queueCapacity {
+		if vrf.queuePolicy == queuePolicyDropLowest {
+			if lowest := q.lowest(); lowest.agedPriority() < task.agedPriority() {
+				q.remove(lowest)
+				vrf.dropTask(kernel, lowest)
+			} else {
+				vrf.dropTask(kernel, task)
+				return
+			}
+		} else {
+			atomic.AddInt64(&vrf.stats.QueueBlockedTasks, 1)
+			for q.Len() >= vrf.queueCapacity {
+				vrf.onTaskRemoved.Wait()
+			}
+		}
+	}
+	q.PushTask(task)
+	vrf.onTaskAdded.Signal()
+}
+
+func (vrf *Verifier) dropTask(kernel int, task *ExecTask) {
+	atomic.AddInt64(&vrf.stats.QueueDroppedTasks, 1)
+	PutExecResult(&ExecResult{
+		Pool:       kernel,
+		ExecTaskID: task.ID,
+		Error:      errTaskDropped,
+	})
+}
+
+// queueSaturation returns the ratio of the number of tasks in the fullest queue to the queue capacity,
+// or 0 if the capacity is not limited.
+func (vrf *Verifier) queueSaturation() float64 {
+	if vrf.queueCapacity == 0 {
+		return 0
+	}
+	vrf.tasksMutex.Lock()
+	defer vrf.tasksMutex.Unlock()
+	max := 0
+	for _, queues := range vrf.kernelEnvTasks {
+		for _, q := range queues {
+			if q.Len() > max {
+				max = q.Len()
+			}
+		}
+	}
+	return float64(max) / float64(vrf.queueCapacity)
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueueDropLowest(t *testing.T) {
+	p := getTestProgram(t)
+	vrf := &Verifier{stats: MakeStats(), queueCapacity: 1, queuePolicy: queuePolicyDropLowest}
+	makeTestQueues(vrf, 1)
+	q := vrf.kernelEnvTasks[0][NewEnvironment]
+	push := func(priority int) *ExecTask {
+		task := MakeExecTask(p)
+		task.priority = priority
+		vrf.tasksMutex.Lock()
+		vrf.pushTask(0, q, task)
+		vrf.tasksMutex.Unlock()
+		return task
+	}
+	dropped := func(task *ExecTask) bool {
+		select {
+		case res := <-task.ExecResultChan:
+			return res.Error == errTaskDropped
+		default:
+			return false
+		}
+	}
+
+	low := push(0)
+	defer DeleteExecTask(low)
+	if got := vrf.queueSaturation(); got != 1 {
+		t.Errorf("got queue saturation %v, want 1", got)
+	}
+	high := push(1)
+	defer DeleteExecTask(high)
+	if !dropped(low) || dropped(high) {
+		t.Errorf("the task with the lower priority was not dropped")
+	}
+	lower := push(0)
+	defer DeleteExecTask(lower)
+	if !dropped(lower) || dropped(high) {
+		t.Errorf("the new task with the lower priority was not dropped")
+	}
+	if task := vrf.GetRunnerTask(0, NewEnvironment); task.ID != high.ID {
+		t.Errorf("got task %v, want %v", task.ID, high.ID)
+	}
+	if vrf.stats.QueueDroppedTasks != 2 {
+		t.Errorf("got %v dropped tasks, want 2", vrf.stats.QueueDroppedTasks)
+	}
+}
+
+func TestQueueBlock(t *testing.T) {
+	p := getTestProgram(t)
+	vrf := &Verifier{stats: MakeStats(), queueCapacity: 1, queuePolicy: queuePolicyBlock}
+	makeTestQueues(vrf, 1)
+	q := vrf.kernelEnvTasks[0][NewEnvironment]
+	first, second := MakeExecTask(p), MakeExecTask(p)
+	defer DeleteExecTask(first)
+	defer DeleteExecTask(second)
+
+	vrf.tasksMutex.Lock()
+	vrf.pushTask(0, q, first)
+	vrf.tasksMutex.Unlock()
+	pushed := make(chan bool)
+	go func() {
+		vrf.tasksMutex.Lock()
+		vrf.pushTask(0, q, second)
+		vrf.tasksMutex.Unlock()
+		close(pushed)
+	}()
+	for atomic.LoadInt64(&vrf.stats.QueueBlockedTasks) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-pushed:
+		t.Fatalf("the task was pushed to the full queue")
+	default:
+	}
+	if task := vrf.GetRunnerTask(0, NewEnvironment); task.ID != first.ID {
+		t.Errorf("got task %v, want %v", task.ID, first.ID)
+	}
+	<-pushed
+	if task := vrf.GetRunnerTask(0, NewEnvironment); task.ID != second.ID {
+		t.Errorf("got task %v, want %v", task.ID, second.ID)
+	}
+}
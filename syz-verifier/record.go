@@ -0,0 +1,111 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/prog"
+)
+
+// In the record mode (-record flag) every tested program is appended to the record log
+// together with raw results of all its executions. In the replay mode (-replay flag)
+// the verdicts are re-computed from the log without starting any VMs, which allows
+// to iterate on the verdict logic against previously captured campaigns.
+// The log is a sequence of JSON-encoded Record objects, one per line.
+
+// Record is a single entry of the record log.
+type Record struct {
+	Prog  string
+	Steps []*RecordStep
+	// Verdict of the original run, nil if the program could not be executed.
+	Verdict *Verdict `json:",omitempty"`
+}
+
+// RecordStep is a single execution of the program on all kernels.
+type RecordStep struct {
+	Env     EnvDescr
+	Results []*ExecResult `json:",omitempty"`
+	Error   string        `json:",omitempty"`
+}
+
+func (rec *Record) addStep(env EnvDescr, results []*ExecResult, err error) {
+	step := &RecordStep{Env: env, Results: results}
+	if err != nil {
+		step.Error = err.Error()
+	}
+	rec.Steps = append(rec.Steps, step)
+}
+
+type recorder struct {
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+func newRecorder(file string) (*recorder, error) {
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open record log: %v", err)
+	}
+	return &recorder{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (r *recorder) write(rec *Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Encoder writes each record with a single write call, so the log stays consistent
+	// even if the process is killed in the middle of a campaign.
+	return r.enc.Encode(rec)
+}
+
+// Replay re-computes verdicts for all programs in the record log.
+// The mismatches are saved to the results dir as during the original run.
+func (vrf *Verifier) Replay(r io.Reader) error {
+	missing := 0
+	dec := json.NewDecoder(r)
+	for n := 1; ; n++ {
+		rec := new(Record)
+		if err := dec.Decode(rec); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return fmt.Errorf("failed to parse record %v: %v", n, err)
+		}
+		p, err := vrf.target.Deserialize([]byte(rec.Prog), prog.NonStrict)
+		if err != nil {
+			return fmt.Errorf("failed to deserialize program in record %v: %v", n, err)
+		}
+		steps := rec.Steps
+		v := vrf.testProgram(p, func(p *prog.Prog, env EnvDescr) ([]*ExecResult, error) {
+			if len(steps) == 0 {
+				// The verdict logic wants more executions than the original run did.
+				missing++
+				return nil, errors.New("no more recorded executions")
+			}
+			step := steps[0]
+			steps = steps[1:]
+			if step.Env != env {
+				log.Logf(1, "record %v: replaying %v environment results for %v", n, step.Env, env)
+			}
+			if step.Error != "" {
+				return nil, errors.New(step.Error)
+			}
+			return step.Results, nil
+		})
+		if v != nil && v.Mismatch && v.NewSignature {
+			vrf.SaveDiffResults(v, p)
+		}
+	}
+	if missing != 0 {
+		log.Logf(0, "%v programs needed more executions than recorded, counted as exec errors", missing)
+	}
+	return nil
+}
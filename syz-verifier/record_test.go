@@ -0,0 +1,96 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/prog"
+)
+
+func TestRecordReplay(t *testing.T) {
+	p := getTestProgram(t)
+	logFile := filepath.Join(t.TempDir(), "record.log")
+	rec, err := newRecorder(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// What the original campaign observed for 3 runs of the same program:
+	// a confirmed mismatch, a flaky mismatch and an execution error.
+	runs := [][]struct {
+		res []*ExecResult
+		err error
+	}{
+		{
+			{res: []*ExecResult{makeExecResult(0, []int{1, 3, 2}), makeExecResult(1, []int{1, 3, 5})}},
+			{res: []*ExecResult{makeExecResult(0, []int{1, 3, 2}), makeExecResult(1, []int{1, 3, 5})}},
+		},
+		{
+			{res: []*ExecResult{makeExecResult(0, []int{1, 3, 2}), makeExecResult(1, []int{1, 4, 2})}},
+			{res: []*ExecResult{makeExecResult(0, []int{1, 3, 2}), makeExecResult(1, []int{1, 3, 2})}},
+		},
+		{
+			{err: errors.New("vm died")},
+		},
+	}
+	// Two divergent runs out of two are enough to confirm a mismatch with these parameters.
+	orig := &Verifier{
+		stats:             emptyTestStats(),
+		pools:             map[int]*poolInfo{0: {}, 1: {}},
+		reruns:            1,
+		flakyRate:         0.5,
+		mismatchThreshold: 0.3,
+	}
+	for _, run := range runs {
+		r := &Record{Prog: string(p.Serialize())}
+		orig.testProgram(p, func(p *prog.Prog, env EnvDescr) ([]*ExecResult, error) {
+			step := run[len(r.Steps)]
+			r.addStep(env, step.res, step.err)
+			return step.res, step.err
+		})
+		if err := rec.write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	vrf := &Verifier{
+		target:            p.Target,
+		resultsdir:        makeTestResultDirectory(t),
+		stats:             emptyTestStats(),
+		pools:             orig.pools,
+		reruns:            orig.reruns,
+		flakyRate:         orig.flakyRate,
+		mismatchThreshold: orig.mismatchThreshold,
+	}
+	if err := vrf.Replay(f); err != nil {
+		t.Fatal(err)
+	}
+	want, got := orig.stats, vrf.stats
+	if got.TotalProgs != 3 || got.MismatchingProgs != 1 || got.FlakyProgs != 1 || got.ExecErrorProgs != 1 {
+		t.Fatalf("bad replay stats: %+v", got)
+	}
+	for name, cs := range want.Calls {
+		if got := got.Calls[name]; got.Occurrences != cs.Occurrences || got.Mismatches != cs.Mismatches ||
+			len(got.States) != len(cs.States) {
+			t.Errorf("call %v: got %+v, want %+v", name, got, cs)
+		}
+	}
+	for _, file := range []string{"result-0", "result-0.prog"} {
+		if !osutil.IsExist(filepath.Join(vrf.resultsdir, file)) {
+			t.Errorf("%v is not written", file)
+		}
+	}
+	if osutil.IsExist(filepath.Join(vrf.resultsdir, "result-1")) {
+		t.Errorf("flaky program is reported as a mismatch")
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/syzkaller/pkg/log"
+)
+
+// The kernels listed in -remote-pools don't have local VMs, their VMs run on other machines
+// managed by syz-verifier -agent (see agent.go). An agent registers its host with RegisterHost
+// and gets the indices for the VMs of the host. The VM indices stay bound to the host name:
+// a host that registers again (e.g. after a network outage or an agent restart) gets the same
+// indices back, so the results and the health of the VMs stay attributed to the host.
+// The tasks in progress on the VMs of a host are failed, and retried like the tasks of crashed
+// VMs, when the host misses heartbeats for remoteHostTimeout, when it registers again and when
+// the stream of a Runner breaks. The VMs of a host that missed heartbeats don't get tasks
+// until the host registers again. Clean VM reboots (see cleanvm.go) of remote VMs are
+// requested in the heartbeat responses.
+
+const (
+	remoteHeartbeatPeriod = 10 * time.Second
+	remoteHostTimeout     = time.Minute
+	// maxPoolVMs is the number of VM indices of a kernel (see vmTasksKey).
+	maxPoolVMs = 1000
+)
+
+// remoteHost is a machine that runs VMs of a remote kernel.
+type remoteHost struct {
+	name          string
+	pool          int
+	vms           []int
+	lastHeartbeat time.Time
+	// alive is unset if the host missed heartbeats.
+	alive bool
+	// reboot are the VMs that must be restarted, they are sent in the next heartbeat response.
+	reboot map[int]bool
+}
+
+// remoteHosts is the registry of the hosts of all remote kernels.
+// All methods can be called on a nil object, then no kernel is remote.
+type remoteHosts struct {
+	mu    sync.Mutex
+	pools map[int]bool
+	hosts map[string]*remoteHost
+	// vms maps vmTasksKey of the registered VMs to their hosts.
+	vms map[int]*remoteHost
+	// next is the next unused VM index of each kernel.
+	next map[int]int
+}
+
+func newRemoteHosts(pools []int) *remoteHosts {
+	if len(pools) == 0 {
+		return nil
+	}
+	rh := &remoteHosts{
+		pools: make(map[int]bool),
+		hosts: make(map[string]*remoteHost),
+		vms:   make(map[int]*remoteHost),
+		next:  make(map[int]int),
+	}
+	for _, pool := range pools {
+		rh.pools[pool] = true
+	}
+	return rh
+}
+
+// parseRemotePools parses the -remote-pools flag value.
+func parseRemotePools(flag string, kernels int) ([]int, error) {
+	if flag == "" {
+		return nil, nil
+	}
+	var res []int
+	seen := make(map[int]bool)
+	for _, s := range strings.Split(flag, ",") {
+		pool, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || pool < 0 || pool >= kernels {
+			return nil, fmt.Errorf("bad remote pool %q: must be an index of a kernel config", s)
+		}
+		if !seen[pool] {
+			seen[pool] = true
+			res = append(res, pool)
+		}
+	}
+	sort.Ints(res)
+	return res, nil
+}
+
+// isRemote returns true if the VMs of the kernel run on remote hosts.
+func (rh *remoteHosts) isRemote(pool int) bool {
+	return rh != nil && rh.pools[pool]
+}
+
+// register registers count VMs of the kernel on the host and returns their indices.
+// If the host was already registered, reconnected is set and the caller must fail the tasks
+// that were in progress on the returned VMs.
+func (rh *remoteHosts) register(name string, pool, count int, now time.Time) (
+	vms []int, reconnected bool, err error) {
+	if !rh.isRemote(pool) {
+		return nil, false, fmt.Errorf("kernel %v is not remote", pool)
+	}
+	if name == "" || count <= 0 {
+		return nil, false, fmt.Errorf("bad host registration: name %q, %v VMs", name, count)
+	}
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	host := rh.hosts[name]
+	if host != nil && host.pool != pool {
+		return nil, false, fmt.Errorf("host %v is already registered for kernel %v", name, host.pool)
+	}
+	if host == nil {
+		host = &remoteHost{name: name, pool: pool}
+		rh.hosts[name] = host
+	} else {
+		reconnected = true
+	}
+	if len(host.vms) < count && rh.next[pool]+count-len(host.vms) > maxPoolVMs {
+		return nil, false, fmt.Errorf("kernel %v has too many VMs", pool)
+	}
+	for len(host.vms) < count {
+		vm := rh.next[pool]
+		rh.next[pool]++
+		host.vms = append(host.vms, vm)
+		rh.vms[vmTasksKey(pool, vm)] = host
+	}
+	// If the host runs less VMs than before, the remaining indices stay reserved for it.
+	host.alive = true
+	host.lastHeartbeat = now
+	host.reboot = nil
+	return append([]int{}, host.vms[:count]...), reconnected, nil
+}
+
+// heartbeat records the heartbeat of the host and returns the VMs it must restart.
+// It returns false if the host must register again.
+func (rh *remoteHosts) heartbeat(name string, now time.Time) ([]int, bool) {
+	if rh == nil {
+		return nil, false
+	}
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	host := rh.hosts[name]
+	if host == nil || !host.alive {
+		return nil, false
+	}
+	host.lastHeartbeat = now
+	var reboot []int
+	for vm := range host.reboot {
+		reboot = append(reboot, vm)
+	}
+	sort.Ints(reboot)
+	host.reboot = nil
+	return reboot, true
+}
+
+// expire marks the hosts that missed heartbeats since deadline as dead and returns them.
+// The caller must fail the tasks in progress on their VMs.
+func (rh *remoteHosts) expire(deadline time.Time) []remoteHost {
+	if rh == nil {
+		return nil
+	}
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	var res []remoteHost
+	for _, host := range rh.hosts {
+		if host.alive && host.lastHeartbeat.Before(deadline) {
+			host.alive = false
+			res = append(res, *host)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].name < res[j].name
+	})
+	return res
+}
+
+// alive returns true if the VM is local or belongs to a live host.
+func (rh *remoteHosts) alive(pool, vm int) bool {
+	if !rh.isRemote(pool) {
+		return true
+	}
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	host := rh.vms[vmTasksKey(pool, vm)]
+	return host != nil && host.alive
+}
+
+// hostName returns the name of the host of the VM, or "" if the VM is local.
+func (rh *remoteHosts) hostName(pool, vm int) string {
+	if !rh.isRemote(pool) {
+		return ""
+	}
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	if host := rh.vms[vmTasksKey(pool, vm)]; host != nil {
+		return host.name
+	}
+	return ""
+}
+
+// requestReboot requests the host of the remote VM to restart it.
+func (rh *remoteHosts) requestReboot(pool, vm int) {
+	if !rh.isRemote(pool) {
+		return
+	}
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	host := rh.vms[vmTasksKey(pool, vm)]
+	if host == nil {
+		return
+	}
+	if host.reboot == nil {
+		host.reboot = make(map[int]bool)
+	}
+	host.reboot[vm] = true
+}
+
+// RegisterHost registers the VMs of a remote host, the tasks that were in progress
+// on the VMs of a reconnected host are failed.
+func (srv *RPCServer) RegisterHost(name string, pool, count int) ([]int, error) {
+	vms, reconnected, err := srv.remote.register(name, pool, count, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if reconnected {
+		log.Logf(0, "host %v of kernel %v reconnected with %v VMs", name, pool, count)
+	} else {
+		log.Logf(0, "host %v of kernel %v registered with %v VMs", name, pool, count)
+	}
+	for _, vm := range vms {
+		if reconnected {
+			srv.cleanup(pool, vm)
+		}
+		srv.vrf.health.booting(pool, vm)
+		srv.vrf.health.setHost(pool, vm, name)
+	}
+	return vms, nil
+}
+
+// Heartbeat records the heartbeat of a remote host, see remoteHosts.heartbeat.
+func (srv *RPCServer) Heartbeat(name string) ([]int, bool) {
+	return srv.remote.heartbeat(name, time.Now())
+}
+
+// expireRemoteHosts fails the tasks in progress on the VMs of the hosts that missed heartbeats.
+func (srv *RPCServer) expireRemoteHosts(now time.Time) {
+	for _, host := range srv.remote.expire(now.Add(-remoteHostTimeout)) {
+		log.Logf(0, "host %v of kernel %v missed heartbeats since %v",
+			host.name, host.pool, host.lastHeartbeat.Format(time.RFC3339))
+		for _, vm := range host.vms {
+			srv.cleanup(host.pool, vm)
+		}
+	}
+}
+
+func (srv *RPCServer) remoteHostsLoop() {
+	for range time.NewTicker(remoteHeartbeatPeriod).C {
+		srv.expireRemoteHosts(time.Now())
+	}
+}
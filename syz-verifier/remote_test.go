@@ -0,0 +1,169 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/pkg/rpctype"
+)
+
+func TestParseRemotePools(t *testing.T) {
+	pools, err := parseRemotePools("2, 1,2", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]int{1, 2}, pools); diff != "" {
+		t.Errorf("remote pools mismatch (-want +got):\n%s", diff)
+	}
+	for _, flag := range []string{"3", "-1", "a", "1,"} {
+		if _, err := parseRemotePools(flag, 3); err == nil {
+			t.Errorf("%q: the error is not returned", flag)
+		}
+	}
+}
+
+func TestRemoteHosts(t *testing.T) {
+	var local *remoteHosts
+	if local.isRemote(0) || !local.alive(0, 0) {
+		t.Fatalf("nil registry has remote VMs")
+	}
+	now := time.Now()
+	rh := newRemoteHosts([]int{1, 2})
+	register := func(name string, pool, count int, wantReconnected bool, want ...int) {
+		t.Helper()
+		vms, reconnected, err := rh.register(name, pool, count, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, vms); diff != "" || reconnected != wantReconnected {
+			t.Fatalf("%v: VMs mismatch (-want +got):\n%s, reconnected %v", name, diff, reconnected)
+		}
+	}
+	register("a", 1, 2, false, 0, 1)
+	register("b", 1, 1, false, 2)
+	register("c", 2, 1, false, 0)
+	// A reconnected host gets the same VMs, the new VMs get new indices.
+	register("a", 1, 3, true, 0, 1, 3)
+	register("a", 1, 1, true, 0)
+	register("a", 1, 3, true, 0, 1, 3)
+	for _, bad := range []struct {
+		name  string
+		pool  int
+		count int
+	}{
+		{"a", 2, 1},
+		{"d", 0, 1},
+		{"d", 1, 0},
+		{"", 1, 1},
+		{"d", 1, maxPoolVMs},
+	} {
+		if _, _, err := rh.register(bad.name, bad.pool, bad.count, now); err == nil {
+			t.Errorf("%+v: the error is not returned", bad)
+		}
+	}
+	if name := rh.hostName(1, 3); name != "a" {
+		t.Errorf("VM 3 belongs to %q, want a", name)
+	}
+
+	rh.requestReboot(1, 3)
+	rh.requestReboot(1, 2)
+	if reboot, ok := rh.heartbeat("a", now.Add(time.Minute)); !ok || !cmp.Equal(reboot, []int{3}) {
+		t.Errorf("got heartbeat reboots %v/%v, want [3]", reboot, ok)
+	}
+	if reboot, ok := rh.heartbeat("a", now.Add(time.Minute)); !ok || reboot != nil {
+		t.Errorf("the reboot is requested again: %v/%v", reboot, ok)
+	}
+	if _, ok := rh.heartbeat("d", now); ok {
+		t.Errorf("unknown host is not asked to register")
+	}
+
+	// Hosts b and c missed heartbeats.
+	expired := rh.expire(now.Add(time.Second))
+	if len(expired) != 2 || expired[0].name != "b" || expired[1].name != "c" {
+		t.Fatalf("got expired hosts %+v, want b and c", expired)
+	}
+	if len(rh.expire(now.Add(time.Second))) != 0 {
+		t.Fatalf("the hosts expired twice")
+	}
+	if rh.alive(1, 2) || !rh.alive(1, 0) || rh.alive(1, 4) {
+		t.Errorf("wrong live VMs")
+	}
+	if _, ok := rh.heartbeat("b", now.Add(time.Minute)); ok {
+		t.Errorf("dead host is not asked to register")
+	}
+	register("b", 1, 1, true, 2)
+	if !rh.alive(1, 2) {
+		t.Errorf("reconnected host is not alive")
+	}
+}
+
+func TestRemoteHostFailure(t *testing.T) {
+	p := getTestProgram(t)
+	vrf := &Verifier{stats: MakeStats(), pools: map[int]*poolInfo{0: {}}}
+	makeTestQueues(vrf, 1)
+	srv := &RPCServer{vrf: vrf, remote: newRemoteHosts([]int{0})}
+	vrf.srv = srv
+
+	r := new(rpctype.RunnerConnectRes)
+	if err := srv.Connect(&rpctype.RunnerConnectArgs{Pool: 0, VM: 0}, r); err == nil {
+		t.Fatalf("VM of an unregistered host connected")
+	}
+	vms, err := srv.RegisterHost("a", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Connect(&rpctype.RunnerConnectArgs{Pool: 0, VM: vms[0]}, r); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func() chan error {
+		done := make(chan error, 1)
+		go func() {
+			_, err := vrf.Run(p, NewEnvironment)
+			done <- err
+		}()
+		return done
+	}
+	// The tasks in progress on the VMs of a host that missed heartbeats are failed.
+	done := run()
+	task := vrf.GetRunnerTask(0, NewEnvironment)
+	srv.startWaitResult(0, vms[0], task.ID)
+	srv.expireRemoteHosts(time.Now())
+	select {
+	case err := <-done:
+		t.Fatalf("the task failed before the host expired: %v", err)
+	default:
+	}
+	srv.expireRemoteHosts(time.Now().Add(2 * remoteHostTimeout))
+	if err := <-done; err == nil {
+		t.Fatalf("the task of the expired host did not fail")
+	}
+	a := &rpctype.NextExchangeArgs{Pool: 0, VM: vms[0]}
+	if err := srv.NextExchange(a, new(rpctype.NextExchangeRes)); err == nil {
+		t.Fatalf("VM of an expired host got a task")
+	}
+
+	// The tasks in progress on the VMs of a reconnected host are failed as well.
+	if _, err := srv.RegisterHost("a", 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	done = run()
+	task = vrf.GetRunnerTask(0, NewEnvironment)
+	srv.startWaitResult(0, vms[0], task.ID)
+	if _, err := srv.RegisterHost("a", 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err == nil {
+		t.Fatalf("the task of the reconnected host did not fail")
+	}
+
+	// Clean VM reboots are requested in the heartbeats.
+	srv.rebootVM(0, vms[0])
+	if reboot, ok := srv.Heartbeat("a"); !ok || !cmp.Equal(reboot, vms) {
+		t.Fatalf("got heartbeat reboots %v/%v, want %v", reboot, ok, vms)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/pkg/osutil"
+)
+
+// Besides the report written when syz-verifier exits, reports with the current statistics can be
+// written to several sinks (-report-sinks) periodically (-report-interval) and on demand
+// (SIGUSR1 or POST /api/report).
+
+// reportFilesKeep is the number of rotated report files kept by the file sink.
+const reportFilesKeep = 10
+
+// reportSink is a destination of the reports.
+type reportSink interface {
+	write(data []byte) error
+	String() string
+}
+
+type writerSink struct {
+	name string
+	w    io.Writer
+}
+
+func (s *writerSink) write(data []byte) error {
+	_, err := s.w.Write(data)
+	return err
+}
+
+func (s *writerSink) String() string {
+	return s.name
+}
+
+// fileSink writes every report to the file, previous reports are rotated to file.1, file.2, etc.
+type fileSink struct {
+	file string
+	keep int
+}
+
+func (s *fileSink) write(data []byte) error {
+	for i := s.keep - 1; i > 0; i-- {
+		src := s.file
+		if i > 1 {
+			src = fmt.Sprintf("%v.%v", s.file, i-1)
+		}
+		if !osutil.IsExist(src) {
+			continue
+		}
+		if err := os.Rename(src, fmt.Sprintf("%v.%v", s.file, i)); err != nil {
+			return err
+		}
+	}
+	return osutil.WriteFile(s.file, data)
+}
+
+func (s *fileSink) String() string {
+	return "file:" + s.file
+}
+
+// httpSink sends every report in a POST request.
+type httpSink struct {
+	url         string
+	contentType string
+}
+
+func (s *httpSink) write(data []byte) error {
+	resp, err := http.Post(s.url, s.contentType, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%v: %v", s.url, resp.Status)
+	}
+	return nil
+}
+
+func (s *httpSink) String() string {
+	return s.url
+}
+
+// parseReportSinks parses the -report-sinks flag: a comma-separated list of
+// "stdout", "file:<path>" (relative to workdir) and "http(s)://<url>".
+func parseReportSinks(spec, workdir string, jsonReports bool) ([]reportSink, error) {
+	var sinks []reportSink
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+		case item == "stdout":
+			sinks = append(sinks, &writerSink{"stdout", os.Stdout})
+		case strings.HasPrefix(item, "file:"):
+			file := strings.TrimPrefix(item, "file:")
+			if file == "" {
+				return nil, fmt.Errorf("bad report sink %q: empty file name", item)
+			}
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(workdir, file)
+			}
+			sinks = append(sinks, &fileSink{file, reportFilesKeep})
+		case strings.HasPrefix(item, "http://") || strings.HasPrefix(item, "https://"):
+			contentType := "text/plain; charset=utf-8"
+			if jsonReports {
+				contentType = "application/json"
+			}
+			sinks = append(sinks, &httpSink{item, contentType})
+		default:
+			return nil, fmt.Errorf("bad report sink %q", item)
+		}
+	}
+	return sinks, nil
+}
+
+// reportSinks writes reports generated by gen to all sinks.
+// All methods can be called on a nil object.
+type reportSinks struct {
+	mu    sync.Mutex
+	sinks []reportSink
+	gen   func(w io.Writer) error
+}
+
+// report writes the current report to all sinks.
+func (rs *reportSinks) report() error {
+	if rs == nil {
+		return nil
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	buf := new(bytes.Buffer)
+	if err := rs.gen(buf); err != nil {
+		return err
+	}
+	var errs []string
+	for _, sink := range rs.sinks {
+		if err := sink.write(buf.Bytes()); err != nil {
+			errs = append(errs, fmt.Sprintf("%v: %v", sink, err))
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("failed to write report: %v", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// reportLoop writes reports every interval (if not 0) and on every SIGUSR1.
+func (vrf *Verifier) reportLoop(interval time.Duration) {
+	vrf.progGeneratorInit.Wait()
+	var tick <-chan time.Time
+	if interval > 0 {
+		tick = time.NewTicker(interval).C
+	}
+	sig := make(chan os.Signal, 1)
+	notifyReportSignal(sig)
+	for {
+		select {
+		case <-tick:
+		case <-sig:
+			log.Logf(0, "writing report on signal")
+		}
+		if err := vrf.reports.report(); err != nil {
+			log.Logf(0, "%v", err)
+		}
+	}
+}
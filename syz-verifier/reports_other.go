@@ -0,0 +1,14 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+//go:build !freebsd && !netbsd && !openbsd && !linux && !darwin
+// +build !freebsd,!netbsd,!openbsd,!linux,!darwin
+
+package main
+
+import (
+	"os"
+)
+
+func notifyReportSignal(c chan os.Signal) {
+}
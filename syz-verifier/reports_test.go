@@ -0,0 +1,74 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseReportSinks(t *testing.T) {
+	sinks, err := parseReportSinks("stdout, file:report.txt,file:/tmp/report,http://localhost/report", "/workdir", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, sink := range sinks {
+		got = append(got, sink.String())
+	}
+	want := "[stdout file:/workdir/report.txt file:/tmp/report http://localhost/report]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("got sinks %v, want %v", got, want)
+	}
+	for _, spec := range []string{"stderr", "file:", "ftp://localhost"} {
+		if _, err := parseReportSinks(spec, "/workdir", false); err == nil {
+			t.Errorf("bad sink %q is accepted", spec)
+		}
+	}
+}
+
+func TestReportSinks(t *testing.T) {
+	var posted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		posted = append(posted, string(data))
+	}))
+	defer srv.Close()
+	file := filepath.Join(t.TempDir(), "report")
+	seq := 0
+	rs := &reportSinks{
+		sinks: []reportSink{&fileSink{file, 3}, &httpSink{srv.URL, "text/plain"}},
+		gen: func(w io.Writer) error {
+			seq++
+			_, err := fmt.Fprintf(w, "report %v", seq)
+			return err
+		},
+	}
+	for i := 0; i < 4; i++ {
+		if err := rs.report(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fmt.Sprint(posted) != "[report 1 report 2 report 3 report 4]" {
+		t.Errorf("bad posted reports: %q", posted)
+	}
+	for suffix, want := range map[string]string{"": "report 4", ".1": "report 3", ".2": "report 2"} {
+		data, err := ioutil.ReadFile(file + suffix)
+		if err != nil || string(data) != want {
+			t.Errorf("bad report file %v: %q (%v), want %q", file+suffix, data, err, want)
+		}
+	}
+	if data, err := ioutil.ReadFile(file + ".3"); err == nil {
+		t.Errorf("too many rotated report files: %q", data)
+	}
+	var nilSinks *reportSinks
+	if err := nilSinks.report(); err != nil {
+		t.Errorf("nil sinks failed: %v", err)
+	}
+}
@@ -4,20 +4,26 @@
 package main
 
 import (
+	"crypto/tls"
 	"errors"
+	"fmt"
 	"net"
 	"os"
+	"path/filepath"
 	"sync"
 
 	"github.com/google/syzkaller/pkg/log"
 	"github.com/google/syzkaller/pkg/rpctype"
+	"github.com/google/syzkaller/pkg/rpctype/runnerpb"
+	"google.golang.org/grpc"
 )
 
-// RPCServer is a wrapper around the rpc.Server. It communicates with  Runners,
+// RPCServer communicates with Runners over gRPC (see grpcServer),
 // generates programs and sends complete Results for verification.
 type RPCServer struct {
-	vrf  *Verifier
-	port int
+	vrf       *Verifier
+	port      int
+	tlsClient *rpctype.TLSFiles
 
 	// protects next variables
 	mu sync.Mutex
@@ -25,29 +31,75 @@ type RPCServer struct {
 	notChecked int
 	// vmTasks store the per-VM currently assigned tasks Ids
 	vmTasksInProgress map[int]map[int64]bool
+	// vmEnvs tracks which VMs are clean (see cleanvm.go).
+	vmEnvs map[int]*vmEnvState
+
+	// remote tracks the hosts that run the VMs of remote kernels (see remote.go).
+	remote *remoteHosts
 }
 
 func startRPCServer(vrf *Verifier) (*RPCServer, error) {
 	srv := &RPCServer{
 		vrf:        vrf,
 		notChecked: len(vrf.pools),
+		remote:     newRemoteHosts(vrf.remotePools),
 	}
 
-	s, err := rpctype.NewRPCServer(vrf.addr, "Verifier", srv)
+	var tlsCfg *tls.Config
+	if vrf.tls {
+		tlsServer, tlsClient, err := rpctype.GenerateTLS(filepath.Join(vrf.workdir, "tls"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate tls certificates: %v", err)
+		}
+		if tlsCfg, err = tlsServer.ServerConfig(); err != nil {
+			return nil, err
+		}
+		srv.tlsClient = tlsClient
+	}
+	var opts []grpc.ServerOption
+	if vrf.rpcToken != "" {
+		if !vrf.tls {
+			log.Logf(0, "WARNING: rpc_tls is not enabled, the rpc token is sent in plain text")
+		}
+		opts = rpctype.TokenServerOptions(vrf.rpcToken)
+	}
+	s, ln, err := rpctype.NewGRPCServer(vrf.addr, tlsCfg, opts...)
 	if err != nil {
 		return nil, err
 	}
+	runnerpb.RegisterVerifierServer(s, &grpcServer{srv: srv})
 
-	log.Logf(0, "serving rpc on tcp://%v", s.Addr())
-	srv.port = s.Addr().(*net.TCPAddr).Port
+	log.Logf(0, "serving grpc on tcp://%v", ln.Addr())
+	srv.port = ln.Addr().(*net.TCPAddr).Port
 
-	go s.Serve()
+	go func() {
+		if err := s.Serve(ln); err != nil {
+			log.Logf(0, "grpc server failed: %v", err)
+		}
+	}()
+	if srv.remote != nil {
+		go srv.remoteHostsLoop()
+	}
+	if vrf.runnerTimeout != 0 {
+		go srv.runnerHealthLoop(vrf.runnerTimeout)
+	}
 	return srv, nil
 }
 
 // Connect notifies the RPCServer that a new Runner was started.
 func (srv *RPCServer) Connect(a *rpctype.RunnerConnectArgs, r *rpctype.RunnerConnectRes) error {
+	if srv.remote.isRemote(a.Pool) {
+		if !srv.remote.alive(a.Pool, a.VM) {
+			return fmt.Errorf("VM %v of kernel %v does not belong to a live host", a.VM, a.Pool)
+		}
+		// Remote VMs are not managed by the VM loop, so the clean VM state is reset here.
+		srv.vmBooted(a.Pool, a.VM)
+		srv.vrf.health.running(a.Pool, a.VM)
+	}
 	r.CheckUnsupportedCalls = !srv.vrf.pools[a.Pool].checked
+	r.CollectOutputs = srv.vrf.compareOutputs
+	r.StateProbes = srv.vrf.stateProbes
+	r.HeartbeatPeriod = srv.vrf.runnerTimeout / runnerHeartbeats
 	return nil
 }
 
@@ -68,7 +120,7 @@ func (srv *RPCServer) UpdateUnsupported(a *rpctype.UpdateUnsupportedArgs, r *int
 	vrf := srv.vrf
 
 	for _, unsupported := range a.UnsupportedCalls {
-		if c := vrf.target.Syscalls[unsupported.ID]; vrf.calls[c] {
+		if c := vrf.poolSyscall(a.Pool, unsupported.ID); c != nil && vrf.calls[c] {
 			vrf.reasons[c] = unsupported.Reason
 		}
 	}
@@ -78,9 +130,22 @@ func (srv *RPCServer) UpdateUnsupported(a *rpctype.UpdateUnsupportedArgs, r *int
 		vrf.finalizeCallSet(os.Stdout)
 
 		vrf.stats.SetSyscallMask(vrf.calls)
+		if vrf.checkpointFile != "" {
+			if err := vrf.restoreCheckpoint(); err != nil {
+				log.Fatalf("%v", err)
+			}
+			go vrf.checkpointLoop(vrf.checkpointPeriod)
+		}
+		if vrf.corpusFile != "" {
+			if err := vrf.loadCorpus(vrf.corpusFile); err != nil {
+				log.Fatalf("%v", err)
+			}
+		}
 		vrf.SetPrintStatAtSIGINT()
 
-		vrf.choiceTable = vrf.target.BuildChoiceTable(nil, vrf.calls)
+		vrf.callsMu.Lock()
+		vrf.buildChoiceTable()
+		vrf.callsMu.Unlock()
 		vrf.progGeneratorInit.Done()
 	}
 	return nil
@@ -89,18 +154,32 @@ func (srv *RPCServer) UpdateUnsupported(a *rpctype.UpdateUnsupportedArgs, r *int
 // NextExchange is called when a Runner requests a new program to execute and,
 // potentially, wants to send a new Result to the RPCServer.
 func (srv *RPCServer) NextExchange(a *rpctype.NextExchangeArgs, r *rpctype.NextExchangeRes) error {
+	if !srv.remote.alive(a.Pool, a.VM) {
+		return fmt.Errorf("VM %v of kernel %v does not belong to a live host", a.VM, a.Pool)
+	}
 	if a.Info.Calls != nil {
 		srv.stopWaitResult(a.Pool, a.VM, a.ExecTaskID)
+		srv.vrf.health.resultReceived(a.Pool, a.VM)
+		srv.vrf.stats.addCoverage(a.Pool, &a.Info)
+		srv.vrf.translateErrnos(a.Pool, &a.Info)
 		PutExecResult(&ExecResult{
 			Pool:       a.Pool,
 			Hanged:     a.Hanged,
 			Info:       a.Info,
 			ExecTaskID: a.ExecTaskID,
+			Trace:      a.Trace,
 		})
 	}
 
-	// TODO: NewEnvironment is the currently hardcoded logic. Relax it.
-	task := srv.vrf.GetRunnerTask(a.Pool, NewEnvironment)
+	srv.vrf.poolThrottle(a.Pool).acquire(vmTasksKey(a.Pool, a.VM))
+	task := srv.vrf.GetRunnerTask(a.Pool, srv.vmEnv(a.Pool, a.VM))
+	if task == nil {
+		// The VM is rebooted to execute CleanVMEnvironment tasks.
+		srv.rebootVM(a.Pool, a.VM)
+		r.Reboot = true
+		r.ID = rpctype.NoTask
+		return nil
+	}
 	srv.startWaitResult(a.Pool, a.VM, task.ID)
 	r.ExecTask = *task
 
@@ -133,7 +212,7 @@ func (srv *RPCServer) stopWaitResult(poolID, vmID int, taskID int64) {
 	delete(srv.vmTasksInProgress[vmTasksKey(poolID, vmID)], taskID)
 }
 
-// cleanup is called when a vm.Instance crashes.
+// cleanup is called when a vm.Instance crashes or a remote VM is lost.
 func (srv *RPCServer) cleanup(poolID, vmID int) {
 	srv.mu.Lock()
 	defer srv.mu.Unlock()
@@ -148,4 +227,5 @@ func (srv *RPCServer) cleanup(poolID, vmID int) {
 		})
 	}
 	delete(srv.vmTasksInProgress, vmTasksKey(poolID, vmID))
+	srv.vrf.poolThrottle(poolID).release(vmTasksKey(poolID, vmID))
 }
@@ -0,0 +1,145 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/syzkaller/pkg/log"
+)
+
+// With -runner-timeout, the Runners send heartbeats with the time they have been executing
+// the current program. A Runner is stalled if the verifier did not hear from it (a heartbeat
+// or a result) within the timeout, e.g. the Runner died or the VM hung without the VM monitor
+// noticing, or if it has been executing a single program for longer than the timeout, e.g.
+// syz-executor hung and was not killed. The VMs of stalled Runners are restarted (restored
+// from the snapshot with -snapshots, remote VMs are restarted by their hosts) and the tasks
+// in progress on them are put back to the queues instead of failing, so that other VMs
+// execute them. The number of Runners of each kernel in each state is shown in the stats,
+// so that a kernel whose VMs keep stalling is visible instead of silently losing throughput.
+
+// runnerHeartbeats is the number of heartbeats the Runners send within the timeout.
+const runnerHeartbeats = 4
+
+// RunnerStatsJSON is the number of Runners of a kernel in each state.
+type RunnerStatsJSON struct {
+	Pool     int
+	Running  int
+	Booting  int
+	Stalled  int
+	Restarts int
+}
+
+// RunnerHeartbeat is called periodically by the Runners, busy is for how long
+// the Runner has been executing the current program.
+func (srv *RPCServer) RunnerHeartbeat(poolID, vmID int, busy time.Duration) {
+	srv.vrf.health.heartbeat(poolID, vmID, busy)
+}
+
+func (srv *RPCServer) runnerHealthLoop(timeout time.Duration) {
+	for now := range time.NewTicker(timeout / runnerHeartbeats).C {
+		srv.restartStalled(now, timeout)
+	}
+}
+
+// restartStalled restarts the VMs of the Runners stalled at now and requeues their tasks.
+func (srv *RPCServer) restartStalled(now time.Time, timeout time.Duration) {
+	for _, st := range srv.vrf.health.markStalled(now, timeout) {
+		log.Logf(0, "runner on VM %v of kernel %v %v, restarting the VM",
+			st.VM, st.Pool, st.stallReason(now, timeout))
+		atomic.AddInt64(&srv.vrf.stats.RunnerRestarts, 1)
+		srv.requeueTasks(st.Pool, st.VM)
+		srv.restartVM(st.Pool, st.VM)
+	}
+}
+
+// requeueTasks asks the waiters of the tasks in progress on the VM to put them back to the queues.
+func (srv *RPCServer) requeueTasks(poolID, vmID int) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for taskID := range srv.vmTasksInProgress[vmTasksKey(poolID, vmID)] {
+		PutExecResult(&ExecResult{
+			Pool:       poolID,
+			ExecTaskID: taskID,
+			requeued:   true,
+		})
+	}
+	delete(srv.vmTasksInProgress, vmTasksKey(poolID, vmID))
+}
+
+// stallReason describes why the Runner is stalled at now, returns "" if it is not.
+func (st *VMStatus) stallReason(now time.Time, timeout time.Duration) string {
+	if st.Busy > timeout {
+		return fmt.Sprintf("is executing a program for %v", st.Busy)
+	}
+	seen := st.Started
+	for _, t := range []time.Time{st.LastHeartbeat, st.LastResult} {
+		if t.After(seen) {
+			seen = t
+		}
+	}
+	if silent := now.Sub(seen); silent > timeout {
+		return fmt.Sprintf("did not respond for %v", silent.Round(time.Second))
+	}
+	return ""
+}
+
+// markStalled marks the running Runners that are stalled at now and returns them.
+func (vh *vmHealth) markStalled(now time.Time, timeout time.Duration) []VMStatus {
+	if vh == nil {
+		return nil
+	}
+	vh.mu.Lock()
+	defer vh.mu.Unlock()
+	var res []VMStatus
+	for _, st := range vh.vms {
+		if !st.Running || st.Stalled || st.stallReason(now, timeout) == "" {
+			continue
+		}
+		st.Stalled = true
+		st.Restarts++
+		res = append(res, *st)
+	}
+	return res
+}
+
+// runnerStats returns the states of the Runners of all kernels ordered by pool index.
+func (vh *vmHealth) runnerStats() []*RunnerStatsJSON {
+	pools := make(map[int]*RunnerStatsJSON)
+	var res []*RunnerStatsJSON
+	for _, st := range vh.snapshot() {
+		rs := pools[st.Pool]
+		if rs == nil {
+			rs = &RunnerStatsJSON{Pool: st.Pool}
+			pools[st.Pool] = rs
+			res = append(res, rs)
+		}
+		switch st.State() {
+		case "stalled":
+			rs.Stalled++
+		case "running":
+			rs.Running++
+		default:
+			rs.Booting++
+		}
+		rs.Restarts += st.Restarts
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Pool < res[j].Pool
+	})
+	return res
+}
+
+func formatRunnerStats(runners []*RunnerStatsJSON) string {
+	var res []string
+	for _, rs := range runners {
+		res = append(res, fmt.Sprintf("pool %d: %d running, %d booting, %d stalled, %d restarts",
+			rs.Pool, rs.Running, rs.Booting, rs.Stalled, rs.Restarts))
+	}
+	return strings.Join(res, "; ")
+}
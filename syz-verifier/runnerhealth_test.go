@@ -0,0 +1,82 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/pkg/ipc"
+)
+
+func TestRestartStalled(t *testing.T) {
+	p := getTestProgram(t)
+	vrf := &Verifier{stats: MakeStats(), health: newVMHealth()}
+	vrf.stats.health = vrf.health
+	makeTestQueues(vrf, 1)
+	srv := &RPCServer{vrf: vrf}
+	stop := srv.vmBooted(0, 0)
+	for vm := 0; vm < 2; vm++ {
+		vrf.health.booting(0, vm)
+		vrf.health.running(0, vm)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := vrf.Run(p, NewEnvironment)
+		done <- err
+	}()
+	task := vrf.GetRunnerTask(0, NewEnvironment)
+	srv.startWaitResult(0, 0, task.ID)
+
+	const timeout = 2 * time.Minute
+	now := time.Now()
+	srv.restartStalled(now.Add(time.Minute), timeout)
+	if vrf.stats.RunnerRestarts != 0 {
+		t.Fatalf("healthy runners were restarted")
+	}
+
+	// The Runner of VM 0 hangs in the program, its VM is restarted and the task is requeued.
+	vrf.health.heartbeat(0, 0, 3*time.Minute)
+	srv.restartStalled(now.Add(time.Minute), timeout)
+	select {
+	case <-stop:
+	default:
+		t.Fatalf("the VM of the stalled runner was not restarted")
+	}
+	requeued := vrf.GetRunnerTask(0, NewEnvironment)
+	if requeued.ID != task.ID {
+		t.Fatalf("got task %v, want the requeued task %v", requeued.ID, task.ID)
+	}
+	PutExecResult(&ExecResult{ExecTaskID: task.ID, Info: ipc.ProgInfo{Calls: make([]ipc.CallInfo, len(p.Calls))}})
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if vrf.stats.RunnerRestarts != 1 || vrf.stats.RequeuedTasks != 1 {
+		t.Fatalf("got %v restarts and %v requeued tasks, want 1 and 1",
+			vrf.stats.RunnerRestarts, vrf.stats.RequeuedTasks)
+	}
+	if vrf.cleanVMReboots[0] != 0 {
+		t.Fatalf("the restart is accounted as a clean VM reboot: %v", vrf.cleanVMReboots)
+	}
+
+	// The Runner of VM 1 does not respond, the stalled Runner of VM 0 is not restarted again.
+	srv.restartStalled(now.Add(3*time.Minute), timeout)
+	want := []*RunnerStatsJSON{{Pool: 0, Stalled: 2, Restarts: 2}}
+	if diff := cmp.Diff(want, vrf.health.runnerStats()); diff != "" {
+		t.Fatalf("runner stats mismatch (-want +got):\n%s", diff)
+	}
+
+	vrf.health.booting(0, 0)
+	vrf.health.running(0, 0)
+	vrf.health.booting(0, 1)
+	want = []*RunnerStatsJSON{{Pool: 0, Running: 1, Booting: 1, Restarts: 2}}
+	if diff := cmp.Diff(want, vrf.health.runnerStats()); diff != "" {
+		t.Fatalf("runner stats mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := formatRunnerStats(want), "pool 0: 1 running, 1 booting, 0 stalled, 2 restarts"; got != want {
+		t.Errorf("got runner stats %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/syzkaller/pkg/ipc"
+	"github.com/google/syzkaller/prog"
+)
+
+// Tasks can specify the executor sandbox the program is executed in, the Runner executes such
+// tasks in a new environment with the sandbox instead of the sandbox of the kernel config.
+// With -sandboxes=none,setuid,namespace, programs with new mismatch signatures are rerun once
+// in each of the sandboxes and the report shows in which of them the mismatch reproduces.
+// A mismatch that reproduces only in some sandboxes depends on the permissions of the program
+// (e.g. a call fails with EPERM on one kernel only in the setuid sandbox) rather than on
+// a difference in the behavior of the kernels.
+
+// SandboxResult is the result of the rerun of a mismatching program in a sandbox.
+type SandboxResult struct {
+	Sandbox string
+	// Mismatch is set if the mismatch reproduced in the sandbox.
+	Mismatch bool
+	// Error is set if the program could not be executed in the sandbox.
+	Error string `json:",omitempty"`
+}
+
+// compareSandboxes reruns the mismatching program in each sandbox.
+func compareSandboxes(p *prog.Prog, v *Verdict, sandboxes []string, run func(sandbox string) runFunc) []*SandboxResult {
+	var res []*SandboxResult
+	for _, sandbox := range sandboxes {
+		sr := &SandboxResult{Sandbox: sandbox}
+		results, err := run(sandbox)(p, NewEnvironment)
+		if err != nil {
+			sr.Error = err.Error()
+		} else {
+			sr.Mismatch = diverges(results) && mismatchSignature(p, results) == v.Signature
+		}
+		res = append(res, sr)
+	}
+	return res
+}
+
+// sandboxDependent returns true if the mismatch reproduced only in some of the sandboxes.
+func sandboxDependent(res []*SandboxResult) bool {
+	reproduced, missed := false, false
+	for _, sr := range res {
+		if sr.Error != "" {
+			continue
+		}
+		reproduced = reproduced || sr.Mismatch
+		missed = missed || !sr.Mismatch
+	}
+	return reproduced && missed
+}
+
+// formatSandboxes returns the sandbox comparison for the mismatch report.
+func formatSandboxes(res []*SandboxResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Sandbox comparison:\n")
+	for _, sr := range res {
+		switch {
+		case sr.Error != "":
+			fmt.Fprintf(&b, "\t%v: failed: %v\n", sr.Sandbox, sr.Error)
+		case sr.Mismatch:
+			fmt.Fprintf(&b, "\t%v: mismatch reproduced\n", sr.Sandbox)
+		default:
+			fmt.Fprintf(&b, "\t%v: no mismatch\n", sr.Sandbox)
+		}
+	}
+	if sandboxDependent(res) {
+		fmt.Fprintf(&b, "\tthe mismatch depends on the sandbox\n")
+	}
+	return b.String()
+}
+
+// parseSandboxes parses the comma-separated list of the -sandboxes flag.
+func parseSandboxes(list string) ([]string, error) {
+	var res []string
+	for _, sandbox := range strings.Split(list, ",") {
+		if sandbox == "" {
+			continue
+		}
+		if _, err := ipc.SandboxToFlags(sandbox); err != nil {
+			return nil, fmt.Errorf("bad sandbox %q: %v", sandbox, err)
+		}
+		res = append(res, sandbox)
+	}
+	return res, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/pkg/ipc"
+	"github.com/google/syzkaller/prog"
+)
+
+func TestParseSandboxes(t *testing.T) {
+	got, err := parseSandboxes("none,,setuid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"none", "setuid"}; !cmp.Equal(got, want) {
+		t.Errorf("got sandboxes %v, want %v", got, want)
+	}
+	if _, err := parseSandboxes("none,root"); err == nil {
+		t.Errorf("unknown sandbox is accepted")
+	}
+}
+
+func TestCompareSandboxes(t *testing.T) {
+	p := getTestProgram(t)
+	mismatch := []*ExecResult{
+		makeExecResult(0, []int{1, 3, 2}),
+		makeExecResult(1, []int{1, 3, 5}),
+	}
+	v := &Verdict{Results: mismatch, Mismatch: true, Signature: mismatchSignature(p, mismatch)}
+	run := func(sandbox string) runFunc {
+		return func(p *prog.Prog, env EnvDescr) ([]*ExecResult, error) {
+			switch sandbox {
+			case "none":
+				return []*ExecResult{
+					makeExecResult(0, []int{1, 3, 2}),
+					makeExecResult(1, []int{1, 3, 2}),
+				}, nil
+			case "setuid":
+				return mismatch, nil
+			default:
+				return nil, errors.New("no runners")
+			}
+		}
+	}
+	res := compareSandboxes(p, v, []string{"none", "setuid", "namespace"}, run)
+	want := []*SandboxResult{
+		{Sandbox: "none"},
+		{Sandbox: "setuid", Mismatch: true},
+		{Sandbox: "namespace", Error: "no runners"},
+	}
+	if diff := cmp.Diff(want, res); diff != "" {
+		t.Fatalf("sandbox results mismatch (-want +got):\n%s", diff)
+	}
+	wantReport := "Sandbox comparison:\n" +
+		"\tnone: no mismatch\n" +
+		"\tsetuid: mismatch reproduced\n" +
+		"\tnamespace: failed: no runners\n" +
+		"\tthe mismatch depends on the sandbox\n"
+	if got := formatSandboxes(res); got != wantReport {
+		t.Errorf("got report:\n%s\nwant:\n%s", got, wantReport)
+	}
+	if sandboxDependent(res[1:]) {
+		t.Errorf("mismatch reproduced in all sandboxes is sandbox dependent")
+	}
+}
+
+func TestRunInSandbox(t *testing.T) {
+	p := getTestProgram(t)
+	vrf := &Verifier{stats: MakeStats()}
+	makeTestQueues(vrf, 1)
+	done := make(chan error, 1)
+	go func() {
+		_, err := vrf.RunInSandbox("setuid")(p, NewEnvironment)
+		done <- err
+	}()
+	task := vrf.GetRunnerTask(0, NewEnvironment)
+	if task.Sandbox != "setuid" {
+		t.Fatalf("got task sandbox %q, want setuid", task.Sandbox)
+	}
+	PutExecResult(&ExecResult{ExecTaskID: task.ID, Info: ipc.ProgInfo{Calls: make([]ipc.CallInfo, len(p.Calls))}})
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/syzkaller/prog"
+)
+
+// Many mismatching programs boil down to the same divergence. A mismatch signature identifies
+// the divergence by the first mismatching syscall and its return states on all kernels.
+// Only the first program with each signature is saved to the results dir,
+// and the statistics count unique signatures separately from mismatching programs.
+
+// SignatureJSON is a mismatch signature and the number of programs with it.
+type SignatureJSON struct {
+	Signature string
+	Count     int64
+}
+
+// mismatchSignature returns the signature of the divergence of the results.
+func mismatchSignature(p *prog.Prog, results []*ExecResult) string {
+	rr := CompareResults(results, p)
+	for _, cr := range rr.Reports {
+		if cr.Mismatch {
+			return formatSignature(cr)
+		}
+	}
+	// The results diverge, but the states match due to the call annotations (see statesMatch).
+	for _, cr := range rr.Reports {
+		for _, state := range cr.States {
+			if state != cr.States[results[0].Pool] {
+				return formatSignature(cr)
+			}
+		}
+	}
+	return "unknown"
+}
+
+func formatSignature(cr *CallReport) string {
+	pools := make([]int, 0, len(cr.States))
+	for pool := range cr.States {
+		pools = append(pools, pool)
+	}
+	sort.Ints(pools)
+	var states []string
+	for _, pool := range pools {
+		states = append(states, cr.States[pool].String())
+	}
+	return fmt.Sprintf("%v: %v", cr.Call, strings.Join(states, " | "))
+}
+
+// addSignature records a mismatching program with the signature and returns true
+// if this is the first program with this signature.
+func (stats *Stats) addSignature(sig string) bool {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	if stats.signatures == nil {
+		stats.signatures = make(map[string]int64)
+	}
+	stats.signatures[sig]++
+	if stats.signatures[sig] != 1 {
+		return false
+	}
+	stats.UniqueMismatches++
+	return true
+}
+
+// topSignatures returns up to n signatures with the most programs (all if n is 0).
+// Must be called with stats.mu held.
+func (stats *Stats) topSignatures(n int) []*SignatureJSON {
+	var res []*SignatureJSON
+	for sig, count := range stats.signatures {
+		res = append(res, &SignatureJSON{sig, count})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].Signature < res[j].Signature
+	})
+	if n != 0 && len(res) > n {
+		res = res[:n]
+	}
+	return res
+}
@@ -0,0 +1,49 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMismatchSignature(t *testing.T) {
+	p := getTestProgram(t)
+	sig := mismatchSignature(p, []*ExecResult{
+		makeExecResult(0, []int{1, 3, 2}),
+		makeExecResult(1, []int{1, 3, 5}),
+	})
+	want := "test$res0: Flags: 0 (not executed), Errno: 2 ENOENT (no such file or directory) | " +
+		"Flags: 0 (not executed), Errno: 5 EIO (input/output error)"
+	if sig != want {
+		t.Errorf("signature: got %q, want %q", sig, want)
+	}
+
+	stats := emptyTestStats()
+	if !stats.addSignature(sig) {
+		t.Errorf("first signature is not new")
+	}
+	if stats.addSignature(sig) {
+		t.Errorf("repeated signature is new")
+	}
+	if !stats.addSignature("other") {
+		t.Errorf("other signature is not new")
+	}
+	got := stats.GetJSON(1)
+	if got.UniqueMismatches != 2 {
+		t.Errorf("unique mismatches: got %v, want 2", got.UniqueMismatches)
+	}
+	wantSigs := []*SignatureJSON{{sig, 2}, {"other", 1}}
+	if diff := cmp.Diff(wantSigs, got.Signatures); diff != "" {
+		t.Errorf("signatures (-want +got):\n%s", diff)
+	}
+
+	restored := emptyTestStats()
+	restored.addSignature("other")
+	restored.restore(stats.checkpoint())
+	if restored.UniqueMismatches != 2 || restored.signatures["other"] != 2 {
+		t.Errorf("restored signatures: %v unique, %v", restored.UniqueMismatches, restored.signatures)
+	}
+}
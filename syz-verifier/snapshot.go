@@ -0,0 +1,49 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/vm"
+)
+
+// With -snapshots, the state of each VM of the local kernels is saved after it boots and the Runner
+// and the executor are copied into it, before the Runner is started (qemu VMs only, the snapshot
+// is saved in the temporary overlay of the image). When the Runner is stopped, e.g. because
+// the VM must be clean to execute CleanVMEnvironment tasks, or the VM reaches vm_running_time,
+// the VM is restored from the snapshot and the Runner is started again instead of rebooting
+// the VM, which is much faster. VMs in which the kernel crashed are still rebooted.
+// The VMs offer the SnapshotEnvironment until they execute a task, so tasks can ask to be executed
+// in a restored VM; with -clean-vm-rerun, confirmed mismatches are rerun in SnapshotEnvironment.
+// The VMs of remote kernels (-remote-pools) can't be restored.
+
+// restoreVM restores the VM from the snapshot after the Runner stopped and returns true
+// if the VM can be used again.
+func (vrf *Verifier) restoreVM(inst *vm.Instance, poolID, vmID int) bool {
+	vrf.srv.cleanup(poolID, vmID)
+	vrf.health.booting(poolID, vmID)
+	if err := inst.Restore(); err != nil {
+		log.Logf(0, "failed to restore VM %v of kernel %v from the snapshot: %v", vmID, poolID, err)
+		return false
+	}
+	atomic.AddInt64(&vrf.stats.SnapshotRestores, 1)
+	log.New("vm").With("pool", poolID).Logf(1, "restored the VM from the snapshot")
+	return true
+}
+
+// checkSnapshotsConfig checks that the VMs of all local kernels support snapshots.
+func checkSnapshotsConfig(pools map[int]*poolInfo, remotePools []int) error {
+	if len(remotePools) != 0 {
+		return fmt.Errorf("-snapshots can't be used with -remote-pools")
+	}
+	for idx := 0; idx < len(pools); idx++ {
+		if typ := pools[idx].cfg.Type; typ != "qemu" {
+			return fmt.Errorf("-snapshots requires qemu VMs, kernel %v uses %v", idx, typ)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/syzkaller/pkg/mgrconfig"
+)
+
+func TestSnapshotEnvironment(t *testing.T) {
+	p := getTestProgram(t)
+	vrf := &Verifier{stats: MakeStats(), snapshots: true}
+	makeTestQueues(vrf, 1)
+	srv := &RPCServer{vrf: vrf}
+	srv.vmBooted(0, 0)
+
+	snapshot := MakeExecTask(p)
+	defer DeleteExecTask(snapshot)
+	clean := MakeExecTask(p)
+	defer DeleteExecTask(clean)
+	vrf.kernelEnvTasks[0][SnapshotEnvironment].PushTask(snapshot)
+	vrf.kernelEnvTasks[0][CleanVMEnvironment].PushTask(clean)
+
+	// The restored VM gets the snapshot task, then it needs to be restored again for the clean VM task.
+	if env := srv.vmEnv(0, 0); env != SnapshotEnvironment {
+		t.Fatalf("booted VM env: got %v, want %v", env, SnapshotEnvironment)
+	}
+	if task := vrf.GetRunnerTask(0, SnapshotEnvironment); task == nil || task.ID != snapshot.ID {
+		t.Fatalf("restored VM got task %+v, want %v", task, snapshot.ID)
+	}
+	if task := vrf.GetRunnerTask(0, NewEnvironment); task != nil {
+		t.Fatalf("dirty VM got task %+v instead of the restore", task)
+	}
+	srv.rebootVM(0, 0)
+	srv.vmBooted(0, 0)
+	if env := srv.vmEnv(0, 0); env != SnapshotEnvironment {
+		t.Fatalf("restored VM env: got %v, want %v", env, SnapshotEnvironment)
+	}
+	if task := vrf.GetRunnerTask(0, SnapshotEnvironment); task == nil || task.ID != clean.ID {
+		t.Fatalf("restored VM got task %+v, want %v", task, clean.ID)
+	}
+	if vrf.cleanVMReboots[0] != 0 {
+		t.Fatalf("restore is still accounted: %v", vrf.cleanVMReboots)
+	}
+
+	// Confirmed mismatches are rerun in restored VMs.
+	var envs []EnvDescr
+	vrf.rerunInCleanVM(&Verdict{}, func(env EnvDescr) ([]*ExecResult, error) {
+		envs = append(envs, env)
+		return []*ExecResult{makeExecResult(0, []int{1}), makeExecResult(1, []int{1})}, nil
+	})
+	if len(envs) != 1 || envs[0] != SnapshotEnvironment {
+		t.Fatalf("bad rerun environments: %v", envs)
+	}
+}
+
+func TestCheckSnapshotsConfig(t *testing.T) {
+	pools := map[int]*poolInfo{
+		0: {cfg: &mgrconfig.Config{Type: "qemu"}},
+		1: {cfg: &mgrconfig.Config{Type: "qemu"}},
+	}
+	if err := checkSnapshotsConfig(pools, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSnapshotsConfig(pools, []int{1}); err == nil {
+		t.Errorf("snapshots are accepted with remote kernels")
+	}
+	pools[1].cfg.Type = "gce"
+	if err := checkSnapshotsConfig(pools, nil); err == nil {
+		t.Errorf("snapshots are accepted with gce VMs")
+	}
+}
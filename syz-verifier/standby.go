@@ -0,0 +1,133 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/syzkaller/pkg/log"
+)
+
+// standbyPool keeps some of the VMs of a kernel booted but idle. When an active VM
+// crashes or reaches its running time limit, a standby VM starts the Runner immediately
+// instead of the kernel losing a Runner for the whole boot time.
+//
+// All VMs of the pool are booted in the same way, a booted VM becomes active
+// if there are less than count-standby active VMs and waits otherwise.
+// If max > min, the number of standby VMs is tuned to the expected number of VM restarts
+// during one boot: restarts per task * task arrival rate * boot time.
+type standbyPool struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	count   int
+	min     int
+	max     int
+	standby int
+	active  int
+
+	// Stats for auto-tuning, reset on each tune call.
+	restarts int64
+	arrivals int64 // updated atomically
+	bootTime time.Duration
+}
+
+func newStandbyPool(count, min, max int) *standbyPool {
+	if max < min {
+		max = min
+	}
+	// At least one VM must be active.
+	if max > count-1 {
+		max = count - 1
+	}
+	if min > max {
+		min = max
+	}
+	if min < 0 {
+		min = 0
+	}
+	sp := &standbyPool{
+		count:   count,
+		min:     min,
+		max:     max,
+		standby: min,
+	}
+	sp.cond = sync.NewCond(&sp.mu)
+	return sp
+}
+
+// booted records boot time of a VM.
+func (sp *standbyPool) booted(d time.Duration) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if sp.bootTime == 0 {
+		sp.bootTime = d
+	} else {
+		sp.bootTime = (sp.bootTime*7 + d) / 8
+	}
+}
+
+// activate waits until the booted VM can become active.
+func (sp *standbyPool) activate() {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	for sp.active >= sp.count-sp.standby {
+		sp.cond.Wait()
+	}
+	sp.active++
+}
+
+// deactivate is called when an active VM exits.
+func (sp *standbyPool) deactivate() {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.active--
+	sp.restarts++
+	sp.cond.Broadcast()
+}
+
+func (sp *standbyPool) taskArrived() {
+	atomic.AddInt64(&sp.arrivals, 1)
+}
+
+// tune adjusts the number of standby VMs based on the stats collected during the last period.
+// Returns the old and the new number of standby VMs.
+func (sp *standbyPool) tune(period time.Duration) (int, int) {
+	arrivals := atomic.SwapInt64(&sp.arrivals, 0)
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	restarts := sp.restarts
+	sp.restarts = 0
+	old := sp.standby
+	if sp.max <= sp.min || arrivals == 0 {
+		return old, old
+	}
+	arrivalRate := float64(arrivals) / period.Seconds()
+	restartsPerTask := float64(restarts) / float64(arrivals)
+	want := int(math.Ceil(restartsPerTask * arrivalRate * sp.bootTime.Seconds()))
+	if want < sp.min {
+		want = sp.min
+	}
+	if want > sp.max {
+		want = sp.max
+	}
+	if want < old {
+		// More VMs can be active now.
+		sp.cond.Broadcast()
+	}
+	sp.standby = want
+	return old, want
+}
+
+func (vrf *Verifier) tuneStandby(period time.Duration) {
+	for range time.NewTicker(period).C {
+		for poolID, pi := range vrf.pools {
+			if old, n := pi.standby.tune(period); n != old {
+				log.Logf(0, "pool %v: %v standby VMs", poolID, n)
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStandbyPoolLimits(t *testing.T) {
+	tests := []struct {
+		count, min, max          int
+		wantMin, wantMax, wantSB int
+	}{
+		{4, 1, 0, 1, 1, 1},
+		{4, 1, 2, 1, 2, 1},
+		{4, 0, 10, 0, 3, 0},
+		{1, 1, 1, 0, 0, 0},
+	}
+	for _, test := range tests {
+		sp := newStandbyPool(test.count, test.min, test.max)
+		if sp.min != test.wantMin || sp.max != test.wantMax || sp.standby != test.wantSB {
+			t.Errorf("newStandbyPool(%v, %v, %v): min=%v max=%v standby=%v, want %v %v %v",
+				test.count, test.min, test.max, sp.min, sp.max, sp.standby,
+				test.wantMin, test.wantMax, test.wantSB)
+		}
+	}
+}
+
+func TestStandbyPoolActivate(t *testing.T) {
+	sp := newStandbyPool(3, 1, 1)
+	sp.activate()
+	sp.activate()
+	activated := make(chan bool)
+	go func() {
+		sp.activate()
+		close(activated)
+	}()
+	select {
+	case <-activated:
+		t.Fatalf("standby VM was activated while all active VMs are running")
+	case <-time.After(100 * time.Millisecond):
+	}
+	sp.deactivate()
+	select {
+	case <-activated:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("standby VM was not activated after an active VM exited")
+	}
+}
+
+func TestStandbyPoolTune(t *testing.T) {
+	sp := newStandbyPool(10, 1, 5)
+	sp.booted(2 * time.Minute)
+	// 600 tasks per minute, 1 restart per 100 tasks: 6 restarts per minute,
+	// with 2 minutes boot time we need 12 standby VMs, limited by max.
+	for i := 0; i < 600; i++ {
+		sp.taskArrived()
+	}
+	for i := 0; i < 6; i++ {
+		sp.active++
+		sp.deactivate()
+	}
+	if old, n := sp.tune(time.Minute); old != 1 || n != 5 {
+		t.Fatalf("tune: %v -> %v, want 1 -> 5", old, n)
+	}
+	// 1 restart per 600 tasks: 1 restart per minute, 2 standby VMs.
+	for i := 0; i < 600; i++ {
+		sp.taskArrived()
+	}
+	sp.active++
+	sp.deactivate()
+	if old, n := sp.tune(time.Minute); old != 5 || n != 2 {
+		t.Fatalf("tune: %v -> %v, want 5 -> 2", old, n)
+	}
+	// No tasks, no information: keep the current number.
+	if old, n := sp.tune(time.Minute); old != 2 || n != 2 {
+		t.Fatalf("tune: %v -> %v, want 2 -> 2", old, n)
+	}
+}
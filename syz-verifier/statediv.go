@@ -0,0 +1,181 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/google/syzkaller/prog"
+)
+
+// With -state-probes, the runners snapshot the given files and directories (e.g. /proc/self/fd,
+// /proc/self/mounts or /sys/class/net) before and after each program (see ipc.FlagCollectState).
+// The change of each probe made by the program is compared between the kernels, so that programs
+// whose calls return the same states, but have different side effects (e.g. leak an fd, create
+// a mount or a network device only on some kernel) are flagged as well. The snapshots themselves
+// can't be compared, since the environments of the kernels differ.
+// Probes containing counters or timestamps (e.g. /proc/self/stat) change on every run, such
+// probes only produce noise.
+
+// stateInodeRe matches inode numbers, e.g. in "3 -> pipe:[12345]" targets of /proc/self/fd.
+var stateInodeRe = regexp.MustCompile(`:\[[0-9]+\]`)
+
+// StateDivergence describes a probe whose change differs between the kernels.
+type StateDivergence struct {
+	Probe string
+	// Delta is the change of the probe made by the program on each kernel (pool index):
+	// sorted added (+) and removed (-) lines.
+	Delta map[int][]string
+}
+
+// stateDivergence returns the probes whose change made by the program differs between the kernels.
+func stateDivergence(results []*ExecResult) []*StateDivergence {
+	var deltas []map[string][]string
+	for _, r := range results {
+		if r.Crashed || r.Hanged || r.Info.StateAfter == nil {
+			return nil
+		}
+		deltas = append(deltas, stateDelta(parseState(r.Info.StateBefore), parseState(r.Info.StateAfter)))
+	}
+	probes := make(map[string]bool)
+	for _, delta := range deltas {
+		for probe := range delta {
+			probes[probe] = true
+		}
+	}
+	var res []*StateDivergence
+	for _, probe := range sortedKeys(probes) {
+		div := &StateDivergence{Probe: probe, Delta: make(map[int][]string)}
+		diverged := false
+		for i, delta := range deltas {
+			div.Delta[results[i].Pool] = delta[probe]
+			if strings.Join(delta[probe], "\n") != strings.Join(deltas[0][probe], "\n") {
+				diverged = true
+			}
+		}
+		if diverged {
+			res = append(res, div)
+		}
+	}
+	return res
+}
+
+// parseState splits the state snapshot into the lines of each probe.
+func parseState(state []byte) map[string][]string {
+	res := make(map[string][]string)
+	probe := ""
+	for _, line := range strings.Split(string(state), "\n") {
+		if strings.HasPrefix(line, "== ") {
+			probe = line[3:]
+			res[probe] = nil
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		res[probe] = append(res[probe], stateInodeRe.ReplaceAllString(line, ":[]"))
+	}
+	return res
+}
+
+// stateDelta returns the lines added to and removed from each probe that changed.
+func stateDelta(before, after map[string][]string) map[string][]string {
+	res := make(map[string][]string)
+	for probe, lines := range after {
+		counts := make(map[string]int)
+		for _, line := range before[probe] {
+			counts[line]--
+		}
+		for _, line := range lines {
+			counts[line]++
+		}
+		var delta []string
+		for line, n := range counts {
+			for ; n > 0; n-- {
+				delta = append(delta, "+"+line)
+			}
+			for ; n < 0; n++ {
+				delta = append(delta, "-"+line)
+			}
+		}
+		if len(delta) != 0 {
+			sort.Strings(delta)
+			res[probe] = delta
+		}
+	}
+	return res
+}
+
+func sortedKeys(m map[string]bool) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// addStateDivergence records the state divergent program and returns true
+// if some of the probes diverged for the first time.
+func (stats *Stats) addStateDivergence(divs []*StateDivergence) bool {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	atomic.AddInt64(&stats.StateDivergentProgs, 1)
+	if stats.stateDivergences == nil {
+		stats.stateDivergences = make(map[string]int64)
+	}
+	added := false
+	for _, div := range divs {
+		if stats.stateDivergences[div.Probe] == 0 {
+			added = true
+		}
+		stats.stateDivergences[div.Probe]++
+	}
+	return added
+}
+
+// createStateReport creates the report of the state divergent program.
+func createStateReport(p *prog.Prog, divs []*StateDivergence) []byte {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "STATE DIVERGENCE: the calls return the same states, but their side effects differ\n\n")
+	buf.Write(p.Serialize())
+	for _, div := range divs {
+		fmt.Fprintf(buf, "\n%s:\n", div.Probe)
+		pools := make([]int, 0, len(div.Delta))
+		for pool := range div.Delta {
+			pools = append(pools, pool)
+		}
+		sort.Ints(pools)
+		for _, pool := range pools {
+			fmt.Fprintf(buf, "\tpool %d:\n", pool)
+			if len(div.Delta[pool]) == 0 {
+				fmt.Fprintf(buf, "\t\tno change\n")
+			}
+			for _, line := range div.Delta[pool] {
+				fmt.Fprintf(buf, "\t\t%s\n", line)
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+// parseStateProbes parses the comma-separated list of the -state-probes flag.
+func parseStateProbes(list string) ([]string, error) {
+	var res []string
+	for _, probe := range strings.Split(list, ",") {
+		if probe == "" {
+			continue
+		}
+		if !strings.HasPrefix(probe, "/") || strings.Contains(probe, ":") {
+			return nil, fmt.Errorf("bad state probe %q: must be an absolute path without colons", probe)
+		}
+		res = append(res, probe)
+	}
+	return res, nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/syzkaller/pkg/ipc"
+	"github.com/google/syzkaller/prog"
+)
+
+func makeStateResult(pool int, before, after string) *ExecResult {
+	r := makeExecResult(pool, []int{0, 0, 0})
+	r.Info.StateBefore, r.Info.StateAfter = []byte(before), []byte(after)
+	return r
+}
+
+func TestStateDivergence(t *testing.T) {
+	const before = "== /proc/self/fd\n0 -> /dev/null\n1 -> pipe:[100]\n== /proc/self/mounts\nproc /proc proc rw 0 0\n"
+	tests := []struct {
+		name    string
+		res     []*ExecResult
+		diverge []string
+	}{
+		{"no change", []*ExecResult{makeStateResult(0, before, before), makeStateResult(1, before, before)}, nil},
+		{"same change", []*ExecResult{
+			makeStateResult(0, before, before+"== /proc/self/fd\n3 -> socket:[123]\n"),
+			// Inode numbers differ between the kernels.
+			makeStateResult(1, before, before+"== /proc/self/fd\n3 -> socket:[456]\n"),
+		}, nil},
+		{"leaked fd", []*ExecResult{
+			makeStateResult(0, before, before),
+			makeStateResult(1, before, "== /proc/self/fd\n0 -> /dev/null\n1 -> pipe:[100]\n3 -> pipe:[101]\n"+
+				"== /proc/self/mounts\nproc /proc proc rw 0 0\n"),
+		}, []string{"/proc/self/fd"}},
+		{"removed mount", []*ExecResult{
+			makeStateResult(0, before, before),
+			makeStateResult(1, before, before),
+			makeStateResult(2, before, "== /proc/self/fd\n0 -> /dev/null\n1 -> pipe:[100]\n== /proc/self/mounts\n"),
+		}, []string{"/proc/self/mounts"}},
+		{"not collected", []*ExecResult{makeStateResult(0, before, before), makeExecResult(1, []int{0, 0, 0})}, nil},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, div := range stateDivergence(test.res) {
+				got = append(got, div.Probe)
+				if len(div.Delta) != len(test.res) {
+					t.Errorf("bad divergence: %+v", div)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(test.diverge, ",") {
+				t.Errorf("got diverging probes %v, want %v", got, test.diverge)
+			}
+		})
+	}
+}
+
+func TestParseStateProbes(t *testing.T) {
+	probes, err := parseStateProbes("/proc/self/fd,,/proc/self/mounts")
+	if err != nil || strings.Join(probes, ",") != "/proc/self/fd,/proc/self/mounts" {
+		t.Errorf("got probes %q, error %v", probes, err)
+	}
+	for _, bad := range []string{"proc/self/fd", "/proc/self/fd:/proc/self/mounts"} {
+		if _, err := parseStateProbes(bad); err == nil {
+			t.Errorf("parsed bad probes %q", bad)
+		}
+	}
+}
+
+func TestTestProgramStateDivergence(t *testing.T) {
+	p := getTestProgram(t)
+	vrf := &Verifier{
+		stats:             emptyTestStats(),
+		reruns:            2,
+		flakyRate:         defaultFlakyRate,
+		mismatchThreshold: 0.2,
+		stateProbes:       []string{"/proc/self/fd"},
+	}
+	run := func(p *prog.Prog, env EnvDescr) ([]*ExecResult, error) {
+		return []*ExecResult{
+			makeStateResult(0, "== /proc/self/fd\n", "== /proc/self/fd\n"),
+			makeStateResult(1, "== /proc/self/fd\n", "== /proc/self/fd\n3 -> anon_inode:[eventfd]\n"),
+		}, nil
+	}
+	v := vrf.testProgram(p, run)
+	if v.Mismatch || len(v.StateDivergence) != 1 || !v.NewStateDivergence {
+		t.Fatalf("bad verdict: %+v", v)
+	}
+	if v = vrf.testProgram(p, run); v.NewStateDivergence {
+		t.Errorf("the same probe diverged for the first time twice")
+	}
+	if vrf.stats.StateDivergentProgs != 2 || vrf.stats.stateDivergences["/proc/self/fd"] != 2 {
+		t.Errorf("got %v state divergent programs, %v", vrf.stats.StateDivergentProgs,
+			vrf.stats.stateDivergences)
+	}
+	report := string(createStateReport(p, v.StateDivergence))
+	if !strings.Contains(report, "\tpool 0:\n\t\tno change\n\tpool 1:\n\t\t+3 -> anon_inode:[eventfd]\n") {
+		t.Errorf("bad report:\n%s", report)
+	}
+
+	// The state is not compared for diverging programs.
+	diverging := func(p *prog.Prog, env EnvDescr) ([]*ExecResult, error) {
+		res, _ := run(p, env)
+		res[1].Info.Calls[0].Errno = 1
+		res[1].Info.Calls[0].Flags = ipc.CallExecuted | ipc.CallFinished
+		return res, nil
+	}
+	if v = vrf.testProgram(p, diverging); v.StateDivergence != nil {
+		t.Errorf("state is compared for a diverging program")
+	}
+}
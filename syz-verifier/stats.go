@@ -4,11 +4,17 @@
 package main
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/syzkaller/pkg/ipc"
 	"github.com/google/syzkaller/prog"
 )
 
@@ -22,7 +28,71 @@ type Stats struct {
 	ExecErrorProgs      int64
 	FlakyProgs          int64
 	MismatchingProgs    int64
-	StartTime           time.Time
+	// UniqueMismatches is the number of distinct mismatch signatures (see signature.go).
+	UniqueMismatches int64
+	// SuppressedProgs is the number of confirmed mismatches matching suppressions (see suppress.go).
+	SuppressedProgs int64
+	// CoverDivergentProgs is the number of programs whose coverage differs drastically
+	// between the kernels (see coverdiv.go).
+	CoverDivergentProgs int64
+	// StateDivergentProgs is the number of programs whose side effects differ
+	// between the kernels (see statediv.go).
+	StateDivergentProgs int64
+	// Confirmed mismatches rerun in clean VMs and those that did not diverge in the rerun (see cleanvm.go).
+	CleanVMReruns int64
+	CleanVMFlips  int64
+	// HubProgs is the number of programs received from syz-hub and added to the backlog (see hub.go).
+	HubProgs int64
+	// SnapshotRestores is the number of VMs restored from the snapshot instead of rebooted (see snapshot.go).
+	SnapshotRestores int64
+	// Stalled Runners whose VMs were restarted and tasks of them put back to the queues (see runnerhealth.go).
+	RunnerRestarts int64
+	RequeuedTasks  int64
+	StartTime      time.Time
+	// Task queue wait times: number of dispatched tasks, tasks that waited longer
+	// than taskStarvationTime, total and maximum wait time.
+	DispatchedTasks int64
+	StarvedTasks    int64
+	TotalTaskWait   time.Duration
+	MaxTaskWait     time.Duration
+	// Tasks that did not return a result before the deadline and those that were abandoned
+	// after all retries (see deadline.go).
+	TimedOutTasks  int64
+	AbandonedTasks int64
+	// Tasks that waited for space in a full queue and those dropped from full queues (see queuelimit.go).
+	QueueBlockedTasks int64
+	QueueDroppedTasks int64
+
+	// subsystems maps syscall names to their subsystems (see subsystem.go),
+	// it is set before the verification starts.
+	subsystems map[string]string
+	// poolVMs is the number of VMs of each kernel (pool index), it is set before the verification starts.
+	poolVMs map[int]int
+	// health tracks the state of the Runners (see runnerhealth.go), it is set before the verification starts.
+	health *vmHealth
+
+	// mu protects CallStats.States and the sums below, counters are updated atomically.
+	mu sync.Mutex
+	// Sums of the probabilities of nondeterminism of the classified programs.
+	mismatchingNondeterminism float64
+	flakyNondeterminism       float64
+	// Queue wait and execution times of the recent tasks for each environment type.
+	latency map[EnvDescr]*taskLatency
+	// PCs reached on each kernel (pool index), see coverage.go.
+	cover map[int]map[uint32]bool
+	// Representative programs for mismatching calls, see examples.go.
+	examples map[string]*mismatchExample
+	// Number of flaky programs with each cause (see classify.go).
+	flakyCauses map[string]int64
+	// Number of executed tasks and their total execution time for each kernel (see balance.go).
+	poolTasks map[int]int64
+	poolBusy  map[int]time.Duration
+	// Number of mismatching programs with each signature.
+	signatures map[string]int64
+	// Number of coverage divergent programs for each call (see coverdiv.go).
+	coverDivergences map[string]int64
+	// Number of state divergent programs for each probe (see statediv.go).
+	stateDivergences map[string]int64
 }
 
 // CallStats stores information used to generate statistics for the
@@ -38,6 +108,9 @@ type CallStats struct {
 	Occurrences int64
 	// States stores the kernel return state that caused mismatches.
 	States map[ReturnState]bool
+	// Outliers stores for each kernel (pool index) the number of mismatches
+	// in which its return state differed from the majority of kernels.
+	Outliers map[int]int64
 }
 
 // MakeStats creates a stats object.
@@ -62,6 +135,8 @@ func (stats *Stats) SetSyscallMask(calls map[*prog.Syscall]bool) {
 // supported system calls for which errno mismatches were identified in
 // the verified programs, shown in decreasing order.
 func (stats *Stats) GetTextDescription(deltaTime float64) string {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
 	var result strings.Builder
 
 	tc := stats.totalCallsExecuted()
@@ -72,14 +147,287 @@ func (stats *Stats) GetTextDescription(deltaTime float64) string {
 		stats.MismatchingProgs, stats.TotalProgs, getPercentage(stats.MismatchingProgs, stats.TotalProgs))
 	fmt.Fprintf(&result, "flaky programs: %d / total number of programs: %d (%0.2f %%)\n\n",
 		stats.FlakyProgs, stats.TotalProgs, getPercentage(stats.FlakyProgs, stats.TotalProgs))
+	if stats.SuppressedProgs != 0 {
+		fmt.Fprintf(&result, "suppressed mismatching programs: %d\n\n", stats.SuppressedProgs)
+	}
+	if stats.CoverDivergentProgs != 0 {
+		fmt.Fprintf(&result, "coverage divergent programs: %d (%s)\n\n",
+			stats.CoverDivergentProgs, formatCounts(stats.coverDivergences))
+	}
+	if stats.StateDivergentProgs != 0 {
+		fmt.Fprintf(&result, "state divergent programs: %d (%s)\n\n",
+			stats.StateDivergentProgs, formatCounts(stats.stateDivergences))
+	}
+	if stats.CleanVMReruns != 0 {
+		fmt.Fprintf(&result, "mismatches not reproduced in clean VMs: %d / mismatches rerun in clean VMs: %d (%0.2f %%)\n\n",
+			stats.CleanVMFlips, stats.CleanVMReruns, getPercentage(stats.CleanVMFlips, stats.CleanVMReruns))
+	}
+	if stats.HubProgs != 0 {
+		fmt.Fprintf(&result, "programs received from syz-hub: %d\n\n", stats.HubProgs)
+	}
+	if stats.SnapshotRestores != 0 {
+		fmt.Fprintf(&result, "VMs restored from snapshots: %d\n\n", stats.SnapshotRestores)
+	}
+	if runners := stats.health.runnerStats(); len(runners) != 0 {
+		fmt.Fprintf(&result, "runners: %s\n\n", formatRunnerStats(runners))
+	}
+	if stats.RunnerRestarts != 0 {
+		fmt.Fprintf(&result, "stalled runners restarted: %d, requeued tasks: %d\n\n",
+			stats.RunnerRestarts, stats.RequeuedTasks)
+	}
+	if len(stats.flakyCauses) != 0 {
+		fmt.Fprintf(&result, "flaky programs by cause: %s\n\n", formatCounts(stats.flakyCauses))
+	}
+	if stats.UniqueMismatches != 0 {
+		fmt.Fprintf(&result, "unique mismatches: %d / true mismatching programs: %d\n",
+			stats.UniqueMismatches, stats.MismatchingProgs)
+		for _, sig := range stats.topSignatures(reportTopMismatches) {
+			fmt.Fprintf(&result, "\t%d\t%s\n", sig.Count, sig.Signature)
+		}
+		fmt.Fprintf(&result, "\n")
+	}
+	if stats.DispatchedTasks != 0 {
+		fmt.Fprintf(&result, "task queue wait: average %v, max %v, starved tasks: %d / %d (%0.2f %%)\n\n",
+			stats.TotalTaskWait/time.Duration(stats.DispatchedTasks), stats.MaxTaskWait,
+			stats.StarvedTasks, stats.DispatchedTasks, getPercentage(stats.StarvedTasks, stats.DispatchedTasks))
+	}
+	if stats.TimedOutTasks != 0 {
+		fmt.Fprintf(&result, "timed out tasks: %d, abandoned tasks: %d\n\n",
+			stats.TimedOutTasks, stats.AbandonedTasks)
+	}
+	if stats.QueueBlockedTasks != 0 || stats.QueueDroppedTasks != 0 {
+		fmt.Fprintf(&result, "full task queues: blocked tasks: %d, dropped tasks: %d\n\n",
+			stats.QueueBlockedTasks, stats.QueueDroppedTasks)
+	}
+	if stats.mismatchingNondeterminism != 0 || stats.flakyNondeterminism != 0 {
+		var mismatching, flaky float64
+		if stats.MismatchingProgs != 0 {
+			mismatching = stats.mismatchingNondeterminism / float64(stats.MismatchingProgs)
+		}
+		if stats.FlakyProgs != 0 {
+			flaky = stats.flakyNondeterminism / float64(stats.FlakyProgs)
+		}
+		fmt.Fprintf(&result, "average probability of nondeterminism: mismatching programs: %0.4f, "+
+			"flaky programs: %0.4f\n\n", mismatching, flaky)
+	}
+	if cover := stats.coverage(deltaTime); len(cover) != 0 {
+		fmt.Fprintf(&result, "coverage (PCs): %s\n\n", formatCoverage(cover))
+	}
+	if pools := stats.poolStats(deltaTime); len(pools) != 0 {
+		fmt.Fprintf(&result, "VM utilization: %s\n\n", formatPoolStats(pools))
+	}
+	for _, lat := range stats.latencies() {
+		fmt.Fprintf(&result, "task latency (%s environment, %d tasks): wait %v, execution %v\n\n",
+			lat.Env, lat.Exec.Count, lat.Wait, lat.Exec)
+	}
+	if outliers := stats.totalOutliers(); len(outliers) != 0 {
+		fmt.Fprintf(&result, "mismatches by kernel: %s\n\n", formatOutliers(outliers))
+	}
+	if subsystems := stats.subsystemStats(); len(subsystems) != 0 {
+		result.WriteString(formatSubsystemStats(subsystems))
+	}
 	cs := stats.getOrderedStats()
 	for _, c := range cs {
 		fmt.Fprintf(&result, "%s\n", stats.getCallStatsTextDescription(c.Name))
 	}
+	if top := stats.topMismatches(); len(top) != 0 {
+		result.WriteString(formatTopMismatches(top))
+	}
 
 	return result.String()
 }
 
+// StatsJSON is the structured form of Stats for consumption by external tools.
+type StatsJSON struct {
+	StartTime           time.Time
+	Minutes             float64 // duration of the verification
+	TotalCalls          int64
+	TotalCallMismatches int64
+	TotalProgs          int64
+	ExecErrorProgs      int64
+	FlakyProgs          int64
+	MismatchingProgs    int64
+	UniqueMismatches    int64
+	SuppressedProgs     int64
+	CoverDivergentProgs int64
+	StateDivergentProgs int64
+	CleanVMReruns       int64
+	CleanVMFlips        int64
+	HubProgs            int64
+	SnapshotRestores    int64
+	RunnerRestarts      int64
+	RequeuedTasks       int64
+	ProgsPerMinute      float64
+	DispatchedTasks     int64
+	StarvedTasks        int64
+	AverTaskWait        time.Duration
+	MaxTaskWait         time.Duration
+	TimedOutTasks       int64
+	AbandonedTasks      int64
+	QueueBlockedTasks   int64
+	QueueDroppedTasks   int64
+	// Coverage contains coverage reached on each kernel (empty if coverage is not collected).
+	Coverage []*KernelCoverageJSON `json:",omitempty"`
+	// Pools contains the throughput and the utilization of the VMs of each kernel.
+	Pools []*PoolStatsJSON `json:",omitempty"`
+	// Runners contains the number of Runners of each kernel in each state.
+	Runners []*RunnerStatsJSON `json:",omitempty"`
+	// Latency contains percentiles of task latencies for each environment type.
+	Latency []*EnvLatencyJSON `json:",omitempty"`
+	// Average probabilities of nondeterminism of the classified programs.
+	MismatchingNondeterminism float64
+	FlakyNondeterminism       float64
+	// FlakyCauses is the number of flaky programs with each cause of the divergence.
+	FlakyCauses map[string]int64 `json:",omitempty"`
+	// CoverDivergences is the number of coverage divergent programs for each call.
+	CoverDivergences map[string]int64 `json:",omitempty"`
+	// StateDivergences is the number of state divergent programs for each probe.
+	StateDivergences map[string]int64 `json:",omitempty"`
+	// Outliers is the number of call mismatches for which each kernel (pool index) was the outlier.
+	Outliers map[int]int64 `json:",omitempty"`
+	// Subsystems contains statistics of the subsystems in decreasing order of the mismatch rate.
+	Subsystems []*SubsystemStatsJSON `json:",omitempty"`
+	// Calls contains all executed calls in decreasing order of the mismatch rate.
+	Calls []*CallStatsJSON
+	// Signatures contains all mismatch signatures in decreasing order of the number of programs.
+	Signatures []*SignatureJSON `json:",omitempty"`
+	// TopMismatches contains the calls with the most confirmed mismatches and their representative programs.
+	TopMismatches []*MismatchExampleJSON `json:",omitempty"`
+}
+
+// CallStatsJSON is the structured form of CallStats.
+type CallStatsJSON struct {
+	Name        string
+	Mismatches  int64
+	Occurrences int64
+	States      []*ReturnStateJSON
+	Outliers    map[int]int64 `json:",omitempty"`
+}
+
+// ReturnStateJSON is a ReturnState with its human-readable description.
+type ReturnStateJSON struct {
+	Errno       int
+	Flags       ipc.CallFlags
+	Crashed     bool
+	Outputs     string `json:",omitempty"`
+	Description string
+}
+
+// GetJSON returns the structured form of the statistics.
+func (stats *Stats) GetJSON(deltaTime float64) *StatsJSON {
+	calls := stats.callsSnapshot()
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	res := &StatsJSON{
+		StartTime:           stats.StartTime,
+		Minutes:             deltaTime,
+		TotalCalls:          stats.totalCallsExecuted(),
+		TotalCallMismatches: atomic.LoadInt64(&stats.TotalCallMismatches),
+		TotalProgs:          atomic.LoadInt64(&stats.TotalProgs),
+		ExecErrorProgs:      atomic.LoadInt64(&stats.ExecErrorProgs),
+		FlakyProgs:          stats.FlakyProgs,
+		MismatchingProgs:    stats.MismatchingProgs,
+		UniqueMismatches:    stats.UniqueMismatches,
+		SuppressedProgs:     atomic.LoadInt64(&stats.SuppressedProgs),
+		CoverDivergentProgs: atomic.LoadInt64(&stats.CoverDivergentProgs),
+		StateDivergentProgs: atomic.LoadInt64(&stats.StateDivergentProgs),
+		CleanVMReruns:       atomic.LoadInt64(&stats.CleanVMReruns),
+		CleanVMFlips:        atomic.LoadInt64(&stats.CleanVMFlips),
+		HubProgs:            atomic.LoadInt64(&stats.HubProgs),
+		SnapshotRestores:    atomic.LoadInt64(&stats.SnapshotRestores),
+		RunnerRestarts:      atomic.LoadInt64(&stats.RunnerRestarts),
+		RequeuedTasks:       atomic.LoadInt64(&stats.RequeuedTasks),
+		DispatchedTasks:     stats.DispatchedTasks,
+		StarvedTasks:        stats.StarvedTasks,
+		MaxTaskWait:         stats.MaxTaskWait,
+		TimedOutTasks:       atomic.LoadInt64(&stats.TimedOutTasks),
+		AbandonedTasks:      atomic.LoadInt64(&stats.AbandonedTasks),
+		QueueBlockedTasks:   atomic.LoadInt64(&stats.QueueBlockedTasks),
+		QueueDroppedTasks:   atomic.LoadInt64(&stats.QueueDroppedTasks),
+	}
+	if deltaTime != 0 {
+		res.ProgsPerMinute = float64(res.TotalProgs) / deltaTime
+	}
+	if stats.DispatchedTasks != 0 {
+		res.AverTaskWait = stats.TotalTaskWait / time.Duration(stats.DispatchedTasks)
+	}
+	if stats.MismatchingProgs != 0 {
+		res.MismatchingNondeterminism = stats.mismatchingNondeterminism / float64(stats.MismatchingProgs)
+	}
+	if stats.FlakyProgs != 0 {
+		res.FlakyNondeterminism = stats.flakyNondeterminism / float64(stats.FlakyProgs)
+		res.FlakyCauses = copyCounts(stats.flakyCauses)
+	}
+	if outliers := stats.totalOutliers(); len(outliers) != 0 {
+		res.Outliers = outliers
+	}
+	res.CoverDivergences = copyCounts(stats.coverDivergences)
+	res.StateDivergences = copyCounts(stats.stateDivergences)
+	res.Coverage = stats.coverage(deltaTime)
+	res.Pools = stats.poolStats(deltaTime)
+	res.Runners = stats.health.runnerStats()
+	res.Latency = stats.latencies()
+	res.Subsystems = stats.subsystemStats()
+	res.TopMismatches = stats.topMismatches()
+	res.Signatures = stats.topSignatures(0)
+	for i := range calls {
+		res.Calls = append(res.Calls, makeCallStatsJSON(&calls[i]))
+	}
+	return res
+}
+
+func makeCallStatsJSON(cs *CallStats) *CallStatsJSON {
+	call := &CallStatsJSON{
+		Name:        cs.Name,
+		Mismatches:  cs.Mismatches,
+		Occurrences: cs.Occurrences,
+		States:      []*ReturnStateJSON{},
+		Outliers:    cs.Outliers,
+	}
+	for state := range cs.States {
+		call.States = append(call.States, &ReturnStateJSON{
+			Errno:       state.Errno,
+			Flags:       state.Flags,
+			Crashed:     state.Crashed,
+			Outputs:     state.Outputs,
+			Description: state.String(),
+		})
+	}
+	sort.Slice(call.States, func(i, j int) bool {
+		return call.States[i].Description < call.States[j].Description
+	})
+	return call
+}
+
+// GetJSONDescription is the JSON counterpart of GetTextDescription.
+func (stats *Stats) GetJSONDescription(deltaTime float64) ([]byte, error) {
+	return json.MarshalIndent(stats.GetJSON(deltaTime), "", "\t")
+}
+
+// WriteCSV writes statistics of all executed calls in the CSV format, one call per row,
+// in decreasing order of the mismatch rate.
+func (stats *Stats) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"name", "occurrences", "mismatches", "mismatch_rate", "distinct_states", "states"})
+	for _, cs := range stats.callsSnapshot() {
+		var states []string
+		for state := range cs.States {
+			states = append(states, state.String())
+		}
+		sort.Strings(states)
+		cw.Write([]string{
+			cs.Name,
+			fmt.Sprint(cs.Occurrences),
+			fmt.Sprint(cs.Mismatches),
+			fmt.Sprintf("%0.2f", getPercentage(cs.Mismatches, cs.Occurrences)),
+			fmt.Sprint(len(cs.States)),
+			strings.Join(states, "; "),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
 // getCallStatsTextDescription creates a report with the current statistics for call.
 func (stats *Stats) getCallStatsTextDescription(call string) string {
 	syscallStat, ok := stats.Calls[call]
@@ -87,13 +435,44 @@ func (stats *Stats) getCallStatsTextDescription(call string) string {
 		return ""
 	}
 	syscallName, mismatches, occurrences := syscallStat.Name, syscallStat.Mismatches, syscallStat.Occurrences
+	outliers := ""
+	if len(syscallStat.Outliers) != 0 {
+		outliers = fmt.Sprintf("\t↳ mismatches by kernel: %s\n", formatOutliers(syscallStat.Outliers))
+	}
 	return fmt.Sprintf("statistics for %s:\n"+
 		"\t↳ mismatches of %s / occurrences of %s: %d / %d (%0.2f %%)\n"+
 		"\t↳ mismatches of %s / total number of mismatches: "+
 		"%d / %d (%0.2f %%)\n"+
-		"\t↳ %d distinct states identified: %v\n", syscallName, syscallName, syscallName, mismatches, occurrences,
+		"\t↳ %d distinct states identified: %v\n%s", syscallName, syscallName, syscallName, mismatches, occurrences,
 		getPercentage(mismatches, occurrences), syscallName, mismatches, stats.TotalCallMismatches,
-		getPercentage(mismatches, stats.TotalCallMismatches), len(syscallStat.States), stats.getOrderedStates(syscallName))
+		getPercentage(mismatches, stats.TotalCallMismatches), len(syscallStat.States), stats.getOrderedStates(syscallName),
+		outliers)
+}
+
+// totalOutliers returns the number of call mismatches for which each kernel was the outlier.
+// Must be called with stats.mu held.
+func (stats *Stats) totalOutliers() map[int]int64 {
+	res := make(map[int]int64)
+	for _, cs := range stats.Calls {
+		for pool, n := range cs.Outliers {
+			res[pool] += n
+		}
+	}
+	return res
+}
+
+// formatOutliers returns the outlier counts ordered by pool index, e.g. "pool 0: 3, pool 1: 10".
+func formatOutliers(outliers map[int]int64) string {
+	pools := make([]int, 0, len(outliers))
+	for pool := range outliers {
+		pools = append(pools, pool)
+	}
+	sort.Ints(pools)
+	var res []string
+	for _, pool := range pools {
+		res = append(res, fmt.Sprintf("pool %d: %d", pool, outliers[pool]))
+	}
+	return strings.Join(res, ", ")
 }
 
 func (stats *Stats) totalCallsExecuted() int64 {
@@ -119,6 +498,134 @@ func (stats *Stats) getOrderedStats() []*CallStats {
 	return css
 }
 
+// addTaskWait records the time a task waited in the queue before being dispatched to a Runner.
+func (stats *Stats) addTaskWait(wait time.Duration) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.DispatchedTasks++
+	stats.TotalTaskWait += wait
+	if stats.MaxTaskWait < wait {
+		stats.MaxTaskWait = wait
+	}
+	if wait > taskStarvationTime {
+		stats.StarvedTasks++
+	}
+}
+
+// addVerdict records classification of a divergent program.
+func (stats *Stats) addVerdict(v *Verdict) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	if v.Mismatch {
+		atomic.AddInt64(&stats.MismatchingProgs, 1)
+		stats.mismatchingNondeterminism += v.Nondeterminism
+	} else {
+		atomic.AddInt64(&stats.FlakyProgs, 1)
+		stats.flakyNondeterminism += v.Nondeterminism
+		if stats.flakyCauses == nil {
+			stats.flakyCauses = make(map[string]int64)
+		}
+		stats.flakyCauses[v.Cause]++
+	}
+}
+
+// addMismatchStates records the states that caused a mismatch of call.
+func (stats *Stats) addMismatchStates(call string, states ...ReturnState) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	cs := stats.Calls[call]
+	if cs == nil {
+		return
+	}
+	for _, state := range states {
+		cs.States[state] = true
+	}
+}
+
+// addOutliers records the kernels that were the outliers in a mismatch of call.
+func (stats *Stats) addOutliers(call string, pools ...int) {
+	if len(pools) == 0 {
+		return
+	}
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	cs := stats.Calls[call]
+	if cs == nil {
+		return
+	}
+	if cs.Outliers == nil {
+		cs.Outliers = make(map[int]int64)
+	}
+	for _, pool := range pools {
+		cs.Outliers[pool]++
+	}
+}
+
+// enabledCallStats returns statistics of the call, nil if the call is not enabled.
+func (stats *Stats) enabledCallStats(name string) *CallStats {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	return stats.Calls[name]
+}
+
+// callSnapshot returns a copy of statistics of the enabled call, false if the call is not enabled.
+func (stats *Stats) callSnapshot(name string) (CallStats, bool) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	cs := stats.Calls[name]
+	if cs == nil {
+		return CallStats{}, false
+	}
+	return copyCallStats(cs), true
+}
+
+// copyCallStats returns a copy of the call statistics.
+// Must be called with stats.mu held.
+func copyCallStats(cs *CallStats) CallStats {
+	states := make(map[ReturnState]bool, len(cs.States))
+	for state := range cs.States {
+		states[state] = true
+	}
+	var outliers map[int]int64
+	if len(cs.Outliers) != 0 {
+		outliers = make(map[int]int64, len(cs.Outliers))
+		for pool, n := range cs.Outliers {
+			outliers[pool] = n
+		}
+	}
+	return CallStats{
+		Name:        cs.Name,
+		Mismatches:  atomic.LoadInt64(&cs.Mismatches),
+		Occurrences: atomic.LoadInt64(&cs.Occurrences),
+		States:      states,
+		Outliers:    outliers,
+	}
+}
+
+// callsSnapshot returns a copy of statistics of all calls that occurred at least once,
+// in decreasing order of the mismatch rate.
+func (stats *Stats) callsSnapshot() []CallStats {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	var res []CallStats
+	for _, cs := range stats.Calls {
+		occurrences := atomic.LoadInt64(&cs.Occurrences)
+		if occurrences == 0 {
+			continue
+		}
+		res = append(res, copyCallStats(cs))
+	}
+	sort.Slice(res, func(i, j int) bool {
+		pi := getPercentage(res[i].Mismatches, res[i].Occurrences)
+		pj := getPercentage(res[j].Mismatches, res[j].Occurrences)
+		if pi != pj {
+			return pi > pj
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
 func (stats *Stats) getOrderedStates(call string) []string {
 	states := stats.Calls[call].States
 	ss := make([]string, 0, len(states))
@@ -132,3 +639,32 @@ func (stats *Stats) getOrderedStates(call string) []string {
 func getPercentage(value, total int64) float64 {
 	return float64(value) / float64(total) * 100
 }
+
+// formatCounts returns the counts ordered by key, e.g. the number of flaky programs with each cause.
+func formatCounts(counts map[string]int64) string {
+	var res []string
+	for key, count := range counts {
+		res = append(res, fmt.Sprintf("%s: %d", key, count))
+	}
+	sort.Strings(res)
+	return strings.Join(res, ", ")
+}
+
+// copyCounts returns a copy of the map (nil if it is empty).
+func copyCounts(m map[string]int64) map[string]int64 {
+	if len(m) == 0 {
+		return nil
+	}
+	res := make(map[string]int64, len(m))
+	for k, v := range m {
+		res[k] = v
+	}
+	return res
+}
+
+// flakyCausesSnapshot returns a copy of the number of flaky programs with each cause.
+func (stats *Stats) flakyCausesSnapshot() map[string]int64 {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	return copyCounts(stats.flakyCauses)
+}
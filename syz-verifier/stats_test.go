@@ -3,6 +3,9 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -17,16 +20,16 @@ func dummyStats() *Stats {
 		Calls: map[string]*CallStats{
 			"foo": {"foo", 2, 8, map[ReturnState]bool{
 				returnState(1, 7): true,
-				returnState(3, 7): true}},
+				returnState(3, 7): true}, nil},
 			"bar": {"bar", 5, 6, map[ReturnState]bool{
 				crashedReturnState(): true,
 				returnState(10, 7):   true,
-				returnState(22, 7):   true}},
+				returnState(22, 7):   true}, map[int]int64{0: 1, 1: 4}},
 			"tar": {"tar", 3, 4, map[ReturnState]bool{
 				returnState(31, 7): true,
 				returnState(17, 7): true,
-				returnState(5, 7):  true}},
-			"biz": {"biz", 0, 2, map[ReturnState]bool{}},
+				returnState(5, 7):  true}, nil},
+			"biz": {"biz", 0, 2, map[ReturnState]bool{}, nil},
 		},
 	}
 }
@@ -47,7 +50,8 @@ func TestGetCallStatsTextDescription(t *testing.T) {
 				"\t↳ mismatches of foo / occurrences of foo: 2 / 8 (25.00 %)\n" +
 				"\t↳ mismatches of foo / total number of mismatches: 2 / 10 (20.00 %)\n" +
 				"\t↳ 2 distinct states identified: " +
-				"[\"Flags: 7, Errno: 1 (operation not permitted)\" \"Flags: 7, Errno: 3 (no such process)\"]\n",
+				"[\"Flags: 7 (executed|finished|blocked), Errno: 1 EPERM (operation not permitted)\" " +
+				"\"Flags: 7 (executed|finished|blocked), Errno: 3 ESRCH (no such process)\"]\n",
 		},
 	}
 
@@ -71,26 +75,91 @@ func TestGetTextDescription(t *testing.T) {
 			"programs / minute: 2.40\n\n"+
 			"true mismatching programs: 6 / total number of programs: 24 (25.00 %)\n"+
 			"flaky programs: 4 / total number of programs: 24 (16.67 %)\n\n"+
+			"mismatches by kernel: pool 0: 1, pool 1: 4\n\n"+
 			"statistics for bar:\n"+
 			"\t↳ mismatches of bar / occurrences of bar: 5 / 6 (83.33 %)\n"+
 			"\t↳ mismatches of bar / total number of mismatches: 5 / 10 (50.00 %)\n"+
 			"\t↳ 3 distinct states identified: "+
-			"[\"Crashed\" \"Flags: 7, Errno: 10 (no child processes)\" "+
-			"\"Flags: 7, Errno: 22 (invalid argument)\"]\n\n"+
+			"[\"Crashed\" \"Flags: 7 (executed|finished|blocked), Errno: 10 ECHILD (no child processes)\" "+
+			"\"Flags: 7 (executed|finished|blocked), Errno: 22 EINVAL (invalid argument)\"]\n"+
+			"\t↳ mismatches by kernel: pool 0: 1, pool 1: 4\n\n"+
 			"statistics for tar:\n"+
 			"\t↳ mismatches of tar / occurrences of tar: 3 / 4 (75.00 %)\n"+
 			"\t↳ mismatches of tar / total number of mismatches: 3 / 10 (30.00 %)\n"+
 			"\t↳ 3 distinct states identified: "+
-			"[\"Flags: 7, Errno: 17 (file exists)\" "+
-			"\"Flags: 7, Errno: 31 (too many links)\" "+
-			"\"Flags: 7, Errno: 5 (input/output error)\"]\n\n"+
+			"[\"Flags: 7 (executed|finished|blocked), Errno: 17 EEXIST (file exists)\" "+
+			"\"Flags: 7 (executed|finished|blocked), Errno: 31 EMLINK (too many links)\" "+
+			"\"Flags: 7 (executed|finished|blocked), Errno: 5 EIO (input/output error)\"]\n\n"+
 			"statistics for foo:\n"+
 			"\t↳ mismatches of foo / occurrences of foo: 2 / 8 (25.00 %)\n"+
 			"\t↳ mismatches of foo / total number of mismatches: 2 / 10 (20.00 %)\n"+
 			"\t↳ 2 distinct states identified: "+
-			"[\"Flags: 7, Errno: 1 (operation not permitted)\" \"Flags: 7, Errno: 3 (no such process)\"]\n\n"
+			"[\"Flags: 7 (executed|finished|blocked), Errno: 1 EPERM (operation not permitted)\" "+
+			"\"Flags: 7 (executed|finished|blocked), Errno: 3 ESRCH (no such process)\"]\n\n"
 
 	if diff := cmp.Diff(want, got); diff != "" {
 		t.Errorf("s.GetTextDescription mismatch (-want +got):\n%s", diff)
 	}
 }
+
+func TestGetTextDescriptionNondeterminism(t *testing.T) {
+	stats := MakeStats()
+	stats.addVerdict(&Verdict{Cause: "errno", Nondeterminism: 0.5})
+	got := stats.GetTextDescription(float64(10))
+	want := "average probability of nondeterminism: mismatching programs: 0.0000, flaky programs: 0.5000\n"
+	if !strings.Contains(got, want) {
+		t.Errorf("s.GetTextDescription does not contain %q:\n%s", want, got)
+	}
+}
+
+func TestGetJSONDescription(t *testing.T) {
+	data, err := dummyStats().GetJSONDescription(float64(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := new(StatsJSON)
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatalf("failed to parse stats: %v\n%s", err, data)
+	}
+	if got.TotalCalls != 20 || got.TotalCallMismatches != 10 || got.TotalProgs != 24 ||
+		got.FlakyProgs != 4 || got.MismatchingProgs != 6 || got.ProgsPerMinute != 2.4 {
+		t.Errorf("bad totals: %+v", got)
+	}
+	var calls []string
+	for _, call := range got.Calls {
+		calls = append(calls, call.Name)
+	}
+	if diff := cmp.Diff([]string{"bar", "tar", "foo", "biz"}, calls); diff != "" {
+		t.Errorf("calls mismatch (-want +got):\n%s", diff)
+	}
+	want := []*ReturnStateJSON{
+		{Crashed: true, Description: "Crashed"},
+		{Errno: 10, Flags: 7, Description: "Flags: 7 (executed|finished|blocked), Errno: 10 ECHILD (no child processes)"},
+		{Errno: 22, Flags: 7, Description: "Flags: 7 (executed|finished|blocked), Errno: 22 EINVAL (invalid argument)"},
+	}
+	if diff := cmp.Diff(want, got.Calls[0].States); diff != "" {
+		t.Errorf("states mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[int]int64{0: 1, 1: 4}, got.Outliers); diff != "" {
+		t.Errorf("outliers mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := dummyStats().WriteCSV(buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "name,occurrences,mismatches,mismatch_rate,distinct_states,states\n" +
+		"bar,6,5,83.33,3,\"Crashed; Flags: 7 (executed|finished|blocked), Errno: 10 ECHILD (no child processes); " +
+		"Flags: 7 (executed|finished|blocked), Errno: 22 EINVAL (invalid argument)\"\n" +
+		"tar,4,3,75.00,3,\"Flags: 7 (executed|finished|blocked), Errno: 17 EEXIST (file exists); " +
+		"Flags: 7 (executed|finished|blocked), Errno: 31 EMLINK (too many links); " +
+		"Flags: 7 (executed|finished|blocked), Errno: 5 EIO (input/output error)\"\n" +
+		"foo,8,2,25.00,2,\"Flags: 7 (executed|finished|blocked), Errno: 1 EPERM (operation not permitted); " +
+		"Flags: 7 (executed|finished|blocked), Errno: 3 ESRCH (no such process)\"\n" +
+		"biz,2,0,0.00,0,\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("csv mismatch (-want +got):\n%s", diff)
+	}
+}
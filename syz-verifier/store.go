@@ -0,0 +1,189 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/syzkaller/pkg/db"
+	"github.com/google/syzkaller/pkg/hash"
+	"github.com/google/syzkaller/prog"
+)
+
+// Outcomes of all tested programs are stored in <workdir>/results.db, so they can be analyzed
+// with the -query flag (see query.go) without keeping them in memory. The database is SQLite
+// (see store_sqlite.go) if syz-verifier is built with cgo, which allows to query large databases
+// using indexes. Otherwise it's an append-only pkg/db file and queries scan all outcomes.
+
+// Outcome is the result of testing a single program.
+type Outcome struct {
+	// Hash of the serialized program.
+	Hash string
+	Prog string
+	// Started and Finished are the times when the first run started and the verdict was computed.
+	Started  time.Time
+	Finished time.Time
+	// Calls contains return states of each call of the program on all kernels
+	// in the last divergent run (or the last run if the program never diverged).
+	Calls []*CallReport `json:",omitempty"`
+	// Verdict is nil if the program could not be executed.
+	Verdict *Verdict `json:",omitempty"`
+}
+
+// Kind returns the verdict of the outcome: "mismatch", "flaky", "suppressed", "match" or "error".
+func (o *Outcome) Kind() string {
+	switch {
+	case o.Verdict == nil:
+		return "error"
+	case o.Verdict.Suppressed != "":
+		return "suppressed"
+	case o.Verdict.Mismatch:
+		return "mismatch"
+	case o.Verdict.Divergent != 0:
+		return "flaky"
+	default:
+		return "match"
+	}
+}
+
+func makeOutcome(p *prog.Prog, started time.Time, v *Verdict, results []*ExecResult) *Outcome {
+	data := p.Serialize()
+	o := &Outcome{
+		Hash:     hash.String(data),
+		Prog:     string(data),
+		Started:  started,
+		Finished: time.Now(),
+		Verdict:  v,
+	}
+	if v != nil && v.Results != nil {
+		results = v.Results
+	}
+	if v != nil && len(results) != 0 {
+		o.Calls = CompareResults(results, p).Reports
+	}
+	return o
+}
+
+type outcomeStore interface {
+	save(o *Outcome) error
+	// selectOutcomes calls fn for every outcome that matches the query in the order of verdicts.
+	selectOutcomes(q outcomeQuery, fn func(o *Outcome) error) error
+	count() (int, error)
+	close() error
+}
+
+// openSQLiteStore is set if syz-verifier is built with cgo.
+var openSQLiteStore func(file string) (outcomeStore, error)
+
+const sqliteMagic = "SQLite format 3\x00"
+
+// openOutcomeStore opens the database in the format of the existing file,
+// new databases are SQLite if it's available.
+func openOutcomeStore(file string) (outcomeStore, error) {
+	format, err := outcomeStoreFormat(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open results database: %v", err)
+	}
+	switch {
+	case format == "sqlite" && openSQLiteStore == nil:
+		return nil, fmt.Errorf("results database %v is an SQLite database, "+
+			"but syz-verifier is built without cgo (build it with make verifier)", file)
+	case format == "sqlite", format == "" && openSQLiteStore != nil:
+		return openSQLiteStore(file)
+	default:
+		return openDBStore(file)
+	}
+}
+
+// outcomeStoreFormat returns "sqlite" or "db" for existing databases and "" if there is no database yet.
+func outcomeStoreFormat(file string) (string, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	magic := make([]byte, len(sqliteMagic))
+	n, err := io.ReadFull(f, magic)
+	switch {
+	case n == 0:
+		return "", nil
+	case err == nil && string(magic) == sqliteMagic:
+		return "sqlite", nil
+	default:
+		return "db", nil
+	}
+}
+
+// dbStore keeps outcomes as JSON records in a pkg/db file.
+type dbStore struct {
+	mu   sync.Mutex
+	file string
+	ap   *db.Appender
+	seq  uint64
+}
+
+func openDBStore(file string) (outcomeStore, error) {
+	ap, err := db.OpenAppender(file, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open results database: %v", err)
+	}
+	return &dbStore{file: file, ap: ap}, nil
+}
+
+func (st *dbStore) save(o *Outcome) error {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	// The same program can be tested several times, so the key includes the time of the verdict.
+	key := fmt.Sprintf("%v-%v", o.Hash, o.Finished.UnixNano())
+	st.seq++
+	return st.ap.Append(key, data, st.seq)
+}
+
+func (st *dbStore) selectOutcomes(q outcomeQuery, fn func(o *Outcome) error) error {
+	return st.iterate(func(o *Outcome) error {
+		if !q.match(o) {
+			return nil
+		}
+		return fn(o)
+	})
+}
+
+func (st *dbStore) count() (int, error) {
+	n := 0
+	err := st.iterate(func(o *Outcome) error {
+		n++
+		return nil
+	})
+	return n, err
+}
+
+func (st *dbStore) iterate(fn func(o *Outcome) error) error {
+	rd, err := db.OpenReader(st.file)
+	if err != nil {
+		return err
+	}
+	return rd.Iterate(func(key string, rec db.Record) error {
+		o := new(Outcome)
+		if err := json.Unmarshal(rec.Val, o); err != nil {
+			return fmt.Errorf("failed to parse outcome %v: %v", key, err)
+		}
+		return fn(o)
+	})
+}
+
+func (st *dbStore) close() error {
+	return st.ap.Close()
+}
@@ -0,0 +1,170 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+// go-sqlite3 requires cgo, without it the results database is a pkg/db file (see store.go).
+
+//go:build cgo
+// +build cgo
+
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// The results database has a row in the outcomes table for every verdict and a row in the calls table
+// for every call of the program (return states are stored as JSON since they are only printed).
+// Timestamps are stored as Unix nanoseconds. Queries are translated to SQL (see query.go) and use
+// the indexes, so e.g. "since monday" does not scan outcomes of the whole multi-week campaign.
+const outcomeSchema = `
+CREATE TABLE IF NOT EXISTS outcomes (
+	id INTEGER PRIMARY KEY,
+	hash TEXT NOT NULL,
+	prog TEXT NOT NULL,
+	started INTEGER NOT NULL,
+	finished INTEGER NOT NULL,
+	kind TEXT NOT NULL,
+	verdict TEXT
+);
+CREATE INDEX IF NOT EXISTS outcomes_hash ON outcomes(hash);
+CREATE INDEX IF NOT EXISTS outcomes_finished ON outcomes(finished);
+CREATE INDEX IF NOT EXISTS outcomes_kind ON outcomes(kind, finished);
+CREATE TABLE IF NOT EXISTS calls (
+	outcome INTEGER NOT NULL REFERENCES outcomes(id),
+	idx INTEGER NOT NULL,
+	call TEXT NOT NULL,
+	mismatch INTEGER NOT NULL,
+	report TEXT NOT NULL,
+	PRIMARY KEY (outcome, idx)
+);
+CREATE INDEX IF NOT EXISTS calls_call ON calls(call, mismatch);
+`
+
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func init() {
+	openSQLiteStore = openSQLite
+}
+
+func openSQLite(file string) (outcomeStore, error) {
+	// WAL allows to query the database while the verifier is writing to it.
+	db, err := sql.Open("sqlite3", "file:"+file+"?_journal_mode=WAL&_synchronous=NORMAL&_busy_timeout=10000")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open results database: %v", err)
+	}
+	if _, err := db.Exec(outcomeSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open results database %v: %v", file, err)
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+func (st *sqliteStore) close() error {
+	return st.db.Close()
+}
+
+func (st *sqliteStore) save(o *Outcome) error {
+	var verdict []byte
+	if o.Verdict != nil {
+		var err error
+		if verdict, err = json.Marshal(o.Verdict); err != nil {
+			return err
+		}
+	}
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec("INSERT INTO outcomes (hash, prog, started, finished, kind, verdict) VALUES (?, ?, ?, ?, ?, ?)",
+		o.Hash, o.Prog, o.Started.UnixNano(), o.Finished.UnixNano(), o.Kind(), nullString(verdict))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for idx, cr := range o.Calls {
+		report, err := json.Marshal(cr)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO calls (outcome, idx, call, mismatch, report) VALUES (?, ?, ?, ?, ?)",
+			id, idx, cr.Call, cr.Mismatch, string(report)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func nullString(data []byte) sql.NullString {
+	return sql.NullString{String: string(data), Valid: data != nil}
+}
+
+func (st *sqliteStore) selectOutcomes(q outcomeQuery, fn func(o *Outcome) error) error {
+	where, args := q.where()
+	rows, err := st.db.Query("SELECT o.id, o.hash, o.prog, o.started, o.finished, o.verdict FROM outcomes o "+
+		"WHERE "+where+" ORDER BY o.finished, o.id", args...)
+	if err != nil {
+		return fmt.Errorf("failed to query outcomes: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, started, finished int64
+		var verdict sql.NullString
+		o := new(Outcome)
+		if err := rows.Scan(&id, &o.Hash, &o.Prog, &started, &finished, &verdict); err != nil {
+			return err
+		}
+		o.Started = time.Unix(0, started)
+		o.Finished = time.Unix(0, finished)
+		if verdict.Valid {
+			o.Verdict = new(Verdict)
+			if err := json.Unmarshal([]byte(verdict.String), o.Verdict); err != nil {
+				return fmt.Errorf("failed to parse verdict of outcome %v: %v", id, err)
+			}
+		}
+		if o.Calls, err = st.loadCalls(id); err != nil {
+			return err
+		}
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (st *sqliteStore) loadCalls(id int64) ([]*CallReport, error) {
+	rows, err := st.db.Query("SELECT report FROM calls WHERE outcome = ? ORDER BY idx", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calls: %v", err)
+	}
+	defer rows.Close()
+	var calls []*CallReport
+	for rows.Next() {
+		var report string
+		if err := rows.Scan(&report); err != nil {
+			return nil, err
+		}
+		cr := new(CallReport)
+		if err := json.Unmarshal([]byte(report), cr); err != nil {
+			return nil, fmt.Errorf("failed to parse call of outcome %v: %v", id, err)
+		}
+		calls = append(calls, cr)
+	}
+	return calls, rows.Err()
+}
+
+func (st *sqliteStore) count() (int, error) {
+	var n int
+	err := st.db.QueryRow("SELECT COUNT(*) FROM outcomes").Scan(&n)
+	return n, err
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+//go:build cgo
+// +build cgo
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestOutcomeQueryPlan checks that selective queries use the indexes instead of scanning all outcomes.
+func TestOutcomeQueryPlan(t *testing.T) {
+	st, err := openSQLite(filepath.Join(t.TempDir(), "results.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.close()
+	db := st.(*sqliteStore).db
+	for _, query := range []string{
+		"since monday",
+		"verdict mismatch and since monday",
+		"mismatch call bpf$PROG_LOAD",
+		"contains call bpf$* and before 2021-09-06",
+		"program 0123abcd",
+	} {
+		q, err := parseOutcomeQuery(query, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		where, args := q.where()
+		rows, err := db.Query("EXPLAIN QUERY PLAN SELECT o.id FROM outcomes o WHERE "+where, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var plan []string
+		for rows.Next() {
+			var id, parent, notused int
+			var detail string
+			if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+				t.Fatal(err)
+			}
+			plan = append(plan, detail)
+		}
+		rows.Close()
+		for _, step := range plan {
+			if strings.HasPrefix(step, "SCAN") {
+				t.Errorf("query %q scans a table:\n%v", query, strings.Join(plan, "\n"))
+			}
+		}
+	}
+}
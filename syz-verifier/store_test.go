@@ -0,0 +1,146 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/pkg/osutil"
+)
+
+func TestOutcomeStore(t *testing.T) {
+	backends := map[string]func(string) (outcomeStore, error){
+		"db":     openDBStore,
+		"sqlite": openSQLiteStore,
+	}
+	for name, open := range backends {
+		open := open
+		t.Run(name, func(t *testing.T) {
+			if open == nil {
+				t.Skip("syz-verifier is built without cgo")
+			}
+			testOutcomeStore(t, open)
+		})
+	}
+}
+
+func testOutcomeStore(t *testing.T, open func(string) (outcomeStore, error)) {
+	p := getTestProgram(t)
+	file := filepath.Join(t.TempDir(), "results.db")
+	st, err := open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.close()
+	started := time.Date(2021, 9, 6, 10, 0, 0, 0, time.Local)
+	mismatch := makeOutcome(p, started, &Verdict{
+		Results:   []*ExecResult{makeExecResult(0, []int{1, 3, 2}), makeExecResult(1, []int{1, 3, 5})},
+		Runs:      5,
+		Divergent: 5,
+		Mismatch:  true,
+	}, nil)
+	mismatch.Finished = time.Date(2021, 9, 6, 11, 0, 0, 0, time.Local)
+	match := makeOutcome(p, started, &Verdict{Runs: 1},
+		[]*ExecResult{makeExecResult(0, []int{1, 3, 2}), makeExecResult(1, []int{1, 3, 2})})
+	match.Finished = time.Date(2021, 9, 3, 11, 0, 0, 0, time.Local)
+	failed := makeOutcome(p, started, nil, nil)
+	failed.Finished = time.Date(2021, 9, 7, 11, 0, 0, 0, time.Local)
+	// Outcomes are saved in the order of verdicts.
+	for _, o := range []*Outcome{match, mismatch, failed} {
+		if err := st.save(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Wednesday, so "monday" is 2021-09-06.
+	now := time.Date(2021, 9, 8, 15, 0, 0, 0, time.Local)
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"verdict mismatch", []string{"mismatch"}},
+		{"mismatch call test$res0 and since monday", []string{"mismatch"}},
+		{"mismatch call minimize$*", nil},
+		{"contains call minimize$*", []string{"match", "mismatch"}},
+		{"since monday", []string{"mismatch", "error"}},
+		{"before 2021-09-06 or verdict error", []string{"match", "error"}},
+		{"not verdict match and since 2021-09-06T12:00", []string{"error"}},
+		{"program " + mismatch.Hash[:8], []string{"match", "mismatch", "error"}},
+		{"program 0123456789abcdef and verdict mismatch", nil},
+		{"program " + strings.ToUpper(match.Hash[:8]) + " and verdict match", []string{"match"}},
+	}
+	for _, test := range tests {
+		q, err := parseOutcomeQuery(test.query, now)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", test.query, err)
+		}
+		var got []string
+		err = st.selectOutcomes(q, func(o *Outcome) error {
+			got = append(got, o.Kind())
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("query %q mismatch (-want +got):\n%s", test.query, diff)
+		}
+	}
+
+	out := new(bytes.Buffer)
+	if err := runQuery(out, file, "verdict mismatch"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"2021-09-06 11:00:00 " + mismatch.Hash + " mismatch, diverged in 5 of 5 runs\n",
+		"\t[!] test$res0()\n\t\t↳ Pool: 0, Flags: 0 (not executed), Errno: 2 ENOENT (no such file or directory)\n",
+		"1/3 outcomes matched the query\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("query output misses %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestOutcomeStoreFormat(t *testing.T) {
+	dir := t.TempDir()
+	// Existing pkg/db databases are opened as is.
+	dbFile := filepath.Join(dir, "db")
+	st, err := openDBStore(dbFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.close()
+	if st, err = openOutcomeStore(dbFile); err != nil {
+		t.Fatal(err)
+	}
+	st.close()
+	if _, ok := st.(*dbStore); !ok {
+		t.Fatalf("pkg/db database is opened as %T", st)
+	}
+	// New databases are SQLite if it's available.
+	st, err = openOutcomeStore(filepath.Join(dir, "new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.close()
+	if _, isDB := st.(*dbStore); isDB != (openSQLiteStore == nil) {
+		t.Fatalf("new database is opened as %T", st)
+	}
+	// SQLite databases can't be opened without cgo.
+	sqliteFile := filepath.Join(dir, "sqlite")
+	if err := osutil.WriteFile(sqliteFile, []byte(sqliteMagic+"foo")); err != nil {
+		t.Fatal(err)
+	}
+	if openSQLiteStore == nil {
+		if _, err := openOutcomeStore(sqliteFile); err == nil || !strings.Contains(err.Error(), "without cgo") {
+			t.Fatalf("opening SQLite database without cgo: %v", err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/google/syzkaller/pkg/ast"
+)
+
+// Syscalls are grouped into subsystems by the syzlang files that describe them,
+// so that users verifying large syscall sets can see which kernel areas diverge most.
+
+// unknownSubsystem is the subsystem of syscalls not found in the descriptions.
+const unknownSubsystem = "unknown"
+
+// SubsystemStatsJSON contains aggregated statistics of the syscalls of a subsystem.
+type SubsystemStatsJSON struct {
+	Name string
+	// Calls is the number of executed syscalls, MismatchingCalls is the number of those that mismatched.
+	Calls            int
+	MismatchingCalls int
+	Occurrences      int64
+	Mismatches       int64
+}
+
+// loadSubsystems returns the subsystem of every syscall described in dir (e.g. sys/linux).
+func loadSubsystems(dir string) (map[string]string, error) {
+	var errs []string
+	desc := ast.ParseGlob(filepath.Join(dir, "*.txt"), func(pos ast.Pos, msg string) {
+		errs = append(errs, fmt.Sprintf("%v: %v", pos, msg))
+	})
+	if desc == nil {
+		return nil, fmt.Errorf("failed to parse descriptions: %v", strings.Join(errs, "; "))
+	}
+	res := make(map[string]string)
+	for _, node := range desc.Nodes {
+		if call, ok := node.(*ast.Call); ok {
+			res[call.Name.Name] = fileSubsystem(call.Pos.File)
+		}
+	}
+	return res, nil
+}
+
+// fileSubsystem returns the subsystem of the syscalls described in the file.
+// Files describing the same area (e.g. socket_inet.txt and netfilter.txt) are grouped together.
+func fileSubsystem(file string) string {
+	name := strings.TrimSuffix(filepath.Base(file), ".txt")
+	parts := strings.Split(name, "_")
+	switch parts[0] {
+	case "dev":
+		if len(parts) > 1 {
+			return parts[1]
+		}
+	case "socket", "net", "netfilter", "vnet":
+		return "net"
+	case "fs", "security", "bpf":
+		return parts[0]
+	}
+	return name
+}
+
+// subsystemStats returns statistics of the subsystems with executed syscalls
+// in decreasing order of the mismatch rate, nil if the subsystems are not known.
+// Must be called with stats.mu held.
+func (stats *Stats) subsystemStats() []*SubsystemStatsJSON {
+	if stats.subsystems == nil {
+		return nil
+	}
+	subsystems := make(map[string]*SubsystemStatsJSON)
+	for _, cs := range stats.Calls {
+		occurrences := atomic.LoadInt64(&cs.Occurrences)
+		if occurrences == 0 {
+			continue
+		}
+		name := stats.subsystems[cs.Name]
+		if name == "" {
+			name = unknownSubsystem
+		}
+		ss := subsystems[name]
+		if ss == nil {
+			ss = &SubsystemStatsJSON{Name: name}
+			subsystems[name] = ss
+		}
+		mismatches := atomic.LoadInt64(&cs.Mismatches)
+		ss.Calls++
+		ss.Occurrences += occurrences
+		ss.Mismatches += mismatches
+		if mismatches != 0 {
+			ss.MismatchingCalls++
+		}
+	}
+	var res []*SubsystemStatsJSON
+	for _, ss := range subsystems {
+		res = append(res, ss)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		ri := getPercentage(res[i].Mismatches, res[i].Occurrences)
+		rj := getPercentage(res[j].Mismatches, res[j].Occurrences)
+		if ri != rj {
+			return ri > rj
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+func formatSubsystemStats(subsystems []*SubsystemStatsJSON) string {
+	var res strings.Builder
+	res.WriteString("mismatches by subsystem:\n")
+	for _, ss := range subsystems {
+		fmt.Fprintf(&res, "\t%s: mismatches / occurrences: %d / %d (%0.2f %%), mismatching syscalls: %d / %d\n",
+			ss.Name, ss.Mismatches, ss.Occurrences, getPercentage(ss.Mismatches, ss.Occurrences),
+			ss.MismatchingCalls, ss.Calls)
+	}
+	res.WriteString("\n")
+	return res.String()
+}
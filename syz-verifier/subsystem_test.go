@@ -0,0 +1,102 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFileSubsystem(t *testing.T) {
+	tests := map[string]string{
+		"sys/linux/bpf.txt":                        "bpf",
+		"sys/linux/bpf_trace.txt":                  "bpf",
+		"sys/linux/dev_kvm.txt":                    "kvm",
+		"sys/linux/dev_snd_pcm.txt":                "snd",
+		"sys/linux/socket_inet_tcp.txt":            "net",
+		"sys/linux/netfilter_ipv4.txt":             "net",
+		"sys/linux/fs_ioctl_ext4.txt":              "fs",
+		"sys/linux/io_uring.txt":                   "io_uring",
+		"sys/linux/security_selinux.txt":           "security",
+		"sys/linux/socket_netlink_generic.txt":     "net",
+		"sys/linux/sys.txt":                        "sys",
+		"sys/linux/watch_queue.txt":                "watch_queue",
+		"sys/linux/dev.txt":                        "dev",
+		"sys/linux/vnet_mptcp.txt":                 "net",
+		"sys/linux/filesystem.txt":                 "filesystem",
+		"sys/linux/dev_infiniband_rdma_cm.txt":     "infiniband",
+		"sys/linux/socket_netlink_route_sched.txt": "net",
+	}
+	for file, want := range tests {
+		if got := fileSubsystem(file); got != want {
+			t.Errorf("%v: got subsystem %q, want %q", file, got, want)
+		}
+	}
+}
+
+func TestLoadSubsystems(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"bpf.txt":         "bpf$PROG_LOAD(cmd const[5], arg ptr[in, int32], size len[arg])\n",
+		"socket_inet.txt": "socket$inet(domain const[2], type int32, proto int32)\n",
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := loadSubsystems(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"bpf$PROG_LOAD": "bpf", "socket$inet": "net"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("subsystems (-want +got):\n%s", diff)
+	}
+	if _, err := loadSubsystems(t.TempDir()); err == nil {
+		t.Errorf("no error for a directory without descriptions")
+	}
+}
+
+func TestSubsystemStats(t *testing.T) {
+	stats := &Stats{
+		Calls: map[string]*CallStats{
+			"bpf$PROG_LOAD":  makeCallStats("bpf$PROG_LOAD", 10, 5, nil),
+			"bpf$MAP_CREATE": makeCallStats("bpf$MAP_CREATE", 10, 0, nil),
+			"socket$inet":    makeCallStats("socket$inet", 20, 2, nil),
+			"syz_foo":        makeCallStats("syz_foo", 4, 0, nil),
+			"socket$unix":    makeCallStats("socket$unix", 0, 0, nil),
+		},
+		subsystems: map[string]string{
+			"bpf$PROG_LOAD":  "bpf",
+			"bpf$MAP_CREATE": "bpf",
+			"socket$inet":    "net",
+			"socket$unix":    "net",
+		},
+	}
+	got := stats.GetJSON(1).Subsystems
+	want := []*SubsystemStatsJSON{
+		{Name: "bpf", Calls: 2, MismatchingCalls: 1, Occurrences: 20, Mismatches: 5},
+		{Name: "net", Calls: 1, MismatchingCalls: 1, Occurrences: 20, Mismatches: 2},
+		{Name: "unknown", Calls: 1, MismatchingCalls: 0, Occurrences: 4, Mismatches: 0},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("subsystem stats (-want +got):\n%s", diff)
+	}
+	text := stats.GetTextDescription(1)
+	wantText := "mismatches by subsystem:\n" +
+		"\tbpf: mismatches / occurrences: 5 / 20 (25.00 %), mismatching syscalls: 1 / 2\n" +
+		"\tnet: mismatches / occurrences: 2 / 20 (10.00 %), mismatching syscalls: 1 / 1\n" +
+		"\tunknown: mismatches / occurrences: 0 / 4 (0.00 %), mismatching syscalls: 0 / 1\n\n"
+	if !strings.Contains(text, wantText) {
+		t.Errorf("text stats do not contain subsystems:\n%s\nwant:\n%s", text, wantText)
+	}
+	if (&Stats{Calls: stats.Calls}).GetJSON(1).Subsystems != nil {
+		t.Errorf("subsystem stats without known subsystems")
+	}
+}
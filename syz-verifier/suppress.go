@@ -0,0 +1,133 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/google/syzkaller/pkg/config"
+	"github.com/google/syzkaller/prog"
+)
+
+// Known intentional differences between the kernels can be suppressed with the -suppressions file,
+// a JSON list of Suppression objects, e.g.:
+//	[
+//		{"Call": "openat\\$.*", "State": "Errno: 13 ", "Reason": "LSM denies access on the new kernel"},
+//		{"Report": "statx.*\\n.*Errno: 22", "Reason": "statx is not supported on the old kernel"}
+//	]
+// Mismatches of calls matching call suppressions are not counted in the call statistics.
+// Confirmed mismatching programs in which all mismatching calls match call suppressions or whose
+// report matches a report suppression are counted as suppressed and are not reported.
+
+// Suppression describes a known mismatch.
+type Suppression struct {
+	// Call is a regexp that matches the whole name of the mismatching call.
+	Call string `json:",omitempty"`
+	// State is a regexp that matches the return state of the mismatching call on some kernel
+	// (e.g. "Errno: 95 "), any state matches if it's empty.
+	State string `json:",omitempty"`
+	// Report is a regexp that matches the mismatch report of the program.
+	Report string `json:",omitempty"`
+	Reason string `json:",omitempty"`
+
+	call   *regexp.Regexp
+	state  *regexp.Regexp
+	report *regexp.Regexp
+}
+
+// suppressions is the list of known mismatches. All methods can be called on a nil object.
+type suppressions struct {
+	list []*Suppression
+}
+
+// loadSuppressions loads the suppressions from the file and compiles their regexps.
+func loadSuppressions(file string) (*suppressions, error) {
+	var list []*Suppression
+	if err := config.LoadFile(file, &list); err != nil {
+		return nil, fmt.Errorf("failed to load suppressions: %v", err)
+	}
+	for i, s := range list {
+		if (s.Call == "") == (s.Report == "") {
+			return nil, fmt.Errorf("suppression #%v: exactly one of Call and Report must be set", i)
+		}
+		if s.State != "" && s.Call == "" {
+			return nil, fmt.Errorf("suppression #%v: State requires Call", i)
+		}
+		var err error
+		for _, re := range []struct {
+			expr string
+			res  **regexp.Regexp
+		}{
+			{"^(?:" + s.Call + ")$", &s.call},
+			{s.State, &s.state},
+			{s.Report, &s.report},
+		} {
+			if *re.res, err = regexp.Compile(re.expr); err != nil {
+				return nil, fmt.Errorf("suppression #%v: %v", i, err)
+			}
+		}
+	}
+	return &suppressions{list}, nil
+}
+
+// matchCall returns the call suppression that matches the mismatching call, nil if there is none.
+func (ss *suppressions) matchCall(cr *CallReport) *Suppression {
+	if ss == nil {
+		return nil
+	}
+	for _, s := range ss.list {
+		if s.Call == "" || !s.call.MatchString(cr.Call) {
+			continue
+		}
+		for _, state := range cr.States {
+			if s.state.MatchString(state.String()) {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// match returns the suppression that matches the mismatching program, nil if there is none.
+func (ss *suppressions) match(p *prog.Prog, results []*ExecResult) *Suppression {
+	if ss == nil {
+		return nil
+	}
+	rr := CompareResults(results, p)
+	var matched *Suppression
+	for _, cr := range rr.Reports {
+		if !cr.Mismatch {
+			continue
+		}
+		matched = ss.matchCall(cr)
+		if matched == nil {
+			break
+		}
+	}
+	if matched != nil {
+		return matched
+	}
+	report := createReport(rr, len(results))
+	for _, s := range ss.list {
+		if s.Report != "" && s.report.Match(report) {
+			return s
+		}
+	}
+	return nil
+}
+
+func (s *Suppression) String() string {
+	what := s.Report
+	if s.Call != "" {
+		what = s.Call
+		if s.State != "" {
+			what += " " + s.State
+		}
+	}
+	if s.Reason == "" {
+		return what
+	}
+	return fmt.Sprintf("%v (%v)", what, s.Reason)
+}
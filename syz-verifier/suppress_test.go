@@ -0,0 +1,93 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/prog"
+)
+
+func TestLoadSuppressions(t *testing.T) {
+	for _, test := range []struct {
+		data string
+		ok   bool
+	}{
+		{`[{"Call": "minimize\\$.*", "State": "Errno: 1 ", "Reason": "known"}, {"Report": "test\\$res0"}]`, true},
+		{`[{"Call": "foo", "Report": "bar"}]`, false},
+		{`[{"State": "Errno: 1 "}]`, false},
+		{`[{"Call": "("}]`, false},
+		{`[{"Call": "foo", "Foo": "bar"}]`, false},
+	} {
+		file := filepath.Join(t.TempDir(), "suppressions.json")
+		if err := osutil.WriteFile(file, []byte(test.data)); err != nil {
+			t.Fatal(err)
+		}
+		_, err := loadSuppressions(file)
+		if (err == nil) != test.ok {
+			t.Errorf("%v: got error %v", test.data, err)
+		}
+	}
+}
+
+func TestSuppressMismatch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "suppressions.json")
+	data := `[
+		# minimize$0 fails with EPERM on the new kernel.
+		{"Call": "minimize\\$.*", "State": "Errno: 1 ", "Reason": "known"},
+		{"Report": "\\[!\\] test\\$res0"}
+	]`
+	if err := osutil.WriteFile(file, []byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	ss, err := loadSuppressions(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := getTestProgram(t)
+	tests := []struct {
+		name       string
+		res        []*ExecResult
+		suppressed string
+	}{
+		{"call", []*ExecResult{makeExecResult(0, []int{0, 0, 0}), makeExecResult(1, []int{0, 1, 0})},
+			`minimize\$.* Errno: 1  (known)`},
+		{"other state", []*ExecResult{makeExecResult(0, []int{0, 0, 0}), makeExecResult(1, []int{0, 2, 0})}, ""},
+		{"other call", []*ExecResult{makeExecResult(0, []int{0, 0, 0}), makeExecResult(1, []int{2, 1, 0})}, ""},
+		{"report", []*ExecResult{makeExecResult(0, []int{0, 0, 0}), makeExecResult(1, []int{0, 0, 2})},
+			`\[!\] test\$res0`},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			vrf := &Verifier{
+				stats:             emptyTestStats(),
+				reruns:            2,
+				flakyRate:         defaultFlakyRate,
+				mismatchThreshold: 0.2,
+				suppressions:      ss,
+			}
+			v := vrf.testProgram(p, func(p *prog.Prog, env EnvDescr) ([]*ExecResult, error) {
+				return test.res, nil
+			})
+			if v.Suppressed != test.suppressed || v.Mismatch != (test.suppressed == "") {
+				t.Fatalf("bad verdict: %+v, want suppression %q", v, test.suppressed)
+			}
+			wantSuppressed, wantMismatching := int64(1), int64(0)
+			if test.suppressed == "" {
+				wantSuppressed, wantMismatching = 0, 1
+			}
+			if vrf.stats.SuppressedProgs != wantSuppressed || vrf.stats.MismatchingProgs != wantMismatching {
+				t.Fatalf("got %v suppressed and %v mismatching programs", vrf.stats.SuppressedProgs,
+					vrf.stats.MismatchingProgs)
+			}
+			// Mismatches of the suppressed call are not counted.
+			if test.name == "call" && vrf.stats.Calls["minimize$0"].Mismatches != 0 {
+				t.Errorf("mismatches of the suppressed call are counted")
+			}
+		})
+	}
+}
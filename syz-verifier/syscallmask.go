@@ -0,0 +1,121 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/prog"
+)
+
+// The set of enabled system calls can be changed at runtime ("POST /api/syscalls") without
+// restarting the verifier and its VMs. New programs are generated with the new set of calls,
+// programs of the backlog with disabled calls are skipped, but the tasks that are already
+// in the queues are executed. Statistics of disabled calls are dropped.
+
+// runtimeDisabledReason is the reason of calls disabled at runtime, only such calls
+// can be enabled again (the other disabled calls are not supported by some kernels).
+const runtimeDisabledReason = "disabled at runtime"
+
+// SyscallMaskJSON is a request to change the set of enabled system calls.
+type SyscallMaskJSON struct {
+	Enable  []string `json:",omitempty"`
+	Disable []string `json:",omitempty"`
+}
+
+// enabledCalls returns the sorted names of the enabled calls.
+func (vrf *Verifier) enabledCalls() []string {
+	vrf.callsMu.Lock()
+	defer vrf.callsMu.Unlock()
+	res := make([]string, 0, len(vrf.calls))
+	for c := range vrf.calls {
+		res = append(res, c.Name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// updateSyscallMask enables and disables the calls of the request. Calls that depend
+// on the disabled calls are disabled as well. Nothing is changed if an error is returned.
+func (vrf *Verifier) updateSyscallMask(req *SyscallMaskJSON) error {
+	vrf.progGeneratorInit.Wait()
+	vrf.callsMu.Lock()
+	defer vrf.callsMu.Unlock()
+
+	calls := make(map[*prog.Syscall]bool)
+	for c := range vrf.calls {
+		calls[c] = true
+	}
+	for _, name := range req.Disable {
+		c := vrf.target.SyscallMap[name]
+		if c == nil {
+			return fmt.Errorf("unknown syscall %q", name)
+		}
+		delete(calls, c)
+	}
+	for _, name := range req.Enable {
+		c := vrf.target.SyscallMap[name]
+		if c == nil {
+			return fmt.Errorf("unknown syscall %q", name)
+		}
+		if reason, ok := vrf.reasons[c]; ok && !strings.HasPrefix(reason, runtimeDisabledReason) {
+			return fmt.Errorf("%v can't be enabled: %v", name, reason)
+		}
+		calls[c] = true
+	}
+	_, disabled := vrf.target.TransitivelyEnabledCalls(calls)
+	for _, name := range req.Enable {
+		if reason, ok := disabled[vrf.target.SyscallMap[name]]; ok {
+			return fmt.Errorf("%v can't be enabled: %v", name, reason)
+		}
+	}
+	for c := range disabled {
+		delete(calls, c)
+	}
+	if len(calls) == 0 {
+		return fmt.Errorf("all syscalls would be disabled")
+	}
+
+	var added, removed []*prog.Syscall
+	for c := range calls {
+		if !vrf.calls[c] {
+			added = append(added, c)
+			delete(vrf.reasons, c)
+		}
+	}
+	for c := range vrf.calls {
+		if calls[c] {
+			continue
+		}
+		removed = append(removed, c)
+		vrf.reasons[c] = runtimeDisabledReason
+		if reason, ok := disabled[c]; ok {
+			vrf.reasons[c] = fmt.Sprintf("%v: %v", runtimeDisabledReason, reason)
+		}
+	}
+	vrf.calls = calls
+	vrf.buildChoiceTable()
+	vrf.stats.updateSyscallMask(added, removed)
+	log.Logf(0, "syscall mask changed: %v calls enabled, %v calls disabled, %v calls are enabled now",
+		len(added), len(removed), len(calls))
+	return nil
+}
+
+// updateSyscallMask adds statistics of the enabled calls and drops statistics of the disabled calls.
+func (stats *Stats) updateSyscallMask(enabled, disabled []*prog.Syscall) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	for _, c := range enabled {
+		stats.Calls[c.Name] = &CallStats{
+			Name:   c.Name,
+			States: make(map[ReturnState]bool)}
+	}
+	for _, c := range disabled {
+		delete(stats.Calls, c.Name)
+		delete(stats.examples, c.Name)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/prog"
+)
+
+func TestUpdateSyscallMask(t *testing.T) {
+	target := prog.InitTargetTest(t, "test", "64")
+	calls := make(map[*prog.Syscall]bool)
+	for _, name := range []string{"breaks_returns", "test$res0", "test$res1"} {
+		calls[target.SyscallMap[name]] = true
+	}
+	vrf := &Verifier{
+		target:  target,
+		calls:   calls,
+		reasons: map[*prog.Syscall]string{target.SyscallMap["minimize$0"]: "not supported on kernel 1"},
+		stats:   MakeStats(),
+	}
+	vrf.stats.SetSyscallMask(calls)
+
+	// Calls that depend on the disabled calls are disabled as well.
+	if err := vrf.updateSyscallMask(&SyscallMaskJSON{Disable: []string{"test$res0"}}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"breaks_returns"}, vrf.enabledCalls()); diff != "" {
+		t.Errorf("enabled calls mismatch (-want +got):\n%s", diff)
+	}
+	if vrf.stats.enabledCallStats("test$res1") != nil {
+		t.Errorf("statistics of the disabled call are not dropped")
+	}
+	if reason := vrf.reasons[target.SyscallMap["test$res1"]]; !strings.HasPrefix(reason, runtimeDisabledReason) {
+		t.Errorf("wrong reason of the dependent call: %q", reason)
+	}
+	if vrf.choiceTable == nil || vrf.choiceTable.Enabled(target.SyscallMap["test$res0"].ID) {
+		t.Errorf("choice table is not updated")
+	}
+
+	for _, test := range []struct {
+		req *SyscallMaskJSON
+		err string
+	}{
+		{&SyscallMaskJSON{Enable: []string{"foo"}}, `unknown syscall "foo"`},
+		{&SyscallMaskJSON{Enable: []string{"minimize$0"}}, "minimize$0 can't be enabled: not supported on kernel 1"},
+		{&SyscallMaskJSON{Enable: []string{"test$res1"}}, "test$res1 can't be enabled: "},
+		{&SyscallMaskJSON{Disable: []string{"breaks_returns"}}, "all syscalls would be disabled"},
+	} {
+		err := vrf.updateSyscallMask(test.req)
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("%+v: got error %v, want %q", test.req, err, test.err)
+		}
+	}
+	if diff := cmp.Diff([]string{"breaks_returns"}, vrf.enabledCalls()); diff != "" {
+		t.Errorf("failed request changed enabled calls (-want +got):\n%s", diff)
+	}
+
+	if err := vrf.updateSyscallMask(&SyscallMaskJSON{Enable: []string{"test$res0", "test$res1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"breaks_returns", "test$res0", "test$res1"}, vrf.enabledCalls()); diff != "" {
+		t.Errorf("enabled calls mismatch (-want +got):\n%s", diff)
+	}
+	if cs := vrf.stats.enabledCallStats("test$res1"); cs == nil || cs.Occurrences != 0 {
+		t.Errorf("bad statistics of the enabled call: %+v", cs)
+	}
+	if len(vrf.reasons) != 1 {
+		t.Errorf("reasons of the enabled calls are not removed: %v", vrf.reasons)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/syzkaller/pkg/log"
+)
+
+// Snapshots of Stats can be periodically appended to a file (one JSON object per line),
+// so that the progress of the verification can be plotted over time.
+
+// StatsSample is a snapshot of the verification statistics.
+type StatsSample struct {
+	Time time.Time
+	// Minutes since the start of the verification.
+	Minutes             float64
+	TotalProgs          int64
+	ExecErrorProgs      int64
+	FlakyProgs          int64
+	MismatchingProgs    int64
+	TotalCallMismatches int64
+	// Cover is the number of PCs reached on each kernel (pool index).
+	Cover map[int]int `json:",omitempty"`
+	// Rates over the period since the previous sample.
+	ProgsPerMinute            float64
+	MismatchingProgsPerMinute float64
+	CallMismatchesPerMinute   float64
+	CoverPerMinute            map[int]float64 `json:",omitempty"`
+}
+
+// sample returns the current statistics, rates are computed relative to prev (may be nil).
+func (stats *Stats) sample(prev *StatsSample) *StatsSample {
+	now := time.Now()
+	s := &StatsSample{
+		Time:                now,
+		Minutes:             now.Sub(stats.StartTime).Minutes(),
+		TotalProgs:          atomic.LoadInt64(&stats.TotalProgs),
+		ExecErrorProgs:      atomic.LoadInt64(&stats.ExecErrorProgs),
+		FlakyProgs:          atomic.LoadInt64(&stats.FlakyProgs),
+		MismatchingProgs:    atomic.LoadInt64(&stats.MismatchingProgs),
+		TotalCallMismatches: atomic.LoadInt64(&stats.TotalCallMismatches),
+	}
+	stats.mu.Lock()
+	for pool, pcs := range stats.cover {
+		if s.Cover == nil {
+			s.Cover = make(map[int]int)
+		}
+		s.Cover[pool] = len(pcs)
+	}
+	stats.mu.Unlock()
+	if prev == nil {
+		prev = &StatsSample{Time: stats.StartTime}
+	}
+	if minutes := s.Time.Sub(prev.Time).Minutes(); minutes > 0 {
+		s.ProgsPerMinute = float64(s.TotalProgs-prev.TotalProgs) / minutes
+		s.MismatchingProgsPerMinute = float64(s.MismatchingProgs-prev.MismatchingProgs) / minutes
+		s.CallMismatchesPerMinute = float64(s.TotalCallMismatches-prev.TotalCallMismatches) / minutes
+		for pool, cover := range s.Cover {
+			if s.CoverPerMinute == nil {
+				s.CoverPerMinute = make(map[int]float64)
+			}
+			s.CoverPerMinute[pool] = float64(cover-prev.Cover[pool]) / minutes
+		}
+	}
+	return s
+}
+
+func writeStatsSample(w io.Writer, s *StatsSample) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// openTimeline opens the file with statistics snapshots for appending.
+func openTimeline(file string) (*os.File, error) {
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stats timeline: %v", err)
+	}
+	return f, nil
+}
+
+// timelineLoop appends a statistics snapshot to w every period once the verification has started.
+func (vrf *Verifier) timelineLoop(w io.Writer, period time.Duration) {
+	vrf.progGeneratorInit.Wait()
+	var prev *StatsSample
+	for range time.NewTicker(period).C {
+		s := vrf.stats.sample(prev)
+		if err := writeStatsSample(w, s); err != nil {
+			log.Logf(0, "failed to write stats snapshot: %v", err)
+		}
+		prev = s
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeline(t *testing.T) {
+	stats := dummyStats()
+	stats.StartTime = time.Now().Add(-10 * time.Minute)
+	file := filepath.Join(t.TempDir(), "timeline")
+	f, err := openTimeline(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := stats.sample(nil)
+	if first.ProgsPerMinute < 2.3 || first.ProgsPerMinute > 2.5 {
+		t.Errorf("bad progs/minute in the first sample: %v, want 2.4", first.ProgsPerMinute)
+	}
+	stats.TotalProgs += 6
+	stats.MismatchingProgs++
+	prev := *first
+	prev.Time = prev.Time.Add(-time.Minute)
+	second := stats.sample(&prev)
+	if second.ProgsPerMinute < 5.9 || second.ProgsPerMinute > 6.1 ||
+		second.MismatchingProgsPerMinute < 0.9 || second.MismatchingProgsPerMinute > 1.1 {
+		t.Errorf("bad rates in the second sample: %+v", second)
+	}
+	for _, s := range []*StatsSample{first, second} {
+		if err := writeStatsSample(f, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	// The file is appended to, not truncated.
+	f, err = openTimeline(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := writeStatsSample(f, second); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var progs []int64
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		sample := new(StatsSample)
+		if err := json.Unmarshal([]byte(line), sample); err != nil {
+			t.Fatal(err)
+		}
+		progs = append(progs, sample.TotalProgs)
+	}
+	if len(progs) != 3 || progs[0] != 24 || progs[1] != 30 || progs[2] != 30 {
+		t.Errorf("bad samples in the timeline: %v", progs)
+	}
+}
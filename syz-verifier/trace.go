@@ -0,0 +1,55 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/prog"
+)
+
+// With -traces, programs with new mismatch signatures are executed once more on all kernels
+// under strace (the runners execute syz-executor with strace -f when the task asks for it)
+// and the traces are written next to the report as result-N.kernel-K.strace, so that the
+// divergent path can be seen without rerunning the program. The traces contain the syscalls
+// of syz-executor itself too, the calls of the program are executed by its last child.
+// If strace is not available in the VM, the program is executed without it and the trace
+// contains the reason.
+
+// captureTraces executes the program under strace on all kernels and returns the traces
+// indexed by kernel, nil if the program could not be executed.
+func captureTraces(p *prog.Prog, run runFunc) [][]byte {
+	res, err := run(p, NewEnvironment)
+	if err != nil {
+		log.Logf(0, "failed to trace mismatching program: %v", err)
+		return nil
+	}
+	traces := make([][]byte, len(res))
+	for i, r := range res {
+		traces[i] = r.Trace
+	}
+	return traces
+}
+
+// saveTraces writes the traces next to the report file and removes the traces
+// of the overwritten report.
+func saveTraces(reportFile string, traces [][]byte) {
+	old, _ := filepath.Glob(reportFile + ".kernel-*.strace")
+	for _, file := range old {
+		os.Remove(file)
+	}
+	for kernel, trace := range traces {
+		if trace == nil {
+			continue
+		}
+		file := fmt.Sprintf("%v.kernel-%v.strace", reportFile, kernel)
+		if err := osutil.WriteFile(file, trace); err != nil {
+			log.Logf(0, "failed to write %v file, err %v", filepath.Base(file), err)
+		}
+	}
+}
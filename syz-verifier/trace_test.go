@@ -0,0 +1,66 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/syzkaller/pkg/ipc"
+	"github.com/google/syzkaller/pkg/osutil"
+)
+
+func TestCaptureTraces(t *testing.T) {
+	p := getTestProgram(t)
+	vrf := &Verifier{
+		resultsdir: makeTestResultDirectory(t),
+		stats:      MakeStats(),
+	}
+	makeTestQueues(vrf, 2)
+
+	done := make(chan [][]byte, 1)
+	go func() {
+		done <- captureTraces(p, vrf.RunTraced)
+	}()
+	for kernel := 0; kernel < 2; kernel++ {
+		task := vrf.GetRunnerTask(kernel, NewEnvironment)
+		if !task.Trace {
+			t.Fatalf("task of kernel %v is not traced", kernel)
+		}
+		PutExecResult(&ExecResult{
+			Pool:       kernel,
+			ExecTaskID: task.ID,
+			Info:       ipc.ProgInfo{Calls: make([]ipc.CallInfo, len(p.Calls))},
+			Trace:      []byte{byte('0' + kernel)},
+		})
+	}
+	traces := <-done
+	if len(traces) != 2 || string(traces[0]) != "0" || string(traces[1]) != "1" {
+		t.Fatalf("got traces %q", traces)
+	}
+
+	v := &Verdict{
+		Results: []*ExecResult{
+			makeExecResult(0, []int{1, 3, 2}),
+			makeExecResult(1, []int{1, 3, 5}),
+		},
+		Traces: traces,
+	}
+	vrf.SaveDiffResults(v, p)
+	for kernel, want := range []string{"0", "1"} {
+		data, err := ioutil.ReadFile(filepath.Join(vrf.resultsdir, "result-0.kernel-"+want+".strace"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("got trace %q of kernel %v, want %q", data, kernel, want)
+		}
+	}
+	// The traces of the overwritten report are removed.
+	saveTraces(filepath.Join(vrf.resultsdir, "result-0"), nil)
+	if osutil.IsExist(filepath.Join(vrf.resultsdir, "result-0.kernel-0.strace")) {
+		t.Errorf("the trace of the overwritten report was not removed")
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/syzkaller/prog"
+)
+
+// With -triage, the divergence of programs with new mismatch signatures is bisected before they
+// are written to the results dir. The calls after the first mismatching call can't affect it,
+// so the program is cut after that call, and then the earlier calls are removed one by one
+// if the mismatch still reproduces without them. The remaining earlier calls are the ones whose
+// side effects cause the divergence; if none remain, the mismatching call diverges on its own.
+
+// Triage is the result of the divergence bisection of a mismatching program.
+type Triage struct {
+	// Call is the index of the first mismatching call.
+	Call int
+	// Causes are the indices of the earlier calls needed to reproduce the mismatch.
+	Causes []int `json:",omitempty"`
+	// Sequence is the minimal call sequence that reproduces the mismatch.
+	Sequence string
+	Runs     int
+}
+
+// triageMismatch bisects the divergence of the mismatching program.
+// Returns nil if the mismatch doesn't reproduce with the calls up to the first mismatching call.
+func triageMismatch(p *prog.Prog, v *Verdict, run runFunc) *Triage {
+	call := -1
+	for i, cr := range CompareResults(v.Results, p).Reports {
+		if cr.Mismatch {
+			call = i
+			break
+		}
+	}
+	if call == -1 {
+		return nil
+	}
+	tr := &Triage{Call: call}
+	reproduces := func(p1 *prog.Prog) bool {
+		tr.Runs++
+		return reproduceMismatch(p1, v.Signature, run) != nil
+	}
+	seq := p.Clone()
+	for len(seq.Calls) > call+1 {
+		seq.RemoveCall(len(seq.Calls) - 1)
+	}
+	if !reproduces(seq) {
+		return nil
+	}
+	// Indices of the remaining calls in the original program.
+	indices := make([]int, call+1)
+	for i := range indices {
+		indices[i] = i
+	}
+	for i := call - 1; i >= 0; i-- {
+		candidate := seq.Clone()
+		candidate.RemoveCall(i)
+		if !reproduces(candidate) {
+			continue
+		}
+		seq = candidate
+		indices = append(indices[:i], indices[i+1:]...)
+	}
+	tr.Causes = indices[:len(indices)-1]
+	tr.Sequence = string(seq.Serialize())
+	return tr
+}
+
+// reproduceMismatch runs the program once and returns the results if it diverges with the signature.
+func reproduceMismatch(p *prog.Prog, sig string, run runFunc) []*ExecResult {
+	res, err := run(p, NewEnvironment)
+	if err != nil || !diverges(res) || mismatchSignature(p, res) != sig {
+		return nil
+	}
+	return res
+}
+
+// formatTriage returns the divergence analysis for the mismatch report.
+func formatTriage(tr *Triage, calls []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Divergence analysis (%v runs):\n", tr.Runs)
+	if len(tr.Causes) == 0 {
+		fmt.Fprintf(&b, "\tcall #%v diverges on its own\n", tr.Call)
+	} else {
+		fmt.Fprintf(&b, "\tcall #%v diverges after the side effects of:\n", tr.Call)
+		for _, idx := range tr.Causes {
+			fmt.Fprintf(&b, "\t\t#%v: %s\n", idx, calls[idx])
+		}
+	}
+	fmt.Fprintf(&b, "\tminimal call sequence:\n")
+	for _, line := range strings.Split(strings.TrimSuffix(tr.Sequence, "\n"), "\n") {
+		fmt.Fprintf(&b, "\t\t%s\n", line)
+	}
+	return b.String()
+}
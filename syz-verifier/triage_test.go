@@ -0,0 +1,68 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/syzkaller/prog"
+)
+
+func TestTriageMismatch(t *testing.T) {
+	target := prog.InitTargetTest(t, "test", "64")
+	p, err := target.Deserialize([]byte("test$res0()\nbreaks_returns()\nminimize$0(0x1, 0x1)\nbreaks_returns()\n"),
+		prog.Strict)
+	if err != nil {
+		t.Fatalf("failed to deserialise test program: %v", err)
+	}
+	// minimize$0 fails with EPERM on the second kernel only after test$res0.
+	run := func(p *prog.Prog, env EnvDescr) ([]*ExecResult, error) {
+		var errnos0, errnos1 []int
+		res0 := false
+		for _, c := range p.Calls {
+			errnos0 = append(errnos0, 0)
+			switch {
+			case c.Meta.Name == "test$res0":
+				res0 = true
+				errnos1 = append(errnos1, 0)
+			case c.Meta.Name == "minimize$0" && res0:
+				errnos1 = append(errnos1, 1)
+			default:
+				errnos1 = append(errnos1, 0)
+			}
+		}
+		return []*ExecResult{makeExecResult(0, errnos0), makeExecResult(1, errnos1)}, nil
+	}
+	res, _ := run(p, NewEnvironment)
+	v := &Verdict{Results: res, Mismatch: true, Signature: mismatchSignature(p, res)}
+
+	got := triageMismatch(p, v, run)
+	want := &Triage{
+		Call:     2,
+		Causes:   []int{0},
+		Sequence: "test$res0()\nminimize$0(0x1, 0x1)\n",
+		Runs:     3,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("triage mismatch (-want +got):\n%s", diff)
+	}
+	report := formatTriage(got, strings.Split(string(p.Serialize()), "\n"))
+	if !strings.Contains(report, "call #2 diverges after the side effects of:\n\t\t#0: test$res0()\n") {
+		t.Errorf("bad divergence analysis:\n%v", report)
+	}
+
+	// The mismatch doesn't reproduce without the calls after the mismatching one.
+	flaky := func(p1 *prog.Prog, env EnvDescr) ([]*ExecResult, error) {
+		if len(p1.Calls) == len(p.Calls) {
+			return run(p1, env)
+		}
+		errnos := make([]int, len(p1.Calls))
+		return []*ExecResult{makeExecResult(0, errnos), makeExecResult(1, errnos)}, nil
+	}
+	if got := triageMismatch(p, v, flaky); got != nil {
+		t.Errorf("got triage of the mismatch that doesn't reproduce: %+v", got)
+	}
+}
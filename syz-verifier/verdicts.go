@@ -0,0 +1,119 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// With the -verdicts flag the verdict of every tested program is streamed as one JSON object
+// per line (VerdictLine), so that downstream pipelines can consume results incrementally.
+
+// VerdictLine is the machine-readable verdict of a single program.
+type VerdictLine struct {
+	Hash     string
+	Prog     string
+	Started  time.Time
+	Finished time.Time
+	// Verdict is "match", "mismatch", "flaky", "suppressed" or "error" (the program could not be executed).
+	Verdict        string
+	Runs           int     `json:",omitempty"`
+	Divergent      int     `json:",omitempty"`
+	Nondeterminism float64 `json:",omitempty"`
+	// Cause is the cause of the divergence of a flaky program (see classify.go).
+	Cause string `json:",omitempty"`
+	// Calls contains return states of each call in the last divergent run
+	// (or the last run if the program never diverged).
+	Calls []*VerdictCall `json:",omitempty"`
+	// CoverDivergence contains the calls whose coverage differs drastically between the kernels
+	// (see coverdiv.go).
+	CoverDivergence []*CoverDivergence `json:",omitempty"`
+	// StateDivergence contains the probes whose change differs between the kernels
+	// (see statediv.go).
+	StateDivergence []*StateDivergence `json:",omitempty"`
+}
+
+// VerdictCall contains return states of a call on all kernels.
+type VerdictCall struct {
+	Call     string
+	Mismatch bool
+	// States are return states on each kernel ordered by pool index.
+	States []*ReturnStateJSON
+	// Outliers are the kernels whose states differ from the majority (see outlierPools).
+	Outliers []int `json:",omitempty"`
+}
+
+func makeVerdictLine(o *Outcome) *VerdictLine {
+	line := &VerdictLine{
+		Hash:     o.Hash,
+		Prog:     o.Prog,
+		Started:  o.Started,
+		Finished: o.Finished,
+		Verdict:  o.Kind(),
+	}
+	if v := o.Verdict; v != nil {
+		line.Runs, line.Divergent, line.Nondeterminism = v.Runs, v.Divergent, v.Nondeterminism
+		line.Cause = v.Cause
+		line.CoverDivergence = v.CoverDivergence
+		line.StateDivergence = v.StateDivergence
+	}
+	line.Calls = makeVerdictCalls(o.Calls)
+	return line
+}
+
+// makeVerdictCalls returns the return states of the calls ordered by pool index.
+func makeVerdictCalls(reports []*CallReport) []*VerdictCall {
+	var res []*VerdictCall
+	for _, cr := range reports {
+		call := &VerdictCall{Call: cr.Call, Mismatch: cr.Mismatch, Outliers: cr.Outliers}
+		pools := make([]int, 0, len(cr.States))
+		for pool := range cr.States {
+			pools = append(pools, pool)
+		}
+		sort.Ints(pools)
+		for _, pool := range pools {
+			state := cr.States[pool]
+			call.States = append(call.States, &ReturnStateJSON{
+				Errno:       state.Errno,
+				Flags:       state.Flags,
+				Crashed:     state.Crashed,
+				Outputs:     state.Outputs,
+				Description: state.String(),
+			})
+		}
+		res = append(res, call)
+	}
+	return res
+}
+
+type verdictStream struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// openVerdictStream opens the file for appending verdicts, "-" means stdout.
+func openVerdictStream(file string) (*verdictStream, error) {
+	var w io.Writer = os.Stdout
+	if file != "-" {
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open verdicts stream: %v", err)
+		}
+		w = f
+	}
+	return &verdictStream{enc: json.NewEncoder(w)}, nil
+}
+
+func (vs *verdictStream) write(o *Outcome) error {
+	line := makeVerdictLine(o)
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	return vs.enc.Encode(line)
+}
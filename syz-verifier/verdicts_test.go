@@ -0,0 +1,75 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestVerdictStream(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "verdicts")
+	vs, err := openVerdictStream(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := getTestProgram(t)
+	res := []*ExecResult{
+		makeExecResult(0, []int{1, 3, 2}),
+		makeExecResult(1, []int{1, 3, 5}),
+	}
+	outcomes := []*Outcome{
+		makeOutcome(p, time.Now(), &Verdict{Results: res, Runs: 10, Divergent: 10, Mismatch: true}, nil),
+		makeOutcome(p, time.Now(), nil, nil),
+	}
+	for _, o := range outcomes {
+		if err := vs.write(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines []*VerdictLine
+	for s := bufio.NewScanner(f); s.Scan(); {
+		line := new(VerdictLine)
+		if err := json.Unmarshal(s.Bytes(), line); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("got %v verdicts, want 2", len(lines))
+	}
+	if got := lines[0]; got.Verdict != "mismatch" || got.Runs != 10 || got.Prog != string(p.Serialize()) ||
+		got.Hash != outcomes[0].Hash {
+		t.Errorf("bad mismatch verdict: %+v", got)
+	}
+	wantCall := &VerdictCall{
+		Call:     "test$res0",
+		Mismatch: true,
+		States: []*ReturnStateJSON{
+			{Errno: 2, Description: "Flags: 0 (not executed), Errno: 2 ENOENT (no such file or directory)"},
+			{Errno: 5, Description: "Flags: 0 (not executed), Errno: 5 EIO (input/output error)"},
+		},
+		Outliers: []int{1},
+	}
+	if calls := lines[0].Calls; len(calls) != 3 {
+		t.Errorf("got %v calls, want 3", len(calls))
+	} else if diff := cmp.Diff(wantCall, calls[2]); diff != "" {
+		t.Errorf("call mismatch (-want +got):\n%s", diff)
+	}
+	if got := lines[1]; got.Verdict != "error" || len(got.Calls) != 0 {
+		t.Errorf("bad error verdict: %+v", got)
+	}
+}
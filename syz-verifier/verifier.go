@@ -16,8 +16,10 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/google/syzkaller/pkg/csource"
 	"github.com/google/syzkaller/pkg/instance"
 	"github.com/google/syzkaller/pkg/log"
+	"github.com/google/syzkaller/pkg/mgrconfig"
 	"github.com/google/syzkaller/pkg/osutil"
 	"github.com/google/syzkaller/pkg/rpctype"
 	"github.com/google/syzkaller/prog"
@@ -26,8 +28,7 @@ import (
 
 // Verifier TODO.
 type Verifier struct {
-	pools  map[int]*poolInfo
-	vmStop chan bool
+	pools map[int]*poolInfo
 	// Location of a working directory for all VMs for the syz-verifier process.
 	// Outputs here include:
 	// - <workdir>/crashes/<OS-Arch>/*: crash output files grouped by OS/Arch
@@ -38,45 +39,142 @@ type Verifier struct {
 	crashdir          string
 	resultsdir        string
 	target            *prog.Target
-	runnerBin         string
-	executorBin       string
 	progGeneratorInit sync.WaitGroup
 	choiceTable       *prog.ChoiceTable
 	progIdx           int
 	addr              string
-	srv               *RPCServer
-	calls             map[*prog.Syscall]bool
-	reasons           map[*prog.Syscall]string
-	reportReasons     bool
-	stats             *Stats
-	statsWrite        io.Writer
-	newEnv            bool
-	reruns            int
+	tls               bool
+	// rpcToken is the token runners must present (optional), rpcTokenFile is the file it was read from
+	// that is copied into VMs.
+	rpcTokenFile string
+	rpcToken     string
+	// remotePools are the kernels whose VMs run on remote hosts (see remote.go).
+	remotePools []int
+	srv         *RPCServer
+	// callsMu protects calls, reasons and choiceTable that can be changed at runtime
+	// after the program generator is initialized (see syscallmask.go).
+	callsMu       sync.Mutex
+	calls         map[*prog.Syscall]bool
+	reasons       map[*prog.Syscall]string
+	reportReasons bool
+	stats         *Stats
+	statsWrite    io.Writer
+	statsJSON     bool   // write stats in JSON instead of text
+	statsCSV      string // if set, per-call stats are written to this CSV file at exit
+	reports       *reportSinks
+	health        *vmHealth
+	newEnv        bool
+	reruns        int
+	// Parameters of the flaky programs classifier (see classify.go).
+	flakyRate         float64
+	mismatchThreshold float64
+	rerunPolicy       string
+	agreement         float64
+	// recorder is set if all executed programs and their results are recorded for replay.
+	recorder *recorder
+	// store is set if outcomes of all tested programs are saved to the results database.
+	store outcomeStore
+	// verdicts is set if verdicts of all tested programs are streamed in the JSONL format.
+	verdicts *verdictStream
+	// checkpointFile is set if the verification state is periodically saved (see checkpoint.go).
+	checkpointFile   string
+	checkpointPeriod time.Duration
+	// verified is set if programs that were already tested are skipped.
+	verified *progSet
+	// progress is set if verified programs are logged to resume the verification after a restart
+	// (see progress.go).
+	progress *progressLog
+	// corpusFile is set if programs of a syz-db corpus are verified first (see backlog.go).
+	corpusFile string
+	// backlog contains programs of the corpus and of the tasks restored from the checkpoint.
+	backlogMu sync.Mutex
+	backlog   []*prog.Prog
+	// minimize is set if programs with new mismatch signatures are minimized (see minimize.go).
+	minimize bool
+	// triage is set if the divergence of programs with new mismatch signatures is bisected
+	// (see triage.go).
+	triage bool
+	// guided is set if mismatching programs guide the program generation (see guided.go).
+	guided *guidedCorpus
+	// suppressions is set if known mismatches are suppressed (see suppress.go).
+	suppressions *suppressions
+	// compareOutputs is set if the outputs of the calls are compared in addition to errno
+	// (see outputs.go).
+	compareOutputs bool
+	// coverDivergence is the coverage ratio at which programs that did not diverge are flagged
+	// as coverage divergent, 0 if the coverage is not compared (see coverdiv.go).
+	coverDivergence float64
+	// stateProbes are the files and directories whose change made by the programs that did not
+	// diverge is compared (see statediv.go).
+	stateProbes []string
+	// cleanVMRerun is set if confirmed mismatches are rerun in clean VMs (see cleanvm.go).
+	cleanVMRerun bool
+	// notifier is set if new unique mismatches are notified (see notify.go).
+	notifier *notifier
+	// dash is set if new unique mismatches are uploaded to the dashboard (see dashboard.go).
+	dash *dashReporter
+	// cRepro is set if C reproducers are written for new unique mismatches (see crepro.go).
+	cRepro *csource.Options
+	// traces is set if new unique mismatches are traced with strace on all kernels (see trace.go).
+	traces bool
+	// snapshots is set if the VMs of local kernels are restored from the snapshot taken after boot
+	// instead of being rebooted (see snapshot.go).
+	snapshots bool
+	// sandboxes are the executor sandboxes new unique mismatches are rerun in (see sandbox.go).
+	sandboxes []string
+	// Tasks that did not return a result within taskTimeout are retried taskRetries times,
+	// if taskTimeout is not 0 (see deadline.go).
+	taskTimeout      time.Duration
+	taskRetries      int
+	taskRetryBackoff time.Duration
+	// The VMs of the Runners that did not respond within runnerTimeout are restarted,
+	// if runnerTimeout is not 0 (see runnerhealth.go).
+	runnerTimeout time.Duration
+	// prioritize is set if tasks of programs that are more likely to diverge are dispatched first
+	// (see priority.go).
+	prioritize bool
+	// errnoMaps translate errnos returned by kernels of other architectures (pool index)
+	// to the errnos of the verified target (see crossarch.go).
+	errnoMaps map[int]map[int]int
 
 	// We use single queue for every kernel environment.
-	tasksMutex     sync.Mutex
-	onTaskAdded    *sync.Cond
+	tasksMutex    sync.Mutex
+	onTaskAdded   *sync.Cond
+	onTaskRemoved *sync.Cond
+	// paused is set if the program generation and the dispatch of tasks are paused (see pause.go).
+	paused         bool
+	onResumed      *sync.Cond
 	kernelEnvTasks [][]*ExecTaskQueue
+	// queueCapacity limits the number of tasks in each queue if not 0 (see queuelimit.go).
+	queueCapacity int
+	queuePolicy   string
+	// cleanVMReboots is the number of VMs of each kernel rebooted to execute CleanVMEnvironment tasks.
+	cleanVMReboots map[int]int
 }
 
 func (vrf *Verifier) Init() {
 	vrf.progGeneratorInit.Add(1)
+	vrf.initQueues(len(vrf.pools))
 
-	vrf.onTaskAdded = sync.NewCond(&vrf.tasksMutex)
+	srv, err := startRPCServer(vrf)
+	if err != nil {
+		log.Fatalf("failed to initialise RPC server: %v", err)
+	}
+	vrf.srv = srv
+}
 
-	vrf.kernelEnvTasks = make([][]*ExecTaskQueue, len(vrf.pools))
+// initQueues creates the task queues for all kernels and environments.
+func (vrf *Verifier) initQueues(kernels int) {
+	vrf.onTaskAdded = sync.NewCond(&vrf.tasksMutex)
+	vrf.onTaskRemoved = sync.NewCond(&vrf.tasksMutex)
+	vrf.onResumed = sync.NewCond(&vrf.tasksMutex)
+	vrf.kernelEnvTasks = make([][]*ExecTaskQueue, kernels)
 	for i := range vrf.kernelEnvTasks {
 		vrf.kernelEnvTasks[i] = make([]*ExecTaskQueue, EnvironmentsCount)
 		for j := range vrf.kernelEnvTasks[i] {
 			vrf.kernelEnvTasks[i][j] = MakeExecTaskQueue()
 		}
 	}
-
-	srv, err := startRPCServer(vrf)
-	if err != nil {
-		log.Fatalf("failed to initialise RPC server: %v", err)
-	}
-	vrf.srv = srv
 }
 
 func (vrf *Verifier) StartProgramsAnalysis() {
@@ -84,15 +182,23 @@ func (vrf *Verifier) StartProgramsAnalysis() {
 		vrf.progGeneratorInit.Wait()
 
 		type AnalysisResult struct {
-			Diff []*ExecResult
-			Prog *prog.Prog
+			Verdict *Verdict
+			Prog    *prog.Prog
 		}
 
 		results := make(chan *AnalysisResult)
 		go func() {
 			for result := range results {
-				if result.Diff != nil {
-					vrf.SaveDiffResults(result.Diff, result.Prog)
+				if v := result.Verdict; v != nil && v.Mismatch && v.NewSignature {
+					vrf.SaveDiffResults(v, result.Prog)
+					vrf.notifier.notify(v, result.Prog, len(vrf.pools))
+					vrf.dash.report(v, result.Prog, len(vrf.pools))
+				}
+				if v := result.Verdict; v != nil && v.NewCoverDivergence {
+					vrf.SaveCoverDivergence(v, result.Prog)
+				}
+				if v := result.Verdict; v != nil && v.NewStateDivergence {
+					vrf.SaveStateDivergence(v, result.Prog)
 				}
 			}
 		}()
@@ -100,24 +206,59 @@ func (vrf *Verifier) StartProgramsAnalysis() {
 		for i := 0; i < 100; i++ {
 			go func() {
 				for {
-					prog := vrf.generate()
-					results <- &AnalysisResult{
-						vrf.TestProgram(prog),
-						prog,
+					vrf.waitResumed()
+					prog := vrf.popBacklog()
+					if prog == nil {
+						prog = vrf.generate()
+						if !vrf.verified.add(prog) {
+							continue
+						}
+					}
+					v := vrf.TestProgram(prog)
+					if v != nil && v.Mismatch {
+						vrf.guided.add(prog)
+					}
+					if v != nil && v.Mismatch && v.NewSignature && vrf.minimize {
+						prog, v = vrf.minimizeMismatch(prog, v, vrf.Run)
 					}
+					if v != nil && v.Mismatch && v.NewSignature && vrf.triage {
+						v.Triage = triageMismatch(prog, v, vrf.Run)
+					}
+					if v != nil && v.Mismatch && v.NewSignature && len(vrf.sandboxes) != 0 {
+						v.Sandboxes = compareSandboxes(prog, v, vrf.sandboxes, vrf.RunInSandbox)
+					}
+					if v != nil && v.Mismatch && v.NewSignature && vrf.traces {
+						v.Traces = captureTraces(prog, vrf.RunTraced)
+					}
+					results <- &AnalysisResult{v, prog}
 				}
 			}()
 		}
 	}()
 }
 
+// GetRunnerTask returns a task for a Runner that can execute tasks in the existing environment
+// or the less demanding ones. Returns nil if the VM must be rebooted to execute CleanVMEnvironment tasks.
 func (vrf *Verifier) GetRunnerTask(kernel int, existing EnvDescr) *rpctype.ExecTask {
 	vrf.tasksMutex.Lock()
 	defer vrf.tasksMutex.Unlock()
 
 	for {
+		if vrf.paused {
+			vrf.onResumed.Wait()
+			continue
+		}
+		if existing < CleanVMEnvironment && vrf.needCleanVMReboot(kernel) {
+			return nil
+		}
 		for env := existing; env >= AnyEnvironment; env-- {
 			if task, ok := vrf.kernelEnvTasks[kernel][env].PopTask(); ok {
+				vrf.onTaskRemoved.Broadcast()
+				task.DispatchTime = time.Now()
+				if vrf.taskTimeout != 0 {
+					task.Deadline = task.DispatchTime.Add(vrf.taskTimeout)
+				}
+				vrf.stats.addTaskWait(task.DispatchTime.Sub(task.CreationTime))
 				return task.ToRPC()
 			}
 		}
@@ -126,47 +267,179 @@ func (vrf *Verifier) GetRunnerTask(kernel int, existing EnvDescr) *rpctype.ExecT
 	}
 }
 
+// PutExecResult delivers the result to the task. Results of tasks that already have a result
+// (e.g. of a retried task, see deadline.go) or do not exist anymore are dropped.
 func PutExecResult(result *ExecResult) {
 	c := GetExecResultChan(result.ExecTaskID)
-	c <- result
+	select {
+	case c <- result:
+	default:
+		log.Logf(1, "dropped result of task %v from pool %v", result.ExecTaskID, result.Pool)
+	}
 }
 
-// TestProgram return the results slice if some exec diff was found.
-func (vrf *Verifier) TestProgram(prog *prog.Prog) (result []*ExecResult) {
-	steps := []EnvDescr{
-		NewEnvironment,
-		NewEnvironment,
+// TestProgram runs the program on all kernels and classifies the divergence of the results, if any.
+// Returns nil if the program could not be executed.
+func (vrf *Verifier) TestProgram(p *prog.Prog) *Verdict {
+	run := vrf.Run
+	var rec *Record
+	if vrf.recorder != nil {
+		rec = &Record{Prog: string(p.Serialize())}
+		run = func(p *prog.Prog, env EnvDescr) ([]*ExecResult, error) {
+			res, err := vrf.Run(p, env)
+			rec.addStep(env, res, err)
+			return res, err
+		}
+	}
+	var last []*ExecResult
+	if vrf.store != nil || vrf.verdicts != nil {
+		run0 := run
+		run = func(p *prog.Prog, env EnvDescr) ([]*ExecResult, error) {
+			res, err := run0(p, env)
+			last = res
+			return res, err
+		}
+	}
+	started := time.Now()
+	v := vrf.testProgram(p, run)
+	if v != nil {
+		if err := vrf.progress.add(p, v); err != nil {
+			log.Logf(0, "failed to log verified program: %v", err)
+		}
+	}
+	if rec != nil {
+		rec.Verdict = v
+		if err := vrf.recorder.write(rec); err != nil {
+			log.Logf(0, "failed to record program: %v", err)
+		}
+	}
+	if vrf.store != nil || vrf.verdicts != nil {
+		o := makeOutcome(p, started, v, last)
+		if vrf.store != nil {
+			if err := vrf.store.save(o); err != nil {
+				log.Logf(0, "failed to save program outcome: %v", err)
+			}
+		}
+		if vrf.verdicts != nil {
+			if err := vrf.verdicts.write(o); err != nil {
+				log.Logf(0, "failed to write program verdict: %v", err)
+			}
+		}
 	}
+	return v
+}
+
+// runFunc executes the program on all kernels in the environment.
+type runFunc func(p *prog.Prog, env EnvDescr) ([]*ExecResult, error)
 
+// testProgram computes the verdict for the program using run to execute it.
+func (vrf *Verifier) testProgram(prog *prog.Prog, run runFunc) *Verdict {
 	defer atomic.AddInt64(&vrf.stats.TotalProgs, 1)
 
-	for i, env := range steps {
-		stepRes, err := vrf.Run(prog, env)
+	v := new(Verdict)
+	var first []*ExecResult
+	for {
+		res, err := run(prog, NewEnvironment)
 		if err != nil {
-			atomic.AddInt64(&vrf.stats.ExecErrorProgs, 1)
-			return
-		}
-		vrf.AddCallsExecutionStat(stepRes, prog)
-		if stepRes[0].IsEqual(stepRes[1]) {
-			if i != 0 {
-				atomic.AddInt64(&vrf.stats.FlakyProgs, 1)
+			if v.Divergent == 0 {
+				atomic.AddInt64(&vrf.stats.ExecErrorProgs, 1)
+				return nil
 			}
-			return
+			// The divergence can't be confirmed without the reruns.
+			v.Cause = flakyCauseCrash
+			break
+		}
+		if first == nil {
+			first = res
 		}
-		if i == len(steps)-1 {
-			atomic.AddInt64(&vrf.stats.MismatchingProgs, 1)
-			return stepRes
+		vrf.AddCallsExecutionStat(res, prog)
+		if vrf.classify(v, res) {
+			break
+		}
+	}
+	if v.Mismatch {
+		if s := vrf.suppressions.match(prog, v.Results); s != nil {
+			v.Mismatch = false
+			v.Suppressed = s.String()
+			atomic.AddInt64(&vrf.stats.SuppressedProgs, 1)
+			return v
+		}
+	}
+	if v.Mismatch && vrf.cleanVMRerun {
+		vrf.rerunInCleanVM(v, func(env EnvDescr) ([]*ExecResult, error) {
+			return run(prog, env)
+		})
+	}
+	if v.Divergent != 0 && !v.Mismatch && v.Cause == "" {
+		v.Cause = flakyCause(v)
+	}
+	if v.Divergent != 0 {
+		vrf.stats.addVerdict(v)
+	}
+	if v.Mismatch {
+		vrf.stats.addMismatchExamples(prog, v.Results)
+		v.Signature = mismatchSignature(prog, v.Results)
+		v.NewSignature = vrf.stats.addSignature(v.Signature) && !vrf.progress.knownSignature(v.Signature)
+	}
+	if v.Divergent == 0 && vrf.coverDivergence != 0 {
+		v.CoverDivergence = coverDivergence(prog, first, vrf.coverDivergence)
+		if len(v.CoverDivergence) != 0 {
+			v.NewCoverDivergence = vrf.stats.addCoverDivergence(v.CoverDivergence)
 		}
 	}
-	return
+	if v.Divergent == 0 && len(vrf.stateProbes) != 0 {
+		v.StateDivergence = stateDivergence(first)
+		if len(v.StateDivergence) != 0 {
+			v.NewStateDivergence = vrf.stats.addStateDivergence(v.StateDivergence)
+		}
+	}
+	return v
+}
+
+// queueLen returns the number of tasks waiting for runners in all queues.
+func (vrf *Verifier) queueLen() int {
+	vrf.tasksMutex.Lock()
+	defer vrf.tasksMutex.Unlock()
+	n := 0
+	for _, queues := range vrf.kernelEnvTasks {
+		for _, q := range queues {
+			n += q.Len()
+		}
+	}
+	return n
 }
 
 // Run sends the program for verification to execution queues and return
 // result once it's ready.
 // In case of time-out, return (nil, error).
 func (vrf *Verifier) Run(prog *prog.Prog, env EnvDescr) (result []*ExecResult, err error) {
+	return vrf.run(prog, env, nil)
+}
+
+// RunTraced is like Run, but the program is executed under strace and the results contain
+// the traces (see trace.go).
+func (vrf *Verifier) RunTraced(prog *prog.Prog, env EnvDescr) (result []*ExecResult, err error) {
+	return vrf.run(prog, env, func(task *ExecTask) {
+		task.Trace = true
+	})
+}
+
+// RunInSandbox returns the function that runs programs like Run, but in the executor sandbox
+// (see sandbox.go).
+func (vrf *Verifier) RunInSandbox(sandbox string) runFunc {
+	return func(prog *prog.Prog, env EnvDescr) ([]*ExecResult, error) {
+		return vrf.run(prog, env, func(task *ExecTask) {
+			task.Sandbox = sandbox
+		})
+	}
+}
+
+// run executes the program on all kernels, setup (optional) sets the parameters of the tasks.
+func (vrf *Verifier) run(prog *prog.Prog, env EnvDescr, setup func(task *ExecTask)) (
+	result []*ExecResult, err error) {
 	totalKernels := len(vrf.kernelEnvTasks)
 	result = make([]*ExecResult, totalKernels)
+	priority := vrf.progPriority(prog)
 
 	wg := sync.WaitGroup{}
 	wg.Add(totalKernels)
@@ -174,17 +447,32 @@ func (vrf *Verifier) Run(prog *prog.Prog, env EnvDescr) (result []*ExecResult, e
 		i := i
 		q := vrf.kernelEnvTasks[i][env]
 
+		if pi := vrf.pools[i]; pi != nil && pi.standby != nil {
+			pi.standby.taskArrived()
+		}
 		go func() {
 			defer wg.Done()
 			task := MakeExecTask(prog)
+			task.priority = priority
+			if setup != nil {
+				setup(task)
+			}
 			defer DeleteExecTask(task)
 
 			vrf.tasksMutex.Lock()
-			q.PushTask(task)
-			vrf.onTaskAdded.Signal()
+			vrf.pushTask(i, q, task)
 			vrf.tasksMutex.Unlock()
 
-			result[i] = <-task.ExecResultChan
+			result[i] = vrf.waitTaskResult(task, q, i)
+
+			vrf.tasksMutex.Lock()
+			dispatched := task.DispatchTime
+			vrf.tasksMutex.Unlock()
+			if res := result[i]; res != nil && res.Error == nil && !dispatched.IsZero() {
+				exec := time.Since(dispatched)
+				vrf.stats.addTaskLatency(env, dispatched.Sub(task.CreationTime), exec)
+				vrf.stats.addPoolTask(i, exec)
+			}
 		}()
 	}
 	wg.Wait()
@@ -199,6 +487,7 @@ func (vrf *Verifier) Run(prog *prog.Prog, env EnvDescr) (result []*ExecResult, e
 			return nil, err
 		}
 	}
+	normalizeOutputs(prog, result)
 
 	return result, nil
 }
@@ -217,19 +506,65 @@ func (vrf *Verifier) SetPrintStatAtSIGINT() error {
 		<-osSignalChannel
 		defer os.Exit(0)
 
-		totalExecutionTime := time.Since(vrf.stats.StartTime).Minutes()
+		if vrf.checkpointFile != "" {
+			if err := vrf.saveCheckpoint(); err != nil {
+				log.Logf(0, "failed to save checkpoint: %v", err)
+			}
+		}
+
 		if vrf.stats.TotalCallMismatches < 0 {
 			fmt.Fprint(vrf.statsWrite, "No mismatches occurred until syz-verifier was stopped.")
-		} else {
-			fmt.Fprintf(vrf.statsWrite, "%s", vrf.stats.GetTextDescription(totalExecutionTime))
+		} else if err := vrf.writeStats(vrf.statsWrite); err != nil {
+			log.Logf(0, "failed to write stats: %v", err)
+		}
+		if err := vrf.writeStatsCSV(); err != nil {
+			log.Logf(0, "failed to write stats: %v", err)
+		}
+		if err := vrf.reports.report(); err != nil {
+			log.Logf(0, "%v", err)
 		}
 	}()
 
 	return nil
 }
 
+// writeStats writes the verification statistics to w in the text or JSON format.
+func (vrf *Verifier) writeStats(w io.Writer) error {
+	totalExecutionTime := time.Since(vrf.stats.StartTime).Minutes()
+	if !vrf.statsJSON {
+		_, err := fmt.Fprintf(w, "%s", vrf.stats.GetTextDescription(totalExecutionTime))
+		return err
+	}
+	data, err := vrf.stats.GetJSONDescription(totalExecutionTime)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// writeStatsCSV writes the per-call statistics to the -stats-csv file, if requested.
+func (vrf *Verifier) writeStatsCSV() error {
+	if vrf.statsCSV == "" {
+		return nil
+	}
+	f, err := os.Create(vrf.statsCSV)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := vrf.stats.WriteCSV(f); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
 func (vrf *Verifier) startInstances() {
 	for poolID, pi := range vrf.pools {
+		if pi.pool == nil {
+			// The VMs of remote kernels are started by the agents.
+			continue
+		}
 		totalInstances := pi.pool.Count()
 		for vmID := 0; vmID < totalInstances; vmID++ {
 			go func(pi *poolInfo, poolID, vmID int) {
@@ -242,6 +577,8 @@ func (vrf *Verifier) startInstances() {
 }
 
 func (vrf *Verifier) createAndManageInstance(pi *poolInfo, poolID, vmID int) {
+	bootStart := time.Now()
+	vrf.health.booting(poolID, vmID)
 	inst, err := pi.pool.Create(vmID)
 	if err != nil {
 		log.Fatalf("failed to create instance: %v", err)
@@ -249,29 +586,69 @@ func (vrf *Verifier) createAndManageInstance(pi *poolInfo, poolID, vmID int) {
 	defer inst.Close()
 	defer vrf.srv.cleanup(poolID, vmID)
 
-	fwdAddr, err := inst.Forward(vrf.srv.port)
+	cmd, err := setupRunner(inst, pi.cfg, pi.runnerBin, vrf.srv.port, poolID, vmID, vrf.newEnv,
+		vrf.srv.tlsClient, vrf.rpcTokenFile)
 	if err != nil {
-		log.Fatalf("failed to set up port forwarding: %v", err)
+		log.Fatalf("%v", err)
 	}
 
-	runnerBin, err := inst.Copy(vrf.runnerBin)
-	if err != nil {
-		log.Fatalf(" failed to copy runner binary: %v", err)
+	if vrf.snapshots {
+		if err := inst.Snapshot(); err != nil {
+			log.Fatalf("failed to snapshot VM: %v", err)
+		}
 	}
-	_, err = inst.Copy(vrf.executorBin)
-	if err != nil {
-		log.Fatalf("failed to copy executor binary: %v", err)
+
+	if pi.standby != nil {
+		pi.standby.booted(time.Since(bootStart))
+		pi.standby.activate()
+		defer pi.standby.deactivate()
 	}
 
-	cmd := instance.RunnerCmd(runnerBin, fwdAddr, vrf.target.OS, vrf.target.Arch, poolID, 0, false, vrf.newEnv)
-	outc, errc, err := inst.Run(pi.cfg.Timeouts.VMRunningTime, vrf.vmStop, cmd)
-	if err != nil {
-		log.Fatalf("failed to start runner: %v", err)
+	for {
+		stop := vrf.srv.vmBooted(poolID, vmID)
+		outc, errc, err := inst.Run(pi.cfg.Timeouts.VMRunningTime, stop, cmd)
+		if err != nil {
+			log.Fatalf("failed to start runner: %v", err)
+		}
+		vrf.health.running(poolID, vmID)
+
+		rep := inst.MonitorExecution(outc, errc, pi.Reporter, vm.ExitTimeout)
+		if rep != nil || !vrf.snapshots || !vrf.restoreVM(inst, poolID, vmID) {
+			break
+		}
 	}
 
-	inst.MonitorExecution(outc, errc, pi.Reporter, vm.ExitTimeout)
+	log.New("vm").With("pool", poolID).Logf(0, "rebooting the VM")
+}
 
-	log.Logf(0, "reboot the VM in pool %d", poolID)
+// setupRunner copies the Runner, the executor and the rpc credentials into the VM
+// and returns the command that starts the Runner connecting to the port on the host.
+func setupRunner(inst *vm.Instance, cfg *mgrconfig.Config, runnerBin string, port, poolID, vmID int,
+	newEnv bool, tls *rpctype.TLSFiles, tokenFile string) (string, error) {
+	fwdAddr, err := inst.Forward(port)
+	if err != nil {
+		return "", fmt.Errorf("failed to set up port forwarding: %v", err)
+	}
+	runnerBin, err = inst.Copy(runnerBin)
+	if err != nil {
+		return "", fmt.Errorf("failed to copy runner binary: %v", err)
+	}
+	if _, err := inst.Copy(cfg.ExecutorBin); err != nil {
+		return "", fmt.Errorf("failed to copy executor binary: %v", err)
+	}
+	tlsFiles, err := instance.CopyTLSFiles(inst, tls)
+	if err != nil {
+		return "", err
+	}
+	cmd := instance.RunnerCmd(runnerBin, fwdAddr, cfg.Target.OS, cfg.Target.Arch, poolID, vmID, false, newEnv,
+		cfg.Cover, tlsFiles)
+	if tokenFile != "" {
+		if tokenFile, err = inst.Copy(tokenFile); err != nil {
+			return "", fmt.Errorf("failed to copy token file: %v", err)
+		}
+		cmd += " -token_file=" + tokenFile
+	}
+	return cmd, nil
 }
 
 // finalizeCallSet removes the system calls that are not supported from the set
@@ -310,30 +687,67 @@ func (vrf *Verifier) finalizeCallSet(w io.Writer) {
 func (vrf *Verifier) AddCallsExecutionStat(results []*ExecResult, program *prog.Prog) {
 	rr := CompareResults(results, program)
 	for _, cr := range rr.Reports {
-		atomic.AddInt64(&vrf.stats.Calls[cr.Call].Occurrences, 1)
+		cs := vrf.stats.enabledCallStats(cr.Call)
+		if cs == nil {
+			// The call was disabled at runtime while the program was executed.
+			continue
+		}
+		atomic.AddInt64(&cs.Occurrences, 1)
 
-		if !cr.Mismatch {
+		if !cr.Mismatch || vrf.suppressions.matchCall(cr) != nil {
 			continue
 		}
-		atomic.AddInt64(&vrf.stats.Calls[cr.Call].Mismatches, 1)
+		atomic.AddInt64(&cs.Mismatches, 1)
 		atomic.AddInt64(&vrf.stats.TotalCallMismatches, 1)
-		for _, state := range cr.States {
-			if state0 := cr.States[0]; state0 != state {
-				vrf.stats.Calls[cr.Call].States[state] = true
-				vrf.stats.Calls[cr.Call].States[state0] = true
-			}
+		states := []ReturnState{majorityState(cr.States)}
+		for _, pool := range cr.Outliers {
+			states = append(states, cr.States[pool])
 		}
+		vrf.stats.addMismatchStates(cr.Call, states...)
+		vrf.stats.addOutliers(cr.Call, cr.Outliers...)
 	}
 }
 
 // SaveDiffResults extract diff and save result on the persistent storage.
-func (vrf *Verifier) SaveDiffResults(results []*ExecResult, program *prog.Prog) bool {
-	rr := CompareResults(results, program)
+func (vrf *Verifier) SaveDiffResults(v *Verdict, program *prog.Prog) bool {
+	rr := CompareResults(v.Results, program)
+	rr.Verdict = v
+	reportFile := vrf.saveReport("result", createReport(rr, len(vrf.pools)), program)
+	saveTraces(reportFile, v.Traces)
+	if vrf.cRepro == nil {
+		return true
+	}
+	// Remove the reproducer of the overwritten report if the new one can't be generated.
+	os.Remove(reportFile + ".c")
+	src, err := createCRepro(rr, program, *vrf.cRepro)
+	if err != nil {
+		log.Logf(0, "failed to generate C reproducer: %v", err)
+		return true
+	}
+	if err := osutil.WriteFile(reportFile+".c", src); err != nil {
+		log.Logf(0, "failed to write %v.c file, err %v", filepath.Base(reportFile), err)
+	}
+	return true
+}
 
+// SaveCoverDivergence writes the report of the coverage divergent program to the results dir.
+func (vrf *Verifier) SaveCoverDivergence(v *Verdict, program *prog.Prog) {
+	vrf.saveReport("coverdiv", createCoverReport(program, v.CoverDivergence, vrf.coverDivergence), program)
+}
+
+// SaveStateDivergence writes the report of the state divergent program to the results dir.
+func (vrf *Verifier) SaveStateDivergence(v *Verdict, program *prog.Prog) {
+	vrf.saveReport("statediv", createStateReport(program, v.StateDivergence), program)
+}
+
+// saveReport writes the report and the program to the results dir as <prefix>-N files,
+// the oldest report is overwritten if there are maxResultReports reports already.
+// It returns the path of the report file.
+func (vrf *Verifier) saveReport(prefix string, report []byte, program *prog.Prog) string {
 	oldest := 0
 	var oldestTime time.Time
 	for i := 0; i < maxResultReports; i++ {
-		info, err := os.Stat(filepath.Join(vrf.resultsdir, fmt.Sprintf("result-%d", i)))
+		info, err := os.Stat(filepath.Join(vrf.resultsdir, fmt.Sprintf("%s-%d", prefix, i)))
 		if err != nil {
 			// There are only i-1 report files so the i-th one
 			// can be created.
@@ -349,22 +763,32 @@ func (vrf *Verifier) SaveDiffResults(results []*ExecResult, program *prog.Prog)
 		}
 	}
 
-	err := osutil.WriteFile(filepath.Join(vrf.resultsdir,
-		fmt.Sprintf("result-%d", oldest)), createReport(rr, len(vrf.pools)))
+	reportFile := filepath.Join(vrf.resultsdir, fmt.Sprintf("%s-%d", prefix, oldest))
+	err := osutil.WriteFile(reportFile, report)
 	if err != nil {
-		log.Logf(0, "failed to write result-%d file, err %v", oldest, err)
+		log.Logf(0, "failed to write %s-%d file, err %v", prefix, oldest, err)
+	}
+	// The program in the syzkaller format, so that it can be re-executed with syz-execprog.
+	if err := osutil.WriteFile(reportFile+".prog", program.Serialize()); err != nil {
+		log.Logf(0, "failed to write %s-%d.prog file, err %v", prefix, oldest, err)
 	}
 
-	log.Logf(0, "result-%d written successfully", oldest)
-	return true
+	log.Logf(0, "%s-%d written successfully", prefix, oldest)
+	return reportFile
 }
 
 // generate returns a newly generated program or error.
 func (vrf *Verifier) generate() *prog.Prog {
 	vrf.progGeneratorInit.Wait()
 
+	vrf.callsMu.Lock()
+	ct := vrf.choiceTable
+	vrf.callsMu.Unlock()
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano() + 1e12))
-	return vrf.target.Generate(rnd, prog.RecommendedCalls, vrf.choiceTable)
+	if p := vrf.mutateMismatching(rnd, ct); p != nil {
+		return p
+	}
+	return vrf.target.Generate(rnd, prog.RecommendedCalls, ct)
 }
 
 func createReport(rr *ResultReport, pools int) []byte {
@@ -382,11 +806,39 @@ func createReport(rr *ResultReport, pools int) []byte {
 		// Ensure results are ordered by pool index.
 		for i := 0; i < pools; i++ {
 			state := cr.States[i]
-			data += fmt.Sprintf("\t↳ Pool: %d, %s\n", i, state)
+			data += fmt.Sprintf("\t↳ Pool: %d, %s", i, state)
+			// With two kernels there is no majority to attribute the mismatch to one of them.
+			if pools > 2 && isOutlier(cr, i) {
+				data += " (outlier)"
+			}
+			data += "\n"
 		}
 
 		data += "\n"
 	}
 
+	if v := rr.Verdict; v != nil && v.Runs != 0 {
+		data += fmt.Sprintf("Diverged in %v of %v runs, probability of nondeterminism: %.4f\n",
+			v.Divergent, v.Runs, v.Nondeterminism)
+	}
+	if v := rr.Verdict; v != nil && v.Signature != "" {
+		data += fmt.Sprintf("Mismatch signature: %v\n", v.Signature)
+	}
+	if v := rr.Verdict; v != nil && v.Triage != nil {
+		data += "\n" + formatTriage(v.Triage, calls)
+	}
+	if v := rr.Verdict; v != nil && len(v.Sandboxes) != 0 {
+		data += "\n" + formatSandboxes(v.Sandboxes)
+	}
+
 	return []byte(data)
 }
+
+func isOutlier(cr *CallReport, pool int) bool {
+	for _, outlier := range cr.Outliers {
+		if outlier == pool {
+			return true
+		}
+	}
+	return false
+}
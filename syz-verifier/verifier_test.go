@@ -5,8 +5,10 @@ package main
 
 import (
 	"bytes"
+	"go/token"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -208,8 +210,10 @@ func TestSaveDiffResults(t *testing.T) {
 				TotalCallMismatches: 1,
 				Calls: map[string]*CallStats{
 					"breaks_returns": makeCallStats("breaks_returns", 1, 0, map[ReturnState]bool{}),
-					"test$res0":      makeCallStats("test$res0", 1, 1, map[ReturnState]bool{{Errno: 2}: true, {Errno: 5}: true}),
-					"minimize$0":     makeCallStats("minimize$0", 1, 0, map[ReturnState]bool{}),
+					"test$res0": {Name: "test$res0", Occurrences: 1, Mismatches: 1,
+						States:   map[ReturnState]bool{{Errno: 2}: true, {Errno: 5}: true},
+						Outliers: map[int]int64{1: 1}},
+					"minimize$0": makeCallStats("minimize$0", 1, 0, map[ReturnState]bool{}),
 				},
 			},
 		},
@@ -224,9 +228,9 @@ func TestSaveDiffResults(t *testing.T) {
 			resultFile := filepath.Join(vrf.resultsdir, "result-0")
 
 			vrf.AddCallsExecutionStat(test.res, prog)
-			vrf.SaveDiffResults(test.res, prog)
+			vrf.SaveDiffResults(&Verdict{Results: test.res}, prog)
 
-			if diff := cmp.Diff(test.wantStats, vrf.stats); diff != "" {
+			if diff := cmp.Diff(test.wantStats, vrf.stats, ignoreStatsInternals); diff != "" {
 				t.Errorf("vrf.stats mismatch (-want +got):\n%s", diff)
 			}
 
@@ -256,24 +260,30 @@ func TestCreateReport(t *testing.T) {
 				0: returnState(2, 7),
 				1: returnState(5, 3),
 				2: returnState(22, 1)},
-				Mismatch: true},
+				Mismatch: true, Outliers: []int{1, 2}},
 		},
 	}
 	got := string(createReport(&rr, 3))
 	want := "ERRNO mismatches found for program:\n\n" +
 		"[=] breaks_returns()\n" +
-		"\t↳ Pool: 0, Flags: 1, Errno: 1 (operation not permitted)\n" +
-		"\t↳ Pool: 1, Flags: 1, Errno: 1 (operation not permitted)\n" +
-		"\t↳ Pool: 2, Flags: 1, Errno: 1 (operation not permitted)\n\n" +
+		"\t↳ Pool: 0, Flags: 1 (executed), Errno: 1 EPERM (operation not permitted)\n" +
+		"\t↳ Pool: 1, Flags: 1 (executed), Errno: 1 EPERM (operation not permitted)\n" +
+		"\t↳ Pool: 2, Flags: 1 (executed), Errno: 1 EPERM (operation not permitted)\n\n" +
 		"[=] minimize$0(0x1, 0x1)\n" +
-		"\t↳ Pool: 0, Flags: 3, Errno: 3 (no such process)\n" +
-		"\t↳ Pool: 1, Flags: 3, Errno: 3 (no such process)\n" +
-		"\t↳ Pool: 2, Flags: 3, Errno: 3 (no such process)\n\n" +
+		"\t↳ Pool: 0, Flags: 3 (executed|finished), Errno: 3 ESRCH (no such process)\n" +
+		"\t↳ Pool: 1, Flags: 3 (executed|finished), Errno: 3 ESRCH (no such process)\n" +
+		"\t↳ Pool: 2, Flags: 3 (executed|finished), Errno: 3 ESRCH (no such process)\n\n" +
 		"[!] test$res0()\n" +
-		"\t↳ Pool: 0, Flags: 7, Errno: 2 (no such file or directory)\n" +
-		"\t↳ Pool: 1, Flags: 3, Errno: 5 (input/output error)\n" +
-		"\t↳ Pool: 2, Flags: 1, Errno: 22 (invalid argument)\n\n"
+		"\t↳ Pool: 0, Flags: 7 (executed|finished|blocked), Errno: 2 ENOENT (no such file or directory)\n" +
+		"\t↳ Pool: 1, Flags: 3 (executed|finished), Errno: 5 EIO (input/output error) (outlier)\n" +
+		"\t↳ Pool: 2, Flags: 1 (executed), Errno: 22 EINVAL (invalid argument) (outlier)\n\n"
 	if diff := cmp.Diff(got, want); diff != "" {
 		t.Errorf("createReport: (-want +got):\n%s", diff)
 	}
 }
+
+// ignoreStatsInternals ignores the mutex and other internal fields of Stats.
+var ignoreStatsInternals = cmp.FilterPath(func(p cmp.Path) bool {
+	sf, ok := p.Last().(cmp.StructField)
+	return ok && p.Index(-2).Type() == reflect.TypeOf((*Stats)(nil)).Elem() && !token.IsExported(sf.Name())
+}, cmp.Ignore())
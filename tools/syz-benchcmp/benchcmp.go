@@ -5,6 +5,10 @@
 // First, run syz-manager with -bench=old flag.
 // Then, do experimental modifications and run syz-manager again with -bench=new flag.
 // Then, run syz-benchcmp old new.
+// Fuzzing is very noisy, so it's better to do several runs of each configuration
+// and pass them as comma-separated lists: syz-benchcmp old0,old1,old2 new0,new1,new2.
+// Then graphs show mean values with min/max intervals, and final values of the main
+// metrics are compared with confidence intervals and Welch's t-test p-values.
 package main
 
 import (
@@ -18,6 +22,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/google/syzkaller/pkg/tool"
 )
@@ -30,20 +35,23 @@ var (
 )
 
 type Graph struct {
-	Name    string
-	Headers []string
-	Points  []Point
+	Name      string
+	Headers   []string
+	Points    []Point
+	Intervals bool // points have Mins/Maxs across several runs
 }
 
 type Point struct {
 	Time uint64
 	Vals []uint64
+	Mins []uint64
+	Maxs []uint64
 }
 
 func main() {
 	flag.Parse()
 	if len(flag.Args()) == 0 {
-		fmt.Fprintf(os.Stderr, "usage: syz-benchcmp [flags] bench_file0 [bench_file1 [bench_file2]]...\n")
+		fmt.Fprintf(os.Stderr, "usage: syz-benchcmp [flags] bench_file0[,run1,...] [bench_file1[,run1,...]]...\n")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
@@ -57,17 +65,37 @@ func main() {
 			"crash types": true,
 		}
 	}
+	var files []string
+	var runSide []int // index of the compared configuration for each run
+	var headers, runHeaders []string
+	multipleRuns := false
+	for side, arg := range flag.Args() {
+		runs := strings.Split(arg, ",")
+		header := filepath.Base(runs[0])
+		if len(runs) > 1 {
+			header = fmt.Sprintf("%v (%v runs)", header, len(runs))
+			multipleRuns = true
+		}
+		headers = append(headers, header)
+		for _, fname := range runs {
+			files = append(files, fname)
+			runSide = append(runSide, side)
+			runHeaders = append(runHeaders, filepath.Base(fname))
+		}
+	}
 	points := make(map[string][]Point)
-	headers := []string{}
-	for i, fname := range flag.Args() {
-		headers = append(headers, filepath.Base(fname))
+	metrics := make([][]map[string]float64, len(headers))
+	allKeys := make(map[string]bool)
+	for i, fname := range files {
 		data := readFile(fname)
 		addExecSpeed(data)
+		metrics[runSide[i]] = append(metrics[runSide[i]], runMetrics(data))
 		for _, record := range data {
 			for key, value := range record {
+				allKeys[key] = true
 				pt := Point{
 					Time: record[*flagOver],
-					Vals: make([]uint64, len(flag.Args())),
+					Vals: make([]uint64, len(files)),
 				}
 				pt.Vals[i] = value
 				points[key] = append(points[key], pt)
@@ -85,7 +113,7 @@ func main() {
 		}
 		graphs = append(graphs, &Graph{
 			Name:    key,
-			Headers: headers,
+			Headers: runHeaders,
 			Points:  points,
 		})
 	}
@@ -99,9 +127,27 @@ func main() {
 		sort.Sort(pointSlice(g.Points))
 		skipStart(g)
 		restoreMissingPoints(g)
+		if multipleRuns {
+			aggregateRuns(g, headers, runSide)
+		}
+	}
+	var stats []*MetricStats
+	if multipleRuns {
+		names := defaultMetrics
+		if *flagAll {
+			names = nil
+			for key := range allKeys {
+				names = append(names, key)
+			}
+			names = append(names, "exec/sec")
+			sort.Strings(names)
+		}
+		stats = compareMetrics(names, metrics)
+		printMetricStats(os.Stdout, headers, stats)
+	} else {
+		printFinalStats(graphs)
 	}
-	printFinalStats(graphs)
-	display(graphs)
+	display(graphs, headers, stats)
 }
 
 func readFile(fname string) (data []map[string]uint64) {
@@ -188,6 +234,44 @@ func restoreMissingPoints(g *Graph) {
 	}
 }
 
+// aggregateRuns replaces values of individual runs with mean values of each configuration
+// and records min/max values across the runs.
+func aggregateRuns(g *Graph, headers []string, runSide []int) {
+	g.Headers = headers
+	g.Intervals = true
+	for i, pt := range g.Points {
+		sum := make([]uint64, len(headers))
+		cnt := make([]uint64, len(headers))
+		mins := make([]uint64, len(headers))
+		maxs := make([]uint64, len(headers))
+		for run, v := range pt.Vals {
+			if v == 0 {
+				continue
+			}
+			side := runSide[run]
+			sum[side] += v
+			cnt[side]++
+			if mins[side] == 0 || mins[side] > v {
+				mins[side] = v
+			}
+			if maxs[side] < v {
+				maxs[side] = v
+			}
+		}
+		for side := range sum {
+			if cnt[side] != 0 {
+				sum[side] /= cnt[side]
+			}
+		}
+		g.Points[i] = Point{
+			Time: pt.Time,
+			Vals: sum,
+			Mins: mins,
+			Maxs: maxs,
+		}
+	}
+}
+
 func printFinalStats(graphs []*Graph) {
 	for i := 1; i < len(graphs[0].Headers); i++ {
 		fmt.Printf("%-12v%16v%16v%16v\n", "", graphs[0].Headers[0], graphs[0].Headers[i], "diff")
@@ -220,7 +304,7 @@ func getAxisTitle() string {
 	return *flagOver
 }
 
-func display(graphs []*Graph) {
+func display(graphs []*Graph, headers []string, stats []*MetricStats) {
 	var outf *os.File
 	var err error
 	if *flagOut == "" {
@@ -237,6 +321,8 @@ func display(graphs []*Graph) {
 	vars := map[string]interface{}{
 		"Graphs":     graphs,
 		"HAxisTitle": getAxisTitle(),
+		"Headers":    headers,
+		"Stats":      stats,
 	}
 	if err := htmlTemplate.Execute(outf, vars); err != nil {
 		tool.Failf("failed to execute template: %v", err)
@@ -270,9 +356,15 @@ var htmlTemplate = template.Must(
           data.addColumn({type: 'number'});
           {{range $graph.Headers}}
             data.addColumn({type: 'number', label: '{{.}}'});
+            {{if $graph.Intervals}}
+              data.addColumn({type: 'number', role: 'interval'});
+              data.addColumn({type: 'number', role: 'interval'});
+            {{end}}
           {{end}}
           data.addRows([
-            {{range $graph.Points}} [ {{.Time}}, {{range .Vals}} {{if .}} {{.}} {{end}}, {{end}}
+            {{range $pt := $graph.Points}} [ {{$pt.Time}}, {{range $i, $v := $pt.Vals}} {{if $v}} {{$v}} {{end}},
+              {{if $graph.Intervals}} {{if $v}} {{index $pt.Mins $i}} {{end}}, {{if $v}} {{index $pt.Maxs $i}} {{end}}, {{end}}
+            {{end}}
           ],
           {{end}}
           ]);
@@ -282,6 +374,7 @@ var htmlTemplate = template.Must(
               width: "100%",
               height: document.documentElement.clientHeight * 0.48,
               legend: {position: "in"},
+              intervals: {style: "area"},
               focusTarget: "category",
               hAxis: {title: "{{$.HAxisTitle}}"},
               chartArea: {left: "5%", top: "5%", width: "90%", height:"85%"}
@@ -292,6 +385,21 @@ var htmlTemplate = template.Must(
     </script>
 </head>
 <body>
+   {{if .Stats}}
+   <table border="1" cellpadding="4" style="border-collapse:collapse;">
+     <caption>Final values: mean ± 95% confidence interval; p-values of Welch's t-test against {{index .Headers 0}}</caption>
+     <tr>
+       <th></th>
+       {{range $i, $hdr := .Headers}}<th>{{$hdr}}</th>{{if $i}}<th>p-value</th>{{end}}{{end}}
+     </tr>
+     {{range $ms := .Stats}}
+     <tr>
+       <td>{{$ms.Name}}</td>
+       {{range $i, $s := $ms.Sides}}<td>{{$s}}</td>{{if $i}}<td>{{printf "%.3f" (index $ms.PValues $i)}}</td>{{end}}{{end}}
+     </tr>
+     {{end}}
+   </table>
+   {{end}}
    {{range $id, $graph := .Graphs}}<div id="graph_div_{{$id}}" style="width:50%;display:inline-block;"></div>{{end}}
 </body>
 </html>
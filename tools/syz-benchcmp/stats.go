@@ -0,0 +1,220 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// Summary describes values of a metric across several runs of the same configuration.
+type Summary struct {
+	N      int
+	Mean   float64
+	Stddev float64
+	CI     float64 // half-width of the 95% confidence interval of the mean
+}
+
+// MetricStats compares a single metric across all configurations.
+// PValues[i] is the p-value of Welch's t-test for the difference between
+// configuration i and the first (base) configuration, PValues[0] is unused.
+type MetricStats struct {
+	Name    string
+	Sides   []Summary
+	PValues []float64
+}
+
+// Metrics that are compared by default (final values of the runs).
+var defaultMetrics = []string{"exec/sec", "coverage", "corpus", "crashes", "crash types"}
+
+// runMetrics returns final values of metrics of a single run.
+func runMetrics(data []map[string]uint64) map[string]float64 {
+	res := make(map[string]float64)
+	if len(data) == 0 {
+		return res
+	}
+	last := data[len(data)-1]
+	for key, val := range last {
+		res[key] = float64(val)
+	}
+	if dt := last[*flagOver]; dt != 0 {
+		res["exec/sec"] = float64(last["exec total"]) / float64(dt)
+	}
+	return res
+}
+
+func compareMetrics(names []string, sides [][]map[string]float64) []*MetricStats {
+	var res []*MetricStats
+	for _, name := range names {
+		ms := &MetricStats{
+			Name:    name,
+			PValues: make([]float64, len(sides)),
+		}
+		var samples [][]float64
+		for _, runs := range sides {
+			var vals []float64
+			for _, run := range runs {
+				vals = append(vals, run[name])
+			}
+			samples = append(samples, vals)
+			ms.Sides = append(ms.Sides, summarize(vals))
+		}
+		for i := 1; i < len(samples); i++ {
+			ms.PValues[i] = welchTest(samples[0], samples[i])
+		}
+		res = append(res, ms)
+	}
+	return res
+}
+
+func printMetricStats(w io.Writer, headers []string, stats []*MetricStats) {
+	fmt.Fprintf(w, "%-12v", "")
+	for i, hdr := range headers {
+		fmt.Fprintf(w, "%28v", hdr)
+		if i != 0 {
+			fmt.Fprintf(w, "%10v", "p-value")
+		}
+	}
+	fmt.Fprintf(w, "\n")
+	for _, ms := range stats {
+		fmt.Fprintf(w, "%-12v", ms.Name)
+		for i, s := range ms.Sides {
+			fmt.Fprintf(w, "%28v", s.String())
+			if i != 0 {
+				fmt.Fprintf(w, "%10.3f", ms.PValues[i])
+			}
+		}
+		fmt.Fprintf(w, "\n")
+	}
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("%.1f ± %.1f (n=%v)", s.Mean, s.CI, s.N)
+}
+
+func summarize(vals []float64) Summary {
+	s := Summary{N: len(vals)}
+	if s.N == 0 {
+		return s
+	}
+	s.Mean, s.Stddev = meanStddev(vals)
+	if s.N > 1 {
+		s.CI = studentQuantile(0.05, float64(s.N-1)) * s.Stddev / math.Sqrt(float64(s.N))
+	}
+	return s
+}
+
+// meanStddev returns mean and sample standard deviation of vals.
+func meanStddev(vals []float64) (float64, float64) {
+	sum := 0.0
+	for _, v := range vals {
+		sum += v
+	}
+	mean := sum / float64(len(vals))
+	if len(vals) < 2 {
+		return mean, 0
+	}
+	sum = 0
+	for _, v := range vals {
+		sum += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sum / float64(len(vals)-1))
+}
+
+// welchTest returns two-tailed p-value of Welch's t-test for the difference of means of a and b.
+// It returns NaN if there are not enough samples to perform the test.
+func welchTest(a, b []float64) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return math.NaN()
+	}
+	meanA, sdA := meanStddev(a)
+	meanB, sdB := meanStddev(b)
+	va := sdA * sdA / float64(len(a))
+	vb := sdB * sdB / float64(len(b))
+	if va+vb == 0 {
+		if meanA == meanB {
+			return 1
+		}
+		return 0
+	}
+	t := (meanB - meanA) / math.Sqrt(va+vb)
+	df := (va + vb) * (va + vb) / (va*va/float64(len(a)-1) + vb*vb/float64(len(b)-1))
+	return studentTwoTailed(t, df)
+}
+
+// studentTwoTailed returns P(|T| > |t|) for Student's t-distribution with df degrees of freedom.
+func studentTwoTailed(t, df float64) float64 {
+	return betaInc(df/2, 0.5, df/(df+t*t))
+}
+
+// studentQuantile returns t such that P(|T| > t) = p for Student's t-distribution
+// with df degrees of freedom.
+func studentQuantile(p, df float64) float64 {
+	lo, hi := 0.0, 1e6
+	for i := 0; i < 100; i++ {
+		mid := (lo + hi) / 2
+		if studentTwoTailed(mid, df) > p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// betaInc returns the regularized incomplete beta function I_x(a, b).
+func betaInc(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lgab, _ := math.Lgamma(a + b)
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+	if x < (a+1)/(a+b+2) {
+		return front * betaContFrac(a, b, x) / a
+	}
+	return 1 - front*betaContFrac(b, a, 1-x)/b
+}
+
+// betaContFrac evaluates continued fraction for the incomplete beta function
+// using the modified Lentz's method.
+func betaContFrac(a, b, x float64) float64 {
+	const (
+		maxIter = 300
+		eps     = 1e-14
+		tiny    = 1e-300
+	)
+	clamp := func(v float64) float64 {
+		if math.Abs(v) < tiny {
+			return tiny
+		}
+		return v
+	}
+	c := 1.0
+	d := 1 / clamp(1-(a+b)*x/(a+1))
+	h := d
+	for m := 1; m <= maxIter; m++ {
+		fm := float64(m)
+		// Even step.
+		num := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 / clamp(1+num*d)
+		c = clamp(1 + num/c)
+		h *= d * c
+		// Odd step.
+		num = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 / clamp(1+num*d)
+		c = clamp(1 + num/c)
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < eps {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStudent(t *testing.T) {
+	// Reference values from statistical tables.
+	for _, test := range []struct {
+		t, df, p float64
+	}{
+		{0, 5, 1},
+		{2, 10, 0.0734},
+		{2.228, 10, 0.05},
+		{4.303, 2, 0.05},
+		{12.706, 1, 0.05},
+		{1.96, 1e6, 0.05},
+		{-3, 20, 0.0071},
+	} {
+		if got := studentTwoTailed(test.t, test.df); math.Abs(got-test.p) > 1e-3 {
+			t.Errorf("studentTwoTailed(%v, %v) = %v, want %v", test.t, test.df, got, test.p)
+		}
+	}
+	for _, test := range []struct {
+		df, q float64
+	}{
+		{1, 12.706},
+		{2, 4.303},
+		{4, 2.776},
+		{30, 2.042},
+	} {
+		if got := studentQuantile(0.05, test.df); math.Abs(got-test.q) > 1e-3 {
+			t.Errorf("studentQuantile(0.05, %v) = %v, want %v", test.df, got, test.q)
+		}
+	}
+}
+
+func TestWelch(t *testing.T) {
+	a := []float64{27.5, 21.0, 19.0, 23.6, 17.0, 17.9, 16.9, 20.1, 21.9, 22.6, 23.1, 19.6, 19.0, 21.7, 21.4}
+	b := []float64{27.1, 22.0, 20.8, 23.4, 23.4, 23.5, 25.8, 22.0, 24.8, 20.2, 21.9, 22.1, 22.9, 20.5, 24.4}
+	// Example from https://en.wikipedia.org/wiki/Welch%27s_t-test (t=-2.46, df=24.99).
+	if got, want := welchTest(a, b), 0.021; math.Abs(got-want) > 1e-3 {
+		t.Errorf("welchTest = %v, want %v", got, want)
+	}
+	if got := welchTest([]float64{1, 1}, []float64{1, 1}); got != 1 {
+		t.Errorf("welchTest of equal constant samples = %v, want 1", got)
+	}
+	if got := welchTest([]float64{1}, []float64{2, 3}); !math.IsNaN(got) {
+		t.Errorf("welchTest with a single sample = %v, want NaN", got)
+	}
+	s := summarize([]float64{1, 2, 3})
+	if s.N != 3 || s.Mean != 2 || s.Stddev != 1 || math.Abs(s.CI-2.484) > 1e-3 {
+		t.Errorf("bad summary: %+v", s)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"debug/dwarf"
+
+	"github.com/google/syzkaller/pkg/btf"
+)
+
+// parseKernelBTF parses BTF of the kernel object file and converts struct types into DWARF types,
+// so that they can be checked the same way as structs extracted from DWARF.
+func parseKernelBTF(obj string, ptrSize uint64) (map[string]*dwarf.StructType, error) {
+	types, err := btf.Load(obj)
+	if err != nil {
+		return nil, err
+	}
+	conv := &btfConverter{
+		types:     types,
+		ptrSize:   int64(ptrSize),
+		converted: make(map[uint32]dwarf.Type),
+	}
+	result := make(map[string]*dwarf.StructType)
+	for id, t := range types {
+		if t == nil || t.Name == "" {
+			continue
+		}
+		var str *dwarf.StructType
+		switch typ := conv.convert(uint32(id)).(type) {
+		case *dwarf.StructType:
+			str = typ
+		case *dwarf.TypedefType:
+			// Structs can be referred to by typedef name (the same as for DWARF).
+			str, _ = typ.Type.(*dwarf.StructType)
+		}
+		if str != nil && str.ByteSize > 0 {
+			result[t.Name] = str
+		}
+	}
+	return result, nil
+}
+
+type btfConverter struct {
+	types     []*btf.Type
+	ptrSize   int64
+	converted map[uint32]dwarf.Type
+}
+
+func (conv *btfConverter) convert(id uint32) dwarf.Type {
+	if typ := conv.converted[id]; typ != nil {
+		return typ
+	}
+	if id == 0 || int(id) >= len(conv.types) {
+		return &dwarf.VoidType{}
+	}
+	t := conv.types[id]
+	common := dwarf.CommonType{ByteSize: int64(t.Size), Name: t.Name}
+	var res dwarf.Type
+	switch t.Kind {
+	case btf.KindInt:
+		res = &dwarf.IntType{BasicType: dwarf.BasicType{CommonType: common}}
+	case btf.KindFloat:
+		res = &dwarf.FloatType{BasicType: dwarf.BasicType{CommonType: common}}
+	case btf.KindEnum, btf.KindEnum64:
+		res = &dwarf.EnumType{CommonType: common, EnumName: t.Name}
+	case btf.KindPtr:
+		ptr := &dwarf.PtrType{CommonType: dwarf.CommonType{ByteSize: conv.ptrSize}}
+		// Register the pointer before converting the elem, types can be recursive.
+		conv.converted[id] = ptr
+		ptr.Type = conv.convert(t.Type)
+		res = ptr
+	case btf.KindArray:
+		elem := conv.convert(t.Type)
+		common.ByteSize = int64(t.Count) * elem.Size()
+		res = &dwarf.ArrayType{CommonType: common, Type: elem, Count: int64(t.Count)}
+	case btf.KindTypedef:
+		res = &dwarf.TypedefType{CommonType: common, Type: conv.convert(t.Type)}
+	case btf.KindConst, btf.KindVolatile, btf.KindRestrict, btf.KindTypeTag:
+		qual := map[btf.Kind]string{
+			btf.KindConst:    "const",
+			btf.KindVolatile: "volatile",
+			btf.KindRestrict: "restrict",
+		}[t.Kind]
+		res = &dwarf.QualType{Qual: qual, Type: conv.convert(t.Type)}
+	case btf.KindFwd:
+		res = &dwarf.StructType{StructName: t.Name, Kind: "struct", Incomplete: true}
+	case btf.KindStruct, btf.KindUnion:
+		kind := "struct"
+		if t.Kind == btf.KindUnion {
+			kind = "union"
+		}
+		str := &dwarf.StructType{CommonType: common, StructName: t.Name, Kind: kind}
+		conv.converted[id] = str
+		for _, m := range t.Members {
+			str.Field = append(str.Field, conv.convertField(m))
+		}
+		res = str
+	case btf.KindFunc, btf.KindFuncProto:
+		res = &dwarf.FuncType{}
+	default:
+		res = &dwarf.VoidType{}
+	}
+	conv.converted[id] = res
+	return res
+}
+
+func (conv *btfConverter) convertField(m btf.Member) *dwarf.StructField {
+	fld := &dwarf.StructField{
+		Name:       m.Name,
+		Type:       conv.convert(m.Type),
+		ByteOffset: int64(m.BitOffset / 8),
+	}
+	if m.BitSize == 0 {
+		return fld
+	}
+	// Represent bitfields the same way as DWARF 3 does: ByteOffset is offset of the storage unit,
+	// BitOffset is offset of the end of the field from the end of the storage unit
+	// (which is what checkStruct expects).
+	unitSize := fld.Type.Size()
+	if unitSize == 0 {
+		unitSize = 1
+	}
+	fld.ByteOffset = int64(m.BitOffset) / (unitSize * 8) * unitSize
+	fld.BitSize = int64(m.BitSize)
+	fld.BitOffset = unitSize*8 - (int64(m.BitOffset) - fld.ByteOffset*8) - fld.BitSize
+	fld.DataBitOffset = int64(m.BitOffset)
+	return fld
+}
@@ -24,7 +24,16 @@
 // E.g. -dwarf=0 greatly speeds up checking if you are only interested in netlink warnings
 // (but then again don't commit changes).
 //
+// Alternatively, -btf flag makes struct checking use BTF from .BTF section of the object files
+// (kernel needs to be built with CONFIG_DEBUG_INFO_BTF). BTF is much faster to parse
+// and does not require the special compiler flags above.
+//
 // The results are produced in sys/os/*.warn files.
+// Alternatively, -json flag makes syz-check print all findings (including compiler errors
+// and warnings with positions, codes and fix suggestions) as a JSON array to stdout
+// instead of updating the warn files. With -json, -obj flags are optional: if none
+// are given, only compiler diagnostics for all arches are produced
+// (useful for editor and CI integration).
 // On implementation level syz-check parses vmlinux dwarf, extracts struct descriptions
 // and compares them with what we have (size, fields, alignment, etc). Netlink checking extracts policy symbols
 // from the object files and parses them.
@@ -34,6 +43,7 @@ import (
 	"bytes"
 	"debug/dwarf"
 	"debug/elf"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
@@ -56,21 +66,31 @@ func main() {
 	var (
 		flagOS      = flag.String("os", runtime.GOOS, "OS")
 		flagDWARF   = flag.Bool("dwarf", true, "do checking based on DWARF")
+		flagBTF     = flag.Bool("btf", false, "use BTF instead of DWARF for struct checking")
 		flagNetlink = flag.Bool("netlink", true, "do checking of netlink policies")
+		flagJSON    = flag.Bool("json", false, "print findings as JSON instead of writing warn files")
 	)
 	arches := make(map[string]*string)
 	for arch := range targets.List[targets.Linux] {
 		arches[arch] = flag.String("obj-"+arch, "", arch+" kernel object file")
 	}
 	defer tool.Init()()
+	if *flagJSON && allEmpty(arches) {
+		warnings, failed := checkDescriptions(*flagOS)
+		writeJSON(warnings, len(targets.List[*flagOS]), failed)
+		return
+	}
 	var warnings []Warn
 	for arch, obj := range arches {
 		if *obj == "" {
 			delete(arches, arch)
 			continue
 		}
-		warnings1, err := check(*flagOS, arch, *obj, *flagDWARF, *flagNetlink)
+		warnings1, err := check(*flagOS, arch, *obj, *flagDWARF, *flagBTF, *flagNetlink)
 		if err != nil {
+			if *flagJSON && warnings1 != nil {
+				writeJSON(warnings1, 1, true)
+			}
 			tool.Fail(err)
 		}
 		warnings = append(warnings, warnings1...)
@@ -81,24 +101,31 @@ func main() {
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
+	if *flagJSON {
+		writeJSON(warnings, len(arches), false)
+		return
+	}
 	if err := writeWarnings(*flagOS, len(arches), warnings); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
-func check(OS, arch, obj string, dwarf, netlink bool) ([]Warn, error) {
+func check(OS, arch, obj string, dwarf, btf, netlink bool) ([]Warn, error) {
 	var warnings []Warn
 	if obj == "" {
 		return nil, fmt.Errorf("no object file in -obj-%v flag", arch)
 	}
 	structTypes, locs, warnings1, err := parseDescriptions(OS, arch)
 	if err != nil {
-		return nil, err
+		for i := range warnings1 {
+			warnings1[i].arch = arch
+		}
+		return warnings1, err
 	}
 	warnings = append(warnings, warnings1...)
 	if dwarf {
-		structs, err := parseKernelObject(obj)
+		structs, err := parseKernelStructs(OS, arch, obj, btf)
 		if err != nil {
 			return nil, err
 		}
@@ -121,6 +148,13 @@ func check(OS, arch, obj string, dwarf, netlink bool) ([]Warn, error) {
 	return warnings, nil
 }
 
+func parseKernelStructs(OS, arch, obj string, useBTF bool) (map[string]*dwarf.StructType, error) {
+	if useBTF {
+		return parseKernelBTF(obj, targets.Get(OS, arch).PtrSize)
+	}
+	return parseKernelObject(obj)
+}
+
 const (
 	WarnCompiler           = "compiler"
 	WarnNoSuchStruct       = "no-such-struct"
@@ -140,6 +174,92 @@ type Warn struct {
 	arch string
 	typ  string
 	msg  string
+	diag *compiler.Diagnostic // set for compiler errors/warnings
+}
+
+func allEmpty(arches map[string]*string) bool {
+	for _, obj := range arches {
+		if *obj != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDescriptions does only compiler checking of the descriptions for all arches.
+func checkDescriptions(OS string) ([]Warn, bool) {
+	var warnings []Warn
+	failed := false
+	for arch := range targets.List[OS] {
+		_, _, warnings1, err := parseDescriptions(OS, arch)
+		if err != nil {
+			failed = true
+		}
+		for i := range warnings1 {
+			warnings1[i].arch = arch
+		}
+		warnings = append(warnings, warnings1...)
+	}
+	return warnings, failed
+}
+
+// JSONDiagnostic is a single finding in the -json output.
+type JSONDiagnostic struct {
+	*compiler.Diagnostic
+	// Arches lists the arches the finding is relevant for, empty means all checked arches.
+	Arches []string `json:"arches,omitempty"`
+}
+
+func writeJSON(warnings []Warn, narches int, failed bool) {
+	var res []*JSONDiagnostic
+	dedup := make(map[compiler.Diagnostic]*JSONDiagnostic)
+	for _, warn := range warnings {
+		diag := warn.diag
+		if diag == nil {
+			diag = &compiler.Diagnostic{
+				File:     warn.pos.File,
+				Line:     warn.pos.Line,
+				Col:      warn.pos.Col,
+				Severity: compiler.SeverityWarning,
+				Code:     warn.typ,
+				Message:  warn.msg,
+			}
+		}
+		jd := dedup[*diag]
+		if jd == nil {
+			jd = &JSONDiagnostic{Diagnostic: diag}
+			dedup[*diag] = jd
+			res = append(res, jd)
+		}
+		jd.Arches = append(jd.Arches, warn.arch)
+	}
+	for _, jd := range res {
+		if len(jd.Arches) == narches {
+			jd.Arches = nil
+		}
+		sort.Strings(jd.Arches)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		d1, d2 := res[i], res[j]
+		if d1.File != d2.File {
+			return d1.File < d2.File
+		}
+		if d1.Line != d2.Line {
+			return d1.Line < d2.Line
+		}
+		return d1.Col < d2.Col
+	})
+	if res == nil {
+		res = []*JSONDiagnostic{}
+	}
+	data, err := json.MarshalIndent(res, "", "\t")
+	if err != nil {
+		tool.Fail(err)
+	}
+	os.Stdout.Write(append(data, '\n'))
+	if failed {
+		os.Exit(1)
+	}
 }
 
 func writeWarnings(OS string, narches int, warnings []Warn) error {
@@ -328,21 +448,24 @@ func checkStruct(typ prog.Type, astStruct *ast.Struct, str *dwarf.StructType) ([
 func parseDescriptions(OS, arch string) ([]prog.Type, map[string]*ast.Struct, []Warn, error) {
 	errorBuf := new(bytes.Buffer)
 	var warnings []Warn
+	dh := func(diag *compiler.Diagnostic) {
+		warnings = append(warnings, Warn{pos: diag.Pos(), typ: WarnCompiler, msg: diag.Message, diag: diag})
+		fmt.Fprintf(errorBuf, "%v: %v\n", diag.Pos(), diag.Message)
+	}
 	eh := func(pos ast.Pos, msg string) {
-		warnings = append(warnings, Warn{pos: pos, typ: WarnCompiler, msg: msg})
-		fmt.Fprintf(errorBuf, "%v: %v\n", pos, msg)
+		dh(compiler.SyntaxDiagnostic(pos, msg))
 	}
 	top := ast.ParseGlob(filepath.Join("sys", OS, "*.txt"), eh)
 	if top == nil {
-		return nil, nil, nil, fmt.Errorf("failed to parse txt files:\n%s", errorBuf.Bytes())
+		return nil, nil, warnings, fmt.Errorf("failed to parse txt files:\n%s", errorBuf.Bytes())
 	}
 	consts := compiler.DeserializeConstFile(filepath.Join("sys", OS, "*.const"), eh).Arch(arch)
 	if consts == nil {
-		return nil, nil, nil, fmt.Errorf("failed to parse const files:\n%s", errorBuf.Bytes())
+		return nil, nil, warnings, fmt.Errorf("failed to parse const files:\n%s", errorBuf.Bytes())
 	}
-	prg := compiler.Compile(top, consts, targets.Get(OS, arch), eh)
+	prg := compiler.CompileDiagnostics(top, consts, targets.Get(OS, arch), dh)
 	if prg == nil {
-		return nil, nil, nil, fmt.Errorf("failed to compile descriptions:\n%s", errorBuf.Bytes())
+		return nil, nil, warnings, fmt.Errorf("failed to compile descriptions:\n%s", errorBuf.Bytes())
 	}
 	prog.RestoreLinks(prg.Syscalls, prg.Resources, prg.Types)
 	locs := make(map[string]*ast.Struct)
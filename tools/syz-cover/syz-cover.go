@@ -13,6 +13,10 @@
 //
 // Usage:
 //	syz-cover [-os=OS -arch=ARCH -kernel_src=. -kernel_obj=.] rawcover.file*
+//
+// With -base flag syz-cover generates a diff report that highlights lines
+// that are newly covered or lost compared to the base coverage:
+//	syz-cover -base=old.rawcover[,old2.rawcover] new.rawcover*
 package main
 
 import (
@@ -42,6 +46,7 @@ func main() {
 		flagKernelBuildSrc = flag.String("kernel_build_src", "", "path to kernel image's build dir (optional)")
 		flagKernelObj      = flag.String("kernel_obj", "", "path to kernel build/obj dir")
 		flagExport         = flag.String("csv", "", "export coverage data in csv format (optional)")
+		flagBase           = flag.String("base", "", "comma-separated raw coverage files to diff against (optional)")
 	)
 	defer tool.Init()()
 
@@ -74,6 +79,17 @@ func main() {
 	}
 	progs := []cover.Prog{{PCs: pcs}}
 	buf := new(bytes.Buffer)
+	if *flagBase != "" {
+		basePCs, err := readPCs(strings.Split(*flagBase, ","))
+		if err != nil {
+			tool.Fail(err)
+		}
+		if err := rg.DoDiffHTML(buf, basePCs, pcs); err != nil {
+			tool.Fail(err)
+		}
+		openHTML(buf.Bytes())
+		return
+	}
 	if *flagExport != "" {
 		if err := rg.DoCSV(buf, progs, nil); err != nil {
 			tool.Fail(err)
@@ -86,12 +102,16 @@ func main() {
 	if err := rg.DoHTML(buf, progs, nil); err != nil {
 		tool.Fail(err)
 	}
+	openHTML(buf.Bytes())
+}
+
+func openHTML(data []byte) {
 	fn, err := osutil.TempFile("syz-cover")
 	if err != nil {
 		tool.Fail(err)
 	}
 	fn += ".html"
-	if err := osutil.WriteFile(fn, buf.Bytes()); err != nil {
+	if err := osutil.WriteFile(fn, data); err != nil {
 		tool.Fail(err)
 	}
 	if err := exec.Command("xdg-open", fn).Start(); err != nil {
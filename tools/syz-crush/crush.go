@@ -2,18 +2,25 @@
 // Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
 
 // syz-crush replays crash log on multiple VMs. Usage:
-//   syz-crush -config=config.file execution.log
+//   syz-crush -config=config.file execution.log [variant.log|variant.c...]
 // Intended for reproduction of particularly elusive crashes.
+// If several reproducer variants are given, VMs are adaptively allocated
+// to the variants that crash more often. Results of all runs are aggregated
+// into a final report (per-title counts, time-to-crash distribution, success rate).
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -31,6 +38,7 @@ var (
 	flagDebug       = flag.Bool("debug", false, "dump all VM output to console")
 	flagRestartTime = flag.Duration("restart_time", 0, "how long to run the test")
 	flagInfinite    = flag.Bool("infinite", true, "by default test is run for ever, -infinite=false to stop on crash")
+	flagReport      = flag.String("report", "", "write the final JSON report to this file")
 )
 
 type FileType int
@@ -42,8 +50,8 @@ const (
 
 func main() {
 	flag.Parse()
-	if len(flag.Args()) != 1 || *flagConfig == "" {
-		fmt.Fprintf(os.Stderr, "usage: syz-crush [flags] <execution.log|creprog.c>\n")
+	if len(flag.Args()) == 0 || *flagConfig == "" {
+		fmt.Fprintf(os.Stderr, "usage: syz-crush [flags] <execution.log|creprog.c>+\n")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
@@ -70,29 +78,13 @@ func main() {
 		log.Fatalf("%v", err)
 	}
 
-	reproduceMe := flag.Args()[0]
 	if cfg.Tag == "" {
 		// If no tag is given, use reproducer name as the tag.
-		cfg.Tag = filepath.Base(reproduceMe)
+		cfg.Tag = filepath.Base(flag.Args()[0])
 	}
-	runType := LogFile
-	if strings.HasSuffix(reproduceMe, ".c") {
-		runType = CProg
-	}
-	if runType == CProg {
-		execprog, err := ioutil.ReadFile(reproduceMe)
-		if err != nil {
-			log.Fatalf("error reading source file from '%s'", reproduceMe)
-		}
-
-		cfg.ExecprogBin, err = csource.BuildNoWarn(cfg.Target, execprog)
-		if err != nil {
-			log.Fatalf("failed to build source file: %v", err)
-		}
-
-		log.Printf("compiled csource %v to cprog: %v", reproduceMe, cfg.ExecprogBin)
-	} else {
-		log.Printf("reproducing from log file: %v", reproduceMe)
+	sched := new(scheduler)
+	for _, reproduceMe := range flag.Args() {
+		sched.variants = append(sched.variants, loadVariant(cfg, reproduceMe))
 	}
 
 	log.Printf("booting %v test machines...", vmPool.Count())
@@ -102,7 +94,18 @@ func main() {
 	for i := 0; i < vmPool.Count(); i++ {
 		go func(index int) {
 			for {
-				runDone <- runInstance(cfg, reporter, vmPool, index, *flagRestartTime, runType)
+				v := sched.next()
+				rep, elapsed, err := runInstance(cfg, reporter, vmPool, index, *flagRestartTime, v)
+				if err != nil {
+					log.Printf("vm-%v: %v", index, err)
+					sched.failed(v)
+				} else {
+					sched.done(v, rep, elapsed)
+				}
+				if rep != nil {
+					storeCrash(cfg, v, rep)
+				}
+				runDone <- rep
 				if atomic.LoadUint32(&shutdown) != 0 || !*flagInfinite {
 					// If this is the last worker then we can close the channel.
 					if atomic.AddUint32(&stoppedWorkers, 1) == uint32(vmPool.Count()) {
@@ -124,22 +127,252 @@ func main() {
 		close(vm.Shutdown)
 	}()
 
-	var count, crashes int
-	for rep := range runDone {
-		count++
-		if rep != nil {
-			crashes++
-			storeCrash(cfg, rep)
+	for range runDone {
+		res := sched.summary()
+		log.Printf("instances executed: %v, crashes: %v, failed to run: %v", res.Runs, res.Crashes, res.Failures)
+	}
+
+	res := sched.summary()
+	log.Printf("all done. reproduced %v crashes. reproduce rate %.2f%%", res.Crashes, res.SuccessRate*100.0)
+	res.print()
+	if *flagReport != "" {
+		data, err := json.MarshalIndent(res, "", "\t")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := osutil.WriteFile(*flagReport, data); err != nil {
+			log.Fatalf("failed to write report: %v", err)
+		}
+	}
+}
+
+// variant is a single reproducer passed on the command line.
+type variant struct {
+	file        string
+	runType     FileType
+	execprogBin string
+	runs        int
+	crashes     int
+	failures    int // runs that failed because of infrastructure problems, not counted in runs
+	titles      map[string]int
+	crashTimes  []time.Duration
+}
+
+func loadVariant(cfg *mgrconfig.Config, reproduceMe string) *variant {
+	v := &variant{
+		file:        reproduceMe,
+		runType:     LogFile,
+		execprogBin: cfg.ExecprogBin,
+		titles:      make(map[string]int),
+	}
+	if strings.HasSuffix(reproduceMe, ".c") {
+		v.runType = CProg
+	}
+	if v.runType == CProg {
+		execprog, err := ioutil.ReadFile(reproduceMe)
+		if err != nil {
+			log.Fatalf("error reading source file from '%s'", reproduceMe)
+		}
+
+		v.execprogBin, err = csource.BuildNoWarn(cfg.Target, execprog)
+		if err != nil {
+			log.Fatalf("failed to build source file: %v", err)
+		}
+
+		log.Printf("compiled csource %v to cprog: %v", reproduceMe, v.execprogBin)
+	} else {
+		log.Printf("reproducing from log file: %v", reproduceMe)
+	}
+	return v
+}
+
+// scheduler distributes VM runs among variants. Every variant is run at least once,
+// then runs are allocated with the UCB1 strategy: variants with higher crash rate
+// get more runs, but rarely tried variants still get a chance.
+type scheduler struct {
+	mu       sync.Mutex
+	variants []*variant
+	pending  []int // number of currently running instances per variant
+	total    int
+}
+
+func (s *scheduler) next() *variant {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending == nil {
+		s.pending = make([]int, len(s.variants))
+	}
+	best, bestScore := 0, -1.0
+	for i, v := range s.variants {
+		runs := v.runs + s.pending[i]
+		score := math.Inf(1)
+		if runs != 0 {
+			score = float64(v.crashes)/float64(runs) +
+				math.Sqrt(2*math.Log(float64(s.total+1))/float64(runs))
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	s.pending[best]++
+	return s.variants[best]
+}
+
+func (s *scheduler) done(v *variant, rep *report.Report, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finish(v)
+	s.total++
+	v.runs++
+	if rep != nil {
+		v.crashes++
+		v.titles[rep.Title]++
+		v.crashTimes = append(v.crashTimes, elapsed)
+	}
+}
+
+// failed is called instead of done if the variant could not be run,
+// such runs don't affect the crash rate of the variant.
+func (s *scheduler) failed(v *variant) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finish(v)
+	v.failures++
+}
+
+func (s *scheduler) finish(v *variant) {
+	for i := range s.variants {
+		if s.variants[i] == v {
+			s.pending[i]--
 		}
-		log.Printf("instances executed: %v, crashes: %v", count, crashes)
 	}
+}
 
-	log.Printf("all done. reproduced %v crashes. reproduce rate %.2f%%", crashes, float64(crashes)/float64(count)*100.0)
+// Summary is the aggregated result of all runs, it is written with -report.
+type Summary struct {
+	Runs        int
+	Crashes     int
+	Failures    int `json:",omitempty"`
+	SuccessRate float64
+	Titles      map[string]int
+	TimeToCrash *TimeStats `json:",omitempty"`
+	Variants    []*VariantSummary
+}
+
+type VariantSummary struct {
+	File        string
+	Runs        int
+	Crashes     int
+	Failures    int `json:",omitempty"`
+	SuccessRate float64
+	Titles      map[string]int
+	TimeToCrash *TimeStats `json:",omitempty"`
+}
+
+// TimeStats describes distribution of time from the start of execution to the crash.
+type TimeStats struct {
+	Min    time.Duration
+	Median time.Duration
+	P90    time.Duration
+	Max    time.Duration
+}
+
+func (s *scheduler) summary() *Summary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := &Summary{Titles: make(map[string]int)}
+	var crashTimes []time.Duration
+	for _, v := range s.variants {
+		res.Runs += v.runs
+		res.Crashes += v.crashes
+		res.Failures += v.failures
+		for title, count := range v.titles {
+			res.Titles[title] += count
+		}
+		crashTimes = append(crashTimes, v.crashTimes...)
+		res.Variants = append(res.Variants, &VariantSummary{
+			File:        v.file,
+			Runs:        v.runs,
+			Crashes:     v.crashes,
+			Failures:    v.failures,
+			SuccessRate: successRate(v.crashes, v.runs),
+			Titles:      v.titles,
+			TimeToCrash: timeStats(v.crashTimes),
+		})
+	}
+	res.SuccessRate = successRate(res.Crashes, res.Runs)
+	res.TimeToCrash = timeStats(crashTimes)
+	return res
+}
+
+func successRate(crashes, runs int) float64 {
+	if runs == 0 {
+		return 0
+	}
+	return float64(crashes) / float64(runs)
 }
 
-func storeCrash(cfg *mgrconfig.Config, rep *report.Report) {
+func timeStats(times []time.Duration) *TimeStats {
+	if len(times) == 0 {
+		return nil
+	}
+	sorted := append([]time.Duration{}, times...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return &TimeStats{
+		Min:    sorted[0],
+		Median: sorted[len(sorted)/2],
+		P90:    sorted[len(sorted)*9/10],
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+func (res *Summary) print() {
+	log.Printf("runs: %v, crashes: %v, success rate: %.2f%%", res.Runs, res.Crashes, res.SuccessRate*100)
+	if res.Failures != 0 {
+		log.Printf("failed to run: %v", res.Failures)
+	}
+	printTitles(res.Titles)
+	if res.TimeToCrash != nil {
+		log.Printf("time to crash: %v", res.TimeToCrash)
+	}
+	if len(res.Variants) == 1 {
+		return
+	}
+	for _, v := range res.Variants {
+		log.Printf("%v: runs: %v, crashes: %v, success rate: %.2f%%, failed to run: %v",
+			v.File, v.Runs, v.Crashes, v.SuccessRate*100, v.Failures)
+		printTitles(v.Titles)
+		if v.TimeToCrash != nil {
+			log.Printf("\ttime to crash: %v", v.TimeToCrash)
+		}
+	}
+}
+
+func printTitles(titles map[string]int) {
+	var sorted []string
+	for title := range titles {
+		sorted = append(sorted, title)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return titles[sorted[i]] > titles[sorted[j]] ||
+			titles[sorted[i]] == titles[sorted[j]] && sorted[i] < sorted[j]
+	})
+	for _, title := range sorted {
+		log.Printf("\t%6v %v", titles[title], title)
+	}
+}
+
+func (ts *TimeStats) String() string {
+	return fmt.Sprintf("min %v, median %v, p90 %v, max %v", ts.Min, ts.Median, ts.P90, ts.Max)
+}
+
+var storeMu sync.Mutex
+
+func storeCrash(cfg *mgrconfig.Config, v *variant, rep *report.Report) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
 	id := hash.String([]byte(rep.Title))
-	dir := filepath.Join(filepath.Dir(flag.Args()[0]), "crashes", id)
+	dir := filepath.Join(filepath.Dir(v.file), "crashes", id)
 	osutil.MkdirAll(dir)
 
 	index := 0
@@ -161,43 +394,41 @@ func storeCrash(cfg *mgrconfig.Config, rep *report.Report) {
 			log.Printf("failed to write crash report: %v", err)
 		}
 	}
-	if err := osutil.CopyFile(flag.Args()[0], filepath.Join(dir, fmt.Sprintf("reproducer%v", index))); err != nil {
+	if err := osutil.CopyFile(v.file, filepath.Join(dir, fmt.Sprintf("reproducer%v", index))); err != nil {
 		log.Printf("failed to write crash reproducer: %v", err)
 	}
 }
 
+// runInstance returns the crash report (if any) and the time it took to crash,
+// or an error if the variant could not be run (e.g. the VM failed to boot).
 func runInstance(cfg *mgrconfig.Config, reporter *report.Reporter,
-	vmPool *vm.Pool, index int, timeout time.Duration, runType FileType) *report.Report {
-	log.Printf("vm-%v: starting", index)
+	vmPool *vm.Pool, index int, timeout time.Duration, v *variant) (*report.Report, time.Duration, error) {
+	log.Printf("vm-%v: starting %v", index, v.file)
 	inst, err := vmPool.Create(index)
 	if err != nil {
-		log.Printf("failed to create instance: %v", err)
-		return nil
+		return nil, 0, fmt.Errorf("failed to create instance: %v", err)
 	}
 	defer inst.Close()
 
-	execprogBin, err := inst.Copy(cfg.ExecprogBin)
+	execprogBin, err := inst.Copy(v.execprogBin)
 	if err != nil {
-		log.Printf("failed to copy execprog: %v", err)
-		return nil
+		return nil, 0, fmt.Errorf("failed to copy execprog: %v", err)
 	}
 
 	cmd := ""
-	if runType == LogFile {
+	if v.runType == LogFile {
 		// If SyzExecutorCmd is provided, it means that syz-executor is already in
 		// the image, so no need to copy it.
 		executorBin := cfg.SysTarget.ExecutorBin
 		if executorBin == "" {
 			executorBin, err = inst.Copy(cfg.ExecutorBin)
 			if err != nil {
-				log.Printf("failed to copy executor: %v", err)
-				return nil
+				return nil, 0, fmt.Errorf("failed to copy executor: %v", err)
 			}
 		}
-		logFile, err := inst.Copy(flag.Args()[0])
+		logFile, err := inst.Copy(v.file)
 		if err != nil {
-			log.Printf("failed to copy log: %v", err)
-			return nil
+			return nil, 0, fmt.Errorf("failed to copy log: %v", err)
 		}
 
 		cmd = instance.ExecprogCmd(execprogBin, executorBin, cfg.TargetOS, cfg.TargetArch, cfg.Sandbox,
@@ -206,18 +437,19 @@ func runInstance(cfg *mgrconfig.Config, reporter *report.Reporter,
 		cmd = execprogBin
 	}
 
+	start := time.Now()
 	outc, errc, err := inst.Run(timeout, nil, cmd)
 	if err != nil {
-		log.Printf("failed to run execprog: %v", err)
-		return nil
+		return nil, 0, fmt.Errorf("failed to run execprog: %v", err)
 	}
 
 	log.Printf("vm-%v: crushing...", index)
 	rep := inst.MonitorExecution(outc, errc, reporter, vm.ExitTimeout)
 	if rep != nil {
-		log.Printf("vm-%v: crash: %v", index, rep.Title)
-		return rep
+		elapsed := time.Since(start)
+		log.Printf("vm-%v: crash after %v: %v", index, elapsed, rep.Title)
+		return rep, elapsed, nil
 	}
 	log.Printf("vm-%v: running long enough, stopping", index)
-	return nil
+	return nil, 0, nil
 }
@@ -0,0 +1,71 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/syzkaller/pkg/report"
+)
+
+func TestScheduler(t *testing.T) {
+	sched := new(scheduler)
+	for _, file := range []string{"never.log", "sometimes.log", "often.log"} {
+		sched.variants = append(sched.variants, &variant{file: file, titles: make(map[string]int)})
+	}
+	crashEvery := map[string]int{"sometimes.log": 5, "often.log": 2}
+	for i := 0; i < 300; i++ {
+		v := sched.next()
+		var rep *report.Report
+		if n := crashEvery[v.file]; n != 0 && (v.runs+1)%n == 0 {
+			rep = &report.Report{Title: "crash in " + v.file}
+		}
+		sched.done(v, rep, time.Duration(i)*time.Second)
+	}
+	never, sometimes, often := sched.variants[0], sched.variants[1], sched.variants[2]
+	if never.runs == 0 || never.runs >= sometimes.runs || sometimes.runs >= often.runs {
+		t.Fatalf("bad run allocation: never %v, sometimes %v, often %v",
+			never.runs, sometimes.runs, often.runs)
+	}
+	res := sched.summary()
+	if res.Runs != 300 || res.Crashes != sometimes.crashes+often.crashes {
+		t.Fatalf("bad summary: %+v", res)
+	}
+	if res.Variants[0].TimeToCrash != nil || res.Variants[2].TimeToCrash == nil {
+		t.Fatalf("bad time to crash: %+v %+v", res.Variants[0].TimeToCrash, res.Variants[2].TimeToCrash)
+	}
+	if res.Titles["crash in often.log"] != often.crashes {
+		t.Fatalf("bad titles: %v", res.Titles)
+	}
+}
+
+func TestSchedulerFailures(t *testing.T) {
+	sched := new(scheduler)
+	v := &variant{file: "repro.log", titles: make(map[string]int)}
+	sched.variants = append(sched.variants, v)
+	for i := 0; i < 10; i++ {
+		sched.next()
+		if i%2 == 0 {
+			// E.g. the VM failed to boot.
+			sched.failed(v)
+			continue
+		}
+		var rep *report.Report
+		if i%4 == 1 {
+			rep = &report.Report{Title: "crash"}
+		}
+		sched.done(v, rep, time.Minute)
+	}
+	if sched.pending[0] != 0 {
+		t.Fatalf("%v runs are still pending", sched.pending[0])
+	}
+	res := sched.summary()
+	if res.Runs != 5 || res.Crashes != 3 || res.Failures != 5 || res.SuccessRate != 0.6 {
+		t.Fatalf("bad summary: %+v", res)
+	}
+	if res.Variants[0].Failures != 5 || res.Variants[0].Runs != 5 {
+		t.Fatalf("bad variant summary: %+v", res.Variants[0])
+	}
+}
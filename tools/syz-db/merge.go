@@ -0,0 +1,166 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/google/syzkaller/pkg/db"
+	"github.com/google/syzkaller/pkg/hash"
+	"github.com/google/syzkaller/pkg/signal"
+	"github.com/google/syzkaller/pkg/tool"
+	"github.com/google/syzkaller/prog"
+)
+
+// Coverage metadata for merge is a JSON object that maps program hashes
+// (the corpus database keys) to raw signal of the program:
+//
+//	{"da39a3ee5e6b4b0d3255bfef95601890afd80709": [1234, 5678], ...}
+//
+// Programs that are present in the metadata and don't add any new signal are dropped.
+// Programs without metadata are always preserved since we can't tell what they cover.
+type signalMeta map[string][]uint32
+
+func runMerge(target *prog.Target, output string, inputs, signalFiles []string) {
+	var corpora []*db.Reader
+	for _, file := range inputs {
+		corpus, err := db.OpenReader(file)
+		if err != nil {
+			tool.Failf("failed to open database %v: %v", file, err)
+		}
+		corpora = append(corpora, corpus)
+	}
+	meta := make(signalMeta)
+	for _, file := range signalFiles {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			tool.Failf("failed to read signal file: %v", err)
+		}
+		var m signalMeta
+		if err := json.Unmarshal(data, &m); err != nil {
+			tool.Failf("failed to parse signal file %v: %v", file, err)
+		}
+		for key, raw := range m {
+			meta[key] = append(meta[key], raw...)
+		}
+	}
+	total, merged, err := mergeCorpora(target, corpora, meta, output)
+	if err != nil {
+		tool.Fail(err)
+	}
+	fmt.Fprintf(os.Stderr, "merged %v programs from %v databases into %v programs\n",
+		total, len(corpora), merged)
+}
+
+// mergeSource identifies the record that is used for a merged program.
+type mergeSource struct {
+	corpus int
+	key    string
+	seq    uint64
+}
+
+// mergeCorpora combines records of all corpora into the output database, deduplicates them and,
+// if signal metadata is provided, drops programs that don't add signal. If target is not nil,
+// programs are normalized before deduplication. The resulting version is the minimal version
+// of all corpora, so that the manager re-minimizes programs that came from older corpora.
+// Records are streamed from the input databases twice (first to select programs, then to write them),
+// so only keys are kept in memory. Returns the total number of input and output programs.
+func mergeCorpora(target *prog.Target, corpora []*db.Reader, meta signalMeta, output string) (int, int, error) {
+	var version uint64
+	total := 0
+	merged := make(map[string]mergeSource)
+	var rawSignal map[string][]uint32
+	if len(meta) != 0 {
+		rawSignal = make(map[string][]uint32)
+	}
+	for i, corpus := range corpora {
+		if i == 0 || corpus.Version < version {
+			version = corpus.Version
+		}
+		err := corpus.Iterate(func(key string, rec db.Record) error {
+			total++
+			val, ok := normalizeProg(target, key, rec.Val)
+			if !ok {
+				return nil
+			}
+			newKey := hash.String(val)
+			if raw, ok := meta[key]; ok {
+				rawSignal[newKey] = append(rawSignal[newKey], raw...)
+			}
+			if prev, ok := merged[newKey]; ok && prev.seq >= rec.Seq {
+				return nil
+			}
+			merged[newKey] = mergeSource{i, key, rec.Seq}
+			return nil
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	var keys []string
+	for key := range merged {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	selected := make([]map[string]bool, len(corpora))
+	for i := range selected {
+		selected[i] = make(map[string]bool)
+	}
+	var inputs []signal.Context
+	for _, key := range keys {
+		raw, ok := rawSignal[key]
+		if !ok {
+			src := merged[key]
+			selected[src.corpus][src.key] = true
+			continue
+		}
+		inputs = append(inputs, signal.Context{
+			Signal:  signal.FromRaw(raw, 0),
+			Context: key,
+		})
+	}
+	for _, ctx := range signal.Minimize(inputs) {
+		src := merged[ctx.(string)]
+		selected[src.corpus][src.key] = true
+	}
+	wr, err := db.NewWriter(output, version)
+	if err != nil {
+		return 0, 0, err
+	}
+	written := 0
+	for i, corpus := range corpora {
+		err := corpus.Iterate(func(key string, rec db.Record) error {
+			if !selected[i][key] {
+				return nil
+			}
+			val, _ := normalizeProg(target, key, rec.Val)
+			written++
+			return wr.Write(hash.String(val), val, rec.Seq)
+		})
+		if err != nil {
+			wr.Close()
+			return 0, 0, err
+		}
+	}
+	if err := wr.Close(); err != nil {
+		return 0, 0, err
+	}
+	return total, written, nil
+}
+
+func normalizeProg(target *prog.Target, key string, data []byte) ([]byte, bool) {
+	if target == nil {
+		return data, true
+	}
+	p, err := target.Deserialize(data, prog.NonStrict)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to deserialize %v: %v\n", key, err)
+		return nil, false
+	}
+	return p.Serialize(), true
+}
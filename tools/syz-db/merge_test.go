@@ -0,0 +1,89 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/syzkaller/pkg/db"
+	"github.com/google/syzkaller/pkg/hash"
+)
+
+func TestMerge(t *testing.T) {
+	dir := t.TempDir()
+	progs := []string{
+		"getpid()\n",
+		"getuid()\n",
+		"getgid()\n",
+		"gettid()\n",
+	}
+	key := func(i int) string { return hash.String([]byte(progs[i])) }
+	var corpora []*db.Reader
+	createDB := func(name string, version uint64, recs ...db.Record) *db.Reader {
+		file := filepath.Join(dir, name)
+		if err := db.Create(file, version, recs); err != nil {
+			t.Fatal(err)
+		}
+		corpus, err := db.OpenReader(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return corpus
+	}
+	merge := func(meta signalMeta) (*db.DB, int) {
+		output := filepath.Join(dir, "merged.db")
+		total, merged, err := mergeCorpora(nil, corpora, meta, output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := db.Open(output, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Records) != merged {
+			t.Fatalf("merged %v programs, but the database has %v", merged, len(res.Records))
+		}
+		return res, total
+	}
+	corpus1 := createDB("corpus1.db", 3,
+		db.Record{Val: []byte(progs[0]), Seq: 1},
+		db.Record{Val: []byte(progs[1])},
+		db.Record{Val: []byte(progs[2])},
+	)
+	corpus2 := createDB("corpus2.db", 2,
+		db.Record{Val: []byte(progs[0]), Seq: 5},
+		db.Record{Val: []byte(progs[3])},
+	)
+	corpora = []*db.Reader{corpus1, corpus2}
+
+	res, total := merge(nil)
+	if res.Version != 2 || total != 5 || len(res.Records) != 4 {
+		t.Fatalf("got version %v, total %v, %v records; want 2, 5, 4", res.Version, total, len(res.Records))
+	}
+	if seq := res.Records[key(0)].Seq; seq != 5 {
+		t.Errorf("duplicate program has seq %v, want 5", seq)
+	}
+
+	// Program 1 does not add signal on top of program 0, program 3 has no signal metadata.
+	meta := signalMeta{
+		key(0): {1, 2, 3},
+		key(1): {2, 3},
+		key(2): {4},
+	}
+	res, _ = merge(meta)
+	got := make(map[string]bool)
+	for key := range res.Records {
+		got[key] = true
+	}
+	want := map[string]bool{key(0): true, key(2): true, key(3): true}
+	if len(got) != len(want) {
+		t.Fatalf("got %v programs, want %v", len(got), len(want))
+	}
+	for key := range want {
+		if !got[key] {
+			t.Errorf("program %v is missing", key)
+		}
+	}
+}
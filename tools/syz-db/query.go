@@ -0,0 +1,232 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/syzkaller/pkg/db"
+	"github.com/google/syzkaller/pkg/tool"
+	"github.com/google/syzkaller/prog"
+)
+
+// Queries select programs from a corpus. A query consists of clauses joined with "and"/"or"
+// ("and" binds tighter), every clause can be negated with "not". Supported clauses:
+//
+//	contains call NAME       - the program contains a call matching NAME (a glob, e.g. "openat$*")
+//	prog length OP N         - the number of calls compared with N, OP is one of < <= > >= == !=
+//	touches resource NAME    - the program produces or uses a resource of kind NAME (e.g. fd_bpf)
+//
+// For example: "contains call openat$kvm and not prog length > 20".
+type query [][]*queryClause // disjunction of conjunctions
+
+type queryClause struct {
+	negate bool
+	match  func(p *prog.Prog) bool
+}
+
+func parseQuery(target *prog.Target, str string) (query, error) {
+	var q query
+	for _, disjunct := range splitQuery(strings.Fields(str), "or") {
+		var conj []*queryClause
+		for _, words := range splitQuery(disjunct, "and") {
+			clause, err := parseQueryClause(target, words)
+			if err != nil {
+				return nil, err
+			}
+			conj = append(conj, clause)
+		}
+		q = append(q, conj)
+	}
+	return q, nil
+}
+
+func splitQuery(words []string, sep string) [][]string {
+	res := [][]string{nil}
+	for _, word := range words {
+		if word == sep {
+			res = append(res, nil)
+			continue
+		}
+		res[len(res)-1] = append(res[len(res)-1], word)
+	}
+	return res
+}
+
+func parseQueryClause(target *prog.Target, words []string) (*queryClause, error) {
+	clause := new(queryClause)
+	if len(words) != 0 && words[0] == "not" {
+		clause.negate = true
+		words = words[1:]
+	}
+	if len(words) < 3 {
+		return nil, fmt.Errorf("bad query clause %q", strings.Join(words, " "))
+	}
+	switch what := words[0] + " " + words[1]; what {
+	case "contains call":
+		if len(words) != 3 {
+			return nil, fmt.Errorf("%v: want one call name", what)
+		}
+		pattern := words[2]
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%v: bad pattern %q: %v", what, pattern, err)
+		}
+		found := false
+		for _, call := range target.Syscalls {
+			if ok, _ := filepath.Match(pattern, call.Name); ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%v: no calls match %q", what, pattern)
+		}
+		clause.match = func(p *prog.Prog) bool {
+			for _, c := range p.Calls {
+				if ok, _ := filepath.Match(pattern, c.Meta.Name); ok {
+					return true
+				}
+			}
+			return false
+		}
+	case "prog length":
+		if len(words) != 4 {
+			return nil, fmt.Errorf("%v: want an operator and a number", what)
+		}
+		n, err := strconv.Atoi(words[3])
+		if err != nil {
+			return nil, fmt.Errorf("%v: bad number %q", what, words[3])
+		}
+		cmp := queryOps[words[2]]
+		if cmp == nil {
+			return nil, fmt.Errorf("%v: unknown operator %q", what, words[2])
+		}
+		clause.match = func(p *prog.Prog) bool {
+			return cmp(len(p.Calls), n)
+		}
+	case "touches resource":
+		if len(words) != 3 {
+			return nil, fmt.Errorf("%v: want one resource name", what)
+		}
+		name := words[2]
+		if !knownResource(target, name) {
+			return nil, fmt.Errorf("%v: unknown resource %q", what, name)
+		}
+		clause.match = func(p *prog.Prog) bool {
+			return touchesResource(p, name)
+		}
+	default:
+		return nil, fmt.Errorf("unknown query clause %q", strings.Join(words, " "))
+	}
+	return clause, nil
+}
+
+var queryOps = map[string]func(a, b int) bool{
+	"<":  func(a, b int) bool { return a < b },
+	"<=": func(a, b int) bool { return a <= b },
+	">":  func(a, b int) bool { return a > b },
+	">=": func(a, b int) bool { return a >= b },
+	"==": func(a, b int) bool { return a == b },
+	"!=": func(a, b int) bool { return a != b },
+}
+
+func knownResource(target *prog.Target, name string) bool {
+	for _, res := range target.Resources {
+		if res.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func touchesResource(p *prog.Prog, name string) bool {
+	found := false
+	for _, c := range p.Calls {
+		prog.ForeachArg(c, func(arg prog.Arg, ctx *prog.ArgCtx) {
+			if found {
+				ctx.Stop = true
+				return
+			}
+			res, ok := arg.Type().(*prog.ResourceType)
+			if !ok {
+				return
+			}
+			for _, kind := range res.Desc.Kind {
+				if kind == name {
+					found = true
+					return
+				}
+			}
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+func (q query) match(p *prog.Prog) bool {
+	for _, conj := range q {
+		matched := true
+		for _, clause := range conj {
+			if clause.match(p) == clause.negate {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// runQuery prints programs from the corpus that match the query,
+// or stores them in a new corpus database if output is not empty.
+func runQuery(target *prog.Target, file, queryStr, output string) {
+	q, err := parseQuery(target, queryStr)
+	if err != nil {
+		tool.Failf("failed to parse query: %v", err)
+	}
+	corpus, err := db.OpenReader(file)
+	if err != nil {
+		tool.Failf("failed to open database: %v", err)
+	}
+	var wr *db.Writer
+	if output != "" {
+		if wr, err = db.NewWriter(output, corpus.Version); err != nil {
+			tool.Failf("failed to create database: %v", err)
+		}
+	}
+	matched := 0
+	err = corpus.Iterate(func(key string, rec db.Record) error {
+		p, err := target.Deserialize(rec.Val, prog.NonStrict)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to deserialize %v: %v\n", key, err)
+			return nil
+		}
+		if !q.match(p) {
+			return nil
+		}
+		matched++
+		if wr == nil {
+			fmt.Printf("%s\n", rec.Val)
+			return nil
+		}
+		return wr.Write(key, rec.Val, rec.Seq)
+	})
+	if err != nil {
+		tool.Failf("failed to query database: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "matched %v/%v programs\n", matched, corpus.Len())
+	if wr != nil {
+		if err := wr.Close(); err != nil {
+			tool.Failf("failed to write database: %v", err)
+		}
+	}
+}
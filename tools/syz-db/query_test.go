@@ -0,0 +1,70 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/syzkaller/prog"
+	"github.com/google/syzkaller/sys/targets"
+)
+
+func TestQuery(t *testing.T) {
+	target, err := prog.GetTarget(targets.Linux, targets.AMD64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	progs := []string{
+		`r0 = openat$kvm(0xffffffffffffff9c, &(0x7f0000000000), 0x0, 0x0)
+ioctl$KVM_CREATE_VM(r0, 0xae01, 0x0)
+`,
+		`r0 = bpf$PROG_LOAD(0x5, &(0x7f0000000000)={0x1, 0x0, 0x0, 0x0}, 0x70)
+close(r0)
+`,
+		`getpid()
+`,
+	}
+	tests := []struct {
+		query string
+		match []bool
+	}{
+		{"contains call openat$kvm", []bool{true, false, false}},
+		{"contains call openat$*", []bool{true, false, false}},
+		{"not contains call openat$kvm", []bool{false, true, true}},
+		{"prog length >= 2", []bool{true, true, false}},
+		{"prog length == 1 or contains call bpf$PROG_LOAD", []bool{false, true, true}},
+		{"touches resource fd_bpf_prog", []bool{false, true, false}},
+		{"touches resource fd", []bool{true, true, false}},
+		{"touches resource fd and not touches resource fd_kvm", []bool{false, true, false}},
+	}
+	for _, test := range tests {
+		q, err := parseQuery(target, test.query)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", test.query, err)
+		}
+		for i, text := range progs {
+			p, err := target.Deserialize([]byte(text), prog.NonStrict)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := q.match(p); got != test.match[i] {
+				t.Errorf("query %q, program #%v: got %v, want %v", test.query, i, got, test.match[i])
+			}
+		}
+	}
+	for _, bad := range []string{
+		"",
+		"contains call",
+		"contains call no_such_call",
+		"prog length ~ 2",
+		"prog length > x",
+		"touches resource no_such_resource",
+		"contains call getpid and",
+		"foo bar baz",
+	} {
+		if _, err := parseQuery(target, bad); err == nil {
+			t.Errorf("query %q parsed successfully", bad)
+		}
+	}
+}
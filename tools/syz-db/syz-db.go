@@ -27,6 +27,7 @@ func main() {
 		flagVersion = flag.Uint64("version", 0, "database version")
 		flagOS      = flag.String("os", "", "target OS")
 		flagArch    = flag.String("arch", "", "target arch")
+		flagSignal  = flag.String("signal", "", "comma-separated list of JSON files with program signal (for merge)")
 	)
 	flag.Parse()
 	args := flag.Args()
@@ -44,8 +45,20 @@ func main() {
 		bench(target, args[1])
 		return
 	}
-	if len(args) != 3 {
-		usage()
+	if args[0] == "query" {
+		if len(args) != 3 && len(args) != 4 {
+			usage()
+		}
+		target, err := prog.GetTarget(*flagOS, *flagArch)
+		if err != nil {
+			tool.Failf("failed to find target: %v", err)
+		}
+		output := ""
+		if len(args) == 4 {
+			output = args[3]
+		}
+		runQuery(target, args[1], args[2], output)
+		return
 	}
 	var target *prog.Target
 	if *flagOS != "" || *flagArch != "" {
@@ -55,6 +68,20 @@ func main() {
 			tool.Failf("failed to find target: %v", err)
 		}
 	}
+	if args[0] == "merge" {
+		if len(args) < 3 {
+			usage()
+		}
+		var signalFiles []string
+		if *flagSignal != "" {
+			signalFiles = strings.Split(*flagSignal, ",")
+		}
+		runMerge(target, args[1], args[2:], signalFiles)
+		return
+	}
+	if len(args) != 3 {
+		usage()
+	}
 	switch args[0] {
 	case "pack":
 		pack(args[1], args[2], target, *flagVersion)
@@ -70,6 +97,12 @@ func usage() {
 	fmt.Fprintf(os.Stderr, "  syz-db pack dir corpus.db\n")
 	fmt.Fprintf(os.Stderr, "  syz-db unpack corpus.db dir\n")
 	fmt.Fprintf(os.Stderr, "  syz-db bench corpus.db\n")
+	fmt.Fprintf(os.Stderr, "  syz-db query corpus.db 'query' [filtered-corpus.db]\n")
+	fmt.Fprintf(os.Stderr, "  syz-db [-signal signal.json] merge merged.db corpus1.db corpus2.db ...\n")
+	fmt.Fprintf(os.Stderr, "query is a list of clauses joined with and/or, optionally negated with not:\n")
+	fmt.Fprintf(os.Stderr, "  contains call NAME (glob, e.g. openat$*)\n")
+	fmt.Fprintf(os.Stderr, "  prog length OP N (OP is one of < <= > >= == !=)\n")
+	fmt.Fprintf(os.Stderr, "  touches resource NAME (e.g. fd_bpf)\n")
 	os.Exit(1)
 }
 
@@ -78,7 +111,10 @@ func pack(dir, file string, target *prog.Target, version uint64) {
 	if err != nil {
 		tool.Failf("failed to read dir: %v", err)
 	}
-	var records []db.Record
+	wr, err := db.NewWriter(file, version)
+	if err != nil {
+		tool.Failf("failed to create database: %v", err)
+	}
 	for _, file := range files {
 		data, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
 		if err != nil {
@@ -104,30 +140,30 @@ func pack(dir, file string, target *prog.Target, version uint64) {
 			fmt.Fprintf(os.Stderr, "fixing hash %v -> %v\n", key, sig)
 			key = sig
 		}
-		records = append(records, db.Record{
-			Val: data,
-			Seq: seq,
-		})
+		if err := wr.Write(key, data, seq); err != nil {
+			tool.Failf("failed to write database: %v", err)
+		}
 	}
-	if err := db.Create(file, version, records); err != nil {
-		tool.Fail(err)
+	if err := wr.Close(); err != nil {
+		tool.Failf("failed to write database: %v", err)
 	}
 }
 
 func unpack(file, dir string) {
-	db, err := db.Open(file, false)
+	rd, err := db.OpenReader(file)
 	if err != nil {
 		tool.Failf("failed to open database: %v", err)
 	}
 	osutil.MkdirAll(dir)
-	for key, rec := range db.Records {
+	err = rd.Iterate(func(key string, rec db.Record) error {
 		fname := filepath.Join(dir, key)
 		if rec.Seq != 0 {
 			fname += fmt.Sprintf("-%v", rec.Seq)
 		}
-		if err := osutil.WriteFile(fname, rec.Val); err != nil {
-			tool.Failf("failed to output file: %v", err)
-		}
+		return osutil.WriteFile(fname, rec.Val)
+	})
+	if err != nil {
+		tool.Failf("failed to unpack database: %v", err)
 	}
 }
 
@@ -0,0 +1,122 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+// syz-descgen generates draft syscall descriptions for a kernel driver from its headers.
+// It parses the headers with clang and emits syzlang structs/unions, flags for enums
+// and ioctl syscalls for _IO/_IOR/_IOW/_IOWR command macros. The result is meant to
+// bootstrap descriptions for a new subsystem, it still needs to be reviewed and refined
+// (pointer directions, len fields, resources, etc).
+// Usage:
+//
+//	syz-descgen -name foo -I $KERNEL/include -I $KERNEL/arch/x86/include \
+//		$KERNEL/include/uapi/linux/foo.h > sys/linux/dev_foo.txt
+//
+// If a directory is given, all .h files in it are used.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/syzkaller/pkg/tool"
+)
+
+var (
+	flagName   = flag.String("name", "", "driver name, used for the fd resource and the device file (required)")
+	flagClang  = flag.String("clang", "clang", "clang binary")
+	flagTarget = flag.String("target", "", "clang target triple (default: host)")
+	flagInc    incdirsFlag
+)
+
+type incdirsFlag []string
+
+func (f *incdirsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *incdirsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func main() {
+	flag.Var(&flagInc, "I", "include directory (can be specified multiple times)")
+	flag.Parse()
+	if *flagName == "" || flag.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "usage: syz-descgen -name foo [-I incdir]... header.h|dir...\n")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	headers, err := findHeaders(flag.Args())
+	if err != nil {
+		tool.Fail(err)
+	}
+	gen := newGenerator(*flagName)
+	for _, header := range headers {
+		astData, err := dumpAST(header)
+		if err != nil {
+			tool.Fail(err)
+		}
+		src, err := ioutil.ReadFile(header)
+		if err != nil {
+			tool.Fail(err)
+		}
+		if err := gen.addHeader(header, src, astData); err != nil {
+			tool.Failf("%v: %v", header, err)
+		}
+	}
+	out, err := gen.generate()
+	if err != nil {
+		tool.Fail(err)
+	}
+	os.Stdout.Write(out)
+}
+
+func findHeaders(args []string) ([]string, error) {
+	var headers []string
+	for _, arg := range args {
+		fi, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			headers = append(headers, arg)
+			continue
+		}
+		files, err := filepath.Glob(filepath.Join(arg, "*.h"))
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no headers in %v", arg)
+		}
+		headers = append(headers, files...)
+	}
+	return headers, nil
+}
+
+// dumpAST returns the clang AST of the header in JSON format.
+func dumpAST(header string) ([]byte, error) {
+	args := []string{"-x", "c", "-fsyntax-only", "-Wno-everything", "-Xclang", "-ast-dump=json"}
+	if *flagTarget != "" {
+		args = append(args, "-target", *flagTarget)
+	}
+	for _, inc := range flagInc {
+		args = append(args, "-I", inc)
+	}
+	args = append(args, header)
+	cmd := exec.Command(*flagClang, args...)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%v failed: %v\n%s", *flagClang, err, stderr.Bytes())
+	}
+	return out, nil
+}
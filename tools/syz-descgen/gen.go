@@ -0,0 +1,471 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/google/syzkaller/pkg/ast"
+)
+
+// astNode is a node of clang JSON AST dump (-Xclang -ast-dump=json).
+// Only the parts we need are parsed.
+type astNode struct {
+	Kind               string     `json:"kind"`
+	Name               string     `json:"name"`
+	TagUsed            string     `json:"tagUsed"`
+	IsImplicit         bool       `json:"isImplicit"`
+	IsBitfield         bool       `json:"isBitfield"`
+	CompleteDefinition bool       `json:"completeDefinition"`
+	Value              string     `json:"value"`
+	Loc                *astLoc    `json:"loc"`
+	Type               astType    `json:"type"`
+	Inner              []*astNode `json:"inner"`
+}
+
+type astLoc struct {
+	File         string  `json:"file"`
+	SpellingLoc  *astLoc `json:"spellingLoc"`
+	ExpansionLoc *astLoc `json:"expansionLoc"`
+}
+
+type astType struct {
+	QualType string `json:"qualType"`
+}
+
+type generator struct {
+	name     string
+	includes []string
+	ioctls   []*ioctlCmd
+	records  []*record
+	enums    []*enum
+	decls    map[string]bool   // names of emitted ioctls/records/enums
+	enumSet  map[string]bool   // names of emitted enums
+	typedefs map[string]string // typedef name -> C type
+}
+
+type ioctlCmd struct {
+	name string
+	dir  string // direction of the argument, empty for _IO commands
+	arg  string // C type of the argument
+}
+
+type record struct {
+	name   string
+	union  bool
+	packed bool
+	fields []*field
+}
+
+type field struct {
+	name     string
+	typ      string // C type
+	anon     string // name of the anonymous struct/union/enum typ refers to
+	bitfield string // bitfield width
+}
+
+type enum struct {
+	name   string
+	values []string
+}
+
+func newGenerator(name string) *generator {
+	return &generator{
+		name:     name,
+		decls:    make(map[string]bool),
+		enumSet:  make(map[string]bool),
+		typedefs: make(map[string]string),
+	}
+}
+
+// addHeader adds declarations from the header file to the generated descriptions.
+// src is the header contents (used to find ioctl command macros, which are not present in AST),
+// astData is clang AST dump of the header.
+func (g *generator) addHeader(file string, src, astData []byte) error {
+	tu := new(astNode)
+	if err := json.Unmarshal(astData, tu); err != nil {
+		return fmt.Errorf("failed to parse AST: %v", err)
+	}
+	if tu.Kind != "TranslationUnitDecl" {
+		return fmt.Errorf("unexpected AST root %q", tu.Kind)
+	}
+	g.includes = append(g.includes, includePath(file))
+	// Typedefs are collected from all files since they are needed to resolve types,
+	// but declarations are emitted only for the header itself.
+	for _, n := range tu.Inner {
+		if n.Kind == "TypedefDecl" && !n.IsImplicit {
+			g.typedefs[n.Name] = n.Type.QualType
+		}
+	}
+	curFile := ""
+	// Anonymous struct/union/enum that can be named by the following typedef.
+	var anon *astNode
+	flushAnon := func() {
+		if anon != nil && anon.Kind == "EnumDecl" {
+			g.addAnonEnum(anon)
+		}
+		anon = nil
+	}
+	for _, n := range tu.Inner {
+		if f := n.Loc.file(); f != "" {
+			curFile = f
+		}
+		if n.IsImplicit || filepath.Clean(curFile) != filepath.Clean(file) {
+			continue
+		}
+		switch n.Kind {
+		case "RecordDecl", "EnumDecl":
+			flushAnon()
+			if n.Name != "" {
+				g.addDecl(n, n.Name)
+			} else {
+				anon = n
+			}
+		case "TypedefDecl":
+			if anon != nil && (isAnonType(n.Type.QualType) || namesAnon(n, anon)) {
+				g.addDecl(anon, n.Name)
+				if anon.Kind == "EnumDecl" {
+					g.typedefs[n.Name] = "enum " + n.Name
+				} else {
+					g.typedefs[n.Name] = anon.TagUsed + " " + n.Name
+				}
+				anon = nil
+			}
+			flushAnon()
+		default:
+			flushAnon()
+		}
+	}
+	flushAnon()
+	g.addIoctls(src)
+	return nil
+}
+
+func (loc *astLoc) file() string {
+	if loc == nil {
+		return ""
+	}
+	if loc.File != "" {
+		return loc.File
+	}
+	if f := loc.ExpansionLoc.file(); f != "" {
+		return f
+	}
+	return loc.SpellingLoc.file()
+}
+
+// includePath returns include path for the header (e.g. uapi/linux/foo.h).
+func includePath(file string) string {
+	file = filepath.ToSlash(file)
+	if pos := strings.LastIndex(file, "include/"); pos != -1 {
+		return file[pos+len("include/"):]
+	}
+	return filepath.Base(file)
+}
+
+// namesAnon returns true if typedef n gives name to the anonymous struct/union/enum anon
+// (depending on clang version, such typedefs refer to either "struct (unnamed...)" or "struct NAME").
+func namesAnon(n, anon *astNode) bool {
+	tag := anon.TagUsed
+	if anon.Kind == "EnumDecl" {
+		tag = "enum"
+	}
+	return n.Type.QualType == tag+" "+n.Name
+}
+
+func isAnonType(typ string) bool {
+	return strings.Contains(typ, "(unnamed ") || strings.Contains(typ, "(anonymous ")
+}
+
+func (g *generator) addDecl(n *astNode, name string) {
+	if g.decls[name] {
+		return
+	}
+	switch n.Kind {
+	case "RecordDecl":
+		if !n.CompleteDefinition {
+			return
+		}
+		g.addRecord(n, name)
+	case "EnumDecl":
+		g.addEnum(n, name)
+	}
+}
+
+func (g *generator) addRecord(n *astNode, name string) {
+	rec := &record{
+		name:  name,
+		union: n.TagUsed == "union",
+	}
+	var anon *astNode
+	for _, child := range n.Inner {
+		switch child.Kind {
+		case "PackedAttr":
+			rec.packed = true
+		case "RecordDecl", "EnumDecl":
+			// Named nested types are global in C.
+			if child.Name != "" {
+				g.addDecl(child, child.Name)
+			} else {
+				anon = child
+			}
+		case "FieldDecl":
+			fieldName := child.Name
+			if fieldName == "" {
+				fieldName = fmt.Sprintf("anon%v", len(rec.fields))
+			}
+			anonName := ""
+			if anon != nil && isAnonType(child.Type.QualType) {
+				anonName = name + "_" + fieldName
+				g.addDecl(anon, anonName)
+				anon = nil
+			}
+			fld := &field{
+				name: fieldName,
+				typ:  child.Type.QualType,
+				anon: anonName,
+			}
+			if child.IsBitfield {
+				fld.bitfield = bitfieldWidth(child)
+			}
+			rec.fields = append(rec.fields, fld)
+		}
+	}
+	if len(rec.fields) == 0 {
+		return
+	}
+	g.decls[name] = true
+	g.records = append(g.records, rec)
+}
+
+var intTypeRe = regexp.MustCompile(`^int(8|16|32|64)(be)?$`)
+
+func bitfieldWidth(n *astNode) string {
+	for _, child := range n.Inner {
+		if child.Kind == "ConstantExpr" && child.Value != "" {
+			return child.Value
+		}
+		if child.Kind == "IntegerLiteral" {
+			return child.Value
+		}
+	}
+	return ""
+}
+
+func (g *generator) addEnum(n *astNode, name string) {
+	e := &enum{name: name}
+	for _, child := range n.Inner {
+		if child.Kind == "EnumConstantDecl" {
+			e.values = append(e.values, child.Name)
+		}
+	}
+	if len(e.values) == 0 {
+		return
+	}
+	g.decls[name] = true
+	g.enumSet[name] = true
+	g.enums = append(g.enums, e)
+}
+
+// addAnonEnum adds an anonymous enum (frequently used for constants in UAPI headers)
+// named after the common prefix of its values.
+func (g *generator) addAnonEnum(n *astNode) {
+	var values []string
+	for _, child := range n.Inner {
+		if child.Kind == "EnumConstantDecl" {
+			values = append(values, child.Name)
+		}
+	}
+	if len(values) < 2 {
+		return
+	}
+	prefix := values[0]
+	for _, v := range values[1:] {
+		for !strings.HasPrefix(v, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if pos := strings.LastIndexByte(prefix, '_'); pos != -1 {
+		prefix = prefix[:pos]
+	} else {
+		prefix = ""
+	}
+	if prefix == "" {
+		return
+	}
+	g.addDecl(n, strings.ToLower(prefix))
+}
+
+var builtinTypes = map[string]string{
+	"char":               "int8",
+	"signed char":        "int8",
+	"unsigned char":      "int8",
+	"_Bool":              "int8",
+	"short":              "int16",
+	"unsigned short":     "int16",
+	"int":                "int32",
+	"unsigned int":       "int32",
+	"long":               "intptr",
+	"unsigned long":      "intptr",
+	"long long":          "int64",
+	"unsigned long long": "int64",
+	"__be16":             "int16be",
+	"__be32":             "int32be",
+	"__be64":             "int64be",
+	"void":               "void",
+}
+
+var arrayRe = regexp.MustCompile(`^(.*?) ?((?:\[\d*\])+)$`)
+
+// convType converts C type to syzlang type. anon is the name of the anonymous struct/union
+// if the type refers to one.
+func (g *generator) convType(typ, anon string) string {
+	typ = strings.TrimSpace(typ)
+	for _, qual := range []string{"const", "volatile", "restrict"} {
+		typ = strings.TrimPrefix(typ, qual+" ")
+		typ = strings.TrimSuffix(typ, " "+qual)
+		typ = strings.Replace(typ, "*"+qual, "*", -1)
+	}
+	if strings.Contains(typ, "(*)") {
+		// Function pointer or pointer to array.
+		return "intptr"
+	}
+	if m := arrayRe.FindStringSubmatch(typ); m != nil {
+		res := g.convType(m[1], anon)
+		if res == "void" {
+			res = "int8"
+		}
+		dims := strings.Split(strings.Trim(m[2], "[]"), "][")
+		for i := len(dims) - 1; i >= 0; i-- {
+			if dims[i] == "" {
+				res = fmt.Sprintf("array[%v]", res)
+			} else {
+				res = fmt.Sprintf("array[%v, %v]", res, dims[i])
+			}
+		}
+		return res
+	}
+	if strings.HasSuffix(typ, "*") {
+		elem := g.convType(strings.TrimSuffix(typ, "*"), anon)
+		if elem == "void" || elem == "int8" {
+			elem = "array[int8]"
+		}
+		return fmt.Sprintf("ptr[inout, %v]", elem)
+	}
+	if isAnonType(typ) {
+		if anon == "" {
+			return "intptr"
+		}
+		if strings.HasPrefix(typ, "enum ") {
+			return g.enumType(anon)
+		}
+		return anon
+	}
+	if strings.HasPrefix(typ, "struct ") || strings.HasPrefix(typ, "union ") {
+		return typ[strings.IndexByte(typ, ' ')+1:]
+	}
+	if strings.HasPrefix(typ, "enum ") {
+		return g.enumType(strings.TrimPrefix(typ, "enum "))
+	}
+	if res, ok := builtinTypes[typ]; ok {
+		return res
+	}
+	if td, ok := g.typedefs[typ]; ok && td != typ {
+		return g.convType(td, anon)
+	}
+	return "intptr"
+}
+
+func (g *generator) enumType(name string) string {
+	if g.enumSet[name] {
+		return fmt.Sprintf("flags[%v, int32]", name)
+	}
+	return "int32"
+}
+
+var ioctlRe = regexp.MustCompile(`(?m)^[ \t]*#[ \t]*define[ \t]+(\w+)[ \t]+_IO(R|W|WR)?[ \t]*\((.*)\)[ \t]*$`)
+
+// addIoctls adds ioctl commands defined in the header source with _IO* macros.
+func (g *generator) addIoctls(src []byte) {
+	src = bytes.Replace(src, []byte("\\\n"), nil, -1)
+	for _, m := range ioctlRe.FindAllSubmatch(src, -1) {
+		cmd := &ioctlCmd{name: string(m[1])}
+		if g.decls["ioctl$"+cmd.name] {
+			continue
+		}
+		if dir := string(m[2]); dir != "" {
+			cmd.dir = map[string]string{"R": "out", "W": "in", "WR": "inout"}[dir]
+			args := strings.Split(string(m[3]), ",")
+			if len(args) != 3 {
+				continue
+			}
+			arg := strings.TrimSpace(args[2])
+			cmd.arg = arg
+		}
+		g.decls["ioctl$"+cmd.name] = true
+		g.ioctls = append(g.ioctls, cmd)
+	}
+}
+
+// generate returns formatted syzlang descriptions.
+func (g *generator) generate() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "# Draft descriptions generated by syz-descgen, review and refine before use.\n\n")
+	for _, inc := range g.includes {
+		fmt.Fprintf(buf, "include <%v>\n", inc)
+	}
+	fd := "fd_" + g.name
+	fmt.Fprintf(buf, "\nresource %v[fd]\n\n", fd)
+	fmt.Fprintf(buf, "openat$%v(fd const[AT_FDCWD], file ptr[in, string[\"/dev/%v\"]], "+
+		"flags flags[open_flags], mode const[0]) %v\n", g.name, g.name, fd)
+	for _, cmd := range g.ioctls {
+		fmt.Fprintf(buf, "ioctl$%v(fd %v, cmd const[%v]", cmd.name, fd, cmd.name)
+		if cmd.dir != "" {
+			arg := "array[int8]"
+			if !strings.ContainsAny(cmd.arg, "()") {
+				arg = g.convType(cmd.arg, "")
+			}
+			fmt.Fprintf(buf, ", arg ptr[%v, %v]", cmd.dir, arg)
+		}
+		fmt.Fprintf(buf, ")\n")
+	}
+	for _, rec := range g.records {
+		opening, closing := "{", "}"
+		if rec.union {
+			opening, closing = "[", "]"
+		}
+		fmt.Fprintf(buf, "\n%v %v\n", rec.name, opening)
+		for _, f := range rec.fields {
+			typ := g.convType(f.typ, f.anon)
+			if f.bitfield != "" && intTypeRe.MatchString(typ) {
+				typ += ":" + f.bitfield
+			}
+			fmt.Fprintf(buf, "\t%v\t%v\n", f.name, typ)
+		}
+		fmt.Fprintf(buf, "%v", closing)
+		if rec.packed {
+			fmt.Fprintf(buf, " [packed]")
+		}
+		fmt.Fprintf(buf, "\n")
+	}
+	if len(g.enums) != 0 {
+		fmt.Fprintf(buf, "\n")
+	}
+	for _, e := range g.enums {
+		fmt.Fprintf(buf, "%v = %v\n", e.name, strings.Join(e.values, ", "))
+	}
+	var errors []string
+	desc := ast.Parse(buf.Bytes(), "generated.txt", func(pos ast.Pos, msg string) {
+		errors = append(errors, fmt.Sprintf("%v: %v", pos, msg))
+	})
+	if desc == nil {
+		return nil, fmt.Errorf("generated invalid descriptions:\n%v\n%s", strings.Join(errors, "\n"), buf.Bytes())
+	}
+	return ast.Format(desc), nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var flagUpdate = flag.Bool("update", false, "update golden files")
+
+func TestGenerate(t *testing.T) {
+	header := filepath.Join("testdata", "foo.h")
+	src, err := ioutil.ReadFile(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	astData, err := ioutil.ReadFile(filepath.Join("testdata", "foo.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen := newGenerator("foo")
+	if err := gen.addHeader(header, src, astData); err != nil {
+		t.Fatal(err)
+	}
+	out, err := gen.generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "foo.txt")
+	if *flagUpdate {
+		if err := ioutil.WriteFile(golden, out, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, want) {
+		t.Fatalf("generated descriptions differ from %v:\n%s", golden, out)
+	}
+}
+
+func TestConvType(t *testing.T) {
+	gen := newGenerator("foo")
+	gen.typedefs["__u32"] = "unsigned int"
+	gen.typedefs["u32_alias"] = "__u32"
+	gen.enumSet["foo_mode"] = true
+	tests := []struct {
+		typ  string
+		want string
+	}{
+		{"int", "int32"},
+		{"const unsigned long", "intptr"},
+		{"u32_alias", "int32"},
+		{"__be32", "int32be"},
+		{"char[16]", "array[int8, 16]"},
+		{"__u32 [2][3]", "array[array[int32, 3], 2]"},
+		{"__u32[]", "array[int32]"},
+		{"void *", "ptr[inout, array[int8]]"},
+		{"const char *", "ptr[inout, array[int8]]"},
+		{"struct foo *const", "ptr[inout, foo]"},
+		{"int (*)(void *)", "intptr"},
+		{"enum foo_mode", "flags[foo_mode, int32]"},
+		{"enum bar", "int32"},
+		{"union bar", "bar"},
+		{"unknown_t", "intptr"},
+	}
+	for _, test := range tests {
+		if got := gen.convType(test.typ, ""); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.typ, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+/* SPDX-License-Identifier: GPL-2.0 WITH Linux-syscall-note */
+#ifndef _UAPI_LINUX_FOO_H
+#define _UAPI_LINUX_FOO_H
+
+#include <linux/types.h>
+#include <linux/ioctl.h>
+
+#define FOO_MAX_NAME 16
+
+enum foo_mode {
+	FOO_MODE_OFF,
+	FOO_MODE_ON,
+};
+
+enum {
+	FOO_FLAG_READ = 1,
+	FOO_FLAG_WRITE = 2,
+};
+
+struct foo_info {
+	__u32 id;
+	char name[FOO_MAX_NAME];
+	enum foo_mode mode;
+	__u32 flags : 4;
+	__u32 reserved : 28;
+	union {
+		__u64 val;
+		__u8 raw[8];
+	} u;
+	__u64 data[2][3];
+	struct foo_info *next;
+	__be16 port;
+} __attribute__((packed));
+
+typedef struct {
+	int x;
+	void *ptr;
+	void (*callback)(int);
+} foo_config_t;
+
+#define FOO_GET_INFO	_IOR('F', 1, struct foo_info)
+#define FOO_SET_CONFIG	_IOW('F', 2, foo_config_t)
+#define FOO_RESET	_IO('F', 3)
+#define FOO_XCHG	_IOWR('F', 4, \
+			      __u32)
+
+#endif /* _UAPI_LINUX_FOO_H */
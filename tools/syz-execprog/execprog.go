@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/google/syzkaller/pkg/cover"
+	"github.com/google/syzkaller/pkg/cover/backend"
 	"github.com/google/syzkaller/pkg/csource"
 	"github.com/google/syzkaller/pkg/db"
 	"github.com/google/syzkaller/pkg/host"
@@ -26,18 +27,24 @@ import (
 	"github.com/google/syzkaller/pkg/tool"
 	"github.com/google/syzkaller/prog"
 	_ "github.com/google/syzkaller/sys"
+	"github.com/google/syzkaller/sys/targets"
 )
 
 var (
 	flagOS        = flag.String("os", runtime.GOOS, "target os")
 	flagArch      = flag.String("arch", runtime.GOARCH, "target arch")
 	flagCoverFile = flag.String("coverfile", "", "write coverage to the file")
+	flagPrintCov  = flag.Bool("printcover", false, "print all covered PCs to stdout after execution")
+	flagSancov    = flag.String("sancov", "", "write coverage in LLVM sancov format to FILE.N.sancov for N-th program")
+	flagSancovAll = flag.Bool("sancovmerge", false, "write merged coverage of all programs to a single -sancov file")
 	flagRepeat    = flag.Int("repeat", 1, "repeat execution that many times (0 for infinite loop)")
 	flagProcs     = flag.Int("procs", 2*runtime.NumCPU(), "number of parallel processes to execute programs")
 	flagOutput    = flag.Bool("output", false, "write programs and results to stdout")
 	flagHints     = flag.Bool("hints", false, "do a hints-generation run")
 	flagEnable    = flag.String("enable", "none", "enable only listed additional features")
 	flagDisable   = flag.String("disable", "none", "enable all additional features except listed")
+	flagPerturb   = flag.Int("perturb", 0, "additionally execute each program that many times in the executor"+
+		" with perturbed timing, proc ids and fault injection")
 	// The following flag is only kept to let syzkaller remain compatible with older execprog versions.
 	// In order to test incoming patches or perform bug bisection, syz-ci must use the exact syzkaller
 	// version that detected the bug (as descriptions and syntax could've already been changed), and
@@ -104,6 +111,7 @@ func main() {
 		}
 	}
 	ctx := &Context{
+		target:   target,
 		progs:    progs,
 		config:   config,
 		execOpts: execOpts,
@@ -122,9 +130,16 @@ func main() {
 	}
 	osutil.HandleInterrupts(ctx.shutdown)
 	wg.Wait()
+	if *flagPrintCov {
+		ctx.printCoverage()
+	}
+	if *flagSancov != "" {
+		ctx.writeSancov(*flagSancov)
+	}
 }
 
 type Context struct {
+	target    *prog.Target
 	progs     []*prog.Prog
 	config    *ipc.Config
 	execOpts  *ipc.ExecOpts
@@ -135,6 +150,9 @@ type Context struct {
 	repeat    int
 	pos       int
 	lastPrint time.Time
+	coverMu   sync.Mutex
+	cover     map[uint32]bool
+	progCover map[int]map[uint32]bool // per-program coverage for -sancov
 }
 
 func (ctx *Context) run(pid int) {
@@ -153,12 +171,12 @@ func (ctx *Context) run(pid int) {
 		if ctx.repeat > 0 && idx >= len(ctx.progs)*ctx.repeat {
 			return
 		}
-		entry := ctx.progs[idx%len(ctx.progs)]
-		ctx.execute(pid, env, entry)
+		ctx.execute(pid, env, idx%len(ctx.progs))
 	}
 }
 
-func (ctx *Context) execute(pid int, env *ipc.Env, p *prog.Prog) {
+func (ctx *Context) execute(pid int, env *ipc.Env, progIdx int) {
+	p := ctx.progs[progIdx]
 	// Limit concurrency window.
 	ticket := ctx.gate.Enter()
 	defer ctx.gate.Leave(ticket)
@@ -190,6 +208,9 @@ func (ctx *Context) execute(pid int, env *ipc.Env, p *prog.Prog) {
 			if *flagCoverFile != "" {
 				ctx.dumpCoverage(*flagCoverFile, info)
 			}
+			if *flagPrintCov || *flagSancov != "" {
+				ctx.collectCoverage(progIdx, info)
+			}
 		} else {
 			log.Logf(1, "RESULT: no calls executed")
 		}
@@ -274,6 +295,84 @@ func (ctx *Context) dumpCoverage(coverFile string, info *ipc.ProgInfo) {
 	ctx.dumpCallCoverage(fmt.Sprintf("%v.extra", coverFile), &info.Extra)
 }
 
+func (ctx *Context) collectCoverage(progIdx int, info *ipc.ProgInfo) {
+	ctx.coverMu.Lock()
+	defer ctx.coverMu.Unlock()
+	if ctx.cover == nil {
+		ctx.cover = make(map[uint32]bool)
+		ctx.progCover = make(map[int]map[uint32]bool)
+	}
+	perProg := *flagSancov != "" && !*flagSancovAll
+	if perProg && ctx.progCover[progIdx] == nil {
+		ctx.progCover[progIdx] = make(map[uint32]bool)
+	}
+	for _, inf := range append(info.Calls, info.Extra) {
+		for _, pc := range inf.Cover {
+			ctx.cover[pc] = true
+			if perProg {
+				ctx.progCover[progIdx][pc] = true
+			}
+		}
+	}
+}
+
+// writeSancov writes collected coverage in the sancov format, either merged into the file,
+// or into file.N.sancov for N-th program.
+func (ctx *Context) writeSancov(file string) {
+	if *flagSancovAll {
+		ctx.writeSancovFile(file, ctx.cover)
+		return
+	}
+	for idx, cov := range ctx.progCover {
+		ctx.writeSancovFile(fmt.Sprintf("%v.%v.sancov", file, idx), cov)
+	}
+}
+
+func (ctx *Context) writeSancovFile(file string, cov map[uint32]bool) {
+	// KCOV PCs are return addresses of coverage callbacks, sanitizer runtime stores
+	// PCs of the call instructions. Do the same so that tools can symbolize them.
+	target := targets.Get(ctx.target.OS, ctx.target.Arch)
+	var pcs []uint64
+	for pc := range cov {
+		pcs = append(pcs, backend.PreviousInstructionPC(target, cover.RestorePC(pc, 0xffffffff)))
+	}
+	buf := new(bytes.Buffer)
+	if err := cover.WriteSancov(buf, pcs, ctx.target.PtrSize); err != nil {
+		log.Fatalf("failed to write sancov file: %v", err)
+	}
+	if err := osutil.WriteFile(file, buf.Bytes()); err != nil {
+		log.Fatalf("failed to write sancov file: %v", err)
+	}
+	log.Logf(0, "wrote %v PCs to %v", len(pcs), file)
+}
+
+// coverPrefix prefixes lines with comma-separated covered PCs printed with -printcover.
+// Keep in sync with tools/syz-replay.
+const coverPrefix = "covered PCs: "
+
+func (ctx *Context) printCoverage() {
+	const perLine = 64
+	buf := new(bytes.Buffer)
+	n := 0
+	for pc := range ctx.cover {
+		if n%perLine == 0 {
+			if n != 0 {
+				buf.WriteByte('\n')
+			}
+			buf.WriteString(coverPrefix)
+		} else {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(buf, "0x%x", cover.RestorePC(pc, 0xffffffff))
+		n++
+	}
+	if n != 0 {
+		buf.WriteByte('\n')
+	}
+	os.Stdout.Write(buf.Bytes())
+	log.Logf(0, "covered %v PCs", n)
+}
+
 func (ctx *Context) getProgramIndex() int {
 	ctx.posMu.Lock()
 	idx := ctx.pos
@@ -320,7 +419,7 @@ func createConfig(target *prog.Target, features *host.Features, featuresFlags cs
 	if config.Flags&ipc.FlagSignal != 0 {
 		execOpts.Flags |= ipc.FlagCollectCover
 	}
-	if *flagCoverFile != "" {
+	if *flagCoverFile != "" || *flagPrintCov || *flagSancov != "" {
 		config.Flags |= ipc.FlagSignal
 		execOpts.Flags |= ipc.FlagCollectCover
 		execOpts.Flags &^= ipc.FlagDedupCover
@@ -331,6 +430,13 @@ func createConfig(target *prog.Target, features *host.Features, featuresFlags cs
 		}
 		execOpts.Flags |= ipc.FlagCollectComps
 	}
+	if *flagPerturb != 0 {
+		execOpts.Repeat = *flagPerturb
+		execOpts.Flags |= ipc.FlagPerturbTiming | ipc.FlagPerturbProcID
+		if features[host.FeatureFault].Enabled {
+			execOpts.Flags |= ipc.FlagPerturbFaults
+		}
+	}
 	if features[host.FeatureExtraCoverage].Enabled {
 		config.Flags |= ipc.FlagExtraCover
 	}
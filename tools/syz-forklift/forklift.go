@@ -0,0 +1,118 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+// syz-forklift migrates a corpus built with one revision of syzkaller/descriptions
+// to the current revision, instead of silently losing programs that don't parse anymore on upgrade.
+// Usage:
+//
+//	syz-forklift -os linux -arch amd64 [-rules rules.txt] [-report report.txt] old-corpus.db new-corpus.db
+//
+// Programs are rewritten as follows:
+//   - calls are renamed/removed according to the rules file (see rules type for the format);
+//   - calls that don't exist in the current descriptions are removed (unless -drop-unknown=0);
+//   - arguments are fixed up for the current descriptions (changed flags, resized structs, etc).
+//
+// The report lists all altered and dropped programs with the reasons.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/google/syzkaller/pkg/db"
+	"github.com/google/syzkaller/pkg/hash"
+	"github.com/google/syzkaller/pkg/tool"
+	"github.com/google/syzkaller/prog"
+	_ "github.com/google/syzkaller/sys"
+)
+
+var (
+	flagOS          = flag.String("os", runtime.GOOS, "target os")
+	flagArch        = flag.String("arch", runtime.GOARCH, "target arch")
+	flagRules       = flag.String("rules", "", "file with migration rules")
+	flagReport      = flag.String("report", "", "file to write migration report to (default: stdout)")
+	flagDropUnknown = flag.Bool("drop-unknown", true, "remove calls that don't exist in the current descriptions")
+)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 2 {
+		fmt.Fprintf(os.Stderr, "usage: syz-forklift [flags] old-corpus.db new-corpus.db\n")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	target, err := prog.GetTarget(*flagOS, *flagArch)
+	if err != nil {
+		tool.Fail(err)
+	}
+	r := &rules{}
+	if *flagRules != "" {
+		data, err := ioutil.ReadFile(*flagRules)
+		if err != nil {
+			tool.Fail(err)
+		}
+		if r, err = parseRules(data); err != nil {
+			tool.Failf("%v: %v", *flagRules, err)
+		}
+	}
+	report := io.Writer(os.Stdout)
+	if *flagReport != "" {
+		f, err := os.Create(*flagReport)
+		if err != nil {
+			tool.Fail(err)
+		}
+		defer f.Close()
+		report = f
+	}
+	if err := forklift(target, r, *flagDropUnknown, flag.Arg(0), flag.Arg(1), report); err != nil {
+		tool.Fail(err)
+	}
+}
+
+func forklift(target *prog.Target, r *rules, dropUnknown bool, input, output string, report io.Writer) error {
+	rd, err := db.OpenReader(input)
+	if err != nil {
+		return fmt.Errorf("failed to open %v: %v", input, err)
+	}
+	wr, err := db.NewWriter(output, rd.Version)
+	if err != nil {
+		return err
+	}
+	var stats [statusDropped + 1]int
+	written := make(map[string]string)
+	err = rd.Iterate(func(key string, rec db.Record) error {
+		data, st, reasons := migrate(target, r, dropUnknown, rec.Val)
+		if st == statusDropped {
+			fmt.Fprintf(report, "dropped %v: %v\n", key, strings.Join(reasons, "; "))
+			stats[st]++
+			return nil
+		}
+		newKey := hash.String(data)
+		if prev, ok := written[newKey]; ok {
+			fmt.Fprintf(report, "dropped %v: duplicate of %v after migration\n", key, prev)
+			stats[statusDropped]++
+			return nil
+		}
+		written[newKey] = key
+		if st == statusAltered {
+			fmt.Fprintf(report, "altered %v -> %v: %v\n", key, newKey, strings.Join(reasons, "; "))
+		}
+		stats[st]++
+		return wr.Write(newKey, data, rec.Seq)
+	})
+	if err != nil {
+		wr.Close()
+		return err
+	}
+	if err := wr.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(report, "\nunchanged %v, altered %v, dropped %v programs\n",
+		stats[statusUnchanged], stats[statusAltered], stats[statusDropped])
+	return nil
+}
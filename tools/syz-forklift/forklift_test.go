@@ -0,0 +1,115 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/syzkaller/pkg/db"
+	"github.com/google/syzkaller/prog"
+	_ "github.com/google/syzkaller/sys"
+)
+
+func TestMigrate(t *testing.T) {
+	target, err := prog.GetTarget("test", "64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := parseRules([]byte(`
+# comment
+rename test$old_int test$int
+remove test$gone
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in      string
+		out     string
+		status  status
+		reasons []string
+	}{
+		{
+			in:     "test$int(0x1, 0x2, 0x3, 0x4, 0x5)\n",
+			out:    "test$int(0x1, 0x2, 0x3, 0x4, 0x5)\n",
+			status: statusUnchanged,
+		},
+		{
+			in:      "test$old_int(0x1, 0x2, 0x3, 0x4, 0x5)\n",
+			out:     "test$int(0x1, 0x2, 0x3, 0x4, 0x5)\n",
+			status:  statusAltered,
+			reasons: []string{"renamed call test$old_int to test$int"},
+		},
+		{
+			in:      "r0 = test$res0()\ntest$gone(r0)\ntest$res1(r0)\n",
+			out:     "r0 = test$res0()\ntest$res1(r0)\n",
+			status:  statusAltered,
+			reasons: []string{"removed call test$gone"},
+		},
+		{
+			in:      "test$unknown(0x1)\ntest()\n",
+			out:     "test()\n",
+			status:  statusAltered,
+			reasons: []string{"removed unknown call test$unknown"},
+		},
+		{
+			in:      "test$int(0x1, 0x2, 0x3)\n",
+			out:     "test$int(0x1, 0x2, 0x3, 0x0, 0x0)\n",
+			status:  statusAltered,
+			reasons: []string{"fixed up: missing syscall args"},
+		},
+		{
+			in:      "test$gone()\n",
+			status:  statusDropped,
+			reasons: []string{"removed call test$gone", "no calls left"},
+		},
+	}
+	for i, test := range tests {
+		out, st, reasons := migrate(target, r, true, []byte(test.in))
+		if st != test.status || !bytes.Equal(out, []byte(test.out)) ||
+			strings.Join(reasons, "|") != strings.Join(test.reasons, "|") {
+			t.Errorf("#%v: got %v %q %q, want %v %q %q",
+				i, st, out, reasons, test.status, test.out, test.reasons)
+		}
+	}
+	if _, err := parseRules([]byte("rename foo\n")); err == nil {
+		t.Errorf("bad rule is accepted")
+	}
+}
+
+func TestForklift(t *testing.T) {
+	target, err := prog.GetTarget("test", "64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	input := filepath.Join(dir, "old.db")
+	output := filepath.Join(dir, "new.db")
+	err = db.Create(input, 3, []db.Record{
+		{Val: []byte("test()\n"), Seq: 1},
+		{Val: []byte("test$unknown()\n")},
+		{Val: []byte("test$unknown()\ntest()\n")},
+		{Val: []byte("test$int(0x1)\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := new(bytes.Buffer)
+	if err := forklift(target, &rules{}, true, input, output, report); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(report.String(), "unchanged 1, altered 1, dropped 2 programs") {
+		t.Fatalf("bad report:\n%s", report.Bytes())
+	}
+	res, err := db.Open(output, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Version != 3 || len(res.Records) != 2 {
+		t.Fatalf("got version %v, %v records; want 3, 2", res.Version, len(res.Records))
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/syzkaller/prog"
+)
+
+// Rules describe changes in descriptions that can't be inferred automatically.
+// Rules file contains one rule per line (empty lines and lines starting with # are ignored):
+//
+//	rename OLD_CALL NEW_CALL
+//	remove CALL
+type rules struct {
+	rename map[string]string
+	remove map[string]bool
+}
+
+func parseRules(data []byte) (*rules, error) {
+	r := &rules{
+		rename: make(map[string]string),
+		remove: make(map[string]bool),
+	}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; s.Scan(); line++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		switch {
+		case fields[0] == "rename" && len(fields) == 3:
+			r.rename[fields[1]] = fields[2]
+		case fields[0] == "remove" && len(fields) == 2:
+			r.remove[fields[1]] = true
+		default:
+			return nil, fmt.Errorf("line %v: bad rule %q", line, s.Text())
+		}
+	}
+	return r, s.Err()
+}
+
+type status int
+
+const (
+	statusUnchanged status = iota
+	statusAltered
+	statusDropped
+)
+
+func (st status) String() string {
+	return [...]string{"unchanged", "altered", "dropped"}[st]
+}
+
+var callRe = regexp.MustCompile(`^(\s*(?:r[0-9]+\s*=\s*)?)([^\s(=]+)\(`)
+
+// migrate rewrites the program for the current descriptions of the target.
+// It applies the rules and, if dropUnknown is set, removes calls that don't exist anymore,
+// then lets non-strict deserialization fix up arguments (changed flags, resized structs, etc).
+// Returns the new program, the outcome and human-readable reasons for the changes.
+func migrate(target *prog.Target, r *rules, dropUnknown bool, data []byte) ([]byte, status, []string) {
+	var reasons []string
+	var lines []string
+	calls := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		match := callRe.FindStringSubmatch(line)
+		if match == nil {
+			lines = append(lines, line)
+			continue
+		}
+		name := match[2]
+		if r.remove[name] {
+			reasons = append(reasons, fmt.Sprintf("removed call %v", name))
+			continue
+		}
+		if newName := r.rename[name]; newName != "" {
+			reasons = append(reasons, fmt.Sprintf("renamed call %v to %v", name, newName))
+			line = match[1] + newName + line[len(match[0])-1:]
+			name = newName
+		}
+		if dropUnknown && target.SyscallMap[name] == nil {
+			reasons = append(reasons, fmt.Sprintf("removed unknown call %v", name))
+			continue
+		}
+		calls++
+		lines = append(lines, line)
+	}
+	if calls == 0 {
+		return nil, statusDropped, append(reasons, "no calls left")
+	}
+	data1 := []byte(strings.Join(lines, "\n"))
+	p, strictErr := target.Deserialize(data1, prog.Strict)
+	if strictErr == nil {
+		data2 := p.Serialize()
+		if len(reasons) == 0 && bytes.Equal(data, data2) {
+			return data, statusUnchanged, nil
+		}
+		if !bytes.Equal(data1, data2) {
+			reasons = append(reasons, "reformatted")
+		}
+		return data2, statusAltered, reasons
+	}
+	p, err := target.Deserialize(data1, prog.NonStrict)
+	if err != nil {
+		return nil, statusDropped, append(reasons, firstLine(err))
+	}
+	return p.Serialize(), statusAltered, append(reasons, "fixed up: "+firstLine(strictErr))
+}
+
+// firstLine returns the error message without the dump of the offending program line.
+func firstLine(err error) string {
+	return strings.SplitN(err.Error(), "\n", 2)[0]
+}
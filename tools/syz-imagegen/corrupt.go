@@ -0,0 +1,192 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"encoding/binary"
+	"math/rand"
+)
+
+// Corruption describes a way to damage a valid image to exercise error handling paths of the file system.
+// For each generated image and each corruption of the file system an additional test
+// syz_mount_image_NAME_INDEX_CORRUPTION is generated.
+type Corruption struct {
+	Name string
+	// Corrupt modifies the image in-place. rnd is seeded with the image hash, so results are reproducible.
+	// Returns false if the corruption is not applicable to the image (e.g. there is no journal).
+	Corrupt func(data []byte, rnd *rand.Rand) bool
+}
+
+type region struct {
+	offset int
+	size   int
+}
+
+// clip returns part of the region that is within data.
+func (r region) clip(data []byte) []byte {
+	if r.offset < 0 || r.size <= 0 || r.offset >= len(data) {
+		return nil
+	}
+	end := r.offset + r.size
+	if end > len(data) {
+		end = len(data)
+	}
+	return data[r.offset:end]
+}
+
+// metadataBitflips flips count random bits in the metadata blocks returned by the metadata callback.
+// Only non-zero bytes are corrupted, zero bytes are most likely unused space.
+func metadataBitflips(count int, metadata func(data []byte) []region) Corruption {
+	return Corruption{
+		Name: "bitflips",
+		Corrupt: func(data []byte, rnd *rand.Rand) bool {
+			var candidates []int
+			for _, r := range metadata(data) {
+				for i, v := range r.clip(data) {
+					if v != 0 {
+						candidates = append(candidates, r.offset+i)
+					}
+				}
+			}
+			if len(candidates) == 0 {
+				return false
+			}
+			for i := 0; i < count; i++ {
+				data[candidates[rnd.Intn(len(candidates))]] ^= 1 << uint(rnd.Intn(8))
+			}
+			return true
+		},
+	}
+}
+
+// truncatedJournal emulates a journal that was cut off at a random point
+// (e.g. due to a power loss): the tail of the journal is zeroed.
+func truncatedJournal(journal func(data []byte) (region, bool)) Corruption {
+	return Corruption{
+		Name: "journal",
+		Corrupt: func(data []byte, rnd *rand.Rand) bool {
+			r, ok := journal(data)
+			if !ok {
+				return false
+			}
+			log := r.clip(data)
+			if len(log) < 2 {
+				return false
+			}
+			// Leave at least the journal header intact.
+			tail := log[1+rnd.Intn(len(log)-1):]
+			for i := range tail {
+				tail[i] = 0
+			}
+			return true
+		},
+	}
+}
+
+func fixedRegions(regions ...region) func(data []byte) []region {
+	return func(data []byte) []region {
+		return regions
+	}
+}
+
+const ext4SuperOffset = 1024
+
+// ext4Metadata returns the superblock and the group descriptor table.
+func ext4Metadata(data []byte) []region {
+	if len(data) < ext4SuperOffset+1024 {
+		return nil
+	}
+	blockSize := 1024 << binary.LittleEndian.Uint32(data[ext4SuperOffset+0x18:])
+	gdt := blockSize
+	if blockSize == 1024 {
+		gdt = 2048
+	}
+	return []region{{ext4SuperOffset, 1024}, {gdt, blockSize}}
+}
+
+// ext4Journal finds the journal using the backup of the journal inode blocks in the superblock.
+// This works only for journals that have a single extent/contiguous blocks,
+// which is what mkfs creates for small images.
+func ext4Journal(data []byte) (region, bool) {
+	const (
+		compatHasJournal     = 0x4
+		incompatExtents      = 0x40
+		jnlBackupBlocks      = 1
+		extentHeaderMagic    = 0xf30a
+		superFeatureCompat   = 0x5c
+		superFeatureIncompat = 0x60
+		superJnlBackupType   = 0xfd
+		superJnlBlocks       = 0x10c
+	)
+	if len(data) < ext4SuperOffset+1024 {
+		return region{}, false
+	}
+	sb := data[ext4SuperOffset:]
+	if binary.LittleEndian.Uint32(sb[superFeatureCompat:])&compatHasJournal == 0 ||
+		sb[superJnlBackupType] != jnlBackupBlocks {
+		return region{}, false
+	}
+	blockSize := 1024 << binary.LittleEndian.Uint32(sb[0x18:])
+	blocks := sb[superJnlBlocks:]
+	size := int(binary.LittleEndian.Uint32(blocks[16*4:]))
+	var start uint64
+	if binary.LittleEndian.Uint32(sb[superFeatureIncompat:])&incompatExtents != 0 &&
+		binary.LittleEndian.Uint16(blocks) == extentHeaderMagic {
+		if depth := binary.LittleEndian.Uint16(blocks[6:]); depth != 0 {
+			return region{}, false
+		}
+		extent := blocks[12:]
+		start = uint64(binary.LittleEndian.Uint16(extent[6:]))<<32 | uint64(binary.LittleEndian.Uint32(extent[8:]))
+		if extentSize := int(binary.LittleEndian.Uint16(extent[4:])) * blockSize; extentSize < size {
+			size = extentSize
+		}
+	} else {
+		start = uint64(binary.LittleEndian.Uint32(blocks))
+	}
+	return region{int(start) * blockSize, size}, start != 0 && size != 0
+}
+
+// xfsJournal finds the internal log using the superblock.
+func xfsJournal(data []byte) (region, bool) {
+	if len(data) < 512 {
+		return region{}, false
+	}
+	blockSize := int(binary.BigEndian.Uint32(data[4:]))
+	logStart := binary.BigEndian.Uint64(data[48:])
+	logBlocks := int(binary.BigEndian.Uint32(data[96:]))
+	agBlocks := uint64(binary.BigEndian.Uint32(data[84:]))
+	agBlockLog := data[124]
+	if logStart == 0 || logBlocks == 0 {
+		// External log.
+		return region{}, false
+	}
+	// Log start is encoded as AG number + block within the AG.
+	agno := logStart >> agBlockLog
+	agbno := logStart & (1<<agBlockLog - 1)
+	return region{int(agno*agBlocks+agbno) * blockSize, logBlocks * blockSize}, true
+}
+
+// ntfsMetadata returns the boot sector and the first MFT records (system files).
+func ntfsMetadata(data []byte) []region {
+	if len(data) < 512 {
+		return nil
+	}
+	sectorSize := int(binary.LittleEndian.Uint16(data[0x0b:]))
+	sectorsPerCluster := int(data[0x0d])
+	if sectorsPerCluster > 0x80 {
+		sectorsPerCluster = 1 << uint(256-sectorsPerCluster)
+	}
+	clusterSize := sectorSize * sectorsPerCluster
+	mft := int(binary.LittleEndian.Uint64(data[0x30:])) * clusterSize
+	recordSize := int(int8(data[0x40]))
+	if recordSize < 0 {
+		recordSize = 1 << uint(-recordSize)
+	} else {
+		recordSize *= clusterSize
+	}
+	return []region{{0, 512}, {mft, 16 * recordSize}}
+}
@@ -8,7 +8,7 @@
 // syz-imagegen generates sys/linux/test/syz_mount_image_* test files.
 // It requires the following packages to be installed:
 //	f2fs-tools, xfsprogs, reiserfsprogs, gfs2-utils, ocfs2-tools, genromfs, erofs-utils, makefs, udftools,
-//	mtd-utils, nilfs-tools, squashfs-tools, genisoimage.
+//	mtd-utils, nilfs-tools, squashfs-tools, genisoimage, bcachefs-tools, ntfs-3g.
 package main
 
 import (
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"hash/crc32"
 	"io/ioutil"
+	"math/rand"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -49,6 +50,8 @@ type FileSystem struct {
 	MkfsFlagCombinations [][]string
 	// Custom mkfs invocation, if nil then mkfs.name is invoked in a standard way.
 	Mkfs func(image *Image) error
+	// Corrupted variants of each image to generate in addition to the valid image.
+	Corruptions []Corruption
 }
 
 // nolint:lll
@@ -68,6 +71,10 @@ var fileSystems = []FileSystem{
 				"-O extra_attr -O flexible_inline_xattr -O inode_checksum -O inode_crtime -O project_quota",
 			},
 		},
+		// Superblock and its backup.
+		Corruptions: []Corruption{
+			metadataBitflips(4, fixedRegions(region{1 << 10, 3 << 10}, region{5 << 10, 3 << 10})),
+		},
 	},
 	{
 		Name:    "btrfs",
@@ -78,6 +85,28 @@ var fileSystems = []FileSystem{
 			{"--csum crc32c", "--csum xxhash", "--csum sha256", "--csum blake2"},
 			{"--nodesize 4096 -O mixed-bg", "-O extref", "-O raid56", "-O no-holes", "-O raid1c34"},
 		},
+		// Primary superblock.
+		Corruptions: []Corruption{
+			metadataBitflips(4, fixedRegions(region{64 << 10, 4 << 10})),
+		},
+	},
+	{
+		Name:    "bcachefs",
+		MinSize: 16 << 20,
+		MkfsFlagCombinations: [][]string{
+			{"--block_size=512", "--block_size=4096"},
+			{"--metadata_checksum=none", "--metadata_checksum=crc32c", "--metadata_checksum=xxhash"},
+			{"", "--compression=lz4", "--compression=zstd"},
+			{"", "--metadata_replicas=2 --data_replicas=1"},
+		},
+		Mkfs: func(image *Image) error {
+			_, err := runCmd("bcachefs", append(append([]string{"format", "-f"}, image.flags...), image.disk)...)
+			return err
+		},
+		// Superblock layout and the primary superblock.
+		Corruptions: []Corruption{
+			metadataBitflips(4, fixedRegions(region{4 << 10, 4 << 10})),
+		},
 	},
 	{
 		Name:      "vfat",
@@ -133,6 +162,11 @@ var fileSystems = []FileSystem{
 			},
 			{"-l sunit=16", "-l sunit=64", "-l sunit=128", "-l su=8k"},
 		},
+		// Superblock, AGF, AGI and AGFL of the first allocation group.
+		Corruptions: []Corruption{
+			metadataBitflips(4, fixedRegions(region{0, 4 << 10})),
+			truncatedJournal(xfsJournal),
+		},
 	},
 	{
 		Name:    "minix",
@@ -194,6 +228,32 @@ var fileSystems = []FileSystem{
 			},
 			{"", "-I"},
 		},
+		Corruptions: []Corruption{
+			metadataBitflips(4, ntfsMetadata),
+		},
+	},
+	{
+		// The same images as for ntfs, but mounted with the newer ntfs3 driver.
+		Name:      "ntfs3",
+		MinSize:   1 << 20,
+		MkfsFlags: []string{"-f", "-F", "-L", "syzkaller"},
+		MkfsFlagCombinations: [][]string{
+			{
+				"-s 512 -c 1024",
+				"-s 512 -c 4096",
+				"-s 1024 -c 4096",
+				"-s 4096 -c 4096",
+				"-s 4096 -c 65536",
+			},
+			{"", "-I"},
+		},
+		Mkfs: func(image *Image) error {
+			_, err := runCmd("mkfs.ntfs", append(image.flags, image.disk)...)
+			return err
+		},
+		Corruptions: []Corruption{
+			metadataBitflips(4, ntfsMetadata),
+		},
 	},
 	{
 		Name:      "ext4",
@@ -229,6 +289,10 @@ var fileSystems = []FileSystem{
 				"-b 4096 -I 512 -E lazy_itable_init=1 -E num_backup_sb=1 -E packed_meta_blocks=0 -O ^64bit -O ^extents -O ^bigalloc -O dir_index -O ^dir_nlink -O ea_inode -O encrypt -O ^ext_attr -O ^extra_isize -O ^flex_bg -O huge_file -O inline_data -O ^large_dir -O ^metadata_csum -O ^meta_bg -O ^mmp -O ^quota -O ^resize_inode -O sparse_super2 -O uninit_bg -O ^verity",
 			},
 		},
+		Corruptions: []Corruption{
+			metadataBitflips(4, ext4Metadata),
+			truncatedJournal(ext4Journal),
+		},
 	},
 	{
 		Name:      "gfs2",
@@ -328,6 +392,10 @@ var fileSystems = []FileSystem{
 			_, err := runCmd("mkfs.erofs", append(image.flags, image.disk, image.templateDir)...)
 			return err
 		},
+		// Superblock.
+		Corruptions: []Corruption{
+			metadataBitflips(2, fixedRegions(region{1 << 10, 128})),
+		},
 	},
 	{
 		Name:    "efs",
@@ -466,6 +534,8 @@ func main() {
 		flagPopulate  = flag.String("populate", "", "populate the specified image with files (for internal use)")
 		flagKeepImage = flag.Bool("keep", false, "save disk images as .img files")
 		flagFS        = flag.String("fs", "", "generate images only for this single filesystem")
+		flagCorrupt   = flag.String("corrupt", "all", "comma-separated list of corruptions to generate"+
+			" (bitflips, journal), or all/none")
 	)
 	flag.Parse()
 	if *flagDebug {
@@ -481,7 +551,7 @@ func main() {
 	if err != nil {
 		tool.Fail(err)
 	}
-	images, err := generateImages(target, *flagFS, *flagList)
+	images, err := generateImages(target, *flagFS, *flagCorrupt, *flagList)
 	if err != nil {
 		tool.Fail(err)
 	}
@@ -574,12 +644,13 @@ func printResults(images []*Image, shutdown chan struct{}, keepImage, verbose bo
 	}
 }
 
-func generateImages(target *prog.Target, flagFS string, list bool) ([]*Image, error) {
+func generateImages(target *prog.Target, flagFS, flagCorrupt string, list bool) ([]*Image, error) {
 	var images []*Image
 	for _, fs := range fileSystems {
 		if flagFS != "" && flagFS != fs.Name {
 			continue
 		}
+		fs.Corruptions = selectCorruptions(fs.Corruptions, flagCorrupt)
 		index := 0
 		enumerateFlags(target, &images, &index, fs, fs.MkfsFlags, 0)
 		if list {
@@ -599,6 +670,21 @@ func generateImages(target *prog.Target, flagFS string, list bool) ([]*Image, er
 	return images, nil
 }
 
+func selectCorruptions(corruptions []Corruption, selected string) []Corruption {
+	if selected == "all" {
+		return corruptions
+	}
+	var res []Corruption
+	for _, c := range corruptions {
+		for _, name := range strings.Split(selected, ",") {
+			if c.Name == name {
+				res = append(res, c)
+			}
+		}
+	}
+	return res
+}
+
 func enumerateFlags(target *prog.Target, images *[]*Image, index *int, fs FileSystem, flags []string, flagsIndex int) {
 	if flagsIndex == len(fs.MkfsFlagCombinations) {
 		*images = append(*images, &Image{
@@ -665,6 +751,22 @@ func (image *Image) generateSize() error {
 		return err
 	}
 	image.hash = crc32.ChecksumIEEE(data)
+	if err := image.writeTest(outFile, data); err != nil {
+		return err
+	}
+	for _, c := range image.fs.Corruptions {
+		corrupted := append([]byte{}, data...)
+		if !c.Corrupt(corrupted, rand.New(rand.NewSource(int64(image.hash)))) {
+			continue
+		}
+		if err := image.writeTest(outFile+"_"+c.Name, corrupted); err != nil {
+			return fmt.Errorf("%v corruption: %v", c.Name, err)
+		}
+	}
+	return nil
+}
+
+func (image *Image) writeTest(outFile string, data []byte) error {
 	out, err := writeImage(image.fs, data)
 	if err != nil {
 		return err
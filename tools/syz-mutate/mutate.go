@@ -2,6 +2,8 @@
 // Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
 
 // mutates mutates a given program and prints result.
+// With -interactive flag it allows to mutate the program step by step
+// with named mutation operators, see "help" command for details.
 package main
 
 import (
@@ -27,6 +29,9 @@ var (
 	flagLen    = flag.Int("len", prog.RecommendedCalls, "number of calls in programs")
 	flagEnable = flag.String("enable", "", "comma-separated list of enabled syscalls")
 	flagCorpus = flag.String("corpus", "", "name of the corpus file")
+	flagRepl   = flag.Bool("interactive", false, "mutate the program interactively")
+	flagExec   = flag.String("exec", "", "command to execute programs in interactive mode"+
+		" ({} is replaced with the program file)")
 )
 
 func main() {
@@ -69,17 +74,40 @@ func main() {
 	if flag.NArg() == 0 {
 		p = target.Generate(rs, *flagLen, ct)
 	} else {
-		data, err := ioutil.ReadFile(flag.Arg(0))
+		p, err = loadProg(target, flag.Arg(0))
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to read prog file: %v\n", err)
+			fmt.Fprintf(os.Stderr, "%v\n", err)
 			os.Exit(1)
 		}
-		p, err = target.Deserialize(data, prog.Strict)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to deserialize the program: %v\n", err)
-			os.Exit(1)
+		if !*flagRepl {
+			p.Mutate(rs, *flagLen, ct, corpus)
 		}
-		p.Mutate(rs, *flagLen, ct, corpus)
+	}
+	if *flagRepl {
+		r := &repl{
+			target:  target,
+			rs:      rs,
+			ct:      ct,
+			corpus:  corpus,
+			ncalls:  *flagLen,
+			execCmd: *flagExec,
+			p:       p,
+			out:     os.Stdout,
+		}
+		r.run(os.Stdin)
+		return
 	}
 	fmt.Printf("%s\n", p.Serialize())
 }
+
+func loadProg(target *prog.Target, file string) (*prog.Prog, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prog file: %v", err)
+	}
+	p, err := target.Deserialize(data, prog.Strict)
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize the program: %v", err)
+	}
+	return p, nil
+}
@@ -0,0 +1,237 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/prog"
+)
+
+// repl implements the interactive mode (-interactive): the program is mutated step by step
+// with named mutation operators, every step shows the diff against the previous version.
+type repl struct {
+	target   *prog.Target
+	rs       rand.Source
+	ct       *prog.ChoiceTable
+	corpus   []*prog.Prog
+	ncalls   int
+	execCmd  string
+	autoExec bool
+	history  []*prog.Prog
+	p        *prog.Prog
+	out      io.Writer
+}
+
+const replHelp = `commands:
+  show                 print the current program
+  OP [N]               apply mutation operator OP (N times), OP is one of: %v
+  mutate [N]           apply a random sequence of mutations (N times)
+  undo                 revert the last step
+  exec                 execute the current program with -exec command
+  autoexec on|off      execute the program after every step
+  save FILE            save the current program to FILE
+  load FILE            load a program from FILE
+  help                 print this help
+  quit                 exit
+`
+
+func (r *repl) run(in io.Reader) {
+	fmt.Fprintf(r.out, "%s\n", r.p.Serialize())
+	s := bufio.NewScanner(in)
+	for {
+		fmt.Fprintf(r.out, "> ")
+		if !s.Scan() {
+			fmt.Fprintf(r.out, "\n")
+			return
+		}
+		args := strings.Fields(s.Text())
+		if len(args) == 0 {
+			continue
+		}
+		if args[0] == "quit" || args[0] == "exit" {
+			return
+		}
+		if err := r.command(args[0], args[1:]); err != nil {
+			fmt.Fprintf(r.out, "error: %v\n", err)
+		}
+	}
+}
+
+func (r *repl) command(cmd string, args []string) error {
+	switch cmd {
+	case "help":
+		fmt.Fprintf(r.out, replHelp, strings.Join(prog.MutationOps, ", "))
+	case "show":
+		fmt.Fprintf(r.out, "%s", r.p.Serialize())
+	case "undo":
+		if len(r.history) == 0 {
+			return fmt.Errorf("nothing to undo")
+		}
+		prev := r.p
+		r.p = r.history[len(r.history)-1]
+		r.history = r.history[:len(r.history)-1]
+		r.printDiff(prev, r.p)
+	case "exec":
+		return r.execute()
+	case "autoexec":
+		if len(args) != 1 || args[0] != "on" && args[0] != "off" {
+			return fmt.Errorf("usage: autoexec on|off")
+		}
+		if args[0] == "on" && r.execCmd == "" {
+			return fmt.Errorf("no -exec command specified")
+		}
+		r.autoExec = args[0] == "on"
+	case "save":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: save FILE")
+		}
+		return osutil.WriteFile(args[0], r.p.Serialize())
+	case "load":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: load FILE")
+		}
+		p, err := loadProg(r.target, args[0])
+		if err != nil {
+			return err
+		}
+		r.step(p)
+	default:
+		return r.mutate(cmd, args)
+	}
+	return nil
+}
+
+func (r *repl) mutate(op string, args []string) error {
+	n := 1
+	if len(args) != 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n <= 0 {
+			return fmt.Errorf("bad number of steps %q", args[0])
+		}
+	}
+	p := r.p.Clone()
+	applied := 0
+	for i := 0; i < n; i++ {
+		if op == "mutate" {
+			p.Mutate(r.rs, r.ncalls, r.ct, r.corpus)
+			applied++
+			continue
+		}
+		ok, err := p.MutateOp(r.rs, op, r.ncalls, r.ct, r.corpus)
+		if err != nil {
+			return fmt.Errorf("%v (try help)", err)
+		}
+		if ok {
+			applied++
+		}
+	}
+	if applied == 0 {
+		return fmt.Errorf("%v is not applicable to the program", op)
+	}
+	r.step(p)
+	return nil
+}
+
+func (r *repl) step(p *prog.Prog) {
+	r.history = append(r.history, r.p)
+	prev := r.p
+	r.p = p
+	r.printDiff(prev, r.p)
+	if r.autoExec {
+		if err := r.execute(); err != nil {
+			fmt.Fprintf(r.out, "error: %v\n", err)
+		}
+	}
+}
+
+func (r *repl) printDiff(prev, cur *prog.Prog) {
+	diff := lineDiff(strings.Split(strings.TrimSpace(string(prev.Serialize())), "\n"),
+		strings.Split(strings.TrimSpace(string(cur.Serialize())), "\n"))
+	if len(diff) == 0 {
+		fmt.Fprintf(r.out, "(no changes)\n")
+	}
+	for _, line := range diff {
+		fmt.Fprintf(r.out, "%s\n", line)
+	}
+}
+
+// execute runs the -exec command with the program file, {} in the command is replaced
+// with the file name, otherwise the file name is appended to the command.
+func (r *repl) execute() error {
+	if r.execCmd == "" {
+		return fmt.Errorf("no -exec command specified")
+	}
+	file, err := osutil.WriteTempFile(r.p.Serialize())
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file)
+	command := r.execCmd
+	if strings.Contains(command, "{}") {
+		command = strings.Replace(command, "{}", file, -1)
+	} else {
+		command += " " + file
+	}
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = r.out
+	cmd.Stderr = r.out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%v: %v", command, err)
+	}
+	return nil
+}
+
+// lineDiff returns a unified-like diff of two texts (without hunk headers):
+// unchanged lines are prefixed with space, removed with '-' and added with '+'.
+// Returns nil if the texts are equal.
+func lineDiff(a, b []string) []string {
+	// Programs are small, so the simple quadratic LCS is fine.
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var res []string
+	changed := false
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			res = append(res, " "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			res = append(res, "-"+a[i])
+			i++
+			changed = true
+		default:
+			res = append(res, "+"+b[j])
+			j++
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return res
+}
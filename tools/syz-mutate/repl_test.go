@@ -0,0 +1,29 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		a, b string
+		diff []string
+	}{
+		{"a b c", "a b c", nil},
+		{"a b c", "a x c", []string{" a", "-b", "+x", " c"}},
+		{"a c", "a b c", []string{" a", "+b", " c"}},
+		{"a b c", "b", []string{"-a", " b", "-c"}},
+		{"", "a", []string{"+a"}},
+	}
+	for _, test := range tests {
+		diff := lineDiff(strings.Fields(test.a), strings.Fields(test.b))
+		if !reflect.DeepEqual(diff, test.diff) {
+			t.Errorf("diff(%q, %q):\ngot:  %q\nwant: %q", test.a, test.b, diff, test.diff)
+		}
+	}
+}
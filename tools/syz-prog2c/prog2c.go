@@ -29,6 +29,7 @@ var (
 	flagHandleSegv = flag.Bool("segv", false, "catch and ignore SIGSEGV")
 	flagUseTmpDir  = flag.Bool("tmpdir", false, "create a temporary dir and execute inside it")
 	flagTrace      = flag.Bool("trace", false, "trace syscall results")
+	flagStrace     = flag.Bool("strace", false, "print all calls with arguments and results (strace-like)")
 	flagRepro      = flag.Bool("repro", false, "add heartbeats used by pkg/repro")
 	flagStrict     = flag.Bool("strict", false, "parse input program in strict mode")
 	flagLeak       = flag.Bool("leak", false, "do leak checking")
@@ -94,6 +95,7 @@ func main() {
 		HandleSegv:    *flagHandleSegv,
 		Repro:         *flagRepro,
 		Trace:         *flagTrace,
+		Strace:        *flagStrace,
 	}
 	src, err := csource.Write(p, opts)
 	if err != nil {
@@ -0,0 +1,343 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+// syz-replay executes a large set of programs on multiple VMs. Usage:
+//
+//	syz-replay -config=manager.cfg -workdir=replay corpus.db [execution.log...]
+//
+// Programs are split into chunks, every chunk is executed with syz-execprog
+// on a freshly booted VM. Coverage of all chunks is aggregated in workdir/rawcover
+// (suitable for syz-cover), crashes are saved in workdir/crashes along with
+// the execution log of the chunk that can be passed to syz-repro.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/syzkaller/pkg/db"
+	"github.com/google/syzkaller/pkg/hash"
+	"github.com/google/syzkaller/pkg/mgrconfig"
+	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/pkg/report"
+	"github.com/google/syzkaller/pkg/tool"
+	"github.com/google/syzkaller/prog"
+	"github.com/google/syzkaller/sys/targets"
+	"github.com/google/syzkaller/vm"
+)
+
+var (
+	flagConfig  = flag.String("config", "", "manager configuration file")
+	flagWorkdir = flag.String("workdir", "replay", "directory for chunks, coverage and crashes")
+	flagChunk   = flag.Int("chunk", 1000, "number of programs executed per VM boot")
+	flagTimeout = flag.Duration("chunk_timeout", time.Hour, "execution timeout for a single chunk")
+	flagDebug   = flag.Bool("debug", false, "dump all VM output to console")
+)
+
+// Keep in sync with tools/syz-execprog.
+const coverPrefix = "covered PCs: "
+
+func main() {
+	flag.Parse()
+	if len(flag.Args()) == 0 || *flagConfig == "" || *flagChunk <= 0 {
+		fmt.Fprintf(os.Stderr, "usage: syz-replay [flags] <corpus.db|execution.log>+\n")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	cfg, err := mgrconfig.LoadFile(*flagConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	reporter, err := report.NewReporter(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	vmPool, err := vm.Create(cfg, *flagDebug)
+	if err != nil {
+		log.Fatal(err)
+	}
+	progs := loadPrograms(cfg.Target, flag.Args())
+	if len(progs) == 0 {
+		log.Fatalf("no programs to execute")
+	}
+	chunks, err := writeChunks(filepath.Join(*flagWorkdir, "chunks"), progs, *flagChunk)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("executing %v programs in %v chunks on %v VMs", len(progs), len(chunks), vmPool.Count())
+
+	ctx := &Context{
+		cfg:      cfg,
+		reporter: reporter,
+		vmPool:   vmPool,
+		chunks:   make(chan string, len(chunks)),
+		cover:    make(map[uint64]bool),
+		crashes:  make(map[string]int),
+	}
+	for _, chunk := range chunks {
+		ctx.chunks <- chunk
+	}
+	close(ctx.chunks)
+	shutdown := make(chan struct{})
+	osutil.HandleInterrupts(shutdown)
+	go func() {
+		<-shutdown
+		close(vm.Shutdown)
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < vmPool.Count(); i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			ctx.loop(index)
+		}(i)
+	}
+	wg.Wait()
+
+	if err := ctx.saveCoverage(filepath.Join(*flagWorkdir, "rawcover")); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("done: executed %v/%v chunks, covered %v PCs, %v crashes",
+		ctx.done, len(chunks), len(ctx.cover), len(ctx.crashes))
+	for title, count := range ctx.crashes {
+		log.Printf("%6v %v", count, title)
+	}
+}
+
+type Context struct {
+	cfg      *mgrconfig.Config
+	reporter *report.Reporter
+	vmPool   *vm.Pool
+	chunks   chan string
+
+	mu      sync.Mutex
+	done    int
+	cover   map[uint64]bool
+	crashes map[string]int
+}
+
+func (ctx *Context) loop(index int) {
+	for chunk := range ctx.chunks {
+		select {
+		case <-vm.Shutdown:
+			return
+		default:
+		}
+		rep, pcs, err := ctx.runChunk(index, chunk)
+		if err != nil {
+			log.Printf("vm-%v: %v: %v", index, filepath.Base(chunk), err)
+			continue
+		}
+		ctx.mu.Lock()
+		ctx.done++
+		for _, pc := range pcs {
+			ctx.cover[pc] = true
+		}
+		if rep != nil {
+			ctx.crashes[rep.Title]++
+		}
+		log.Printf("vm-%v: finished %v: executed chunks %v, covered PCs %v, crashes %v",
+			index, filepath.Base(chunk), ctx.done, len(ctx.cover), len(ctx.crashes))
+		ctx.mu.Unlock()
+		if rep != nil {
+			ctx.saveCrash(chunk, rep)
+		}
+	}
+}
+
+func (ctx *Context) runChunk(index int, chunk string) (*report.Report, []uint64, error) {
+	inst, err := ctx.vmPool.Create(index)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create instance: %v", err)
+	}
+	defer inst.Close()
+	execprogBin, err := inst.Copy(ctx.cfg.ExecprogBin)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to copy execprog: %v", err)
+	}
+	// If SyzExecutorCmd is provided, it means that syz-executor is already in
+	// the image, so no need to copy it.
+	executorBin := ctx.cfg.SysTarget.ExecutorBin
+	if executorBin == "" {
+		executorBin, err = inst.Copy(ctx.cfg.ExecutorBin)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to copy executor: %v", err)
+		}
+	}
+	chunkFile, err := inst.Copy(chunk)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to copy programs: %v", err)
+	}
+	outc, errc, err := inst.Run(*flagTimeout, nil, ctx.execprogCmd(execprogBin, executorBin, chunkFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to run execprog: %v", err)
+	}
+	cov := new(coverParser)
+	stop := make(chan struct{})
+	rep := inst.MonitorExecution(cov.tee(outc, stop), errc, ctx.reporter, vm.ExitNormal|vm.ExitTimeout)
+	close(stop)
+	return rep, cov.result(), nil
+}
+
+func (ctx *Context) execprogCmd(execprog, executor, progFile string) string {
+	cfg := ctx.cfg
+	osArg := ""
+	if targets.Get(cfg.TargetOS, cfg.TargetArch).HostFuzzer {
+		osArg = " -os=" + cfg.TargetOS
+	}
+	return fmt.Sprintf("%v -executor=%v -arch=%v%v -sandbox=%v -procs=%v -repeat=1"+
+		" -cover=%v -output=true -printcover=%v %v %v",
+		execprog, executor, cfg.TargetArch, osArg, cfg.Sandbox, cfg.Procs,
+		cfg.Cover, cfg.Cover, tool.OptionalFlags([]tool.Flag{
+			{Name: "slowdown", Value: fmt.Sprint(cfg.Timeouts.Slowdown)},
+		}), progFile)
+}
+
+func (ctx *Context) saveCrash(chunk string, rep *report.Report) {
+	dir := filepath.Join(*flagWorkdir, "crashes", hash.String([]byte(rep.Title)))
+	osutil.MkdirAll(dir)
+	ctx.mu.Lock()
+	index := 0
+	for ; osutil.IsExist(filepath.Join(dir, fmt.Sprintf("log%v", index))); index++ {
+	}
+	// Reserve the index.
+	osutil.WriteFile(filepath.Join(dir, fmt.Sprintf("log%v", index)), rep.Output)
+	ctx.mu.Unlock()
+	log.Printf("saving crash %q with index %v in %v", rep.Title, index, dir)
+	if err := osutil.WriteFile(filepath.Join(dir, "description"), []byte(rep.Title+"\n")); err != nil {
+		log.Printf("failed to write crash description: %v", err)
+	}
+	if len(rep.Report) > 0 {
+		if err := osutil.WriteFile(filepath.Join(dir, fmt.Sprintf("report%v", index)), rep.Report); err != nil {
+			log.Printf("failed to write crash report: %v", err)
+		}
+	}
+	if err := osutil.CopyFile(chunk, filepath.Join(dir, fmt.Sprintf("programs%v", index))); err != nil {
+		log.Printf("failed to save crash programs: %v", err)
+	}
+}
+
+func (ctx *Context) saveCoverage(file string) error {
+	pcs := make([]uint64, 0, len(ctx.cover))
+	for pc := range ctx.cover {
+		pcs = append(pcs, pc)
+	}
+	sort.Slice(pcs, func(i, j int) bool { return pcs[i] < pcs[j] })
+	buf := new(bytes.Buffer)
+	for _, pc := range pcs {
+		fmt.Fprintf(buf, "0x%x\n", pc)
+	}
+	return osutil.WriteFile(file, buf.Bytes())
+}
+
+// coverParser extracts PCs printed by syz-execprog -printcover from the VM output.
+type coverParser struct {
+	mu   sync.Mutex
+	line []byte
+	pcs  []uint64
+}
+
+// tee parses VM output and forwards it to the returned channel until stop is closed.
+func (cp *coverParser) tee(outc <-chan []byte, stop <-chan struct{}) <-chan []byte {
+	res := make(chan []byte)
+	go func() {
+		defer close(res)
+		for out := range outc {
+			cp.mu.Lock()
+			cp.parse(out)
+			cp.mu.Unlock()
+			select {
+			case res <- out:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return res
+}
+
+func (cp *coverParser) result() []uint64 {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.pcs
+}
+
+func (cp *coverParser) parse(out []byte) {
+	cp.line = append(cp.line, out...)
+	for {
+		nl := bytes.IndexByte(cp.line, '\n')
+		if nl == -1 {
+			break
+		}
+		cp.parseLine(string(cp.line[:nl]))
+		cp.line = cp.line[nl+1:]
+	}
+}
+
+func (cp *coverParser) parseLine(line string) {
+	pos := strings.Index(line, coverPrefix)
+	if pos == -1 {
+		return
+	}
+	for _, str := range strings.Split(strings.TrimSpace(line[pos+len(coverPrefix):]), ",") {
+		// The line may be garbled by concurrent kernel output, so ignore what we can't parse.
+		if pc, err := strconv.ParseUint(str, 0, 64); err == nil {
+			cp.pcs = append(cp.pcs, pc)
+		}
+	}
+}
+
+func loadPrograms(target *prog.Target, files []string) []*prog.Prog {
+	var progs []*prog.Prog
+	for _, fn := range files {
+		if corpus, err := db.Open(fn, false); err == nil {
+			for _, rec := range corpus.Records {
+				p, err := target.Deserialize(rec.Val, prog.NonStrict)
+				if err != nil {
+					continue
+				}
+				progs = append(progs, p)
+			}
+			continue
+		}
+		data, err := ioutil.ReadFile(fn)
+		if err != nil {
+			log.Fatalf("failed to read log file: %v", err)
+		}
+		for _, entry := range target.ParseLog(data) {
+			progs = append(progs, entry.P)
+		}
+	}
+	log.Printf("parsed %v programs", len(progs))
+	return progs
+}
+
+// writeChunks writes programs as execution logs of at most n programs each.
+func writeChunks(dir string, progs []*prog.Prog, n int) ([]string, error) {
+	if err := osutil.MkdirAll(dir); err != nil {
+		return nil, err
+	}
+	var chunks []string
+	for i := 0; i < len(progs); i += n {
+		buf := new(bytes.Buffer)
+		for j := i; j < i+n && j < len(progs); j++ {
+			fmt.Fprintf(buf, "executing program 0:\n%s\n", progs[j].Serialize())
+		}
+		file := filepath.Join(dir, fmt.Sprintf("chunk%v", len(chunks)))
+		if err := osutil.WriteFile(file, buf.Bytes()); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, file)
+	}
+	return chunks, nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+// syz-repro2kselftest converts a syzkaller reproducer into a kselftest-compatible test
+// (files for a tools/testing/selftests subdirectory) that can be upstreamed as a regression test.
+// Usage:
+//	syz-repro2kselftest -prog repro.syz -name foo_uaf -title "KASAN: use-after-free in foo" -out dir
+// Reproducers saved by syz-manager and syzbot contain the C reproducer options in the first
+// comment line of the program, these options are used unless -opts is given.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/google/syzkaller/pkg/csource"
+	"github.com/google/syzkaller/pkg/osutil"
+	"github.com/google/syzkaller/prog"
+	_ "github.com/google/syzkaller/sys"
+)
+
+var (
+	flagOS      = flag.String("os", runtime.GOOS, "target os")
+	flagArch    = flag.String("arch", runtime.GOARCH, "target arch")
+	flagProg    = flag.String("prog", "", "file with the reproducer program (required)")
+	flagOpts    = flag.String("opts", "", "C reproducer options in JSON format (default: taken from the program)")
+	flagName    = flag.String("name", "", "test name (required)")
+	flagTitle   = flag.String("title", "", "title of the bug the reproducer triggers")
+	flagTimeout = flag.Duration("timeout", time.Minute, "for how long the reproducer is executed")
+	flagConfigs = flag.String("configs", "", "comma-separated list of additional required kernel configs")
+	flagOut     = flag.String("out", "", "output directory (required)")
+	flagBuild   = flag.Bool("build", false, "also build the generated test")
+)
+
+func main() {
+	flag.Parse()
+	if *flagProg == "" || *flagName == "" || *flagOut == "" {
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	target, err := prog.GetTarget(*flagOS, *flagArch)
+	if err != nil {
+		failf("%v", err)
+	}
+	data, err := ioutil.ReadFile(*flagProg)
+	if err != nil {
+		failf("failed to read prog file: %v", err)
+	}
+	optsData := []byte(*flagOpts)
+	if len(optsData) == 0 {
+		optsData = reproOptions(data)
+	}
+	if len(optsData) == 0 {
+		failf("the program does not contain C reproducer options, specify them with -opts")
+	}
+	opts, err := csource.DeserializeOptions(optsData)
+	if err != nil {
+		failf("failed to parse C reproducer options: %v", err)
+	}
+	// The test has own timeout handling, so the reproducer runs until it's killed.
+	if opts.Repeat {
+		opts.RepeatTimes = 0
+	}
+	// Heartbeats are only useful for pkg/repro.
+	opts.Repro = false
+	p, err := target.Deserialize(data, prog.NonStrict)
+	if err != nil {
+		failf("failed to deserialize the program: %v", err)
+	}
+	var configs []string
+	if *flagConfigs != "" {
+		configs = strings.Split(*flagConfigs, ",")
+	}
+	test, err := csource.WriteKselftest(p, opts, csource.KselftestOptions{
+		Name:    *flagName,
+		Title:   *flagTitle,
+		Timeout: *flagTimeout,
+		Configs: configs,
+	})
+	if err != nil {
+		failf("failed to generate the test: %v", err)
+	}
+	if formatted, err := csource.Format(test.Source); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	} else {
+		test.Source = formatted
+	}
+	if err := osutil.MkdirAll(*flagOut); err != nil {
+		failf("%v", err)
+	}
+	for name, data := range map[string][]byte{
+		*flagName + ".c": test.Source,
+		"Makefile":       test.Makefile,
+		"config":         test.Config,
+		"settings":       test.Settings,
+	} {
+		if err := osutil.WriteFile(filepath.Join(*flagOut, name), data); err != nil {
+			failf("%v", err)
+		}
+	}
+	if !*flagBuild {
+		return
+	}
+	bin, err := csource.Build(target, test.Source)
+	if err != nil {
+		failf("failed to build the test: %v", err)
+	}
+	os.Remove(bin)
+	fmt.Fprintf(os.Stderr, "test build OK\n")
+}
+
+// reproOptions returns serialized C reproducer options from the first comment line of the program.
+func reproOptions(data []byte) []byte {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if !bytes.HasPrefix(line, []byte("#")) {
+			return nil
+		}
+		line = bytes.TrimSpace(line[1:])
+		if bytes.HasPrefix(line, []byte("{")) {
+			return line
+		}
+	}
+	return nil
+}
+
+func failf(msg string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, msg+"\n", args...)
+	os.Exit(1)
+}
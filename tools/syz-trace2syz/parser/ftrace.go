@@ -0,0 +1,113 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/syzkaller/pkg/log"
+)
+
+// ParseFtraceData parses ftrace output (tracefs trace/trace_pipe files) with syscalls events:
+//
+//	cat-1234  [002] .... 12.345678: sys_openat(dfd: ffffff9c, filename: 7ffd9b5f1e50, flags: 0, mode: 0)
+//	cat-1234  [002] .... 12.345690: sys_openat -> 0x3
+//
+// or raw_syscalls events:
+//
+//	cat-1234  [002] .... 12.345678: sys_enter: NR 257 (ffffff9c, 7ffd9b5f1e50, 0, 0, 0, 0)
+//	cat-1234  [002] .... 12.345690: sys_exit: NR 257 = 3
+//
+// raw_syscalls events contain only syscall numbers, syscalls maps them to names.
+// Events of different threads are interleaved, so enter and exit events are matched per thread.
+// Ftrace does not dereference pointers, so all arguments are raw register values.
+func ParseFtraceData(data []byte, syscalls map[uint64]string) (*TraceTree, error) {
+	tree := NewTraceTree()
+	// Threads that have entered a syscall, but not yet exited.
+	pending := make(map[int64]string)
+	enter := func(pid int64, name string, args []IrType) {
+		if name == "" {
+			return
+		}
+		if pending[pid] != "" {
+			log.Logf(2, "missing exit of %v in thread %v", pending[pid], pid)
+		}
+		pending[pid] = name
+		tree.add(NewSyscall(pid, name, args, 0, true, false))
+	}
+	exit := func(pid int64, name string, ret int64) {
+		if name == "" || pending[pid] != name {
+			// The trace may start in the middle of the syscall.
+			log.Logf(2, "skipping exit of %v without enter in thread %v", name, pid)
+			return
+		}
+		delete(pending, pid)
+		tree.add(NewSyscall(pid, name, nil, ret, false, true))
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 64<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		match := ftraceLineRe.FindStringSubmatch(line)
+		if match == nil {
+			log.Logf(4, "skipping line: %s", line)
+			continue
+		}
+		pid, _ := strconv.ParseInt(match[1], 10, 64)
+		event := match[2]
+		if m := ftraceRawEnterRe.FindStringSubmatch(event); m != nil {
+			nr, _ := strconv.ParseUint(m[1], 10, 64)
+			var args []IrType
+			for _, arg := range strings.Split(m[2], ",") {
+				args = append(args, parseFtraceValue(arg))
+			}
+			enter(pid, syscalls[nr], args)
+		} else if m := ftraceRawExitRe.FindStringSubmatch(event); m != nil {
+			nr, _ := strconv.ParseUint(m[1], 10, 64)
+			exit(pid, syscalls[nr], parseInt(m[2]))
+		} else if m := ftraceEnterRe.FindStringSubmatch(event); m != nil {
+			var args []IrType
+			for _, arg := range splitArgs(m[2]) {
+				if colon := strings.Index(arg, ": "); colon != -1 {
+					arg = arg[colon+2:]
+				}
+				args = append(args, parseFtraceValue(arg))
+			}
+			enter(pid, m[1], args)
+		} else if m := ftraceExitRe.FindStringSubmatch(event); m != nil {
+			exit(pid, m[1], parseInt(m[2]))
+		} else {
+			log.Logf(4, "skipping event: %s", event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(tree.TraceMap) == 0 {
+		return nil, nil
+	}
+	return tree, nil
+}
+
+var (
+	// TASK-PID, optional TGID, CPU, optional irq-info flags, timestamp.
+	ftraceLineRe     = regexp.MustCompile(`^\s*.+?-(\d+)\s+(?:\(\s*[\d-]+\)\s+)?\[\d+\]\s+(?:\S+\s+)?[\d.]+:\s+(.*)$`)
+	ftraceRawEnterRe = regexp.MustCompile(`^sys_enter: NR (\d+) \((.*)\)$`)
+	ftraceRawExitRe  = regexp.MustCompile(`^sys_exit: NR (\d+) = (-?\d+)$`)
+	ftraceEnterRe    = regexp.MustCompile(`^sys_(\w+)\((.*)\)$`)
+	ftraceExitRe     = regexp.MustCompile(`^sys_(\w+) -> (0x[0-9a-f]+|-?\d+)$`)
+)
+
+// parseFtraceValue parses an argument value, ftrace prints them in hex without 0x prefix.
+func parseFtraceValue(val string) IrType {
+	v, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(val), "0x"), 16, 64)
+	if err != nil {
+		log.Logf(2, "bad argument value %q", val)
+	}
+	return Constant(v)
+}
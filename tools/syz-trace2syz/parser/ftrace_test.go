@@ -0,0 +1,64 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseFtrace(t *testing.T) {
+	data := `
+# tracer: nop
+#
+#           TASK-PID     CPU#  |||||  TIMESTAMP  FUNCTION
+#              | |         |   |||||     |         |
+             cat-100     [001] .....    10.000000: sys_read -> 0x10
+             cat-100     [001] .....    10.000001: sys_openat(dfd: ffffff9c, filename: 7ffd9b5f1e50, flags: 80000, mode: 0)
+    kworker/0:1-events-7 [000] .....    10.000002: sys_getpid()
+             cat-100     [001] .....    10.000003: sys_openat -> 0x3
+    kworker/0:1-events-7 [000] .....    10.000004: sys_getpid -> 0x7
+             cat-100     [001] d..1.    10.000005: sys_enter: NR 0 (3, 7ffd9b5f0000, 1000, 0, 0, 0)
+             cat-101 (    100) [002] .....    10.000006: sys_enter: NR 39 (0, 0, 0, 0, 0, 0)
+             cat-100     [001] .....    10.000007: sys_exit: NR 0 = -14
+             cat-101 (    100) [002] .....    10.000008: sys_exit: NR 39 = 101
+             cat-100     [001] .....    10.000009: sys_enter: NR 1000 (0, 0, 0, 0, 0, 0)
+             cat-100     [001] .....    10.000010: sys_exit: NR 1000 = 0
+             cat-100     [001] .....    10.000011: sys_close(fd: 3)
+             cat-100     [001] .....    10.000012: sched_switch: prev_comm=cat
+`
+	syscalls := map[uint64]string{
+		0:  "read",
+		39: "getpid",
+	}
+	tree, err := ParseFtraceData([]byte(data), syscalls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int64][]*Syscall{
+		100: {
+			{CallName: "openat", Pid: 100, Args: []IrType{
+				Constant(0xffffff9c), Constant(0x7ffd9b5f1e50), Constant(0x80000), Constant(0)}, Ret: 3},
+			{CallName: "read", Pid: 100, Args: []IrType{
+				Constant(3), Constant(0x7ffd9b5f0000), Constant(0x1000), Constant(0), Constant(0), Constant(0)},
+				Ret: -14},
+			{CallName: "close", Pid: 100, Args: []IrType{Constant(3)}, Paused: true},
+		},
+		101: {
+			{CallName: "getpid", Pid: 101, Args: []IrType{
+				Constant(0), Constant(0), Constant(0), Constant(0), Constant(0), Constant(0)}, Ret: 101},
+		},
+		7: {
+			{CallName: "getpid", Pid: 7, Ret: 7},
+		},
+	}
+	got := make(map[int64][]*Syscall)
+	for pid, trace := range tree.TraceMap {
+		got[pid] = trace.Calls
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/syzkaller/pkg/log"
+)
+
+// ParsePerfData parses output of perf trace, e.g.:
+//
+//	0.046 ( 0.006 ms): cat/4310 openat(dfd: CWD, filename: "/etc/passwd", flags: RDONLY|CLOEXEC) = 3
+//	1000.342 (         ): sleep/1234 nanosleep(rqtp: 0x7ffd2c5f0a70) ...
+//	2000.400 (1000.058 ms): sleep/1234  ... [continued]: nanosleep()) = 0
+//
+// perf omits zero arguments and prefixes of symbolic values by default, so the trace needs
+// to be collected with trace.show_zeros=yes and trace.show_prefix=yes perf config options
+// to get positional arguments and symbolic values that can be resolved with consts.
+// Unresolved symbolic values are replaced with 0.
+func ParsePerfData(data []byte, consts map[string]uint64) (*TraceTree, error) {
+	p := &perfParser{
+		tree:    NewTraceTree(),
+		consts:  consts,
+		pending: make(map[int64]bool),
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 64<<20)
+	for scanner.Scan() {
+		p.parseLine(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(p.tree.TraceMap) == 0 {
+		return nil, nil
+	}
+	return p.tree, nil
+}
+
+type perfParser struct {
+	tree   *TraceTree
+	consts map[string]uint64
+	// Threads that have an unfinished call, the call is completed by a later "[continued]" line.
+	pending map[int64]bool
+}
+
+var (
+	perfLineRe      = regexp.MustCompile(`^\s*[0-9.]+\s+\([^)]*\):\s+(?:\S+/(\d+)\s+)?(.*)$`)
+	perfContinuedRe = regexp.MustCompile(`^\.\.\. \[continued\]: (\w+)\(\)\)\s*(.*)$`)
+	perfCallRe      = regexp.MustCompile(`^(\w+)\(`)
+)
+
+func (p *perfParser) parseLine(line string) {
+	match := perfLineRe.FindStringSubmatch(line)
+	if match == nil {
+		log.Logf(4, "skipping line: %s", line)
+		return
+	}
+	pid := int64(-1)
+	if match[1] != "" {
+		pid, _ = strconv.ParseInt(match[1], 10, 64)
+	}
+	body := strings.TrimSpace(match[2])
+	if cont := perfContinuedRe.FindStringSubmatch(body); cont != nil {
+		if !p.pending[pid] {
+			log.Logf(2, "skipping continuation of %v without start", cont[1])
+			return
+		}
+		delete(p.pending, pid)
+		ret, _ := parsePerfResult(cont[2])
+		p.tree.add(NewSyscall(pid, cont[1], nil, ret, false, true))
+		return
+	}
+	call := perfCallRe.FindStringSubmatch(body)
+	if call == nil {
+		log.Logf(4, "skipping line: %s", line)
+		return
+	}
+	end := matchingParen(body, len(call[0])-1)
+	if end == -1 {
+		log.Logf(2, "skipping malformed line: %s", line)
+		return
+	}
+	var args []IrType
+	for _, arg := range splitArgs(body[len(call[0]):end]) {
+		// Argument names are optional (trace.show_arg_names=no).
+		if colon := strings.Index(arg, ": "); colon != -1 && isIdent(arg[:colon]) {
+			arg = arg[colon+2:]
+		}
+		args = append(args, p.parseValue(arg))
+	}
+	ret, done := parsePerfResult(body[end+1:])
+	if !done {
+		if p.pending[pid] {
+			log.Logf(2, "previous call of %v was not completed", pid)
+		}
+		p.pending[pid] = true
+	}
+	p.tree.add(NewSyscall(pid, call[1], args, ret, !done, false))
+}
+
+// parsePerfResult parses the part after the closing paren of the call, e.g. "= 3" or
+// "= -1 ENOENT (No such file or directory)". Returns false if the call is not finished.
+func parsePerfResult(res string) (int64, bool) {
+	res = strings.TrimSpace(res)
+	if !strings.HasPrefix(res, "=") {
+		return 0, false
+	}
+	fields := strings.Fields(res[1:])
+	if len(fields) == 0 {
+		return 0, true
+	}
+	return parseInt(fields[0]), true
+}
+
+func (p *perfParser) parseValue(val string) IrType {
+	val = strings.TrimSpace(val)
+	switch {
+	case val == "":
+		return Constant(0)
+	case val[0] == '"':
+		str, err := strconv.Unquote(strings.TrimSuffix(val, "..."))
+		if err != nil {
+			str = strings.TrimSuffix(strings.TrimPrefix(val, `"`), `"`)
+		}
+		return newBufferType(str)
+	case val[0] == '{' || val[0] == '[':
+		inner := val[1:]
+		if end := matchingParen(val, 0); end != -1 {
+			inner = val[1:end]
+		}
+		var elems []IrType
+		for _, elem := range splitArgs(inner) {
+			// Struct fields are printed as ".name = value" or "name: value".
+			if eq := strings.Index(elem, " = "); eq != -1 && strings.HasPrefix(elem, ".") {
+				elem = elem[eq+3:]
+			} else if colon := strings.Index(elem, ": "); colon != -1 && isIdent(elem[:colon]) {
+				elem = elem[colon+2:]
+			}
+			elems = append(elems, p.parseValue(elem))
+		}
+		return newGroupType(elems)
+	case val[0] == '-' || val[0] >= '0' && val[0] <= '9':
+		// File descriptors can be followed by the file path, e.g. 3</etc/passwd>.
+		if lt := strings.IndexByte(val, '<'); lt != -1 {
+			val = val[:lt]
+		}
+		return Constant(uint64(parseInt(val)))
+	}
+	res := uint64(0)
+	for _, name := range strings.Split(val, "|") {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "NULL":
+			continue
+		case "CWD":
+			name = "AT_FDCWD"
+		}
+		if v, ok := p.consts[name]; ok {
+			res |= v
+		} else if v, err := strconv.ParseUint(name, 0, 64); err == nil {
+			res |= v
+		} else {
+			log.Logf(2, "unknown symbolic value %v", name)
+		}
+	}
+	return Constant(res)
+}
+
+func parseInt(val string) int64 {
+	if v, err := strconv.ParseInt(val, 0, 64); err == nil {
+		return v
+	}
+	v, _ := strconv.ParseUint(val, 0, 64)
+	return int64(v)
+}
+
+func isIdent(s string) bool {
+	for i, c := range s {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// matchingParen returns index of the paren/bracket/brace that closes the one at start,
+// or -1 if it's not closed.
+func matchingParen(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case '"':
+			i = skipString(s, i)
+		}
+	}
+	return -1
+}
+
+// skipString returns index of the quote that closes the string that starts at start.
+func skipString(s string, start int) int {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return len(s)
+}
+
+// splitArgs splits comma-separated list on the top level (ignoring commas in nested groups and strings).
+func splitArgs(s string) []string {
+	var args []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case '"':
+			i = skipString(s, i)
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" || len(args) != 0 {
+		args = append(args, last)
+	}
+	return args
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 syzkaller project authors. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParsePerf(t *testing.T) {
+	data := `
+# comment
+     0.000 ( 0.011 ms): cat/100 brk(brk: 0) = 0x55d8a6a6d000
+     0.034 ( 0.006 ms): cat/100 access(filename: "/etc/ld.so.preload", mode: R_OK) = -1 ENOENT (No such file)
+     0.046 ( 0.006 ms): cat/100 openat(dfd: CWD, filename: "/etc/passwd", flags: O_RDONLY|O_CLOEXEC, mode: 0) = 3
+     0.050 (         ): cat/100 read(fd: 3</etc/passwd>, buf: 0x7ffd2c5f0a70, count: 4096) ...
+     0.051 ( 0.001 ms): cat/101 clock_nanosleep(which_clock: 0, flags: UNKNOWN, rqtp: {.tv_sec = 1, .tv_nsec = 0}) = 0
+     0.060 ( 0.010 ms): cat/100  ... [continued]: read()) = 1024
+     0.070 ( 0.001 ms): cat/101 close(fd: 3) ...
+     0.070 ( 0.001 ms): cat/100 clone(flags: 0x11, newsp: 0, parent_tid: 0, child_tid: 0x7f00, tls: 0) = 102
+     0.080 ( 0.001 ms): cat/102 getpid() = 102
+     0.090 ( 0.000 ms): cat/100 ... [continued]: write()) = 1
+`
+	consts := map[string]uint64{
+		"AT_FDCWD":  0xffffffffffffff9c,
+		"R_OK":      4,
+		"O_RDONLY":  0,
+		"O_CLOEXEC": 0x80000,
+	}
+	tree, err := ParsePerfData([]byte(data), consts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int64][]*Syscall{
+		100: {
+			{CallName: "brk", Pid: 100, Args: []IrType{Constant(0)}, Ret: 0x55d8a6a6d000},
+			{CallName: "access", Pid: 100, Args: []IrType{
+				newBufferType("/etc/ld.so.preload"), Constant(4)}, Ret: -1},
+			{CallName: "openat", Pid: 100, Args: []IrType{
+				Constant(0xffffffffffffff9c), newBufferType("/etc/passwd"), Constant(0x80000), Constant(0)}, Ret: 3},
+			{CallName: "read", Pid: 100, Args: []IrType{
+				Constant(3), Constant(0x7ffd2c5f0a70), Constant(4096)}, Ret: 1024},
+			{CallName: "clone", Pid: 100, Args: []IrType{
+				Constant(0x11), Constant(0), Constant(0), Constant(0x7f00), Constant(0)}, Ret: 102},
+		},
+		101: {
+			{CallName: "clock_nanosleep", Pid: 101, Args: []IrType{
+				Constant(0), Constant(0), newGroupType([]IrType{Constant(1), Constant(0)})}},
+			{CallName: "close", Pid: 101, Args: []IrType{Constant(3)}, Paused: true},
+		},
+		102: {
+			{CallName: "getpid", Pid: 102, Ret: 102},
+		},
+	}
+	got := make(map[int64][]*Syscall)
+	for pid, trace := range tree.TraceMap {
+		got[pid] = trace.Calls
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal(diff)
+	}
+	if tree.RootPid != 100 {
+		t.Fatalf("root pid %v, want 100", tree.RootPid)
+	}
+	if diff := cmp.Diff(map[int64][]int64{100: {102}}, tree.Ptree); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := map[string][]string{
+		``:                         nil,
+		`a`:                        {`a`},
+		`a, b`:                     {`a`, `b`},
+		`a: "x, \"y"  , b: {1, 2}`: {`a: "x, \"y"`, `b: {1, 2}`},
+		`[1, (2, 3)], `:            {`[1, (2, 3)]`, ``},
+	}
+	for input, want := range tests {
+		if diff := cmp.Diff(want, splitArgs(input)); diff != "" {
+			t.Errorf("%q: %v", input, diff)
+		}
+	}
+}
@@ -11,22 +11,40 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math/rand"
+	"strings"
 
 	"github.com/google/syzkaller/pkg/log"
 	"github.com/google/syzkaller/prog"
 	"github.com/google/syzkaller/tools/syz-trace2syz/parser"
 )
 
+// Supported trace formats.
+const (
+	FormatStrace = "strace"
+	FormatPerf   = "perf"
+	FormatFtrace = "ftrace"
+)
+
+var Formats = []string{FormatStrace, FormatPerf, FormatFtrace}
+
 func ParseFile(filename string, target *prog.Target) ([]*prog.Prog, error) {
+	return ParseFileFormat(filename, FormatStrace, target)
+}
+
+func ParseFileFormat(filename, format string, target *prog.Target) ([]*prog.Prog, error) {
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("error reading file: %v", err)
 	}
-	return ParseData(data, target)
+	return ParseDataFormat(data, format, target)
 }
 
 func ParseData(data []byte, target *prog.Target) ([]*prog.Prog, error) {
-	tree, err := parser.ParseData(data)
+	return ParseDataFormat(data, FormatStrace, target)
+}
+
+func ParseDataFormat(data []byte, format string, target *prog.Target) ([]*prog.Prog, error) {
+	tree, err := parseTrace(data, format, target)
 	if err != nil {
 		return nil, err
 	}
@@ -38,6 +56,24 @@ func ParseData(data []byte, target *prog.Target) ([]*prog.Prog, error) {
 	return progs, nil
 }
 
+func parseTrace(data []byte, format string, target *prog.Target) (*parser.TraceTree, error) {
+	switch format {
+	case FormatStrace:
+		return parser.ParseData(data)
+	case FormatPerf:
+		return parser.ParsePerfData(data, target.ConstMap)
+	case FormatFtrace:
+		syscalls := make(map[uint64]string)
+		for _, meta := range target.Syscalls {
+			if !strings.HasPrefix(meta.CallName, "syz_") {
+				syscalls[meta.NR] = meta.CallName
+			}
+		}
+		return parser.ParseFtraceData(data, syscalls)
+	}
+	return nil, fmt.Errorf("unknown trace format %q, supported formats: %v", format, Formats)
+}
+
 // parseTree groups system calls in the trace by process id.
 // The tree preserves process hierarchy i.e. parent->[]child
 func parseTree(tree *parser.TraceTree, pid int64, target *prog.Target, progs *[]*prog.Prog) {
@@ -292,3 +292,60 @@ ioctl$RTC_WKALM_SET(r0, 0x4028700f, &(0x7f0000000040)={0x0, 0x0, {0x0, 0x0, 0x0,
 		}
 	}
 }
+
+func TestParseFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		input  string
+		output string
+	}{
+		{FormatPerf, `
+0.046 ( 0.006 ms): cat/100 openat(dfd: CWD, filename: "file", flags: O_RDWR|O_CREAT, mode: 0) = 3
+0.050 (         ): cat/100 write(fd: 3, buf: "somedata", count: 8) ...
+0.051 ( 0.001 ms): cat/101 getpid() = 101
+0.060 ( 0.010 ms): cat/100  ... [continued]: write()) = 8
+`, `
+r0 = openat(0xffffffffffffff9c, &(0x7f0000000000)='file\x00', 0x42, 0x0)
+write(r0, &(0x7f0000000040)='somedata', 0x8)
+`,
+		}, {FormatFtrace, `
+cat-100 [001] ..... 10.000001: sys_socket(family: 1d, type: 3, protocol: 1)
+cat-101 [002] ..... 10.000002: sys_enter: NR 39 (0, 0, 0, 0, 0, 0)
+cat-100 [001] ..... 10.000003: sys_socket -> 0x3
+cat-101 [002] ..... 10.000004: sys_exit: NR 39 = 101
+cat-100 [001] ..... 10.000005: sys_enter: NR 3 (3, 0, 0, 0, 0, 0)
+cat-100 [001] ..... 10.000006: sys_exit: NR 3 = 0
+`, `
+r0 = socket$can_raw(0x1d, 0x3, 0x1)
+close(r0)
+`,
+		},
+	}
+	target, err := prog.GetTarget(targets.Linux, targets.AMD64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target.ConstMap = make(map[string]uint64)
+	for _, c := range target.Consts {
+		target.ConstMap[c.Name] = c.Value
+	}
+	for _, test := range tests {
+		input := strings.TrimSpace(test.input)
+		tree, err := parseTrace([]byte(input), test.format, target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := genProg(tree.TraceMap[tree.RootPid], target)
+		if p == nil {
+			t.Fatalf("failed to parse trace")
+		}
+		got := string(bytes.TrimSpace(p.Serialize()))
+		want := strings.TrimSpace(test.output)
+		if want != got {
+			t.Errorf("input:\n%v\n\nwant:\n%v\n\ngot:\n%v", input, want, got)
+		}
+	}
+	if _, err := parseTrace(nil, "foo", target); err == nil {
+		t.Fatalf("unknown format did not fail")
+	}
+}
@@ -9,14 +9,23 @@
 // Simple usage:
 //	strace -o trace -a 1 -s 65500 -v -xx -f -Xraw ./a.out
 //	syz-trace2syz -file trace
+// perf trace and ftrace syscall events output are supported as well:
+//	perf config trace.show_zeros=yes trace.show_prefix=yes
+//	perf trace -o trace -f ./a.out
+//	syz-trace2syz -format perf -file trace
+//	echo 1 > /sys/kernel/tracing/events/syscalls/enable
+//	cat /sys/kernel/tracing/trace_pipe > trace
+//	syz-trace2syz -format ftrace -file trace
 // Intended for seed selection or debugging
 package main
 
 import (
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/google/syzkaller/pkg/db"
 	"github.com/google/syzkaller/pkg/log"
@@ -31,6 +40,8 @@ var (
 	flagFile        = flag.String("file", "", "file to parse")
 	flagDir         = flag.String("dir", "", "directory to parse")
 	flagDeserialize = flag.String("deserialize", "", "(Optional) directory to store deserialized programs")
+	flagFormat      = flag.String("format", proggen.FormatStrace,
+		fmt.Sprintf("trace format (%v)", strings.Join(proggen.Formats, ", ")))
 )
 
 const (
@@ -76,7 +87,7 @@ func parseTraces(target *prog.Target) []*prog.Prog {
 	log.Logf(0, "parsing %v traces", totalFiles)
 	for i, file := range names {
 		log.Logf(1, "parsing file %v/%v: %v", i+1, totalFiles, filepath.Base(names[i]))
-		progs, err := proggen.ParseFile(file, target)
+		progs, err := proggen.ParseFileFormat(file, *flagFormat, target)
 		if err != nil {
 			log.Fatalf("%v", err)
 		}
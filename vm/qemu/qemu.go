@@ -695,6 +695,39 @@ func (inst *instance) Diagnose(rep *report.Report) ([]byte, bool) {
 	return ret, false
 }
 
+// snapshotTag is the name of the internal snapshot of the VM state.
+const snapshotTag = "syz-snapshot"
+
+func (inst *instance) Snapshot() error {
+	// The snapshot is saved in the temporary overlay of the image created by -snapshot.
+	return inst.hmpSnapshot("savevm")
+}
+
+func (inst *instance) Restore() error {
+	// Consume the error of the ssh output of the finished command, otherwise the next Run
+	// would take it for the failure of its command.
+	select {
+	case err := <-inst.merger.Err:
+		if merr, ok := err.(vmimpl.MergerError); !ok || merr.Name != "ssh" {
+			return err
+		}
+	case <-time.After(10 * time.Second * inst.timeouts.Scale):
+	}
+	return inst.hmpSnapshot("loadvm")
+}
+
+// hmpSnapshot executes the savevm/loadvm command, the commands print nothing on success.
+func (inst *instance) hmpSnapshot(cmd string) error {
+	out, err := inst.hmp(cmd+" "+snapshotTag, 0)
+	if err != nil {
+		return fmt.Errorf("%v failed: %v", cmd, err)
+	}
+	if out != "" {
+		return fmt.Errorf("%v failed: %v", cmd, strings.TrimSpace(out))
+	}
+	return nil
+}
+
 func (inst *instance) ssh(args ...string) ([]byte, error) {
 	return osutil.RunCmd(time.Minute*inst.timeouts.Scale, "", "ssh", inst.sshArgs(args...)...)
 }
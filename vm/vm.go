@@ -150,6 +150,22 @@ func (inst *Instance) Info() ([]byte, error) {
 	return nil, nil
 }
 
+// Snapshot saves the state of the VM, so that it can be restored later with Restore.
+func (inst *Instance) Snapshot() error {
+	if si, ok := inst.impl.(vmimpl.Snapshotter); ok {
+		return si.Snapshot()
+	}
+	return fmt.Errorf("VM snapshots are not supported")
+}
+
+// Restore restores the VM to the state saved with Snapshot.
+func (inst *Instance) Restore() error {
+	if si, ok := inst.impl.(vmimpl.Snapshotter); ok {
+		return si.Restore()
+	}
+	return fmt.Errorf("VM snapshots are not supported")
+}
+
 func (inst *Instance) diagnose(rep *report.Report) ([]byte, bool) {
 	if rep == nil {
 		panic("rep is nil")
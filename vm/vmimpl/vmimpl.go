@@ -66,6 +66,15 @@ type Infoer interface {
 	Info() ([]byte, error)
 }
 
+// Snapshotter is an optional interface that can be implemented by Instance.
+type Snapshotter interface {
+	// Snapshot saves the state of the VM.
+	Snapshot() error
+	// Restore restores the VM to the saved state. The commands started with Run
+	// must have finished before the call.
+	Restore() error
+}
+
 // Env contains global constant parameters for a pool of VMs.
 type Env struct {
 	// Unique name